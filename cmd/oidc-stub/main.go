@@ -0,0 +1,33 @@
+// Command oidc-stub runs the stub OpenID Connect provider from oidctest, to
+// try single sign on locally without a real provider. It signs in straight
+// away as the user given on the command line.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"mimpidev/sinkrontrack-server/internal/oidc/oidctest"
+	"net/http"
+	"os"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:9998", "address to listen on")
+	clientId := flag.String("client-id", "sinkrontrack", "client id the server is configured with")
+	subject := flag.String("subject", "oidc-stub-user", "subject of the user signed in")
+	email := flag.String("email", "oidc@test.com", "email address of the user signed in")
+	flag.Parse()
+
+	idp, err := oidctest.NewIdP("http://"+*addr, *clientId)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	idp.Subject = *subject
+	idp.EmailAddress = *email
+	fmt.Println("Stub identity provider at " + idp.Issuer)
+	if err := http.ListenAndServe(*addr, idp); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+}
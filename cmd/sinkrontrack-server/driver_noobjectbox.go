@@ -0,0 +1,6 @@
+//go:build noobjectbox
+// +build noobjectbox
+
+package main
+
+const defaultStorageDriver = "memory"
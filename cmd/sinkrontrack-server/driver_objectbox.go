@@ -0,0 +1,10 @@
+//go:build !noobjectbox
+// +build !noobjectbox
+
+package main
+
+// Build with -tags noobjectbox to leave the ObjectBox driver (and its native
+// library) out of the binary.
+import _ "mimpidev/sinkrontrack-server/internal/storage/objectbox"
+
+const defaultStorageDriver = "objectbox"
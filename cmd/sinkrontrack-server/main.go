@@ -2,33 +2,149 @@ package main
 
 import (
 	"fmt"
+	"mimpidev/sinkrontrack-server/internal/mailer"
+	_ "mimpidev/sinkrontrack-server/internal/mailer/file"
+	_ "mimpidev/sinkrontrack-server/internal/mailer/smtp"
+	"mimpidev/sinkrontrack-server/internal/oidc"
 	"mimpidev/sinkrontrack-server/internal/storage"
+	_ "mimpidev/sinkrontrack-server/internal/storage/memory"
+	_ "mimpidev/sinkrontrack-server/internal/storage/sqlite"
 	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"mimpidev/sinkrontrack-server/pkg/events"
 	"mimpidev/sinkrontrack-server/pkg/playlist"
 	"mimpidev/sinkrontrack-server/pkg/userLogin"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// Mail is written to the log until MAIL_DRIVER picks a driver that delivers it
+const defaultMailDriver = "log"
+
+// registerErrorCodes gives the storage errors handlers return as they are
+// their codes, storage doesn't know about http
+func registerErrorCodes() {
+	webhelper.RegisterErrorCode(storage.ErrUserExists, webhelper.CodeAccountExists)
+	webhelper.RegisterErrorCode(storage.ErrAlreadyInGroup, webhelper.CodeAlreadyInGroup)
+	webhelper.RegisterErrorCode(storage.ErrGroupConflict, webhelper.CodeGroupConflict)
+	webhelper.RegisterErrorCode(storage.ErrLoginExists, webhelper.CodeLoginLinked)
+	webhelper.RegisterErrorCode(storage.ErrRevisionConflict, webhelper.CodeRevisionConflict)
+	webhelper.RegisterErrorCode(storage.ErrTrackOrder, webhelper.CodeInvalidTrackOrder)
+}
+
 func buildRoutes() {
+	webhelper.Use(webhelper.RequestIds, webhelper.Logging, webhelper.Recovery)
+
 	webhelper.NewRoute("GET", "/", webhelper.RootHandler)
 	webhelper.NewRoute("POST", "/users(/|)", userLogin.CreateUserLogin)
-	webhelper.NewRoute("DELETE", "/users/([^/]+)", userLogin.DeleteUserLogin)
-	webhelper.NewRoute("PATCH", "/users/([^/]+)", userLogin.UpdateUserLogin)
-	webhelper.NewRoute("GET", "/users/([^/]+)", userLogin.ListUsers)
-	webhelper.NewRoute("GET", "/users(/|)", userLogin.ListUsers)
 	webhelper.NewRoute("POST", "/users/signin", userLogin.Signin)
+	webhelper.NewRoute("POST", "/users/signin/totp", userLogin.SigninTotp)
 	webhelper.NewRoute("POST", "/users/refreshToken", userLogin.RefreshToken)
-	webhelper.NewRoute("GET", "/playlists(/|)", playlist.ListPlaylist)
-	webhelper.NewRoute("GET", "/playlists/([^/]+)", playlist.GetPlaylist)
-	webhelper.NewRoute("POST", "/playlists(/|)", playlist.CreatePlaylist)
-	webhelper.NewRoute("PATCH", "/playlists/([^/]+)", playlist.UpdatePlaylist)
-	webhelper.NewRoute("DELETE", "/playlists/([^/]+)", playlist.DeletePlaylist)
-	webhelper.NewRoute("POST", "/playlists/([^/]+)/track", playlist.AddTrack)
-	webhelper.NewRoute("PATCH", "/tracks/([^/]+)", playlist.UpdateTrack)
-	webhelper.NewRoute("DELETE", "/tracks/([^/]+)", playlist.DeleteTrack)
+	webhelper.NewRoute("POST", "/users/passwordReset", userLogin.RequestPasswordReset)
+	webhelper.NewRoute("POST", "/users/passwordReset/confirm", userLogin.ResetPassword)
+	webhelper.NewRoute("POST", "/users/verifyEmail/confirm", userLogin.VerifyEmail)
+	webhelper.NewRoute("GET", "/.well-known/jwks.json", userLogin.JWKS)
+	webhelper.NewRoute("GET", "/users/oidc(/|)", userLogin.ListOidcProviders)
+	webhelper.NewRoute("GET", "/users/oidc/{provider}", userLogin.OidcLogin)
+	webhelper.NewRoute("GET", "/users/oidc/{provider}/callback", userLogin.OidcCallback)
+
+	// Scoped tokens and API keys can only use the routes that allow one of
+	// their scopes
+	readPlaylists := webhelper.RequireScope(userLogin.ScopePlaylistRead)
+	writePlaylists := webhelper.RequireScope(userLogin.ScopePlaylistWrite)
+	writePositions := webhelper.RequireScope(userLogin.ScopePositionWrite)
+	userAdmin := webhelper.RequireScope(userLogin.ScopeUserAdmin)
+
+	auth := webhelper.NewGroup("", userLogin.Authenticate)
+	auth.NewRoute("POST", "/users/signout", userLogin.Signout, webhelper.AnyScope)
+	auth.NewRoute("POST", "/users/signoutEverywhere", userLogin.SignoutEverywhere, userAdmin)
+	auth.NewRoute("POST", "/users/verifyEmail", userLogin.SendVerifyEmail, userAdmin)
+	auth.NewRoute("POST", "/users/totp", userLogin.EnrolTotp, userAdmin)
+	auth.NewRoute("POST", "/users/totp/confirm", userLogin.ConfirmTotp, userAdmin)
+	auth.NewRoute("POST", "/users/totp/recoveryCodes", userLogin.RegenerateRecoveryCodes, userAdmin)
+	auth.NewRoute("DELETE", "/users/totp", userLogin.DisableTotp, userAdmin)
+	auth.NewRoute("GET", "/users/identities(/|)", userLogin.ListExternalLogins, userAdmin)
+	auth.NewRoute("GET", "/users/identities/{provider}/link", userLogin.LinkOidc, userAdmin)
+	auth.NewRoute("DELETE", "/users/identities/{provider}", userLogin.DeleteExternalLogin, userAdmin)
+	auth.NewRoute("GET", "/users/apikeys(/|)", userLogin.ListApiKeys, userAdmin)
+	auth.NewRoute("POST", "/users/apikeys(/|)", userLogin.CreateApiKey, userAdmin)
+	auth.NewRoute("DELETE", "/users/apikeys/{uuid}", userLogin.DeleteApiKey, userAdmin)
+	manageLockouts := webhelper.RequirePermission(userLogin.PermLockoutsManage)
+	auth.NewRoute("GET", "/users/lockouts", userLogin.ListLockouts, userAdmin, manageLockouts)
+	auth.NewRoute("DELETE", "/users/lockouts/{email}", userLogin.DeleteLockout, userAdmin, manageLockouts)
+	auth.NewRoute("PUT", "/users/{id}/roles", userLogin.SetUserRoles, userAdmin, webhelper.RequirePermission(userLogin.PermRolesManage))
+	auth.NewRoute("DELETE", "/users/{id}", userLogin.DeleteUserLogin, userAdmin, webhelper.RequireOwner(userLogin.OwnsUserId, userLogin.PermUsersDelete))
+	auth.NewRoute("PATCH", "/users/{id}", userLogin.UpdateUserLogin, userAdmin, webhelper.RequireOwner(userLogin.OwnsUserId, userLogin.PermUsersWrite))
+	auth.NewRoute("GET", "/users/{id}", userLogin.ListUsers, userAdmin, webhelper.RequireOwner(userLogin.OwnsUserId, userLogin.PermUsersRead))
+	auth.NewRoute("GET", "/users(/|)", userLogin.ListUsers, userAdmin, webhelper.RequirePermission(userLogin.PermUsersRead))
+	auth.NewRoute("GET", "/roles(/|)", userLogin.ListRoles, userAdmin, webhelper.RequirePermission(userLogin.PermUsersRead))
+
+	// Family members only get what is shared with them, without making friends
+	makeFriends := webhelper.RequirePermission(userLogin.PermFriendsWrite)
+
+	playlists := auth.Group("/playlists")
+	playlists.NewRoute("GET", "(/|)", playlist.ListPlaylist, readPlaylists)
+	playlists.NewRoute("POST", "(/|)", playlist.CreatePlaylist, writePlaylists)
+	playlists.NewRoute("GET", "/shared", playlist.ListSharedPlaylists, readPlaylists)
+	playlists.NewRoute("GET", "/{uuid}", playlist.GetPlaylist, readPlaylists)
+	// Renaming needs playlist:write too, UpdatePlaylist checks that
+	playlists.NewRoute("PATCH", "/{uuid}", playlist.UpdatePlaylist, webhelper.RequireScope(userLogin.ScopePlaylistWrite, userLogin.ScopePositionWrite))
+	playlists.NewRoute("DELETE", "/{uuid}", playlist.DeletePlaylist, writePlaylists)
+	playlists.NewRoute("POST", "/{uuid}/track", playlist.AddTrack, writePlaylists)
+	playlists.NewRoute("PUT", "/{uuid}/tracks", playlist.ReorderTracks, writePlaylists)
+	playlists.NewRoute("PATCH", "/{uuid}/tracks/{trackUuid}", playlist.MoveTrack, writePlaylists)
+	playlists.NewRoute("DELETE", "/{uuid}/tracks/{trackUuid}", playlist.RemoveTrack, writePlaylists)
+	// Locks belong to a device, which API keys don't have
+	playlists.NewRoute("GET", "/{uuid}/lock", playlist.GetLock, readPlaylists)
+	playlists.NewRoute("POST", "/{uuid}/lock", playlist.AcquireLock, writePositions)
+	playlists.NewRoute("DELETE", "/{uuid}/lock", playlist.ReleaseLock, writePositions)
+	playlists.NewRoute("GET", "/{uuid}/shares", playlist.ListShares, readPlaylists)
+	playlists.NewRoute("PUT", "/{uuid}/shares/{userUuid}", playlist.SharePlaylist, writePlaylists, makeFriends)
+	playlists.NewRoute("DELETE", "/{uuid}/shares/{userUuid}", playlist.UnsharePlaylist, writePlaylists)
+
+	tracks := auth.Group("/tracks")
+	tracks.NewRoute("GET", "(/|)", playlist.ListTracks, readPlaylists)
+	tracks.NewRoute("POST", "(/|)", playlist.CreateTrack, writePlaylists)
+	tracks.NewRoute("PATCH", "/{uuid}", playlist.UpdateTrack, writePlaylists)
+	tracks.NewRoute("DELETE", "/{uuid}", playlist.DeleteTrack, writePlaylists)
+	tracks.NewRoute("GET", "/{uuid}/position", playlist.GetResumePosition, readPlaylists)
+	tracks.NewRoute("PUT", "/{uuid}/position", playlist.UpdateResumePosition, writePositions)
+	tracks.NewRoute("GET", "/{uuid}/plays", playlist.ListTrackPlays, readPlaylists)
+	tracks.NewRoute("POST", "/{uuid}/plays", playlist.AddPlay, writePositions)
 
+	auth.NewRoute("GET", "/history(/|)", playlist.ListHistory, readPlaylists)
+	auth.NewRoute("GET", "/continue(/|)", playlist.ContinueListening, readPlaylists)
+	auth.NewRoute("GET", "/events(/|)", events.Stream, readPlaylists)
+
+	devices := auth.Group("/devices", userAdmin)
+	devices.NewRoute("GET", "(/|)", userLogin.ListDevices)
+	devices.NewRoute("PATCH", "/{uuid}", userLogin.UpdateDevice)
+	devices.NewRoute("DELETE", "/{uuid}", userLogin.DeleteDevice)
+
+	friends := auth.Group("/friends", userAdmin)
+	friends.NewRoute("GET", "(/|)", userLogin.ListFriends)
+	friends.NewRoute("POST", "(/|)", userLogin.RequestFriend, makeFriends)
+	friends.NewRoute("POST", "/{uuid}/accept", userLogin.AcceptFriend, makeFriends)
+	friends.NewRoute("POST", "/{uuid}/decline", userLogin.DeclineFriend)
+	friends.NewRoute("DELETE", "/{uuid}", userLogin.DeleteFriend)
+
+	groups := auth.Group("/groups")
+	groups.NewRoute("GET", "(/|)", userLogin.ListGroups, userAdmin)
+	groups.NewRoute("GET", "/invites", userLogin.ListGroupInvites, userAdmin)
+	groups.NewRoute("POST", "(/|)", userLogin.CreateGroup, userAdmin, webhelper.RequirePermission(userLogin.PermGroupsWrite))
+	groups.NewRoute("GET", "/{uuid}", userLogin.GetGroup, userAdmin)
+	groups.NewRoute("PATCH", "/{uuid}", userLogin.UpdateGroup, userAdmin)
+	groups.NewRoute("DELETE", "/{uuid}", userLogin.DeleteGroup, userAdmin)
+	groups.NewRoute("GET", "/{uuid}/playlists", playlist.ListGroupPlaylists, readPlaylists)
+	groups.NewRoute("POST", "/{uuid}/invites", userLogin.InviteGroupMember, userAdmin)
+	groups.NewRoute("POST", "/{uuid}/invites/accept", userLogin.AcceptGroupInvite, userAdmin)
+	groups.NewRoute("POST", "/{uuid}/invites/decline", userLogin.DeclineGroupInvite, userAdmin)
+	groups.NewRoute("DELETE", "/{uuid}/invites/{userUuid}", userLogin.CancelGroupInvite, userAdmin)
+	groups.NewRoute("POST", "/{uuid}/children", userLogin.CreateChildAccount, userAdmin)
+	groups.NewRoute("PATCH", "/{uuid}/members/{userUuid}", userLogin.UpdateGroupMember, userAdmin)
+	groups.NewRoute("DELETE", "/{uuid}/members/{userUuid}", userLogin.RemoveGroupMember, userAdmin)
 }
 
 func initializeAdminUser() {
@@ -50,7 +166,8 @@ func initializeAdminUser() {
 		user.EmailAddress = os.Getenv("ADMIN_EMAIL")
 		user.Password = os.Getenv("ADMIN_PASSWORD")
 		user.Enabled = true
-		user.AdminUser = true
+		user.EmailVerified = true
+		user.Roles = []string{storage.RoleAdmin}
 		id, err := userLogin.CreateUser(&user)
 		if err != nil {
 			fmt.Println(err.Error())
@@ -60,19 +177,70 @@ func initializeAdminUser() {
 	}
 }
 
+func initializeStorage() storage.DataStorage {
+	driver := os.Getenv("STORAGE_DRIVER")
+	if driver == "" {
+		driver = defaultStorageDriver
+	}
+	store, err := storage.Initialize(driver, os.Getenv("STORAGE_PATH"))
+	if err != nil {
+		fmt.Println(err.Error())
+		fmt.Println("Available storage drivers: " + strings.Join(storage.Drivers(), ", "))
+		os.Exit(2)
+	}
+	return store
+}
+
+func initializeMailer() {
+	driver := os.Getenv("MAIL_DRIVER")
+	if driver == "" {
+		driver = defaultMailDriver
+	}
+	_, err := mailer.Initialize(driver, os.Getenv("MAIL_PATH"), os.Getenv("MAIL_FROM"))
+	if err != nil {
+		fmt.Println(err.Error())
+		fmt.Println("Available mail drivers: " + strings.Join(mailer.Drivers(), ", "))
+		os.Exit(2)
+	}
+}
+
+// initializeIdentityProviders registers each provider named in OIDC_PROVIDERS,
+// set up from its OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET,
+// _REDIRECT_URL and _SCOPES
+func initializeIdentityProviders() {
+	for _, name := range strings.Fields(strings.ReplaceAll(os.Getenv("OIDC_PROVIDERS"), ",", " ")) {
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		client, err := oidc.Discover(oidc.Config{
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientId:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectUrl:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		})
+		if err != nil {
+			fmt.Println("Identity provider " + name + ": " + err.Error())
+			os.Exit(2)
+		}
+		oidc.Register(name, client)
+	}
+}
+
 func main() {
-	storage.Initialize()
-	defer storage.Ob.Close()
+	store := initializeStorage()
+	defer store.Close()
 
-	jwtKey := []byte(os.Getenv("JWT_KEY"))
-	if len(jwtKey) == 0 {
-		fmt.Println("No JWT Key defined in environment")
+	if err := userLogin.LoadSigningKeys(); err != nil {
+		fmt.Println(err.Error())
 		os.Exit(1)
 	}
 
+	initializeMailer()
+	initializeIdentityProviders()
 	initializeAdminUser()
 
+	registerErrorCodes()
 	buildRoutes()
+	go userLogin.PruneRevokedTokens(time.Hour)
 	http.HandleFunc("/", webhelper.Serve)
 	http.ListenAndServe(":9999", nil)
 }
@@ -3,10 +3,12 @@ module mimpidev/sinkrontrack-server
 go 1.17
 
 require (
-	github.com/dgrijalva/jwt-go v3.2.0+incompatible // indirect
-	github.com/google/flatbuffers v1.12.0 // indirect
-	github.com/google/uuid v1.3.0 // indirect
-	github.com/objectbox/objectbox-generator v0.13.0 // indirect
-	github.com/objectbox/objectbox-go v1.6.1 // indirect
-	golang.org/x/crypto v0.0.0-20220525230936-793ad666bf5e // indirect
+	github.com/dgrijalva/jwt-go v3.2.0+incompatible
+	github.com/google/flatbuffers v1.12.0
+	github.com/google/uuid v1.3.0
+	github.com/mattn/go-sqlite3 v1.14.16
+	github.com/objectbox/objectbox-go v1.6.1
+	golang.org/x/crypto v0.0.0-20220525230936-793ad666bf5e
 )
+
+require github.com/objectbox/objectbox-generator v0.13.0 // indirect
@@ -0,0 +1,70 @@
+// Package file is the mail driver for development and tests, it never touches
+// the network. The "file" driver writes each message into a directory and the
+// "log" driver writes them to the server log, without their secrets.
+package file
+
+import (
+	"fmt"
+	"log"
+	"mimpidev/sinkrontrack-server/internal/mailer"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultDirectory = "mail"
+
+func init() {
+	mailer.Register("file", func(dataSource string) (mailer.Mailer, error) {
+		return Open(dataSource)
+	})
+	mailer.Register("log", func(dataSource string) (mailer.Mailer, error) {
+		return &Logger{}, nil
+	})
+}
+
+// Mailer writes every message to its own .eml file in the directory
+type Mailer struct {
+	directory string
+	mutex     sync.Mutex
+	count     int
+}
+
+func Open(directory string) (*Mailer, error) {
+	if directory == "" {
+		directory = defaultDirectory
+	}
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return nil, err
+	}
+	return &Mailer{directory: directory}, nil
+}
+
+func (m *Mailer) Send(message *mailer.Message) error {
+	now := time.Now()
+	m.mutex.Lock()
+	m.count++
+	// Named by time so the files list in the order they were sent
+	name := fmt.Sprintf("%s-%04d.eml", now.UTC().Format("20060102T150405.000000000"), m.count)
+	m.mutex.Unlock()
+	return os.WriteFile(filepath.Join(m.directory, name), mailer.Format(message, now), 0600)
+}
+
+const redacted = "[redacted]"
+
+// Logger writes every message to the log. Anyone who can read the log could
+// use a token in it, so the message's secrets are redacted.
+type Logger struct{}
+
+func (l *Logger) Send(message *mailer.Message) error {
+	body := message.Body
+	for _, secret := range message.Secrets {
+		if secret != "" {
+			body = strings.ReplaceAll(body, secret, redacted)
+		}
+	}
+	log.Printf("mail to %s: %s\n%s", message.To, message.Subject, body)
+	return nil
+}
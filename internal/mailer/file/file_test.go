@@ -0,0 +1,52 @@
+package file
+
+import (
+	"bytes"
+	"log"
+	"mimpidev/sinkrontrack-server/internal/mailer"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileMailer(t *testing.T) {
+	t.Run("Each message is written to its own file", func(t *testing.T) {
+		directory := filepath.Join(t.TempDir(), "mail")
+		m, err := Open(directory)
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		for _, to := range []string{"one@test.com", "two@test.com"} {
+			err := m.Send(&mailer.Message{From: "server@test.com", To: to, Subject: "Hello", Body: "Line one\nLine two"})
+			if err != nil {
+				t.Fatalf("Want no error, got '%s'", err.Error())
+			}
+		}
+		files, _ := filepath.Glob(filepath.Join(directory, "*.eml"))
+		if len(files) != 2 {
+			t.Fatalf("Want 2 files, got %d", len(files))
+		}
+		data, _ := os.ReadFile(files[0])
+		for _, want := range []string{"To: one@test.com\r\n", "Subject: Hello\r\n", "\r\n\r\nLine one\r\nLine two"} {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("Want the message to contain '%q', got '%q'", want, string(data))
+			}
+		}
+	})
+}
+
+func TestLogger(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	m := &Logger{}
+	err := m.Send(&mailer.Message{To: "one@test.com", Subject: "Reset", Body: "Use this code:\n\nsecret-token\n", Secrets: []string{"secret-token"}})
+	if err != nil {
+		t.Fatalf("Want no error, got '%s'", err.Error())
+	}
+	if strings.Contains(logged.String(), "secret-token") || !strings.Contains(logged.String(), "Use this code:\n\n"+redacted) {
+		t.Errorf("Want the token redacted, got '%s'", logged.String())
+	}
+}
@@ -0,0 +1,101 @@
+// Package mailer sends email through whichever driver was configured, an SMTP
+// server in production or a directory or the log for development and tests.
+package mailer
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+const defaultFrom = "sinkrontrack@localhost"
+
+var ErrNoMailer = errors.New("No mailer configured")
+
+// Message is a plain text email
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Body    string
+	Secrets []string // parts of the body that are left out when it is logged
+}
+
+// Mailer is implemented by each of the mail drivers
+type Mailer interface {
+	Send(m *Message) error
+}
+
+// Driver opens a Mailer, dataSource is driver specific (an smtp:// URL, a
+// directory, or blank for the driver default)
+type Driver func(dataSource string) (Mailer, error)
+
+var (
+	driversMutex sync.RWMutex
+	drivers      = make(map[string]Driver)
+)
+
+// Active is the Mailer opened by Initialize, and From the address it sends from
+var (
+	Active Mailer
+	From   = defaultFrom
+)
+
+// Register makes a mail driver available by name, it is intended to be called
+// from the init function of the driver package.
+func Register(name string, driver Driver) {
+	driversMutex.Lock()
+	defer driversMutex.Unlock()
+	if driver == nil {
+		panic("mailer: Register driver is nil")
+	}
+	if _, dup := drivers[name]; dup {
+		panic("mailer: Register called twice for driver " + name)
+	}
+	drivers[name] = driver
+}
+
+// Drivers returns the sorted names of the registered drivers
+func Drivers() []string {
+	driversMutex.RLock()
+	defer driversMutex.RUnlock()
+	var list []string
+	for name := range drivers {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Initialize opens the named driver and makes it the Active mailer, sending
+// from the from address (or the default when it is blank)
+func Initialize(driverName string, dataSource string, from string) (Mailer, error) {
+	driversMutex.RLock()
+	driver, ok := drivers[driverName]
+	driversMutex.RUnlock()
+	if !ok {
+		return nil, errors.New("Unknown mail driver: " + driverName)
+	}
+	mailer, err := driver(dataSource)
+	if err != nil {
+		return nil, err
+	}
+	Active = mailer
+	From = from
+	if From == "" {
+		From = defaultFrom
+	}
+	return Active, nil
+}
+
+// Send sends the message with the Active mailer, from From unless the message
+// says otherwise
+func Send(m *Message) error {
+	if Active == nil {
+		return ErrNoMailer
+	}
+	if m.From == "" {
+		m.From = From
+	}
+	return Active.Send(m)
+}
@@ -0,0 +1,69 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	sent []*Message
+}
+
+func (r *recorder) Send(m *Message) error {
+	r.sent = append(r.sent, m)
+	return nil
+}
+
+// recorded is the recorder the "recorder" driver opens, registered once as
+// Register panics on a name it has already seen
+var recorded *recorder
+
+func init() {
+	Register("recorder", func(dataSource string) (Mailer, error) {
+		return recorded, nil
+	})
+}
+
+// restoreMailer puts back the Active mailer and From address once the test is done
+func restoreMailer(t *testing.T) {
+	active, from := Active, From
+	t.Cleanup(func() {
+		Active, From = active, from
+	})
+}
+
+func TestSend(t *testing.T) {
+	restoreMailer(t)
+	t.Run("Sending without a mailer", func(t *testing.T) {
+		Active = nil
+		if err := Send(&Message{To: "test@test.com"}); err != ErrNoMailer {
+			t.Errorf("Want error '%v', got '%v'", ErrNoMailer, err)
+		}
+	})
+	t.Run("Messages are sent from the configured address", func(t *testing.T) {
+		r := &recorder{}
+		recorded = r
+		if _, err := Initialize("recorder", "", "server@test.com"); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		Send(&Message{To: "test@test.com"})
+		if len(r.sent) != 1 || r.sent[0].From != "server@test.com" {
+			t.Errorf("Want one message from 'server@test.com', got '%+v'", r.sent)
+		}
+	})
+	t.Run("Unknown driver", func(t *testing.T) {
+		if _, err := Initialize("pigeon", "", ""); err == nil {
+			t.Error("Want an error for an unknown driver")
+		}
+	})
+}
+
+func TestFormat(t *testing.T) {
+	t.Run("A line break can't add a header", func(t *testing.T) {
+		data := string(Format(&Message{To: "a@test.com\r\nBcc: b@test.com"}, time.Now()))
+		if strings.Contains(data, "\r\nBcc:") {
+			t.Errorf("Want the Bcc kept in the To header, got '%q'", data)
+		}
+	})
+}
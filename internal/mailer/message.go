@@ -0,0 +1,29 @@
+package mailer
+
+import (
+	"bytes"
+	"mime"
+	"strings"
+	"time"
+)
+
+// Format renders the message as RFC 5322 text, which is what the SMTP driver
+// sends and the file driver writes
+func Format(m *Message, now time.Time) []byte {
+	var b bytes.Buffer
+	header := func(name string, value string) {
+		// A header value can't hold a line break, or it would start a new header
+		value = strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+		b.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", m.From)
+	header("To", m.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	b.WriteString("\r\n")
+	body := strings.ReplaceAll(m.Body, "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return b.Bytes()
+}
@@ -0,0 +1,320 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const discoveryPath = "/.well-known/openid-configuration"
+
+var defaultScopes = []string{"openid", "email", "profile"}
+
+var errInvalidIdToken = errors.New("Invalid ID token from the identity provider")
+var errUnknownSigningKey = errors.New("ID token signed with an unknown key")
+
+// Config sets up a Client. The redirect URL is the callback the provider sends
+// the browser back to, and has to be registered with the provider.
+type Config struct {
+	Issuer       string
+	ClientId     string
+	ClientSecret string // blank for public clients, PKCE protects the code
+	RedirectUrl  string
+	Scopes       []string // defaults to openid, email and profile
+}
+
+// Client is a Provider for any OpenID Connect provider, set up from its
+// discovery document
+type Client struct {
+	config                Config
+	authorizationEndpoint string
+	tokenEndpoint         string
+	jwksUri               string
+	httpClient            *http.Client
+
+	keysMutex sync.RWMutex
+	keys      map[string]interface{}
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+type tokenResponse struct {
+	IdToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// audience is the aud claim, which is either one string or a list of them
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+type idTokenClaims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	ExpiresAt     int64    `json:"exp"`
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	GivenName     string   `json:"given_name"`
+	FamilyName    string   `json:"family_name"`
+}
+
+// Valid only checks the expiry, Exchange checks the rest against the config
+func (c *idTokenClaims) Valid() error {
+	if time.Now().Unix() > c.ExpiresAt {
+		return errInvalidIdToken
+	}
+	return nil
+}
+
+// Discover sets up a Client from the discovery document of the issuer
+func Discover(config Config) (*Client, error) {
+	client := &Client{
+		config:     config,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		keys:       make(map[string]interface{}),
+	}
+	if len(client.config.Scopes) == 0 {
+		client.config.Scopes = defaultScopes
+	}
+	var document discoveryDocument
+	if err := client.getJSON(strings.TrimSuffix(config.Issuer, "/")+discoveryPath, &document); err != nil {
+		return nil, err
+	}
+	if document.Issuer != config.Issuer {
+		return nil, fmt.Errorf("Identity provider issuer '%s' doesn't match '%s'", document.Issuer, config.Issuer)
+	}
+	if document.AuthorizationEndpoint == "" || document.TokenEndpoint == "" || document.JwksUri == "" {
+		return nil, errors.New("Identity provider discovery document is missing endpoints")
+	}
+	client.authorizationEndpoint = document.AuthorizationEndpoint
+	client.tokenEndpoint = document.TokenEndpoint
+	client.jwksUri = document.JwksUri
+	return client, nil
+}
+
+func (c *Client) getJSON(uri string, v interface{}) error {
+	response, err := c.httpClient.Get(uri)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("Identity provider returned %d for %s", response.StatusCode, uri)
+	}
+	return json.NewDecoder(response.Body).Decode(v)
+}
+
+func (c *Client) AuthCodeURL(state string, nonce string, codeChallenge string) string {
+	values := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.config.ClientId},
+		"redirect_uri":          {c.config.RedirectUrl},
+		"scope":                 {strings.Join(c.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(c.authorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return c.authorizationEndpoint + separator + values.Encode()
+}
+
+func (c *Client) Exchange(code string, codeVerifier string, nonce string) (*Identity, error) {
+	values := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.config.RedirectUrl},
+		"client_id":     {c.config.ClientId},
+		"code_verifier": {codeVerifier},
+	}
+	request, err := http.NewRequest("POST", c.tokenEndpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if c.config.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(c.config.ClientId), url.QueryEscape(c.config.ClientSecret))
+	}
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	var tokens tokenResponse
+	if err := json.NewDecoder(response.Body).Decode(&tokens); err != nil {
+		return nil, err
+	}
+	if tokens.Error != "" {
+		return nil, fmt.Errorf("Identity provider refused the code: %s %s", tokens.Error, tokens.ErrorDescription)
+	}
+	if response.StatusCode != http.StatusOK || tokens.IdToken == "" {
+		return nil, fmt.Errorf("Identity provider returned %d without an ID token", response.StatusCode)
+	}
+	return c.verify(tokens.IdToken, nonce)
+}
+
+// verify checks the ID token was signed by the provider, for this client, and
+// for this sign in
+func (c *Client) verify(idToken string, nonce string) (*Identity, error) {
+	claims := &idTokenClaims{}
+	parser := &jwt.Parser{ValidMethods: []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}}
+	token, err := parser.ParseWithClaims(idToken, claims, c.keyFunc)
+	if err != nil || !token.Valid {
+		return nil, errInvalidIdToken
+	}
+	if claims.Issuer != c.config.Issuer || claims.Subject == "" || claims.Nonce != nonce {
+		return nil, errInvalidIdToken
+	}
+	found := false
+	for _, aud := range claims.Audience {
+		found = found || aud == c.config.ClientId
+	}
+	if !found {
+		return nil, errInvalidIdToken
+	}
+	return &Identity{
+		Subject:       claims.Subject,
+		EmailAddress:  claims.Email,
+		EmailVerified: claims.EmailVerified,
+		FirstName:     claims.GivenName,
+		LastName:      claims.FamilyName,
+	}, nil
+}
+
+// keyFunc finds the key the token was signed with, fetching the provider's
+// keys again once when it has rotated to one that isn't known yet
+func (c *Client) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	c.keysMutex.RLock()
+	key, ok := c.keys[kid]
+	c.keysMutex.RUnlock()
+	if !ok {
+		if err := c.fetchKeys(); err != nil {
+			return nil, err
+		}
+		c.keysMutex.RLock()
+		key, ok = c.keys[kid]
+		c.keysMutex.RUnlock()
+		if !ok {
+			return nil, errUnknownSigningKey
+		}
+	}
+	switch key.(type) {
+	case *rsa.PublicKey:
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); ok {
+			return key, nil
+		}
+	case *ecdsa.PublicKey:
+		if _, ok := token.Method.(*jwt.SigningMethodECDSA); ok {
+			return key, nil
+		}
+	}
+	return nil, errUnknownSigningKey
+}
+
+func (c *Client) fetchKeys() error {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := c.getJSON(c.jwksUri, &set); err != nil {
+		return err
+	}
+	keys := make(map[string]interface{})
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Keys of a type we can't verify with are skipped, not an error
+		if key, err := parseJWK(jwk); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	c.keysMutex.Lock()
+	c.keys = keys
+	c.keysMutex.Unlock()
+	return nil
+}
+
+func parseJWK(jwk jsonWebKey) (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.New("Unsupported curve " + jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, errors.New("Unsupported key type " + jwk.Kty)
+}
@@ -0,0 +1,92 @@
+// Package oidc signs users in with an external OpenID Connect provider, using
+// the authorization code flow with PKCE (RFC 7636). Each configured provider
+// is registered by name, the sign in handlers look it up from the URL.
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"sync"
+)
+
+var ErrUnknownProvider = errors.New("Unknown identity provider")
+
+// Identity is who the provider says signed in. Subject is the provider's
+// stable id for the user, the email address can change.
+type Identity struct {
+	Subject       string
+	EmailAddress  string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+}
+
+// Provider is implemented by each identity provider
+type Provider interface {
+	// AuthCodeURL is where the browser is sent to sign in, the provider
+	// redirects back with a code and the state
+	AuthCodeURL(state string, nonce string, codeChallenge string) string
+	// Exchange swaps the code for the identity that signed in, checking the
+	// ID token was issued for the nonce
+	Exchange(code string, codeVerifier string, nonce string) (*Identity, error)
+}
+
+var (
+	providersMutex sync.RWMutex
+	providers      = make(map[string]Provider)
+)
+
+// Register makes a provider available by name
+func Register(name string, provider Provider) {
+	providersMutex.Lock()
+	defer providersMutex.Unlock()
+	if provider == nil {
+		panic("oidc: Register provider is nil")
+	}
+	if _, dup := providers[name]; dup {
+		panic("oidc: Register called twice for provider " + name)
+	}
+	providers[name] = provider
+}
+
+// Lookup returns the provider registered as name
+func Lookup(name string) (Provider, error) {
+	providersMutex.RLock()
+	defer providersMutex.RUnlock()
+	provider, ok := providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
+
+// Providers returns the sorted names of the registered providers
+func Providers() []string {
+	providersMutex.RLock()
+	defer providersMutex.RUnlock()
+	list := []string{}
+	for name := range providers {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// RandomString is an unguessable url safe string, for the state, nonce and
+// code verifier
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge is the S256 PKCE challenge for the code verifier
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,114 @@
+package oidc
+
+import (
+	"encoding/json"
+	"mimpidev/sinkrontrack-server/internal/oidc/oidctest"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// signIn runs the code flow against the stub, returning the code the browser
+// is redirected back with
+func signIn(t *testing.T, client *Client, state string, nonce string, verifier string) string {
+	noRedirect := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	response, err := noRedirect.Get(client.AuthCodeURL(state, nonce, CodeChallenge(verifier)))
+	if err != nil {
+		t.Fatalf("Want no error, got '%s'", err.Error())
+	}
+	location, err := url.Parse(response.Header.Get("Location"))
+	if response.StatusCode != http.StatusFound || err != nil {
+		t.Fatalf("Want a redirect back, got '%d'", response.StatusCode)
+	}
+	if location.Query().Get("state") != state {
+		t.Errorf("Want state '%s', got '%s'", state, location.Query().Get("state"))
+	}
+	return location.Query().Get("code")
+}
+
+// mustIdToken exchanges the code without verifying the ID token
+func mustIdToken(t *testing.T, client *Client, code string) string {
+	values := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {client.config.RedirectUrl},
+		"client_id":     {client.config.ClientId},
+		"code_verifier": {"verifier"},
+	}
+	response, err := http.PostForm(client.tokenEndpoint, values)
+	if err != nil {
+		t.Fatalf("Want no error, got '%s'", err.Error())
+	}
+	defer response.Body.Close()
+	var tokens tokenResponse
+	json.NewDecoder(response.Body).Decode(&tokens)
+	return tokens.IdToken
+}
+
+func TestClient(t *testing.T) {
+	server, err := oidctest.NewServer("sinkrontrack")
+	if err != nil {
+		t.Fatalf("Want no error, got '%s'", err.Error())
+	}
+	defer server.Close()
+	client, err := Discover(Config{Issuer: server.URL, ClientId: "sinkrontrack", RedirectUrl: "http://localhost/callback"})
+	if err != nil {
+		t.Fatalf("Want no error, got '%s'", err.Error())
+	}
+
+	t.Run("A code is exchanged for the identity", func(t *testing.T) {
+		code := signIn(t, client, "state", "nonce", "verifier")
+		identity, err := client.Exchange(code, "verifier", "nonce")
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if identity.Subject != server.Subject || identity.EmailAddress != server.EmailAddress || !identity.EmailVerified {
+			t.Errorf("Want the stub user, got '%+v'", identity)
+		}
+	})
+	t.Run("A code only works once", func(t *testing.T) {
+		code := signIn(t, client, "state", "nonce", "verifier")
+		client.Exchange(code, "verifier", "nonce")
+		if _, err := client.Exchange(code, "verifier", "nonce"); err == nil {
+			t.Error("Want an error exchanging the code again")
+		}
+	})
+	t.Run("The wrong verifier", func(t *testing.T) {
+		code := signIn(t, client, "state", "nonce", "verifier")
+		if _, err := client.Exchange(code, "someone else's", "nonce"); err == nil {
+			t.Error("Want an error for the wrong verifier")
+		}
+	})
+	t.Run("The wrong nonce", func(t *testing.T) {
+		code := signIn(t, client, "state", "nonce", "verifier")
+		if _, err := client.Exchange(code, "verifier", "replayed"); err != errInvalidIdToken {
+			t.Errorf("Want error '%v', got '%v'", errInvalidIdToken, err)
+		}
+	})
+	t.Run("Issued for another client", func(t *testing.T) {
+		code := signIn(t, client, "state", "nonce", "verifier")
+		identity, err := client.verify(mustIdToken(t, client, code), "nonce")
+		if err != nil || identity == nil {
+			t.Fatalf("Want the identity for the right client, got '%v'", err)
+		}
+		other := &Client{config: client.config, keys: client.keys}
+		other.config.ClientId = "other"
+		code = signIn(t, client, "state", "nonce", "verifier")
+		if _, err := other.verify(mustIdToken(t, client, code), "nonce"); err != errInvalidIdToken {
+			t.Errorf("Want error '%v', got '%v'", errInvalidIdToken, err)
+		}
+	})
+}
+
+func TestDiscoverWrongIssuer(t *testing.T) {
+	server, err := oidctest.NewServer("sinkrontrack")
+	if err != nil {
+		t.Fatalf("Want no error, got '%s'", err.Error())
+	}
+	defer server.Close()
+	if _, err := Discover(Config{Issuer: server.URL + "/", ClientId: "sinkrontrack"}); err == nil {
+		t.Error("Want an error when the issuer doesn't match")
+	}
+}
@@ -0,0 +1,183 @@
+// Package oidctest is a stub OpenID Connect provider for tests and local
+// development. Its authorization endpoint signs in straight away as the one
+// configured user, so the whole code flow runs without a browser.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const keyId = "oidctest"
+
+// IdP is the stub provider, the user it signs in as can be changed between
+// sign ins
+type IdP struct {
+	Issuer        string
+	ClientId      string
+	Subject       string
+	EmailAddress  string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+
+	key    *rsa.PrivateKey
+	mutex  sync.Mutex
+	grants map[string]*grant
+}
+
+// grant is an authorization code that hasn't been exchanged yet
+type grant struct {
+	redirectUri   string
+	nonce         string
+	codeChallenge string
+}
+
+// Server is an IdP listening on a local port
+type Server struct {
+	*IdP
+	*httptest.Server
+}
+
+// NewIdP makes a stub provider for the issuer URL it is served from
+func NewIdP(issuer string, clientId string) (*IdP, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &IdP{
+		Issuer:        issuer,
+		ClientId:      clientId,
+		Subject:       "oidctest-subject",
+		EmailAddress:  "oidc@test.com",
+		EmailVerified: true,
+		FirstName:     "Open",
+		LastName:      "Id",
+		key:           key,
+		grants:        make(map[string]*grant),
+	}, nil
+}
+
+// NewServer starts a stub provider on a local port, close it when done
+func NewServer(clientId string) (*Server, error) {
+	idp, err := NewIdP("", clientId)
+	if err != nil {
+		return nil, err
+	}
+	server := httptest.NewServer(idp)
+	idp.Issuer = server.URL
+	return &Server{IdP: idp, Server: server}, nil
+}
+
+func (idp *IdP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.Issuer,
+			"authorization_endpoint": idp.Issuer + "/authorize",
+			"token_endpoint":         idp.Issuer + "/token",
+			"jwks_uri":               idp.Issuer + "/jwks",
+		})
+	case "/authorize":
+		idp.authorize(w, r)
+	case "/token":
+		idp.token(w, r)
+	case "/jwks":
+		json.NewEncoder(w).Encode(map[string][]map[string]string{"keys": {{
+			"kty": "RSA",
+			"kid": keyId,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}}})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// authorize signs in without asking and redirects back with a code
+func (idp *IdP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectUri, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("redirect_uri") == "" || query.Get("client_id") != idp.ClientId ||
+		query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	code := hex.EncodeToString(b)
+	idp.mutex.Lock()
+	idp.grants[code] = &grant{
+		redirectUri:   query.Get("redirect_uri"),
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+	}
+	idp.mutex.Unlock()
+
+	values := redirectUri.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirectUri.RawQuery = values.Encode()
+	http.Redirect(w, r, redirectUri.String(), http.StatusFound)
+}
+
+// token exchanges a code once, for the verifier of its challenge
+func (idp *IdP) token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != "POST" || r.ParseForm() != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_request"})
+		return
+	}
+	code := r.PostForm.Get("code")
+	idp.mutex.Lock()
+	g := idp.grants[code]
+	delete(idp.grants, code)
+	idp.mutex.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if g == nil || g.redirectUri != r.PostForm.Get("redirect_uri") || r.PostForm.Get("client_id") != idp.ClientId ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != g.codeChallenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            idp.Issuer,
+		"sub":            idp.Subject,
+		"aud":            []string{idp.ClientId},
+		"exp":            time.Now().Add(5 * time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          g.nonce,
+		"email":          idp.EmailAddress,
+		"email_verified": idp.EmailVerified,
+		"given_name":     idp.FirstName,
+		"family_name":    idp.LastName,
+	})
+	token.Header["kid"] = keyId
+	idToken, err := token.SignedString(idp.key)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": idToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
@@ -0,0 +1,1264 @@
+// Package memory is a storage driver that keeps everything in process memory.
+// Nothing survives a restart, so it is meant for tests and small deployments.
+package memory
+
+import (
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+func init() {
+	storage.Register("memory", func(dataSource string) (storage.DataStorage, error) {
+		return New(), nil
+	})
+}
+
+// Storage holds each entity by id, with the to-many relations kept as id lists
+// the same way objectbox stores them.
+type Storage struct {
+	mutex sync.RWMutex
+
+	lastUserId     uint64
+	lastPlaylistId uint64
+	lastTrackId    uint64
+	lastFriendId   uint64
+	lastDeviceId   uint64
+	lastResumeId   uint64
+	lastShareId    uint64
+	lastGroupId    uint64
+	lastLoginId    uint64
+	lastApiKeyId   uint64
+	lastRevokedId  uint64
+
+	users     map[uint64]*storage.User
+	playlists map[uint64]*storage.Playlist
+	tracks    map[uint64]*storage.Track
+	friends   map[uint64]*storage.Friend
+	devices   map[uint64]*storage.Device
+	resume    map[uint64]*storage.ResumePosition
+	shares    map[uint64]*storage.PlaylistShare
+	groups    map[uint64]*storage.Group
+	logins    map[uint64]*storage.ExternalLogin
+	apiKeys   map[uint64]*storage.ApiKey
+	plays     []*storage.Play                  // append only, a play's id is its index + 1
+	revoked   map[string]*storage.RevokedToken // by token id
+
+	userPlaylists  map[uint64][]uint64
+	userTracks     map[uint64][]uint64
+	userFriends    map[uint64][]uint64
+	userDevices    map[uint64][]uint64
+	playlistTracks map[uint64][]uint64
+}
+
+func New() *Storage {
+	return &Storage{
+		users:          make(map[uint64]*storage.User),
+		playlists:      make(map[uint64]*storage.Playlist),
+		tracks:         make(map[uint64]*storage.Track),
+		friends:        make(map[uint64]*storage.Friend),
+		devices:        make(map[uint64]*storage.Device),
+		resume:         make(map[uint64]*storage.ResumePosition),
+		shares:         make(map[uint64]*storage.PlaylistShare),
+		groups:         make(map[uint64]*storage.Group),
+		logins:         make(map[uint64]*storage.ExternalLogin),
+		apiKeys:        make(map[uint64]*storage.ApiKey),
+		revoked:        make(map[string]*storage.RevokedToken),
+		userPlaylists:  make(map[uint64][]uint64),
+		userTracks:     make(map[uint64][]uint64),
+		userFriends:    make(map[uint64][]uint64),
+		userDevices:    make(map[uint64][]uint64),
+		playlistTracks: make(map[uint64][]uint64),
+	}
+}
+
+func (s *Storage) Close() error {
+	return nil
+}
+
+// put* functions store the scalar fields of an entity and, like an objectbox
+// Put, any related entities. They must be called with the write lock held.
+
+func (s *Storage) putTrack(t *storage.Track) uint64 {
+	if t.Id == 0 {
+		s.lastTrackId++
+		t.Id = s.lastTrackId
+	}
+	stored := *t
+	s.tracks[t.Id] = &stored
+	return t.Id
+}
+
+func (s *Storage) putPlaylist(p *storage.Playlist) uint64 {
+	if p.Id == 0 {
+		s.lastPlaylistId++
+		p.Id = s.lastPlaylistId
+	}
+	var trackIds []uint64
+	for _, t := range p.Tracks {
+		trackIds = append(trackIds, s.putTrack(t))
+	}
+	stored := *p
+	stored.Tracks = nil
+	s.playlists[p.Id] = &stored
+	s.playlistTracks[p.Id] = trackIds
+	return p.Id
+}
+
+func (s *Storage) putFriend(f *storage.Friend) uint64 {
+	if f.Id == 0 {
+		s.lastFriendId++
+		f.Id = s.lastFriendId
+	}
+	stored := *f
+	s.friends[f.Id] = &stored
+	return f.Id
+}
+
+func (s *Storage) putDevice(d *storage.Device) uint64 {
+	if d.Id == 0 {
+		s.lastDeviceId++
+		d.Id = s.lastDeviceId
+	}
+	stored := &storage.Device{}
+	storage.DeepCopy(d, stored)
+	s.devices[d.Id] = stored
+	return d.Id
+}
+
+func (s *Storage) putUser(m *storage.User) uint64 {
+	if m.Id == 0 {
+		s.lastUserId++
+		m.Id = s.lastUserId
+	} else if m.Id > s.lastUserId {
+		// The admin user is inserted with an id of 1
+		s.lastUserId = m.Id
+	}
+	var playlistIds, trackIds, friendIds, deviceIds []uint64
+	for _, p := range m.Playlists {
+		playlistIds = append(playlistIds, s.putPlaylist(p))
+	}
+	for _, t := range m.Tracks {
+		trackIds = append(trackIds, s.putTrack(t))
+	}
+	for _, f := range m.Friends {
+		friendIds = append(friendIds, s.putFriend(f))
+	}
+	for _, d := range m.Devices {
+		deviceIds = append(deviceIds, s.putDevice(d))
+	}
+	s.putUserRow(m)
+	s.userPlaylists[m.Id] = playlistIds
+	s.userTracks[m.Id] = trackIds
+	s.userFriends[m.Id] = friendIds
+	s.userDevices[m.Id] = deviceIds
+	return m.Id
+}
+
+// putUserRow stores just the user's own fields, its relations are left as
+// they are stored
+func (s *Storage) putUserRow(m *storage.User) {
+	stored := *m
+	stored.Playlists = nil
+	stored.Tracks = nil
+	stored.Friends = nil
+	stored.Devices = nil
+	s.users[m.Id] = &stored
+}
+
+// load* functions return a copy of the entity with its relations populated,
+// they must be called with at least the read lock held.
+
+func (s *Storage) loadTrack(id uint64) *storage.Track {
+	stored, ok := s.tracks[id]
+	if !ok {
+		return nil
+	}
+	t := *stored
+	return &t
+}
+
+func (s *Storage) loadPlaylist(id uint64) *storage.Playlist {
+	stored, ok := s.playlists[id]
+	if !ok {
+		return nil
+	}
+	p := *stored
+	for _, trackId := range s.playlistTracks[id] {
+		if t := s.loadTrack(trackId); t != nil {
+			p.Tracks = append(p.Tracks, t)
+		}
+	}
+	return &p
+}
+
+func (s *Storage) loadUser(id uint64) *storage.User {
+	stored, ok := s.users[id]
+	if !ok {
+		return nil
+	}
+	m := *stored
+	for _, playlistId := range s.userPlaylists[id] {
+		if p := s.loadPlaylist(playlistId); p != nil {
+			m.Playlists = append(m.Playlists, p)
+		}
+	}
+	for _, trackId := range s.userTracks[id] {
+		if t := s.loadTrack(trackId); t != nil {
+			m.Tracks = append(m.Tracks, t)
+		}
+	}
+	for _, friendId := range s.userFriends[id] {
+		if f, ok := s.friends[friendId]; ok {
+			friend := *f
+			m.Friends = append(m.Friends, &friend)
+		}
+	}
+	for _, deviceId := range s.userDevices[id] {
+		if d, ok := s.devices[deviceId]; ok {
+			device := *d
+			m.Devices = append(m.Devices, &device)
+		}
+	}
+	return &m
+}
+
+func (s *Storage) findUserId(m *storage.User) uint64 {
+	if m.Id != 0 {
+		if _, ok := s.users[m.Id]; ok {
+			return m.Id
+		}
+		return 0
+	}
+	for id, user := range s.users {
+		if (m.Uuid != "" && user.Uuid == m.Uuid) ||
+			(m.Uuid == "" && m.EmailAddress != "" && strings.EqualFold(user.EmailAddress, m.EmailAddress)) {
+			return id
+		}
+	}
+	return 0
+}
+
+func (s *Storage) ownsPlaylist(userId uint64, playlistUuid string) bool {
+	for _, id := range s.userPlaylists[userId] {
+		if playlist, ok := s.playlists[id]; ok && playlist.Uuid == playlistUuid {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Storage) findPlaylistId(p *storage.Playlist) uint64 {
+	if p.Id != 0 {
+		if _, ok := s.playlists[p.Id]; ok {
+			return p.Id
+		}
+		return 0
+	}
+	for id, playlist := range s.playlists {
+		if p.Uuid != "" && playlist.Uuid == p.Uuid {
+			return id
+		}
+	}
+	return 0
+}
+
+func (s *Storage) findTrackId(t *storage.Track) uint64 {
+	if t.Id != 0 {
+		if _, ok := s.tracks[t.Id]; ok {
+			return t.Id
+		}
+		return 0
+	}
+	for id, track := range s.tracks {
+		if t.Uuid != "" && track.Uuid == t.Uuid {
+			return id
+		}
+	}
+	return 0
+}
+
+func containsId(ids []uint64, id uint64) bool {
+	for _, value := range ids {
+		if value == id {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTrack(tracks []*storage.Track, trackUuid string) bool {
+	for _, track := range tracks {
+		if track.Uuid == trackUuid {
+			return true
+		}
+	}
+	return false
+}
+
+func removeId(ids []uint64, id uint64) []uint64 {
+	var result []uint64
+	for _, value := range ids {
+		if value != id {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func (s *Storage) InsertUser(m *storage.User) (*uint64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, user := range s.users {
+		if strings.EqualFold(user.EmailAddress, m.EmailAddress) {
+			return nil, storage.ErrUserExists
+		}
+	}
+	m.Uuid = uuid.NewString()
+	id := s.putUser(m)
+	return &id, nil
+}
+
+func (s *Storage) UpdateUser(m *storage.User) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if m.Id == 0 {
+		return storage.ErrMissingId
+	}
+	if _, ok := s.users[m.Id]; !ok {
+		return storage.ErrUserNotFound
+	}
+	s.putUserRow(m)
+	*m = *s.loadUser(m.Id)
+	return nil
+}
+
+func (s *Storage) UseUserTotpStep(m *storage.User, expectedStep int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored, ok := s.users[m.Id]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+	if stored.TotpLastStep != expectedStep {
+		return storage.ErrSecondFactorUsed
+	}
+	stored.TotpLastStep = m.TotpLastStep
+	return nil
+}
+
+func (s *Storage) UseUserRecoveryCode(m *storage.User, hash string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored, ok := s.users[m.Id]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+	for i, code := range stored.TotpRecoveryCodes {
+		if code == hash {
+			stored.TotpRecoveryCodes = append(stored.TotpRecoveryCodes[:i:i], stored.TotpRecoveryCodes[i+1:]...)
+			m.TotpRecoveryCodes = append([]string(nil), stored.TotpRecoveryCodes...)
+			return nil
+		}
+	}
+	return storage.ErrSecondFactorUsed
+}
+
+func (s *Storage) DeleteUser(m *storage.User) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.users[m.Id]; !ok {
+		return storage.ErrUserNotFound
+	}
+	for _, group := range s.groups {
+		userUuid := s.users[m.Id].Uuid
+		if group.Member(userUuid) != nil || group.Invite(userUuid) != nil {
+			group.Members = removeMember(group.Members, userUuid)
+			group.Invites = removeInvite(group.Invites, userUuid)
+			group.Revision++
+		}
+	}
+	for id, login := range s.logins {
+		if login.UserUuid == s.users[m.Id].Uuid {
+			delete(s.logins, id)
+		}
+	}
+	for id, key := range s.apiKeys {
+		if key.UserUuid == s.users[m.Id].Uuid {
+			delete(s.apiKeys, id)
+		}
+	}
+	delete(s.users, m.Id)
+	delete(s.userPlaylists, m.Id)
+	delete(s.userTracks, m.Id)
+	delete(s.userFriends, m.Id)
+	delete(s.userDevices, m.Id)
+	return nil
+}
+
+func (s *Storage) SelectUser(m *storage.User) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if m.Id == 0 && m.Uuid == "" && m.EmailAddress == "" {
+		return storage.ErrMissingId
+	}
+	id := s.findUserId(m)
+	if id == 0 {
+		return storage.ErrUserNotFound
+	}
+	*m = *s.loadUser(id)
+	return nil
+}
+
+func (s *Storage) UserExists(m *storage.User) (bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if m.Id == 0 && m.Uuid == "" && m.EmailAddress == "" {
+		return false, storage.ErrMissingId
+	}
+	return s.findUserId(m) != 0, nil
+}
+
+func (s *Storage) FindUsers(filter storage.UserFilter) ([]*storage.User, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var users []*storage.User
+	for id := uint64(1); id <= s.lastUserId; id++ {
+		user, ok := s.users[id]
+		if !ok ||
+			(filter.Uuid != "" && user.Uuid != filter.Uuid) ||
+			(filter.EmailAddress != "" && !strings.EqualFold(user.EmailAddress, filter.EmailAddress)) ||
+			(filter.Role != "" && !user.HasRole(filter.Role)) ||
+			(filter.PlaylistUuid != "" && !s.ownsPlaylist(id, filter.PlaylistUuid)) {
+			continue
+		}
+		users = append(users, s.loadUser(id))
+	}
+	return users, nil
+}
+
+func (s *Storage) UserAddPlaylist(m *storage.User, p *storage.Playlist) (*uint64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.users[m.Id]; !ok {
+		return nil, storage.ErrUserNotFound
+	}
+	p.Uuid = uuid.NewString()
+	s.userPlaylists[m.Id] = append(s.userPlaylists[m.Id], s.putPlaylist(p))
+	m.Playlists = append(m.Playlists, p)
+	return &m.Id, nil
+}
+
+func (s *Storage) UpdatePlaylist(p *storage.Playlist) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if p.Id == 0 {
+		return storage.ErrMissingId
+	}
+	s.putPlaylist(p)
+	return nil
+}
+
+func (s *Storage) UpdatePlaylistAtRevision(p *storage.Playlist, revision uint64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored, ok := s.playlists[p.Id]
+	if !ok {
+		return storage.ErrPlaylistNotFound
+	}
+	if stored.PositionRevision != revision {
+		return storage.ErrRevisionConflict
+	}
+	stored.Name = p.Name
+	stored.CurrentTrackUuid = p.CurrentTrackUuid
+	stored.Elapsed = p.Elapsed
+	stored.PositionRevision = p.PositionRevision
+	stored.PositionUpdatedAt = p.PositionUpdatedAt
+	*p = *s.loadPlaylist(p.Id)
+	return nil
+}
+
+func (s *Storage) UpdatePlaylistLock(p *storage.Playlist, heldBy string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored, ok := s.playlists[p.Id]
+	if !ok {
+		return storage.ErrPlaylistNotFound
+	}
+	if stored.LockDeviceUuid != heldBy {
+		return storage.ErrLockConflict
+	}
+	stored.LockDeviceUuid = p.LockDeviceUuid
+	stored.ClientLockExpires = p.ClientLockExpires
+	return nil
+}
+
+func (s *Storage) ReorderPlaylistTracks(p *storage.Playlist, trackUuids []string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored := s.loadPlaylist(p.Id)
+	if stored == nil {
+		return storage.ErrPlaylistNotFound
+	}
+	tracks, err := storage.OrderTracks(stored.Tracks, trackUuids)
+	if err != nil {
+		return err
+	}
+	var trackIds []uint64
+	for _, t := range tracks {
+		trackIds = append(trackIds, t.Id)
+	}
+	s.playlistTracks[p.Id] = trackIds
+	p.Tracks = tracks
+	return nil
+}
+
+func (s *Storage) DeletePlaylist(p *storage.Playlist) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.playlists[p.Id]; !ok {
+		return storage.ErrPlaylistNotFound
+	}
+	playlistUuid := s.playlists[p.Id].Uuid
+	delete(s.playlists, p.Id)
+	delete(s.playlistTracks, p.Id)
+	for userId, playlistIds := range s.userPlaylists {
+		s.userPlaylists[userId] = removeId(playlistIds, p.Id)
+	}
+	for id, share := range s.shares {
+		if share.PlaylistUuid == playlistUuid {
+			delete(s.shares, id)
+		}
+	}
+	return nil
+}
+
+func (s *Storage) SelectPlaylist(p *storage.Playlist) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if p.Id == 0 && p.Uuid == "" {
+		return storage.ErrMissingId
+	}
+	id := s.findPlaylistId(p)
+	if id == 0 {
+		return storage.ErrPlaylistNotFound
+	}
+	*p = *s.loadPlaylist(id)
+	return nil
+}
+
+func (s *Storage) PlaylistExists(p *storage.Playlist) (bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if p.Id == 0 && p.Uuid == "" {
+		return false, storage.ErrMissingId
+	}
+	return s.findPlaylistId(p) != 0, nil
+}
+
+func (s *Storage) FindPlaylists(filter storage.PlaylistFilter) ([]*storage.Playlist, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var candidates []uint64
+	if filter.OwnerEmail != "" {
+		for userId, user := range s.users {
+			if strings.EqualFold(user.EmailAddress, filter.OwnerEmail) {
+				candidates = append(candidates, s.userPlaylists[userId]...)
+			}
+		}
+	} else {
+		for id := uint64(1); id <= s.lastPlaylistId; id++ {
+			candidates = append(candidates, id)
+		}
+	}
+
+	var playlists []*storage.Playlist
+	for _, id := range candidates {
+		playlist, ok := s.playlists[id]
+		if !ok ||
+			(filter.Uuid != "" && playlist.Uuid != filter.Uuid) ||
+			(filter.GroupUuid != "" && playlist.GroupUuid != filter.GroupUuid) ||
+			(filter.LockDeviceUuid != "" && playlist.LockDeviceUuid != filter.LockDeviceUuid) {
+			continue
+		}
+		loaded := s.loadPlaylist(id)
+		if filter.TrackUuid != "" && !hasTrack(loaded.Tracks, filter.TrackUuid) {
+			continue
+		}
+		playlists = append(playlists, loaded)
+	}
+	return playlists, nil
+}
+
+func (s *Storage) UserAddTrack(m *storage.User, t *storage.Track) (*uint64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.users[m.Id]; !ok {
+		return nil, storage.ErrUserNotFound
+	}
+	t.Uuid = uuid.NewString()
+	s.userTracks[m.Id] = append(s.userTracks[m.Id], s.putTrack(t))
+	m.Tracks = append(m.Tracks, t)
+	return &t.Id, nil
+}
+
+func (s *Storage) PlaylistAddTrack(p *storage.Playlist, t *storage.Track) (*uint64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.playlists[p.Id]; !ok {
+		return nil, storage.ErrPlaylistNotFound
+	}
+	if t.Uuid == "" {
+		t.Uuid = uuid.NewString()
+	}
+	if t.Id == 0 {
+		s.putTrack(t)
+	}
+	if !containsId(s.playlistTracks[p.Id], t.Id) {
+		s.playlistTracks[p.Id] = append(s.playlistTracks[p.Id], t.Id)
+	}
+	*p = *s.loadPlaylist(p.Id)
+	return &p.Id, nil
+}
+
+func (s *Storage) PlaylistRemoveTrack(p *storage.Playlist, t *storage.Track) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	trackIds := s.playlistTracks[p.Id]
+	remaining := removeId(trackIds, t.Id)
+	if len(remaining) == len(trackIds) {
+		return storage.ErrTrackNotFound
+	}
+	s.playlistTracks[p.Id] = remaining
+	*p = *s.loadPlaylist(p.Id)
+	return nil
+}
+
+func (s *Storage) UpdateTrack(t *storage.Track) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if t.Id == 0 {
+		return storage.ErrMissingId
+	}
+	s.putTrack(t)
+	return nil
+}
+
+func (s *Storage) DeleteTrack(t *storage.Track) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.tracks[t.Id]; !ok {
+		return storage.ErrTrackNotFound
+	}
+	delete(s.tracks, t.Id)
+	for playlistId, trackIds := range s.playlistTracks {
+		s.playlistTracks[playlistId] = removeId(trackIds, t.Id)
+	}
+	for userId, trackIds := range s.userTracks {
+		s.userTracks[userId] = removeId(trackIds, t.Id)
+	}
+	return nil
+}
+
+func (s *Storage) SelectTrack(t *storage.Track) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if t.Id == 0 && t.Uuid == "" {
+		return storage.ErrMissingId
+	}
+	id := s.findTrackId(t)
+	if id == 0 {
+		return storage.ErrTrackNotFound
+	}
+	*t = *s.loadTrack(id)
+	return nil
+}
+
+func (s *Storage) FindTracks(filter storage.TrackFilter) ([]*storage.Track, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var candidates []uint64
+	if filter.OwnerEmail != "" {
+		for userId, user := range s.users {
+			if !strings.EqualFold(user.EmailAddress, filter.OwnerEmail) {
+				continue
+			}
+			candidates = append(candidates, s.userTracks[userId]...)
+			for _, playlistId := range s.userPlaylists[userId] {
+				candidates = append(candidates, s.playlistTracks[playlistId]...)
+			}
+		}
+	} else {
+		for id := uint64(1); id <= s.lastTrackId; id++ {
+			candidates = append(candidates, id)
+		}
+	}
+
+	var tracks []*storage.Track
+	seen := make(map[uint64]bool)
+	for _, id := range candidates {
+		track, ok := s.tracks[id]
+		if !ok || seen[id] || !filter.Matches(track) {
+			continue
+		}
+		seen[id] = true
+		tracks = append(tracks, s.loadTrack(id))
+	}
+	return tracks, nil
+}
+
+func (s *Storage) UserAddFriend(m *storage.User, f *storage.Friend) (*uint64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.users[m.Id]; !ok {
+		return nil, storage.ErrUserNotFound
+	}
+	s.userFriends[m.Id] = append(s.userFriends[m.Id], s.putFriend(f))
+	m.Friends = append(m.Friends, f)
+	return &f.Id, nil
+}
+
+func (s *Storage) UpdateFriend(f *storage.Friend) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.friends[f.Id]; !ok {
+		return storage.ErrFriendNotFound
+	}
+	s.putFriend(f)
+	return nil
+}
+
+func (s *Storage) DeleteFriend(f *storage.Friend) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.friends[f.Id]; !ok {
+		return storage.ErrFriendNotFound
+	}
+	delete(s.friends, f.Id)
+	for userId, friendIds := range s.userFriends {
+		s.userFriends[userId] = removeId(friendIds, f.Id)
+	}
+	return nil
+}
+
+func (s *Storage) SavePlaylistShare(share *storage.PlaylistShare) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	share.Id = 0
+	for id, stored := range s.shares {
+		if stored.PlaylistUuid == share.PlaylistUuid && stored.UserUuid == share.UserUuid {
+			share.Id = id
+			break
+		}
+	}
+	if share.Id == 0 {
+		s.lastShareId++
+		share.Id = s.lastShareId
+	}
+	stored := *share
+	s.shares[share.Id] = &stored
+	return nil
+}
+
+func (s *Storage) DeletePlaylistShare(share *storage.PlaylistShare) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.shares[share.Id]; !ok {
+		return storage.ErrShareNotFound
+	}
+	delete(s.shares, share.Id)
+	return nil
+}
+
+func (s *Storage) FindPlaylistShares(filter storage.PlaylistShareFilter) ([]*storage.PlaylistShare, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	shares := []*storage.PlaylistShare{}
+	for _, share := range s.shares {
+		if (filter.PlaylistUuid != "" && share.PlaylistUuid != filter.PlaylistUuid) ||
+			(filter.OwnerUuid != "" && share.OwnerUuid != filter.OwnerUuid) ||
+			(filter.UserUuid != "" && share.UserUuid != filter.UserUuid) {
+			continue
+		}
+		found := *share
+		shares = append(shares, &found)
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		return shares[i].Id < shares[j].Id
+	})
+	return shares, nil
+}
+
+func removeMember(members []*storage.GroupMember, userUuid string) []*storage.GroupMember {
+	var kept []*storage.GroupMember
+	for _, member := range members {
+		if member.UserUuid != userUuid {
+			kept = append(kept, member)
+		}
+	}
+	return kept
+}
+
+func removeInvite(invites []*storage.GroupInvite, userUuid string) []*storage.GroupInvite {
+	var kept []*storage.GroupInvite
+	for _, invite := range invites {
+		if invite.UserUuid != userUuid {
+			kept = append(kept, invite)
+		}
+	}
+	return kept
+}
+
+func (s *Storage) SaveGroup(g *storage.Group) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if g.Id != 0 {
+		stored, ok := s.groups[g.Id]
+		if !ok {
+			return storage.ErrGroupNotFound
+		}
+		if stored.Revision != g.Revision {
+			return storage.ErrGroupConflict
+		}
+	}
+	for id, group := range s.groups {
+		if id == g.Id {
+			continue
+		}
+		for _, member := range g.Members {
+			if group.Member(member.UserUuid) != nil {
+				return storage.ErrAlreadyInGroup
+			}
+		}
+	}
+	if g.Id == 0 {
+		s.lastGroupId++
+		g.Id = s.lastGroupId
+		g.Uuid = uuid.NewString()
+		g.Revision = 0
+	} else {
+		g.Revision++
+	}
+	stored := &storage.Group{}
+	storage.DeepCopy(g, stored)
+	s.groups[g.Id] = stored
+	return nil
+}
+
+func (s *Storage) DeleteGroup(g *storage.Group) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	group, ok := s.groups[g.Id]
+	if !ok {
+		return storage.ErrGroupNotFound
+	}
+	if group.Revision != g.Revision {
+		return storage.ErrGroupConflict
+	}
+	for _, playlist := range s.playlists {
+		if playlist.GroupUuid == group.Uuid {
+			playlist.GroupUuid = ""
+		}
+	}
+	delete(s.groups, g.Id)
+	return nil
+}
+
+func (s *Storage) FindGroups(filter storage.GroupFilter) ([]*storage.Group, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	groups := []*storage.Group{}
+	for _, group := range s.groups {
+		if (filter.Uuid != "" && group.Uuid != filter.Uuid) ||
+			(filter.MemberUuid != "" && group.Member(filter.MemberUuid) == nil) ||
+			(filter.InviteeUuid != "" && group.Invite(filter.InviteeUuid) == nil) {
+			continue
+		}
+		found := &storage.Group{}
+		storage.DeepCopy(group, found)
+		groups = append(groups, found)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Id < groups[j].Id
+	})
+	return groups, nil
+}
+
+func (s *Storage) InsertExternalLogin(l *storage.ExternalLogin) (*uint64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, stored := range s.logins {
+		if stored.Provider == l.Provider && (stored.Subject == l.Subject || stored.UserUuid == l.UserUuid) {
+			return nil, storage.ErrLoginExists
+		}
+	}
+	s.lastLoginId++
+	l.Id = s.lastLoginId
+	stored := *l
+	s.logins[l.Id] = &stored
+	return &l.Id, nil
+}
+
+func (s *Storage) DeleteExternalLogin(l *storage.ExternalLogin) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.logins[l.Id]; !ok {
+		return storage.ErrLoginNotFound
+	}
+	delete(s.logins, l.Id)
+	return nil
+}
+
+func (s *Storage) FindExternalLogins(filter storage.ExternalLoginFilter) ([]*storage.ExternalLogin, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	logins := []*storage.ExternalLogin{}
+	for _, login := range s.logins {
+		if (filter.Provider != "" && login.Provider != filter.Provider) ||
+			(filter.Subject != "" && login.Subject != filter.Subject) ||
+			(filter.UserUuid != "" && login.UserUuid != filter.UserUuid) {
+			continue
+		}
+		found := *login
+		logins = append(logins, &found)
+	}
+	sort.Slice(logins, func(i, j int) bool {
+		return logins[i].Id < logins[j].Id
+	})
+	return logins, nil
+}
+
+func (s *Storage) InsertApiKey(k *storage.ApiKey) (*uint64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.lastApiKeyId++
+	k.Id = s.lastApiKeyId
+	k.Uuid = uuid.NewString()
+	stored := &storage.ApiKey{}
+	storage.DeepCopy(k, stored)
+	s.apiKeys[k.Id] = stored
+	return &k.Id, nil
+}
+
+func (s *Storage) UpdateApiKey(k *storage.ApiKey) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.apiKeys[k.Id]; !ok {
+		return storage.ErrApiKeyNotFound
+	}
+	stored := &storage.ApiKey{}
+	storage.DeepCopy(k, stored)
+	s.apiKeys[k.Id] = stored
+	return nil
+}
+
+func (s *Storage) DeleteApiKey(k *storage.ApiKey) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.apiKeys[k.Id]; !ok {
+		return storage.ErrApiKeyNotFound
+	}
+	delete(s.apiKeys, k.Id)
+	return nil
+}
+
+func (s *Storage) FindApiKeys(filter storage.ApiKeyFilter) ([]*storage.ApiKey, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	keys := []*storage.ApiKey{}
+	for _, key := range s.apiKeys {
+		if (filter.Uuid != "" && key.Uuid != filter.Uuid) ||
+			(filter.UserUuid != "" && key.UserUuid != filter.UserUuid) {
+			continue
+		}
+		found := &storage.ApiKey{}
+		storage.DeepCopy(key, found)
+		keys = append(keys, found)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Id < keys[j].Id
+	})
+	return keys, nil
+}
+
+func (s *Storage) UserAddDevice(m *storage.User, d *storage.Device) (*uint64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.users[m.Id]; !ok {
+		return nil, storage.ErrUserNotFound
+	}
+	d.Uuid = uuid.NewString()
+	s.userDevices[m.Id] = append(s.userDevices[m.Id], s.putDevice(d))
+	m.Devices = append(m.Devices, d)
+	return &d.Id, nil
+}
+
+func (s *Storage) UpdateDevice(d *storage.Device) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if d.Id == 0 {
+		return storage.ErrMissingId
+	}
+	s.putDevice(d)
+	return nil
+}
+
+func (s *Storage) UpdateDeviceDetails(d *storage.Device) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored, ok := s.devices[d.Id]
+	if !ok {
+		return storage.ErrDeviceNotFound
+	}
+	stored.Name = d.Name
+	stored.LastSeen = d.LastSeen
+	return nil
+}
+
+func (s *Storage) RotateDeviceRefreshToken(d *storage.Device, expectedHash string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored, ok := s.devices[d.Id]
+	if !ok {
+		return storage.ErrDeviceNotFound
+	}
+	if stored.RefreshTokenHash != expectedHash {
+		return storage.ErrRefreshConflict
+	}
+	stored.LastSeen = d.LastSeen
+	stored.LastTokenId = d.LastTokenId
+	stored.RefreshTokenHash = d.RefreshTokenHash
+	stored.RefreshTokenExpires = d.RefreshTokenExpires
+	stored.RefreshTokenFamily = d.RefreshTokenFamily
+	stored.RefreshTokenGeneration = d.RefreshTokenGeneration
+	stored.Scopes = append([]string(nil), d.Scopes...)
+	return nil
+}
+
+func (s *Storage) DeleteDevice(d *storage.Device) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.devices[d.Id]; !ok {
+		return storage.ErrDeviceNotFound
+	}
+	delete(s.devices, d.Id)
+	for userId, deviceIds := range s.userDevices {
+		s.userDevices[userId] = removeId(deviceIds, d.Id)
+	}
+	return nil
+}
+
+func (s *Storage) SelectDevice(d *storage.Device) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if d.Id == 0 && d.Uuid == "" {
+		return storage.ErrMissingId
+	}
+	for id, device := range s.devices {
+		if (d.Id != 0 && id == d.Id) ||
+			(d.Id == 0 && device.Uuid == d.Uuid) {
+			*d = storage.Device{}
+			storage.DeepCopy(device, d)
+			return nil
+		}
+	}
+	return storage.ErrDeviceNotFound
+}
+
+func (s *Storage) FindDevices(filter storage.DeviceFilter) ([]*storage.Device, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var candidates []uint64
+	if filter.OwnerEmail != "" {
+		for userId, user := range s.users {
+			if strings.EqualFold(user.EmailAddress, filter.OwnerEmail) {
+				candidates = append(candidates, s.userDevices[userId]...)
+			}
+		}
+	} else {
+		for id := uint64(1); id <= s.lastDeviceId; id++ {
+			candidates = append(candidates, id)
+		}
+	}
+
+	var devices []*storage.Device
+	for _, id := range candidates {
+		device, ok := s.devices[id]
+		if !ok ||
+			(filter.Uuid != "" && device.Uuid != filter.Uuid) {
+			continue
+		}
+		d := &storage.Device{}
+		storage.DeepCopy(device, d)
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
+
+func (s *Storage) SaveResumePosition(r *storage.ResumePosition) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r.Id = 0
+	for id, stored := range s.resume {
+		if stored.UserUuid == r.UserUuid && stored.TrackUuid == r.TrackUuid {
+			r.Id = id
+			break
+		}
+	}
+	if r.Id == 0 {
+		s.lastResumeId++
+		r.Id = s.lastResumeId
+	}
+	stored := *r
+	s.resume[r.Id] = &stored
+	return nil
+}
+
+func (s *Storage) FindResumePositions(filter storage.ResumePositionFilter) ([]*storage.ResumePosition, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	positions := []*storage.ResumePosition{}
+	for _, position := range s.resume {
+		if (filter.UserUuid != "" && position.UserUuid != filter.UserUuid) ||
+			(filter.TrackUuid != "" && position.TrackUuid != filter.TrackUuid) {
+			continue
+		}
+		r := *position
+		positions = append(positions, &r)
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].UpdatedAt != positions[j].UpdatedAt {
+			return positions[i].UpdatedAt > positions[j].UpdatedAt
+		}
+		return positions[i].Id > positions[j].Id
+	})
+	if filter.Limit > 0 && len(positions) > filter.Limit {
+		positions = positions[:filter.Limit]
+	}
+	return positions, nil
+}
+
+func (s *Storage) AddPlay(p *storage.Play) (*uint64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p.Id = uint64(len(s.plays)) + 1
+	stored := *p
+	s.plays = append(s.plays, &stored)
+	return &p.Id, nil
+}
+
+func (s *Storage) FindPlays(filter storage.PlayFilter) ([]*storage.Play, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	plays := []*storage.Play{}
+	for _, play := range s.plays {
+		if (filter.UserUuid != "" && play.UserUuid != filter.UserUuid) ||
+			(filter.TrackUuid != "" && play.TrackUuid != filter.TrackUuid) ||
+			play.EndedAt < filter.Since {
+			continue
+		}
+		p := *play
+		plays = append(plays, &p)
+	}
+	sort.Slice(plays, func(i, j int) bool {
+		if plays[i].EndedAt != plays[j].EndedAt {
+			return plays[i].EndedAt > plays[j].EndedAt
+		}
+		return plays[i].Id > plays[j].Id
+	})
+	if filter.Limit > 0 && len(plays) > filter.Limit {
+		plays = plays[:filter.Limit]
+	}
+	return plays, nil
+}
+
+func (s *Storage) RevokeToken(t *storage.RevokedToken) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if stored, ok := s.revoked[t.TokenId]; ok {
+		t.Id = stored.Id
+		return nil
+	}
+	s.lastRevokedId++
+	t.Id = s.lastRevokedId
+	stored := *t
+	s.revoked[t.TokenId] = &stored
+	return nil
+}
+
+func (s *Storage) IsTokenRevoked(tokenId string) (bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	_, ok := s.revoked[tokenId]
+	return ok, nil
+}
+
+func (s *Storage) PruneRevokedTokens(before int64) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pruned := 0
+	for tokenId, t := range s.revoked {
+		if t.ExpiresAt < before {
+			delete(s.revoked, tokenId)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
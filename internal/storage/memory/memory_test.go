@@ -0,0 +1,13 @@
+package memory
+
+import (
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/storage/storagetest"
+	"testing"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.TestDataStorage(t, func(t *testing.T) storage.DataStorage {
+		return New()
+	})
+}
@@ -1,45 +1,234 @@
 package storage
 
-//go:generate go run github.com/objectbox/objectbox-go/cmd/objectbox-gogen
 type Track struct {
 	Id               uint64
-	Uuid             string `objectbox:"index:hash64"`
-	Path             string `objectbox:"index:hash64"`
-	ArtistName       string `objectbox:"index:hash64"`
-	SongName         string `objectbox:"index:hash64"`
-	AlbumName        string `objectbox:"index:hash64"`
+	Uuid             string
+	Path             string
+	ArtistName       string
+	SongName         string
+	AlbumName        string
 	AlbumTrackNumber int
 	TrackLength      int
+	ContentHash      string // optional, set by clients that hash the audio so moved files are still recognised
 }
 
 type Playlist struct {
 	Id                uint64
-	Uuid              string `objectbox:"index:hash64"`
+	Uuid              string
 	Name              string
-	CurrentTrackId    uint16
+	CurrentTrackUuid  string // Uuid of the track being played, its position is its index in Tracks
 	Elapsed           int
-	Tracks            []*Track
-	ClientIdLock      string
+	Tracks            []*Track // in playlist order
+	LockDeviceUuid    string   // Uuid of the Device holding the lock
 	ClientLockExpires int64
-	hashValue         string `objectbox:"-"`
+	PositionRevision  uint64 // bumped every time CurrentTrackUuid/Elapsed change
+	PositionUpdatedAt int64  // client timestamp (unix milliseconds) of the stored position
+	GroupUuid         string // household group the playlist belongs to, blank when it is only its owner's
 }
 
+// Friend is one side of a friendship, both users keep an entry for the other
+// one. FriendId is the other user's Uuid.
 type Friend struct {
-	Id       uint64
-	friendId string `objectbox:"index:hash64"`
+	Id        uint64
+	FriendId  string
+	Status    string // FriendRequestSent, FriendRequestReceived or FriendAccepted
+	UpdatedAt int64  // unix seconds
+}
+
+const (
+	FriendRequestSent     = "sent"     // waiting for the other user to answer
+	FriendRequestReceived = "received" // waiting for this user to answer
+	FriendAccepted        = "accepted"
+)
+
+// PlaylistShare grants a friend access to a playlist, there is one per
+// playlist and user
+type PlaylistShare struct {
+	Id           uint64
+	PlaylistUuid string
+	OwnerUuid    string // user who shared the playlist
+	UserUuid     string // friend it is shared with
+	Access       string // ShareRead or ShareCollaborate
+	CreatedAt    int64  // unix seconds
+}
+
+const (
+	ShareRead        = "read"        // can load the playlist and follow along
+	ShareCollaborate = "collaborate" // can also change the position, lock and tracks
+)
+
+// Group is a household sharing the server. Every member can see the playlists
+// that belong to it, and its managers look after the other members.
+type Group struct {
+	Id        uint64
+	Uuid      string
+	Name      string
+	CreatedAt int64 // unix seconds
+	Members   []*GroupMember
+	Invites   []*GroupInvite
+	Revision  uint64 // goes up with every save, so a stale copy of the group can't be saved
+}
+
+// GroupMember is a user in a group, a user can only be in one group
+type GroupMember struct {
+	UserUuid     string
+	Manager      bool
+	Restrictions []string // what the managers don't let the member do, RestrictEditPlaylists or RestrictDeletePlaylists
+}
+
+// GroupInvite asks a user to join the group, they become a member once they
+// accept it. Being invited doesn't count as being in the group.
+type GroupInvite struct {
+	UserUuid  string
+	InvitedBy string // uuid of the manager who sent it
+	CreatedAt int64  // unix seconds
+}
+
+const (
+	RestrictEditPlaylists   = "edit-playlists"   // the group's playlists are read only
+	RestrictDeletePlaylists = "delete-playlists" // can't delete the group's playlists
+)
+
+// Member returns the user's membership of the group, or nil
+func (g *Group) Member(userUuid string) *GroupMember {
+	for _, member := range g.Members {
+		if member.UserUuid == userUuid {
+			return member
+		}
+	}
+	return nil
+}
+
+// Invite returns the group's invite for the user, or nil
+func (g *Group) Invite(userUuid string) *GroupInvite {
+	for _, invite := range g.Invites {
+		if invite.UserUuid == userUuid {
+			return invite
+		}
+	}
+	return nil
+}
+
+// Restricted reports whether the managers have placed the restriction on the member
+func (m *GroupMember) Restricted(restriction string) bool {
+	for _, r := range m.Restrictions {
+		if r == restriction {
+			return true
+		}
+	}
+	return false
+}
+
+// ExternalLogin links an account at an external identity provider to a user,
+// so they can sign in through it. There is one per provider and subject, and
+// a user can only be linked once to each provider.
+type ExternalLogin struct {
+	Id           uint64
+	Provider     string // name the provider is configured under
+	Subject      string // the provider's id for the account, its "sub" claim
+	UserUuid     string
+	EmailAddress string // what the provider said when it was linked, for showing the user
+	CreatedAt    int64  // unix seconds
+}
+
+// ApiKey lets a script or headless player act as its user without signing
+// in, limited to its scopes. Only a hash of the key's secret is kept.
+type ApiKey struct {
+	Id         uint64
+	Uuid       string
+	UserUuid   string
+	Name       string
+	SecretHash string   // sha256 of the secret part of the key
+	Scopes     []string // what the key can be used for
+	CreatedAt  int64    // unix seconds
+	ExpiresAt  int64    // unix seconds, 0 when the key doesn't expire
+	LastUsed   int64    // unix seconds
+}
+
+// Device is a client the user has signed in from, every token is issued to a device
+type Device struct {
+	Id          uint64
+	Uuid        string
+	Name        string
+	Type        string // X-Authentication-Type sent when signing in
+	LastSeen    int64  // unix seconds
+	LastTokenId string // Id of the newest token issued to the device, older tokens are rejected
+
+	RefreshTokenHash    string   // sha256 of the device's current refresh token, empty once signed out
+	RefreshTokenExpires int64    // unix seconds
+	Scopes              []string // what the device's tokens are limited to, nil when they aren't
+
+	RefreshTokenFamily     string // shared by every refresh token since the device signed in
+	RefreshTokenGeneration uint64 // counts the refreshes of the family, the current token has the highest
+}
+
+// ResumePosition is where a user left off in a track, there is one per user and track
+type ResumePosition struct {
+	Id           uint64
+	UserUuid     string
+	TrackUuid    string
+	PlaylistUuid string // playlist the track was played from, if any
+	DeviceUuid   string
+	Elapsed      int
+	UpdatedAt    int64 // unix seconds
+}
+
+// Play is one entry in a user's listening history, plays are only ever added
+type Play struct {
+	Id           uint64
+	UserUuid     string
+	TrackUuid    string
+	PlaylistUuid string
+	DeviceUuid   string
+	StartOffset  int
+	EndOffset    int
+	StartedAt    int64 // unix seconds
+	EndedAt      int64 // unix seconds
+}
+
+// RevokedToken is a token that was signed out before it expired, it can be
+// forgotten once it has expired
+type RevokedToken struct {
+	Id        uint64
+	TokenId   string // jti of the token
+	ExpiresAt int64  // unix seconds
 }
 
 type User struct {
-	Id           uint64 // going to be an internal objectBoxId
-	Uuid         string `objectbox:"index:hash64"`
-	FirstName    string
-	LastName     string
-	EmailAddress string `objectbox:"index:hash64"`
-	Password     string
-	Enabled      bool
-	AdminUser    bool
-	LastPlaylist uint64
-	Tracks       []*Track
-	Playlists    []*Playlist
-	Friends      []*Friend
+	Id            uint64 // internal id assigned by the storage driver
+	Uuid          string
+	FirstName     string
+	LastName      string
+	EmailAddress  string
+	Password      string
+	Enabled       bool
+	EmailVerified bool     // the user has followed the link in a verification or password reset email
+	Roles         []string // names of the user's roles, what each role allows is up to the handlers
+	// Two factor authentication, the secret is set while enrolling and only
+	// asked for at sign in once enabled
+	TotpSecret        string // base32
+	TotpEnabled       bool
+	TotpLastStep      int64    // time step of the last code used, so each code only works once
+	TotpRecoveryCodes []string // sha256 hashes of the unused recovery codes
+	LastPlaylist      uint64
+	Tracks            []*Track // the user's library, every track in their playlists is also in here
+	Playlists         []*Playlist
+	Friends           []*Friend
+	Devices           []*Device
+}
+
+// The roles every driver needs to know about, the rest are only names to them
+const (
+	RoleAdmin  = "admin"  // what users from before roles with AdminUser set become
+	RoleMember = "member" // what every other user becomes
+)
+
+// HasRole reports whether the user has been given the role
+func (m *User) HasRole(role string) bool {
+	for _, r := range m.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,159 @@
+package objectbox
+
+//go:generate go run github.com/objectbox/objectbox-go/cmd/objectbox-gogen
+type Track struct {
+	Id               uint64
+	Uuid             string `objectbox:"index:hash64"`
+	Path             string `objectbox:"index:hash64"`
+	ArtistName       string `objectbox:"index:hash64"`
+	SongName         string `objectbox:"index:hash64"`
+	AlbumName        string `objectbox:"index:hash64"`
+	AlbumTrackNumber int
+	TrackLength      int
+	ContentHash      string `objectbox:"index:hash64"`
+}
+
+type Playlist struct {
+	Id                uint64
+	Uuid              string `objectbox:"index:hash64"`
+	Name              string
+	CurrentTrackUuid  string
+	Elapsed           int
+	Tracks            []*Track
+	TrackOrder        []string // uuids of Tracks in playlist order, the relation itself is unordered
+	LockDeviceUuid    string
+	ClientLockExpires int64
+	PositionRevision  uint64
+	PositionUpdatedAt int64
+	GroupUuid         string `objectbox:"index:hash64"`
+	hashValue         string `objectbox:"-"`
+}
+
+type Friend struct {
+	Id        uint64
+	FriendId  string `objectbox:"index:hash64"`
+	Status    string // blank for friends stored before requests, see toUser
+	UpdatedAt int64
+}
+
+type PlaylistShare struct {
+	Id           uint64
+	PlaylistUuid string `objectbox:"index:hash64"`
+	OwnerUuid    string `objectbox:"index:hash64"`
+	UserUuid     string `objectbox:"index:hash64"`
+	Access       string
+	CreatedAt    int64
+}
+
+type Group struct {
+	Id        uint64
+	Uuid      string `objectbox:"index:hash64"`
+	Name      string
+	CreatedAt int64
+	Revision  uint64
+}
+
+// GroupMember is stored on its own, by the Uuid of its group
+type GroupMember struct {
+	Id           uint64
+	GroupUuid    string `objectbox:"index:hash64"`
+	UserUuid     string `objectbox:"index:hash64"`
+	Manager      bool
+	Restrictions []string
+}
+
+// GroupInvite is stored on its own too, by the Uuid of its group
+type GroupInvite struct {
+	Id        uint64
+	GroupUuid string `objectbox:"index:hash64"`
+	UserUuid  string `objectbox:"index:hash64"`
+	InvitedBy string
+	CreatedAt int64
+}
+
+type ExternalLogin struct {
+	Id           uint64
+	Provider     string `objectbox:"index:hash64"`
+	Subject      string `objectbox:"index:hash64"`
+	UserUuid     string `objectbox:"index:hash64"`
+	EmailAddress string
+	CreatedAt    int64
+}
+
+type ApiKey struct {
+	Id         uint64
+	Uuid       string `objectbox:"index:hash64"`
+	UserUuid   string `objectbox:"index:hash64"`
+	Name       string
+	SecretHash string
+	Scopes     []string
+	CreatedAt  int64
+	ExpiresAt  int64
+	LastUsed   int64
+}
+
+type Device struct {
+	Id          uint64
+	Uuid        string `objectbox:"index:hash64"`
+	Name        string
+	Type        string
+	LastSeen    int64
+	LastTokenId string
+
+	RefreshTokenHash    string
+	RefreshTokenExpires int64
+	Scopes              []string
+
+	RefreshTokenFamily     string
+	RefreshTokenGeneration uint64
+}
+
+type ResumePosition struct {
+	Id           uint64
+	UserUuid     string `objectbox:"index:hash64"`
+	TrackUuid    string `objectbox:"index:hash64"`
+	PlaylistUuid string
+	DeviceUuid   string
+	Elapsed      int
+	UpdatedAt    int64
+}
+
+type Play struct {
+	Id           uint64
+	UserUuid     string `objectbox:"index:hash64"`
+	TrackUuid    string `objectbox:"index:hash64"`
+	PlaylistUuid string
+	DeviceUuid   string
+	StartOffset  int
+	EndOffset    int
+	StartedAt    int64
+	EndedAt      int64
+}
+
+type User struct {
+	Id                uint64 // going to be an internal objectBoxId
+	Uuid              string `objectbox:"index:hash64"`
+	FirstName         string
+	LastName          string
+	EmailAddress      string `objectbox:"index:hash64"`
+	Password          string
+	Enabled           bool
+	EmailVerified     bool
+	AdminUser         bool // replaced by Roles, only read for users stored before roles
+	Roles             []string
+	TotpSecret        string
+	TotpEnabled       bool
+	TotpLastStep      int64
+	TotpRecoveryCodes []string
+	LastPlaylist      uint64
+	Tracks            []*Track
+	Playlists         []*Playlist
+	Friends           []*Friend
+	Devices           []*Device
+}
+
+type RevokedToken struct {
+	Id        uint64
+	TokenId   string `objectbox:"index:hash64"`
+	ExpiresAt int64  `objectbox:"index"`
+}
@@ -0,0 +1,5589 @@
+// Code generated by ObjectBox; DO NOT EDIT.
+// Learn more about defining entities and generating this file - visit https://golang.objectbox.io/entity-annotations
+
+package objectbox
+
+import (
+	"errors"
+	"github.com/google/flatbuffers/go"
+	"github.com/objectbox/objectbox-go/objectbox"
+	"github.com/objectbox/objectbox-go/objectbox/fbutils"
+)
+
+type track_EntityInfo struct {
+	objectbox.Entity
+	Uid uint64
+}
+
+var TrackBinding = track_EntityInfo{
+	Entity: objectbox.Entity{
+		Id: 1,
+	},
+	Uid: 1009144760383425933,
+}
+
+// Track_ contains type-based Property helpers to facilitate some common operations such as Queries.
+var Track_ = struct {
+	Id               *objectbox.PropertyUint64
+	Uuid             *objectbox.PropertyString
+	Path             *objectbox.PropertyString
+	ArtistName       *objectbox.PropertyString
+	SongName         *objectbox.PropertyString
+	AlbumName        *objectbox.PropertyString
+	AlbumTrackNumber *objectbox.PropertyInt
+	TrackLength      *objectbox.PropertyInt
+	ContentHash      *objectbox.PropertyString
+}{
+	Id: &objectbox.PropertyUint64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     1,
+			Entity: &TrackBinding.Entity,
+		},
+	},
+	Uuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     2,
+			Entity: &TrackBinding.Entity,
+		},
+	},
+	Path: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     3,
+			Entity: &TrackBinding.Entity,
+		},
+	},
+	ArtistName: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     4,
+			Entity: &TrackBinding.Entity,
+		},
+	},
+	SongName: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     5,
+			Entity: &TrackBinding.Entity,
+		},
+	},
+	AlbumName: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     6,
+			Entity: &TrackBinding.Entity,
+		},
+	},
+	AlbumTrackNumber: &objectbox.PropertyInt{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     7,
+			Entity: &TrackBinding.Entity,
+		},
+	},
+	TrackLength: &objectbox.PropertyInt{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     8,
+			Entity: &TrackBinding.Entity,
+		},
+	},
+	ContentHash: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     9,
+			Entity: &TrackBinding.Entity,
+		},
+	},
+}
+
+// GeneratorVersion is called by ObjectBox to verify the compatibility of the generator used to generate this code
+func (track_EntityInfo) GeneratorVersion() int {
+	return 6
+}
+
+// AddToModel is called by ObjectBox during model build
+func (track_EntityInfo) AddToModel(model *objectbox.Model) {
+	model.Entity("Track", 1, 1009144760383425933)
+	model.Property("Id", 6, 1, 8328082909706324046)
+	model.PropertyFlags(1)
+	model.Property("Uuid", 9, 2, 1987961737853460063)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(1, 4092924393197870388)
+	model.Property("Path", 9, 3, 2074561992841698810)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(2, 8718592668905612706)
+	model.Property("ArtistName", 9, 4, 4607640011094518809)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(3, 4058904924878660498)
+	model.Property("SongName", 9, 5, 662266339228307963)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(4, 24294085824141789)
+	model.Property("AlbumName", 9, 6, 1221981067660187423)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(5, 1169029257320798961)
+	model.Property("AlbumTrackNumber", 6, 7, 756298788315811931)
+	model.Property("TrackLength", 6, 8, 9108779320025497871)
+	model.Property("ContentHash", 9, 9, 5351439480044899576)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(15, 6699762972752065997)
+	model.EntityLastPropertyId(9, 5351439480044899576)
+}
+
+// GetId is called by ObjectBox during Put operations to check for existing ID on an object
+func (track_EntityInfo) GetId(object interface{}) (uint64, error) {
+	return object.(*Track).Id, nil
+}
+
+// SetId is called by ObjectBox during Put to update an ID on an object that has just been inserted
+func (track_EntityInfo) SetId(object interface{}, id uint64) error {
+	object.(*Track).Id = id
+	return nil
+}
+
+// PutRelated is called by ObjectBox to put related entities before the object itself is flattened and put
+func (track_EntityInfo) PutRelated(ob *objectbox.ObjectBox, object interface{}, id uint64) error {
+	return nil
+}
+
+// Flatten is called by ObjectBox to transform an object to a FlatBuffer
+func (track_EntityInfo) Flatten(object interface{}, fbb *flatbuffers.Builder, id uint64) error {
+	obj := object.(*Track)
+	var offsetUuid = fbutils.CreateStringOffset(fbb, obj.Uuid)
+	var offsetPath = fbutils.CreateStringOffset(fbb, obj.Path)
+	var offsetArtistName = fbutils.CreateStringOffset(fbb, obj.ArtistName)
+	var offsetSongName = fbutils.CreateStringOffset(fbb, obj.SongName)
+	var offsetAlbumName = fbutils.CreateStringOffset(fbb, obj.AlbumName)
+	var offsetContentHash = fbutils.CreateStringOffset(fbb, obj.ContentHash)
+
+	// build the FlatBuffers object
+	fbb.StartObject(9)
+	fbutils.SetUint64Slot(fbb, 0, id)
+	fbutils.SetUOffsetTSlot(fbb, 1, offsetUuid)
+	fbutils.SetUOffsetTSlot(fbb, 2, offsetPath)
+	fbutils.SetUOffsetTSlot(fbb, 3, offsetArtistName)
+	fbutils.SetUOffsetTSlot(fbb, 4, offsetSongName)
+	fbutils.SetUOffsetTSlot(fbb, 5, offsetAlbumName)
+	fbutils.SetInt64Slot(fbb, 6, int64(obj.AlbumTrackNumber))
+	fbutils.SetInt64Slot(fbb, 7, int64(obj.TrackLength))
+	fbutils.SetUOffsetTSlot(fbb, 8, offsetContentHash)
+	return nil
+}
+
+// Load is called by ObjectBox to load an object from a FlatBuffer
+func (track_EntityInfo) Load(ob *objectbox.ObjectBox, bytes []byte) (interface{}, error) {
+	if len(bytes) == 0 { // sanity check, should "never" happen
+		return nil, errors.New("can't deserialize an object of type 'Track' - no data received")
+	}
+
+	var table = &flatbuffers.Table{
+		Bytes: bytes,
+		Pos:   flatbuffers.GetUOffsetT(bytes),
+	}
+
+	var propId = table.GetUint64Slot(4, 0)
+
+	return &Track{
+		Id:               propId,
+		Uuid:             fbutils.GetStringSlot(table, 6),
+		Path:             fbutils.GetStringSlot(table, 8),
+		ArtistName:       fbutils.GetStringSlot(table, 10),
+		SongName:         fbutils.GetStringSlot(table, 12),
+		AlbumName:        fbutils.GetStringSlot(table, 14),
+		AlbumTrackNumber: fbutils.GetIntSlot(table, 16),
+		TrackLength:      fbutils.GetIntSlot(table, 18),
+		ContentHash:      fbutils.GetStringSlot(table, 20),
+	}, nil
+}
+
+// MakeSlice is called by ObjectBox to construct a new slice to hold the read objects
+func (track_EntityInfo) MakeSlice(capacity int) interface{} {
+	return make([]*Track, 0, capacity)
+}
+
+// AppendToSlice is called by ObjectBox to fill the slice of the read objects
+func (track_EntityInfo) AppendToSlice(slice interface{}, object interface{}) interface{} {
+	if object == nil {
+		return append(slice.([]*Track), nil)
+	}
+	return append(slice.([]*Track), object.(*Track))
+}
+
+// Box provides CRUD access to Track objects
+type TrackBox struct {
+	*objectbox.Box
+}
+
+// BoxForTrack opens a box of Track objects
+func BoxForTrack(ob *objectbox.ObjectBox) *TrackBox {
+	return &TrackBox{
+		Box: ob.InternalBox(1),
+	}
+}
+
+// Put synchronously inserts/updates a single object.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the Track.Id property on the passed object will be assigned the new ID as well.
+func (box *TrackBox) Put(object *Track) (uint64, error) {
+	return box.Box.Put(object)
+}
+
+// Insert synchronously inserts a single object. As opposed to Put, Insert will fail if given an ID that already exists.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the Track.Id property on the passed object will be assigned the new ID as well.
+func (box *TrackBox) Insert(object *Track) (uint64, error) {
+	return box.Box.Insert(object)
+}
+
+// Update synchronously updates a single object.
+// As opposed to Put, Update will fail if an object with the same ID is not found in the database.
+func (box *TrackBox) Update(object *Track) error {
+	return box.Box.Update(object)
+}
+
+// PutAsync asynchronously inserts/updates a single object.
+// Deprecated: use box.Async().Put() instead
+func (box *TrackBox) PutAsync(object *Track) (uint64, error) {
+	return box.Box.PutAsync(object)
+}
+
+// PutMany inserts multiple objects in single transaction.
+// In case Ids are not set on the objects, they would be assigned automatically (auto-increment).
+//
+// Returns: IDs of the put objects (in the same order).
+// When inserting, the Track.Id property on the objects in the slice will be assigned the new IDs as well.
+//
+// Note: In case an error occurs during the transaction, some of the objects may already have the Track.Id assigned
+// even though the transaction has been rolled back and the objects are not stored under those IDs.
+//
+// Note: The slice may be empty or even nil; in both cases, an empty IDs slice and no error is returned.
+func (box *TrackBox) PutMany(objects []*Track) ([]uint64, error) {
+	return box.Box.PutMany(objects)
+}
+
+// Get reads a single object.
+//
+// Returns nil (and no error) in case the object with the given ID doesn't exist.
+func (box *TrackBox) Get(id uint64) (*Track, error) {
+	object, err := box.Box.Get(id)
+	if err != nil {
+		return nil, err
+	} else if object == nil {
+		return nil, nil
+	}
+	return object.(*Track), nil
+}
+
+// GetMany reads multiple objects at once.
+// If any of the objects doesn't exist, its position in the return slice is nil
+func (box *TrackBox) GetMany(ids ...uint64) ([]*Track, error) {
+	objects, err := box.Box.GetMany(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Track), nil
+}
+
+// GetManyExisting reads multiple objects at once, skipping those that do not exist.
+func (box *TrackBox) GetManyExisting(ids ...uint64) ([]*Track, error) {
+	objects, err := box.Box.GetManyExisting(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Track), nil
+}
+
+// GetAll reads all stored objects
+func (box *TrackBox) GetAll() ([]*Track, error) {
+	objects, err := box.Box.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Track), nil
+}
+
+// Remove deletes a single object
+func (box *TrackBox) Remove(object *Track) error {
+	return box.Box.Remove(object)
+}
+
+// RemoveMany deletes multiple objects at once.
+// Returns the number of deleted object or error on failure.
+// Note that this method will not fail if an object is not found (e.g. already removed).
+// In case you need to strictly check whether all of the objects exist before removing them,
+// you can execute multiple box.Contains() and box.Remove() inside a single write transaction.
+func (box *TrackBox) RemoveMany(objects ...*Track) (uint64, error) {
+	var ids = make([]uint64, len(objects))
+	for k, object := range objects {
+		ids[k] = object.Id
+	}
+	return box.Box.RemoveIds(ids...)
+}
+
+// Creates a query with the given conditions. Use the fields of the Track_ struct to create conditions.
+// Keep the *TrackQuery if you intend to execute the query multiple times.
+// Note: this function panics if you try to create illegal queries; e.g. use properties of an alien type.
+// This is typically a programming error. Use QueryOrError instead if you want the explicit error check.
+func (box *TrackBox) Query(conditions ...objectbox.Condition) *TrackQuery {
+	return &TrackQuery{
+		box.Box.Query(conditions...),
+	}
+}
+
+// Creates a query with the given conditions. Use the fields of the Track_ struct to create conditions.
+// Keep the *TrackQuery if you intend to execute the query multiple times.
+func (box *TrackBox) QueryOrError(conditions ...objectbox.Condition) (*TrackQuery, error) {
+	if query, err := box.Box.QueryOrError(conditions...); err != nil {
+		return nil, err
+	} else {
+		return &TrackQuery{query}, nil
+	}
+}
+
+// Async provides access to the default Async Box for asynchronous operations. See TrackAsyncBox for more information.
+func (box *TrackBox) Async() *TrackAsyncBox {
+	return &TrackAsyncBox{AsyncBox: box.Box.Async()}
+}
+
+// TrackAsyncBox provides asynchronous operations on Track objects.
+//
+// Asynchronous operations are executed on a separate internal thread for better performance.
+//
+// There are two main use cases:
+//
+// 1) "execute & forget:" you gain faster put/remove operations as you don't have to wait for the transaction to finish.
+//
+// 2) Many small transactions: if your write load is typically a lot of individual puts that happen in parallel,
+// this will merge small transactions into bigger ones. This results in a significant gain in overall throughput.
+//
+// In situations with (extremely) high async load, an async method may be throttled (~1ms) or delayed up to 1 second.
+// In the unlikely event that the object could still not be enqueued (full queue), an error will be returned.
+//
+// Note that async methods do not give you hard durability guarantees like the synchronous Box provides.
+// There is a small time window in which the data may not have been committed durably yet.
+type TrackAsyncBox struct {
+	*objectbox.AsyncBox
+}
+
+// AsyncBoxForTrack creates a new async box with the given operation timeout in case an async queue is full.
+// The returned struct must be freed explicitly using the Close() method.
+// It's usually preferable to use TrackBox::Async() which takes care of resource management and doesn't require closing.
+func AsyncBoxForTrack(ob *objectbox.ObjectBox, timeoutMs uint64) *TrackAsyncBox {
+	var async, err = objectbox.NewAsyncBox(ob, 1, timeoutMs)
+	if err != nil {
+		panic("Could not create async box for entity ID 1: %s" + err.Error())
+	}
+	return &TrackAsyncBox{AsyncBox: async}
+}
+
+// Put inserts/updates a single object asynchronously.
+// When inserting a new object, the Id property on the passed object will be assigned the new ID the entity would hold
+// if the insert is ultimately successful. The newly assigned ID may not become valid if the insert fails.
+func (asyncBox *TrackAsyncBox) Put(object *Track) (uint64, error) {
+	return asyncBox.AsyncBox.Put(object)
+}
+
+// Insert a single object asynchronously.
+// The Id property on the passed object will be assigned the new ID the entity would hold if the insert is ultimately
+// successful. The newly assigned ID may not become valid if the insert fails.
+// Fails silently if an object with the same ID already exists (this error is not returned).
+func (asyncBox *TrackAsyncBox) Insert(object *Track) (id uint64, err error) {
+	return asyncBox.AsyncBox.Insert(object)
+}
+
+// Update a single object asynchronously.
+// The object must already exists or the update fails silently (without an error returned).
+func (asyncBox *TrackAsyncBox) Update(object *Track) error {
+	return asyncBox.AsyncBox.Update(object)
+}
+
+// Remove deletes a single object asynchronously.
+func (asyncBox *TrackAsyncBox) Remove(object *Track) error {
+	return asyncBox.AsyncBox.Remove(object)
+}
+
+// Query provides a way to search stored objects
+//
+// For example, you can find all Track which Id is either 42 or 47:
+//
+//	box.Query(Track_.Id.In(42, 47)).Find()
+type TrackQuery struct {
+	*objectbox.Query
+}
+
+// Find returns all objects matching the query
+func (query *TrackQuery) Find() ([]*Track, error) {
+	objects, err := query.Query.Find()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Track), nil
+}
+
+// Offset defines the index of the first object to process (how many objects to skip)
+func (query *TrackQuery) Offset(offset uint64) *TrackQuery {
+	query.Query.Offset(offset)
+	return query
+}
+
+// Limit sets the number of elements to process by the query
+func (query *TrackQuery) Limit(limit uint64) *TrackQuery {
+	query.Query.Limit(limit)
+	return query
+}
+
+type playlist_EntityInfo struct {
+	objectbox.Entity
+	Uid uint64
+}
+
+var PlaylistBinding = playlist_EntityInfo{
+	Entity: objectbox.Entity{
+		Id: 2,
+	},
+	Uid: 1182139793609600194,
+}
+
+// Playlist_ contains type-based Property helpers to facilitate some common operations such as Queries.
+var Playlist_ = struct {
+	Id                *objectbox.PropertyUint64
+	Uuid              *objectbox.PropertyString
+	Name              *objectbox.PropertyString
+	Elapsed           *objectbox.PropertyInt
+	ClientLockExpires *objectbox.PropertyInt64
+	PositionRevision  *objectbox.PropertyUint64
+	PositionUpdatedAt *objectbox.PropertyInt64
+	LockDeviceUuid    *objectbox.PropertyString
+	CurrentTrackUuid  *objectbox.PropertyString
+	TrackOrder        *objectbox.PropertyStringVector
+	GroupUuid         *objectbox.PropertyString
+	Tracks            *objectbox.RelationToMany
+}{
+	Id: &objectbox.PropertyUint64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     1,
+			Entity: &PlaylistBinding.Entity,
+		},
+	},
+	Uuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     2,
+			Entity: &PlaylistBinding.Entity,
+		},
+	},
+	Name: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     3,
+			Entity: &PlaylistBinding.Entity,
+		},
+	},
+	Elapsed: &objectbox.PropertyInt{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     5,
+			Entity: &PlaylistBinding.Entity,
+		},
+	},
+	ClientLockExpires: &objectbox.PropertyInt64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     7,
+			Entity: &PlaylistBinding.Entity,
+		},
+	},
+	PositionRevision: &objectbox.PropertyUint64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     8,
+			Entity: &PlaylistBinding.Entity,
+		},
+	},
+	PositionUpdatedAt: &objectbox.PropertyInt64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     9,
+			Entity: &PlaylistBinding.Entity,
+		},
+	},
+	LockDeviceUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     10,
+			Entity: &PlaylistBinding.Entity,
+		},
+	},
+	CurrentTrackUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     11,
+			Entity: &PlaylistBinding.Entity,
+		},
+	},
+	TrackOrder: &objectbox.PropertyStringVector{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     12,
+			Entity: &PlaylistBinding.Entity,
+		},
+	},
+	GroupUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     13,
+			Entity: &PlaylistBinding.Entity,
+		},
+	},
+	Tracks: &objectbox.RelationToMany{
+		Id:     1,
+		Source: &PlaylistBinding.Entity,
+		Target: &TrackBinding.Entity,
+	},
+}
+
+// GeneratorVersion is called by ObjectBox to verify the compatibility of the generator used to generate this code
+func (playlist_EntityInfo) GeneratorVersion() int {
+	return 6
+}
+
+// AddToModel is called by ObjectBox during model build
+func (playlist_EntityInfo) AddToModel(model *objectbox.Model) {
+	model.Entity("Playlist", 2, 1182139793609600194)
+	model.Property("Id", 6, 1, 1796519454618579982)
+	model.PropertyFlags(1)
+	model.Property("Uuid", 9, 2, 7106733261962919725)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(6, 4305032471893927022)
+	model.Property("Name", 9, 3, 7337893131552777598)
+	model.Property("Elapsed", 6, 5, 1873524423846790449)
+	model.Property("ClientLockExpires", 6, 7, 8516664682714891262)
+	model.Property("PositionRevision", 6, 8, 1458242173729686791)
+	model.PropertyFlags(8192)
+	model.Property("PositionUpdatedAt", 6, 9, 1559079837039561876)
+	model.Property("LockDeviceUuid", 9, 10, 6764391094859222386)
+	model.Property("CurrentTrackUuid", 9, 11, 6244005628586468873)
+	model.Property("TrackOrder", 30, 12, 4464112201404399956)
+	model.Property("GroupUuid", 9, 13, 2181278139206252317)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(21, 3808189936448424415)
+	model.EntityLastPropertyId(13, 2181278139206252317)
+	model.Relation(1, 3267482171217122691, TrackBinding.Id, TrackBinding.Uid)
+}
+
+// GetId is called by ObjectBox during Put operations to check for existing ID on an object
+func (playlist_EntityInfo) GetId(object interface{}) (uint64, error) {
+	return object.(*Playlist).Id, nil
+}
+
+// SetId is called by ObjectBox during Put to update an ID on an object that has just been inserted
+func (playlist_EntityInfo) SetId(object interface{}, id uint64) error {
+	object.(*Playlist).Id = id
+	return nil
+}
+
+// PutRelated is called by ObjectBox to put related entities before the object itself is flattened and put
+func (playlist_EntityInfo) PutRelated(ob *objectbox.ObjectBox, object interface{}, id uint64) error {
+	if err := BoxForPlaylist(ob).RelationReplace(Playlist_.Tracks, id, object, object.(*Playlist).Tracks); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Flatten is called by ObjectBox to transform an object to a FlatBuffer
+func (playlist_EntityInfo) Flatten(object interface{}, fbb *flatbuffers.Builder, id uint64) error {
+	obj := object.(*Playlist)
+	var offsetUuid = fbutils.CreateStringOffset(fbb, obj.Uuid)
+	var offsetName = fbutils.CreateStringOffset(fbb, obj.Name)
+	var offsetLockDeviceUuid = fbutils.CreateStringOffset(fbb, obj.LockDeviceUuid)
+	var offsetCurrentTrackUuid = fbutils.CreateStringOffset(fbb, obj.CurrentTrackUuid)
+	var offsetTrackOrder = fbutils.CreateStringVectorOffset(fbb, obj.TrackOrder)
+	var offsetGroupUuid = fbutils.CreateStringOffset(fbb, obj.GroupUuid)
+
+	// build the FlatBuffers object
+	fbb.StartObject(13)
+	fbutils.SetUint64Slot(fbb, 0, id)
+	fbutils.SetUOffsetTSlot(fbb, 1, offsetUuid)
+	fbutils.SetUOffsetTSlot(fbb, 2, offsetName)
+	fbutils.SetUOffsetTSlot(fbb, 10, offsetCurrentTrackUuid)
+	fbutils.SetInt64Slot(fbb, 4, int64(obj.Elapsed))
+	fbutils.SetUOffsetTSlot(fbb, 11, offsetTrackOrder)
+	fbutils.SetUOffsetTSlot(fbb, 9, offsetLockDeviceUuid)
+	fbutils.SetInt64Slot(fbb, 6, obj.ClientLockExpires)
+	fbutils.SetUint64Slot(fbb, 7, obj.PositionRevision)
+	fbutils.SetInt64Slot(fbb, 8, obj.PositionUpdatedAt)
+	fbutils.SetUOffsetTSlot(fbb, 12, offsetGroupUuid)
+	return nil
+}
+
+// Load is called by ObjectBox to load an object from a FlatBuffer
+func (playlist_EntityInfo) Load(ob *objectbox.ObjectBox, bytes []byte) (interface{}, error) {
+	if len(bytes) == 0 { // sanity check, should "never" happen
+		return nil, errors.New("can't deserialize an object of type 'Playlist' - no data received")
+	}
+
+	var table = &flatbuffers.Table{
+		Bytes: bytes,
+		Pos:   flatbuffers.GetUOffsetT(bytes),
+	}
+
+	var propId = table.GetUint64Slot(4, 0)
+
+	var relTracks []*Track
+	if rIds, err := BoxForPlaylist(ob).RelationIds(Playlist_.Tracks, propId); err != nil {
+		return nil, err
+	} else if rSlice, err := BoxForTrack(ob).GetManyExisting(rIds...); err != nil {
+		return nil, err
+	} else {
+		relTracks = rSlice
+	}
+
+	return &Playlist{
+		Id:                propId,
+		Uuid:              fbutils.GetStringSlot(table, 6),
+		Name:              fbutils.GetStringSlot(table, 8),
+		CurrentTrackUuid:  fbutils.GetStringSlot(table, 24),
+		Elapsed:           fbutils.GetIntSlot(table, 12),
+		Tracks:            relTracks,
+		TrackOrder:        fbutils.GetStringVectorSlot(table, 26),
+		LockDeviceUuid:    fbutils.GetStringSlot(table, 22),
+		ClientLockExpires: fbutils.GetInt64Slot(table, 16),
+		PositionRevision:  fbutils.GetUint64Slot(table, 18),
+		PositionUpdatedAt: fbutils.GetInt64Slot(table, 20),
+		GroupUuid:         fbutils.GetStringSlot(table, 28),
+	}, nil
+}
+
+// MakeSlice is called by ObjectBox to construct a new slice to hold the read objects
+func (playlist_EntityInfo) MakeSlice(capacity int) interface{} {
+	return make([]*Playlist, 0, capacity)
+}
+
+// AppendToSlice is called by ObjectBox to fill the slice of the read objects
+func (playlist_EntityInfo) AppendToSlice(slice interface{}, object interface{}) interface{} {
+	if object == nil {
+		return append(slice.([]*Playlist), nil)
+	}
+	return append(slice.([]*Playlist), object.(*Playlist))
+}
+
+// Box provides CRUD access to Playlist objects
+type PlaylistBox struct {
+	*objectbox.Box
+}
+
+// BoxForPlaylist opens a box of Playlist objects
+func BoxForPlaylist(ob *objectbox.ObjectBox) *PlaylistBox {
+	return &PlaylistBox{
+		Box: ob.InternalBox(2),
+	}
+}
+
+// Put synchronously inserts/updates a single object.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the Playlist.Id property on the passed object will be assigned the new ID as well.
+func (box *PlaylistBox) Put(object *Playlist) (uint64, error) {
+	return box.Box.Put(object)
+}
+
+// Insert synchronously inserts a single object. As opposed to Put, Insert will fail if given an ID that already exists.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the Playlist.Id property on the passed object will be assigned the new ID as well.
+func (box *PlaylistBox) Insert(object *Playlist) (uint64, error) {
+	return box.Box.Insert(object)
+}
+
+// Update synchronously updates a single object.
+// As opposed to Put, Update will fail if an object with the same ID is not found in the database.
+func (box *PlaylistBox) Update(object *Playlist) error {
+	return box.Box.Update(object)
+}
+
+// PutAsync asynchronously inserts/updates a single object.
+// Deprecated: use box.Async().Put() instead
+func (box *PlaylistBox) PutAsync(object *Playlist) (uint64, error) {
+	return box.Box.PutAsync(object)
+}
+
+// PutMany inserts multiple objects in single transaction.
+// In case Ids are not set on the objects, they would be assigned automatically (auto-increment).
+//
+// Returns: IDs of the put objects (in the same order).
+// When inserting, the Playlist.Id property on the objects in the slice will be assigned the new IDs as well.
+//
+// Note: In case an error occurs during the transaction, some of the objects may already have the Playlist.Id assigned
+// even though the transaction has been rolled back and the objects are not stored under those IDs.
+//
+// Note: The slice may be empty or even nil; in both cases, an empty IDs slice and no error is returned.
+func (box *PlaylistBox) PutMany(objects []*Playlist) ([]uint64, error) {
+	return box.Box.PutMany(objects)
+}
+
+// Get reads a single object.
+//
+// Returns nil (and no error) in case the object with the given ID doesn't exist.
+func (box *PlaylistBox) Get(id uint64) (*Playlist, error) {
+	object, err := box.Box.Get(id)
+	if err != nil {
+		return nil, err
+	} else if object == nil {
+		return nil, nil
+	}
+	return object.(*Playlist), nil
+}
+
+// GetMany reads multiple objects at once.
+// If any of the objects doesn't exist, its position in the return slice is nil
+func (box *PlaylistBox) GetMany(ids ...uint64) ([]*Playlist, error) {
+	objects, err := box.Box.GetMany(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Playlist), nil
+}
+
+// GetManyExisting reads multiple objects at once, skipping those that do not exist.
+func (box *PlaylistBox) GetManyExisting(ids ...uint64) ([]*Playlist, error) {
+	objects, err := box.Box.GetManyExisting(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Playlist), nil
+}
+
+// GetAll reads all stored objects
+func (box *PlaylistBox) GetAll() ([]*Playlist, error) {
+	objects, err := box.Box.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Playlist), nil
+}
+
+// Remove deletes a single object
+func (box *PlaylistBox) Remove(object *Playlist) error {
+	return box.Box.Remove(object)
+}
+
+// RemoveMany deletes multiple objects at once.
+// Returns the number of deleted object or error on failure.
+// Note that this method will not fail if an object is not found (e.g. already removed).
+// In case you need to strictly check whether all of the objects exist before removing them,
+// you can execute multiple box.Contains() and box.Remove() inside a single write transaction.
+func (box *PlaylistBox) RemoveMany(objects ...*Playlist) (uint64, error) {
+	var ids = make([]uint64, len(objects))
+	for k, object := range objects {
+		ids[k] = object.Id
+	}
+	return box.Box.RemoveIds(ids...)
+}
+
+// Creates a query with the given conditions. Use the fields of the Playlist_ struct to create conditions.
+// Keep the *PlaylistQuery if you intend to execute the query multiple times.
+// Note: this function panics if you try to create illegal queries; e.g. use properties of an alien type.
+// This is typically a programming error. Use QueryOrError instead if you want the explicit error check.
+func (box *PlaylistBox) Query(conditions ...objectbox.Condition) *PlaylistQuery {
+	return &PlaylistQuery{
+		box.Box.Query(conditions...),
+	}
+}
+
+// Creates a query with the given conditions. Use the fields of the Playlist_ struct to create conditions.
+// Keep the *PlaylistQuery if you intend to execute the query multiple times.
+func (box *PlaylistBox) QueryOrError(conditions ...objectbox.Condition) (*PlaylistQuery, error) {
+	if query, err := box.Box.QueryOrError(conditions...); err != nil {
+		return nil, err
+	} else {
+		return &PlaylistQuery{query}, nil
+	}
+}
+
+// Async provides access to the default Async Box for asynchronous operations. See PlaylistAsyncBox for more information.
+func (box *PlaylistBox) Async() *PlaylistAsyncBox {
+	return &PlaylistAsyncBox{AsyncBox: box.Box.Async()}
+}
+
+// PlaylistAsyncBox provides asynchronous operations on Playlist objects.
+//
+// Asynchronous operations are executed on a separate internal thread for better performance.
+//
+// There are two main use cases:
+//
+// 1) "execute & forget:" you gain faster put/remove operations as you don't have to wait for the transaction to finish.
+//
+// 2) Many small transactions: if your write load is typically a lot of individual puts that happen in parallel,
+// this will merge small transactions into bigger ones. This results in a significant gain in overall throughput.
+//
+// In situations with (extremely) high async load, an async method may be throttled (~1ms) or delayed up to 1 second.
+// In the unlikely event that the object could still not be enqueued (full queue), an error will be returned.
+//
+// Note that async methods do not give you hard durability guarantees like the synchronous Box provides.
+// There is a small time window in which the data may not have been committed durably yet.
+type PlaylistAsyncBox struct {
+	*objectbox.AsyncBox
+}
+
+// AsyncBoxForPlaylist creates a new async box with the given operation timeout in case an async queue is full.
+// The returned struct must be freed explicitly using the Close() method.
+// It's usually preferable to use PlaylistBox::Async() which takes care of resource management and doesn't require closing.
+func AsyncBoxForPlaylist(ob *objectbox.ObjectBox, timeoutMs uint64) *PlaylistAsyncBox {
+	var async, err = objectbox.NewAsyncBox(ob, 2, timeoutMs)
+	if err != nil {
+		panic("Could not create async box for entity ID 2: %s" + err.Error())
+	}
+	return &PlaylistAsyncBox{AsyncBox: async}
+}
+
+// Put inserts/updates a single object asynchronously.
+// When inserting a new object, the Id property on the passed object will be assigned the new ID the entity would hold
+// if the insert is ultimately successful. The newly assigned ID may not become valid if the insert fails.
+func (asyncBox *PlaylistAsyncBox) Put(object *Playlist) (uint64, error) {
+	return asyncBox.AsyncBox.Put(object)
+}
+
+// Insert a single object asynchronously.
+// The Id property on the passed object will be assigned the new ID the entity would hold if the insert is ultimately
+// successful. The newly assigned ID may not become valid if the insert fails.
+// Fails silently if an object with the same ID already exists (this error is not returned).
+func (asyncBox *PlaylistAsyncBox) Insert(object *Playlist) (id uint64, err error) {
+	return asyncBox.AsyncBox.Insert(object)
+}
+
+// Update a single object asynchronously.
+// The object must already exists or the update fails silently (without an error returned).
+func (asyncBox *PlaylistAsyncBox) Update(object *Playlist) error {
+	return asyncBox.AsyncBox.Update(object)
+}
+
+// Remove deletes a single object asynchronously.
+func (asyncBox *PlaylistAsyncBox) Remove(object *Playlist) error {
+	return asyncBox.AsyncBox.Remove(object)
+}
+
+// Query provides a way to search stored objects
+//
+// For example, you can find all Playlist which Id is either 42 or 47:
+//
+//	box.Query(Playlist_.Id.In(42, 47)).Find()
+type PlaylistQuery struct {
+	*objectbox.Query
+}
+
+// Find returns all objects matching the query
+func (query *PlaylistQuery) Find() ([]*Playlist, error) {
+	objects, err := query.Query.Find()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Playlist), nil
+}
+
+// Offset defines the index of the first object to process (how many objects to skip)
+func (query *PlaylistQuery) Offset(offset uint64) *PlaylistQuery {
+	query.Query.Offset(offset)
+	return query
+}
+
+// Limit sets the number of elements to process by the query
+func (query *PlaylistQuery) Limit(limit uint64) *PlaylistQuery {
+	query.Query.Limit(limit)
+	return query
+}
+
+type friend_EntityInfo struct {
+	objectbox.Entity
+	Uid uint64
+}
+
+var FriendBinding = friend_EntityInfo{
+	Entity: objectbox.Entity{
+		Id: 3,
+	},
+	Uid: 6526345522080463439,
+}
+
+// Friend_ contains type-based Property helpers to facilitate some common operations such as Queries.
+var Friend_ = struct {
+	Id        *objectbox.PropertyUint64
+	FriendId  *objectbox.PropertyString
+	Status    *objectbox.PropertyString
+	UpdatedAt *objectbox.PropertyInt64
+}{
+	Id: &objectbox.PropertyUint64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     1,
+			Entity: &FriendBinding.Entity,
+		},
+	},
+	FriendId: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     2,
+			Entity: &FriendBinding.Entity,
+		},
+	},
+	Status: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     3,
+			Entity: &FriendBinding.Entity,
+		},
+	},
+	UpdatedAt: &objectbox.PropertyInt64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     4,
+			Entity: &FriendBinding.Entity,
+		},
+	},
+}
+
+// GeneratorVersion is called by ObjectBox to verify the compatibility of the generator used to generate this code
+func (friend_EntityInfo) GeneratorVersion() int {
+	return 6
+}
+
+// AddToModel is called by ObjectBox during model build
+func (friend_EntityInfo) AddToModel(model *objectbox.Model) {
+	model.Entity("Friend", 3, 6526345522080463439)
+	model.Property("Id", 6, 1, 883542943098408523)
+	model.PropertyFlags(1)
+	model.Property("FriendId", 9, 2, 6453426738975360861)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(7, 8254895745365351782)
+	model.Property("Status", 9, 3, 6725661808204833811)
+	model.Property("UpdatedAt", 6, 4, 538138125315477776)
+	model.EntityLastPropertyId(4, 538138125315477776)
+}
+
+// GetId is called by ObjectBox during Put operations to check for existing ID on an object
+func (friend_EntityInfo) GetId(object interface{}) (uint64, error) {
+	return object.(*Friend).Id, nil
+}
+
+// SetId is called by ObjectBox during Put to update an ID on an object that has just been inserted
+func (friend_EntityInfo) SetId(object interface{}, id uint64) error {
+	object.(*Friend).Id = id
+	return nil
+}
+
+// PutRelated is called by ObjectBox to put related entities before the object itself is flattened and put
+func (friend_EntityInfo) PutRelated(ob *objectbox.ObjectBox, object interface{}, id uint64) error {
+	return nil
+}
+
+// Flatten is called by ObjectBox to transform an object to a FlatBuffer
+func (friend_EntityInfo) Flatten(object interface{}, fbb *flatbuffers.Builder, id uint64) error {
+	obj := object.(*Friend)
+	var offsetFriendId = fbutils.CreateStringOffset(fbb, obj.FriendId)
+	var offsetStatus = fbutils.CreateStringOffset(fbb, obj.Status)
+
+	// build the FlatBuffers object
+	fbb.StartObject(4)
+	fbutils.SetUint64Slot(fbb, 0, id)
+	fbutils.SetUOffsetTSlot(fbb, 1, offsetFriendId)
+	fbutils.SetUOffsetTSlot(fbb, 2, offsetStatus)
+	fbutils.SetInt64Slot(fbb, 3, obj.UpdatedAt)
+	return nil
+}
+
+// Load is called by ObjectBox to load an object from a FlatBuffer
+func (friend_EntityInfo) Load(ob *objectbox.ObjectBox, bytes []byte) (interface{}, error) {
+	if len(bytes) == 0 { // sanity check, should "never" happen
+		return nil, errors.New("can't deserialize an object of type 'Friend' - no data received")
+	}
+
+	var table = &flatbuffers.Table{
+		Bytes: bytes,
+		Pos:   flatbuffers.GetUOffsetT(bytes),
+	}
+
+	var propId = table.GetUint64Slot(4, 0)
+
+	return &Friend{
+		Id:        propId,
+		FriendId:  fbutils.GetStringSlot(table, 6),
+		Status:    fbutils.GetStringSlot(table, 8),
+		UpdatedAt: fbutils.GetInt64Slot(table, 10),
+	}, nil
+}
+
+// MakeSlice is called by ObjectBox to construct a new slice to hold the read objects
+func (friend_EntityInfo) MakeSlice(capacity int) interface{} {
+	return make([]*Friend, 0, capacity)
+}
+
+// AppendToSlice is called by ObjectBox to fill the slice of the read objects
+func (friend_EntityInfo) AppendToSlice(slice interface{}, object interface{}) interface{} {
+	if object == nil {
+		return append(slice.([]*Friend), nil)
+	}
+	return append(slice.([]*Friend), object.(*Friend))
+}
+
+// Box provides CRUD access to Friend objects
+type FriendBox struct {
+	*objectbox.Box
+}
+
+// BoxForFriend opens a box of Friend objects
+func BoxForFriend(ob *objectbox.ObjectBox) *FriendBox {
+	return &FriendBox{
+		Box: ob.InternalBox(3),
+	}
+}
+
+// Put synchronously inserts/updates a single object.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the Friend.Id property on the passed object will be assigned the new ID as well.
+func (box *FriendBox) Put(object *Friend) (uint64, error) {
+	return box.Box.Put(object)
+}
+
+// Insert synchronously inserts a single object. As opposed to Put, Insert will fail if given an ID that already exists.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the Friend.Id property on the passed object will be assigned the new ID as well.
+func (box *FriendBox) Insert(object *Friend) (uint64, error) {
+	return box.Box.Insert(object)
+}
+
+// Update synchronously updates a single object.
+// As opposed to Put, Update will fail if an object with the same ID is not found in the database.
+func (box *FriendBox) Update(object *Friend) error {
+	return box.Box.Update(object)
+}
+
+// PutAsync asynchronously inserts/updates a single object.
+// Deprecated: use box.Async().Put() instead
+func (box *FriendBox) PutAsync(object *Friend) (uint64, error) {
+	return box.Box.PutAsync(object)
+}
+
+// PutMany inserts multiple objects in single transaction.
+// In case Ids are not set on the objects, they would be assigned automatically (auto-increment).
+//
+// Returns: IDs of the put objects (in the same order).
+// When inserting, the Friend.Id property on the objects in the slice will be assigned the new IDs as well.
+//
+// Note: In case an error occurs during the transaction, some of the objects may already have the Friend.Id assigned
+// even though the transaction has been rolled back and the objects are not stored under those IDs.
+//
+// Note: The slice may be empty or even nil; in both cases, an empty IDs slice and no error is returned.
+func (box *FriendBox) PutMany(objects []*Friend) ([]uint64, error) {
+	return box.Box.PutMany(objects)
+}
+
+// Get reads a single object.
+//
+// Returns nil (and no error) in case the object with the given ID doesn't exist.
+func (box *FriendBox) Get(id uint64) (*Friend, error) {
+	object, err := box.Box.Get(id)
+	if err != nil {
+		return nil, err
+	} else if object == nil {
+		return nil, nil
+	}
+	return object.(*Friend), nil
+}
+
+// GetMany reads multiple objects at once.
+// If any of the objects doesn't exist, its position in the return slice is nil
+func (box *FriendBox) GetMany(ids ...uint64) ([]*Friend, error) {
+	objects, err := box.Box.GetMany(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Friend), nil
+}
+
+// GetManyExisting reads multiple objects at once, skipping those that do not exist.
+func (box *FriendBox) GetManyExisting(ids ...uint64) ([]*Friend, error) {
+	objects, err := box.Box.GetManyExisting(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Friend), nil
+}
+
+// GetAll reads all stored objects
+func (box *FriendBox) GetAll() ([]*Friend, error) {
+	objects, err := box.Box.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Friend), nil
+}
+
+// Remove deletes a single object
+func (box *FriendBox) Remove(object *Friend) error {
+	return box.Box.Remove(object)
+}
+
+// RemoveMany deletes multiple objects at once.
+// Returns the number of deleted object or error on failure.
+// Note that this method will not fail if an object is not found (e.g. already removed).
+// In case you need to strictly check whether all of the objects exist before removing them,
+// you can execute multiple box.Contains() and box.Remove() inside a single write transaction.
+func (box *FriendBox) RemoveMany(objects ...*Friend) (uint64, error) {
+	var ids = make([]uint64, len(objects))
+	for k, object := range objects {
+		ids[k] = object.Id
+	}
+	return box.Box.RemoveIds(ids...)
+}
+
+// Creates a query with the given conditions. Use the fields of the Friend_ struct to create conditions.
+// Keep the *FriendQuery if you intend to execute the query multiple times.
+// Note: this function panics if you try to create illegal queries; e.g. use properties of an alien type.
+// This is typically a programming error. Use QueryOrError instead if you want the explicit error check.
+func (box *FriendBox) Query(conditions ...objectbox.Condition) *FriendQuery {
+	return &FriendQuery{
+		box.Box.Query(conditions...),
+	}
+}
+
+// Creates a query with the given conditions. Use the fields of the Friend_ struct to create conditions.
+// Keep the *FriendQuery if you intend to execute the query multiple times.
+func (box *FriendBox) QueryOrError(conditions ...objectbox.Condition) (*FriendQuery, error) {
+	if query, err := box.Box.QueryOrError(conditions...); err != nil {
+		return nil, err
+	} else {
+		return &FriendQuery{query}, nil
+	}
+}
+
+// Async provides access to the default Async Box for asynchronous operations. See FriendAsyncBox for more information.
+func (box *FriendBox) Async() *FriendAsyncBox {
+	return &FriendAsyncBox{AsyncBox: box.Box.Async()}
+}
+
+// FriendAsyncBox provides asynchronous operations on Friend objects.
+//
+// Asynchronous operations are executed on a separate internal thread for better performance.
+//
+// There are two main use cases:
+//
+// 1) "execute & forget:" you gain faster put/remove operations as you don't have to wait for the transaction to finish.
+//
+// 2) Many small transactions: if your write load is typically a lot of individual puts that happen in parallel,
+// this will merge small transactions into bigger ones. This results in a significant gain in overall throughput.
+//
+// In situations with (extremely) high async load, an async method may be throttled (~1ms) or delayed up to 1 second.
+// In the unlikely event that the object could still not be enqueued (full queue), an error will be returned.
+//
+// Note that async methods do not give you hard durability guarantees like the synchronous Box provides.
+// There is a small time window in which the data may not have been committed durably yet.
+type FriendAsyncBox struct {
+	*objectbox.AsyncBox
+}
+
+// AsyncBoxForFriend creates a new async box with the given operation timeout in case an async queue is full.
+// The returned struct must be freed explicitly using the Close() method.
+// It's usually preferable to use FriendBox::Async() which takes care of resource management and doesn't require closing.
+func AsyncBoxForFriend(ob *objectbox.ObjectBox, timeoutMs uint64) *FriendAsyncBox {
+	var async, err = objectbox.NewAsyncBox(ob, 3, timeoutMs)
+	if err != nil {
+		panic("Could not create async box for entity ID 3: %s" + err.Error())
+	}
+	return &FriendAsyncBox{AsyncBox: async}
+}
+
+// Put inserts/updates a single object asynchronously.
+// When inserting a new object, the Id property on the passed object will be assigned the new ID the entity would hold
+// if the insert is ultimately successful. The newly assigned ID may not become valid if the insert fails.
+func (asyncBox *FriendAsyncBox) Put(object *Friend) (uint64, error) {
+	return asyncBox.AsyncBox.Put(object)
+}
+
+// Insert a single object asynchronously.
+// The Id property on the passed object will be assigned the new ID the entity would hold if the insert is ultimately
+// successful. The newly assigned ID may not become valid if the insert fails.
+// Fails silently if an object with the same ID already exists (this error is not returned).
+func (asyncBox *FriendAsyncBox) Insert(object *Friend) (id uint64, err error) {
+	return asyncBox.AsyncBox.Insert(object)
+}
+
+// Update a single object asynchronously.
+// The object must already exists or the update fails silently (without an error returned).
+func (asyncBox *FriendAsyncBox) Update(object *Friend) error {
+	return asyncBox.AsyncBox.Update(object)
+}
+
+// Remove deletes a single object asynchronously.
+func (asyncBox *FriendAsyncBox) Remove(object *Friend) error {
+	return asyncBox.AsyncBox.Remove(object)
+}
+
+// Query provides a way to search stored objects
+//
+// For example, you can find all Friend which Id is either 42 or 47:
+//
+//	box.Query(Friend_.Id.In(42, 47)).Find()
+type FriendQuery struct {
+	*objectbox.Query
+}
+
+// Find returns all objects matching the query
+func (query *FriendQuery) Find() ([]*Friend, error) {
+	objects, err := query.Query.Find()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Friend), nil
+}
+
+// Offset defines the index of the first object to process (how many objects to skip)
+func (query *FriendQuery) Offset(offset uint64) *FriendQuery {
+	query.Query.Offset(offset)
+	return query
+}
+
+// Limit sets the number of elements to process by the query
+func (query *FriendQuery) Limit(limit uint64) *FriendQuery {
+	query.Query.Limit(limit)
+	return query
+}
+
+type user_EntityInfo struct {
+	objectbox.Entity
+	Uid uint64
+}
+
+var UserBinding = user_EntityInfo{
+	Entity: objectbox.Entity{
+		Id: 4,
+	},
+	Uid: 4728390412674560454,
+}
+
+// User_ contains type-based Property helpers to facilitate some common operations such as Queries.
+var User_ = struct {
+	Id                *objectbox.PropertyUint64
+	Uuid              *objectbox.PropertyString
+	FirstName         *objectbox.PropertyString
+	LastName          *objectbox.PropertyString
+	EmailAddress      *objectbox.PropertyString
+	Password          *objectbox.PropertyString
+	Enabled           *objectbox.PropertyBool
+	AdminUser         *objectbox.PropertyBool
+	LastPlaylist      *objectbox.PropertyUint64
+	EmailVerified     *objectbox.PropertyBool
+	TotpSecret        *objectbox.PropertyString
+	TotpEnabled       *objectbox.PropertyBool
+	TotpLastStep      *objectbox.PropertyInt64
+	TotpRecoveryCodes *objectbox.PropertyStringVector
+	Roles             *objectbox.PropertyStringVector
+	Tracks            *objectbox.RelationToMany
+	Playlists         *objectbox.RelationToMany
+	Friends           *objectbox.RelationToMany
+	Devices           *objectbox.RelationToMany
+}{
+	Id: &objectbox.PropertyUint64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     1,
+			Entity: &UserBinding.Entity,
+		},
+	},
+	Uuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     2,
+			Entity: &UserBinding.Entity,
+		},
+	},
+	FirstName: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     3,
+			Entity: &UserBinding.Entity,
+		},
+	},
+	LastName: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     4,
+			Entity: &UserBinding.Entity,
+		},
+	},
+	EmailAddress: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     5,
+			Entity: &UserBinding.Entity,
+		},
+	},
+	Password: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     6,
+			Entity: &UserBinding.Entity,
+		},
+	},
+	Enabled: &objectbox.PropertyBool{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     7,
+			Entity: &UserBinding.Entity,
+		},
+	},
+	AdminUser: &objectbox.PropertyBool{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     8,
+			Entity: &UserBinding.Entity,
+		},
+	},
+	LastPlaylist: &objectbox.PropertyUint64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     9,
+			Entity: &UserBinding.Entity,
+		},
+	},
+	EmailVerified: &objectbox.PropertyBool{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     10,
+			Entity: &UserBinding.Entity,
+		},
+	},
+	TotpSecret: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     11,
+			Entity: &UserBinding.Entity,
+		},
+	},
+	TotpEnabled: &objectbox.PropertyBool{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     12,
+			Entity: &UserBinding.Entity,
+		},
+	},
+	TotpLastStep: &objectbox.PropertyInt64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     13,
+			Entity: &UserBinding.Entity,
+		},
+	},
+	TotpRecoveryCodes: &objectbox.PropertyStringVector{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     14,
+			Entity: &UserBinding.Entity,
+		},
+	},
+	Roles: &objectbox.PropertyStringVector{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     15,
+			Entity: &UserBinding.Entity,
+		},
+	},
+	Tracks: &objectbox.RelationToMany{
+		Id:     2,
+		Source: &UserBinding.Entity,
+		Target: &TrackBinding.Entity,
+	},
+	Playlists: &objectbox.RelationToMany{
+		Id:     3,
+		Source: &UserBinding.Entity,
+		Target: &PlaylistBinding.Entity,
+	},
+	Friends: &objectbox.RelationToMany{
+		Id:     4,
+		Source: &UserBinding.Entity,
+		Target: &FriendBinding.Entity,
+	},
+	Devices: &objectbox.RelationToMany{
+		Id:     5,
+		Source: &UserBinding.Entity,
+		Target: &DeviceBinding.Entity,
+	},
+}
+
+// GeneratorVersion is called by ObjectBox to verify the compatibility of the generator used to generate this code
+func (user_EntityInfo) GeneratorVersion() int {
+	return 6
+}
+
+// AddToModel is called by ObjectBox during model build
+func (user_EntityInfo) AddToModel(model *objectbox.Model) {
+	model.Entity("User", 4, 4728390412674560454)
+	model.Property("Id", 6, 1, 4588827918934279293)
+	model.PropertyFlags(1)
+	model.Property("Uuid", 9, 2, 5843302712282538112)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(8, 4804161878894786730)
+	model.Property("FirstName", 9, 3, 7789964683614264008)
+	model.Property("LastName", 9, 4, 6952393024719262183)
+	model.Property("EmailAddress", 9, 5, 7450737382308600686)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(9, 2872564972079443293)
+	model.Property("Password", 9, 6, 729577253119581218)
+	model.Property("Enabled", 1, 7, 1652173814307647868)
+	model.Property("AdminUser", 1, 8, 6739946297492405455)
+	model.Property("LastPlaylist", 6, 9, 386897330895652911)
+	model.PropertyFlags(8192)
+	model.Property("EmailVerified", 1, 10, 6011640202380362996)
+	model.Property("TotpSecret", 9, 11, 4067102810211611404)
+	model.Property("TotpEnabled", 1, 12, 7752024718861140875)
+	model.Property("TotpLastStep", 6, 13, 4085993109116033398)
+	model.Property("TotpRecoveryCodes", 30, 14, 3094614748319967253)
+	model.Property("Roles", 30, 15, 2444111804633055227)
+	model.EntityLastPropertyId(15, 2444111804633055227)
+	model.Relation(2, 8897016110600791681, TrackBinding.Id, TrackBinding.Uid)
+	model.Relation(3, 5520690084346431236, PlaylistBinding.Id, PlaylistBinding.Uid)
+	model.Relation(4, 4712361723987089641, FriendBinding.Id, FriendBinding.Uid)
+	model.Relation(5, 7938334410148932394, DeviceBinding.Id, DeviceBinding.Uid)
+}
+
+// GetId is called by ObjectBox during Put operations to check for existing ID on an object
+func (user_EntityInfo) GetId(object interface{}) (uint64, error) {
+	return object.(*User).Id, nil
+}
+
+// SetId is called by ObjectBox during Put to update an ID on an object that has just been inserted
+func (user_EntityInfo) SetId(object interface{}, id uint64) error {
+	object.(*User).Id = id
+	return nil
+}
+
+// PutRelated is called by ObjectBox to put related entities before the object itself is flattened and put
+func (user_EntityInfo) PutRelated(ob *objectbox.ObjectBox, object interface{}, id uint64) error {
+	if err := BoxForUser(ob).RelationReplace(User_.Tracks, id, object, object.(*User).Tracks); err != nil {
+		return err
+	}
+
+	if err := BoxForUser(ob).RelationReplace(User_.Playlists, id, object, object.(*User).Playlists); err != nil {
+		return err
+	}
+
+	if err := BoxForUser(ob).RelationReplace(User_.Friends, id, object, object.(*User).Friends); err != nil {
+		return err
+	}
+
+	if err := BoxForUser(ob).RelationReplace(User_.Devices, id, object, object.(*User).Devices); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Flatten is called by ObjectBox to transform an object to a FlatBuffer
+func (user_EntityInfo) Flatten(object interface{}, fbb *flatbuffers.Builder, id uint64) error {
+	obj := object.(*User)
+	var offsetUuid = fbutils.CreateStringOffset(fbb, obj.Uuid)
+	var offsetFirstName = fbutils.CreateStringOffset(fbb, obj.FirstName)
+	var offsetLastName = fbutils.CreateStringOffset(fbb, obj.LastName)
+	var offsetEmailAddress = fbutils.CreateStringOffset(fbb, obj.EmailAddress)
+	var offsetPassword = fbutils.CreateStringOffset(fbb, obj.Password)
+	var offsetTotpSecret = fbutils.CreateStringOffset(fbb, obj.TotpSecret)
+	var offsetTotpRecoveryCodes = fbutils.CreateStringVectorOffset(fbb, obj.TotpRecoveryCodes)
+	var offsetRoles = fbutils.CreateStringVectorOffset(fbb, obj.Roles)
+
+	// build the FlatBuffers object
+	fbb.StartObject(15)
+	fbutils.SetUint64Slot(fbb, 0, id)
+	fbutils.SetUOffsetTSlot(fbb, 1, offsetUuid)
+	fbutils.SetUOffsetTSlot(fbb, 2, offsetFirstName)
+	fbutils.SetUOffsetTSlot(fbb, 3, offsetLastName)
+	fbutils.SetUOffsetTSlot(fbb, 4, offsetEmailAddress)
+	fbutils.SetUOffsetTSlot(fbb, 5, offsetPassword)
+	fbutils.SetBoolSlot(fbb, 6, obj.Enabled)
+	fbutils.SetBoolSlot(fbb, 9, obj.EmailVerified)
+	fbutils.SetBoolSlot(fbb, 7, obj.AdminUser)
+	fbutils.SetUOffsetTSlot(fbb, 14, offsetRoles)
+	fbutils.SetUOffsetTSlot(fbb, 10, offsetTotpSecret)
+	fbutils.SetBoolSlot(fbb, 11, obj.TotpEnabled)
+	fbutils.SetInt64Slot(fbb, 12, obj.TotpLastStep)
+	fbutils.SetUOffsetTSlot(fbb, 13, offsetTotpRecoveryCodes)
+	fbutils.SetUint64Slot(fbb, 8, obj.LastPlaylist)
+	return nil
+}
+
+// Load is called by ObjectBox to load an object from a FlatBuffer
+func (user_EntityInfo) Load(ob *objectbox.ObjectBox, bytes []byte) (interface{}, error) {
+	if len(bytes) == 0 { // sanity check, should "never" happen
+		return nil, errors.New("can't deserialize an object of type 'User' - no data received")
+	}
+
+	var table = &flatbuffers.Table{
+		Bytes: bytes,
+		Pos:   flatbuffers.GetUOffsetT(bytes),
+	}
+
+	var propId = table.GetUint64Slot(4, 0)
+
+	var relTracks []*Track
+	if rIds, err := BoxForUser(ob).RelationIds(User_.Tracks, propId); err != nil {
+		return nil, err
+	} else if rSlice, err := BoxForTrack(ob).GetManyExisting(rIds...); err != nil {
+		return nil, err
+	} else {
+		relTracks = rSlice
+	}
+
+	var relPlaylists []*Playlist
+	if rIds, err := BoxForUser(ob).RelationIds(User_.Playlists, propId); err != nil {
+		return nil, err
+	} else if rSlice, err := BoxForPlaylist(ob).GetManyExisting(rIds...); err != nil {
+		return nil, err
+	} else {
+		relPlaylists = rSlice
+	}
+
+	var relFriends []*Friend
+	if rIds, err := BoxForUser(ob).RelationIds(User_.Friends, propId); err != nil {
+		return nil, err
+	} else if rSlice, err := BoxForFriend(ob).GetManyExisting(rIds...); err != nil {
+		return nil, err
+	} else {
+		relFriends = rSlice
+	}
+
+	var relDevices []*Device
+	if rIds, err := BoxForUser(ob).RelationIds(User_.Devices, propId); err != nil {
+		return nil, err
+	} else if rSlice, err := BoxForDevice(ob).GetManyExisting(rIds...); err != nil {
+		return nil, err
+	} else {
+		relDevices = rSlice
+	}
+
+	return &User{
+		Id:                propId,
+		Uuid:              fbutils.GetStringSlot(table, 6),
+		FirstName:         fbutils.GetStringSlot(table, 8),
+		LastName:          fbutils.GetStringSlot(table, 10),
+		EmailAddress:      fbutils.GetStringSlot(table, 12),
+		Password:          fbutils.GetStringSlot(table, 14),
+		Enabled:           fbutils.GetBoolSlot(table, 16),
+		EmailVerified:     fbutils.GetBoolSlot(table, 22),
+		AdminUser:         fbutils.GetBoolSlot(table, 18),
+		Roles:             fbutils.GetStringVectorSlot(table, 32),
+		TotpSecret:        fbutils.GetStringSlot(table, 24),
+		TotpEnabled:       fbutils.GetBoolSlot(table, 26),
+		TotpLastStep:      fbutils.GetInt64Slot(table, 28),
+		TotpRecoveryCodes: fbutils.GetStringVectorSlot(table, 30),
+		LastPlaylist:      fbutils.GetUint64Slot(table, 20),
+		Tracks:            relTracks,
+		Playlists:         relPlaylists,
+		Friends:           relFriends,
+		Devices:           relDevices,
+	}, nil
+}
+
+// MakeSlice is called by ObjectBox to construct a new slice to hold the read objects
+func (user_EntityInfo) MakeSlice(capacity int) interface{} {
+	return make([]*User, 0, capacity)
+}
+
+// AppendToSlice is called by ObjectBox to fill the slice of the read objects
+func (user_EntityInfo) AppendToSlice(slice interface{}, object interface{}) interface{} {
+	if object == nil {
+		return append(slice.([]*User), nil)
+	}
+	return append(slice.([]*User), object.(*User))
+}
+
+// Box provides CRUD access to User objects
+type UserBox struct {
+	*objectbox.Box
+}
+
+// BoxForUser opens a box of User objects
+func BoxForUser(ob *objectbox.ObjectBox) *UserBox {
+	return &UserBox{
+		Box: ob.InternalBox(4),
+	}
+}
+
+// Put synchronously inserts/updates a single object.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the User.Id property on the passed object will be assigned the new ID as well.
+func (box *UserBox) Put(object *User) (uint64, error) {
+	return box.Box.Put(object)
+}
+
+// Insert synchronously inserts a single object. As opposed to Put, Insert will fail if given an ID that already exists.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the User.Id property on the passed object will be assigned the new ID as well.
+func (box *UserBox) Insert(object *User) (uint64, error) {
+	return box.Box.Insert(object)
+}
+
+// Update synchronously updates a single object.
+// As opposed to Put, Update will fail if an object with the same ID is not found in the database.
+func (box *UserBox) Update(object *User) error {
+	return box.Box.Update(object)
+}
+
+// PutAsync asynchronously inserts/updates a single object.
+// Deprecated: use box.Async().Put() instead
+func (box *UserBox) PutAsync(object *User) (uint64, error) {
+	return box.Box.PutAsync(object)
+}
+
+// PutMany inserts multiple objects in single transaction.
+// In case Ids are not set on the objects, they would be assigned automatically (auto-increment).
+//
+// Returns: IDs of the put objects (in the same order).
+// When inserting, the User.Id property on the objects in the slice will be assigned the new IDs as well.
+//
+// Note: In case an error occurs during the transaction, some of the objects may already have the User.Id assigned
+// even though the transaction has been rolled back and the objects are not stored under those IDs.
+//
+// Note: The slice may be empty or even nil; in both cases, an empty IDs slice and no error is returned.
+func (box *UserBox) PutMany(objects []*User) ([]uint64, error) {
+	return box.Box.PutMany(objects)
+}
+
+// Get reads a single object.
+//
+// Returns nil (and no error) in case the object with the given ID doesn't exist.
+func (box *UserBox) Get(id uint64) (*User, error) {
+	object, err := box.Box.Get(id)
+	if err != nil {
+		return nil, err
+	} else if object == nil {
+		return nil, nil
+	}
+	return object.(*User), nil
+}
+
+// GetMany reads multiple objects at once.
+// If any of the objects doesn't exist, its position in the return slice is nil
+func (box *UserBox) GetMany(ids ...uint64) ([]*User, error) {
+	objects, err := box.Box.GetMany(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*User), nil
+}
+
+// GetManyExisting reads multiple objects at once, skipping those that do not exist.
+func (box *UserBox) GetManyExisting(ids ...uint64) ([]*User, error) {
+	objects, err := box.Box.GetManyExisting(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*User), nil
+}
+
+// GetAll reads all stored objects
+func (box *UserBox) GetAll() ([]*User, error) {
+	objects, err := box.Box.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*User), nil
+}
+
+// Remove deletes a single object
+func (box *UserBox) Remove(object *User) error {
+	return box.Box.Remove(object)
+}
+
+// RemoveMany deletes multiple objects at once.
+// Returns the number of deleted object or error on failure.
+// Note that this method will not fail if an object is not found (e.g. already removed).
+// In case you need to strictly check whether all of the objects exist before removing them,
+// you can execute multiple box.Contains() and box.Remove() inside a single write transaction.
+func (box *UserBox) RemoveMany(objects ...*User) (uint64, error) {
+	var ids = make([]uint64, len(objects))
+	for k, object := range objects {
+		ids[k] = object.Id
+	}
+	return box.Box.RemoveIds(ids...)
+}
+
+// Creates a query with the given conditions. Use the fields of the User_ struct to create conditions.
+// Keep the *UserQuery if you intend to execute the query multiple times.
+// Note: this function panics if you try to create illegal queries; e.g. use properties of an alien type.
+// This is typically a programming error. Use QueryOrError instead if you want the explicit error check.
+func (box *UserBox) Query(conditions ...objectbox.Condition) *UserQuery {
+	return &UserQuery{
+		box.Box.Query(conditions...),
+	}
+}
+
+// Creates a query with the given conditions. Use the fields of the User_ struct to create conditions.
+// Keep the *UserQuery if you intend to execute the query multiple times.
+func (box *UserBox) QueryOrError(conditions ...objectbox.Condition) (*UserQuery, error) {
+	if query, err := box.Box.QueryOrError(conditions...); err != nil {
+		return nil, err
+	} else {
+		return &UserQuery{query}, nil
+	}
+}
+
+// Async provides access to the default Async Box for asynchronous operations. See UserAsyncBox for more information.
+func (box *UserBox) Async() *UserAsyncBox {
+	return &UserAsyncBox{AsyncBox: box.Box.Async()}
+}
+
+// UserAsyncBox provides asynchronous operations on User objects.
+//
+// Asynchronous operations are executed on a separate internal thread for better performance.
+//
+// There are two main use cases:
+//
+// 1) "execute & forget:" you gain faster put/remove operations as you don't have to wait for the transaction to finish.
+//
+// 2) Many small transactions: if your write load is typically a lot of individual puts that happen in parallel,
+// this will merge small transactions into bigger ones. This results in a significant gain in overall throughput.
+//
+// In situations with (extremely) high async load, an async method may be throttled (~1ms) or delayed up to 1 second.
+// In the unlikely event that the object could still not be enqueued (full queue), an error will be returned.
+//
+// Note that async methods do not give you hard durability guarantees like the synchronous Box provides.
+// There is a small time window in which the data may not have been committed durably yet.
+type UserAsyncBox struct {
+	*objectbox.AsyncBox
+}
+
+// AsyncBoxForUser creates a new async box with the given operation timeout in case an async queue is full.
+// The returned struct must be freed explicitly using the Close() method.
+// It's usually preferable to use UserBox::Async() which takes care of resource management and doesn't require closing.
+func AsyncBoxForUser(ob *objectbox.ObjectBox, timeoutMs uint64) *UserAsyncBox {
+	var async, err = objectbox.NewAsyncBox(ob, 4, timeoutMs)
+	if err != nil {
+		panic("Could not create async box for entity ID 4: %s" + err.Error())
+	}
+	return &UserAsyncBox{AsyncBox: async}
+}
+
+// Put inserts/updates a single object asynchronously.
+// When inserting a new object, the Id property on the passed object will be assigned the new ID the entity would hold
+// if the insert is ultimately successful. The newly assigned ID may not become valid if the insert fails.
+func (asyncBox *UserAsyncBox) Put(object *User) (uint64, error) {
+	return asyncBox.AsyncBox.Put(object)
+}
+
+// Insert a single object asynchronously.
+// The Id property on the passed object will be assigned the new ID the entity would hold if the insert is ultimately
+// successful. The newly assigned ID may not become valid if the insert fails.
+// Fails silently if an object with the same ID already exists (this error is not returned).
+func (asyncBox *UserAsyncBox) Insert(object *User) (id uint64, err error) {
+	return asyncBox.AsyncBox.Insert(object)
+}
+
+// Update a single object asynchronously.
+// The object must already exists or the update fails silently (without an error returned).
+func (asyncBox *UserAsyncBox) Update(object *User) error {
+	return asyncBox.AsyncBox.Update(object)
+}
+
+// Remove deletes a single object asynchronously.
+func (asyncBox *UserAsyncBox) Remove(object *User) error {
+	return asyncBox.AsyncBox.Remove(object)
+}
+
+// Query provides a way to search stored objects
+//
+// For example, you can find all User which Id is either 42 or 47:
+//
+//	box.Query(User_.Id.In(42, 47)).Find()
+type UserQuery struct {
+	*objectbox.Query
+}
+
+// Find returns all objects matching the query
+func (query *UserQuery) Find() ([]*User, error) {
+	objects, err := query.Query.Find()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*User), nil
+}
+
+// Offset defines the index of the first object to process (how many objects to skip)
+func (query *UserQuery) Offset(offset uint64) *UserQuery {
+	query.Query.Offset(offset)
+	return query
+}
+
+// Limit sets the number of elements to process by the query
+func (query *UserQuery) Limit(limit uint64) *UserQuery {
+	query.Query.Limit(limit)
+	return query
+}
+
+type device_EntityInfo struct {
+	objectbox.Entity
+	Uid uint64
+}
+
+var DeviceBinding = device_EntityInfo{
+	Entity: objectbox.Entity{
+		Id: 5,
+	},
+	Uid: 8639849269344428237,
+}
+
+// Device_ contains type-based Property helpers to facilitate some common operations such as Queries.
+var Device_ = struct {
+	Id                     *objectbox.PropertyUint64
+	Uuid                   *objectbox.PropertyString
+	Name                   *objectbox.PropertyString
+	Type                   *objectbox.PropertyString
+	LastSeen               *objectbox.PropertyInt64
+	LastTokenId            *objectbox.PropertyString
+	RefreshTokenHash       *objectbox.PropertyString
+	RefreshTokenExpires    *objectbox.PropertyInt64
+	Scopes                 *objectbox.PropertyStringVector
+	RefreshTokenFamily     *objectbox.PropertyString
+	RefreshTokenGeneration *objectbox.PropertyUint64
+}{
+	Id: &objectbox.PropertyUint64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     1,
+			Entity: &DeviceBinding.Entity,
+		},
+	},
+	Uuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     2,
+			Entity: &DeviceBinding.Entity,
+		},
+	},
+	Name: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     3,
+			Entity: &DeviceBinding.Entity,
+		},
+	},
+	Type: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     4,
+			Entity: &DeviceBinding.Entity,
+		},
+	},
+	LastSeen: &objectbox.PropertyInt64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     5,
+			Entity: &DeviceBinding.Entity,
+		},
+	},
+	LastTokenId: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     6,
+			Entity: &DeviceBinding.Entity,
+		},
+	},
+	RefreshTokenHash: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     7,
+			Entity: &DeviceBinding.Entity,
+		},
+	},
+	RefreshTokenExpires: &objectbox.PropertyInt64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     8,
+			Entity: &DeviceBinding.Entity,
+		},
+	},
+	Scopes: &objectbox.PropertyStringVector{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     9,
+			Entity: &DeviceBinding.Entity,
+		},
+	},
+	RefreshTokenFamily: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     11,
+			Entity: &DeviceBinding.Entity,
+		},
+	},
+	RefreshTokenGeneration: &objectbox.PropertyUint64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     12,
+			Entity: &DeviceBinding.Entity,
+		},
+	},
+}
+
+// GeneratorVersion is called by ObjectBox to verify the compatibility of the generator used to generate this code
+func (device_EntityInfo) GeneratorVersion() int {
+	return 6
+}
+
+// AddToModel is called by ObjectBox during model build
+func (device_EntityInfo) AddToModel(model *objectbox.Model) {
+	model.Entity("Device", 5, 8639849269344428237)
+	model.Property("Id", 6, 1, 3504019990367262477)
+	model.PropertyFlags(1)
+	model.Property("Uuid", 9, 2, 8262384626904268783)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(10, 3808854623145374884)
+	model.Property("Name", 9, 3, 4842703486022894190)
+	model.Property("Type", 9, 4, 8199439234169547527)
+	model.Property("LastSeen", 6, 5, 6592164034321043669)
+	model.Property("LastTokenId", 9, 6, 1386777584671385201)
+	model.Property("RefreshTokenHash", 9, 7, 422163114027615571)
+	model.Property("RefreshTokenExpires", 6, 8, 3656265181759051786)
+	model.Property("Scopes", 30, 9, 1762726394986823850)
+	model.Property("RefreshTokenFamily", 9, 11, 5457580730297514762)
+	model.Property("RefreshTokenGeneration", 6, 12, 7414101516954546110)
+	model.PropertyFlags(8192)
+	model.EntityLastPropertyId(12, 7414101516954546110)
+}
+
+// GetId is called by ObjectBox during Put operations to check for existing ID on an object
+func (device_EntityInfo) GetId(object interface{}) (uint64, error) {
+	return object.(*Device).Id, nil
+}
+
+// SetId is called by ObjectBox during Put to update an ID on an object that has just been inserted
+func (device_EntityInfo) SetId(object interface{}, id uint64) error {
+	object.(*Device).Id = id
+	return nil
+}
+
+// PutRelated is called by ObjectBox to put related entities before the object itself is flattened and put
+func (device_EntityInfo) PutRelated(ob *objectbox.ObjectBox, object interface{}, id uint64) error {
+	return nil
+}
+
+// Flatten is called by ObjectBox to transform an object to a FlatBuffer
+func (device_EntityInfo) Flatten(object interface{}, fbb *flatbuffers.Builder, id uint64) error {
+	obj := object.(*Device)
+	var offsetUuid = fbutils.CreateStringOffset(fbb, obj.Uuid)
+	var offsetName = fbutils.CreateStringOffset(fbb, obj.Name)
+	var offsetType = fbutils.CreateStringOffset(fbb, obj.Type)
+	var offsetLastTokenId = fbutils.CreateStringOffset(fbb, obj.LastTokenId)
+	var offsetRefreshTokenHash = fbutils.CreateStringOffset(fbb, obj.RefreshTokenHash)
+	var offsetScopes = fbutils.CreateStringVectorOffset(fbb, obj.Scopes)
+	var offsetRefreshTokenFamily = fbutils.CreateStringOffset(fbb, obj.RefreshTokenFamily)
+
+	// build the FlatBuffers object
+	fbb.StartObject(12)
+	fbutils.SetUint64Slot(fbb, 0, id)
+	fbutils.SetUOffsetTSlot(fbb, 1, offsetUuid)
+	fbutils.SetUOffsetTSlot(fbb, 2, offsetName)
+	fbutils.SetUOffsetTSlot(fbb, 3, offsetType)
+	fbutils.SetInt64Slot(fbb, 4, obj.LastSeen)
+	fbutils.SetUOffsetTSlot(fbb, 5, offsetLastTokenId)
+	fbutils.SetUOffsetTSlot(fbb, 6, offsetRefreshTokenHash)
+	fbutils.SetInt64Slot(fbb, 7, obj.RefreshTokenExpires)
+	fbutils.SetUOffsetTSlot(fbb, 8, offsetScopes)
+	fbutils.SetUOffsetTSlot(fbb, 10, offsetRefreshTokenFamily)
+	fbutils.SetUint64Slot(fbb, 11, obj.RefreshTokenGeneration)
+	return nil
+}
+
+// Load is called by ObjectBox to load an object from a FlatBuffer
+func (device_EntityInfo) Load(ob *objectbox.ObjectBox, bytes []byte) (interface{}, error) {
+	if len(bytes) == 0 { // sanity check, should "never" happen
+		return nil, errors.New("can't deserialize an object of type 'Device' - no data received")
+	}
+
+	var table = &flatbuffers.Table{
+		Bytes: bytes,
+		Pos:   flatbuffers.GetUOffsetT(bytes),
+	}
+
+	var propId = table.GetUint64Slot(4, 0)
+
+	return &Device{
+		Id:                     propId,
+		Uuid:                   fbutils.GetStringSlot(table, 6),
+		Name:                   fbutils.GetStringSlot(table, 8),
+		Type:                   fbutils.GetStringSlot(table, 10),
+		LastSeen:               fbutils.GetInt64Slot(table, 12),
+		LastTokenId:            fbutils.GetStringSlot(table, 14),
+		RefreshTokenHash:       fbutils.GetStringSlot(table, 16),
+		RefreshTokenExpires:    fbutils.GetInt64Slot(table, 18),
+		Scopes:                 fbutils.GetStringVectorSlot(table, 20),
+		RefreshTokenFamily:     fbutils.GetStringSlot(table, 24),
+		RefreshTokenGeneration: fbutils.GetUint64Slot(table, 26),
+	}, nil
+}
+
+// MakeSlice is called by ObjectBox to construct a new slice to hold the read objects
+func (device_EntityInfo) MakeSlice(capacity int) interface{} {
+	return make([]*Device, 0, capacity)
+}
+
+// AppendToSlice is called by ObjectBox to fill the slice of the read objects
+func (device_EntityInfo) AppendToSlice(slice interface{}, object interface{}) interface{} {
+	if object == nil {
+		return append(slice.([]*Device), nil)
+	}
+	return append(slice.([]*Device), object.(*Device))
+}
+
+// Box provides CRUD access to Device objects
+type DeviceBox struct {
+	*objectbox.Box
+}
+
+// BoxForDevice opens a box of Device objects
+func BoxForDevice(ob *objectbox.ObjectBox) *DeviceBox {
+	return &DeviceBox{
+		Box: ob.InternalBox(5),
+	}
+}
+
+// Put synchronously inserts/updates a single object.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the Device.Id property on the passed object will be assigned the new ID as well.
+func (box *DeviceBox) Put(object *Device) (uint64, error) {
+	return box.Box.Put(object)
+}
+
+// Insert synchronously inserts a single object. As opposed to Put, Insert will fail if given an ID that already exists.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the Device.Id property on the passed object will be assigned the new ID as well.
+func (box *DeviceBox) Insert(object *Device) (uint64, error) {
+	return box.Box.Insert(object)
+}
+
+// Update synchronously updates a single object.
+// As opposed to Put, Update will fail if an object with the same ID is not found in the database.
+func (box *DeviceBox) Update(object *Device) error {
+	return box.Box.Update(object)
+}
+
+// PutAsync asynchronously inserts/updates a single object.
+// Deprecated: use box.Async().Put() instead
+func (box *DeviceBox) PutAsync(object *Device) (uint64, error) {
+	return box.Box.PutAsync(object)
+}
+
+// PutMany inserts multiple objects in single transaction.
+// In case Ids are not set on the objects, they would be assigned automatically (auto-increment).
+//
+// Returns: IDs of the put objects (in the same order).
+// When inserting, the Device.Id property on the objects in the slice will be assigned the new IDs as well.
+//
+// Note: In case an error occurs during the transaction, some of the objects may already have the Device.Id assigned
+// even though the transaction has been rolled back and the objects are not stored under those IDs.
+//
+// Note: The slice may be empty or even nil; in both cases, an empty IDs slice and no error is returned.
+func (box *DeviceBox) PutMany(objects []*Device) ([]uint64, error) {
+	return box.Box.PutMany(objects)
+}
+
+// Get reads a single object.
+//
+// Returns nil (and no error) in case the object with the given ID doesn't exist.
+func (box *DeviceBox) Get(id uint64) (*Device, error) {
+	object, err := box.Box.Get(id)
+	if err != nil {
+		return nil, err
+	} else if object == nil {
+		return nil, nil
+	}
+	return object.(*Device), nil
+}
+
+// GetMany reads multiple objects at once.
+// If any of the objects doesn't exist, its position in the return slice is nil
+func (box *DeviceBox) GetMany(ids ...uint64) ([]*Device, error) {
+	objects, err := box.Box.GetMany(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Device), nil
+}
+
+// GetManyExisting reads multiple objects at once, skipping those that do not exist.
+func (box *DeviceBox) GetManyExisting(ids ...uint64) ([]*Device, error) {
+	objects, err := box.Box.GetManyExisting(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Device), nil
+}
+
+// GetAll reads all stored objects
+func (box *DeviceBox) GetAll() ([]*Device, error) {
+	objects, err := box.Box.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Device), nil
+}
+
+// Remove deletes a single object
+func (box *DeviceBox) Remove(object *Device) error {
+	return box.Box.Remove(object)
+}
+
+// RemoveMany deletes multiple objects at once.
+// Returns the number of deleted object or error on failure.
+// Note that this method will not fail if an object is not found (e.g. already removed).
+// In case you need to strictly check whether all of the objects exist before removing them,
+// you can execute multiple box.Contains() and box.Remove() inside a single write transaction.
+func (box *DeviceBox) RemoveMany(objects ...*Device) (uint64, error) {
+	var ids = make([]uint64, len(objects))
+	for k, object := range objects {
+		ids[k] = object.Id
+	}
+	return box.Box.RemoveIds(ids...)
+}
+
+// Creates a query with the given conditions. Use the fields of the Device_ struct to create conditions.
+// Keep the *DeviceQuery if you intend to execute the query multiple times.
+// Note: this function panics if you try to create illegal queries; e.g. use properties of an alien type.
+// This is typically a programming error. Use QueryOrError instead if you want the explicit error check.
+func (box *DeviceBox) Query(conditions ...objectbox.Condition) *DeviceQuery {
+	return &DeviceQuery{
+		box.Box.Query(conditions...),
+	}
+}
+
+// Creates a query with the given conditions. Use the fields of the Device_ struct to create conditions.
+// Keep the *DeviceQuery if you intend to execute the query multiple times.
+func (box *DeviceBox) QueryOrError(conditions ...objectbox.Condition) (*DeviceQuery, error) {
+	if query, err := box.Box.QueryOrError(conditions...); err != nil {
+		return nil, err
+	} else {
+		return &DeviceQuery{query}, nil
+	}
+}
+
+// Async provides access to the default Async Box for asynchronous operations. See DeviceAsyncBox for more information.
+func (box *DeviceBox) Async() *DeviceAsyncBox {
+	return &DeviceAsyncBox{AsyncBox: box.Box.Async()}
+}
+
+// DeviceAsyncBox provides asynchronous operations on Device objects.
+//
+// Asynchronous operations are executed on a separate internal thread for better performance.
+//
+// There are two main use cases:
+//
+// 1) "execute & forget:" you gain faster put/remove operations as you don't have to wait for the transaction to finish.
+//
+// 2) Many small transactions: if your write load is typically a lot of individual puts that happen in parallel,
+// this will merge small transactions into bigger ones. This results in a significant gain in overall throughput.
+//
+// In situations with (extremely) high async load, an async method may be throttled (~1ms) or delayed up to 1 second.
+// In the unlikely event that the object could still not be enqueued (full queue), an error will be returned.
+//
+// Note that async methods do not give you hard durability guarantees like the synchronous Box provides.
+// There is a small time window in which the data may not have been committed durably yet.
+type DeviceAsyncBox struct {
+	*objectbox.AsyncBox
+}
+
+// AsyncBoxForDevice creates a new async box with the given operation timeout in case an async queue is full.
+// The returned struct must be freed explicitly using the Close() method.
+// It's usually preferable to use DeviceBox::Async() which takes care of resource management and doesn't require closing.
+func AsyncBoxForDevice(ob *objectbox.ObjectBox, timeoutMs uint64) *DeviceAsyncBox {
+	var async, err = objectbox.NewAsyncBox(ob, 5, timeoutMs)
+	if err != nil {
+		panic("Could not create async box for entity ID 5: %s" + err.Error())
+	}
+	return &DeviceAsyncBox{AsyncBox: async}
+}
+
+// Put inserts/updates a single object asynchronously.
+// When inserting a new object, the Id property on the passed object will be assigned the new ID the entity would hold
+// if the insert is ultimately successful. The newly assigned ID may not become valid if the insert fails.
+func (asyncBox *DeviceAsyncBox) Put(object *Device) (uint64, error) {
+	return asyncBox.AsyncBox.Put(object)
+}
+
+// Insert a single object asynchronously.
+// The Id property on the passed object will be assigned the new ID the entity would hold if the insert is ultimately
+// successful. The newly assigned ID may not become valid if the insert fails.
+// Fails silently if an object with the same ID already exists (this error is not returned).
+func (asyncBox *DeviceAsyncBox) Insert(object *Device) (id uint64, err error) {
+	return asyncBox.AsyncBox.Insert(object)
+}
+
+// Update a single object asynchronously.
+// The object must already exists or the update fails silently (without an error returned).
+func (asyncBox *DeviceAsyncBox) Update(object *Device) error {
+	return asyncBox.AsyncBox.Update(object)
+}
+
+// Remove deletes a single object asynchronously.
+func (asyncBox *DeviceAsyncBox) Remove(object *Device) error {
+	return asyncBox.AsyncBox.Remove(object)
+}
+
+// Query provides a way to search stored objects
+//
+// For example, you can find all Device which Id is either 42 or 47:
+//
+//	box.Query(Device_.Id.In(42, 47)).Find()
+type DeviceQuery struct {
+	*objectbox.Query
+}
+
+// Find returns all objects matching the query
+func (query *DeviceQuery) Find() ([]*Device, error) {
+	objects, err := query.Query.Find()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Device), nil
+}
+
+// Offset defines the index of the first object to process (how many objects to skip)
+func (query *DeviceQuery) Offset(offset uint64) *DeviceQuery {
+	query.Query.Offset(offset)
+	return query
+}
+
+// Limit sets the number of elements to process by the query
+func (query *DeviceQuery) Limit(limit uint64) *DeviceQuery {
+	query.Query.Limit(limit)
+	return query
+}
+
+type resumePosition_EntityInfo struct {
+	objectbox.Entity
+	Uid uint64
+}
+
+var ResumePositionBinding = resumePosition_EntityInfo{
+	Entity: objectbox.Entity{
+		Id: 6,
+	},
+	Uid: 8766801366638148412,
+}
+
+// ResumePosition_ contains type-based Property helpers to facilitate some common operations such as Queries.
+var ResumePosition_ = struct {
+	Id           *objectbox.PropertyUint64
+	UserUuid     *objectbox.PropertyString
+	TrackUuid    *objectbox.PropertyString
+	PlaylistUuid *objectbox.PropertyString
+	DeviceUuid   *objectbox.PropertyString
+	Elapsed      *objectbox.PropertyInt
+	UpdatedAt    *objectbox.PropertyInt64
+}{
+	Id: &objectbox.PropertyUint64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     1,
+			Entity: &ResumePositionBinding.Entity,
+		},
+	},
+	UserUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     2,
+			Entity: &ResumePositionBinding.Entity,
+		},
+	},
+	TrackUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     3,
+			Entity: &ResumePositionBinding.Entity,
+		},
+	},
+	PlaylistUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     4,
+			Entity: &ResumePositionBinding.Entity,
+		},
+	},
+	DeviceUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     5,
+			Entity: &ResumePositionBinding.Entity,
+		},
+	},
+	Elapsed: &objectbox.PropertyInt{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     6,
+			Entity: &ResumePositionBinding.Entity,
+		},
+	},
+	UpdatedAt: &objectbox.PropertyInt64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     7,
+			Entity: &ResumePositionBinding.Entity,
+		},
+	},
+}
+
+// GeneratorVersion is called by ObjectBox to verify the compatibility of the generator used to generate this code
+func (resumePosition_EntityInfo) GeneratorVersion() int {
+	return 6
+}
+
+// AddToModel is called by ObjectBox during model build
+func (resumePosition_EntityInfo) AddToModel(model *objectbox.Model) {
+	model.Entity("ResumePosition", 6, 8766801366638148412)
+	model.Property("Id", 6, 1, 6120464664252967013)
+	model.PropertyFlags(1)
+	model.Property("UserUuid", 9, 2, 2235962977017379989)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(11, 9072205202462282960)
+	model.Property("TrackUuid", 9, 3, 4367939057911573651)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(12, 3072140408693865602)
+	model.Property("PlaylistUuid", 9, 4, 8908760279301174377)
+	model.Property("DeviceUuid", 9, 5, 6586483851390497318)
+	model.Property("Elapsed", 6, 6, 3867234186648548727)
+	model.Property("UpdatedAt", 6, 7, 2259941467783547089)
+	model.EntityLastPropertyId(7, 2259941467783547089)
+}
+
+// GetId is called by ObjectBox during Put operations to check for existing ID on an object
+func (resumePosition_EntityInfo) GetId(object interface{}) (uint64, error) {
+	return object.(*ResumePosition).Id, nil
+}
+
+// SetId is called by ObjectBox during Put to update an ID on an object that has just been inserted
+func (resumePosition_EntityInfo) SetId(object interface{}, id uint64) error {
+	object.(*ResumePosition).Id = id
+	return nil
+}
+
+// PutRelated is called by ObjectBox to put related entities before the object itself is flattened and put
+func (resumePosition_EntityInfo) PutRelated(ob *objectbox.ObjectBox, object interface{}, id uint64) error {
+	return nil
+}
+
+// Flatten is called by ObjectBox to transform an object to a FlatBuffer
+func (resumePosition_EntityInfo) Flatten(object interface{}, fbb *flatbuffers.Builder, id uint64) error {
+	obj := object.(*ResumePosition)
+	var offsetUserUuid = fbutils.CreateStringOffset(fbb, obj.UserUuid)
+	var offsetTrackUuid = fbutils.CreateStringOffset(fbb, obj.TrackUuid)
+	var offsetPlaylistUuid = fbutils.CreateStringOffset(fbb, obj.PlaylistUuid)
+	var offsetDeviceUuid = fbutils.CreateStringOffset(fbb, obj.DeviceUuid)
+
+	// build the FlatBuffers object
+	fbb.StartObject(7)
+	fbutils.SetUint64Slot(fbb, 0, id)
+	fbutils.SetUOffsetTSlot(fbb, 1, offsetUserUuid)
+	fbutils.SetUOffsetTSlot(fbb, 2, offsetTrackUuid)
+	fbutils.SetUOffsetTSlot(fbb, 3, offsetPlaylistUuid)
+	fbutils.SetUOffsetTSlot(fbb, 4, offsetDeviceUuid)
+	fbutils.SetInt64Slot(fbb, 5, int64(obj.Elapsed))
+	fbutils.SetInt64Slot(fbb, 6, obj.UpdatedAt)
+	return nil
+}
+
+// Load is called by ObjectBox to load an object from a FlatBuffer
+func (resumePosition_EntityInfo) Load(ob *objectbox.ObjectBox, bytes []byte) (interface{}, error) {
+	if len(bytes) == 0 { // sanity check, should "never" happen
+		return nil, errors.New("can't deserialize an object of type 'ResumePosition' - no data received")
+	}
+
+	var table = &flatbuffers.Table{
+		Bytes: bytes,
+		Pos:   flatbuffers.GetUOffsetT(bytes),
+	}
+
+	var propId = table.GetUint64Slot(4, 0)
+
+	return &ResumePosition{
+		Id:           propId,
+		UserUuid:     fbutils.GetStringSlot(table, 6),
+		TrackUuid:    fbutils.GetStringSlot(table, 8),
+		PlaylistUuid: fbutils.GetStringSlot(table, 10),
+		DeviceUuid:   fbutils.GetStringSlot(table, 12),
+		Elapsed:      fbutils.GetIntSlot(table, 14),
+		UpdatedAt:    fbutils.GetInt64Slot(table, 16),
+	}, nil
+}
+
+// MakeSlice is called by ObjectBox to construct a new slice to hold the read objects
+func (resumePosition_EntityInfo) MakeSlice(capacity int) interface{} {
+	return make([]*ResumePosition, 0, capacity)
+}
+
+// AppendToSlice is called by ObjectBox to fill the slice of the read objects
+func (resumePosition_EntityInfo) AppendToSlice(slice interface{}, object interface{}) interface{} {
+	if object == nil {
+		return append(slice.([]*ResumePosition), nil)
+	}
+	return append(slice.([]*ResumePosition), object.(*ResumePosition))
+}
+
+// Box provides CRUD access to ResumePosition objects
+type ResumePositionBox struct {
+	*objectbox.Box
+}
+
+// BoxForResumePosition opens a box of ResumePosition objects
+func BoxForResumePosition(ob *objectbox.ObjectBox) *ResumePositionBox {
+	return &ResumePositionBox{
+		Box: ob.InternalBox(6),
+	}
+}
+
+// Put synchronously inserts/updates a single object.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the ResumePosition.Id property on the passed object will be assigned the new ID as well.
+func (box *ResumePositionBox) Put(object *ResumePosition) (uint64, error) {
+	return box.Box.Put(object)
+}
+
+// Insert synchronously inserts a single object. As opposed to Put, Insert will fail if given an ID that already exists.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the ResumePosition.Id property on the passed object will be assigned the new ID as well.
+func (box *ResumePositionBox) Insert(object *ResumePosition) (uint64, error) {
+	return box.Box.Insert(object)
+}
+
+// Update synchronously updates a single object.
+// As opposed to Put, Update will fail if an object with the same ID is not found in the database.
+func (box *ResumePositionBox) Update(object *ResumePosition) error {
+	return box.Box.Update(object)
+}
+
+// PutAsync asynchronously inserts/updates a single object.
+// Deprecated: use box.Async().Put() instead
+func (box *ResumePositionBox) PutAsync(object *ResumePosition) (uint64, error) {
+	return box.Box.PutAsync(object)
+}
+
+// PutMany inserts multiple objects in single transaction.
+// In case Ids are not set on the objects, they would be assigned automatically (auto-increment).
+//
+// Returns: IDs of the put objects (in the same order).
+// When inserting, the ResumePosition.Id property on the objects in the slice will be assigned the new IDs as well.
+//
+// Note: In case an error occurs during the transaction, some of the objects may already have the ResumePosition.Id assigned
+// even though the transaction has been rolled back and the objects are not stored under those IDs.
+//
+// Note: The slice may be empty or even nil; in both cases, an empty IDs slice and no error is returned.
+func (box *ResumePositionBox) PutMany(objects []*ResumePosition) ([]uint64, error) {
+	return box.Box.PutMany(objects)
+}
+
+// Get reads a single object.
+//
+// Returns nil (and no error) in case the object with the given ID doesn't exist.
+func (box *ResumePositionBox) Get(id uint64) (*ResumePosition, error) {
+	object, err := box.Box.Get(id)
+	if err != nil {
+		return nil, err
+	} else if object == nil {
+		return nil, nil
+	}
+	return object.(*ResumePosition), nil
+}
+
+// GetMany reads multiple objects at once.
+// If any of the objects doesn't exist, its position in the return slice is nil
+func (box *ResumePositionBox) GetMany(ids ...uint64) ([]*ResumePosition, error) {
+	objects, err := box.Box.GetMany(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*ResumePosition), nil
+}
+
+// GetManyExisting reads multiple objects at once, skipping those that do not exist.
+func (box *ResumePositionBox) GetManyExisting(ids ...uint64) ([]*ResumePosition, error) {
+	objects, err := box.Box.GetManyExisting(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*ResumePosition), nil
+}
+
+// GetAll reads all stored objects
+func (box *ResumePositionBox) GetAll() ([]*ResumePosition, error) {
+	objects, err := box.Box.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*ResumePosition), nil
+}
+
+// Remove deletes a single object
+func (box *ResumePositionBox) Remove(object *ResumePosition) error {
+	return box.Box.Remove(object)
+}
+
+// RemoveMany deletes multiple objects at once.
+// Returns the number of deleted object or error on failure.
+// Note that this method will not fail if an object is not found (e.g. already removed).
+// In case you need to strictly check whether all of the objects exist before removing them,
+// you can execute multiple box.Contains() and box.Remove() inside a single write transaction.
+func (box *ResumePositionBox) RemoveMany(objects ...*ResumePosition) (uint64, error) {
+	var ids = make([]uint64, len(objects))
+	for k, object := range objects {
+		ids[k] = object.Id
+	}
+	return box.Box.RemoveIds(ids...)
+}
+
+// Creates a query with the given conditions. Use the fields of the ResumePosition_ struct to create conditions.
+// Keep the *ResumePositionQuery if you intend to execute the query multiple times.
+// Note: this function panics if you try to create illegal queries; e.g. use properties of an alien type.
+// This is typically a programming error. Use QueryOrError instead if you want the explicit error check.
+func (box *ResumePositionBox) Query(conditions ...objectbox.Condition) *ResumePositionQuery {
+	return &ResumePositionQuery{
+		box.Box.Query(conditions...),
+	}
+}
+
+// Creates a query with the given conditions. Use the fields of the ResumePosition_ struct to create conditions.
+// Keep the *ResumePositionQuery if you intend to execute the query multiple times.
+func (box *ResumePositionBox) QueryOrError(conditions ...objectbox.Condition) (*ResumePositionQuery, error) {
+	if query, err := box.Box.QueryOrError(conditions...); err != nil {
+		return nil, err
+	} else {
+		return &ResumePositionQuery{query}, nil
+	}
+}
+
+// Async provides access to the default Async Box for asynchronous operations. See ResumePositionAsyncBox for more information.
+func (box *ResumePositionBox) Async() *ResumePositionAsyncBox {
+	return &ResumePositionAsyncBox{AsyncBox: box.Box.Async()}
+}
+
+// ResumePositionAsyncBox provides asynchronous operations on ResumePosition objects.
+//
+// Asynchronous operations are executed on a separate internal thread for better performance.
+//
+// There are two main use cases:
+//
+// 1) "execute & forget:" you gain faster put/remove operations as you don't have to wait for the transaction to finish.
+//
+// 2) Many small transactions: if your write load is typically a lot of individual puts that happen in parallel,
+// this will merge small transactions into bigger ones. This results in a significant gain in overall throughput.
+//
+// In situations with (extremely) high async load, an async method may be throttled (~1ms) or delayed up to 1 second.
+// In the unlikely event that the object could still not be enqueued (full queue), an error will be returned.
+//
+// Note that async methods do not give you hard durability guarantees like the synchronous Box provides.
+// There is a small time window in which the data may not have been committed durably yet.
+type ResumePositionAsyncBox struct {
+	*objectbox.AsyncBox
+}
+
+// AsyncBoxForResumePosition creates a new async box with the given operation timeout in case an async queue is full.
+// The returned struct must be freed explicitly using the Close() method.
+// It's usually preferable to use ResumePositionBox::Async() which takes care of resource management and doesn't require closing.
+func AsyncBoxForResumePosition(ob *objectbox.ObjectBox, timeoutMs uint64) *ResumePositionAsyncBox {
+	var async, err = objectbox.NewAsyncBox(ob, 6, timeoutMs)
+	if err != nil {
+		panic("Could not create async box for entity ID 6: %s" + err.Error())
+	}
+	return &ResumePositionAsyncBox{AsyncBox: async}
+}
+
+// Put inserts/updates a single object asynchronously.
+// When inserting a new object, the Id property on the passed object will be assigned the new ID the entity would hold
+// if the insert is ultimately successful. The newly assigned ID may not become valid if the insert fails.
+func (asyncBox *ResumePositionAsyncBox) Put(object *ResumePosition) (uint64, error) {
+	return asyncBox.AsyncBox.Put(object)
+}
+
+// Insert a single object asynchronously.
+// The Id property on the passed object will be assigned the new ID the entity would hold if the insert is ultimately
+// successful. The newly assigned ID may not become valid if the insert fails.
+// Fails silently if an object with the same ID already exists (this error is not returned).
+func (asyncBox *ResumePositionAsyncBox) Insert(object *ResumePosition) (id uint64, err error) {
+	return asyncBox.AsyncBox.Insert(object)
+}
+
+// Update a single object asynchronously.
+// The object must already exists or the update fails silently (without an error returned).
+func (asyncBox *ResumePositionAsyncBox) Update(object *ResumePosition) error {
+	return asyncBox.AsyncBox.Update(object)
+}
+
+// Remove deletes a single object asynchronously.
+func (asyncBox *ResumePositionAsyncBox) Remove(object *ResumePosition) error {
+	return asyncBox.AsyncBox.Remove(object)
+}
+
+// Query provides a way to search stored objects
+//
+// For example, you can find all ResumePosition which Id is either 42 or 47:
+//
+//	box.Query(ResumePosition_.Id.In(42, 47)).Find()
+type ResumePositionQuery struct {
+	*objectbox.Query
+}
+
+// Find returns all objects matching the query
+func (query *ResumePositionQuery) Find() ([]*ResumePosition, error) {
+	objects, err := query.Query.Find()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*ResumePosition), nil
+}
+
+// Offset defines the index of the first object to process (how many objects to skip)
+func (query *ResumePositionQuery) Offset(offset uint64) *ResumePositionQuery {
+	query.Query.Offset(offset)
+	return query
+}
+
+// Limit sets the number of elements to process by the query
+func (query *ResumePositionQuery) Limit(limit uint64) *ResumePositionQuery {
+	query.Query.Limit(limit)
+	return query
+}
+
+type play_EntityInfo struct {
+	objectbox.Entity
+	Uid uint64
+}
+
+var PlayBinding = play_EntityInfo{
+	Entity: objectbox.Entity{
+		Id: 7,
+	},
+	Uid: 8889788259202642179,
+}
+
+// Play_ contains type-based Property helpers to facilitate some common operations such as Queries.
+var Play_ = struct {
+	Id           *objectbox.PropertyUint64
+	UserUuid     *objectbox.PropertyString
+	TrackUuid    *objectbox.PropertyString
+	PlaylistUuid *objectbox.PropertyString
+	DeviceUuid   *objectbox.PropertyString
+	StartOffset  *objectbox.PropertyInt
+	EndOffset    *objectbox.PropertyInt
+	StartedAt    *objectbox.PropertyInt64
+	EndedAt      *objectbox.PropertyInt64
+}{
+	Id: &objectbox.PropertyUint64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     1,
+			Entity: &PlayBinding.Entity,
+		},
+	},
+	UserUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     2,
+			Entity: &PlayBinding.Entity,
+		},
+	},
+	TrackUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     3,
+			Entity: &PlayBinding.Entity,
+		},
+	},
+	PlaylistUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     4,
+			Entity: &PlayBinding.Entity,
+		},
+	},
+	DeviceUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     5,
+			Entity: &PlayBinding.Entity,
+		},
+	},
+	StartOffset: &objectbox.PropertyInt{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     6,
+			Entity: &PlayBinding.Entity,
+		},
+	},
+	EndOffset: &objectbox.PropertyInt{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     7,
+			Entity: &PlayBinding.Entity,
+		},
+	},
+	StartedAt: &objectbox.PropertyInt64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     8,
+			Entity: &PlayBinding.Entity,
+		},
+	},
+	EndedAt: &objectbox.PropertyInt64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     9,
+			Entity: &PlayBinding.Entity,
+		},
+	},
+}
+
+// GeneratorVersion is called by ObjectBox to verify the compatibility of the generator used to generate this code
+func (play_EntityInfo) GeneratorVersion() int {
+	return 6
+}
+
+// AddToModel is called by ObjectBox during model build
+func (play_EntityInfo) AddToModel(model *objectbox.Model) {
+	model.Entity("Play", 7, 8889788259202642179)
+	model.Property("Id", 6, 1, 8532482365961896731)
+	model.PropertyFlags(1)
+	model.Property("UserUuid", 9, 2, 3066305184845006176)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(13, 2418459191098256224)
+	model.Property("TrackUuid", 9, 3, 6250758562177926501)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(14, 6076681320830595563)
+	model.Property("PlaylistUuid", 9, 4, 4711016638382715537)
+	model.Property("DeviceUuid", 9, 5, 2085264194788582206)
+	model.Property("StartOffset", 6, 6, 5899432884948115131)
+	model.Property("EndOffset", 6, 7, 4921951619292586768)
+	model.Property("StartedAt", 6, 8, 9109590023525558417)
+	model.Property("EndedAt", 6, 9, 1880046230973257277)
+	model.EntityLastPropertyId(9, 1880046230973257277)
+}
+
+// GetId is called by ObjectBox during Put operations to check for existing ID on an object
+func (play_EntityInfo) GetId(object interface{}) (uint64, error) {
+	return object.(*Play).Id, nil
+}
+
+// SetId is called by ObjectBox during Put to update an ID on an object that has just been inserted
+func (play_EntityInfo) SetId(object interface{}, id uint64) error {
+	object.(*Play).Id = id
+	return nil
+}
+
+// PutRelated is called by ObjectBox to put related entities before the object itself is flattened and put
+func (play_EntityInfo) PutRelated(ob *objectbox.ObjectBox, object interface{}, id uint64) error {
+	return nil
+}
+
+// Flatten is called by ObjectBox to transform an object to a FlatBuffer
+func (play_EntityInfo) Flatten(object interface{}, fbb *flatbuffers.Builder, id uint64) error {
+	obj := object.(*Play)
+	var offsetUserUuid = fbutils.CreateStringOffset(fbb, obj.UserUuid)
+	var offsetTrackUuid = fbutils.CreateStringOffset(fbb, obj.TrackUuid)
+	var offsetPlaylistUuid = fbutils.CreateStringOffset(fbb, obj.PlaylistUuid)
+	var offsetDeviceUuid = fbutils.CreateStringOffset(fbb, obj.DeviceUuid)
+
+	// build the FlatBuffers object
+	fbb.StartObject(9)
+	fbutils.SetUint64Slot(fbb, 0, id)
+	fbutils.SetUOffsetTSlot(fbb, 1, offsetUserUuid)
+	fbutils.SetUOffsetTSlot(fbb, 2, offsetTrackUuid)
+	fbutils.SetUOffsetTSlot(fbb, 3, offsetPlaylistUuid)
+	fbutils.SetUOffsetTSlot(fbb, 4, offsetDeviceUuid)
+	fbutils.SetInt64Slot(fbb, 5, int64(obj.StartOffset))
+	fbutils.SetInt64Slot(fbb, 6, int64(obj.EndOffset))
+	fbutils.SetInt64Slot(fbb, 7, obj.StartedAt)
+	fbutils.SetInt64Slot(fbb, 8, obj.EndedAt)
+	return nil
+}
+
+// Load is called by ObjectBox to load an object from a FlatBuffer
+func (play_EntityInfo) Load(ob *objectbox.ObjectBox, bytes []byte) (interface{}, error) {
+	if len(bytes) == 0 { // sanity check, should "never" happen
+		return nil, errors.New("can't deserialize an object of type 'Play' - no data received")
+	}
+
+	var table = &flatbuffers.Table{
+		Bytes: bytes,
+		Pos:   flatbuffers.GetUOffsetT(bytes),
+	}
+
+	var propId = table.GetUint64Slot(4, 0)
+
+	return &Play{
+		Id:           propId,
+		UserUuid:     fbutils.GetStringSlot(table, 6),
+		TrackUuid:    fbutils.GetStringSlot(table, 8),
+		PlaylistUuid: fbutils.GetStringSlot(table, 10),
+		DeviceUuid:   fbutils.GetStringSlot(table, 12),
+		StartOffset:  fbutils.GetIntSlot(table, 14),
+		EndOffset:    fbutils.GetIntSlot(table, 16),
+		StartedAt:    fbutils.GetInt64Slot(table, 18),
+		EndedAt:      fbutils.GetInt64Slot(table, 20),
+	}, nil
+}
+
+// MakeSlice is called by ObjectBox to construct a new slice to hold the read objects
+func (play_EntityInfo) MakeSlice(capacity int) interface{} {
+	return make([]*Play, 0, capacity)
+}
+
+// AppendToSlice is called by ObjectBox to fill the slice of the read objects
+func (play_EntityInfo) AppendToSlice(slice interface{}, object interface{}) interface{} {
+	if object == nil {
+		return append(slice.([]*Play), nil)
+	}
+	return append(slice.([]*Play), object.(*Play))
+}
+
+// Box provides CRUD access to Play objects
+type PlayBox struct {
+	*objectbox.Box
+}
+
+// BoxForPlay opens a box of Play objects
+func BoxForPlay(ob *objectbox.ObjectBox) *PlayBox {
+	return &PlayBox{
+		Box: ob.InternalBox(7),
+	}
+}
+
+// Put synchronously inserts/updates a single object.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the Play.Id property on the passed object will be assigned the new ID as well.
+func (box *PlayBox) Put(object *Play) (uint64, error) {
+	return box.Box.Put(object)
+}
+
+// Insert synchronously inserts a single object. As opposed to Put, Insert will fail if given an ID that already exists.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the Play.Id property on the passed object will be assigned the new ID as well.
+func (box *PlayBox) Insert(object *Play) (uint64, error) {
+	return box.Box.Insert(object)
+}
+
+// Update synchronously updates a single object.
+// As opposed to Put, Update will fail if an object with the same ID is not found in the database.
+func (box *PlayBox) Update(object *Play) error {
+	return box.Box.Update(object)
+}
+
+// PutAsync asynchronously inserts/updates a single object.
+// Deprecated: use box.Async().Put() instead
+func (box *PlayBox) PutAsync(object *Play) (uint64, error) {
+	return box.Box.PutAsync(object)
+}
+
+// PutMany inserts multiple objects in single transaction.
+// In case Ids are not set on the objects, they would be assigned automatically (auto-increment).
+//
+// Returns: IDs of the put objects (in the same order).
+// When inserting, the Play.Id property on the objects in the slice will be assigned the new IDs as well.
+//
+// Note: In case an error occurs during the transaction, some of the objects may already have the Play.Id assigned
+// even though the transaction has been rolled back and the objects are not stored under those IDs.
+//
+// Note: The slice may be empty or even nil; in both cases, an empty IDs slice and no error is returned.
+func (box *PlayBox) PutMany(objects []*Play) ([]uint64, error) {
+	return box.Box.PutMany(objects)
+}
+
+// Get reads a single object.
+//
+// Returns nil (and no error) in case the object with the given ID doesn't exist.
+func (box *PlayBox) Get(id uint64) (*Play, error) {
+	object, err := box.Box.Get(id)
+	if err != nil {
+		return nil, err
+	} else if object == nil {
+		return nil, nil
+	}
+	return object.(*Play), nil
+}
+
+// GetMany reads multiple objects at once.
+// If any of the objects doesn't exist, its position in the return slice is nil
+func (box *PlayBox) GetMany(ids ...uint64) ([]*Play, error) {
+	objects, err := box.Box.GetMany(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Play), nil
+}
+
+// GetManyExisting reads multiple objects at once, skipping those that do not exist.
+func (box *PlayBox) GetManyExisting(ids ...uint64) ([]*Play, error) {
+	objects, err := box.Box.GetManyExisting(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Play), nil
+}
+
+// GetAll reads all stored objects
+func (box *PlayBox) GetAll() ([]*Play, error) {
+	objects, err := box.Box.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Play), nil
+}
+
+// Remove deletes a single object
+func (box *PlayBox) Remove(object *Play) error {
+	return box.Box.Remove(object)
+}
+
+// RemoveMany deletes multiple objects at once.
+// Returns the number of deleted object or error on failure.
+// Note that this method will not fail if an object is not found (e.g. already removed).
+// In case you need to strictly check whether all of the objects exist before removing them,
+// you can execute multiple box.Contains() and box.Remove() inside a single write transaction.
+func (box *PlayBox) RemoveMany(objects ...*Play) (uint64, error) {
+	var ids = make([]uint64, len(objects))
+	for k, object := range objects {
+		ids[k] = object.Id
+	}
+	return box.Box.RemoveIds(ids...)
+}
+
+// Creates a query with the given conditions. Use the fields of the Play_ struct to create conditions.
+// Keep the *PlayQuery if you intend to execute the query multiple times.
+// Note: this function panics if you try to create illegal queries; e.g. use properties of an alien type.
+// This is typically a programming error. Use QueryOrError instead if you want the explicit error check.
+func (box *PlayBox) Query(conditions ...objectbox.Condition) *PlayQuery {
+	return &PlayQuery{
+		box.Box.Query(conditions...),
+	}
+}
+
+// Creates a query with the given conditions. Use the fields of the Play_ struct to create conditions.
+// Keep the *PlayQuery if you intend to execute the query multiple times.
+func (box *PlayBox) QueryOrError(conditions ...objectbox.Condition) (*PlayQuery, error) {
+	if query, err := box.Box.QueryOrError(conditions...); err != nil {
+		return nil, err
+	} else {
+		return &PlayQuery{query}, nil
+	}
+}
+
+// Async provides access to the default Async Box for asynchronous operations. See PlayAsyncBox for more information.
+func (box *PlayBox) Async() *PlayAsyncBox {
+	return &PlayAsyncBox{AsyncBox: box.Box.Async()}
+}
+
+// PlayAsyncBox provides asynchronous operations on Play objects.
+//
+// Asynchronous operations are executed on a separate internal thread for better performance.
+//
+// There are two main use cases:
+//
+// 1) "execute & forget:" you gain faster put/remove operations as you don't have to wait for the transaction to finish.
+//
+// 2) Many small transactions: if your write load is typically a lot of individual puts that happen in parallel,
+// this will merge small transactions into bigger ones. This results in a significant gain in overall throughput.
+//
+// In situations with (extremely) high async load, an async method may be throttled (~1ms) or delayed up to 1 second.
+// In the unlikely event that the object could still not be enqueued (full queue), an error will be returned.
+//
+// Note that async methods do not give you hard durability guarantees like the synchronous Box provides.
+// There is a small time window in which the data may not have been committed durably yet.
+type PlayAsyncBox struct {
+	*objectbox.AsyncBox
+}
+
+// AsyncBoxForPlay creates a new async box with the given operation timeout in case an async queue is full.
+// The returned struct must be freed explicitly using the Close() method.
+// It's usually preferable to use PlayBox::Async() which takes care of resource management and doesn't require closing.
+func AsyncBoxForPlay(ob *objectbox.ObjectBox, timeoutMs uint64) *PlayAsyncBox {
+	var async, err = objectbox.NewAsyncBox(ob, 7, timeoutMs)
+	if err != nil {
+		panic("Could not create async box for entity ID 7: %s" + err.Error())
+	}
+	return &PlayAsyncBox{AsyncBox: async}
+}
+
+// Put inserts/updates a single object asynchronously.
+// When inserting a new object, the Id property on the passed object will be assigned the new ID the entity would hold
+// if the insert is ultimately successful. The newly assigned ID may not become valid if the insert fails.
+func (asyncBox *PlayAsyncBox) Put(object *Play) (uint64, error) {
+	return asyncBox.AsyncBox.Put(object)
+}
+
+// Insert a single object asynchronously.
+// The Id property on the passed object will be assigned the new ID the entity would hold if the insert is ultimately
+// successful. The newly assigned ID may not become valid if the insert fails.
+// Fails silently if an object with the same ID already exists (this error is not returned).
+func (asyncBox *PlayAsyncBox) Insert(object *Play) (id uint64, err error) {
+	return asyncBox.AsyncBox.Insert(object)
+}
+
+// Update a single object asynchronously.
+// The object must already exists or the update fails silently (without an error returned).
+func (asyncBox *PlayAsyncBox) Update(object *Play) error {
+	return asyncBox.AsyncBox.Update(object)
+}
+
+// Remove deletes a single object asynchronously.
+func (asyncBox *PlayAsyncBox) Remove(object *Play) error {
+	return asyncBox.AsyncBox.Remove(object)
+}
+
+// Query provides a way to search stored objects
+//
+// For example, you can find all Play which Id is either 42 or 47:
+//
+//	box.Query(Play_.Id.In(42, 47)).Find()
+type PlayQuery struct {
+	*objectbox.Query
+}
+
+// Find returns all objects matching the query
+func (query *PlayQuery) Find() ([]*Play, error) {
+	objects, err := query.Query.Find()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Play), nil
+}
+
+// Offset defines the index of the first object to process (how many objects to skip)
+func (query *PlayQuery) Offset(offset uint64) *PlayQuery {
+	query.Query.Offset(offset)
+	return query
+}
+
+// Limit sets the number of elements to process by the query
+func (query *PlayQuery) Limit(limit uint64) *PlayQuery {
+	query.Query.Limit(limit)
+	return query
+}
+
+type playlistShare_EntityInfo struct {
+	objectbox.Entity
+	Uid uint64
+}
+
+var PlaylistShareBinding = playlistShare_EntityInfo{
+	Entity: objectbox.Entity{
+		Id: 8,
+	},
+	Uid: 2317106437888666988,
+}
+
+// PlaylistShare_ contains type-based Property helpers to facilitate some common operations such as Queries.
+var PlaylistShare_ = struct {
+	Id           *objectbox.PropertyUint64
+	PlaylistUuid *objectbox.PropertyString
+	OwnerUuid    *objectbox.PropertyString
+	UserUuid     *objectbox.PropertyString
+	Access       *objectbox.PropertyString
+	CreatedAt    *objectbox.PropertyInt64
+}{
+	Id: &objectbox.PropertyUint64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     1,
+			Entity: &PlaylistShareBinding.Entity,
+		},
+	},
+	PlaylistUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     2,
+			Entity: &PlaylistShareBinding.Entity,
+		},
+	},
+	OwnerUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     3,
+			Entity: &PlaylistShareBinding.Entity,
+		},
+	},
+	UserUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     4,
+			Entity: &PlaylistShareBinding.Entity,
+		},
+	},
+	Access: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     5,
+			Entity: &PlaylistShareBinding.Entity,
+		},
+	},
+	CreatedAt: &objectbox.PropertyInt64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     6,
+			Entity: &PlaylistShareBinding.Entity,
+		},
+	},
+}
+
+// GeneratorVersion is called by ObjectBox to verify the compatibility of the generator used to generate this code
+func (playlistShare_EntityInfo) GeneratorVersion() int {
+	return 6
+}
+
+// AddToModel is called by ObjectBox during model build
+func (playlistShare_EntityInfo) AddToModel(model *objectbox.Model) {
+	model.Entity("PlaylistShare", 8, 2317106437888666988)
+	model.Property("Id", 6, 1, 4925343756579564349)
+	model.PropertyFlags(1)
+	model.Property("PlaylistUuid", 9, 2, 6712411533782516827)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(16, 3051071566638319185)
+	model.Property("OwnerUuid", 9, 3, 1184806199885396773)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(17, 5349001279339596553)
+	model.Property("UserUuid", 9, 4, 3615738402137251213)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(18, 1765462552903764278)
+	model.Property("Access", 9, 5, 5912796655227057644)
+	model.Property("CreatedAt", 6, 6, 8620964760569058408)
+	model.EntityLastPropertyId(6, 8620964760569058408)
+}
+
+// GetId is called by ObjectBox during Put operations to check for existing ID on an object
+func (playlistShare_EntityInfo) GetId(object interface{}) (uint64, error) {
+	return object.(*PlaylistShare).Id, nil
+}
+
+// SetId is called by ObjectBox during Put to update an ID on an object that has just been inserted
+func (playlistShare_EntityInfo) SetId(object interface{}, id uint64) error {
+	object.(*PlaylistShare).Id = id
+	return nil
+}
+
+// PutRelated is called by ObjectBox to put related entities before the object itself is flattened and put
+func (playlistShare_EntityInfo) PutRelated(ob *objectbox.ObjectBox, object interface{}, id uint64) error {
+	return nil
+}
+
+// Flatten is called by ObjectBox to transform an object to a FlatBuffer
+func (playlistShare_EntityInfo) Flatten(object interface{}, fbb *flatbuffers.Builder, id uint64) error {
+	obj := object.(*PlaylistShare)
+	var offsetPlaylistUuid = fbutils.CreateStringOffset(fbb, obj.PlaylistUuid)
+	var offsetOwnerUuid = fbutils.CreateStringOffset(fbb, obj.OwnerUuid)
+	var offsetUserUuid = fbutils.CreateStringOffset(fbb, obj.UserUuid)
+	var offsetAccess = fbutils.CreateStringOffset(fbb, obj.Access)
+
+	// build the FlatBuffers object
+	fbb.StartObject(6)
+	fbutils.SetUint64Slot(fbb, 0, id)
+	fbutils.SetUOffsetTSlot(fbb, 1, offsetPlaylistUuid)
+	fbutils.SetUOffsetTSlot(fbb, 2, offsetOwnerUuid)
+	fbutils.SetUOffsetTSlot(fbb, 3, offsetUserUuid)
+	fbutils.SetUOffsetTSlot(fbb, 4, offsetAccess)
+	fbutils.SetInt64Slot(fbb, 5, obj.CreatedAt)
+	return nil
+}
+
+// Load is called by ObjectBox to load an object from a FlatBuffer
+func (playlistShare_EntityInfo) Load(ob *objectbox.ObjectBox, bytes []byte) (interface{}, error) {
+	if len(bytes) == 0 { // sanity check, should "never" happen
+		return nil, errors.New("can't deserialize an object of type 'PlaylistShare' - no data received")
+	}
+
+	var table = &flatbuffers.Table{
+		Bytes: bytes,
+		Pos:   flatbuffers.GetUOffsetT(bytes),
+	}
+
+	var propId = table.GetUint64Slot(4, 0)
+
+	return &PlaylistShare{
+		Id:           propId,
+		PlaylistUuid: fbutils.GetStringSlot(table, 6),
+		OwnerUuid:    fbutils.GetStringSlot(table, 8),
+		UserUuid:     fbutils.GetStringSlot(table, 10),
+		Access:       fbutils.GetStringSlot(table, 12),
+		CreatedAt:    fbutils.GetInt64Slot(table, 14),
+	}, nil
+}
+
+// MakeSlice is called by ObjectBox to construct a new slice to hold the read objects
+func (playlistShare_EntityInfo) MakeSlice(capacity int) interface{} {
+	return make([]*PlaylistShare, 0, capacity)
+}
+
+// AppendToSlice is called by ObjectBox to fill the slice of the read objects
+func (playlistShare_EntityInfo) AppendToSlice(slice interface{}, object interface{}) interface{} {
+	if object == nil {
+		return append(slice.([]*PlaylistShare), nil)
+	}
+	return append(slice.([]*PlaylistShare), object.(*PlaylistShare))
+}
+
+// Box provides CRUD access to PlaylistShare objects
+type PlaylistShareBox struct {
+	*objectbox.Box
+}
+
+// BoxForPlaylistShare opens a box of PlaylistShare objects
+func BoxForPlaylistShare(ob *objectbox.ObjectBox) *PlaylistShareBox {
+	return &PlaylistShareBox{
+		Box: ob.InternalBox(8),
+	}
+}
+
+// Put synchronously inserts/updates a single object.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the PlaylistShare.Id property on the passed object will be assigned the new ID as well.
+func (box *PlaylistShareBox) Put(object *PlaylistShare) (uint64, error) {
+	return box.Box.Put(object)
+}
+
+// Insert synchronously inserts a single object. As opposed to Put, Insert will fail if given an ID that already exists.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the PlaylistShare.Id property on the passed object will be assigned the new ID as well.
+func (box *PlaylistShareBox) Insert(object *PlaylistShare) (uint64, error) {
+	return box.Box.Insert(object)
+}
+
+// Update synchronously updates a single object.
+// As opposed to Put, Update will fail if an object with the same ID is not found in the database.
+func (box *PlaylistShareBox) Update(object *PlaylistShare) error {
+	return box.Box.Update(object)
+}
+
+// PutAsync asynchronously inserts/updates a single object.
+// Deprecated: use box.Async().Put() instead
+func (box *PlaylistShareBox) PutAsync(object *PlaylistShare) (uint64, error) {
+	return box.Box.PutAsync(object)
+}
+
+// PutMany inserts multiple objects in single transaction.
+// In case Ids are not set on the objects, they would be assigned automatically (auto-increment).
+//
+// Returns: IDs of the put objects (in the same order).
+// When inserting, the PlaylistShare.Id property on the objects in the slice will be assigned the new IDs as well.
+//
+// Note: In case an error occurs during the transaction, some of the objects may already have the PlaylistShare.Id assigned
+// even though the transaction has been rolled back and the objects are not stored under those IDs.
+//
+// Note: The slice may be empty or even nil; in both cases, an empty IDs slice and no error is returned.
+func (box *PlaylistShareBox) PutMany(objects []*PlaylistShare) ([]uint64, error) {
+	return box.Box.PutMany(objects)
+}
+
+// Get reads a single object.
+//
+// Returns nil (and no error) in case the object with the given ID doesn't exist.
+func (box *PlaylistShareBox) Get(id uint64) (*PlaylistShare, error) {
+	object, err := box.Box.Get(id)
+	if err != nil {
+		return nil, err
+	} else if object == nil {
+		return nil, nil
+	}
+	return object.(*PlaylistShare), nil
+}
+
+// GetMany reads multiple objects at once.
+// If any of the objects doesn't exist, its position in the return slice is nil
+func (box *PlaylistShareBox) GetMany(ids ...uint64) ([]*PlaylistShare, error) {
+	objects, err := box.Box.GetMany(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*PlaylistShare), nil
+}
+
+// GetManyExisting reads multiple objects at once, skipping those that do not exist.
+func (box *PlaylistShareBox) GetManyExisting(ids ...uint64) ([]*PlaylistShare, error) {
+	objects, err := box.Box.GetManyExisting(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*PlaylistShare), nil
+}
+
+// GetAll reads all stored objects
+func (box *PlaylistShareBox) GetAll() ([]*PlaylistShare, error) {
+	objects, err := box.Box.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*PlaylistShare), nil
+}
+
+// Remove deletes a single object
+func (box *PlaylistShareBox) Remove(object *PlaylistShare) error {
+	return box.Box.Remove(object)
+}
+
+// RemoveMany deletes multiple objects at once.
+// Returns the number of deleted object or error on failure.
+// Note that this method will not fail if an object is not found (e.g. already removed).
+// In case you need to strictly check whether all of the objects exist before removing them,
+// you can execute multiple box.Contains() and box.Remove() inside a single write transaction.
+func (box *PlaylistShareBox) RemoveMany(objects ...*PlaylistShare) (uint64, error) {
+	var ids = make([]uint64, len(objects))
+	for k, object := range objects {
+		ids[k] = object.Id
+	}
+	return box.Box.RemoveIds(ids...)
+}
+
+// Creates a query with the given conditions. Use the fields of the PlaylistShare_ struct to create conditions.
+// Keep the *PlaylistShareQuery if you intend to execute the query multiple times.
+// Note: this function panics if you try to create illegal queries; e.g. use properties of an alien type.
+// This is typically a programming error. Use QueryOrError instead if you want the explicit error check.
+func (box *PlaylistShareBox) Query(conditions ...objectbox.Condition) *PlaylistShareQuery {
+	return &PlaylistShareQuery{
+		box.Box.Query(conditions...),
+	}
+}
+
+// Creates a query with the given conditions. Use the fields of the PlaylistShare_ struct to create conditions.
+// Keep the *PlaylistShareQuery if you intend to execute the query multiple times.
+func (box *PlaylistShareBox) QueryOrError(conditions ...objectbox.Condition) (*PlaylistShareQuery, error) {
+	if query, err := box.Box.QueryOrError(conditions...); err != nil {
+		return nil, err
+	} else {
+		return &PlaylistShareQuery{query}, nil
+	}
+}
+
+// Async provides access to the default Async Box for asynchronous operations. See PlaylistShareAsyncBox for more information.
+func (box *PlaylistShareBox) Async() *PlaylistShareAsyncBox {
+	return &PlaylistShareAsyncBox{AsyncBox: box.Box.Async()}
+}
+
+// PlaylistShareAsyncBox provides asynchronous operations on PlaylistShare objects.
+//
+// Asynchronous operations are executed on a separate internal thread for better performance.
+//
+// There are two main use cases:
+//
+// 1) "execute & forget:" you gain faster put/remove operations as you don't have to wait for the transaction to finish.
+//
+// 2) Many small transactions: if your write load is typically a lot of individual puts that happen in parallel,
+// this will merge small transactions into bigger ones. This results in a significant gain in overall throughput.
+//
+// In situations with (extremely) high async load, an async method may be throttled (~1ms) or delayed up to 1 second.
+// In the unlikely event that the object could still not be enqueued (full queue), an error will be returned.
+//
+// Note that async methods do not give you hard durability guarantees like the synchronous Box provides.
+// There is a small time window in which the data may not have been committed durably yet.
+type PlaylistShareAsyncBox struct {
+	*objectbox.AsyncBox
+}
+
+// AsyncBoxForPlaylistShare creates a new async box with the given operation timeout in case an async queue is full.
+// The returned struct must be freed explicitly using the Close() method.
+// It's usually preferable to use PlaylistShareBox::Async() which takes care of resource management and doesn't require closing.
+func AsyncBoxForPlaylistShare(ob *objectbox.ObjectBox, timeoutMs uint64) *PlaylistShareAsyncBox {
+	var async, err = objectbox.NewAsyncBox(ob, 8, timeoutMs)
+	if err != nil {
+		panic("Could not create async box for entity ID 8: %s" + err.Error())
+	}
+	return &PlaylistShareAsyncBox{AsyncBox: async}
+}
+
+// Put inserts/updates a single object asynchronously.
+// When inserting a new object, the Id property on the passed object will be assigned the new ID the entity would hold
+// if the insert is ultimately successful. The newly assigned ID may not become valid if the insert fails.
+func (asyncBox *PlaylistShareAsyncBox) Put(object *PlaylistShare) (uint64, error) {
+	return asyncBox.AsyncBox.Put(object)
+}
+
+// Insert a single object asynchronously.
+// The Id property on the passed object will be assigned the new ID the entity would hold if the insert is ultimately
+// successful. The newly assigned ID may not become valid if the insert fails.
+// Fails silently if an object with the same ID already exists (this error is not returned).
+func (asyncBox *PlaylistShareAsyncBox) Insert(object *PlaylistShare) (id uint64, err error) {
+	return asyncBox.AsyncBox.Insert(object)
+}
+
+// Update a single object asynchronously.
+// The object must already exists or the update fails silently (without an error returned).
+func (asyncBox *PlaylistShareAsyncBox) Update(object *PlaylistShare) error {
+	return asyncBox.AsyncBox.Update(object)
+}
+
+// Remove deletes a single object asynchronously.
+func (asyncBox *PlaylistShareAsyncBox) Remove(object *PlaylistShare) error {
+	return asyncBox.AsyncBox.Remove(object)
+}
+
+// Query provides a way to search stored objects
+//
+// For example, you can find all PlaylistShare which Id is either 42 or 47:
+//
+//	box.Query(PlaylistShare_.Id.In(42, 47)).Find()
+type PlaylistShareQuery struct {
+	*objectbox.Query
+}
+
+// Find returns all objects matching the query
+func (query *PlaylistShareQuery) Find() ([]*PlaylistShare, error) {
+	objects, err := query.Query.Find()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*PlaylistShare), nil
+}
+
+// Offset defines the index of the first object to process (how many objects to skip)
+func (query *PlaylistShareQuery) Offset(offset uint64) *PlaylistShareQuery {
+	query.Query.Offset(offset)
+	return query
+}
+
+// Limit sets the number of elements to process by the query
+func (query *PlaylistShareQuery) Limit(limit uint64) *PlaylistShareQuery {
+	query.Query.Limit(limit)
+	return query
+}
+
+type revokedToken_EntityInfo struct {
+	objectbox.Entity
+	Uid uint64
+}
+
+var RevokedTokenBinding = revokedToken_EntityInfo{
+	Entity: objectbox.Entity{
+		Id: 9,
+	},
+	Uid: 7146266075970575127,
+}
+
+// RevokedToken_ contains type-based Property helpers to facilitate some common operations such as Queries.
+var RevokedToken_ = struct {
+	Id        *objectbox.PropertyUint64
+	TokenId   *objectbox.PropertyString
+	ExpiresAt *objectbox.PropertyInt64
+}{
+	Id: &objectbox.PropertyUint64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     1,
+			Entity: &RevokedTokenBinding.Entity,
+		},
+	},
+	TokenId: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     2,
+			Entity: &RevokedTokenBinding.Entity,
+		},
+	},
+	ExpiresAt: &objectbox.PropertyInt64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     3,
+			Entity: &RevokedTokenBinding.Entity,
+		},
+	},
+}
+
+// GeneratorVersion is called by ObjectBox to verify the compatibility of the generator used to generate this code
+func (revokedToken_EntityInfo) GeneratorVersion() int {
+	return 6
+}
+
+// AddToModel is called by ObjectBox during model build
+func (revokedToken_EntityInfo) AddToModel(model *objectbox.Model) {
+	model.Entity("RevokedToken", 9, 7146266075970575127)
+	model.Property("Id", 6, 1, 2271812808706027926)
+	model.PropertyFlags(1)
+	model.Property("TokenId", 9, 2, 3261457568157967020)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(19, 2009977291789526710)
+	model.Property("ExpiresAt", 6, 3, 2365653985806014451)
+	model.PropertyFlags(8)
+	model.PropertyIndex(20, 519798302818540185)
+	model.EntityLastPropertyId(3, 2365653985806014451)
+}
+
+// GetId is called by ObjectBox during Put operations to check for existing ID on an object
+func (revokedToken_EntityInfo) GetId(object interface{}) (uint64, error) {
+	return object.(*RevokedToken).Id, nil
+}
+
+// SetId is called by ObjectBox during Put to update an ID on an object that has just been inserted
+func (revokedToken_EntityInfo) SetId(object interface{}, id uint64) error {
+	object.(*RevokedToken).Id = id
+	return nil
+}
+
+// PutRelated is called by ObjectBox to put related entities before the object itself is flattened and put
+func (revokedToken_EntityInfo) PutRelated(ob *objectbox.ObjectBox, object interface{}, id uint64) error {
+	return nil
+}
+
+// Flatten is called by ObjectBox to transform an object to a FlatBuffer
+func (revokedToken_EntityInfo) Flatten(object interface{}, fbb *flatbuffers.Builder, id uint64) error {
+	obj := object.(*RevokedToken)
+	var offsetTokenId = fbutils.CreateStringOffset(fbb, obj.TokenId)
+
+	// build the FlatBuffers object
+	fbb.StartObject(3)
+	fbutils.SetUint64Slot(fbb, 0, id)
+	fbutils.SetUOffsetTSlot(fbb, 1, offsetTokenId)
+	fbutils.SetInt64Slot(fbb, 2, obj.ExpiresAt)
+	return nil
+}
+
+// Load is called by ObjectBox to load an object from a FlatBuffer
+func (revokedToken_EntityInfo) Load(ob *objectbox.ObjectBox, bytes []byte) (interface{}, error) {
+	if len(bytes) == 0 { // sanity check, should "never" happen
+		return nil, errors.New("can't deserialize an object of type 'RevokedToken' - no data received")
+	}
+
+	var table = &flatbuffers.Table{
+		Bytes: bytes,
+		Pos:   flatbuffers.GetUOffsetT(bytes),
+	}
+
+	var propId = table.GetUint64Slot(4, 0)
+
+	return &RevokedToken{
+		Id:        propId,
+		TokenId:   fbutils.GetStringSlot(table, 6),
+		ExpiresAt: fbutils.GetInt64Slot(table, 8),
+	}, nil
+}
+
+// MakeSlice is called by ObjectBox to construct a new slice to hold the read objects
+func (revokedToken_EntityInfo) MakeSlice(capacity int) interface{} {
+	return make([]*RevokedToken, 0, capacity)
+}
+
+// AppendToSlice is called by ObjectBox to fill the slice of the read objects
+func (revokedToken_EntityInfo) AppendToSlice(slice interface{}, object interface{}) interface{} {
+	if object == nil {
+		return append(slice.([]*RevokedToken), nil)
+	}
+	return append(slice.([]*RevokedToken), object.(*RevokedToken))
+}
+
+// Box provides CRUD access to RevokedToken objects
+type RevokedTokenBox struct {
+	*objectbox.Box
+}
+
+// BoxForRevokedToken opens a box of RevokedToken objects
+func BoxForRevokedToken(ob *objectbox.ObjectBox) *RevokedTokenBox {
+	return &RevokedTokenBox{
+		Box: ob.InternalBox(9),
+	}
+}
+
+// Put synchronously inserts/updates a single object.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the RevokedToken.Id property on the passed object will be assigned the new ID as well.
+func (box *RevokedTokenBox) Put(object *RevokedToken) (uint64, error) {
+	return box.Box.Put(object)
+}
+
+// Insert synchronously inserts a single object. As opposed to Put, Insert will fail if given an ID that already exists.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the RevokedToken.Id property on the passed object will be assigned the new ID as well.
+func (box *RevokedTokenBox) Insert(object *RevokedToken) (uint64, error) {
+	return box.Box.Insert(object)
+}
+
+// Update synchronously updates a single object.
+// As opposed to Put, Update will fail if an object with the same ID is not found in the database.
+func (box *RevokedTokenBox) Update(object *RevokedToken) error {
+	return box.Box.Update(object)
+}
+
+// PutAsync asynchronously inserts/updates a single object.
+// Deprecated: use box.Async().Put() instead
+func (box *RevokedTokenBox) PutAsync(object *RevokedToken) (uint64, error) {
+	return box.Box.PutAsync(object)
+}
+
+// PutMany inserts multiple objects in single transaction.
+// In case Ids are not set on the objects, they would be assigned automatically (auto-increment).
+//
+// Returns: IDs of the put objects (in the same order).
+// When inserting, the RevokedToken.Id property on the objects in the slice will be assigned the new IDs as well.
+//
+// Note: In case an error occurs during the transaction, some of the objects may already have the RevokedToken.Id assigned
+// even though the transaction has been rolled back and the objects are not stored under those IDs.
+//
+// Note: The slice may be empty or even nil; in both cases, an empty IDs slice and no error is returned.
+func (box *RevokedTokenBox) PutMany(objects []*RevokedToken) ([]uint64, error) {
+	return box.Box.PutMany(objects)
+}
+
+// Get reads a single object.
+//
+// Returns nil (and no error) in case the object with the given ID doesn't exist.
+func (box *RevokedTokenBox) Get(id uint64) (*RevokedToken, error) {
+	object, err := box.Box.Get(id)
+	if err != nil {
+		return nil, err
+	} else if object == nil {
+		return nil, nil
+	}
+	return object.(*RevokedToken), nil
+}
+
+// GetMany reads multiple objects at once.
+// If any of the objects doesn't exist, its position in the return slice is nil
+func (box *RevokedTokenBox) GetMany(ids ...uint64) ([]*RevokedToken, error) {
+	objects, err := box.Box.GetMany(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*RevokedToken), nil
+}
+
+// GetManyExisting reads multiple objects at once, skipping those that do not exist.
+func (box *RevokedTokenBox) GetManyExisting(ids ...uint64) ([]*RevokedToken, error) {
+	objects, err := box.Box.GetManyExisting(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*RevokedToken), nil
+}
+
+// GetAll reads all stored objects
+func (box *RevokedTokenBox) GetAll() ([]*RevokedToken, error) {
+	objects, err := box.Box.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*RevokedToken), nil
+}
+
+// Remove deletes a single object
+func (box *RevokedTokenBox) Remove(object *RevokedToken) error {
+	return box.Box.Remove(object)
+}
+
+// RemoveMany deletes multiple objects at once.
+// Returns the number of deleted object or error on failure.
+// Note that this method will not fail if an object is not found (e.g. already removed).
+// In case you need to strictly check whether all of the objects exist before removing them,
+// you can execute multiple box.Contains() and box.Remove() inside a single write transaction.
+func (box *RevokedTokenBox) RemoveMany(objects ...*RevokedToken) (uint64, error) {
+	var ids = make([]uint64, len(objects))
+	for k, object := range objects {
+		ids[k] = object.Id
+	}
+	return box.Box.RemoveIds(ids...)
+}
+
+// Creates a query with the given conditions. Use the fields of the RevokedToken_ struct to create conditions.
+// Keep the *RevokedTokenQuery if you intend to execute the query multiple times.
+// Note: this function panics if you try to create illegal queries; e.g. use properties of an alien type.
+// This is typically a programming error. Use QueryOrError instead if you want the explicit error check.
+func (box *RevokedTokenBox) Query(conditions ...objectbox.Condition) *RevokedTokenQuery {
+	return &RevokedTokenQuery{
+		box.Box.Query(conditions...),
+	}
+}
+
+// Creates a query with the given conditions. Use the fields of the RevokedToken_ struct to create conditions.
+// Keep the *RevokedTokenQuery if you intend to execute the query multiple times.
+func (box *RevokedTokenBox) QueryOrError(conditions ...objectbox.Condition) (*RevokedTokenQuery, error) {
+	if query, err := box.Box.QueryOrError(conditions...); err != nil {
+		return nil, err
+	} else {
+		return &RevokedTokenQuery{query}, nil
+	}
+}
+
+// Async provides access to the default Async Box for asynchronous operations. See RevokedTokenAsyncBox for more information.
+func (box *RevokedTokenBox) Async() *RevokedTokenAsyncBox {
+	return &RevokedTokenAsyncBox{AsyncBox: box.Box.Async()}
+}
+
+// RevokedTokenAsyncBox provides asynchronous operations on RevokedToken objects.
+//
+// Asynchronous operations are executed on a separate internal thread for better performance.
+//
+// There are two main use cases:
+//
+// 1) "execute & forget:" you gain faster put/remove operations as you don't have to wait for the transaction to finish.
+//
+// 2) Many small transactions: if your write load is typically a lot of individual puts that happen in parallel,
+// this will merge small transactions into bigger ones. This results in a significant gain in overall throughput.
+//
+// In situations with (extremely) high async load, an async method may be throttled (~1ms) or delayed up to 1 second.
+// In the unlikely event that the object could still not be enqueued (full queue), an error will be returned.
+//
+// Note that async methods do not give you hard durability guarantees like the synchronous Box provides.
+// There is a small time window in which the data may not have been committed durably yet.
+type RevokedTokenAsyncBox struct {
+	*objectbox.AsyncBox
+}
+
+// AsyncBoxForRevokedToken creates a new async box with the given operation timeout in case an async queue is full.
+// The returned struct must be freed explicitly using the Close() method.
+// It's usually preferable to use RevokedTokenBox::Async() which takes care of resource management and doesn't require closing.
+func AsyncBoxForRevokedToken(ob *objectbox.ObjectBox, timeoutMs uint64) *RevokedTokenAsyncBox {
+	var async, err = objectbox.NewAsyncBox(ob, 9, timeoutMs)
+	if err != nil {
+		panic("Could not create async box for entity ID 9: %s" + err.Error())
+	}
+	return &RevokedTokenAsyncBox{AsyncBox: async}
+}
+
+// Put inserts/updates a single object asynchronously.
+// When inserting a new object, the Id property on the passed object will be assigned the new ID the entity would hold
+// if the insert is ultimately successful. The newly assigned ID may not become valid if the insert fails.
+func (asyncBox *RevokedTokenAsyncBox) Put(object *RevokedToken) (uint64, error) {
+	return asyncBox.AsyncBox.Put(object)
+}
+
+// Insert a single object asynchronously.
+// The Id property on the passed object will be assigned the new ID the entity would hold if the insert is ultimately
+// successful. The newly assigned ID may not become valid if the insert fails.
+// Fails silently if an object with the same ID already exists (this error is not returned).
+func (asyncBox *RevokedTokenAsyncBox) Insert(object *RevokedToken) (id uint64, err error) {
+	return asyncBox.AsyncBox.Insert(object)
+}
+
+// Update a single object asynchronously.
+// The object must already exists or the update fails silently (without an error returned).
+func (asyncBox *RevokedTokenAsyncBox) Update(object *RevokedToken) error {
+	return asyncBox.AsyncBox.Update(object)
+}
+
+// Remove deletes a single object asynchronously.
+func (asyncBox *RevokedTokenAsyncBox) Remove(object *RevokedToken) error {
+	return asyncBox.AsyncBox.Remove(object)
+}
+
+// Query provides a way to search stored objects
+//
+// For example, you can find all RevokedToken which Id is either 42 or 47:
+//
+//	box.Query(RevokedToken_.Id.In(42, 47)).Find()
+type RevokedTokenQuery struct {
+	*objectbox.Query
+}
+
+// Find returns all objects matching the query
+func (query *RevokedTokenQuery) Find() ([]*RevokedToken, error) {
+	objects, err := query.Query.Find()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*RevokedToken), nil
+}
+
+// Offset defines the index of the first object to process (how many objects to skip)
+func (query *RevokedTokenQuery) Offset(offset uint64) *RevokedTokenQuery {
+	query.Query.Offset(offset)
+	return query
+}
+
+// Limit sets the number of elements to process by the query
+func (query *RevokedTokenQuery) Limit(limit uint64) *RevokedTokenQuery {
+	query.Query.Limit(limit)
+	return query
+}
+
+type group_EntityInfo struct {
+	objectbox.Entity
+	Uid uint64
+}
+
+var GroupBinding = group_EntityInfo{
+	Entity: objectbox.Entity{
+		Id: 10,
+	},
+	Uid: 619284358394406223,
+}
+
+// Group_ contains type-based Property helpers to facilitate some common operations such as Queries.
+var Group_ = struct {
+	Id        *objectbox.PropertyUint64
+	Uuid      *objectbox.PropertyString
+	Name      *objectbox.PropertyString
+	CreatedAt *objectbox.PropertyInt64
+	Revision  *objectbox.PropertyUint64
+}{
+	Id: &objectbox.PropertyUint64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     1,
+			Entity: &GroupBinding.Entity,
+		},
+	},
+	Uuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     2,
+			Entity: &GroupBinding.Entity,
+		},
+	},
+	Name: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     3,
+			Entity: &GroupBinding.Entity,
+		},
+	},
+	CreatedAt: &objectbox.PropertyInt64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     4,
+			Entity: &GroupBinding.Entity,
+		},
+	},
+	Revision: &objectbox.PropertyUint64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     5,
+			Entity: &GroupBinding.Entity,
+		},
+	},
+}
+
+// GeneratorVersion is called by ObjectBox to verify the compatibility of the generator used to generate this code
+func (group_EntityInfo) GeneratorVersion() int {
+	return 6
+}
+
+// AddToModel is called by ObjectBox during model build
+func (group_EntityInfo) AddToModel(model *objectbox.Model) {
+	model.Entity("Group", 10, 619284358394406223)
+	model.Property("Id", 6, 1, 1302667619086230312)
+	model.PropertyFlags(1)
+	model.Property("Uuid", 9, 2, 519089973534604573)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(22, 1711103091585024784)
+	model.Property("Name", 9, 3, 664523953253485311)
+	model.Property("CreatedAt", 6, 4, 6650649464989205094)
+	model.Property("Revision", 6, 5, 7260993654395529408)
+	model.PropertyFlags(8192)
+	model.EntityLastPropertyId(5, 7260993654395529408)
+}
+
+// GetId is called by ObjectBox during Put operations to check for existing ID on an object
+func (group_EntityInfo) GetId(object interface{}) (uint64, error) {
+	return object.(*Group).Id, nil
+}
+
+// SetId is called by ObjectBox during Put to update an ID on an object that has just been inserted
+func (group_EntityInfo) SetId(object interface{}, id uint64) error {
+	object.(*Group).Id = id
+	return nil
+}
+
+// PutRelated is called by ObjectBox to put related entities before the object itself is flattened and put
+func (group_EntityInfo) PutRelated(ob *objectbox.ObjectBox, object interface{}, id uint64) error {
+	return nil
+}
+
+// Flatten is called by ObjectBox to transform an object to a FlatBuffer
+func (group_EntityInfo) Flatten(object interface{}, fbb *flatbuffers.Builder, id uint64) error {
+	obj := object.(*Group)
+	var offsetUuid = fbutils.CreateStringOffset(fbb, obj.Uuid)
+	var offsetName = fbutils.CreateStringOffset(fbb, obj.Name)
+
+	// build the FlatBuffers object
+	fbb.StartObject(5)
+	fbutils.SetUint64Slot(fbb, 0, id)
+	fbutils.SetUOffsetTSlot(fbb, 1, offsetUuid)
+	fbutils.SetUOffsetTSlot(fbb, 2, offsetName)
+	fbutils.SetInt64Slot(fbb, 3, obj.CreatedAt)
+	fbutils.SetUint64Slot(fbb, 4, obj.Revision)
+	return nil
+}
+
+// Load is called by ObjectBox to load an object from a FlatBuffer
+func (group_EntityInfo) Load(ob *objectbox.ObjectBox, bytes []byte) (interface{}, error) {
+	if len(bytes) == 0 { // sanity check, should "never" happen
+		return nil, errors.New("can't deserialize an object of type 'Group' - no data received")
+	}
+
+	var table = &flatbuffers.Table{
+		Bytes: bytes,
+		Pos:   flatbuffers.GetUOffsetT(bytes),
+	}
+
+	var propId = table.GetUint64Slot(4, 0)
+
+	return &Group{
+		Id:        propId,
+		Uuid:      fbutils.GetStringSlot(table, 6),
+		Name:      fbutils.GetStringSlot(table, 8),
+		CreatedAt: fbutils.GetInt64Slot(table, 10),
+		Revision:  fbutils.GetUint64Slot(table, 12),
+	}, nil
+}
+
+// MakeSlice is called by ObjectBox to construct a new slice to hold the read objects
+func (group_EntityInfo) MakeSlice(capacity int) interface{} {
+	return make([]*Group, 0, capacity)
+}
+
+// AppendToSlice is called by ObjectBox to fill the slice of the read objects
+func (group_EntityInfo) AppendToSlice(slice interface{}, object interface{}) interface{} {
+	if object == nil {
+		return append(slice.([]*Group), nil)
+	}
+	return append(slice.([]*Group), object.(*Group))
+}
+
+// Box provides CRUD access to Group objects
+type GroupBox struct {
+	*objectbox.Box
+}
+
+// BoxForGroup opens a box of Group objects
+func BoxForGroup(ob *objectbox.ObjectBox) *GroupBox {
+	return &GroupBox{
+		Box: ob.InternalBox(10),
+	}
+}
+
+// Put synchronously inserts/updates a single object.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the Group.Id property on the passed object will be assigned the new ID as well.
+func (box *GroupBox) Put(object *Group) (uint64, error) {
+	return box.Box.Put(object)
+}
+
+// Insert synchronously inserts a single object. As opposed to Put, Insert will fail if given an ID that already exists.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the Group.Id property on the passed object will be assigned the new ID as well.
+func (box *GroupBox) Insert(object *Group) (uint64, error) {
+	return box.Box.Insert(object)
+}
+
+// Update synchronously updates a single object.
+// As opposed to Put, Update will fail if an object with the same ID is not found in the database.
+func (box *GroupBox) Update(object *Group) error {
+	return box.Box.Update(object)
+}
+
+// PutAsync asynchronously inserts/updates a single object.
+// Deprecated: use box.Async().Put() instead
+func (box *GroupBox) PutAsync(object *Group) (uint64, error) {
+	return box.Box.PutAsync(object)
+}
+
+// PutMany inserts multiple objects in single transaction.
+// In case Ids are not set on the objects, they would be assigned automatically (auto-increment).
+//
+// Returns: IDs of the put objects (in the same order).
+// When inserting, the Group.Id property on the objects in the slice will be assigned the new IDs as well.
+//
+// Note: In case an error occurs during the transaction, some of the objects may already have the Group.Id assigned
+// even though the transaction has been rolled back and the objects are not stored under those IDs.
+//
+// Note: The slice may be empty or even nil; in both cases, an empty IDs slice and no error is returned.
+func (box *GroupBox) PutMany(objects []*Group) ([]uint64, error) {
+	return box.Box.PutMany(objects)
+}
+
+// Get reads a single object.
+//
+// Returns nil (and no error) in case the object with the given ID doesn't exist.
+func (box *GroupBox) Get(id uint64) (*Group, error) {
+	object, err := box.Box.Get(id)
+	if err != nil {
+		return nil, err
+	} else if object == nil {
+		return nil, nil
+	}
+	return object.(*Group), nil
+}
+
+// GetMany reads multiple objects at once.
+// If any of the objects doesn't exist, its position in the return slice is nil
+func (box *GroupBox) GetMany(ids ...uint64) ([]*Group, error) {
+	objects, err := box.Box.GetMany(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Group), nil
+}
+
+// GetManyExisting reads multiple objects at once, skipping those that do not exist.
+func (box *GroupBox) GetManyExisting(ids ...uint64) ([]*Group, error) {
+	objects, err := box.Box.GetManyExisting(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Group), nil
+}
+
+// GetAll reads all stored objects
+func (box *GroupBox) GetAll() ([]*Group, error) {
+	objects, err := box.Box.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Group), nil
+}
+
+// Remove deletes a single object
+func (box *GroupBox) Remove(object *Group) error {
+	return box.Box.Remove(object)
+}
+
+// RemoveMany deletes multiple objects at once.
+// Returns the number of deleted object or error on failure.
+// Note that this method will not fail if an object is not found (e.g. already removed).
+// In case you need to strictly check whether all of the objects exist before removing them,
+// you can execute multiple box.Contains() and box.Remove() inside a single write transaction.
+func (box *GroupBox) RemoveMany(objects ...*Group) (uint64, error) {
+	var ids = make([]uint64, len(objects))
+	for k, object := range objects {
+		ids[k] = object.Id
+	}
+	return box.Box.RemoveIds(ids...)
+}
+
+// Creates a query with the given conditions. Use the fields of the Group_ struct to create conditions.
+// Keep the *GroupQuery if you intend to execute the query multiple times.
+// Note: this function panics if you try to create illegal queries; e.g. use properties of an alien type.
+// This is typically a programming error. Use QueryOrError instead if you want the explicit error check.
+func (box *GroupBox) Query(conditions ...objectbox.Condition) *GroupQuery {
+	return &GroupQuery{
+		box.Box.Query(conditions...),
+	}
+}
+
+// Creates a query with the given conditions. Use the fields of the Group_ struct to create conditions.
+// Keep the *GroupQuery if you intend to execute the query multiple times.
+func (box *GroupBox) QueryOrError(conditions ...objectbox.Condition) (*GroupQuery, error) {
+	if query, err := box.Box.QueryOrError(conditions...); err != nil {
+		return nil, err
+	} else {
+		return &GroupQuery{query}, nil
+	}
+}
+
+// Async provides access to the default Async Box for asynchronous operations. See GroupAsyncBox for more information.
+func (box *GroupBox) Async() *GroupAsyncBox {
+	return &GroupAsyncBox{AsyncBox: box.Box.Async()}
+}
+
+// GroupAsyncBox provides asynchronous operations on Group objects.
+//
+// Asynchronous operations are executed on a separate internal thread for better performance.
+//
+// There are two main use cases:
+//
+// 1) "execute & forget:" you gain faster put/remove operations as you don't have to wait for the transaction to finish.
+//
+// 2) Many small transactions: if your write load is typically a lot of individual puts that happen in parallel,
+// this will merge small transactions into bigger ones. This results in a significant gain in overall throughput.
+//
+// In situations with (extremely) high async load, an async method may be throttled (~1ms) or delayed up to 1 second.
+// In the unlikely event that the object could still not be enqueued (full queue), an error will be returned.
+//
+// Note that async methods do not give you hard durability guarantees like the synchronous Box provides.
+// There is a small time window in which the data may not have been committed durably yet.
+type GroupAsyncBox struct {
+	*objectbox.AsyncBox
+}
+
+// AsyncBoxForGroup creates a new async box with the given operation timeout in case an async queue is full.
+// The returned struct must be freed explicitly using the Close() method.
+// It's usually preferable to use GroupBox::Async() which takes care of resource management and doesn't require closing.
+func AsyncBoxForGroup(ob *objectbox.ObjectBox, timeoutMs uint64) *GroupAsyncBox {
+	var async, err = objectbox.NewAsyncBox(ob, 10, timeoutMs)
+	if err != nil {
+		panic("Could not create async box for entity ID 10: %s" + err.Error())
+	}
+	return &GroupAsyncBox{AsyncBox: async}
+}
+
+// Put inserts/updates a single object asynchronously.
+// When inserting a new object, the Id property on the passed object will be assigned the new ID the entity would hold
+// if the insert is ultimately successful. The newly assigned ID may not become valid if the insert fails.
+func (asyncBox *GroupAsyncBox) Put(object *Group) (uint64, error) {
+	return asyncBox.AsyncBox.Put(object)
+}
+
+// Insert a single object asynchronously.
+// The Id property on the passed object will be assigned the new ID the entity would hold if the insert is ultimately
+// successful. The newly assigned ID may not become valid if the insert fails.
+// Fails silently if an object with the same ID already exists (this error is not returned).
+func (asyncBox *GroupAsyncBox) Insert(object *Group) (id uint64, err error) {
+	return asyncBox.AsyncBox.Insert(object)
+}
+
+// Update a single object asynchronously.
+// The object must already exists or the update fails silently (without an error returned).
+func (asyncBox *GroupAsyncBox) Update(object *Group) error {
+	return asyncBox.AsyncBox.Update(object)
+}
+
+// Remove deletes a single object asynchronously.
+func (asyncBox *GroupAsyncBox) Remove(object *Group) error {
+	return asyncBox.AsyncBox.Remove(object)
+}
+
+// Query provides a way to search stored objects
+//
+// For example, you can find all Group which Id is either 42 or 47:
+//
+//	box.Query(Group_.Id.In(42, 47)).Find()
+type GroupQuery struct {
+	*objectbox.Query
+}
+
+// Find returns all objects matching the query
+func (query *GroupQuery) Find() ([]*Group, error) {
+	objects, err := query.Query.Find()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*Group), nil
+}
+
+// Offset defines the index of the first object to process (how many objects to skip)
+func (query *GroupQuery) Offset(offset uint64) *GroupQuery {
+	query.Query.Offset(offset)
+	return query
+}
+
+// Limit sets the number of elements to process by the query
+func (query *GroupQuery) Limit(limit uint64) *GroupQuery {
+	query.Query.Limit(limit)
+	return query
+}
+
+type groupMember_EntityInfo struct {
+	objectbox.Entity
+	Uid uint64
+}
+
+var GroupMemberBinding = groupMember_EntityInfo{
+	Entity: objectbox.Entity{
+		Id: 11,
+	},
+	Uid: 5285562574331162280,
+}
+
+// GroupMember_ contains type-based Property helpers to facilitate some common operations such as Queries.
+var GroupMember_ = struct {
+	Id           *objectbox.PropertyUint64
+	GroupUuid    *objectbox.PropertyString
+	UserUuid     *objectbox.PropertyString
+	Manager      *objectbox.PropertyBool
+	Restrictions *objectbox.PropertyStringVector
+}{
+	Id: &objectbox.PropertyUint64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     1,
+			Entity: &GroupMemberBinding.Entity,
+		},
+	},
+	GroupUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     2,
+			Entity: &GroupMemberBinding.Entity,
+		},
+	},
+	UserUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     3,
+			Entity: &GroupMemberBinding.Entity,
+		},
+	},
+	Manager: &objectbox.PropertyBool{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     4,
+			Entity: &GroupMemberBinding.Entity,
+		},
+	},
+	Restrictions: &objectbox.PropertyStringVector{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     5,
+			Entity: &GroupMemberBinding.Entity,
+		},
+	},
+}
+
+// GeneratorVersion is called by ObjectBox to verify the compatibility of the generator used to generate this code
+func (groupMember_EntityInfo) GeneratorVersion() int {
+	return 6
+}
+
+// AddToModel is called by ObjectBox during model build
+func (groupMember_EntityInfo) AddToModel(model *objectbox.Model) {
+	model.Entity("GroupMember", 11, 5285562574331162280)
+	model.Property("Id", 6, 1, 8334018554062847086)
+	model.PropertyFlags(1)
+	model.Property("GroupUuid", 9, 2, 1220760667510763002)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(23, 122732439815954516)
+	model.Property("UserUuid", 9, 3, 8489848043880788116)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(24, 3718679921989787501)
+	model.Property("Manager", 1, 4, 5789720435529085869)
+	model.Property("Restrictions", 30, 5, 7397147902383893257)
+	model.EntityLastPropertyId(5, 7397147902383893257)
+}
+
+// GetId is called by ObjectBox during Put operations to check for existing ID on an object
+func (groupMember_EntityInfo) GetId(object interface{}) (uint64, error) {
+	return object.(*GroupMember).Id, nil
+}
+
+// SetId is called by ObjectBox during Put to update an ID on an object that has just been inserted
+func (groupMember_EntityInfo) SetId(object interface{}, id uint64) error {
+	object.(*GroupMember).Id = id
+	return nil
+}
+
+// PutRelated is called by ObjectBox to put related entities before the object itself is flattened and put
+func (groupMember_EntityInfo) PutRelated(ob *objectbox.ObjectBox, object interface{}, id uint64) error {
+	return nil
+}
+
+// Flatten is called by ObjectBox to transform an object to a FlatBuffer
+func (groupMember_EntityInfo) Flatten(object interface{}, fbb *flatbuffers.Builder, id uint64) error {
+	obj := object.(*GroupMember)
+	var offsetGroupUuid = fbutils.CreateStringOffset(fbb, obj.GroupUuid)
+	var offsetUserUuid = fbutils.CreateStringOffset(fbb, obj.UserUuid)
+	var offsetRestrictions = fbutils.CreateStringVectorOffset(fbb, obj.Restrictions)
+
+	// build the FlatBuffers object
+	fbb.StartObject(5)
+	fbutils.SetUint64Slot(fbb, 0, id)
+	fbutils.SetUOffsetTSlot(fbb, 1, offsetGroupUuid)
+	fbutils.SetUOffsetTSlot(fbb, 2, offsetUserUuid)
+	fbutils.SetBoolSlot(fbb, 3, obj.Manager)
+	fbutils.SetUOffsetTSlot(fbb, 4, offsetRestrictions)
+	return nil
+}
+
+// Load is called by ObjectBox to load an object from a FlatBuffer
+func (groupMember_EntityInfo) Load(ob *objectbox.ObjectBox, bytes []byte) (interface{}, error) {
+	if len(bytes) == 0 { // sanity check, should "never" happen
+		return nil, errors.New("can't deserialize an object of type 'GroupMember' - no data received")
+	}
+
+	var table = &flatbuffers.Table{
+		Bytes: bytes,
+		Pos:   flatbuffers.GetUOffsetT(bytes),
+	}
+
+	var propId = table.GetUint64Slot(4, 0)
+
+	return &GroupMember{
+		Id:           propId,
+		GroupUuid:    fbutils.GetStringSlot(table, 6),
+		UserUuid:     fbutils.GetStringSlot(table, 8),
+		Manager:      fbutils.GetBoolSlot(table, 10),
+		Restrictions: fbutils.GetStringVectorSlot(table, 12),
+	}, nil
+}
+
+// MakeSlice is called by ObjectBox to construct a new slice to hold the read objects
+func (groupMember_EntityInfo) MakeSlice(capacity int) interface{} {
+	return make([]*GroupMember, 0, capacity)
+}
+
+// AppendToSlice is called by ObjectBox to fill the slice of the read objects
+func (groupMember_EntityInfo) AppendToSlice(slice interface{}, object interface{}) interface{} {
+	if object == nil {
+		return append(slice.([]*GroupMember), nil)
+	}
+	return append(slice.([]*GroupMember), object.(*GroupMember))
+}
+
+// Box provides CRUD access to GroupMember objects
+type GroupMemberBox struct {
+	*objectbox.Box
+}
+
+// BoxForGroupMember opens a box of GroupMember objects
+func BoxForGroupMember(ob *objectbox.ObjectBox) *GroupMemberBox {
+	return &GroupMemberBox{
+		Box: ob.InternalBox(11),
+	}
+}
+
+// Put synchronously inserts/updates a single object.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the GroupMember.Id property on the passed object will be assigned the new ID as well.
+func (box *GroupMemberBox) Put(object *GroupMember) (uint64, error) {
+	return box.Box.Put(object)
+}
+
+// Insert synchronously inserts a single object. As opposed to Put, Insert will fail if given an ID that already exists.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the GroupMember.Id property on the passed object will be assigned the new ID as well.
+func (box *GroupMemberBox) Insert(object *GroupMember) (uint64, error) {
+	return box.Box.Insert(object)
+}
+
+// Update synchronously updates a single object.
+// As opposed to Put, Update will fail if an object with the same ID is not found in the database.
+func (box *GroupMemberBox) Update(object *GroupMember) error {
+	return box.Box.Update(object)
+}
+
+// PutAsync asynchronously inserts/updates a single object.
+// Deprecated: use box.Async().Put() instead
+func (box *GroupMemberBox) PutAsync(object *GroupMember) (uint64, error) {
+	return box.Box.PutAsync(object)
+}
+
+// PutMany inserts multiple objects in single transaction.
+// In case Ids are not set on the objects, they would be assigned automatically (auto-increment).
+//
+// Returns: IDs of the put objects (in the same order).
+// When inserting, the GroupMember.Id property on the objects in the slice will be assigned the new IDs as well.
+//
+// Note: In case an error occurs during the transaction, some of the objects may already have the GroupMember.Id assigned
+// even though the transaction has been rolled back and the objects are not stored under those IDs.
+//
+// Note: The slice may be empty or even nil; in both cases, an empty IDs slice and no error is returned.
+func (box *GroupMemberBox) PutMany(objects []*GroupMember) ([]uint64, error) {
+	return box.Box.PutMany(objects)
+}
+
+// Get reads a single object.
+//
+// Returns nil (and no error) in case the object with the given ID doesn't exist.
+func (box *GroupMemberBox) Get(id uint64) (*GroupMember, error) {
+	object, err := box.Box.Get(id)
+	if err != nil {
+		return nil, err
+	} else if object == nil {
+		return nil, nil
+	}
+	return object.(*GroupMember), nil
+}
+
+// GetMany reads multiple objects at once.
+// If any of the objects doesn't exist, its position in the return slice is nil
+func (box *GroupMemberBox) GetMany(ids ...uint64) ([]*GroupMember, error) {
+	objects, err := box.Box.GetMany(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*GroupMember), nil
+}
+
+// GetManyExisting reads multiple objects at once, skipping those that do not exist.
+func (box *GroupMemberBox) GetManyExisting(ids ...uint64) ([]*GroupMember, error) {
+	objects, err := box.Box.GetManyExisting(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*GroupMember), nil
+}
+
+// GetAll reads all stored objects
+func (box *GroupMemberBox) GetAll() ([]*GroupMember, error) {
+	objects, err := box.Box.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*GroupMember), nil
+}
+
+// Remove deletes a single object
+func (box *GroupMemberBox) Remove(object *GroupMember) error {
+	return box.Box.Remove(object)
+}
+
+// RemoveMany deletes multiple objects at once.
+// Returns the number of deleted object or error on failure.
+// Note that this method will not fail if an object is not found (e.g. already removed).
+// In case you need to strictly check whether all of the objects exist before removing them,
+// you can execute multiple box.Contains() and box.Remove() inside a single write transaction.
+func (box *GroupMemberBox) RemoveMany(objects ...*GroupMember) (uint64, error) {
+	var ids = make([]uint64, len(objects))
+	for k, object := range objects {
+		ids[k] = object.Id
+	}
+	return box.Box.RemoveIds(ids...)
+}
+
+// Creates a query with the given conditions. Use the fields of the GroupMember_ struct to create conditions.
+// Keep the *GroupMemberQuery if you intend to execute the query multiple times.
+// Note: this function panics if you try to create illegal queries; e.g. use properties of an alien type.
+// This is typically a programming error. Use QueryOrError instead if you want the explicit error check.
+func (box *GroupMemberBox) Query(conditions ...objectbox.Condition) *GroupMemberQuery {
+	return &GroupMemberQuery{
+		box.Box.Query(conditions...),
+	}
+}
+
+// Creates a query with the given conditions. Use the fields of the GroupMember_ struct to create conditions.
+// Keep the *GroupMemberQuery if you intend to execute the query multiple times.
+func (box *GroupMemberBox) QueryOrError(conditions ...objectbox.Condition) (*GroupMemberQuery, error) {
+	if query, err := box.Box.QueryOrError(conditions...); err != nil {
+		return nil, err
+	} else {
+		return &GroupMemberQuery{query}, nil
+	}
+}
+
+// Async provides access to the default Async Box for asynchronous operations. See GroupMemberAsyncBox for more information.
+func (box *GroupMemberBox) Async() *GroupMemberAsyncBox {
+	return &GroupMemberAsyncBox{AsyncBox: box.Box.Async()}
+}
+
+// GroupMemberAsyncBox provides asynchronous operations on GroupMember objects.
+//
+// Asynchronous operations are executed on a separate internal thread for better performance.
+//
+// There are two main use cases:
+//
+// 1) "execute & forget:" you gain faster put/remove operations as you don't have to wait for the transaction to finish.
+//
+// 2) Many small transactions: if your write load is typically a lot of individual puts that happen in parallel,
+// this will merge small transactions into bigger ones. This results in a significant gain in overall throughput.
+//
+// In situations with (extremely) high async load, an async method may be throttled (~1ms) or delayed up to 1 second.
+// In the unlikely event that the object could still not be enqueued (full queue), an error will be returned.
+//
+// Note that async methods do not give you hard durability guarantees like the synchronous Box provides.
+// There is a small time window in which the data may not have been committed durably yet.
+type GroupMemberAsyncBox struct {
+	*objectbox.AsyncBox
+}
+
+// AsyncBoxForGroupMember creates a new async box with the given operation timeout in case an async queue is full.
+// The returned struct must be freed explicitly using the Close() method.
+// It's usually preferable to use GroupMemberBox::Async() which takes care of resource management and doesn't require closing.
+func AsyncBoxForGroupMember(ob *objectbox.ObjectBox, timeoutMs uint64) *GroupMemberAsyncBox {
+	var async, err = objectbox.NewAsyncBox(ob, 11, timeoutMs)
+	if err != nil {
+		panic("Could not create async box for entity ID 11: %s" + err.Error())
+	}
+	return &GroupMemberAsyncBox{AsyncBox: async}
+}
+
+// Put inserts/updates a single object asynchronously.
+// When inserting a new object, the Id property on the passed object will be assigned the new ID the entity would hold
+// if the insert is ultimately successful. The newly assigned ID may not become valid if the insert fails.
+func (asyncBox *GroupMemberAsyncBox) Put(object *GroupMember) (uint64, error) {
+	return asyncBox.AsyncBox.Put(object)
+}
+
+// Insert a single object asynchronously.
+// The Id property on the passed object will be assigned the new ID the entity would hold if the insert is ultimately
+// successful. The newly assigned ID may not become valid if the insert fails.
+// Fails silently if an object with the same ID already exists (this error is not returned).
+func (asyncBox *GroupMemberAsyncBox) Insert(object *GroupMember) (id uint64, err error) {
+	return asyncBox.AsyncBox.Insert(object)
+}
+
+// Update a single object asynchronously.
+// The object must already exists or the update fails silently (without an error returned).
+func (asyncBox *GroupMemberAsyncBox) Update(object *GroupMember) error {
+	return asyncBox.AsyncBox.Update(object)
+}
+
+// Remove deletes a single object asynchronously.
+func (asyncBox *GroupMemberAsyncBox) Remove(object *GroupMember) error {
+	return asyncBox.AsyncBox.Remove(object)
+}
+
+// Query provides a way to search stored objects
+//
+// For example, you can find all GroupMember which Id is either 42 or 47:
+//
+//	box.Query(GroupMember_.Id.In(42, 47)).Find()
+type GroupMemberQuery struct {
+	*objectbox.Query
+}
+
+// Find returns all objects matching the query
+func (query *GroupMemberQuery) Find() ([]*GroupMember, error) {
+	objects, err := query.Query.Find()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*GroupMember), nil
+}
+
+// Offset defines the index of the first object to process (how many objects to skip)
+func (query *GroupMemberQuery) Offset(offset uint64) *GroupMemberQuery {
+	query.Query.Offset(offset)
+	return query
+}
+
+// Limit sets the number of elements to process by the query
+func (query *GroupMemberQuery) Limit(limit uint64) *GroupMemberQuery {
+	query.Query.Limit(limit)
+	return query
+}
+
+type externalLogin_EntityInfo struct {
+	objectbox.Entity
+	Uid uint64
+}
+
+var ExternalLoginBinding = externalLogin_EntityInfo{
+	Entity: objectbox.Entity{
+		Id: 12,
+	},
+	Uid: 1365406794865810604,
+}
+
+// ExternalLogin_ contains type-based Property helpers to facilitate some common operations such as Queries.
+var ExternalLogin_ = struct {
+	Id           *objectbox.PropertyUint64
+	Provider     *objectbox.PropertyString
+	Subject      *objectbox.PropertyString
+	UserUuid     *objectbox.PropertyString
+	EmailAddress *objectbox.PropertyString
+	CreatedAt    *objectbox.PropertyInt64
+}{
+	Id: &objectbox.PropertyUint64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     1,
+			Entity: &ExternalLoginBinding.Entity,
+		},
+	},
+	Provider: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     2,
+			Entity: &ExternalLoginBinding.Entity,
+		},
+	},
+	Subject: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     3,
+			Entity: &ExternalLoginBinding.Entity,
+		},
+	},
+	UserUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     4,
+			Entity: &ExternalLoginBinding.Entity,
+		},
+	},
+	EmailAddress: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     5,
+			Entity: &ExternalLoginBinding.Entity,
+		},
+	},
+	CreatedAt: &objectbox.PropertyInt64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     6,
+			Entity: &ExternalLoginBinding.Entity,
+		},
+	},
+}
+
+// GeneratorVersion is called by ObjectBox to verify the compatibility of the generator used to generate this code
+func (externalLogin_EntityInfo) GeneratorVersion() int {
+	return 6
+}
+
+// AddToModel is called by ObjectBox during model build
+func (externalLogin_EntityInfo) AddToModel(model *objectbox.Model) {
+	model.Entity("ExternalLogin", 12, 1365406794865810604)
+	model.Property("Id", 6, 1, 2319060729834576770)
+	model.PropertyFlags(1)
+	model.Property("Provider", 9, 2, 4372956704843605022)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(25, 9005671957798708274)
+	model.Property("Subject", 9, 3, 6587127247943076881)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(26, 4397048092838394260)
+	model.Property("UserUuid", 9, 4, 7898468382193564088)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(27, 718543780949648335)
+	model.Property("EmailAddress", 9, 5, 2308894464534966696)
+	model.Property("CreatedAt", 6, 6, 1612413895590267802)
+	model.EntityLastPropertyId(6, 1612413895590267802)
+}
+
+// GetId is called by ObjectBox during Put operations to check for existing ID on an object
+func (externalLogin_EntityInfo) GetId(object interface{}) (uint64, error) {
+	return object.(*ExternalLogin).Id, nil
+}
+
+// SetId is called by ObjectBox during Put to update an ID on an object that has just been inserted
+func (externalLogin_EntityInfo) SetId(object interface{}, id uint64) error {
+	object.(*ExternalLogin).Id = id
+	return nil
+}
+
+// PutRelated is called by ObjectBox to put related entities before the object itself is flattened and put
+func (externalLogin_EntityInfo) PutRelated(ob *objectbox.ObjectBox, object interface{}, id uint64) error {
+	return nil
+}
+
+// Flatten is called by ObjectBox to transform an object to a FlatBuffer
+func (externalLogin_EntityInfo) Flatten(object interface{}, fbb *flatbuffers.Builder, id uint64) error {
+	obj := object.(*ExternalLogin)
+	var offsetProvider = fbutils.CreateStringOffset(fbb, obj.Provider)
+	var offsetSubject = fbutils.CreateStringOffset(fbb, obj.Subject)
+	var offsetUserUuid = fbutils.CreateStringOffset(fbb, obj.UserUuid)
+	var offsetEmailAddress = fbutils.CreateStringOffset(fbb, obj.EmailAddress)
+
+	// build the FlatBuffers object
+	fbb.StartObject(6)
+	fbutils.SetUint64Slot(fbb, 0, id)
+	fbutils.SetUOffsetTSlot(fbb, 1, offsetProvider)
+	fbutils.SetUOffsetTSlot(fbb, 2, offsetSubject)
+	fbutils.SetUOffsetTSlot(fbb, 3, offsetUserUuid)
+	fbutils.SetUOffsetTSlot(fbb, 4, offsetEmailAddress)
+	fbutils.SetInt64Slot(fbb, 5, obj.CreatedAt)
+	return nil
+}
+
+// Load is called by ObjectBox to load an object from a FlatBuffer
+func (externalLogin_EntityInfo) Load(ob *objectbox.ObjectBox, bytes []byte) (interface{}, error) {
+	if len(bytes) == 0 { // sanity check, should "never" happen
+		return nil, errors.New("can't deserialize an object of type 'ExternalLogin' - no data received")
+	}
+
+	var table = &flatbuffers.Table{
+		Bytes: bytes,
+		Pos:   flatbuffers.GetUOffsetT(bytes),
+	}
+
+	var propId = table.GetUint64Slot(4, 0)
+
+	return &ExternalLogin{
+		Id:           propId,
+		Provider:     fbutils.GetStringSlot(table, 6),
+		Subject:      fbutils.GetStringSlot(table, 8),
+		UserUuid:     fbutils.GetStringSlot(table, 10),
+		EmailAddress: fbutils.GetStringSlot(table, 12),
+		CreatedAt:    fbutils.GetInt64Slot(table, 14),
+	}, nil
+}
+
+// MakeSlice is called by ObjectBox to construct a new slice to hold the read objects
+func (externalLogin_EntityInfo) MakeSlice(capacity int) interface{} {
+	return make([]*ExternalLogin, 0, capacity)
+}
+
+// AppendToSlice is called by ObjectBox to fill the slice of the read objects
+func (externalLogin_EntityInfo) AppendToSlice(slice interface{}, object interface{}) interface{} {
+	if object == nil {
+		return append(slice.([]*ExternalLogin), nil)
+	}
+	return append(slice.([]*ExternalLogin), object.(*ExternalLogin))
+}
+
+// Box provides CRUD access to ExternalLogin objects
+type ExternalLoginBox struct {
+	*objectbox.Box
+}
+
+// BoxForExternalLogin opens a box of ExternalLogin objects
+func BoxForExternalLogin(ob *objectbox.ObjectBox) *ExternalLoginBox {
+	return &ExternalLoginBox{
+		Box: ob.InternalBox(12),
+	}
+}
+
+// Put synchronously inserts/updates a single object.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the ExternalLogin.Id property on the passed object will be assigned the new ID as well.
+func (box *ExternalLoginBox) Put(object *ExternalLogin) (uint64, error) {
+	return box.Box.Put(object)
+}
+
+// Insert synchronously inserts a single object. As opposed to Put, Insert will fail if given an ID that already exists.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the ExternalLogin.Id property on the passed object will be assigned the new ID as well.
+func (box *ExternalLoginBox) Insert(object *ExternalLogin) (uint64, error) {
+	return box.Box.Insert(object)
+}
+
+// Update synchronously updates a single object.
+// As opposed to Put, Update will fail if an object with the same ID is not found in the database.
+func (box *ExternalLoginBox) Update(object *ExternalLogin) error {
+	return box.Box.Update(object)
+}
+
+// PutAsync asynchronously inserts/updates a single object.
+// Deprecated: use box.Async().Put() instead
+func (box *ExternalLoginBox) PutAsync(object *ExternalLogin) (uint64, error) {
+	return box.Box.PutAsync(object)
+}
+
+// PutMany inserts multiple objects in single transaction.
+// In case Ids are not set on the objects, they would be assigned automatically (auto-increment).
+//
+// Returns: IDs of the put objects (in the same order).
+// When inserting, the ExternalLogin.Id property on the objects in the slice will be assigned the new IDs as well.
+//
+// Note: In case an error occurs during the transaction, some of the objects may already have the ExternalLogin.Id assigned
+// even though the transaction has been rolled back and the objects are not stored under those IDs.
+//
+// Note: The slice may be empty or even nil; in both cases, an empty IDs slice and no error is returned.
+func (box *ExternalLoginBox) PutMany(objects []*ExternalLogin) ([]uint64, error) {
+	return box.Box.PutMany(objects)
+}
+
+// Get reads a single object.
+//
+// Returns nil (and no error) in case the object with the given ID doesn't exist.
+func (box *ExternalLoginBox) Get(id uint64) (*ExternalLogin, error) {
+	object, err := box.Box.Get(id)
+	if err != nil {
+		return nil, err
+	} else if object == nil {
+		return nil, nil
+	}
+	return object.(*ExternalLogin), nil
+}
+
+// GetMany reads multiple objects at once.
+// If any of the objects doesn't exist, its position in the return slice is nil
+func (box *ExternalLoginBox) GetMany(ids ...uint64) ([]*ExternalLogin, error) {
+	objects, err := box.Box.GetMany(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*ExternalLogin), nil
+}
+
+// GetManyExisting reads multiple objects at once, skipping those that do not exist.
+func (box *ExternalLoginBox) GetManyExisting(ids ...uint64) ([]*ExternalLogin, error) {
+	objects, err := box.Box.GetManyExisting(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*ExternalLogin), nil
+}
+
+// GetAll reads all stored objects
+func (box *ExternalLoginBox) GetAll() ([]*ExternalLogin, error) {
+	objects, err := box.Box.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*ExternalLogin), nil
+}
+
+// Remove deletes a single object
+func (box *ExternalLoginBox) Remove(object *ExternalLogin) error {
+	return box.Box.Remove(object)
+}
+
+// RemoveMany deletes multiple objects at once.
+// Returns the number of deleted object or error on failure.
+// Note that this method will not fail if an object is not found (e.g. already removed).
+// In case you need to strictly check whether all of the objects exist before removing them,
+// you can execute multiple box.Contains() and box.Remove() inside a single write transaction.
+func (box *ExternalLoginBox) RemoveMany(objects ...*ExternalLogin) (uint64, error) {
+	var ids = make([]uint64, len(objects))
+	for k, object := range objects {
+		ids[k] = object.Id
+	}
+	return box.Box.RemoveIds(ids...)
+}
+
+// Creates a query with the given conditions. Use the fields of the ExternalLogin_ struct to create conditions.
+// Keep the *ExternalLoginQuery if you intend to execute the query multiple times.
+// Note: this function panics if you try to create illegal queries; e.g. use properties of an alien type.
+// This is typically a programming error. Use QueryOrError instead if you want the explicit error check.
+func (box *ExternalLoginBox) Query(conditions ...objectbox.Condition) *ExternalLoginQuery {
+	return &ExternalLoginQuery{
+		box.Box.Query(conditions...),
+	}
+}
+
+// Creates a query with the given conditions. Use the fields of the ExternalLogin_ struct to create conditions.
+// Keep the *ExternalLoginQuery if you intend to execute the query multiple times.
+func (box *ExternalLoginBox) QueryOrError(conditions ...objectbox.Condition) (*ExternalLoginQuery, error) {
+	if query, err := box.Box.QueryOrError(conditions...); err != nil {
+		return nil, err
+	} else {
+		return &ExternalLoginQuery{query}, nil
+	}
+}
+
+// Async provides access to the default Async Box for asynchronous operations. See ExternalLoginAsyncBox for more information.
+func (box *ExternalLoginBox) Async() *ExternalLoginAsyncBox {
+	return &ExternalLoginAsyncBox{AsyncBox: box.Box.Async()}
+}
+
+// ExternalLoginAsyncBox provides asynchronous operations on ExternalLogin objects.
+//
+// Asynchronous operations are executed on a separate internal thread for better performance.
+//
+// There are two main use cases:
+//
+// 1) "execute & forget:" you gain faster put/remove operations as you don't have to wait for the transaction to finish.
+//
+// 2) Many small transactions: if your write load is typically a lot of individual puts that happen in parallel,
+// this will merge small transactions into bigger ones. This results in a significant gain in overall throughput.
+//
+// In situations with (extremely) high async load, an async method may be throttled (~1ms) or delayed up to 1 second.
+// In the unlikely event that the object could still not be enqueued (full queue), an error will be returned.
+//
+// Note that async methods do not give you hard durability guarantees like the synchronous Box provides.
+// There is a small time window in which the data may not have been committed durably yet.
+type ExternalLoginAsyncBox struct {
+	*objectbox.AsyncBox
+}
+
+// AsyncBoxForExternalLogin creates a new async box with the given operation timeout in case an async queue is full.
+// The returned struct must be freed explicitly using the Close() method.
+// It's usually preferable to use ExternalLoginBox::Async() which takes care of resource management and doesn't require closing.
+func AsyncBoxForExternalLogin(ob *objectbox.ObjectBox, timeoutMs uint64) *ExternalLoginAsyncBox {
+	var async, err = objectbox.NewAsyncBox(ob, 12, timeoutMs)
+	if err != nil {
+		panic("Could not create async box for entity ID 12: %s" + err.Error())
+	}
+	return &ExternalLoginAsyncBox{AsyncBox: async}
+}
+
+// Put inserts/updates a single object asynchronously.
+// When inserting a new object, the Id property on the passed object will be assigned the new ID the entity would hold
+// if the insert is ultimately successful. The newly assigned ID may not become valid if the insert fails.
+func (asyncBox *ExternalLoginAsyncBox) Put(object *ExternalLogin) (uint64, error) {
+	return asyncBox.AsyncBox.Put(object)
+}
+
+// Insert a single object asynchronously.
+// The Id property on the passed object will be assigned the new ID the entity would hold if the insert is ultimately
+// successful. The newly assigned ID may not become valid if the insert fails.
+// Fails silently if an object with the same ID already exists (this error is not returned).
+func (asyncBox *ExternalLoginAsyncBox) Insert(object *ExternalLogin) (id uint64, err error) {
+	return asyncBox.AsyncBox.Insert(object)
+}
+
+// Update a single object asynchronously.
+// The object must already exists or the update fails silently (without an error returned).
+func (asyncBox *ExternalLoginAsyncBox) Update(object *ExternalLogin) error {
+	return asyncBox.AsyncBox.Update(object)
+}
+
+// Remove deletes a single object asynchronously.
+func (asyncBox *ExternalLoginAsyncBox) Remove(object *ExternalLogin) error {
+	return asyncBox.AsyncBox.Remove(object)
+}
+
+// Query provides a way to search stored objects
+//
+// For example, you can find all ExternalLogin which Id is either 42 or 47:
+//
+//	box.Query(ExternalLogin_.Id.In(42, 47)).Find()
+type ExternalLoginQuery struct {
+	*objectbox.Query
+}
+
+// Find returns all objects matching the query
+func (query *ExternalLoginQuery) Find() ([]*ExternalLogin, error) {
+	objects, err := query.Query.Find()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*ExternalLogin), nil
+}
+
+// Offset defines the index of the first object to process (how many objects to skip)
+func (query *ExternalLoginQuery) Offset(offset uint64) *ExternalLoginQuery {
+	query.Query.Offset(offset)
+	return query
+}
+
+// Limit sets the number of elements to process by the query
+func (query *ExternalLoginQuery) Limit(limit uint64) *ExternalLoginQuery {
+	query.Query.Limit(limit)
+	return query
+}
+
+type apiKey_EntityInfo struct {
+	objectbox.Entity
+	Uid uint64
+}
+
+var ApiKeyBinding = apiKey_EntityInfo{
+	Entity: objectbox.Entity{
+		Id: 13,
+	},
+	Uid: 5379062487498711370,
+}
+
+// ApiKey_ contains type-based Property helpers to facilitate some common operations such as Queries.
+var ApiKey_ = struct {
+	Id         *objectbox.PropertyUint64
+	Uuid       *objectbox.PropertyString
+	UserUuid   *objectbox.PropertyString
+	Name       *objectbox.PropertyString
+	SecretHash *objectbox.PropertyString
+	Scopes     *objectbox.PropertyStringVector
+	CreatedAt  *objectbox.PropertyInt64
+	ExpiresAt  *objectbox.PropertyInt64
+	LastUsed   *objectbox.PropertyInt64
+}{
+	Id: &objectbox.PropertyUint64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     1,
+			Entity: &ApiKeyBinding.Entity,
+		},
+	},
+	Uuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     2,
+			Entity: &ApiKeyBinding.Entity,
+		},
+	},
+	UserUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     3,
+			Entity: &ApiKeyBinding.Entity,
+		},
+	},
+	Name: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     4,
+			Entity: &ApiKeyBinding.Entity,
+		},
+	},
+	SecretHash: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     5,
+			Entity: &ApiKeyBinding.Entity,
+		},
+	},
+	Scopes: &objectbox.PropertyStringVector{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     6,
+			Entity: &ApiKeyBinding.Entity,
+		},
+	},
+	CreatedAt: &objectbox.PropertyInt64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     7,
+			Entity: &ApiKeyBinding.Entity,
+		},
+	},
+	ExpiresAt: &objectbox.PropertyInt64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     8,
+			Entity: &ApiKeyBinding.Entity,
+		},
+	},
+	LastUsed: &objectbox.PropertyInt64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     9,
+			Entity: &ApiKeyBinding.Entity,
+		},
+	},
+}
+
+// GeneratorVersion is called by ObjectBox to verify the compatibility of the generator used to generate this code
+func (apiKey_EntityInfo) GeneratorVersion() int {
+	return 6
+}
+
+// AddToModel is called by ObjectBox during model build
+func (apiKey_EntityInfo) AddToModel(model *objectbox.Model) {
+	model.Entity("ApiKey", 13, 5379062487498711370)
+	model.Property("Id", 6, 1, 1352941022210302087)
+	model.PropertyFlags(1)
+	model.Property("Uuid", 9, 2, 7090262198555954908)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(28, 5017081794322503830)
+	model.Property("UserUuid", 9, 3, 2934998201077093492)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(29, 4824838432063572048)
+	model.Property("Name", 9, 4, 4019241208877530506)
+	model.Property("SecretHash", 9, 5, 7710152667149056703)
+	model.Property("Scopes", 30, 6, 7418422908579391116)
+	model.Property("CreatedAt", 6, 7, 1816852472359759447)
+	model.Property("ExpiresAt", 6, 8, 533703046858670657)
+	model.Property("LastUsed", 6, 9, 1219523843184048647)
+	model.EntityLastPropertyId(9, 1219523843184048647)
+}
+
+// GetId is called by ObjectBox during Put operations to check for existing ID on an object
+func (apiKey_EntityInfo) GetId(object interface{}) (uint64, error) {
+	return object.(*ApiKey).Id, nil
+}
+
+// SetId is called by ObjectBox during Put to update an ID on an object that has just been inserted
+func (apiKey_EntityInfo) SetId(object interface{}, id uint64) error {
+	object.(*ApiKey).Id = id
+	return nil
+}
+
+// PutRelated is called by ObjectBox to put related entities before the object itself is flattened and put
+func (apiKey_EntityInfo) PutRelated(ob *objectbox.ObjectBox, object interface{}, id uint64) error {
+	return nil
+}
+
+// Flatten is called by ObjectBox to transform an object to a FlatBuffer
+func (apiKey_EntityInfo) Flatten(object interface{}, fbb *flatbuffers.Builder, id uint64) error {
+	obj := object.(*ApiKey)
+	var offsetUuid = fbutils.CreateStringOffset(fbb, obj.Uuid)
+	var offsetUserUuid = fbutils.CreateStringOffset(fbb, obj.UserUuid)
+	var offsetName = fbutils.CreateStringOffset(fbb, obj.Name)
+	var offsetSecretHash = fbutils.CreateStringOffset(fbb, obj.SecretHash)
+	var offsetScopes = fbutils.CreateStringVectorOffset(fbb, obj.Scopes)
+
+	// build the FlatBuffers object
+	fbb.StartObject(9)
+	fbutils.SetUint64Slot(fbb, 0, id)
+	fbutils.SetUOffsetTSlot(fbb, 1, offsetUuid)
+	fbutils.SetUOffsetTSlot(fbb, 2, offsetUserUuid)
+	fbutils.SetUOffsetTSlot(fbb, 3, offsetName)
+	fbutils.SetUOffsetTSlot(fbb, 4, offsetSecretHash)
+	fbutils.SetUOffsetTSlot(fbb, 5, offsetScopes)
+	fbutils.SetInt64Slot(fbb, 6, obj.CreatedAt)
+	fbutils.SetInt64Slot(fbb, 7, obj.ExpiresAt)
+	fbutils.SetInt64Slot(fbb, 8, obj.LastUsed)
+	return nil
+}
+
+// Load is called by ObjectBox to load an object from a FlatBuffer
+func (apiKey_EntityInfo) Load(ob *objectbox.ObjectBox, bytes []byte) (interface{}, error) {
+	if len(bytes) == 0 { // sanity check, should "never" happen
+		return nil, errors.New("can't deserialize an object of type 'ApiKey' - no data received")
+	}
+
+	var table = &flatbuffers.Table{
+		Bytes: bytes,
+		Pos:   flatbuffers.GetUOffsetT(bytes),
+	}
+
+	var propId = table.GetUint64Slot(4, 0)
+
+	return &ApiKey{
+		Id:         propId,
+		Uuid:       fbutils.GetStringSlot(table, 6),
+		UserUuid:   fbutils.GetStringSlot(table, 8),
+		Name:       fbutils.GetStringSlot(table, 10),
+		SecretHash: fbutils.GetStringSlot(table, 12),
+		Scopes:     fbutils.GetStringVectorSlot(table, 14),
+		CreatedAt:  fbutils.GetInt64Slot(table, 16),
+		ExpiresAt:  fbutils.GetInt64Slot(table, 18),
+		LastUsed:   fbutils.GetInt64Slot(table, 20),
+	}, nil
+}
+
+// MakeSlice is called by ObjectBox to construct a new slice to hold the read objects
+func (apiKey_EntityInfo) MakeSlice(capacity int) interface{} {
+	return make([]*ApiKey, 0, capacity)
+}
+
+// AppendToSlice is called by ObjectBox to fill the slice of the read objects
+func (apiKey_EntityInfo) AppendToSlice(slice interface{}, object interface{}) interface{} {
+	if object == nil {
+		return append(slice.([]*ApiKey), nil)
+	}
+	return append(slice.([]*ApiKey), object.(*ApiKey))
+}
+
+// Box provides CRUD access to ApiKey objects
+type ApiKeyBox struct {
+	*objectbox.Box
+}
+
+// BoxForApiKey opens a box of ApiKey objects
+func BoxForApiKey(ob *objectbox.ObjectBox) *ApiKeyBox {
+	return &ApiKeyBox{
+		Box: ob.InternalBox(13),
+	}
+}
+
+// Put synchronously inserts/updates a single object.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the ApiKey.Id property on the passed object will be assigned the new ID as well.
+func (box *ApiKeyBox) Put(object *ApiKey) (uint64, error) {
+	return box.Box.Put(object)
+}
+
+// Insert synchronously inserts a single object. As opposed to Put, Insert will fail if given an ID that already exists.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the ApiKey.Id property on the passed object will be assigned the new ID as well.
+func (box *ApiKeyBox) Insert(object *ApiKey) (uint64, error) {
+	return box.Box.Insert(object)
+}
+
+// Update synchronously updates a single object.
+// As opposed to Put, Update will fail if an object with the same ID is not found in the database.
+func (box *ApiKeyBox) Update(object *ApiKey) error {
+	return box.Box.Update(object)
+}
+
+// PutAsync asynchronously inserts/updates a single object.
+// Deprecated: use box.Async().Put() instead
+func (box *ApiKeyBox) PutAsync(object *ApiKey) (uint64, error) {
+	return box.Box.PutAsync(object)
+}
+
+// PutMany inserts multiple objects in single transaction.
+// In case Ids are not set on the objects, they would be assigned automatically (auto-increment).
+//
+// Returns: IDs of the put objects (in the same order).
+// When inserting, the ApiKey.Id property on the objects in the slice will be assigned the new IDs as well.
+//
+// Note: In case an error occurs during the transaction, some of the objects may already have the ApiKey.Id assigned
+// even though the transaction has been rolled back and the objects are not stored under those IDs.
+//
+// Note: The slice may be empty or even nil; in both cases, an empty IDs slice and no error is returned.
+func (box *ApiKeyBox) PutMany(objects []*ApiKey) ([]uint64, error) {
+	return box.Box.PutMany(objects)
+}
+
+// Get reads a single object.
+//
+// Returns nil (and no error) in case the object with the given ID doesn't exist.
+func (box *ApiKeyBox) Get(id uint64) (*ApiKey, error) {
+	object, err := box.Box.Get(id)
+	if err != nil {
+		return nil, err
+	} else if object == nil {
+		return nil, nil
+	}
+	return object.(*ApiKey), nil
+}
+
+// GetMany reads multiple objects at once.
+// If any of the objects doesn't exist, its position in the return slice is nil
+func (box *ApiKeyBox) GetMany(ids ...uint64) ([]*ApiKey, error) {
+	objects, err := box.Box.GetMany(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*ApiKey), nil
+}
+
+// GetManyExisting reads multiple objects at once, skipping those that do not exist.
+func (box *ApiKeyBox) GetManyExisting(ids ...uint64) ([]*ApiKey, error) {
+	objects, err := box.Box.GetManyExisting(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*ApiKey), nil
+}
+
+// GetAll reads all stored objects
+func (box *ApiKeyBox) GetAll() ([]*ApiKey, error) {
+	objects, err := box.Box.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*ApiKey), nil
+}
+
+// Remove deletes a single object
+func (box *ApiKeyBox) Remove(object *ApiKey) error {
+	return box.Box.Remove(object)
+}
+
+// RemoveMany deletes multiple objects at once.
+// Returns the number of deleted object or error on failure.
+// Note that this method will not fail if an object is not found (e.g. already removed).
+// In case you need to strictly check whether all of the objects exist before removing them,
+// you can execute multiple box.Contains() and box.Remove() inside a single write transaction.
+func (box *ApiKeyBox) RemoveMany(objects ...*ApiKey) (uint64, error) {
+	var ids = make([]uint64, len(objects))
+	for k, object := range objects {
+		ids[k] = object.Id
+	}
+	return box.Box.RemoveIds(ids...)
+}
+
+// Creates a query with the given conditions. Use the fields of the ApiKey_ struct to create conditions.
+// Keep the *ApiKeyQuery if you intend to execute the query multiple times.
+// Note: this function panics if you try to create illegal queries; e.g. use properties of an alien type.
+// This is typically a programming error. Use QueryOrError instead if you want the explicit error check.
+func (box *ApiKeyBox) Query(conditions ...objectbox.Condition) *ApiKeyQuery {
+	return &ApiKeyQuery{
+		box.Box.Query(conditions...),
+	}
+}
+
+// Creates a query with the given conditions. Use the fields of the ApiKey_ struct to create conditions.
+// Keep the *ApiKeyQuery if you intend to execute the query multiple times.
+func (box *ApiKeyBox) QueryOrError(conditions ...objectbox.Condition) (*ApiKeyQuery, error) {
+	if query, err := box.Box.QueryOrError(conditions...); err != nil {
+		return nil, err
+	} else {
+		return &ApiKeyQuery{query}, nil
+	}
+}
+
+// Async provides access to the default Async Box for asynchronous operations. See ApiKeyAsyncBox for more information.
+func (box *ApiKeyBox) Async() *ApiKeyAsyncBox {
+	return &ApiKeyAsyncBox{AsyncBox: box.Box.Async()}
+}
+
+// ApiKeyAsyncBox provides asynchronous operations on ApiKey objects.
+//
+// Asynchronous operations are executed on a separate internal thread for better performance.
+//
+// There are two main use cases:
+//
+// 1) "execute & forget:" you gain faster put/remove operations as you don't have to wait for the transaction to finish.
+//
+// 2) Many small transactions: if your write load is typically a lot of individual puts that happen in parallel,
+// this will merge small transactions into bigger ones. This results in a significant gain in overall throughput.
+//
+// In situations with (extremely) high async load, an async method may be throttled (~1ms) or delayed up to 1 second.
+// In the unlikely event that the object could still not be enqueued (full queue), an error will be returned.
+//
+// Note that async methods do not give you hard durability guarantees like the synchronous Box provides.
+// There is a small time window in which the data may not have been committed durably yet.
+type ApiKeyAsyncBox struct {
+	*objectbox.AsyncBox
+}
+
+// AsyncBoxForApiKey creates a new async box with the given operation timeout in case an async queue is full.
+// The returned struct must be freed explicitly using the Close() method.
+// It's usually preferable to use ApiKeyBox::Async() which takes care of resource management and doesn't require closing.
+func AsyncBoxForApiKey(ob *objectbox.ObjectBox, timeoutMs uint64) *ApiKeyAsyncBox {
+	var async, err = objectbox.NewAsyncBox(ob, 13, timeoutMs)
+	if err != nil {
+		panic("Could not create async box for entity ID 13: %s" + err.Error())
+	}
+	return &ApiKeyAsyncBox{AsyncBox: async}
+}
+
+// Put inserts/updates a single object asynchronously.
+// When inserting a new object, the Id property on the passed object will be assigned the new ID the entity would hold
+// if the insert is ultimately successful. The newly assigned ID may not become valid if the insert fails.
+func (asyncBox *ApiKeyAsyncBox) Put(object *ApiKey) (uint64, error) {
+	return asyncBox.AsyncBox.Put(object)
+}
+
+// Insert a single object asynchronously.
+// The Id property on the passed object will be assigned the new ID the entity would hold if the insert is ultimately
+// successful. The newly assigned ID may not become valid if the insert fails.
+// Fails silently if an object with the same ID already exists (this error is not returned).
+func (asyncBox *ApiKeyAsyncBox) Insert(object *ApiKey) (id uint64, err error) {
+	return asyncBox.AsyncBox.Insert(object)
+}
+
+// Update a single object asynchronously.
+// The object must already exists or the update fails silently (without an error returned).
+func (asyncBox *ApiKeyAsyncBox) Update(object *ApiKey) error {
+	return asyncBox.AsyncBox.Update(object)
+}
+
+// Remove deletes a single object asynchronously.
+func (asyncBox *ApiKeyAsyncBox) Remove(object *ApiKey) error {
+	return asyncBox.AsyncBox.Remove(object)
+}
+
+// Query provides a way to search stored objects
+//
+// For example, you can find all ApiKey which Id is either 42 or 47:
+//
+//	box.Query(ApiKey_.Id.In(42, 47)).Find()
+type ApiKeyQuery struct {
+	*objectbox.Query
+}
+
+// Find returns all objects matching the query
+func (query *ApiKeyQuery) Find() ([]*ApiKey, error) {
+	objects, err := query.Query.Find()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*ApiKey), nil
+}
+
+// Offset defines the index of the first object to process (how many objects to skip)
+func (query *ApiKeyQuery) Offset(offset uint64) *ApiKeyQuery {
+	query.Query.Offset(offset)
+	return query
+}
+
+// Limit sets the number of elements to process by the query
+func (query *ApiKeyQuery) Limit(limit uint64) *ApiKeyQuery {
+	query.Query.Limit(limit)
+	return query
+}
+
+type groupInvite_EntityInfo struct {
+	objectbox.Entity
+	Uid uint64
+}
+
+var GroupInviteBinding = groupInvite_EntityInfo{
+	Entity: objectbox.Entity{
+		Id: 14,
+	},
+	Uid: 6121175068780277743,
+}
+
+// GroupInvite_ contains type-based Property helpers to facilitate some common operations such as Queries.
+var GroupInvite_ = struct {
+	Id        *objectbox.PropertyUint64
+	GroupUuid *objectbox.PropertyString
+	UserUuid  *objectbox.PropertyString
+	InvitedBy *objectbox.PropertyString
+	CreatedAt *objectbox.PropertyInt64
+}{
+	Id: &objectbox.PropertyUint64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     1,
+			Entity: &GroupInviteBinding.Entity,
+		},
+	},
+	GroupUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     2,
+			Entity: &GroupInviteBinding.Entity,
+		},
+	},
+	UserUuid: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     3,
+			Entity: &GroupInviteBinding.Entity,
+		},
+	},
+	InvitedBy: &objectbox.PropertyString{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     4,
+			Entity: &GroupInviteBinding.Entity,
+		},
+	},
+	CreatedAt: &objectbox.PropertyInt64{
+		BaseProperty: &objectbox.BaseProperty{
+			Id:     5,
+			Entity: &GroupInviteBinding.Entity,
+		},
+	},
+}
+
+// GeneratorVersion is called by ObjectBox to verify the compatibility of the generator used to generate this code
+func (groupInvite_EntityInfo) GeneratorVersion() int {
+	return 6
+}
+
+// AddToModel is called by ObjectBox during model build
+func (groupInvite_EntityInfo) AddToModel(model *objectbox.Model) {
+	model.Entity("GroupInvite", 14, 6121175068780277743)
+	model.Property("Id", 6, 1, 3895260468189296365)
+	model.PropertyFlags(1)
+	model.Property("GroupUuid", 9, 2, 3618634618206517039)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(30, 3025345714769507842)
+	model.Property("UserUuid", 9, 3, 852944288331664275)
+	model.PropertyFlags(4096)
+	model.PropertyIndex(31, 8821719009232673125)
+	model.Property("InvitedBy", 9, 4, 824404669258454060)
+	model.Property("CreatedAt", 6, 5, 2243518543204311092)
+	model.EntityLastPropertyId(5, 2243518543204311092)
+}
+
+// GetId is called by ObjectBox during Put operations to check for existing ID on an object
+func (groupInvite_EntityInfo) GetId(object interface{}) (uint64, error) {
+	return object.(*GroupInvite).Id, nil
+}
+
+// SetId is called by ObjectBox during Put to update an ID on an object that has just been inserted
+func (groupInvite_EntityInfo) SetId(object interface{}, id uint64) error {
+	object.(*GroupInvite).Id = id
+	return nil
+}
+
+// PutRelated is called by ObjectBox to put related entities before the object itself is flattened and put
+func (groupInvite_EntityInfo) PutRelated(ob *objectbox.ObjectBox, object interface{}, id uint64) error {
+	return nil
+}
+
+// Flatten is called by ObjectBox to transform an object to a FlatBuffer
+func (groupInvite_EntityInfo) Flatten(object interface{}, fbb *flatbuffers.Builder, id uint64) error {
+	obj := object.(*GroupInvite)
+	var offsetGroupUuid = fbutils.CreateStringOffset(fbb, obj.GroupUuid)
+	var offsetUserUuid = fbutils.CreateStringOffset(fbb, obj.UserUuid)
+	var offsetInvitedBy = fbutils.CreateStringOffset(fbb, obj.InvitedBy)
+
+	// build the FlatBuffers object
+	fbb.StartObject(5)
+	fbutils.SetUint64Slot(fbb, 0, id)
+	fbutils.SetUOffsetTSlot(fbb, 1, offsetGroupUuid)
+	fbutils.SetUOffsetTSlot(fbb, 2, offsetUserUuid)
+	fbutils.SetUOffsetTSlot(fbb, 3, offsetInvitedBy)
+	fbutils.SetInt64Slot(fbb, 4, obj.CreatedAt)
+	return nil
+}
+
+// Load is called by ObjectBox to load an object from a FlatBuffer
+func (groupInvite_EntityInfo) Load(ob *objectbox.ObjectBox, bytes []byte) (interface{}, error) {
+	if len(bytes) == 0 { // sanity check, should "never" happen
+		return nil, errors.New("can't deserialize an object of type 'GroupInvite' - no data received")
+	}
+
+	var table = &flatbuffers.Table{
+		Bytes: bytes,
+		Pos:   flatbuffers.GetUOffsetT(bytes),
+	}
+
+	var propId = table.GetUint64Slot(4, 0)
+
+	return &GroupInvite{
+		Id:        propId,
+		GroupUuid: fbutils.GetStringSlot(table, 6),
+		UserUuid:  fbutils.GetStringSlot(table, 8),
+		InvitedBy: fbutils.GetStringSlot(table, 10),
+		CreatedAt: fbutils.GetInt64Slot(table, 12),
+	}, nil
+}
+
+// MakeSlice is called by ObjectBox to construct a new slice to hold the read objects
+func (groupInvite_EntityInfo) MakeSlice(capacity int) interface{} {
+	return make([]*GroupInvite, 0, capacity)
+}
+
+// AppendToSlice is called by ObjectBox to fill the slice of the read objects
+func (groupInvite_EntityInfo) AppendToSlice(slice interface{}, object interface{}) interface{} {
+	if object == nil {
+		return append(slice.([]*GroupInvite), nil)
+	}
+	return append(slice.([]*GroupInvite), object.(*GroupInvite))
+}
+
+// Box provides CRUD access to GroupInvite objects
+type GroupInviteBox struct {
+	*objectbox.Box
+}
+
+// BoxForGroupInvite opens a box of GroupInvite objects
+func BoxForGroupInvite(ob *objectbox.ObjectBox) *GroupInviteBox {
+	return &GroupInviteBox{
+		Box: ob.InternalBox(14),
+	}
+}
+
+// Put synchronously inserts/updates a single object.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the GroupInvite.Id property on the passed object will be assigned the new ID as well.
+func (box *GroupInviteBox) Put(object *GroupInvite) (uint64, error) {
+	return box.Box.Put(object)
+}
+
+// Insert synchronously inserts a single object. As opposed to Put, Insert will fail if given an ID that already exists.
+// In case the Id is not specified, it would be assigned automatically (auto-increment).
+// When inserting, the GroupInvite.Id property on the passed object will be assigned the new ID as well.
+func (box *GroupInviteBox) Insert(object *GroupInvite) (uint64, error) {
+	return box.Box.Insert(object)
+}
+
+// Update synchronously updates a single object.
+// As opposed to Put, Update will fail if an object with the same ID is not found in the database.
+func (box *GroupInviteBox) Update(object *GroupInvite) error {
+	return box.Box.Update(object)
+}
+
+// PutAsync asynchronously inserts/updates a single object.
+// Deprecated: use box.Async().Put() instead
+func (box *GroupInviteBox) PutAsync(object *GroupInvite) (uint64, error) {
+	return box.Box.PutAsync(object)
+}
+
+// PutMany inserts multiple objects in single transaction.
+// In case Ids are not set on the objects, they would be assigned automatically (auto-increment).
+//
+// Returns: IDs of the put objects (in the same order).
+// When inserting, the GroupInvite.Id property on the objects in the slice will be assigned the new IDs as well.
+//
+// Note: In case an error occurs during the transaction, some of the objects may already have the GroupInvite.Id assigned
+// even though the transaction has been rolled back and the objects are not stored under those IDs.
+//
+// Note: The slice may be empty or even nil; in both cases, an empty IDs slice and no error is returned.
+func (box *GroupInviteBox) PutMany(objects []*GroupInvite) ([]uint64, error) {
+	return box.Box.PutMany(objects)
+}
+
+// Get reads a single object.
+//
+// Returns nil (and no error) in case the object with the given ID doesn't exist.
+func (box *GroupInviteBox) Get(id uint64) (*GroupInvite, error) {
+	object, err := box.Box.Get(id)
+	if err != nil {
+		return nil, err
+	} else if object == nil {
+		return nil, nil
+	}
+	return object.(*GroupInvite), nil
+}
+
+// GetMany reads multiple objects at once.
+// If any of the objects doesn't exist, its position in the return slice is nil
+func (box *GroupInviteBox) GetMany(ids ...uint64) ([]*GroupInvite, error) {
+	objects, err := box.Box.GetMany(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*GroupInvite), nil
+}
+
+// GetManyExisting reads multiple objects at once, skipping those that do not exist.
+func (box *GroupInviteBox) GetManyExisting(ids ...uint64) ([]*GroupInvite, error) {
+	objects, err := box.Box.GetManyExisting(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*GroupInvite), nil
+}
+
+// GetAll reads all stored objects
+func (box *GroupInviteBox) GetAll() ([]*GroupInvite, error) {
+	objects, err := box.Box.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*GroupInvite), nil
+}
+
+// Remove deletes a single object
+func (box *GroupInviteBox) Remove(object *GroupInvite) error {
+	return box.Box.Remove(object)
+}
+
+// RemoveMany deletes multiple objects at once.
+// Returns the number of deleted object or error on failure.
+// Note that this method will not fail if an object is not found (e.g. already removed).
+// In case you need to strictly check whether all of the objects exist before removing them,
+// you can execute multiple box.Contains() and box.Remove() inside a single write transaction.
+func (box *GroupInviteBox) RemoveMany(objects ...*GroupInvite) (uint64, error) {
+	var ids = make([]uint64, len(objects))
+	for k, object := range objects {
+		ids[k] = object.Id
+	}
+	return box.Box.RemoveIds(ids...)
+}
+
+// Creates a query with the given conditions. Use the fields of the GroupInvite_ struct to create conditions.
+// Keep the *GroupInviteQuery if you intend to execute the query multiple times.
+// Note: this function panics if you try to create illegal queries; e.g. use properties of an alien type.
+// This is typically a programming error. Use QueryOrError instead if you want the explicit error check.
+func (box *GroupInviteBox) Query(conditions ...objectbox.Condition) *GroupInviteQuery {
+	return &GroupInviteQuery{
+		box.Box.Query(conditions...),
+	}
+}
+
+// Creates a query with the given conditions. Use the fields of the GroupInvite_ struct to create conditions.
+// Keep the *GroupInviteQuery if you intend to execute the query multiple times.
+func (box *GroupInviteBox) QueryOrError(conditions ...objectbox.Condition) (*GroupInviteQuery, error) {
+	if query, err := box.Box.QueryOrError(conditions...); err != nil {
+		return nil, err
+	} else {
+		return &GroupInviteQuery{query}, nil
+	}
+}
+
+// Async provides access to the default Async Box for asynchronous operations. See GroupInviteAsyncBox for more information.
+func (box *GroupInviteBox) Async() *GroupInviteAsyncBox {
+	return &GroupInviteAsyncBox{AsyncBox: box.Box.Async()}
+}
+
+// GroupInviteAsyncBox provides asynchronous operations on GroupInvite objects.
+//
+// Asynchronous operations are executed on a separate internal thread for better performance.
+//
+// There are two main use cases:
+//
+// 1) "execute & forget:" you gain faster put/remove operations as you don't have to wait for the transaction to finish.
+//
+// 2) Many small transactions: if your write load is typically a lot of individual puts that happen in parallel,
+// this will merge small transactions into bigger ones. This results in a significant gain in overall throughput.
+//
+// In situations with (extremely) high async load, an async method may be throttled (~1ms) or delayed up to 1 second.
+// In the unlikely event that the object could still not be enqueued (full queue), an error will be returned.
+//
+// Note that async methods do not give you hard durability guarantees like the synchronous Box provides.
+// There is a small time window in which the data may not have been committed durably yet.
+type GroupInviteAsyncBox struct {
+	*objectbox.AsyncBox
+}
+
+// AsyncBoxForGroupInvite creates a new async box with the given operation timeout in case an async queue is full.
+// The returned struct must be freed explicitly using the Close() method.
+// It's usually preferable to use GroupInviteBox::Async() which takes care of resource management and doesn't require closing.
+func AsyncBoxForGroupInvite(ob *objectbox.ObjectBox, timeoutMs uint64) *GroupInviteAsyncBox {
+	var async, err = objectbox.NewAsyncBox(ob, 14, timeoutMs)
+	if err != nil {
+		panic("Could not create async box for entity ID 14: %s" + err.Error())
+	}
+	return &GroupInviteAsyncBox{AsyncBox: async}
+}
+
+// Put inserts/updates a single object asynchronously.
+// When inserting a new object, the Id property on the passed object will be assigned the new ID the entity would hold
+// if the insert is ultimately successful. The newly assigned ID may not become valid if the insert fails.
+func (asyncBox *GroupInviteAsyncBox) Put(object *GroupInvite) (uint64, error) {
+	return asyncBox.AsyncBox.Put(object)
+}
+
+// Insert a single object asynchronously.
+// The Id property on the passed object will be assigned the new ID the entity would hold if the insert is ultimately
+// successful. The newly assigned ID may not become valid if the insert fails.
+// Fails silently if an object with the same ID already exists (this error is not returned).
+func (asyncBox *GroupInviteAsyncBox) Insert(object *GroupInvite) (id uint64, err error) {
+	return asyncBox.AsyncBox.Insert(object)
+}
+
+// Update a single object asynchronously.
+// The object must already exists or the update fails silently (without an error returned).
+func (asyncBox *GroupInviteAsyncBox) Update(object *GroupInvite) error {
+	return asyncBox.AsyncBox.Update(object)
+}
+
+// Remove deletes a single object asynchronously.
+func (asyncBox *GroupInviteAsyncBox) Remove(object *GroupInvite) error {
+	return asyncBox.AsyncBox.Remove(object)
+}
+
+// Query provides a way to search stored objects
+//
+// For example, you can find all GroupInvite which Id is either 42 or 47:
+//
+//	box.Query(GroupInvite_.Id.In(42, 47)).Find()
+type GroupInviteQuery struct {
+	*objectbox.Query
+}
+
+// Find returns all objects matching the query
+func (query *GroupInviteQuery) Find() ([]*GroupInvite, error) {
+	objects, err := query.Query.Find()
+	if err != nil {
+		return nil, err
+	}
+	return objects.([]*GroupInvite), nil
+}
+
+// Offset defines the index of the first object to process (how many objects to skip)
+func (query *GroupInviteQuery) Offset(offset uint64) *GroupInviteQuery {
+	query.Query.Offset(offset)
+	return query
+}
+
+// Limit sets the number of elements to process by the query
+func (query *GroupInviteQuery) Limit(limit uint64) *GroupInviteQuery {
+	query.Query.Limit(limit)
+	return query
+}
@@ -0,0 +1,34 @@
+// Code generated by ObjectBox; DO NOT EDIT.
+
+package objectbox
+
+import (
+	"github.com/objectbox/objectbox-go/objectbox"
+)
+
+// ObjectBoxModel declares and builds the model from all the entities in the package.
+// It is usually used when setting-up ObjectBox as an argument to the Builder.Model() function.
+func ObjectBoxModel() *objectbox.Model {
+	model := objectbox.NewModel()
+	model.GeneratorVersion(6)
+
+	model.RegisterBinding(TrackBinding)
+	model.RegisterBinding(PlaylistBinding)
+	model.RegisterBinding(FriendBinding)
+	model.RegisterBinding(UserBinding)
+	model.RegisterBinding(DeviceBinding)
+	model.RegisterBinding(ResumePositionBinding)
+	model.RegisterBinding(PlayBinding)
+	model.RegisterBinding(PlaylistShareBinding)
+	model.RegisterBinding(RevokedTokenBinding)
+	model.RegisterBinding(GroupBinding)
+	model.RegisterBinding(GroupMemberBinding)
+	model.RegisterBinding(ExternalLoginBinding)
+	model.RegisterBinding(ApiKeyBinding)
+	model.RegisterBinding(GroupInviteBinding)
+	model.LastEntityId(14, 6121175068780277743)
+	model.LastIndexId(31, 8821719009232673125)
+	model.LastRelationId(5, 7938334410148932394)
+
+	return model
+}
@@ -0,0 +1,28 @@
+//go:build !noobjectbox
+// +build !noobjectbox
+
+package objectbox
+
+// These link the ObjectBox native library, build with -tags noobjectbox to
+// leave them out.
+
+import (
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/storage/storagetest"
+	"testing"
+)
+
+func openTestStorage(t *testing.T) *Storage {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open database: %s", err.Error())
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestStorage(t *testing.T) {
+	storagetest.TestDataStorage(t, func(t *testing.T) storage.DataStorage {
+		return openTestStorage(t)
+	})
+}
@@ -0,0 +1,1377 @@
+// Package objectbox is the storage driver backed by an ObjectBox database.
+// It needs the ObjectBox native library to be installed.
+package objectbox
+
+import (
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/objectbox/objectbox-go/objectbox"
+)
+
+func init() {
+	storage.Register("objectbox", func(dataSource string) (storage.DataStorage, error) {
+		return Open(dataSource)
+	})
+}
+
+type Storage struct {
+	ob *objectbox.ObjectBox
+}
+
+// Open builds the ObjectBox database in directory, or the ObjectBox default
+// directory when it is blank
+func Open(directory string) (*Storage, error) {
+	builder := objectbox.NewBuilder().Model(ObjectBoxModel())
+	if directory != "" {
+		builder = builder.Directory(directory)
+	}
+	ob, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	return &Storage{ob: ob}, nil
+}
+
+func (s *Storage) Close() error {
+	s.ob.Close()
+	return nil
+}
+
+// The entities in this package mirror the storage model, so the conversions
+// are done with a field by field copy.
+
+func toUser(src *User) *storage.User {
+	for _, p := range src.Playlists {
+		p.applyTrackOrder()
+	}
+	dest := &storage.User{}
+	storage.DeepCopy(src, dest)
+	// Friends stored before friend requests existed were added directly
+	for _, f := range dest.Friends {
+		if f.Status == "" {
+			f.Status = storage.FriendAccepted
+		}
+	}
+	if len(dest.Roles) == 0 {
+		dest.Roles = []string{storage.RoleMember}
+		if src.AdminUser {
+			dest.Roles = []string{storage.RoleAdmin}
+		}
+	}
+	return dest
+}
+
+func fromUser(src *storage.User) *User {
+	dest := &User{}
+	storage.DeepCopy(src, dest)
+	for _, p := range dest.Playlists {
+		p.storeTrackOrder()
+	}
+	return dest
+}
+
+func toPlaylist(src *Playlist) *storage.Playlist {
+	src.applyTrackOrder()
+	dest := &storage.Playlist{}
+	storage.DeepCopy(src, dest)
+	return dest
+}
+
+func fromPlaylist(src *storage.Playlist) *Playlist {
+	dest := &Playlist{}
+	storage.DeepCopy(src, dest)
+	// ObjectBox won't update a relation from a nil slice, which is what the
+	// copy makes of a playlist without tracks
+	if dest.Tracks == nil {
+		dest.Tracks = []*Track{}
+	}
+	dest.storeTrackOrder()
+	return dest
+}
+
+func (p *Playlist) storeTrackOrder() {
+	p.TrackOrder = nil
+	for _, t := range p.Tracks {
+		p.TrackOrder = append(p.TrackOrder, t.Uuid)
+	}
+}
+
+// applyTrackOrder sorts Tracks into playlist order, any track missing from
+// TrackOrder goes last
+func (p *Playlist) applyTrackOrder() {
+	positions := make(map[string]int)
+	for i, uuid := range p.TrackOrder {
+		positions[uuid] = i
+	}
+	position := func(t *Track) int {
+		if i, ok := positions[t.Uuid]; ok {
+			return i
+		}
+		return len(p.TrackOrder)
+	}
+	sort.SliceStable(p.Tracks, func(i, j int) bool {
+		return position(p.Tracks[i]) < position(p.Tracks[j])
+	})
+}
+
+func toTrack(src *Track) *storage.Track {
+	dest := &storage.Track{}
+	storage.DeepCopy(src, dest)
+	return dest
+}
+
+func fromTrack(src *storage.Track) *Track {
+	dest := &Track{}
+	storage.DeepCopy(src, dest)
+	return dest
+}
+
+func (s *Storage) findUser(m *storage.User) (*User, error) {
+	box := BoxForUser(s.ob)
+
+	if m.Id != 0 {
+		return box.Get(m.Id)
+	}
+	var condition objectbox.Condition
+	if m.Uuid != "" {
+		condition = User_.Uuid.Equals(m.Uuid, true)
+	} else if m.EmailAddress != "" {
+		condition = User_.EmailAddress.Equals(m.EmailAddress, false)
+	} else {
+		return nil, storage.ErrMissingId
+	}
+	users, err := box.Query(condition).Limit(1).Find()
+	if err != nil || len(users) == 0 {
+		return nil, err
+	}
+	return users[0], nil
+}
+
+// changeUser puts the stored user back after change has been made to it, so
+// its relations are the stored ones rather than whatever the caller loaded
+func (s *Storage) changeUser(m *storage.User, change func(user *User)) error {
+	if m.Id == 0 {
+		return storage.ErrMissingId
+	}
+	box := BoxForUser(s.ob)
+	return s.ob.RunInWriteTx(func() error {
+		user, err := box.Get(m.Id)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return storage.ErrUserNotFound
+		}
+		change(user)
+		_, err = box.Put(user)
+		return err
+	})
+}
+
+func (s *Storage) InsertUser(m *storage.User) (*uint64, error) {
+	box := BoxForUser(s.ob)
+	findResults, err := box.Query(User_.EmailAddress.Equals(m.EmailAddress, false)).Count()
+	if err != nil {
+		return nil, err
+	}
+	if findResults > 0 {
+		return nil, storage.ErrUserExists
+	}
+	m.Uuid = uuid.NewString()
+	user := fromUser(m)
+	id, err := box.Put(user)
+	if err != nil {
+		return nil, err
+	}
+	*m = *toUser(user)
+	return &id, nil
+}
+
+func (s *Storage) UpdateUser(m *storage.User) error {
+	err := s.changeUser(m, func(user *User) {
+		updated := fromUser(m)
+		updated.Tracks = user.Tracks
+		updated.Playlists = user.Playlists
+		updated.Friends = user.Friends
+		updated.Devices = user.Devices
+		*user = *updated
+	})
+	if err != nil {
+		return err
+	}
+	return s.SelectUser(m)
+}
+
+func (s *Storage) UseUserTotpStep(m *storage.User, expectedStep int64) error {
+	box := BoxForUser(s.ob)
+	return s.ob.RunInWriteTx(func() error {
+		user, err := box.Get(m.Id)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return storage.ErrUserNotFound
+		}
+		if user.TotpLastStep != expectedStep {
+			return storage.ErrSecondFactorUsed
+		}
+		user.TotpLastStep = m.TotpLastStep
+		_, err = box.Put(user)
+		return err
+	})
+}
+
+func (s *Storage) UseUserRecoveryCode(m *storage.User, hash string) error {
+	box := BoxForUser(s.ob)
+	return s.ob.RunInWriteTx(func() error {
+		user, err := box.Get(m.Id)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return storage.ErrUserNotFound
+		}
+		for i, code := range user.TotpRecoveryCodes {
+			if code == hash {
+				user.TotpRecoveryCodes = append(user.TotpRecoveryCodes[:i:i], user.TotpRecoveryCodes[i+1:]...)
+				if _, err := box.Put(user); err != nil {
+					return err
+				}
+				m.TotpRecoveryCodes = append([]string(nil), user.TotpRecoveryCodes...)
+				return nil
+			}
+		}
+		return storage.ErrSecondFactorUsed
+	})
+}
+
+// touchUserGroups moves on the revision of every group the user is in or has
+// been invited to, before they are taken out of them
+func (s *Storage) touchUserGroups(userUuid string) error {
+	groupUuids := map[string]bool{}
+	members, err := BoxForGroupMember(s.ob).Query(GroupMember_.UserUuid.Equals(userUuid, true)).Find()
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		groupUuids[member.GroupUuid] = true
+	}
+	invites, err := BoxForGroupInvite(s.ob).Query(GroupInvite_.UserUuid.Equals(userUuid, true)).Find()
+	if err != nil {
+		return err
+	}
+	for _, invite := range invites {
+		groupUuids[invite.GroupUuid] = true
+	}
+	box := BoxForGroup(s.ob)
+	for groupUuid := range groupUuids {
+		found, err := box.Query(Group_.Uuid.Equals(groupUuid, true)).Find()
+		if err != nil {
+			return err
+		}
+		for _, group := range found {
+			group.Revision++
+			if _, err := box.Put(group); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Storage) DeleteUser(m *storage.User) error {
+	box := BoxForUser(s.ob)
+	return s.ob.RunInWriteTx(func() error {
+		user, err := box.Get(m.Id)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return storage.ErrUserNotFound
+		}
+		if err := s.touchUserGroups(user.Uuid); err != nil {
+			return err
+		}
+		_, err = BoxForGroupMember(s.ob).Query(GroupMember_.UserUuid.Equals(user.Uuid, true)).Remove()
+		if err != nil {
+			return err
+		}
+		_, err = BoxForGroupInvite(s.ob).Query(GroupInvite_.UserUuid.Equals(user.Uuid, true)).Remove()
+		if err != nil {
+			return err
+		}
+		_, err = BoxForExternalLogin(s.ob).Query(ExternalLogin_.UserUuid.Equals(user.Uuid, true)).Remove()
+		if err != nil {
+			return err
+		}
+		_, err = BoxForApiKey(s.ob).Query(ApiKey_.UserUuid.Equals(user.Uuid, true)).Remove()
+		if err != nil {
+			return err
+		}
+		return box.RemoveId(m.Id)
+	})
+}
+
+func (s *Storage) SelectUser(m *storage.User) error {
+	user, err := s.findUser(m)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return storage.ErrUserNotFound
+	}
+	*m = *toUser(user)
+	return nil
+}
+
+func (s *Storage) UserExists(m *storage.User) (bool, error) {
+	user, err := s.findUser(m)
+	if err != nil {
+		return false, err
+	}
+	return user != nil, nil
+}
+
+func (s *Storage) FindUsers(filter storage.UserFilter) ([]*storage.User, error) {
+	box := BoxForUser(s.ob)
+	var conditions []objectbox.Condition
+	if filter.Uuid != "" {
+		conditions = append(conditions, User_.Uuid.Equals(filter.Uuid, true))
+	}
+	if filter.EmailAddress != "" {
+		conditions = append(conditions, User_.EmailAddress.Equals(filter.EmailAddress, false))
+	}
+	if filter.PlaylistUuid != "" {
+		conditions = append(conditions, User_.Playlists.Link(Playlist_.Uuid.Equals(filter.PlaylistUuid, true)))
+	}
+	users, err := box.Query(conditions...).Find()
+	if err != nil {
+		return nil, err
+	}
+	var result []*storage.User
+	for _, user := range users {
+		// Roles are checked after converting, users stored before roles only
+		// have AdminUser
+		m := toUser(user)
+		if filter.Role != "" && !m.HasRole(filter.Role) {
+			continue
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+func (s *Storage) UserAddPlaylist(m *storage.User, p *storage.Playlist) (*uint64, error) {
+	p.Uuid = uuid.NewString()
+	playlist := fromPlaylist(p)
+	err := s.changeUser(m, func(user *User) {
+		user.Playlists = append(user.Playlists, playlist)
+	})
+	if err != nil {
+		return nil, err
+	}
+	*p = *toPlaylist(playlist)
+	m.Playlists = append(m.Playlists, p)
+	return &m.Id, nil
+}
+
+func (s *Storage) UpdatePlaylist(p *storage.Playlist) error {
+	if p.Id == 0 {
+		return storage.ErrMissingId
+	}
+	box := BoxForPlaylist(s.ob)
+	_, err := box.Put(fromPlaylist(p))
+	return err
+}
+
+func (s *Storage) UpdatePlaylistAtRevision(p *storage.Playlist, revision uint64) error {
+	box := BoxForPlaylist(s.ob)
+	return s.ob.RunInWriteTx(func() error {
+		stored, err := box.Get(p.Id)
+		if err != nil {
+			return err
+		}
+		if stored == nil {
+			return storage.ErrPlaylistNotFound
+		}
+		if stored.PositionRevision != revision {
+			return storage.ErrRevisionConflict
+		}
+		stored.Name = p.Name
+		stored.CurrentTrackUuid = p.CurrentTrackUuid
+		stored.Elapsed = p.Elapsed
+		stored.PositionRevision = p.PositionRevision
+		stored.PositionUpdatedAt = p.PositionUpdatedAt
+		if _, err := box.Put(stored); err != nil {
+			return err
+		}
+		*p = *toPlaylist(stored)
+		return nil
+	})
+}
+
+func (s *Storage) UpdatePlaylistLock(p *storage.Playlist, heldBy string) error {
+	box := BoxForPlaylist(s.ob)
+	return s.ob.RunInWriteTx(func() error {
+		stored, err := box.Get(p.Id)
+		if err != nil {
+			return err
+		}
+		if stored == nil {
+			return storage.ErrPlaylistNotFound
+		}
+		if stored.LockDeviceUuid != heldBy {
+			return storage.ErrLockConflict
+		}
+		stored.LockDeviceUuid = p.LockDeviceUuid
+		stored.ClientLockExpires = p.ClientLockExpires
+		_, err = box.Put(stored)
+		return err
+	})
+}
+
+func (s *Storage) ReorderPlaylistTracks(p *storage.Playlist, trackUuids []string) error {
+	box := BoxForPlaylist(s.ob)
+	return s.ob.RunInWriteTx(func() error {
+		stored, err := box.Get(p.Id)
+		if err != nil {
+			return err
+		}
+		if stored == nil {
+			return storage.ErrPlaylistNotFound
+		}
+		playlist := toPlaylist(stored)
+		tracks, err := storage.OrderTracks(playlist.Tracks, trackUuids)
+		if err != nil {
+			return err
+		}
+		stored.TrackOrder = trackUuids
+		if _, err := box.Put(stored); err != nil {
+			return err
+		}
+		p.Tracks = tracks
+		return nil
+	})
+}
+
+func (s *Storage) DeletePlaylist(p *storage.Playlist) error {
+	box := BoxForPlaylist(s.ob)
+	return s.ob.RunInWriteTx(func() error {
+		playlist, err := box.Get(p.Id)
+		if err != nil {
+			return err
+		}
+		if playlist == nil {
+			return storage.ErrPlaylistNotFound
+		}
+		_, err = BoxForPlaylistShare(s.ob).Query(PlaylistShare_.PlaylistUuid.Equals(playlist.Uuid, true)).Remove()
+		if err != nil {
+			return err
+		}
+		return box.RemoveId(p.Id)
+	})
+}
+
+func (s *Storage) findPlaylist(p *storage.Playlist) (*Playlist, error) {
+	box := BoxForPlaylist(s.ob)
+	if p.Id != 0 {
+		return box.Get(p.Id)
+	}
+	if p.Uuid == "" {
+		return nil, storage.ErrMissingId
+	}
+	playlists, err := box.Query(Playlist_.Uuid.Equals(p.Uuid, true)).Limit(1).Find()
+	if err != nil || len(playlists) == 0 {
+		return nil, err
+	}
+	return playlists[0], nil
+}
+
+func (s *Storage) SelectPlaylist(p *storage.Playlist) error {
+	playlist, err := s.findPlaylist(p)
+	if err != nil {
+		return err
+	}
+	if playlist == nil {
+		return storage.ErrPlaylistNotFound
+	}
+	*p = *toPlaylist(playlist)
+	return nil
+}
+
+func (s *Storage) PlaylistExists(p *storage.Playlist) (bool, error) {
+	playlist, err := s.findPlaylist(p)
+	if err != nil {
+		return false, err
+	}
+	return playlist != nil, nil
+}
+
+func (s *Storage) FindPlaylists(filter storage.PlaylistFilter) ([]*storage.Playlist, error) {
+	var playlists []*Playlist
+	if filter.OwnerEmail != "" {
+		// The relation is owned by the user, so walk it from that side
+		users, err := BoxForUser(s.ob).Query(User_.EmailAddress.Equals(filter.OwnerEmail, false)).Find()
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			playlists = append(playlists, user.Playlists...)
+		}
+	} else {
+		var conditions []objectbox.Condition
+		if filter.Uuid != "" {
+			conditions = append(conditions, Playlist_.Uuid.Equals(filter.Uuid, true))
+		}
+		if filter.GroupUuid != "" {
+			conditions = append(conditions, Playlist_.GroupUuid.Equals(filter.GroupUuid, true))
+		}
+		if filter.LockDeviceUuid != "" {
+			conditions = append(conditions, Playlist_.LockDeviceUuid.Equals(filter.LockDeviceUuid, true))
+		}
+		if filter.TrackUuid != "" {
+			conditions = append(conditions, Playlist_.Tracks.Link(Track_.Uuid.Equals(filter.TrackUuid, true)))
+		}
+		found, err := BoxForPlaylist(s.ob).Query(conditions...).Find()
+		if err != nil {
+			return nil, err
+		}
+		playlists = found
+	}
+
+	var result []*storage.Playlist
+	for _, playlist := range playlists {
+		if (filter.Uuid != "" && playlist.Uuid != filter.Uuid) ||
+			(filter.GroupUuid != "" && playlist.GroupUuid != filter.GroupUuid) ||
+			(filter.LockDeviceUuid != "" && playlist.LockDeviceUuid != filter.LockDeviceUuid) {
+			continue
+		}
+		converted := toPlaylist(playlist)
+		if filter.TrackUuid != "" && !hasTrack(converted.Tracks, filter.TrackUuid) {
+			continue
+		}
+		result = append(result, converted)
+	}
+	return result, nil
+}
+
+func hasTrack(tracks []*storage.Track, trackUuid string) bool {
+	for _, track := range tracks {
+		if track.Uuid == trackUuid {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Storage) UserAddTrack(m *storage.User, t *storage.Track) (*uint64, error) {
+	t.Uuid = uuid.NewString()
+	track := fromTrack(t)
+	err := s.changeUser(m, func(user *User) {
+		user.Tracks = append(user.Tracks, track)
+	})
+	if err != nil {
+		return nil, err
+	}
+	*t = *toTrack(track)
+	m.Tracks = append(m.Tracks, t)
+	return &t.Id, nil
+}
+
+func (s *Storage) PlaylistAddTrack(p *storage.Playlist, t *storage.Track) (*uint64, error) {
+	box := BoxForPlaylist(s.ob)
+	trackBox := BoxForTrack(s.ob)
+	if t.Uuid == "" {
+		t.Uuid = uuid.NewString()
+	}
+	err := s.ob.RunInWriteTx(func() error {
+		stored, err := box.Get(p.Id)
+		if err != nil {
+			return err
+		}
+		if stored == nil {
+			return storage.ErrPlaylistNotFound
+		}
+		if t.Id == 0 {
+			id, err := trackBox.Put(fromTrack(t))
+			if err != nil {
+				return err
+			}
+			t.Id = id
+		}
+		track, err := trackBox.Get(t.Id)
+		if err != nil {
+			return err
+		}
+		if track == nil {
+			return storage.ErrTrackNotFound
+		}
+		stored.applyTrackOrder()
+		for _, existing := range stored.Tracks {
+			if existing.Id == track.Id {
+				*p = *toPlaylist(stored)
+				return nil
+			}
+		}
+		stored.Tracks = append(stored.Tracks, track)
+		stored.storeTrackOrder()
+		if _, err := box.Put(stored); err != nil {
+			return err
+		}
+		*p = *toPlaylist(stored)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &p.Id, nil
+}
+
+func (s *Storage) PlaylistRemoveTrack(p *storage.Playlist, t *storage.Track) error {
+	box := BoxForPlaylist(s.ob)
+	return s.ob.RunInWriteTx(func() error {
+		stored, err := box.Get(p.Id)
+		if err != nil {
+			return err
+		}
+		if stored == nil {
+			return storage.ErrPlaylistNotFound
+		}
+		stored.applyTrackOrder()
+		var tracks []*Track
+		for _, track := range stored.Tracks {
+			if track.Id != t.Id {
+				tracks = append(tracks, track)
+			}
+		}
+		if len(tracks) == len(stored.Tracks) {
+			return storage.ErrTrackNotFound
+		}
+		stored.Tracks = tracks
+		stored.storeTrackOrder()
+		if _, err := box.Put(stored); err != nil {
+			return err
+		}
+		*p = *toPlaylist(stored)
+		return nil
+	})
+}
+
+func (s *Storage) UpdateTrack(t *storage.Track) error {
+	if t.Id == 0 {
+		return storage.ErrMissingId
+	}
+	box := BoxForTrack(s.ob)
+	_, err := box.Put(fromTrack(t))
+	return err
+}
+
+func (s *Storage) DeleteTrack(t *storage.Track) error {
+	box := BoxForTrack(s.ob)
+	return box.RemoveId(t.Id)
+}
+
+func (s *Storage) SelectTrack(t *storage.Track) error {
+	box := BoxForTrack(s.ob)
+	var track *Track
+	if t.Id != 0 {
+		found, err := box.Get(t.Id)
+		if err != nil {
+			return err
+		}
+		track = found
+	} else if t.Uuid != "" {
+		found, err := box.Query(Track_.Uuid.Equals(t.Uuid, true)).Limit(1).Find()
+		if err != nil {
+			return err
+		}
+		if len(found) > 0 {
+			track = found[0]
+		}
+	} else {
+		return storage.ErrMissingId
+	}
+	if track == nil {
+		return storage.ErrTrackNotFound
+	}
+	*t = *toTrack(track)
+	return nil
+}
+
+func (s *Storage) FindTracks(filter storage.TrackFilter) ([]*storage.Track, error) {
+	var tracks []*Track
+	if filter.OwnerEmail != "" {
+		users, err := BoxForUser(s.ob).Query(User_.EmailAddress.Equals(filter.OwnerEmail, false)).Find()
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			tracks = append(tracks, user.Tracks...)
+			for _, playlist := range user.Playlists {
+				tracks = append(tracks, playlist.Tracks...)
+			}
+		}
+	} else {
+		var conditions []objectbox.Condition
+		if filter.Uuid != "" {
+			conditions = append(conditions, Track_.Uuid.Equals(filter.Uuid, true))
+		}
+		found, err := BoxForTrack(s.ob).Query(conditions...).Find()
+		if err != nil {
+			return nil, err
+		}
+		tracks = found
+	}
+
+	var result []*storage.Track
+	seen := make(map[uint64]bool)
+	for _, track := range tracks {
+		if seen[track.Id] {
+			continue
+		}
+		seen[track.Id] = true
+		if t := toTrack(track); filter.Matches(t) {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func (s *Storage) UserAddFriend(m *storage.User, f *storage.Friend) (*uint64, error) {
+	friend := &Friend{}
+	storage.DeepCopy(f, friend)
+	err := s.changeUser(m, func(user *User) {
+		user.Friends = append(user.Friends, friend)
+	})
+	if err != nil {
+		return nil, err
+	}
+	f.Id = friend.Id
+	m.Friends = append(m.Friends, f)
+	return &f.Id, nil
+}
+
+func (s *Storage) UpdateFriend(f *storage.Friend) error {
+	if f.Id == 0 {
+		return storage.ErrMissingId
+	}
+	box := BoxForFriend(s.ob)
+	return s.ob.RunInWriteTx(func() error {
+		found, err := box.Get(f.Id)
+		if err != nil {
+			return err
+		}
+		if found == nil {
+			return storage.ErrFriendNotFound
+		}
+		friend := &Friend{}
+		storage.DeepCopy(f, friend)
+		_, err = box.Put(friend)
+		return err
+	})
+}
+
+func (s *Storage) DeleteFriend(f *storage.Friend) error {
+	box := BoxForFriend(s.ob)
+	return box.RemoveId(f.Id)
+}
+
+func (s *Storage) SavePlaylistShare(share *storage.PlaylistShare) error {
+	box := BoxForPlaylistShare(s.ob)
+	return s.ob.RunInWriteTx(func() error {
+		found, err := box.Query(
+			PlaylistShare_.PlaylistUuid.Equals(share.PlaylistUuid, true),
+			PlaylistShare_.UserUuid.Equals(share.UserUuid, true)).Limit(1).Find()
+		if err != nil {
+			return err
+		}
+		share.Id = 0
+		if len(found) > 0 {
+			share.Id = found[0].Id
+		}
+		stored := &PlaylistShare{}
+		storage.DeepCopy(share, stored)
+		id, err := box.Put(stored)
+		if err != nil {
+			return err
+		}
+		share.Id = id
+		return nil
+	})
+}
+
+func (s *Storage) DeletePlaylistShare(share *storage.PlaylistShare) error {
+	box := BoxForPlaylistShare(s.ob)
+	found, err := box.Get(share.Id)
+	if err != nil {
+		return err
+	}
+	if found == nil {
+		return storage.ErrShareNotFound
+	}
+	return box.RemoveId(share.Id)
+}
+
+func (s *Storage) FindPlaylistShares(filter storage.PlaylistShareFilter) ([]*storage.PlaylistShare, error) {
+	var conditions []objectbox.Condition
+	if filter.PlaylistUuid != "" {
+		conditions = append(conditions, PlaylistShare_.PlaylistUuid.Equals(filter.PlaylistUuid, true))
+	}
+	if filter.OwnerUuid != "" {
+		conditions = append(conditions, PlaylistShare_.OwnerUuid.Equals(filter.OwnerUuid, true))
+	}
+	if filter.UserUuid != "" {
+		conditions = append(conditions, PlaylistShare_.UserUuid.Equals(filter.UserUuid, true))
+	}
+	conditions = append(conditions, PlaylistShare_.Id.OrderAsc())
+	found, err := BoxForPlaylistShare(s.ob).Query(conditions...).Find()
+	if err != nil {
+		return nil, err
+	}
+	shares := []*storage.PlaylistShare{}
+	for _, share := range found {
+		result := &storage.PlaylistShare{}
+		storage.DeepCopy(share, result)
+		shares = append(shares, result)
+	}
+	return shares, nil
+}
+
+// checkGroupRevision returns ErrGroupConflict when the stored group has moved
+// on from g
+func (s *Storage) checkGroupRevision(g *storage.Group) error {
+	stored, err := BoxForGroup(s.ob).Get(g.Id)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		return storage.ErrGroupNotFound
+	}
+	if stored.Revision != g.Revision {
+		return storage.ErrGroupConflict
+	}
+	return nil
+}
+
+func (s *Storage) SaveGroup(g *storage.Group) error {
+	box := BoxForGroup(s.ob)
+	members := BoxForGroupMember(s.ob)
+	invites := BoxForGroupInvite(s.ob)
+	return s.ob.RunInWriteTx(func() error {
+		for _, member := range g.Members {
+			found, err := members.Query(GroupMember_.UserUuid.Equals(member.UserUuid, true)).Find()
+			if err != nil {
+				return err
+			}
+			if len(found) > 0 && found[0].GroupUuid != g.Uuid {
+				return storage.ErrAlreadyInGroup
+			}
+		}
+		revision := uint64(0)
+		if g.Id == 0 {
+			g.Uuid = uuid.NewString()
+		} else if err := s.checkGroupRevision(g); err != nil {
+			return err
+		} else {
+			revision = g.Revision + 1
+		}
+		id, err := box.Put(&Group{Id: g.Id, Uuid: g.Uuid, Name: g.Name, CreatedAt: g.CreatedAt, Revision: revision})
+		if err != nil {
+			return err
+		}
+		g.Id = id
+		g.Revision = revision
+		if _, err := members.Query(GroupMember_.GroupUuid.Equals(g.Uuid, true)).Remove(); err != nil {
+			return err
+		}
+		for _, member := range g.Members {
+			_, err := members.Put(&GroupMember{GroupUuid: g.Uuid, UserUuid: member.UserUuid,
+				Manager: member.Manager, Restrictions: member.Restrictions})
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := invites.Query(GroupInvite_.GroupUuid.Equals(g.Uuid, true)).Remove(); err != nil {
+			return err
+		}
+		for _, invite := range g.Invites {
+			_, err := invites.Put(&GroupInvite{GroupUuid: g.Uuid, UserUuid: invite.UserUuid,
+				InvitedBy: invite.InvitedBy, CreatedAt: invite.CreatedAt})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Storage) DeleteGroup(g *storage.Group) error {
+	box := BoxForGroup(s.ob)
+	return s.ob.RunInWriteTx(func() error {
+		if err := s.checkGroupRevision(g); err != nil {
+			return err
+		}
+		group, err := box.Get(g.Id)
+		if err != nil {
+			return err
+		}
+		playlists := BoxForPlaylist(s.ob)
+		found, err := playlists.Query(Playlist_.GroupUuid.Equals(group.Uuid, true)).Find()
+		if err != nil {
+			return err
+		}
+		for _, playlist := range found {
+			playlist.GroupUuid = ""
+			if _, err := playlists.Put(playlist); err != nil {
+				return err
+			}
+		}
+		if _, err := BoxForGroupMember(s.ob).Query(GroupMember_.GroupUuid.Equals(group.Uuid, true)).Remove(); err != nil {
+			return err
+		}
+		if _, err := BoxForGroupInvite(s.ob).Query(GroupInvite_.GroupUuid.Equals(group.Uuid, true)).Remove(); err != nil {
+			return err
+		}
+		return box.RemoveId(g.Id)
+	})
+}
+
+func (s *Storage) FindGroups(filter storage.GroupFilter) ([]*storage.Group, error) {
+	members := BoxForGroupMember(s.ob)
+	invites := BoxForGroupInvite(s.ob)
+	conditions := []objectbox.Condition{Group_.Id.OrderAsc()}
+	if filter.Uuid != "" {
+		conditions = append(conditions, Group_.Uuid.Equals(filter.Uuid, true))
+	}
+	if filter.MemberUuid != "" {
+		found, err := members.Query(GroupMember_.UserUuid.Equals(filter.MemberUuid, true)).Find()
+		if err != nil {
+			return nil, err
+		}
+		if len(found) == 0 {
+			return []*storage.Group{}, nil
+		}
+		conditions = append(conditions, Group_.Uuid.Equals(found[0].GroupUuid, true))
+	}
+	found, err := BoxForGroup(s.ob).Query(conditions...).Find()
+	if err != nil {
+		return nil, err
+	}
+	groups := []*storage.Group{}
+	for _, group := range found {
+		result := &storage.Group{Id: group.Id, Uuid: group.Uuid, Name: group.Name, CreatedAt: group.CreatedAt,
+			Revision: group.Revision}
+		stored, err := members.Query(GroupMember_.GroupUuid.Equals(group.Uuid, true), GroupMember_.Id.OrderAsc()).Find()
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range stored {
+			result.Members = append(result.Members, &storage.GroupMember{UserUuid: member.UserUuid,
+				Manager: member.Manager, Restrictions: member.Restrictions})
+		}
+		invited, err := invites.Query(GroupInvite_.GroupUuid.Equals(group.Uuid, true), GroupInvite_.Id.OrderAsc()).Find()
+		if err != nil {
+			return nil, err
+		}
+		for _, invite := range invited {
+			result.Invites = append(result.Invites, &storage.GroupInvite{UserUuid: invite.UserUuid,
+				InvitedBy: invite.InvitedBy, CreatedAt: invite.CreatedAt})
+		}
+		if filter.InviteeUuid != "" && result.Invite(filter.InviteeUuid) == nil {
+			continue
+		}
+		groups = append(groups, result)
+	}
+	return groups, nil
+}
+
+func (s *Storage) InsertExternalLogin(l *storage.ExternalLogin) (*uint64, error) {
+	box := BoxForExternalLogin(s.ob)
+	err := s.ob.RunInWriteTx(func() error {
+		count, err := box.Query(ExternalLogin_.Provider.Equals(l.Provider, true),
+			objectbox.Any(ExternalLogin_.Subject.Equals(l.Subject, true), ExternalLogin_.UserUuid.Equals(l.UserUuid, true))).Count()
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return storage.ErrLoginExists
+		}
+		stored := &ExternalLogin{}
+		storage.DeepCopy(l, stored)
+		stored.Id = 0
+		id, err := box.Put(stored)
+		l.Id = id
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &l.Id, nil
+}
+
+func (s *Storage) DeleteExternalLogin(l *storage.ExternalLogin) error {
+	box := BoxForExternalLogin(s.ob)
+	stored, err := box.Get(l.Id)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		return storage.ErrLoginNotFound
+	}
+	return box.RemoveId(l.Id)
+}
+
+func (s *Storage) FindExternalLogins(filter storage.ExternalLoginFilter) ([]*storage.ExternalLogin, error) {
+	var conditions []objectbox.Condition
+	if filter.Provider != "" {
+		conditions = append(conditions, ExternalLogin_.Provider.Equals(filter.Provider, true))
+	}
+	if filter.Subject != "" {
+		conditions = append(conditions, ExternalLogin_.Subject.Equals(filter.Subject, true))
+	}
+	if filter.UserUuid != "" {
+		conditions = append(conditions, ExternalLogin_.UserUuid.Equals(filter.UserUuid, true))
+	}
+	conditions = append(conditions, ExternalLogin_.Id.OrderAsc())
+	found, err := BoxForExternalLogin(s.ob).Query(conditions...).Find()
+	if err != nil {
+		return nil, err
+	}
+	logins := []*storage.ExternalLogin{}
+	for _, login := range found {
+		result := &storage.ExternalLogin{}
+		storage.DeepCopy(login, result)
+		logins = append(logins, result)
+	}
+	return logins, nil
+}
+
+func (s *Storage) InsertApiKey(k *storage.ApiKey) (*uint64, error) {
+	k.Uuid = uuid.NewString()
+	stored := &ApiKey{}
+	storage.DeepCopy(k, stored)
+	stored.Id = 0
+	id, err := BoxForApiKey(s.ob).Put(stored)
+	if err != nil {
+		return nil, err
+	}
+	k.Id = id
+	return &k.Id, nil
+}
+
+func (s *Storage) UpdateApiKey(k *storage.ApiKey) error {
+	box := BoxForApiKey(s.ob)
+	return s.ob.RunInWriteTx(func() error {
+		stored, err := box.Get(k.Id)
+		if err != nil {
+			return err
+		}
+		if stored == nil {
+			return storage.ErrApiKeyNotFound
+		}
+		stored.Name = k.Name
+		stored.Scopes = k.Scopes
+		stored.ExpiresAt = k.ExpiresAt
+		stored.LastUsed = k.LastUsed
+		_, err = box.Put(stored)
+		return err
+	})
+}
+
+func (s *Storage) DeleteApiKey(k *storage.ApiKey) error {
+	box := BoxForApiKey(s.ob)
+	stored, err := box.Get(k.Id)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		return storage.ErrApiKeyNotFound
+	}
+	return box.RemoveId(k.Id)
+}
+
+func (s *Storage) FindApiKeys(filter storage.ApiKeyFilter) ([]*storage.ApiKey, error) {
+	var conditions []objectbox.Condition
+	if filter.Uuid != "" {
+		conditions = append(conditions, ApiKey_.Uuid.Equals(filter.Uuid, true))
+	}
+	if filter.UserUuid != "" {
+		conditions = append(conditions, ApiKey_.UserUuid.Equals(filter.UserUuid, true))
+	}
+	conditions = append(conditions, ApiKey_.Id.OrderAsc())
+	found, err := BoxForApiKey(s.ob).Query(conditions...).Find()
+	if err != nil {
+		return nil, err
+	}
+	keys := []*storage.ApiKey{}
+	for _, key := range found {
+		result := &storage.ApiKey{}
+		storage.DeepCopy(key, result)
+		keys = append(keys, result)
+	}
+	return keys, nil
+}
+
+func toDevice(src *Device) *storage.Device {
+	dest := &storage.Device{}
+	storage.DeepCopy(src, dest)
+	return dest
+}
+
+func fromDevice(src *storage.Device) *Device {
+	dest := &Device{}
+	storage.DeepCopy(src, dest)
+	return dest
+}
+
+func (s *Storage) UserAddDevice(m *storage.User, d *storage.Device) (*uint64, error) {
+	d.Uuid = uuid.NewString()
+	device := fromDevice(d)
+	err := s.changeUser(m, func(user *User) {
+		user.Devices = append(user.Devices, device)
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.Id = device.Id
+	m.Devices = append(m.Devices, d)
+	return &d.Id, nil
+}
+
+func (s *Storage) UpdateDevice(d *storage.Device) error {
+	if d.Id == 0 {
+		return storage.ErrMissingId
+	}
+	box := BoxForDevice(s.ob)
+	_, err := box.Put(fromDevice(d))
+	return err
+}
+
+func (s *Storage) UpdateDeviceDetails(d *storage.Device) error {
+	box := BoxForDevice(s.ob)
+	return s.ob.RunInWriteTx(func() error {
+		stored, err := box.Get(d.Id)
+		if err != nil {
+			return err
+		}
+		if stored == nil {
+			return storage.ErrDeviceNotFound
+		}
+		stored.Name = d.Name
+		stored.LastSeen = d.LastSeen
+		_, err = box.Put(stored)
+		return err
+	})
+}
+
+func (s *Storage) RotateDeviceRefreshToken(d *storage.Device, expectedHash string) error {
+	box := BoxForDevice(s.ob)
+	return s.ob.RunInWriteTx(func() error {
+		stored, err := box.Get(d.Id)
+		if err != nil {
+			return err
+		}
+		if stored == nil {
+			return storage.ErrDeviceNotFound
+		}
+		if stored.RefreshTokenHash != expectedHash {
+			return storage.ErrRefreshConflict
+		}
+		stored.LastSeen = d.LastSeen
+		stored.LastTokenId = d.LastTokenId
+		stored.RefreshTokenHash = d.RefreshTokenHash
+		stored.RefreshTokenExpires = d.RefreshTokenExpires
+		stored.RefreshTokenFamily = d.RefreshTokenFamily
+		stored.RefreshTokenGeneration = d.RefreshTokenGeneration
+		stored.Scopes = d.Scopes
+		_, err = box.Put(stored)
+		return err
+	})
+}
+
+func (s *Storage) DeleteDevice(d *storage.Device) error {
+	box := BoxForDevice(s.ob)
+	return box.RemoveId(d.Id)
+}
+
+func (s *Storage) SelectDevice(d *storage.Device) error {
+	box := BoxForDevice(s.ob)
+	var device *Device
+	if d.Id != 0 {
+		found, err := box.Get(d.Id)
+		if err != nil {
+			return err
+		}
+		device = found
+	} else if d.Uuid != "" {
+		found, err := box.Query(Device_.Uuid.Equals(d.Uuid, true)).Limit(1).Find()
+		if err != nil {
+			return err
+		}
+		if len(found) > 0 {
+			device = found[0]
+		}
+	} else {
+		return storage.ErrMissingId
+	}
+	if device == nil {
+		return storage.ErrDeviceNotFound
+	}
+	*d = *toDevice(device)
+	return nil
+}
+
+func (s *Storage) FindDevices(filter storage.DeviceFilter) ([]*storage.Device, error) {
+	var devices []*Device
+	if filter.OwnerEmail != "" {
+		users, err := BoxForUser(s.ob).Query(User_.EmailAddress.Equals(filter.OwnerEmail, false)).Find()
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			devices = append(devices, user.Devices...)
+		}
+	} else {
+		var conditions []objectbox.Condition
+		if filter.Uuid != "" {
+			conditions = append(conditions, Device_.Uuid.Equals(filter.Uuid, true))
+		}
+		found, err := BoxForDevice(s.ob).Query(conditions...).Find()
+		if err != nil {
+			return nil, err
+		}
+		devices = found
+	}
+
+	var result []*storage.Device
+	for _, device := range devices {
+		if filter.Uuid != "" && device.Uuid != filter.Uuid {
+			continue
+		}
+		result = append(result, toDevice(device))
+	}
+	return result, nil
+}
+
+func (s *Storage) SaveResumePosition(r *storage.ResumePosition) error {
+	box := BoxForResumePosition(s.ob)
+	return s.ob.RunInWriteTx(func() error {
+		found, err := box.Query(
+			ResumePosition_.UserUuid.Equals(r.UserUuid, true),
+			ResumePosition_.TrackUuid.Equals(r.TrackUuid, true)).Limit(1).Find()
+		if err != nil {
+			return err
+		}
+		r.Id = 0
+		if len(found) > 0 {
+			r.Id = found[0].Id
+		}
+		position := &ResumePosition{}
+		storage.DeepCopy(r, position)
+		id, err := box.Put(position)
+		if err != nil {
+			return err
+		}
+		r.Id = id
+		return nil
+	})
+}
+
+func (s *Storage) FindResumePositions(filter storage.ResumePositionFilter) ([]*storage.ResumePosition, error) {
+	var conditions []objectbox.Condition
+	if filter.UserUuid != "" {
+		conditions = append(conditions, ResumePosition_.UserUuid.Equals(filter.UserUuid, true))
+	}
+	if filter.TrackUuid != "" {
+		conditions = append(conditions, ResumePosition_.TrackUuid.Equals(filter.TrackUuid, true))
+	}
+	conditions = append(conditions, ResumePosition_.UpdatedAt.OrderDesc(), ResumePosition_.Id.OrderDesc())
+	query := BoxForResumePosition(s.ob).Query(conditions...)
+	if filter.Limit > 0 {
+		query.Limit(uint64(filter.Limit))
+	}
+	found, err := query.Find()
+	if err != nil {
+		return nil, err
+	}
+	positions := []*storage.ResumePosition{}
+	for _, position := range found {
+		r := &storage.ResumePosition{}
+		storage.DeepCopy(position, r)
+		positions = append(positions, r)
+	}
+	return positions, nil
+}
+
+func (s *Storage) AddPlay(p *storage.Play) (*uint64, error) {
+	play := &Play{}
+	storage.DeepCopy(p, play)
+	play.Id = 0
+	id, err := BoxForPlay(s.ob).Put(play)
+	if err != nil {
+		return nil, err
+	}
+	p.Id = id
+	return &p.Id, nil
+}
+
+func (s *Storage) FindPlays(filter storage.PlayFilter) ([]*storage.Play, error) {
+	conditions := []objectbox.Condition{Play_.EndedAt.GreaterOrEqual(filter.Since)}
+	if filter.UserUuid != "" {
+		conditions = append(conditions, Play_.UserUuid.Equals(filter.UserUuid, true))
+	}
+	if filter.TrackUuid != "" {
+		conditions = append(conditions, Play_.TrackUuid.Equals(filter.TrackUuid, true))
+	}
+	conditions = append(conditions, Play_.EndedAt.OrderDesc(), Play_.Id.OrderDesc())
+	query := BoxForPlay(s.ob).Query(conditions...)
+	if filter.Limit > 0 {
+		query.Limit(uint64(filter.Limit))
+	}
+	found, err := query.Find()
+	if err != nil {
+		return nil, err
+	}
+	plays := []*storage.Play{}
+	for _, play := range found {
+		p := &storage.Play{}
+		storage.DeepCopy(play, p)
+		plays = append(plays, p)
+	}
+	return plays, nil
+}
+
+func (s *Storage) RevokeToken(t *storage.RevokedToken) error {
+	box := BoxForRevokedToken(s.ob)
+	return s.ob.RunInWriteTx(func() error {
+		found, err := box.Query(RevokedToken_.TokenId.Equals(t.TokenId, true)).Limit(1).Find()
+		if err != nil {
+			return err
+		}
+		if len(found) > 0 {
+			t.Id = found[0].Id
+			return nil
+		}
+		stored := &RevokedToken{}
+		storage.DeepCopy(t, stored)
+		stored.Id = 0
+		id, err := box.Put(stored)
+		if err != nil {
+			return err
+		}
+		t.Id = id
+		return nil
+	})
+}
+
+func (s *Storage) IsTokenRevoked(tokenId string) (bool, error) {
+	count, err := BoxForRevokedToken(s.ob).Query(RevokedToken_.TokenId.Equals(tokenId, true)).Count()
+	return count > 0, err
+}
+
+func (s *Storage) PruneRevokedTokens(before int64) (int, error) {
+	pruned, err := BoxForRevokedToken(s.ob).Query(RevokedToken_.ExpiresAt.LessThan(before)).Remove()
+	return int(pruned), err
+}
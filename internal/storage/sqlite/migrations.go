@@ -0,0 +1,388 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migration is one step of the schema, steps are applied in version order
+// and each version is only ever applied once. Never edit a released
+// migration, add a new one to the end of the list instead.
+type migration struct {
+	version     int
+	description string
+	statements  []string
+}
+
+var migrations = []migration{
+	{
+		version:     1,
+		description: "users, playlists, tracks and friends",
+		statements: []string{
+			`CREATE TABLE users (
+				id            INTEGER PRIMARY KEY AUTOINCREMENT,
+				uuid          TEXT    NOT NULL UNIQUE,
+				first_name    TEXT    NOT NULL DEFAULT '',
+				last_name     TEXT    NOT NULL DEFAULT '',
+				email_address TEXT    NOT NULL UNIQUE COLLATE NOCASE,
+				password      TEXT    NOT NULL DEFAULT '',
+				enabled       INTEGER NOT NULL DEFAULT 0,
+				admin_user    INTEGER NOT NULL DEFAULT 0,
+				last_playlist INTEGER NOT NULL DEFAULT 0
+			)`,
+			`CREATE TABLE playlists (
+				id                  INTEGER PRIMARY KEY AUTOINCREMENT,
+				uuid                TEXT    NOT NULL UNIQUE,
+				name                TEXT    NOT NULL DEFAULT '',
+				current_track_id    INTEGER NOT NULL DEFAULT 0,
+				elapsed             INTEGER NOT NULL DEFAULT 0,
+				client_id_lock      TEXT    NOT NULL DEFAULT '',
+				client_lock_expires INTEGER NOT NULL DEFAULT 0
+			)`,
+			`CREATE TABLE tracks (
+				id                 INTEGER PRIMARY KEY AUTOINCREMENT,
+				uuid               TEXT    NOT NULL UNIQUE,
+				path               TEXT    NOT NULL DEFAULT '',
+				artist_name        TEXT    NOT NULL DEFAULT '',
+				song_name          TEXT    NOT NULL DEFAULT '',
+				album_name         TEXT    NOT NULL DEFAULT '',
+				album_track_number INTEGER NOT NULL DEFAULT 0,
+				track_length       INTEGER NOT NULL DEFAULT 0
+			)`,
+			`CREATE INDEX tracks_path ON tracks (path)`,
+			`CREATE TABLE friends (
+				id        INTEGER PRIMARY KEY AUTOINCREMENT,
+				friend_id TEXT    NOT NULL DEFAULT ''
+			)`,
+			`CREATE INDEX friends_friend_id ON friends (friend_id)`,
+			`CREATE TABLE user_playlists (
+				user_id     INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+				playlist_id INTEGER NOT NULL REFERENCES playlists (id) ON DELETE CASCADE,
+				PRIMARY KEY (user_id, playlist_id)
+			)`,
+			`CREATE TABLE user_tracks (
+				user_id  INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+				track_id INTEGER NOT NULL REFERENCES tracks (id) ON DELETE CASCADE,
+				PRIMARY KEY (user_id, track_id)
+			)`,
+			`CREATE TABLE user_friends (
+				user_id   INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+				friend_id INTEGER NOT NULL REFERENCES friends (id) ON DELETE CASCADE,
+				PRIMARY KEY (user_id, friend_id)
+			)`,
+			`CREATE TABLE playlist_tracks (
+				playlist_id INTEGER NOT NULL REFERENCES playlists (id) ON DELETE CASCADE,
+				track_id    INTEGER NOT NULL REFERENCES tracks (id) ON DELETE CASCADE,
+				PRIMARY KEY (playlist_id, track_id)
+			)`,
+		},
+	},
+	{
+		version:     2,
+		description: "playlist position revisions",
+		statements: []string{
+			`ALTER TABLE playlists ADD COLUMN position_revision INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE playlists ADD COLUMN position_updated_at INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		version:     3,
+		description: "devices, playlist locks reference a device",
+		statements: []string{
+			`CREATE TABLE devices (
+				id            INTEGER PRIMARY KEY AUTOINCREMENT,
+				uuid          TEXT    NOT NULL UNIQUE,
+				name          TEXT    NOT NULL DEFAULT '',
+				type          TEXT    NOT NULL DEFAULT '',
+				last_seen     INTEGER NOT NULL DEFAULT 0,
+				last_token_id TEXT    NOT NULL DEFAULT ''
+			)`,
+			`CREATE TABLE user_devices (
+				user_id   INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+				device_id INTEGER NOT NULL REFERENCES devices (id) ON DELETE CASCADE,
+				PRIMARY KEY (user_id, device_id)
+			)`,
+			// Existing locks hold a token id rather than a device, so drop them
+			`ALTER TABLE playlists RENAME COLUMN client_id_lock TO lock_device_uuid`,
+			`UPDATE playlists SET lock_device_uuid = '', client_lock_expires = 0`,
+		},
+	},
+	{
+		version:     4,
+		description: "resume positions and listening history",
+		statements: []string{
+			`CREATE TABLE resume_positions (
+				id            INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_uuid     TEXT    NOT NULL,
+				track_uuid    TEXT    NOT NULL,
+				playlist_uuid TEXT    NOT NULL DEFAULT '',
+				device_uuid   TEXT    NOT NULL DEFAULT '',
+				elapsed       INTEGER NOT NULL DEFAULT 0,
+				updated_at    INTEGER NOT NULL DEFAULT 0,
+				UNIQUE (user_uuid, track_uuid)
+			)`,
+			`CREATE INDEX resume_positions_updated ON resume_positions (user_uuid, updated_at)`,
+			`CREATE TABLE plays (
+				id            INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_uuid     TEXT    NOT NULL,
+				track_uuid    TEXT    NOT NULL,
+				playlist_uuid TEXT    NOT NULL DEFAULT '',
+				device_uuid   TEXT    NOT NULL DEFAULT '',
+				start_offset  INTEGER NOT NULL DEFAULT 0,
+				end_offset    INTEGER NOT NULL DEFAULT 0,
+				started_at    INTEGER NOT NULL DEFAULT 0,
+				ended_at      INTEGER NOT NULL DEFAULT 0
+			)`,
+			`CREATE INDEX plays_ended ON plays (user_uuid, ended_at)`,
+		},
+	},
+	{
+		version:     5,
+		description: "track positions, current track by uuid",
+		statements: []string{
+			`ALTER TABLE playlist_tracks ADD COLUMN position INTEGER NOT NULL DEFAULT 0`,
+			`UPDATE playlist_tracks SET position = (
+				SELECT COUNT(*) FROM playlist_tracks AS earlier
+				WHERE earlier.playlist_id = playlist_tracks.playlist_id
+					AND earlier.rowid <= playlist_tracks.rowid)`,
+			`ALTER TABLE playlists ADD COLUMN current_track_uuid TEXT NOT NULL DEFAULT ''`,
+			// current_track_id was only ever set to a track id, anything else is dropped
+			`UPDATE playlists SET current_track_uuid = COALESCE((
+				SELECT tracks.uuid FROM tracks
+				JOIN playlist_tracks ON playlist_tracks.track_id = tracks.id
+				WHERE playlist_tracks.playlist_id = playlists.id
+					AND tracks.id = playlists.current_track_id), '')`,
+			`ALTER TABLE playlists DROP COLUMN current_track_id`,
+		},
+	},
+	{
+		version:     6,
+		description: "per user track library",
+		statements: []string{
+			`ALTER TABLE tracks ADD COLUMN content_hash TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX tracks_content_hash ON tracks (content_hash)`,
+			// Tracks used to belong to playlists only, put them in their owner's
+			// library. Tracks already duplicated across playlists stay separate.
+			`INSERT OR IGNORE INTO user_tracks (user_id, track_id)
+				SELECT user_playlists.user_id, playlist_tracks.track_id FROM user_playlists
+				JOIN playlist_tracks ON playlist_tracks.playlist_id = user_playlists.playlist_id
+				ORDER BY playlist_tracks.track_id`,
+		},
+	},
+	{
+		version:     7,
+		description: "friend requests and playlist shares",
+		statements: []string{
+			`ALTER TABLE friends ADD COLUMN status TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE friends ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0`,
+			// Friends from before requests existed were added directly
+			`UPDATE friends SET status = 'accepted'`,
+			`CREATE TABLE playlist_shares (
+				id            INTEGER PRIMARY KEY AUTOINCREMENT,
+				playlist_uuid TEXT    NOT NULL,
+				owner_uuid    TEXT    NOT NULL DEFAULT '',
+				user_uuid     TEXT    NOT NULL,
+				access        TEXT    NOT NULL DEFAULT '',
+				created_at    INTEGER NOT NULL DEFAULT 0,
+				UNIQUE (playlist_uuid, user_uuid)
+			)`,
+			`CREATE INDEX playlist_shares_user ON playlist_shares (user_uuid)`,
+		},
+	},
+	{
+		version:     8,
+		description: "device refresh tokens",
+		statements: []string{
+			`ALTER TABLE devices ADD COLUMN refresh_token_hash TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE devices ADD COLUMN refresh_token_expires INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		version:     9,
+		description: "revoked tokens",
+		statements: []string{
+			`CREATE TABLE revoked_tokens (
+				id         INTEGER PRIMARY KEY AUTOINCREMENT,
+				token_id   TEXT    NOT NULL UNIQUE,
+				expires_at INTEGER NOT NULL DEFAULT 0
+			)`,
+			`CREATE INDEX revoked_tokens_expires_at ON revoked_tokens (expires_at)`,
+		},
+	},
+	{
+		version:     10,
+		description: "verified email addresses",
+		statements: []string{
+			`ALTER TABLE users ADD COLUMN email_verified INTEGER NOT NULL DEFAULT 0`,
+			// Users from before verification existed aren't asked to verify
+			`UPDATE users SET email_verified = 1`,
+		},
+	},
+	{
+		version:     11,
+		description: "two factor authentication",
+		statements: []string{
+			`ALTER TABLE users ADD COLUMN totp_secret TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE users ADD COLUMN totp_enabled INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE users ADD COLUMN totp_last_step INTEGER NOT NULL DEFAULT 0`,
+			// The recovery code hashes joined with commas
+			`ALTER TABLE users ADD COLUMN totp_recovery_codes TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version:     12,
+		description: "user roles",
+		statements: []string{
+			// The role names joined with commas, admin_user is no longer used
+			`ALTER TABLE users ADD COLUMN roles TEXT NOT NULL DEFAULT ''`,
+			`UPDATE users SET roles = CASE admin_user WHEN 1 THEN 'admin' ELSE 'member' END`,
+		},
+	},
+	{
+		version:     13,
+		description: "household groups",
+		statements: []string{
+			`CREATE TABLE groups (
+				id         INTEGER PRIMARY KEY AUTOINCREMENT,
+				uuid       TEXT    NOT NULL UNIQUE,
+				name       TEXT    NOT NULL DEFAULT '',
+				created_at INTEGER NOT NULL DEFAULT 0
+			)`,
+			// A user can only be in one group, restrictions are joined with commas
+			`CREATE TABLE group_members (
+				group_id     INTEGER NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+				user_uuid    TEXT    NOT NULL UNIQUE,
+				manager      INTEGER NOT NULL DEFAULT 0,
+				restrictions TEXT    NOT NULL DEFAULT ''
+			)`,
+			`CREATE INDEX group_members_group ON group_members (group_id)`,
+			`ALTER TABLE playlists ADD COLUMN group_uuid TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX playlists_group ON playlists (group_uuid)`,
+		},
+	},
+	{
+		version:     14,
+		description: "external logins",
+		statements: []string{
+			`CREATE TABLE external_logins (
+				id            INTEGER PRIMARY KEY AUTOINCREMENT,
+				provider      TEXT    NOT NULL,
+				subject       TEXT    NOT NULL,
+				user_uuid     TEXT    NOT NULL,
+				email_address TEXT    NOT NULL DEFAULT '',
+				created_at    INTEGER NOT NULL DEFAULT 0,
+				UNIQUE (provider, subject),
+				UNIQUE (provider, user_uuid)
+			)`,
+			`CREATE INDEX external_logins_user ON external_logins (user_uuid)`,
+		},
+	},
+	{
+		version:     15,
+		description: "api keys",
+		statements: []string{
+			`CREATE TABLE api_keys (
+				id          INTEGER PRIMARY KEY AUTOINCREMENT,
+				uuid        TEXT    NOT NULL UNIQUE,
+				user_uuid   TEXT    NOT NULL,
+				name        TEXT    NOT NULL DEFAULT '',
+				secret_hash TEXT    NOT NULL,
+				scopes      TEXT    NOT NULL DEFAULT '',
+				created_at  INTEGER NOT NULL DEFAULT 0,
+				expires_at  INTEGER NOT NULL DEFAULT 0,
+				last_used   INTEGER NOT NULL DEFAULT 0
+			)`,
+			`CREATE INDEX api_keys_user ON api_keys (user_uuid)`,
+		},
+	},
+	{
+		version:     16,
+		description: "device token scopes",
+		statements: []string{
+			`ALTER TABLE devices ADD COLUMN scopes TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version:     17,
+		description: "device refresh token families",
+		statements: []string{
+			`ALTER TABLE devices ADD COLUMN refresh_token_family TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE devices ADD COLUMN refresh_token_generation INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		version:     18,
+		description: "group invites",
+		statements: []string{
+			// Unlike members a user can be invited to several groups at once
+			`CREATE TABLE group_invites (
+				group_id   INTEGER NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+				user_uuid  TEXT    NOT NULL,
+				invited_by TEXT    NOT NULL DEFAULT '',
+				created_at INTEGER NOT NULL DEFAULT 0,
+				UNIQUE (group_id, user_uuid)
+			)`,
+			`CREATE INDEX group_invites_user ON group_invites (user_uuid)`,
+		},
+	},
+	{
+		version:     19,
+		description: "group revisions",
+		statements: []string{
+			`ALTER TABLE groups ADD COLUMN revision INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+}
+
+// migrate brings the schema up to the latest version, recording every applied
+// version in schema_migrations so it can be checked with the sqlite3 shell.
+func migrate(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version     INTEGER PRIMARY KEY,
+		description TEXT    NOT NULL,
+		applied_at  INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+
+	var current int
+	err = db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		for _, statement := range m.statements {
+			if _, err := tx.Exec(statement); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.description, err)
+			}
+		}
+		_, err = tx.Exec(`INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, ?, ?)`,
+			m.version, m.description, time.Now().Unix())
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SchemaVersion returns the latest migration applied to the database
+func (s *Storage) SchemaVersion() (int, error) {
+	var version int
+	err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
@@ -0,0 +1,200 @@
+package sqlite
+
+import (
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/storage/storagetest"
+	"path/filepath"
+	"testing"
+)
+
+func openTestStorage(t *testing.T) *Storage {
+	s, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %s", err.Error())
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestStorage(t *testing.T) {
+	storagetest.TestDataStorage(t, func(t *testing.T) storage.DataStorage {
+		return openTestStorage(t)
+	})
+}
+
+func TestMigrations(t *testing.T) {
+	t.Run("A new database is migrated to the latest version", func(t *testing.T) {
+		s := openTestStorage(t)
+		version, err := s.SchemaVersion()
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		latest := migrations[len(migrations)-1].version
+		if version != latest {
+			t.Errorf("Want schema version %d, got %d", latest, version)
+		}
+	})
+	t.Run("Reopening a database keeps the data and does not re-run migrations", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "test.db")
+		s, err := Open(fileName)
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		s.InsertUser(&storage.User{EmailAddress: "test@test.com"})
+		s.Close()
+
+		s, err = Open(fileName)
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		defer s.Close()
+		var applied int
+		s.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied)
+		if applied != len(migrations) {
+			t.Errorf("Want %d applied migrations, got %d", len(migrations), applied)
+		}
+		exists, _ := s.UserExists(&storage.User{EmailAddress: "test@test.com"})
+		if !exists {
+			t.Error("Want user to survive reopening the database")
+		}
+	})
+	t.Run("Upgrading to track positions keeps the order and current track", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "test.db")
+		latest := migrations
+		migrations = latest[:4]
+		s, err := Open(fileName)
+		migrations = latest
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		statements := []string{
+			`INSERT INTO playlists (id, uuid, name, current_track_id) VALUES (1, 'playlist-1', 'Test', 12)`,
+			`INSERT INTO tracks (id, uuid) VALUES (11, 'track-11'), (12, 'track-12')`,
+			`INSERT INTO playlist_tracks (playlist_id, track_id) VALUES (1, 12), (1, 11)`,
+		}
+		for _, statement := range statements {
+			if _, err := s.db.Exec(statement); err != nil {
+				t.Fatalf("Want no error, got '%s'", err.Error())
+			}
+		}
+		s.Close()
+
+		s, err = Open(fileName)
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		defer s.Close()
+		playlist := &storage.Playlist{Uuid: "playlist-1"}
+		if err := s.SelectPlaylist(playlist); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if playlist.CurrentTrackUuid != "track-12" {
+			t.Errorf("Want current track 'track-12', got '%s'", playlist.CurrentTrackUuid)
+		}
+		if len(playlist.Tracks) != 2 || playlist.Tracks[0].Uuid != "track-12" {
+			t.Errorf("Want 'track-12' first, got %d tracks", len(playlist.Tracks))
+		}
+	})
+	t.Run("Upgrading to friend requests keeps existing friends", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "test.db")
+		latest := migrations
+		migrations = latest[:6]
+		s, err := Open(fileName)
+		migrations = latest
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		user := &storage.User{Id: 1}
+		statements := []string{
+			`INSERT INTO users (id, uuid, email_address) VALUES (1, 'user-1', 'test@test.com')`,
+			`INSERT INTO friends (id, friend_id) VALUES (1, 'friend-1')`,
+			`INSERT INTO user_friends (user_id, friend_id) VALUES (1, 1)`,
+		}
+		for _, statement := range statements {
+			if _, err := s.db.Exec(statement); err != nil {
+				t.Fatalf("Want no error, got '%s'", err.Error())
+			}
+		}
+		s.Close()
+
+		s, err = Open(fileName)
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		defer s.Close()
+		s.SelectUser(user)
+		if len(user.Friends) != 1 || user.Friends[0].Status != storage.FriendAccepted {
+			t.Errorf("Want the friend accepted, got '%+v'", user.Friends)
+		}
+	})
+	t.Run("Upgrading to verified email addresses verifies existing users", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "test.db")
+		latest := migrations
+		migrations = latest[:9]
+		s, err := Open(fileName)
+		migrations = latest
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if _, err := s.db.Exec(`INSERT INTO users (id, uuid, email_address) VALUES (1, 'user-1', 'test@test.com')`); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		s.Close()
+
+		s, err = Open(fileName)
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		defer s.Close()
+		user := &storage.User{Id: 1}
+		s.SelectUser(user)
+		if !user.EmailVerified {
+			t.Error("Want the existing user verified")
+		}
+		user = &storage.User{EmailAddress: "new@test.com"}
+		s.InsertUser(user)
+		s.SelectUser(user)
+		if user.EmailVerified {
+			t.Error("Want a new user unverified")
+		}
+	})
+	t.Run("Upgrading to roles keeps the admin user", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "test.db")
+		latest := migrations
+		migrations = latest[:11]
+		s, err := Open(fileName)
+		migrations = latest
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		_, err = s.db.Exec(`INSERT INTO users (id, uuid, email_address, admin_user) VALUES
+			(1, 'user-1', 'admin@test.com', 1), (2, 'user-2', 'test@test.com', 0)`)
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		s.Close()
+
+		s, err = Open(fileName)
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		defer s.Close()
+		admin := &storage.User{Id: 1}
+		s.SelectUser(admin)
+		user := &storage.User{Id: 2}
+		s.SelectUser(user)
+		if len(admin.Roles) != 1 || admin.Roles[0] != storage.RoleAdmin {
+			t.Errorf("Want the admin user to be an admin, got '%v'", admin.Roles)
+		}
+		if len(user.Roles) != 1 || user.Roles[0] != storage.RoleMember {
+			t.Errorf("Want the user to be a member, got '%v'", user.Roles)
+		}
+	})
+	t.Run("Migration versions are in ascending order", func(t *testing.T) {
+		for i := 1; i < len(migrations); i++ {
+			if migrations[i].version <= migrations[i-1].version {
+				t.Errorf("Migration %d is out of order", migrations[i].version)
+			}
+		}
+	})
+}
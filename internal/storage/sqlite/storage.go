@@ -0,0 +1,1534 @@
+// Package sqlite is the storage driver backed by a single SQLite database file,
+// which can be inspected, backed up and queried with the standard sqlite tools.
+package sqlite
+
+import (
+	"database/sql"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"strings"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const defaultDataSource = "sinkrontrack.db"
+
+func init() {
+	storage.Register("sqlite", func(dataSource string) (storage.DataStorage, error) {
+		return Open(dataSource)
+	})
+}
+
+type Storage struct {
+	db *sql.DB
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Open opens (creating if needed) the database file and runs any outstanding
+// schema migrations. A blank fileName uses sinkrontrack.db in the working directory.
+func Open(fileName string) (*Storage, error) {
+	if fileName == "" {
+		fileName = defaultDataSource
+	}
+	dsn := fileName
+	if strings.Contains(dsn, "?") {
+		dsn += "&"
+	} else {
+		dsn += "?"
+	}
+	dsn += "_foreign_keys=1&_busy_timeout=5000"
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// sqlite only allows one writer, so don't let database/sql pretend otherwise
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Storage{db: db}, nil
+}
+
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+// transaction runs fn inside a transaction, committing when it returns nil
+func (s *Storage) transaction(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// nullId turns a zero id into NULL, so the insert picks the next id
+func nullId(id uint64) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return int64(id)
+}
+
+// upsert runs an INSERT ... ON CONFLICT statement, returning the id of the row
+func upsert(q queryer, id uint64, query string, args ...interface{}) (uint64, error) {
+	result, err := q.Exec(query, append([]interface{}{nullId(id)}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+	if id != 0 {
+		return id, nil
+	}
+	lastId, err := result.LastInsertId()
+	return uint64(lastId), err
+}
+
+// replaceRelation sets the ids related to ownerId to exactly ids, keeping their order
+func replaceRelation(q queryer, table string, ownerColumn string, targetColumn string, ownerId uint64, ids []uint64) error {
+	_, err := q.Exec("DELETE FROM "+table+" WHERE "+ownerColumn+" = ?", ownerId)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		_, err := q.Exec("INSERT OR IGNORE INTO "+table+" ("+ownerColumn+", "+targetColumn+") VALUES (?, ?)", ownerId, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// put* functions store an entity and, like an objectbox Put, any related entities
+
+func putTrack(q queryer, t *storage.Track) error {
+	id, err := upsert(q, t.Id, `INSERT INTO tracks
+		(id, uuid, path, artist_name, song_name, album_name, album_track_number, track_length, content_hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			uuid = excluded.uuid, path = excluded.path, artist_name = excluded.artist_name,
+			song_name = excluded.song_name, album_name = excluded.album_name,
+			album_track_number = excluded.album_track_number, track_length = excluded.track_length,
+			content_hash = excluded.content_hash`,
+		t.Uuid, t.Path, t.ArtistName, t.SongName, t.AlbumName, t.AlbumTrackNumber, t.TrackLength, t.ContentHash)
+	if err != nil {
+		return err
+	}
+	t.Id = id
+	return nil
+}
+
+func putPlaylist(q queryer, p *storage.Playlist) error {
+	id, err := upsert(q, p.Id, `INSERT INTO playlists
+		(id, uuid, name, current_track_uuid, elapsed, lock_device_uuid, client_lock_expires,
+			position_revision, position_updated_at, group_uuid)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			uuid = excluded.uuid, name = excluded.name, current_track_uuid = excluded.current_track_uuid,
+			elapsed = excluded.elapsed, lock_device_uuid = excluded.lock_device_uuid,
+			client_lock_expires = excluded.client_lock_expires,
+			position_revision = excluded.position_revision,
+			position_updated_at = excluded.position_updated_at, group_uuid = excluded.group_uuid`,
+		p.Uuid, p.Name, p.CurrentTrackUuid, p.Elapsed, p.LockDeviceUuid, p.ClientLockExpires,
+		p.PositionRevision, p.PositionUpdatedAt, p.GroupUuid)
+	if err != nil {
+		return err
+	}
+	p.Id = id
+
+	for _, t := range p.Tracks {
+		if err := putTrack(q, t); err != nil {
+			return err
+		}
+	}
+	return putPlaylistTracks(q, p.Id, p.Tracks)
+}
+
+// putPlaylistTracks replaces the playlist's tracks, numbering their positions from 1
+func putPlaylistTracks(q queryer, playlistId uint64, tracks []*storage.Track) error {
+	_, err := q.Exec(`DELETE FROM playlist_tracks WHERE playlist_id = ?`, playlistId)
+	if err != nil {
+		return err
+	}
+	for i, t := range tracks {
+		_, err := q.Exec(`INSERT OR IGNORE INTO playlist_tracks (playlist_id, track_id, position) VALUES (?, ?, ?)`,
+			playlistId, t.Id, i+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func putFriend(q queryer, f *storage.Friend) error {
+	id, err := upsert(q, f.Id, `INSERT INTO friends (id, friend_id, status, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			friend_id = excluded.friend_id, status = excluded.status, updated_at = excluded.updated_at`,
+		f.FriendId, f.Status, f.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	f.Id = id
+	return nil
+}
+
+func putDevice(q queryer, d *storage.Device) error {
+	id, err := upsert(q, d.Id, `INSERT INTO devices (id, uuid, name, type, last_seen, last_token_id,
+			refresh_token_hash, refresh_token_expires, scopes, refresh_token_family, refresh_token_generation)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			uuid = excluded.uuid, name = excluded.name, type = excluded.type,
+			last_seen = excluded.last_seen, last_token_id = excluded.last_token_id,
+			refresh_token_hash = excluded.refresh_token_hash,
+			refresh_token_expires = excluded.refresh_token_expires, scopes = excluded.scopes,
+			refresh_token_family = excluded.refresh_token_family,
+			refresh_token_generation = excluded.refresh_token_generation`,
+		d.Uuid, d.Name, d.Type, d.LastSeen, d.LastTokenId, d.RefreshTokenHash, d.RefreshTokenExpires,
+		strings.Join(d.Scopes, ","), d.RefreshTokenFamily, d.RefreshTokenGeneration)
+	if err != nil {
+		return err
+	}
+	d.Id = id
+	return nil
+}
+
+// putUserRow stores just the user's own fields, its relations are left as
+// they are stored
+func putUserRow(q queryer, m *storage.User) error {
+	id, err := upsert(q, m.Id, `INSERT INTO users
+		(id, uuid, first_name, last_name, email_address, password, enabled, email_verified, roles, last_playlist,
+			totp_secret, totp_enabled, totp_last_step, totp_recovery_codes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			uuid = excluded.uuid, first_name = excluded.first_name, last_name = excluded.last_name,
+			email_address = excluded.email_address, password = excluded.password,
+			enabled = excluded.enabled, email_verified = excluded.email_verified, roles = excluded.roles,
+			last_playlist = excluded.last_playlist,
+			totp_secret = excluded.totp_secret, totp_enabled = excluded.totp_enabled,
+			totp_last_step = excluded.totp_last_step, totp_recovery_codes = excluded.totp_recovery_codes`,
+		m.Uuid, m.FirstName, m.LastName, m.EmailAddress, m.Password, m.Enabled, m.EmailVerified, strings.Join(m.Roles, ","), m.LastPlaylist,
+		m.TotpSecret, m.TotpEnabled, m.TotpLastStep, strings.Join(m.TotpRecoveryCodes, ","))
+	if err != nil {
+		return err
+	}
+	m.Id = id
+	return nil
+}
+
+func putUser(q queryer, m *storage.User) error {
+	if err := putUserRow(q, m); err != nil {
+		return err
+	}
+
+	var playlistIds, trackIds, friendIds, deviceIds []uint64
+	for _, p := range m.Playlists {
+		if err := putPlaylist(q, p); err != nil {
+			return err
+		}
+		playlistIds = append(playlistIds, p.Id)
+	}
+	for _, t := range m.Tracks {
+		if err := putTrack(q, t); err != nil {
+			return err
+		}
+		trackIds = append(trackIds, t.Id)
+	}
+	for _, f := range m.Friends {
+		if err := putFriend(q, f); err != nil {
+			return err
+		}
+		friendIds = append(friendIds, f.Id)
+	}
+	for _, d := range m.Devices {
+		if err := putDevice(q, d); err != nil {
+			return err
+		}
+		deviceIds = append(deviceIds, d.Id)
+	}
+	if err := replaceRelation(q, "user_playlists", "user_id", "playlist_id", m.Id, playlistIds); err != nil {
+		return err
+	}
+	if err := replaceRelation(q, "user_tracks", "user_id", "track_id", m.Id, trackIds); err != nil {
+		return err
+	}
+	if err := replaceRelation(q, "user_friends", "user_id", "friend_id", m.Id, friendIds); err != nil {
+		return err
+	}
+	return replaceRelation(q, "user_devices", "user_id", "device_id", m.Id, deviceIds)
+}
+
+// checkUser returns ErrUserNotFound when there is no user with the id
+func checkUser(q queryer, userId uint64) error {
+	var count int
+	if err := q.QueryRow(`SELECT COUNT(*) FROM users WHERE id = ?`, userId).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return storage.ErrUserNotFound
+	}
+	return nil
+}
+
+// addToUser relates a newly stored entity to the user, after the user's
+// other entities of its kind
+func addToUser(q queryer, table string, targetColumn string, userId uint64, id uint64) error {
+	if err := checkUser(q, userId); err != nil {
+		return err
+	}
+	_, err := q.Exec("INSERT INTO "+table+" (user_id, "+targetColumn+") VALUES (?, ?)", userId, id)
+	return err
+}
+
+// load* functions read entities with their relations populated
+
+const trackColumns = `tracks.id, tracks.uuid, tracks.path, tracks.artist_name, tracks.song_name,
+	tracks.album_name, tracks.album_track_number, tracks.track_length, tracks.content_hash`
+
+func scanTracks(rows *sql.Rows) ([]*storage.Track, error) {
+	defer rows.Close()
+	var tracks []*storage.Track
+	for rows.Next() {
+		t := &storage.Track{}
+		err := rows.Scan(&t.Id, &t.Uuid, &t.Path, &t.ArtistName, &t.SongName,
+			&t.AlbumName, &t.AlbumTrackNumber, &t.TrackLength, &t.ContentHash)
+		if err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks, rows.Err()
+}
+
+const playlistColumns = `playlists.id, playlists.uuid, playlists.name, playlists.current_track_uuid,
+	playlists.elapsed, playlists.lock_device_uuid, playlists.client_lock_expires,
+	playlists.position_revision, playlists.position_updated_at, playlists.group_uuid`
+
+func loadPlaylists(q queryer, query string, args ...interface{}) ([]*storage.Playlist, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var playlists []*storage.Playlist
+	for rows.Next() {
+		p := &storage.Playlist{}
+		err := rows.Scan(&p.Id, &p.Uuid, &p.Name, &p.CurrentTrackUuid,
+			&p.Elapsed, &p.LockDeviceUuid, &p.ClientLockExpires,
+			&p.PositionRevision, &p.PositionUpdatedAt, &p.GroupUuid)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		playlists = append(playlists, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, p := range playlists {
+		trackRows, err := q.Query(`SELECT `+trackColumns+` FROM tracks
+			JOIN playlist_tracks ON playlist_tracks.track_id = tracks.id
+			WHERE playlist_tracks.playlist_id = ? ORDER BY playlist_tracks.position`, p.Id)
+		if err != nil {
+			return nil, err
+		}
+		p.Tracks, err = scanTracks(trackRows)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return playlists, nil
+}
+
+const deviceColumns = `devices.id, devices.uuid, devices.name, devices.type,
+	devices.last_seen, devices.last_token_id, devices.refresh_token_hash, devices.refresh_token_expires,
+	devices.scopes, devices.refresh_token_family, devices.refresh_token_generation`
+
+func scanDevices(rows *sql.Rows) ([]*storage.Device, error) {
+	defer rows.Close()
+	var devices []*storage.Device
+	for rows.Next() {
+		d := &storage.Device{}
+		var scopes string
+		err := rows.Scan(&d.Id, &d.Uuid, &d.Name, &d.Type, &d.LastSeen, &d.LastTokenId,
+			&d.RefreshTokenHash, &d.RefreshTokenExpires, &scopes,
+			&d.RefreshTokenFamily, &d.RefreshTokenGeneration)
+		if err != nil {
+			return nil, err
+		}
+		if scopes != "" {
+			d.Scopes = strings.Split(scopes, ",")
+		}
+		devices = append(devices, d)
+	}
+	return devices, rows.Err()
+}
+
+const userColumns = `users.id, users.uuid, users.first_name, users.last_name, users.email_address,
+	users.password, users.enabled, users.email_verified, users.roles, users.last_playlist,
+	users.totp_secret, users.totp_enabled, users.totp_last_step, users.totp_recovery_codes`
+
+func loadUsers(q queryer, query string, args ...interface{}) ([]*storage.User, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var users []*storage.User
+	for rows.Next() {
+		m := &storage.User{}
+		var roles, recoveryCodes string
+		err := rows.Scan(&m.Id, &m.Uuid, &m.FirstName, &m.LastName, &m.EmailAddress,
+			&m.Password, &m.Enabled, &m.EmailVerified, &roles, &m.LastPlaylist,
+			&m.TotpSecret, &m.TotpEnabled, &m.TotpLastStep, &recoveryCodes)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if roles != "" {
+			m.Roles = strings.Split(roles, ",")
+		}
+		if recoveryCodes != "" {
+			m.TotpRecoveryCodes = strings.Split(recoveryCodes, ",")
+		}
+		users = append(users, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, m := range users {
+		m.Playlists, err = loadPlaylists(q, `SELECT `+playlistColumns+` FROM playlists
+			JOIN user_playlists ON user_playlists.playlist_id = playlists.id
+			WHERE user_playlists.user_id = ? ORDER BY user_playlists.rowid`, m.Id)
+		if err != nil {
+			return nil, err
+		}
+		trackRows, err := q.Query(`SELECT `+trackColumns+` FROM tracks
+			JOIN user_tracks ON user_tracks.track_id = tracks.id
+			WHERE user_tracks.user_id = ? ORDER BY user_tracks.rowid`, m.Id)
+		if err != nil {
+			return nil, err
+		}
+		m.Tracks, err = scanTracks(trackRows)
+		if err != nil {
+			return nil, err
+		}
+		friendRows, err := q.Query(`SELECT friends.id, friends.friend_id, friends.status, friends.updated_at FROM friends
+			JOIN user_friends ON user_friends.friend_id = friends.id
+			WHERE user_friends.user_id = ? ORDER BY user_friends.rowid`, m.Id)
+		if err != nil {
+			return nil, err
+		}
+		for friendRows.Next() {
+			f := &storage.Friend{}
+			if err := friendRows.Scan(&f.Id, &f.FriendId, &f.Status, &f.UpdatedAt); err != nil {
+				friendRows.Close()
+				return nil, err
+			}
+			m.Friends = append(m.Friends, f)
+		}
+		friendRows.Close()
+		deviceRows, err := q.Query(`SELECT `+deviceColumns+` FROM devices
+			JOIN user_devices ON user_devices.device_id = devices.id
+			WHERE user_devices.user_id = ? ORDER BY user_devices.rowid`, m.Id)
+		if err != nil {
+			return nil, err
+		}
+		m.Devices, err = scanDevices(deviceRows)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
+}
+
+// userKey builds the WHERE clause used to look a user up by Id, Uuid or EmailAddress
+func userKey(m *storage.User) (string, interface{}, error) {
+	if m.Id != 0 {
+		return "users.id = ?", m.Id, nil
+	} else if m.Uuid != "" {
+		return "users.uuid = ?", m.Uuid, nil
+	} else if m.EmailAddress != "" {
+		return "users.email_address = ?", m.EmailAddress, nil
+	}
+	return "", nil, storage.ErrMissingId
+}
+
+func (s *Storage) InsertUser(m *storage.User) (*uint64, error) {
+	err := s.transaction(func(tx *sql.Tx) error {
+		var count int
+		err := tx.QueryRow(`SELECT COUNT(*) FROM users WHERE email_address = ?`, m.EmailAddress).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return storage.ErrUserExists
+		}
+		m.Uuid = uuid.NewString()
+		return putUser(tx, m)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &m.Id, nil
+}
+
+func (s *Storage) UpdateUser(m *storage.User) error {
+	if m.Id == 0 {
+		return storage.ErrMissingId
+	}
+	err := s.transaction(func(tx *sql.Tx) error {
+		if err := checkUser(tx, m.Id); err != nil {
+			return err
+		}
+		return putUserRow(tx, m)
+	})
+	if err != nil {
+		return err
+	}
+	return s.SelectUser(m)
+}
+
+func (s *Storage) UseUserTotpStep(m *storage.User, expectedStep int64) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		var stored int64
+		err := tx.QueryRow(`SELECT totp_last_step FROM users WHERE id = ?`, m.Id).Scan(&stored)
+		if err == sql.ErrNoRows {
+			return storage.ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if stored != expectedStep {
+			return storage.ErrSecondFactorUsed
+		}
+		_, err = tx.Exec(`UPDATE users SET totp_last_step = ? WHERE id = ?`, m.TotpLastStep, m.Id)
+		return err
+	})
+}
+
+func (s *Storage) UseUserRecoveryCode(m *storage.User, hash string) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		var stored string
+		err := tx.QueryRow(`SELECT totp_recovery_codes FROM users WHERE id = ?`, m.Id).Scan(&stored)
+		if err == sql.ErrNoRows {
+			return storage.ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var remaining []string
+		used := false
+		for _, code := range strings.Split(stored, ",") {
+			if code == hash && !used {
+				used = true
+			} else if code != "" {
+				remaining = append(remaining, code)
+			}
+		}
+		if !used {
+			return storage.ErrSecondFactorUsed
+		}
+		_, err = tx.Exec(`UPDATE users SET totp_recovery_codes = ? WHERE id = ?`, strings.Join(remaining, ","), m.Id)
+		if err != nil {
+			return err
+		}
+		m.TotpRecoveryCodes = remaining
+		return nil
+	})
+}
+
+func (s *Storage) DeleteUser(m *storage.User) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`UPDATE groups SET revision = revision + 1
+			WHERE id IN (SELECT group_id FROM group_members WHERE user_uuid = (SELECT uuid FROM users WHERE id = ?)
+				UNION SELECT group_id FROM group_invites WHERE user_uuid = (SELECT uuid FROM users WHERE id = ?))`,
+			m.Id, m.Id)
+		if err != nil {
+			return err
+		}
+		for _, table := range []string{"group_members", "group_invites", "external_logins", "api_keys"} {
+			_, err := tx.Exec(`DELETE FROM `+table+`
+				WHERE user_uuid = (SELECT uuid FROM users WHERE id = ?)`, m.Id)
+			if err != nil {
+				return err
+			}
+		}
+		result, err := tx.Exec(`DELETE FROM users WHERE id = ?`, m.Id)
+		if err != nil {
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return storage.ErrUserNotFound
+		}
+		return nil
+	})
+}
+
+func (s *Storage) SelectUser(m *storage.User) error {
+	where, key, err := userKey(m)
+	if err != nil {
+		return err
+	}
+	users, err := loadUsers(s.db, `SELECT `+userColumns+` FROM users WHERE `+where, key)
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return storage.ErrUserNotFound
+	}
+	*m = *users[0]
+	return nil
+}
+
+func (s *Storage) UserExists(m *storage.User) (bool, error) {
+	where, key, err := userKey(m)
+	if err != nil {
+		return false, err
+	}
+	var count int
+	err = s.db.QueryRow(`SELECT COUNT(*) FROM users WHERE `+where, key).Scan(&count)
+	return count > 0, err
+}
+
+func (s *Storage) FindUsers(filter storage.UserFilter) ([]*storage.User, error) {
+	return loadUsers(s.db, `SELECT `+userColumns+` FROM users
+		WHERE (? = '' OR uuid = ?) AND (? = '' OR email_address = ?)
+			AND (? = '' OR ',' || roles || ',' LIKE '%,' || ? || ',%')
+			AND (? = '' OR id IN (SELECT user_playlists.user_id FROM user_playlists
+				JOIN playlists ON playlists.id = user_playlists.playlist_id WHERE playlists.uuid = ?))
+		ORDER BY id`,
+		filter.Uuid, filter.Uuid, filter.EmailAddress, filter.EmailAddress, filter.Role, filter.Role,
+		filter.PlaylistUuid, filter.PlaylistUuid)
+}
+
+func (s *Storage) UserAddPlaylist(m *storage.User, p *storage.Playlist) (*uint64, error) {
+	p.Uuid = uuid.NewString()
+	err := s.transaction(func(tx *sql.Tx) error {
+		if err := putPlaylist(tx, p); err != nil {
+			return err
+		}
+		return addToUser(tx, "user_playlists", "playlist_id", m.Id, p.Id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.Playlists = append(m.Playlists, p)
+	return &m.Id, nil
+}
+
+func (s *Storage) UpdatePlaylist(p *storage.Playlist) error {
+	if p.Id == 0 {
+		return storage.ErrMissingId
+	}
+	return s.transaction(func(tx *sql.Tx) error {
+		return putPlaylist(tx, p)
+	})
+}
+
+func (s *Storage) UpdatePlaylistAtRevision(p *storage.Playlist, revision uint64) error {
+	if p.Id == 0 {
+		return storage.ErrMissingId
+	}
+	return s.transaction(func(tx *sql.Tx) error {
+		var stored uint64
+		err := tx.QueryRow(`SELECT position_revision FROM playlists WHERE id = ?`, p.Id).Scan(&stored)
+		if err == sql.ErrNoRows {
+			return storage.ErrPlaylistNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if stored != revision {
+			return storage.ErrRevisionConflict
+		}
+		_, err = tx.Exec(`UPDATE playlists SET name = ?, current_track_uuid = ?, elapsed = ?,
+				position_revision = ?, position_updated_at = ?
+			WHERE id = ?`,
+			p.Name, p.CurrentTrackUuid, p.Elapsed, p.PositionRevision, p.PositionUpdatedAt, p.Id)
+		if err != nil {
+			return err
+		}
+		playlists, err := loadPlaylists(tx, `SELECT `+playlistColumns+` FROM playlists WHERE id = ?`, p.Id)
+		if err != nil {
+			return err
+		}
+		*p = *playlists[0]
+		return nil
+	})
+}
+
+func (s *Storage) UpdatePlaylistLock(p *storage.Playlist, heldBy string) error {
+	if p.Id == 0 {
+		return storage.ErrMissingId
+	}
+	return s.transaction(func(tx *sql.Tx) error {
+		var stored string
+		err := tx.QueryRow(`SELECT lock_device_uuid FROM playlists WHERE id = ?`, p.Id).Scan(&stored)
+		if err == sql.ErrNoRows {
+			return storage.ErrPlaylistNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if stored != heldBy {
+			return storage.ErrLockConflict
+		}
+		_, err = tx.Exec(`UPDATE playlists SET lock_device_uuid = ?, client_lock_expires = ? WHERE id = ?`,
+			p.LockDeviceUuid, p.ClientLockExpires, p.Id)
+		return err
+	})
+}
+
+func (s *Storage) ReorderPlaylistTracks(p *storage.Playlist, trackUuids []string) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		playlists, err := loadPlaylists(tx, `SELECT `+playlistColumns+` FROM playlists WHERE id = ?`, p.Id)
+		if err != nil {
+			return err
+		}
+		if len(playlists) == 0 {
+			return storage.ErrPlaylistNotFound
+		}
+		tracks, err := storage.OrderTracks(playlists[0].Tracks, trackUuids)
+		if err != nil {
+			return err
+		}
+		if err := putPlaylistTracks(tx, p.Id, tracks); err != nil {
+			return err
+		}
+		p.Tracks = tracks
+		return nil
+	})
+}
+
+func (s *Storage) DeletePlaylist(p *storage.Playlist) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM playlist_shares
+			WHERE playlist_uuid = (SELECT uuid FROM playlists WHERE id = ?)`, p.Id)
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec(`DELETE FROM playlists WHERE id = ?`, p.Id)
+		if err != nil {
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return storage.ErrPlaylistNotFound
+		}
+		return nil
+	})
+}
+
+func playlistKey(p *storage.Playlist) (string, interface{}, error) {
+	if p.Id != 0 {
+		return "playlists.id = ?", p.Id, nil
+	} else if p.Uuid != "" {
+		return "playlists.uuid = ?", p.Uuid, nil
+	}
+	return "", nil, storage.ErrMissingId
+}
+
+func (s *Storage) SelectPlaylist(p *storage.Playlist) error {
+	where, key, err := playlistKey(p)
+	if err != nil {
+		return err
+	}
+	playlists, err := loadPlaylists(s.db, `SELECT `+playlistColumns+` FROM playlists WHERE `+where, key)
+	if err != nil {
+		return err
+	}
+	if len(playlists) == 0 {
+		return storage.ErrPlaylistNotFound
+	}
+	*p = *playlists[0]
+	return nil
+}
+
+func (s *Storage) PlaylistExists(p *storage.Playlist) (bool, error) {
+	where, key, err := playlistKey(p)
+	if err != nil {
+		return false, err
+	}
+	var count int
+	err = s.db.QueryRow(`SELECT COUNT(*) FROM playlists WHERE `+where, key).Scan(&count)
+	return count > 0, err
+}
+
+// playlistsWithTrack selects the id of every playlist holding the track uuid
+const playlistsWithTrack = `SELECT playlist_tracks.playlist_id FROM playlist_tracks
+	JOIN tracks ON tracks.id = playlist_tracks.track_id WHERE tracks.uuid = ?`
+
+func (s *Storage) FindPlaylists(filter storage.PlaylistFilter) ([]*storage.Playlist, error) {
+	if filter.OwnerEmail != "" {
+		return loadPlaylists(s.db, `SELECT `+playlistColumns+` FROM playlists
+			JOIN user_playlists ON user_playlists.playlist_id = playlists.id
+			JOIN users ON users.id = user_playlists.user_id
+			WHERE users.email_address = ? AND (? = '' OR playlists.uuid = ?)
+				AND (? = '' OR playlists.group_uuid = ?)
+				AND (? = '' OR playlists.lock_device_uuid = ?)
+				AND (? = '' OR playlists.id IN (`+playlistsWithTrack+`))
+			ORDER BY user_playlists.rowid`,
+			filter.OwnerEmail, filter.Uuid, filter.Uuid, filter.GroupUuid, filter.GroupUuid,
+			filter.LockDeviceUuid, filter.LockDeviceUuid, filter.TrackUuid, filter.TrackUuid)
+	}
+	return loadPlaylists(s.db, `SELECT `+playlistColumns+` FROM playlists
+		WHERE (? = '' OR uuid = ?) AND (? = '' OR group_uuid = ?)
+			AND (? = '' OR lock_device_uuid = ?)
+			AND (? = '' OR id IN (`+playlistsWithTrack+`)) ORDER BY id`,
+		filter.Uuid, filter.Uuid, filter.GroupUuid, filter.GroupUuid,
+		filter.LockDeviceUuid, filter.LockDeviceUuid, filter.TrackUuid, filter.TrackUuid)
+}
+
+func (s *Storage) UserAddTrack(m *storage.User, t *storage.Track) (*uint64, error) {
+	t.Uuid = uuid.NewString()
+	err := s.transaction(func(tx *sql.Tx) error {
+		if err := putTrack(tx, t); err != nil {
+			return err
+		}
+		return addToUser(tx, "user_tracks", "track_id", m.Id, t.Id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.Tracks = append(m.Tracks, t)
+	return &t.Id, nil
+}
+
+func (s *Storage) PlaylistAddTrack(p *storage.Playlist, t *storage.Track) (*uint64, error) {
+	if t.Uuid == "" {
+		t.Uuid = uuid.NewString()
+	}
+	err := s.transaction(func(tx *sql.Tx) error {
+		var count int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM playlists WHERE id = ?`, p.Id).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			return storage.ErrPlaylistNotFound
+		}
+		if t.Id == 0 {
+			if err := putTrack(tx, t); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec(`INSERT OR IGNORE INTO playlist_tracks (playlist_id, track_id, position)
+			SELECT ?, ?, COALESCE(MAX(position), 0) + 1 FROM playlist_tracks WHERE playlist_id = ?`,
+			p.Id, t.Id, p.Id)
+		if err != nil {
+			return err
+		}
+		playlists, err := loadPlaylists(tx, `SELECT `+playlistColumns+` FROM playlists WHERE id = ?`, p.Id)
+		if err != nil {
+			return err
+		}
+		*p = *playlists[0]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &p.Id, nil
+}
+
+func (s *Storage) PlaylistRemoveTrack(p *storage.Playlist, t *storage.Track) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`DELETE FROM playlist_tracks WHERE playlist_id = ? AND track_id = ?`, p.Id, t.Id)
+		if err != nil {
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return storage.ErrTrackNotFound
+		}
+		playlists, err := loadPlaylists(tx, `SELECT `+playlistColumns+` FROM playlists WHERE id = ?`, p.Id)
+		if err != nil {
+			return err
+		}
+		*p = *playlists[0]
+		return nil
+	})
+}
+
+func (s *Storage) UpdateTrack(t *storage.Track) error {
+	if t.Id == 0 {
+		return storage.ErrMissingId
+	}
+	return putTrack(s.db, t)
+}
+
+func (s *Storage) DeleteTrack(t *storage.Track) error {
+	result, err := s.db.Exec(`DELETE FROM tracks WHERE id = ?`, t.Id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return storage.ErrTrackNotFound
+	}
+	return nil
+}
+
+func (s *Storage) SelectTrack(t *storage.Track) error {
+	var rows *sql.Rows
+	var err error
+	if t.Id != 0 {
+		rows, err = s.db.Query(`SELECT `+trackColumns+` FROM tracks WHERE id = ?`, t.Id)
+	} else if t.Uuid != "" {
+		rows, err = s.db.Query(`SELECT `+trackColumns+` FROM tracks WHERE uuid = ?`, t.Uuid)
+	} else {
+		return storage.ErrMissingId
+	}
+	if err != nil {
+		return err
+	}
+	tracks, err := scanTracks(rows)
+	if err != nil {
+		return err
+	}
+	if len(tracks) == 0 {
+		return storage.ErrTrackNotFound
+	}
+	*t = *tracks[0]
+	return nil
+}
+
+// trackFilterWhere applies every TrackFilter field except OwnerEmail, with
+// the arguments from trackFilterArgs
+const trackFilterWhere = `(? = '' OR tracks.uuid = ?) AND (? = '' OR tracks.path = ?)
+	AND (? = '' OR tracks.content_hash = ?)
+	AND (? = '' OR tracks.song_name LIKE ? ESCAPE '\' OR tracks.artist_name LIKE ? ESCAPE '\'
+		OR tracks.album_name LIKE ? ESCAPE '\' OR tracks.path LIKE ? ESCAPE '\')`
+
+func trackFilterArgs(filter storage.TrackFilter) []interface{} {
+	// LIKE is already case insensitive, only its wildcards need escaping
+	search := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(filter.Search)
+	search = "%" + search + "%"
+	return []interface{}{filter.Uuid, filter.Uuid, filter.Path, filter.Path,
+		filter.ContentHash, filter.ContentHash,
+		filter.Search, search, search, search, search}
+}
+
+func (s *Storage) FindTracks(filter storage.TrackFilter) ([]*storage.Track, error) {
+	var rows *sql.Rows
+	var err error
+	if filter.OwnerEmail != "" {
+		rows, err = s.db.Query(`SELECT `+trackColumns+` FROM tracks WHERE `+trackFilterWhere+` AND tracks.id IN (
+				SELECT user_tracks.track_id FROM user_tracks
+				JOIN users ON users.id = user_tracks.user_id
+				WHERE users.email_address = ?
+				UNION
+				SELECT playlist_tracks.track_id FROM playlist_tracks
+				JOIN user_playlists ON user_playlists.playlist_id = playlist_tracks.playlist_id
+				JOIN users ON users.id = user_playlists.user_id
+				WHERE users.email_address = ?
+			) ORDER BY tracks.id`,
+			append(trackFilterArgs(filter), filter.OwnerEmail, filter.OwnerEmail)...)
+	} else {
+		rows, err = s.db.Query(`SELECT `+trackColumns+` FROM tracks
+			WHERE `+trackFilterWhere+` ORDER BY tracks.id`,
+			trackFilterArgs(filter)...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return scanTracks(rows)
+}
+
+func (s *Storage) UserAddFriend(m *storage.User, f *storage.Friend) (*uint64, error) {
+	err := s.transaction(func(tx *sql.Tx) error {
+		if err := putFriend(tx, f); err != nil {
+			return err
+		}
+		return addToUser(tx, "user_friends", "friend_id", m.Id, f.Id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.Friends = append(m.Friends, f)
+	return &f.Id, nil
+}
+
+func (s *Storage) UpdateFriend(f *storage.Friend) error {
+	if f.Id == 0 {
+		return storage.ErrMissingId
+	}
+	result, err := s.db.Exec(`UPDATE friends SET friend_id = ?, status = ?, updated_at = ? WHERE id = ?`,
+		f.FriendId, f.Status, f.UpdatedAt, f.Id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return storage.ErrFriendNotFound
+	}
+	return nil
+}
+
+func (s *Storage) DeleteFriend(f *storage.Friend) error {
+	result, err := s.db.Exec(`DELETE FROM friends WHERE id = ?`, f.Id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return storage.ErrFriendNotFound
+	}
+	return nil
+}
+
+func (s *Storage) SavePlaylistShare(share *storage.PlaylistShare) error {
+	return s.db.QueryRow(`INSERT INTO playlist_shares
+			(playlist_uuid, owner_uuid, user_uuid, access, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (playlist_uuid, user_uuid) DO UPDATE SET
+			owner_uuid = excluded.owner_uuid, access = excluded.access, created_at = excluded.created_at
+		RETURNING id`,
+		share.PlaylistUuid, share.OwnerUuid, share.UserUuid, share.Access, share.CreatedAt).Scan(&share.Id)
+}
+
+func (s *Storage) DeletePlaylistShare(share *storage.PlaylistShare) error {
+	result, err := s.db.Exec(`DELETE FROM playlist_shares WHERE id = ?`, share.Id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return storage.ErrShareNotFound
+	}
+	return nil
+}
+
+func (s *Storage) FindPlaylistShares(filter storage.PlaylistShareFilter) ([]*storage.PlaylistShare, error) {
+	rows, err := s.db.Query(`SELECT id, playlist_uuid, owner_uuid, user_uuid, access, created_at
+		FROM playlist_shares
+		WHERE (? = '' OR playlist_uuid = ?) AND (? = '' OR owner_uuid = ?) AND (? = '' OR user_uuid = ?)
+		ORDER BY id`,
+		filter.PlaylistUuid, filter.PlaylistUuid, filter.OwnerUuid, filter.OwnerUuid, filter.UserUuid, filter.UserUuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	shares := []*storage.PlaylistShare{}
+	for rows.Next() {
+		share := &storage.PlaylistShare{}
+		err := rows.Scan(&share.Id, &share.PlaylistUuid, &share.OwnerUuid, &share.UserUuid, &share.Access, &share.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+func (s *Storage) InsertExternalLogin(l *storage.ExternalLogin) (*uint64, error) {
+	err := s.transaction(func(tx *sql.Tx) error {
+		var count int
+		err := tx.QueryRow(`SELECT COUNT(*) FROM external_logins
+			WHERE provider = ? AND (subject = ? OR user_uuid = ?)`, l.Provider, l.Subject, l.UserUuid).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return storage.ErrLoginExists
+		}
+		result, err := tx.Exec(`INSERT INTO external_logins (provider, subject, user_uuid, email_address, created_at)
+			VALUES (?, ?, ?, ?, ?)`, l.Provider, l.Subject, l.UserUuid, l.EmailAddress, l.CreatedAt)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		l.Id = uint64(id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &l.Id, nil
+}
+
+func (s *Storage) DeleteExternalLogin(l *storage.ExternalLogin) error {
+	result, err := s.db.Exec(`DELETE FROM external_logins WHERE id = ?`, l.Id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return storage.ErrLoginNotFound
+	}
+	return nil
+}
+
+func (s *Storage) FindExternalLogins(filter storage.ExternalLoginFilter) ([]*storage.ExternalLogin, error) {
+	rows, err := s.db.Query(`SELECT id, provider, subject, user_uuid, email_address, created_at
+		FROM external_logins
+		WHERE (? = '' OR provider = ?) AND (? = '' OR subject = ?) AND (? = '' OR user_uuid = ?)
+		ORDER BY id`,
+		filter.Provider, filter.Provider, filter.Subject, filter.Subject, filter.UserUuid, filter.UserUuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	logins := []*storage.ExternalLogin{}
+	for rows.Next() {
+		login := &storage.ExternalLogin{}
+		err := rows.Scan(&login.Id, &login.Provider, &login.Subject, &login.UserUuid, &login.EmailAddress, &login.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		logins = append(logins, login)
+	}
+	return logins, rows.Err()
+}
+
+func (s *Storage) InsertApiKey(k *storage.ApiKey) (*uint64, error) {
+	k.Uuid = uuid.NewString()
+	result, err := s.db.Exec(`INSERT INTO api_keys
+		(uuid, user_uuid, name, secret_hash, scopes, created_at, expires_at, last_used)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		k.Uuid, k.UserUuid, k.Name, k.SecretHash, strings.Join(k.Scopes, ","), k.CreatedAt, k.ExpiresAt, k.LastUsed)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	k.Id = uint64(id)
+	return &k.Id, nil
+}
+
+func (s *Storage) UpdateApiKey(k *storage.ApiKey) error {
+	result, err := s.db.Exec(`UPDATE api_keys SET name = ?, scopes = ?, expires_at = ?, last_used = ? WHERE id = ?`,
+		k.Name, strings.Join(k.Scopes, ","), k.ExpiresAt, k.LastUsed, k.Id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return storage.ErrApiKeyNotFound
+	}
+	return nil
+}
+
+func (s *Storage) DeleteApiKey(k *storage.ApiKey) error {
+	result, err := s.db.Exec(`DELETE FROM api_keys WHERE id = ?`, k.Id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return storage.ErrApiKeyNotFound
+	}
+	return nil
+}
+
+func (s *Storage) FindApiKeys(filter storage.ApiKeyFilter) ([]*storage.ApiKey, error) {
+	rows, err := s.db.Query(`SELECT id, uuid, user_uuid, name, secret_hash, scopes, created_at, expires_at, last_used
+		FROM api_keys
+		WHERE (? = '' OR uuid = ?) AND (? = '' OR user_uuid = ?)
+		ORDER BY id`,
+		filter.Uuid, filter.Uuid, filter.UserUuid, filter.UserUuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := []*storage.ApiKey{}
+	for rows.Next() {
+		key := &storage.ApiKey{}
+		var scopes string
+		err := rows.Scan(&key.Id, &key.Uuid, &key.UserUuid, &key.Name, &key.SecretHash, &scopes,
+			&key.CreatedAt, &key.ExpiresAt, &key.LastUsed)
+		if err != nil {
+			return nil, err
+		}
+		if scopes != "" {
+			key.Scopes = strings.Split(scopes, ",")
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (s *Storage) UserAddDevice(m *storage.User, d *storage.Device) (*uint64, error) {
+	d.Uuid = uuid.NewString()
+	err := s.transaction(func(tx *sql.Tx) error {
+		if err := putDevice(tx, d); err != nil {
+			return err
+		}
+		return addToUser(tx, "user_devices", "device_id", m.Id, d.Id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.Devices = append(m.Devices, d)
+	return &d.Id, nil
+}
+
+func (s *Storage) UpdateDevice(d *storage.Device) error {
+	if d.Id == 0 {
+		return storage.ErrMissingId
+	}
+	return putDevice(s.db, d)
+}
+
+func (s *Storage) UpdateDeviceDetails(d *storage.Device) error {
+	result, err := s.db.Exec(`UPDATE devices SET name = ?, last_seen = ? WHERE id = ?`, d.Name, d.LastSeen, d.Id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return storage.ErrDeviceNotFound
+	}
+	return nil
+}
+
+func (s *Storage) RotateDeviceRefreshToken(d *storage.Device, expectedHash string) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		var stored string
+		err := tx.QueryRow(`SELECT refresh_token_hash FROM devices WHERE id = ?`, d.Id).Scan(&stored)
+		if err == sql.ErrNoRows {
+			return storage.ErrDeviceNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if stored != expectedHash {
+			return storage.ErrRefreshConflict
+		}
+		_, err = tx.Exec(`UPDATE devices SET last_seen = ?, last_token_id = ?, refresh_token_hash = ?,
+				refresh_token_expires = ?, refresh_token_family = ?, refresh_token_generation = ?, scopes = ?
+			WHERE id = ?`,
+			d.LastSeen, d.LastTokenId, d.RefreshTokenHash, d.RefreshTokenExpires,
+			d.RefreshTokenFamily, d.RefreshTokenGeneration, strings.Join(d.Scopes, ","), d.Id)
+		return err
+	})
+}
+
+func (s *Storage) DeleteDevice(d *storage.Device) error {
+	result, err := s.db.Exec(`DELETE FROM devices WHERE id = ?`, d.Id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return storage.ErrDeviceNotFound
+	}
+	return nil
+}
+
+func (s *Storage) SelectDevice(d *storage.Device) error {
+	var rows *sql.Rows
+	var err error
+	if d.Id != 0 {
+		rows, err = s.db.Query(`SELECT `+deviceColumns+` FROM devices WHERE id = ?`, d.Id)
+	} else if d.Uuid != "" {
+		rows, err = s.db.Query(`SELECT `+deviceColumns+` FROM devices WHERE uuid = ?`, d.Uuid)
+	} else {
+		return storage.ErrMissingId
+	}
+	if err != nil {
+		return err
+	}
+	devices, err := scanDevices(rows)
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		return storage.ErrDeviceNotFound
+	}
+	*d = *devices[0]
+	return nil
+}
+
+func (s *Storage) FindDevices(filter storage.DeviceFilter) ([]*storage.Device, error) {
+	var rows *sql.Rows
+	var err error
+	if filter.OwnerEmail != "" {
+		rows, err = s.db.Query(`SELECT `+deviceColumns+` FROM devices
+			JOIN user_devices ON user_devices.device_id = devices.id
+			JOIN users ON users.id = user_devices.user_id
+			WHERE users.email_address = ? AND (? = '' OR devices.uuid = ?)
+			ORDER BY user_devices.rowid`,
+			filter.OwnerEmail, filter.Uuid, filter.Uuid)
+	} else {
+		rows, err = s.db.Query(`SELECT `+deviceColumns+` FROM devices
+			WHERE (? = '' OR uuid = ?) ORDER BY id`,
+			filter.Uuid, filter.Uuid)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return scanDevices(rows)
+}
+
+// sqlLimit turns a filter limit into a LIMIT argument, sqlite treats a
+// negative limit as no limit
+func sqlLimit(limit int) int {
+	if limit <= 0 {
+		return -1
+	}
+	return limit
+}
+
+func (s *Storage) SaveResumePosition(r *storage.ResumePosition) error {
+	return s.db.QueryRow(`INSERT INTO resume_positions
+			(user_uuid, track_uuid, playlist_uuid, device_uuid, elapsed, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_uuid, track_uuid) DO UPDATE SET
+			playlist_uuid = excluded.playlist_uuid, device_uuid = excluded.device_uuid,
+			elapsed = excluded.elapsed, updated_at = excluded.updated_at
+		RETURNING id`,
+		r.UserUuid, r.TrackUuid, r.PlaylistUuid, r.DeviceUuid, r.Elapsed, r.UpdatedAt).Scan(&r.Id)
+}
+
+func (s *Storage) FindResumePositions(filter storage.ResumePositionFilter) ([]*storage.ResumePosition, error) {
+	rows, err := s.db.Query(`SELECT id, user_uuid, track_uuid, playlist_uuid, device_uuid, elapsed, updated_at
+		FROM resume_positions
+		WHERE (? = '' OR user_uuid = ?) AND (? = '' OR track_uuid = ?)
+		ORDER BY updated_at DESC, id DESC LIMIT ?`,
+		filter.UserUuid, filter.UserUuid, filter.TrackUuid, filter.TrackUuid, sqlLimit(filter.Limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	positions := []*storage.ResumePosition{}
+	for rows.Next() {
+		r := &storage.ResumePosition{}
+		err := rows.Scan(&r.Id, &r.UserUuid, &r.TrackUuid, &r.PlaylistUuid, &r.DeviceUuid, &r.Elapsed, &r.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, r)
+	}
+	return positions, rows.Err()
+}
+
+func (s *Storage) AddPlay(p *storage.Play) (*uint64, error) {
+	result, err := s.db.Exec(`INSERT INTO plays
+			(user_uuid, track_uuid, playlist_uuid, device_uuid, start_offset, end_offset, started_at, ended_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.UserUuid, p.TrackUuid, p.PlaylistUuid, p.DeviceUuid, p.StartOffset, p.EndOffset, p.StartedAt, p.EndedAt)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	p.Id = uint64(id)
+	return &p.Id, nil
+}
+
+func (s *Storage) FindPlays(filter storage.PlayFilter) ([]*storage.Play, error) {
+	rows, err := s.db.Query(`SELECT id, user_uuid, track_uuid, playlist_uuid, device_uuid,
+			start_offset, end_offset, started_at, ended_at
+		FROM plays
+		WHERE (? = '' OR user_uuid = ?) AND (? = '' OR track_uuid = ?) AND ended_at >= ?
+		ORDER BY ended_at DESC, id DESC LIMIT ?`,
+		filter.UserUuid, filter.UserUuid, filter.TrackUuid, filter.TrackUuid, filter.Since, sqlLimit(filter.Limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	plays := []*storage.Play{}
+	for rows.Next() {
+		p := &storage.Play{}
+		err := rows.Scan(&p.Id, &p.UserUuid, &p.TrackUuid, &p.PlaylistUuid, &p.DeviceUuid,
+			&p.StartOffset, &p.EndOffset, &p.StartedAt, &p.EndedAt)
+		if err != nil {
+			return nil, err
+		}
+		plays = append(plays, p)
+	}
+	return plays, rows.Err()
+}
+
+func (s *Storage) RevokeToken(t *storage.RevokedToken) error {
+	return s.db.QueryRow(`INSERT INTO revoked_tokens (token_id, expires_at) VALUES (?, ?)
+		ON CONFLICT (token_id) DO UPDATE SET token_id = excluded.token_id
+		RETURNING id`, t.TokenId, t.ExpiresAt).Scan(&t.Id)
+}
+
+func (s *Storage) IsTokenRevoked(tokenId string) (bool, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM revoked_tokens WHERE token_id = ?`, tokenId).Scan(&count)
+	return count > 0, err
+}
+
+func (s *Storage) PruneRevokedTokens(before int64) (int, error) {
+	result, err := s.db.Exec(`DELETE FROM revoked_tokens WHERE expires_at < ?`, before)
+	if err != nil {
+		return 0, err
+	}
+	pruned, err := result.RowsAffected()
+	return int(pruned), err
+}
+
+// checkGroupRevision returns ErrGroupConflict when the stored group has moved
+// on from g
+func checkGroupRevision(tx *sql.Tx, g *storage.Group) error {
+	var revision uint64
+	err := tx.QueryRow(`SELECT revision FROM groups WHERE id = ?`, g.Id).Scan(&revision)
+	if err == sql.ErrNoRows {
+		return storage.ErrGroupNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if revision != g.Revision {
+		return storage.ErrGroupConflict
+	}
+	return nil
+}
+
+func (s *Storage) SaveGroup(g *storage.Group) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		for _, member := range g.Members {
+			var count int
+			err := tx.QueryRow(`SELECT COUNT(*) FROM group_members WHERE user_uuid = ? AND group_id != ?`,
+				member.UserUuid, g.Id).Scan(&count)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return storage.ErrAlreadyInGroup
+			}
+		}
+		inserted := g.Id == 0
+		if inserted {
+			g.Uuid = uuid.NewString()
+			g.Revision = 0
+			err := tx.QueryRow(`INSERT INTO groups (uuid, name, created_at) VALUES (?, ?, ?) RETURNING id`,
+				g.Uuid, g.Name, g.CreatedAt).Scan(&g.Id)
+			if err != nil {
+				return err
+			}
+		} else {
+			if err := checkGroupRevision(tx, g); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`UPDATE groups SET name = ?, created_at = ?, revision = ? WHERE id = ?`,
+				g.Name, g.CreatedAt, g.Revision+1, g.Id)
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(`DELETE FROM group_members WHERE group_id = ?`, g.Id); err != nil {
+			return err
+		}
+		for _, member := range g.Members {
+			_, err := tx.Exec(`INSERT INTO group_members (group_id, user_uuid, manager, restrictions) VALUES (?, ?, ?, ?)`,
+				g.Id, member.UserUuid, member.Manager, strings.Join(member.Restrictions, ","))
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(`DELETE FROM group_invites WHERE group_id = ?`, g.Id); err != nil {
+			return err
+		}
+		for _, invite := range g.Invites {
+			_, err := tx.Exec(`INSERT INTO group_invites (group_id, user_uuid, invited_by, created_at) VALUES (?, ?, ?, ?)`,
+				g.Id, invite.UserUuid, invite.InvitedBy, invite.CreatedAt)
+			if err != nil {
+				return err
+			}
+		}
+		if !inserted {
+			g.Revision++
+		}
+		return nil
+	})
+}
+
+func (s *Storage) DeleteGroup(g *storage.Group) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		if err := checkGroupRevision(tx, g); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`UPDATE playlists SET group_uuid = ''
+			WHERE group_uuid = (SELECT uuid FROM groups WHERE id = ?)`, g.Id)
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec(`DELETE FROM groups WHERE id = ?`, g.Id)
+		if err != nil {
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return storage.ErrGroupNotFound
+		}
+		return nil
+	})
+}
+
+func (s *Storage) FindGroups(filter storage.GroupFilter) ([]*storage.Group, error) {
+	rows, err := s.db.Query(`SELECT id, uuid, name, created_at, revision FROM groups
+		WHERE (? = '' OR uuid = ?)
+			AND (? = '' OR id IN (SELECT group_id FROM group_members WHERE user_uuid = ?))
+			AND (? = '' OR id IN (SELECT group_id FROM group_invites WHERE user_uuid = ?))
+		ORDER BY id`,
+		filter.Uuid, filter.Uuid, filter.MemberUuid, filter.MemberUuid, filter.InviteeUuid, filter.InviteeUuid)
+	if err != nil {
+		return nil, err
+	}
+	groups := []*storage.Group{}
+	for rows.Next() {
+		g := &storage.Group{}
+		if err := rows.Scan(&g.Id, &g.Uuid, &g.Name, &g.CreatedAt, &g.Revision); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, g := range groups {
+		memberRows, err := s.db.Query(`SELECT user_uuid, manager, restrictions FROM group_members
+			WHERE group_id = ? ORDER BY rowid`, g.Id)
+		if err != nil {
+			return nil, err
+		}
+		for memberRows.Next() {
+			member := &storage.GroupMember{}
+			var restrictions string
+			if err := memberRows.Scan(&member.UserUuid, &member.Manager, &restrictions); err != nil {
+				memberRows.Close()
+				return nil, err
+			}
+			if restrictions != "" {
+				member.Restrictions = strings.Split(restrictions, ",")
+			}
+			g.Members = append(g.Members, member)
+		}
+		memberRows.Close()
+		if err := memberRows.Err(); err != nil {
+			return nil, err
+		}
+
+		inviteRows, err := s.db.Query(`SELECT user_uuid, invited_by, created_at FROM group_invites
+			WHERE group_id = ? ORDER BY rowid`, g.Id)
+		if err != nil {
+			return nil, err
+		}
+		for inviteRows.Next() {
+			invite := &storage.GroupInvite{}
+			if err := inviteRows.Scan(&invite.UserUuid, &invite.InvitedBy, &invite.CreatedAt); err != nil {
+				inviteRows.Close()
+				return nil, err
+			}
+			g.Invites = append(g.Invites, invite)
+		}
+		inviteRows.Close()
+		if err := inviteRows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
@@ -4,226 +4,592 @@ import (
 	"bytes"
 	"encoding/gob"
 	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Store is the storage backend selected at startup, every model method below
+// is routed through it.
+var Store DataStorage
 
-	"github.com/google/uuid"
-	"github.com/objectbox/objectbox-go/objectbox"
+// Errors shared by all of the drivers, so callers don't need to know which
+// backend they are talking to
+var (
+	ErrUserExists       = errors.New("User already exists")
+	ErrUserNotFound     = errors.New("Failed to Find User account")
+	ErrPlaylistNotFound = errors.New("Failed to Find Playlist")
+	ErrTrackNotFound    = errors.New("Failed to Find Track")
+	ErrFriendNotFound   = errors.New("Failed to Find Friend")
+	ErrShareNotFound    = errors.New("Failed to Find Playlist Share")
+	ErrGroupNotFound    = errors.New("Failed to Find Group")
+	ErrAlreadyInGroup   = errors.New("User is already in a group")
+	ErrLoginNotFound    = errors.New("Failed to Find External Login")
+	ErrLoginExists      = errors.New("External Login is already linked")
+	ErrApiKeyNotFound   = errors.New("Failed to Find API Key")
+	ErrDeviceNotFound   = errors.New("Failed to Find Device")
+	ErrMissingId        = errors.New("Missing Id")
+	ErrRevisionConflict = errors.New("Playlist has been updated by another client")
+	ErrLockConflict     = errors.New("Playlist lock has been changed by another device")
+	ErrRefreshConflict  = errors.New("Refresh Token has been changed by another request")
+	ErrGroupConflict    = errors.New("Group has been changed by someone else")
+	ErrSecondFactorUsed = errors.New("Two factor code has already been used")
+	ErrTrackOrder       = errors.New("Track order must list every track in the playlist once")
 )
 
-var Ob *objectbox.ObjectBox
+// UserFilter limits the users returned by FindUsers, blank fields match everything
+type UserFilter struct {
+	Uuid         string
+	EmailAddress string
+	Role         string // users who have been given the role
+	PlaylistUuid string // the user who owns the playlist
+}
 
-type DataStorage interface {
-	Insert() (*uint64, error)
-	Update() (*uint64, error)
-	Delete() error
-	Select() error
-	Find(conditions ...objectbox.Condition) (bool, error)
-	AddPlaylist(p *Playlist) (*uint64, error)
-	AddTrack(t *Track)
-	Copy(src interface{})
+// PlaylistFilter limits the playlists returned by FindPlaylists. When
+// OwnerEmail is set only playlists belonging to that user are returned.
+type PlaylistFilter struct {
+	Uuid           string
+	OwnerEmail     string
+	GroupUuid      string // playlists belonging to the household group
+	LockDeviceUuid string // playlists the device has locked, whoever owns them
+	TrackUuid      string // playlists the track is in
 }
 
-func Initialize() *objectbox.ObjectBox {
-	Ob, _ = objectbox.NewBuilder().Model(ObjectBoxModel()).Build()
-	return Ob
+// TrackFilter limits the tracks returned by FindTracks. When OwnerEmail is set
+// only tracks in that user's library or playlists are returned. Search matches
+// a case insensitive substring of the song, artist, album or path.
+type TrackFilter struct {
+	Uuid        string
+	OwnerEmail  string
+	Path        string
+	ContentHash string
+	Search      string
 }
 
-func (m *User) Insert() (*uint64, error) {
-	box := BoxForUser(Ob)
-	findQuery := box.Query(User_.EmailAddress.Equals(m.EmailAddress, false))
-	findResults, _ := findQuery.Count()
-	if findResults > 0 {
-		return nil, errors.New("User already exists")
+// Matches reports whether t passes every filter except OwnerEmail, for the
+// drivers that filter tracks in memory
+func (filter TrackFilter) Matches(t *Track) bool {
+	if (filter.Uuid != "" && t.Uuid != filter.Uuid) ||
+		(filter.Path != "" && t.Path != filter.Path) ||
+		(filter.ContentHash != "" && t.ContentHash != filter.ContentHash) {
+		return false
 	}
-	m.Uuid = uuid.NewString()
-	id, err := box.Put(m)
+	if filter.Search == "" {
+		return true
+	}
+	search := strings.ToLower(filter.Search)
+	for _, field := range []string{t.SongName, t.ArtistName, t.AlbumName, t.Path} {
+		if strings.Contains(strings.ToLower(field), search) {
+			return true
+		}
+	}
+	return false
+}
 
-	return &id, err
+// DeviceFilter limits the devices returned by FindDevices. When OwnerEmail is
+// set only that user's devices are returned.
+type DeviceFilter struct {
+	Uuid       string
+	OwnerEmail string
 }
 
-func (m *User) Update() error {
-	box := BoxForUser(Ob)
-	_, err := box.Put(m)
-	if err != nil {
-		return err
-	}
-	errs := m.Select()
+// ResumePositionFilter limits the positions returned by FindResumePositions,
+// which are returned most recently updated first. Limit 0 returns them all.
+type ResumePositionFilter struct {
+	UserUuid  string
+	TrackUuid string
+	Limit     int
+}
 
-	return errs
+// PlayFilter limits the plays returned by FindPlays, which are returned most
+// recent first. Only plays ending at or after Since are returned, Limit 0
+// returns them all.
+type PlayFilter struct {
+	UserUuid  string
+	TrackUuid string
+	Since     int64
+	Limit     int
 }
 
-func (m *User) Delete() error {
-	// Moving all of the Code here.. so it can be datastorage agnostic
-	box := BoxForUser(Ob)
-	err := box.Remove(m)
-	return err
+// PlaylistShareFilter limits the shares returned by FindPlaylistShares, which
+// are returned oldest first
+type PlaylistShareFilter struct {
+	PlaylistUuid string
+	OwnerUuid    string
+	UserUuid     string
 }
 
-func (m *User) Select() error {
-	box := BoxForUser(Ob)
+// GroupFilter limits the groups returned by FindGroups, which are returned
+// oldest first
+type GroupFilter struct {
+	Uuid        string
+	MemberUuid  string // the group the user is a member of
+	InviteeUuid string // groups that have invited the user
+}
 
-	if m.Id != 0 {
-		loadUser, err := box.Get(m.Id)
-		if err != nil {
-			return err
-		}
-		m = loadUser
-	} else if m.Uuid != "" {
-		userList, _ := m.Find(User_.Uuid.Equals(m.Uuid, true))
-		if len(userList) == 0 {
-			return errors.New("Failed to Find User account")
-		}
-		loadUser, err := box.Get(userList[0].Id)
-		if err != nil {
-			return err
-		}
-		m = loadUser
-	} else if m.EmailAddress != "" {
-		userList, _ := m.Find(User_.EmailAddress.Equals(m.EmailAddress, true))
-		if len(userList) == 0 {
-			return errors.New("Failed to Find User account")
-		}
-		loadUser, err := box.Get(userList[0].Id)
-		if err != nil {
-			return err
-		}
-		m = loadUser
-	}
+// ExternalLoginFilter limits the logins returned by FindExternalLogins, which
+// are returned oldest first
+type ExternalLoginFilter struct {
+	Provider string
+	Subject  string
+	UserUuid string
+}
 
-	return nil
+// ApiKeyFilter limits the keys returned by FindApiKeys, which are returned
+// oldest first
+type ApiKeyFilter struct {
+	Uuid     string
+	UserUuid string
 }
 
-func (m *User) Exists() (bool, error) {
-	box := BoxForUser(Ob)
+type UserStorage interface {
+	InsertUser(m *User) (*uint64, error)
+	// UpdateUser stores just the user's own fields, its playlists, tracks,
+	// friends and devices are only changed through their own methods
+	UpdateUser(m *User) error
+	// UseUserTotpStep stores just m.TotpLastStep, when the stored step still
+	// equals expectedStep, otherwise it returns ErrSecondFactorUsed
+	UseUserTotpStep(m *User, expectedStep int64) error
+	// UseUserRecoveryCode takes the hash out of the stored recovery codes,
+	// leaving m.TotpRecoveryCodes with the rest. It returns ErrSecondFactorUsed
+	// when the hash is no longer stored.
+	UseUserRecoveryCode(m *User, hash string) error
+	// DeleteUser also takes the user out of their group, unlinks their
+	// external logins and deletes their API keys
+	DeleteUser(m *User) error
+	SelectUser(m *User) error
+	UserExists(m *User) (bool, error)
+	FindUsers(filter UserFilter) ([]*User, error)
+}
 
-	if m.Id != 0 {
-		user, err := box.Get(m.Id)
-		if user != nil {
-			return true, nil
-		}
-		return false, err
-	} else if m.Uuid != "" {
-		userList, _ := m.Find(User_.Uuid.Equals(m.Uuid, true))
-		if len(userList) == 0 {
-			return false, errors.New("Failed to Find User account")
-		}
+// The UserAdd* methods store the new entity and relate it to the user, the
+// rest of m is not stored
+
+type PlaylistStorage interface {
+	UserAddPlaylist(m *User, p *Playlist) (*uint64, error)
+	UpdatePlaylist(p *Playlist) error
+	// UpdatePlaylistAtRevision only stores p when the stored PositionRevision
+	// still equals revision, otherwise it returns ErrRevisionConflict. Only the
+	// Name and the position (CurrentTrackUuid, Elapsed, PositionRevision and
+	// PositionUpdatedAt) are stored, p is then reloaded with the stored tracks
+	// and lock.
+	UpdatePlaylistAtRevision(p *Playlist, revision uint64) error
+	// UpdatePlaylistLock stores just the LockDeviceUuid and ClientLockExpires
+	// of p, when the stored lock is still held by heldBy, otherwise it returns
+	// ErrLockConflict
+	UpdatePlaylistLock(p *Playlist, heldBy string) error
+	// ReorderPlaylistTracks stores the playlist's tracks in the order of
+	// trackUuids, which must hold every track of the stored playlist exactly
+	// once, otherwise it returns ErrTrackOrder. Only the order is stored, p.Tracks
+	// is replaced with the reordered tracks.
+	ReorderPlaylistTracks(p *Playlist, trackUuids []string) error
+	// DeletePlaylist also deletes every share of the playlist
+	DeletePlaylist(p *Playlist) error
+	SelectPlaylist(p *Playlist) error
+	PlaylistExists(p *Playlist) (bool, error)
+	FindPlaylists(filter PlaylistFilter) ([]*Playlist, error)
+}
+
+type TrackStorage interface {
+	// UserAddTrack adds a new track to the user's library
+	UserAddTrack(m *User, t *Track) (*uint64, error)
+	// PlaylistAddTrack adds t to the end of the playlist, t is stored first
+	// and is given a Uuid when it doesn't have one yet
+	PlaylistAddTrack(p *Playlist, t *Track) (*uint64, error)
+	// PlaylistRemoveTrack takes t out of the playlist, it stays in the library
+	PlaylistRemoveTrack(p *Playlist, t *Track) error
+	UpdateTrack(t *Track) error
+	DeleteTrack(t *Track) error
+	SelectTrack(t *Track) error
+	FindTracks(filter TrackFilter) ([]*Track, error)
+}
+
+type FriendStorage interface {
+	UserAddFriend(m *User, f *Friend) (*uint64, error)
+	UpdateFriend(f *Friend) error
+	DeleteFriend(f *Friend) error
+}
+
+type ShareStorage interface {
+	// SavePlaylistShare inserts or replaces the share for the playlist and user
+	SavePlaylistShare(s *PlaylistShare) error
+	DeletePlaylistShare(s *PlaylistShare) error
+	FindPlaylistShares(filter PlaylistShareFilter) ([]*PlaylistShare, error)
+}
+
+type GroupStorage interface {
+	// SaveGroup inserts the group when it has no Id, giving it a Uuid, or
+	// replaces it, its members and its invites. A user already in another group can't be
+	// added, that returns ErrAlreadyInGroup. A group is only replaced while the
+	// stored Revision still equals g.Revision, otherwise it returns
+	// ErrGroupConflict, and saving it moves g.Revision on.
+	SaveGroup(g *Group) error
+	// DeleteGroup also takes its playlists out of the group, they stay with
+	// the members they belong to. Like SaveGroup it returns ErrGroupConflict
+	// when g.Revision is stale.
+	DeleteGroup(g *Group) error
+	FindGroups(filter GroupFilter) ([]*Group, error)
+}
+
+type ExternalLoginStorage interface {
+	// InsertExternalLogin links the login, it returns ErrLoginExists when the
+	// subject is already linked or the user already has a login with the
+	// provider
+	InsertExternalLogin(l *ExternalLogin) (*uint64, error)
+	DeleteExternalLogin(l *ExternalLogin) error
+	FindExternalLogins(filter ExternalLoginFilter) ([]*ExternalLogin, error)
+}
+
+type ApiKeyStorage interface {
+	// InsertApiKey gives the key a Uuid
+	InsertApiKey(k *ApiKey) (*uint64, error)
+	UpdateApiKey(k *ApiKey) error
+	DeleteApiKey(k *ApiKey) error
+	FindApiKeys(filter ApiKeyFilter) ([]*ApiKey, error)
+}
+
+type DeviceStorage interface {
+	UserAddDevice(m *User, d *Device) (*uint64, error)
+	UpdateDevice(d *Device) error
+	// UpdateDeviceDetails stores just the Name and LastSeen of d, so a device
+	// loaded before a sign in or refresh can't put back the token it replaced
+	UpdateDeviceDetails(d *Device) error
+	// RotateDeviceRefreshToken stores just the token fields (LastTokenId, the
+	// RefreshToken* fields and Scopes) and LastSeen of d, when the stored
+	// RefreshTokenHash still equals expectedHash, otherwise it returns
+	// ErrRefreshConflict
+	RotateDeviceRefreshToken(d *Device, expectedHash string) error
+	DeleteDevice(d *Device) error
+	SelectDevice(d *Device) error
+	FindDevices(filter DeviceFilter) ([]*Device, error)
+}
+
+type ListeningStorage interface {
+	// SaveResumePosition inserts or replaces the position for the user and track
+	SaveResumePosition(r *ResumePosition) error
+	FindResumePositions(filter ResumePositionFilter) ([]*ResumePosition, error)
+	AddPlay(p *Play) (*uint64, error)
+	FindPlays(filter PlayFilter) ([]*Play, error)
+}
+
+type TokenStorage interface {
+	// RevokeToken adds the token to the denylist, revoking it again is not an error
+	RevokeToken(t *RevokedToken) error
+	IsTokenRevoked(tokenId string) (bool, error)
+	// PruneRevokedTokens forgets the tokens that expired before the unix time,
+	// returning how many were removed
+	PruneRevokedTokens(before int64) (int, error)
+}
+
+// DataStorage is the backend neutral repository implemented by each storage
+// driver (objectbox, memory, ...)
+type DataStorage interface {
+	UserStorage
+	PlaylistStorage
+	TrackStorage
+	FriendStorage
+	ShareStorage
+	GroupStorage
+	ExternalLoginStorage
+	ApiKeyStorage
+	DeviceStorage
+	ListeningStorage
+	TokenStorage
+	Close() error
+}
+
+// Driver opens a DataStorage, dataSource is driver specific (a directory,
+// a file name, or blank for the driver default)
+type Driver func(dataSource string) (DataStorage, error)
+
+var (
+	driversMutex sync.RWMutex
+	drivers      = make(map[string]Driver)
+)
+
+// Register makes a storage driver available by name, it is intended to be
+// called from the init function of the driver package.
+func Register(name string, driver Driver) {
+	driversMutex.Lock()
+	defer driversMutex.Unlock()
+	if driver == nil {
+		panic("storage: Register driver is nil")
 	}
-	return false, errors.New("User has no value to search for")
+	if _, dup := drivers[name]; dup {
+		panic("storage: Register called twice for driver " + name)
+	}
+	drivers[name] = driver
 }
 
-func IsAdminUser(username string) bool {
-	box := BoxForUser(Ob)
-	findQuery := box.Query(User_.EmailAddress.Equals(username, false),
-		User_.AdminUser.Equals(true))
-	findResults, _ := findQuery.Count()
-	if findResults > 0 {
-		return true
+// Drivers returns the sorted names of the registered drivers
+func Drivers() []string {
+	driversMutex.RLock()
+	defer driversMutex.RUnlock()
+	var list []string
+	for name := range drivers {
+		list = append(list, name)
 	}
+	sort.Strings(list)
+	return list
+}
 
-	return false
+// Initialize opens the named driver and makes it the active Store
+func Initialize(driverName string, dataSource string) (DataStorage, error) {
+	driversMutex.RLock()
+	driver, ok := drivers[driverName]
+	driversMutex.RUnlock()
+	if !ok {
+		return nil, errors.New("Unknown storage driver: " + driverName)
+	}
+	store, err := driver(dataSource)
+	if err != nil {
+		return nil, err
+	}
+	Store = store
+	return Store, nil
 }
 
-// I'm not sure this is the best way to do a find on an object, but for testing my code, I think it's the best way to go.
-func (m *User) Find(conditions ...objectbox.Condition) ([]*User, error) {
-	box := BoxForUser(Ob)
-	findQuery := box.Query(conditions...)
-	users, err := findQuery.Find()
-	return users, err
+func (m *User) Insert() (*uint64, error) {
+	return Store.InsertUser(m)
+}
+
+func (m *User) Update() error {
+	return Store.UpdateUser(m)
+}
+
+func (m *User) UseTotpStep(expectedStep int64) error {
+	return Store.UseUserTotpStep(m, expectedStep)
+}
+
+func (m *User) UseRecoveryCode(hash string) error {
+	return Store.UseUserRecoveryCode(m, hash)
+}
+
+func (m *User) Delete() error {
+	return Store.DeleteUser(m)
+}
+
+// Select loads the user by Id, Uuid or EmailAddress (checked in that order)
+func (m *User) Select() error {
+	return Store.SelectUser(m)
+}
+
+func (m *User) Exists() (bool, error) {
+	return Store.UserExists(m)
+}
+
+func (m *User) Find(filter UserFilter) ([]*User, error) {
+	return Store.FindUsers(filter)
 }
 
 func UserAddPlaylist(m *User, p *Playlist) (*uint64, error) {
-	box := BoxForUser(Ob)
-	p.Uuid = uuid.NewString()
-	m.Playlists = append(m.Playlists, p)
-	index, err := box.Put(m)
-	return &index, err
+	return Store.UserAddPlaylist(m, p)
+}
+
+func UserAddFriend(m *User, f *Friend) (*uint64, error) {
+	return Store.UserAddFriend(m, f)
 }
 
 func (p *Playlist) Delete() error {
-	box := BoxForPlaylist(Ob)
-	err := box.Remove(p)
-	return err
+	return Store.DeletePlaylist(p)
 }
 
+// Select loads the playlist by Id or Uuid
 func (p *Playlist) Select() error {
-	box := BoxForPlaylist(Ob)
-
-	if p.Id != 0 {
-		loadPlaylist, err := box.Get(p.Id)
-		if err != nil {
-			return err
-		}
-		p = loadPlaylist
-	} else if p.Uuid != "" {
-		playlistResult, _ := p.Find(Playlist_.Uuid.Equals(p.Uuid, true))
-		if len(playlistResult) == 0 {
-			return errors.New("Failed to Find Playlist")
-		}
-		loadPlaylist, err := box.Get(playlistResult[0].Id)
-		if err != nil {
-			return err
-		}
-		p = loadPlaylist
-	}
-	return errors.New("Missing Id")
+	return Store.SelectPlaylist(p)
 }
 
 func (p *Playlist) Exists() (bool, error) {
-	box := BoxForPlaylist(Ob)
+	return Store.PlaylistExists(p)
+}
 
-	if p.Id != 0 {
-		loadPlaylist, err := box.Get(p.Id)
-		if loadPlaylist != nil {
-			return true, nil
-		}
-		return false, err
-	} else if p.Uuid != "" {
-		playlistResult, _ := p.Find(Playlist_.Uuid.Equals(p.Uuid, true))
-		if len(playlistResult) == 0 {
-			return false, errors.New("Failed to Find Playlist")
+func (p *Playlist) Find(filter PlaylistFilter) ([]*Playlist, error) {
+	return Store.FindPlaylists(filter)
+}
+
+func (p *Playlist) Update() error {
+	return Store.UpdatePlaylist(p)
+}
+
+func (p *Playlist) UpdateAtRevision(revision uint64) error {
+	return Store.UpdatePlaylistAtRevision(p, revision)
+}
+
+func (p *Playlist) UpdateLock(heldBy string) error {
+	return Store.UpdatePlaylistLock(p, heldBy)
+}
+
+func (p *Playlist) ReorderTracks(trackUuids []string) error {
+	return Store.ReorderPlaylistTracks(p, trackUuids)
+}
+
+// OrderTracks returns tracks in the order of trackUuids, or ErrTrackOrder
+// when trackUuids isn't exactly the uuids of tracks. Drivers use it to check
+// a new order against the stored tracks.
+func OrderTracks(tracks []*Track, trackUuids []string) ([]*Track, error) {
+	if len(tracks) != len(trackUuids) {
+		return nil, ErrTrackOrder
+	}
+	byUuid := make(map[string]*Track)
+	for _, t := range tracks {
+		byUuid[t.Uuid] = t
+	}
+	ordered := make([]*Track, 0, len(tracks))
+	for _, uuid := range trackUuids {
+		t, ok := byUuid[uuid]
+		if !ok {
+			return nil, ErrTrackOrder
 		}
-		return true, nil
+		delete(byUuid, uuid)
+		ordered = append(ordered, t)
 	}
-	return false, errors.New("Missing id")
+	return ordered, nil
 }
 
-func (p *Playlist) Find(conditions ...objectbox.Condition) ([]*Playlist, error) {
-	box := BoxForPlaylist(Ob)
-	findQuery := box.Query(conditions...)
-	playlists, err := findQuery.Find()
-	return playlists, err
+func PlaylistAddTrack(p *Playlist, t *Track) (*uint64, error) {
+	return Store.PlaylistAddTrack(p, t)
 }
 
-func (p *Playlist) Update() error {
-	box := BoxForPlaylist(Ob)
-	_, err := box.Put(p)
-	return err
+func PlaylistRemoveTrack(p *Playlist, t *Track) error {
+	return Store.PlaylistRemoveTrack(p, t)
+}
+
+func UserAddTrack(m *User, t *Track) (*uint64, error) {
+	return Store.UserAddTrack(m, t)
 }
 
-func PlaylistAddTrack(p *Playlist,t *Track) (*uint64, error) {
-	box := BoxForPlaylist(Ob)
-	t.Uuid = uuid.NewString()
-	p.Tracks = append(p.Tracks, t)
-	index, err := box.Put(p)
-	return &index, err
+func (t *Track) Find(filter TrackFilter) ([]*Track, error) {
+	return Store.FindTracks(filter)
 }
 
-func (t *Track) Find(conditions ...objectbox.Condition) ([]*Track, error) {
-	box := BoxForTrack(Ob)
-	findQuery := box.Query(conditions...)
-	tracks, err := findQuery.Find()
-	return tracks, err
+// Select loads the track by Id or Uuid
+func (t *Track) Select() error {
+	return Store.SelectTrack(t)
 }
 
 func (t *Track) Delete() error {
-	box := BoxForTrack(Ob)
-	err := box.Remove(t)
-	return err
+	return Store.DeleteTrack(t)
 }
 
 func (t *Track) Update() error {
-	box := BoxForTrack(Ob)
-	_, err := box.Put(t)
-	return err
+	return Store.UpdateTrack(t)
+}
+
+func (f *Friend) Update() error {
+	return Store.UpdateFriend(f)
+}
+
+func (f *Friend) Delete() error {
+	return Store.DeleteFriend(f)
+}
+
+func (s *PlaylistShare) Save() error {
+	return Store.SavePlaylistShare(s)
+}
+
+func (s *PlaylistShare) Delete() error {
+	return Store.DeletePlaylistShare(s)
+}
+
+func (s *PlaylistShare) Find(filter PlaylistShareFilter) ([]*PlaylistShare, error) {
+	return Store.FindPlaylistShares(filter)
+}
+
+func (g *Group) Save() error {
+	return Store.SaveGroup(g)
+}
+
+func (g *Group) Delete() error {
+	return Store.DeleteGroup(g)
+}
+
+func (g *Group) Find(filter GroupFilter) ([]*Group, error) {
+	return Store.FindGroups(filter)
+}
+
+func (l *ExternalLogin) Insert() (*uint64, error) {
+	return Store.InsertExternalLogin(l)
+}
+
+func (l *ExternalLogin) Delete() error {
+	return Store.DeleteExternalLogin(l)
+}
+
+func (l *ExternalLogin) Find(filter ExternalLoginFilter) ([]*ExternalLogin, error) {
+	return Store.FindExternalLogins(filter)
+}
+
+func (k *ApiKey) Insert() (*uint64, error) {
+	return Store.InsertApiKey(k)
+}
+
+func (k *ApiKey) Update() error {
+	return Store.UpdateApiKey(k)
+}
+
+func (k *ApiKey) Delete() error {
+	return Store.DeleteApiKey(k)
+}
+
+func (k *ApiKey) Find(filter ApiKeyFilter) ([]*ApiKey, error) {
+	return Store.FindApiKeys(filter)
+}
+
+func UserAddDevice(m *User, d *Device) (*uint64, error) {
+	return Store.UserAddDevice(m, d)
+}
+
+func (d *Device) Find(filter DeviceFilter) ([]*Device, error) {
+	return Store.FindDevices(filter)
+}
+
+// Select loads the device by Id or Uuid
+func (d *Device) Select() error {
+	return Store.SelectDevice(d)
+}
+
+func (d *Device) Update() error {
+	return Store.UpdateDevice(d)
+}
+
+func (d *Device) UpdateDetails() error {
+	return Store.UpdateDeviceDetails(d)
+}
+
+func (d *Device) RotateRefreshToken(expectedHash string) error {
+	return Store.RotateDeviceRefreshToken(d, expectedHash)
+}
+
+func (d *Device) Delete() error {
+	return Store.DeleteDevice(d)
+}
+
+func (r *ResumePosition) Save() error {
+	return Store.SaveResumePosition(r)
+}
+
+func (r *ResumePosition) Find(filter ResumePositionFilter) ([]*ResumePosition, error) {
+	return Store.FindResumePositions(filter)
+}
+
+func (p *Play) Insert() (*uint64, error) {
+	return Store.AddPlay(p)
+}
+
+func (p *Play) Find(filter PlayFilter) ([]*Play, error) {
+	return Store.FindPlays(filter)
+}
+
+func (t *RevokedToken) Insert() error {
+	return Store.RevokeToken(t)
+}
+
+func IsTokenRevoked(tokenId string) (bool, error) {
+	return Store.IsTokenRevoked(tokenId)
+}
+
+func PruneRevokedTokens(before int64) (int, error) {
+	return Store.PruneRevokedTokens(before)
 }
 
 func DeepCopy(src, dest interface{}) {
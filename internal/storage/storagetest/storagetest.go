@@ -0,0 +1,1302 @@
+// Package storagetest holds the tests every storage driver has to pass, each
+// driver runs them from its own test file with TestDataStorage.
+package storagetest
+
+import (
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"testing"
+)
+
+// Open returns a new, empty DataStorage for a single test
+type Open func(t *testing.T) storage.DataStorage
+
+func TestDataStorage(t *testing.T, open Open) {
+	t.Run("Users", func(t *testing.T) { testUsers(t, open) })
+	t.Run("Playlists", func(t *testing.T) { testPlaylists(t, open) })
+	t.Run("Friends", func(t *testing.T) { testFriends(t, open) })
+	t.Run("Shares", func(t *testing.T) { testShares(t, open) })
+	t.Run("Groups", func(t *testing.T) { testGroups(t, open) })
+	t.Run("External logins", func(t *testing.T) { testExternalLogins(t, open) })
+	t.Run("API keys", func(t *testing.T) { testApiKeys(t, open) })
+	t.Run("Devices", func(t *testing.T) { testDevices(t, open) })
+	t.Run("Listening", func(t *testing.T) { testListening(t, open) })
+	t.Run("Revoked tokens", func(t *testing.T) { testRevokedTokens(t, open) })
+}
+
+func testUsers(t *testing.T, open Open) {
+	t.Run("Insert and select a user by id, uuid and email", func(t *testing.T) {
+		s := open(t)
+		user := &storage.User{FirstName: "Test", EmailAddress: "test@test.com"}
+		id, err := s.InsertUser(user)
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if *id != 1 || user.Uuid == "" {
+			t.Errorf("Want id 1 and a uuid, got id '%d' uuid '%s'", *id, user.Uuid)
+		}
+
+		for _, search := range []*storage.User{{Id: 1}, {Uuid: user.Uuid}, {EmailAddress: "TEST@test.com"}} {
+			err := s.SelectUser(search)
+			if err != nil {
+				t.Errorf("Want no error, got '%s'", err.Error())
+			}
+			if search.FirstName != "Test" {
+				t.Errorf("Want FirstName 'Test', got '%s'", search.FirstName)
+			}
+		}
+	})
+	t.Run("Reject a duplicate email address", func(t *testing.T) {
+		s := open(t)
+		s.InsertUser(&storage.User{EmailAddress: "test@test.com"})
+		_, err := s.InsertUser(&storage.User{EmailAddress: "test@test.com"})
+		if err != storage.ErrUserExists {
+			t.Errorf("Want error '%v', got '%v'", storage.ErrUserExists, err)
+		}
+	})
+	t.Run("Users inserted after the admin get a new id", func(t *testing.T) {
+		s := open(t)
+		s.InsertUser(&storage.User{Id: 1, EmailAddress: "admin@test.com", Roles: []string{storage.RoleAdmin}})
+		id, err := s.InsertUser(&storage.User{EmailAddress: "user@test.com"})
+		if err != nil || *id == 1 {
+			t.Fatalf("Want a new id without an error, got '%v' '%v'", id, err)
+		}
+		admin := &storage.User{Id: 1}
+		if err := s.SelectUser(admin); err != nil || !admin.HasRole(storage.RoleAdmin) {
+			t.Errorf("Want the admin user kept, got '%+v' '%v'", admin, err)
+		}
+	})
+	t.Run("A verified email address is kept", func(t *testing.T) {
+		s := open(t)
+		user := &storage.User{EmailAddress: "test@test.com"}
+		s.InsertUser(user)
+		user.EmailVerified = true
+		if err := s.UpdateUser(user); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		search := &storage.User{Id: user.Id}
+		s.SelectUser(search)
+		if !search.EmailVerified {
+			t.Error("Want the email address verified")
+		}
+	})
+	t.Run("Two factor authentication is kept", func(t *testing.T) {
+		s := open(t)
+		user := &storage.User{EmailAddress: "test@test.com"}
+		s.InsertUser(user)
+		user.TotpSecret = "JBSWY3DPEHPK3PXP"
+		user.TotpEnabled = true
+		user.TotpLastStep = 12345
+		user.TotpRecoveryCodes = []string{"hash-1", "hash-2"}
+		if err := s.UpdateUser(user); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		search := &storage.User{Id: user.Id}
+		s.SelectUser(search)
+		if search.TotpSecret != "JBSWY3DPEHPK3PXP" || !search.TotpEnabled || search.TotpLastStep != 12345 ||
+			len(search.TotpRecoveryCodes) != 2 || search.TotpRecoveryCodes[1] != "hash-2" {
+			t.Errorf("Want the two factor settings kept, got '%+v'", search)
+		}
+		search.TotpRecoveryCodes = nil
+		s.UpdateUser(search)
+		search = &storage.User{Id: user.Id}
+		s.SelectUser(search)
+		if len(search.TotpRecoveryCodes) != 0 {
+			t.Errorf("Want no recovery codes, got '%v'", search.TotpRecoveryCodes)
+		}
+	})
+	t.Run("A two factor code is only used once", func(t *testing.T) {
+		s := open(t)
+		user := &storage.User{EmailAddress: "test@test.com"}
+		s.InsertUser(user)
+		user.TotpLastStep = 100
+		user.TotpRecoveryCodes = []string{"hash-1", "hash-2"}
+		s.UpdateUser(user)
+
+		// Two requests both checked step 101 against step 100
+		first := &storage.User{Id: user.Id, TotpLastStep: 101}
+		if err := s.UseUserTotpStep(first, 100); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		second := &storage.User{Id: user.Id, TotpLastStep: 101}
+		if err := s.UseUserTotpStep(second, 100); err != storage.ErrSecondFactorUsed {
+			t.Errorf("Want error '%v', got '%v'", storage.ErrSecondFactorUsed, err)
+		}
+
+		if err := s.UseUserRecoveryCode(first, "hash-1"); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if len(first.TotpRecoveryCodes) != 1 || first.TotpRecoveryCodes[0] != "hash-2" {
+			t.Errorf("Want the other recovery code left, got '%v'", first.TotpRecoveryCodes)
+		}
+		if err := s.UseUserRecoveryCode(second, "hash-1"); err != storage.ErrSecondFactorUsed {
+			t.Errorf("Want error '%v', got '%v'", storage.ErrSecondFactorUsed, err)
+		}
+
+		search := &storage.User{Id: user.Id}
+		s.SelectUser(search)
+		if search.EmailAddress != "test@test.com" || search.TotpLastStep != 101 ||
+			len(search.TotpRecoveryCodes) != 1 || search.TotpRecoveryCodes[0] != "hash-2" {
+			t.Errorf("Want just the step and the used code stored, got '%+v'", search)
+		}
+		if err := s.UseUserTotpStep(&storage.User{Id: 99}, 0); err != storage.ErrUserNotFound {
+			t.Errorf("Want error '%v', got '%v'", storage.ErrUserNotFound, err)
+		}
+	})
+	t.Run("Updating a stale user leaves what it owns alone", func(t *testing.T) {
+		s := open(t)
+		user := &storage.User{EmailAddress: "test@test.com"}
+		s.InsertUser(user)
+		playlist := &storage.Playlist{Name: "Test"}
+		s.UserAddPlaylist(user, playlist)
+		device := &storage.Device{Name: "Phone", LastTokenId: "token-1"}
+		s.UserAddDevice(user, device)
+		stale := &storage.User{Id: user.Id}
+		s.SelectUser(stale)
+
+		playlist.LockDeviceUuid = device.Uuid
+		s.UpdatePlaylistLock(playlist, "")
+		device.LastTokenId = "token-2"
+		s.UpdateDevice(device)
+		s.UserAddTrack(user, &storage.Track{SongName: "Song1"})
+		stale.FirstName = "Stale"
+		if err := s.UpdateUser(stale); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		s.UserAddFriend(stale, &storage.Friend{FriendId: "friend", Status: storage.FriendAccepted})
+
+		loaded := &storage.User{Id: user.Id}
+		s.SelectUser(loaded)
+		if loaded.FirstName != "Stale" || len(loaded.Tracks) != 1 || len(loaded.Friends) != 1 {
+			t.Errorf("Want the user updated with its track and friend, got '%+v'", loaded)
+		}
+		if len(loaded.Playlists) != 1 || loaded.Playlists[0].LockDeviceUuid != device.Uuid {
+			t.Errorf("Want the lock kept, got '%+v'", loaded.Playlists)
+		}
+		if len(loaded.Devices) != 1 || loaded.Devices[0].LastTokenId != "token-2" {
+			t.Errorf("Want the newest token kept, got '%+v'", loaded.Devices)
+		}
+	})
+	t.Run("Select a user that does not exist", func(t *testing.T) {
+		s := open(t)
+		err := s.SelectUser(&storage.User{Id: 5})
+		if err != storage.ErrUserNotFound {
+			t.Errorf("Want error '%v', got '%v'", storage.ErrUserNotFound, err)
+		}
+		exists, err := s.UserExists(&storage.User{Id: 5})
+		if exists || err != nil {
+			t.Errorf("Want user to not exist without an error, got '%v' '%v'", exists, err)
+		}
+	})
+	t.Run("Users are found by role", func(t *testing.T) {
+		s := open(t)
+		s.InsertUser(&storage.User{EmailAddress: "admin@test.com", Roles: []string{storage.RoleMember, storage.RoleAdmin}})
+		s.InsertUser(&storage.User{EmailAddress: "user@test.com", Roles: []string{storage.RoleMember}})
+		s.InsertUser(&storage.User{EmailAddress: "other@test.com", Roles: []string{"administrator"}})
+		users, err := s.FindUsers(storage.UserFilter{Role: storage.RoleAdmin})
+		if err != nil || len(users) != 1 || users[0].EmailAddress != "admin@test.com" {
+			t.Fatalf("Want only admin@test.com without an error, got %d users '%v'", len(users), err)
+		}
+		if len(users[0].Roles) != 2 || users[0].Roles[1] != storage.RoleAdmin {
+			t.Errorf("Want the roles kept in order, got '%v'", users[0].Roles)
+		}
+		users, _ = s.FindUsers(storage.UserFilter{Role: storage.RoleMember})
+		if len(users) != 2 {
+			t.Errorf("Want 2 members, got %d", len(users))
+		}
+	})
+	t.Run("Users are found by the playlist they own", func(t *testing.T) {
+		s := open(t)
+		owner := &storage.User{EmailAddress: "owner@test.com"}
+		s.InsertUser(owner)
+		s.InsertUser(&storage.User{EmailAddress: "other@test.com"})
+		playlist := &storage.Playlist{Name: "Mine"}
+		s.UserAddPlaylist(owner, playlist)
+
+		users, err := s.FindUsers(storage.UserFilter{PlaylistUuid: playlist.Uuid})
+		if err != nil || len(users) != 1 || users[0].EmailAddress != "owner@test.com" {
+			t.Fatalf("Want only owner@test.com without an error, got %d users '%v'", len(users), err)
+		}
+		users, _ = s.FindUsers(storage.UserFilter{PlaylistUuid: "missing"})
+		if len(users) != 0 {
+			t.Errorf("Want no owner of a missing playlist, got %d", len(users))
+		}
+	})
+	t.Run("Delete a user", func(t *testing.T) {
+		s := open(t)
+		user := &storage.User{EmailAddress: "test@test.com"}
+		s.InsertUser(user)
+		if err := s.DeleteUser(user); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		users, _ := s.FindUsers(storage.UserFilter{})
+		if len(users) != 0 {
+			t.Errorf("Want 0 users, got %d", len(users))
+		}
+	})
+}
+
+func testPlaylists(t *testing.T, open Open) {
+	t.Run("Playlists and tracks are related to their owner", func(t *testing.T) {
+		s := open(t)
+		owner := &storage.User{EmailAddress: "owner@test.com"}
+		other := &storage.User{EmailAddress: "other@test.com"}
+		s.InsertUser(owner)
+		s.InsertUser(other)
+
+		playlist := &storage.Playlist{Name: "Test"}
+		if _, err := s.UserAddPlaylist(owner, playlist); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		track := &storage.Track{SongName: "Song1"}
+		if _, err := s.PlaylistAddTrack(playlist, track); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+
+		playlists, _ := s.FindPlaylists(storage.PlaylistFilter{Uuid: playlist.Uuid, OwnerEmail: "owner@test.com"})
+		if len(playlists) != 1 || len(playlists[0].Tracks) != 1 {
+			t.Fatalf("Want 1 playlist with 1 track, got %d", len(playlists))
+		}
+		playlists, _ = s.FindPlaylists(storage.PlaylistFilter{Uuid: playlist.Uuid, OwnerEmail: "other@test.com"})
+		if len(playlists) != 0 {
+			t.Errorf("Want 0 playlists for another user, got %d", len(playlists))
+		}
+
+		tracks, _ := s.FindTracks(storage.TrackFilter{Uuid: track.Uuid, OwnerEmail: "owner@test.com"})
+		if len(tracks) != 1 {
+			t.Errorf("Want 1 track, got %d", len(tracks))
+		}
+		tracks, _ = s.FindTracks(storage.TrackFilter{Uuid: track.Uuid, OwnerEmail: "other@test.com"})
+		if len(tracks) != 0 {
+			t.Errorf("Want 0 tracks for another user, got %d", len(tracks))
+		}
+	})
+	t.Run("Update and delete a playlist", func(t *testing.T) {
+		s := open(t)
+		owner := &storage.User{EmailAddress: "owner@test.com"}
+		s.InsertUser(owner)
+		playlist := &storage.Playlist{Name: "Test"}
+		s.UserAddPlaylist(owner, playlist)
+
+		playlist.Elapsed = 42
+		if err := s.UpdatePlaylist(playlist); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		loaded := &storage.Playlist{Uuid: playlist.Uuid}
+		s.SelectPlaylist(loaded)
+		if loaded.Elapsed != 42 {
+			t.Errorf("Want Elapsed 42, got %d", loaded.Elapsed)
+		}
+
+		if err := s.DeletePlaylist(playlist); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		s.SelectUser(owner)
+		if len(owner.Playlists) != 0 {
+			t.Errorf("Want 0 playlists, got %d", len(owner.Playlists))
+		}
+	})
+	t.Run("Update at revision only stores an unchanged revision", func(t *testing.T) {
+		s := open(t)
+		owner := &storage.User{EmailAddress: "owner@test.com"}
+		s.InsertUser(owner)
+		playlist := &storage.Playlist{Name: "Test"}
+		s.UserAddPlaylist(owner, playlist)
+
+		playlist.Elapsed = 10
+		playlist.PositionRevision = 1
+		if err := s.UpdatePlaylistAtRevision(playlist, 0); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		playlist.Elapsed = 20
+		playlist.PositionRevision = 1
+		if err := s.UpdatePlaylistAtRevision(playlist, 0); err != storage.ErrRevisionConflict {
+			t.Fatalf("Want error '%v', got '%v'", storage.ErrRevisionConflict, err)
+		}
+		loaded := &storage.Playlist{Id: playlist.Id}
+		s.SelectPlaylist(loaded)
+		if loaded.Elapsed != 10 || loaded.PositionRevision != 1 {
+			t.Errorf("Want elapsed 10 at revision 1, got %d at %d", loaded.Elapsed, loaded.PositionRevision)
+		}
+	})
+	t.Run("Update at revision leaves the tracks alone", func(t *testing.T) {
+		s := open(t)
+		owner := &storage.User{EmailAddress: "owner@test.com"}
+		s.InsertUser(owner)
+		playlist := &storage.Playlist{Name: "Test"}
+		s.UserAddPlaylist(owner, playlist)
+		first := &storage.Track{SongName: "old"}
+		second := &storage.Track{SongName: "Song2"}
+		s.PlaylistAddTrack(playlist, first)
+		s.PlaylistAddTrack(playlist, second)
+		stale := &storage.Playlist{Id: playlist.Id}
+		s.SelectPlaylist(stale)
+
+		first.SongName = "new"
+		s.UpdateTrack(first)
+		s.ReorderPlaylistTracks(playlist, []string{second.Uuid, first.Uuid})
+		added := &storage.Track{SongName: "Song3"}
+		s.PlaylistAddTrack(playlist, added)
+		stale.Elapsed = 10
+		stale.PositionRevision = 1
+		if err := s.UpdatePlaylistAtRevision(stale, 0); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if len(stale.Tracks) != 3 {
+			t.Errorf("Want the stored tracks back, got %d", len(stale.Tracks))
+		}
+		loaded := &storage.Playlist{Id: playlist.Id}
+		s.SelectPlaylist(loaded)
+		if loaded.Elapsed != 10 || len(loaded.Tracks) != 3 {
+			t.Fatalf("Want elapsed 10 with 3 tracks, got %d with %d", loaded.Elapsed, len(loaded.Tracks))
+		}
+		if loaded.Tracks[0].Uuid != second.Uuid || loaded.Tracks[1].SongName != "new" || loaded.Tracks[2].Uuid != added.Uuid {
+			t.Errorf("Want the new order with the edited track, got '%s' '%s' '%s'",
+				loaded.Tracks[0].SongName, loaded.Tracks[1].SongName, loaded.Tracks[2].SongName)
+		}
+	})
+	t.Run("Adding a track leaves the rest of the playlist alone", func(t *testing.T) {
+		s := open(t)
+		owner := &storage.User{EmailAddress: "owner@test.com"}
+		s.InsertUser(owner)
+		playlist := &storage.Playlist{Name: "Test"}
+		s.UserAddPlaylist(owner, playlist)
+		first := &storage.Track{SongName: "Song1"}
+		s.PlaylistAddTrack(playlist, first)
+		stale := &storage.Playlist{Id: playlist.Id}
+		s.SelectPlaylist(stale)
+
+		playlist.Elapsed = 10
+		playlist.PositionRevision = 1
+		playlist.CurrentTrackUuid = first.Uuid
+		if err := s.UpdatePlaylistAtRevision(playlist, 0); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		playlist.LockDeviceUuid = "device-1"
+		if err := s.UpdatePlaylistLock(playlist, ""); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		added := &storage.Track{SongName: "Song2"}
+		if _, err := s.PlaylistAddTrack(stale, added); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if stale.Elapsed != 10 || len(stale.Tracks) != 2 {
+			t.Errorf("Want the stored playlist back, got elapsed %d with %d tracks", stale.Elapsed, len(stale.Tracks))
+		}
+		loaded := &storage.Playlist{Id: playlist.Id}
+		s.SelectPlaylist(loaded)
+		if loaded.Elapsed != 10 || loaded.PositionRevision != 1 || loaded.CurrentTrackUuid != first.Uuid {
+			t.Errorf("Want elapsed 10 at revision 1, got %d at %d", loaded.Elapsed, loaded.PositionRevision)
+		}
+		if loaded.LockDeviceUuid != "device-1" {
+			t.Errorf("Want the lock held by device-1, got '%s'", loaded.LockDeviceUuid)
+		}
+		if len(loaded.Tracks) != 2 || loaded.Tracks[1].Uuid != added.Uuid {
+			t.Fatalf("Want the new track last of 2, got %d tracks", len(loaded.Tracks))
+		}
+	})
+	t.Run("Adding a track to a missing playlist fails", func(t *testing.T) {
+		s := open(t)
+		missing := &storage.Playlist{Id: 99}
+		if _, err := s.PlaylistAddTrack(missing, &storage.Track{SongName: "Song1"}); err != storage.ErrPlaylistNotFound {
+			t.Errorf("Want error '%v', got '%v'", storage.ErrPlaylistNotFound, err)
+		}
+	})
+	t.Run("Find playlists by the device holding the lock", func(t *testing.T) {
+		s := open(t)
+		owner := &storage.User{EmailAddress: "owner@test.com"}
+		s.InsertUser(owner)
+		locked := &storage.Playlist{Name: "Locked"}
+		s.UserAddPlaylist(owner, locked)
+		s.UserAddPlaylist(owner, &storage.Playlist{Name: "Free"})
+		locked.LockDeviceUuid = "device-1"
+		locked.ClientLockExpires = 100
+		s.UpdatePlaylistLock(locked, "")
+
+		playlists, err := s.FindPlaylists(storage.PlaylistFilter{LockDeviceUuid: "device-1"})
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if len(playlists) != 1 || playlists[0].Uuid != locked.Uuid {
+			t.Fatalf("Want only the locked playlist, got '%+v'", playlists)
+		}
+		playlists, _ = s.FindPlaylists(storage.PlaylistFilter{OwnerEmail: owner.EmailAddress, LockDeviceUuid: "device-2"})
+		if len(playlists) != 0 {
+			t.Errorf("Want no playlists locked by another device, got '%+v'", playlists)
+		}
+	})
+	t.Run("Update lock only replaces the expected holder", func(t *testing.T) {
+		s := open(t)
+		owner := &storage.User{EmailAddress: "owner@test.com"}
+		s.InsertUser(owner)
+		playlist := &storage.Playlist{Name: "Test"}
+		s.UserAddPlaylist(owner, playlist)
+
+		playlist.LockDeviceUuid = "device-1"
+		playlist.ClientLockExpires = 100
+		if err := s.UpdatePlaylistLock(playlist, ""); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		playlist.LockDeviceUuid = "device-2"
+		if err := s.UpdatePlaylistLock(playlist, ""); err != storage.ErrLockConflict {
+			t.Fatalf("Want error '%v', got '%v'", storage.ErrLockConflict, err)
+		}
+
+		// Position updates leave the stored lock alone
+		stale := &storage.Playlist{Id: playlist.Id}
+		s.SelectPlaylist(stale)
+		stale.LockDeviceUuid = ""
+		stale.Elapsed = 10
+		stale.PositionRevision = 1
+		if err := s.UpdatePlaylistAtRevision(stale, 0); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		loaded := &storage.Playlist{Id: playlist.Id}
+		s.SelectPlaylist(loaded)
+		if loaded.LockDeviceUuid != "device-1" || loaded.ClientLockExpires != 100 || loaded.Elapsed != 10 {
+			t.Errorf("Want lock held by device-1 at elapsed 10, got '%s' at %d", loaded.LockDeviceUuid, loaded.Elapsed)
+		}
+	})
+	t.Run("Tracks keep their playlist order", func(t *testing.T) {
+		s := open(t)
+		owner := &storage.User{EmailAddress: "owner@test.com"}
+		s.InsertUser(owner)
+		playlist := &storage.Playlist{Name: "Test"}
+		s.UserAddPlaylist(owner, playlist)
+		var uuids []string
+		for _, name := range []string{"Song1", "Song2", "Song3"} {
+			track := &storage.Track{SongName: name}
+			s.PlaylistAddTrack(playlist, track)
+			uuids = append(uuids, track.Uuid)
+		}
+		playlist.CurrentTrackUuid = uuids[1]
+		s.UpdatePlaylist(playlist)
+
+		reordered := []string{uuids[2], uuids[0], uuids[1]}
+		if err := s.ReorderPlaylistTracks(playlist, reordered); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		loaded := &storage.Playlist{Id: playlist.Id}
+		s.SelectPlaylist(loaded)
+		if len(loaded.Tracks) != 3 {
+			t.Fatalf("Want 3 tracks, got %d", len(loaded.Tracks))
+		}
+		for i, uuid := range reordered {
+			if loaded.Tracks[i].Uuid != uuid {
+				t.Errorf("Want track %d to be '%s', got '%s'", i+1, uuid, loaded.Tracks[i].Uuid)
+			}
+		}
+		if loaded.CurrentTrackUuid != uuids[1] {
+			t.Errorf("Want current track '%s', got '%s'", uuids[1], loaded.CurrentTrackUuid)
+		}
+
+		// Adding a track appends it to the reordered playlist
+		track := &storage.Track{SongName: "Song4"}
+		s.PlaylistAddTrack(loaded, track)
+		s.SelectPlaylist(loaded)
+		if len(loaded.Tracks) != 4 || loaded.Tracks[0].Uuid != uuids[2] || loaded.Tracks[3].Uuid != track.Uuid {
+			t.Errorf("Want the new track last, got %d tracks", len(loaded.Tracks))
+		}
+
+		if err := s.ReorderPlaylistTracks(playlist, reordered); err != storage.ErrTrackOrder {
+			t.Errorf("Want error '%v' for a missing track, got '%v'", storage.ErrTrackOrder, err)
+		}
+		duplicated := []string{uuids[0], uuids[0], uuids[1], uuids[2]}
+		if err := s.ReorderPlaylistTracks(playlist, duplicated); err != storage.ErrTrackOrder {
+			t.Errorf("Want error '%v' for a duplicated track, got '%v'", storage.ErrTrackOrder, err)
+		}
+	})
+	t.Run("A library track is shared by playlists", func(t *testing.T) {
+		s := open(t)
+		owner := &storage.User{EmailAddress: "owner@test.com"}
+		s.InsertUser(owner)
+		track := &storage.Track{Path: "/music/Album/01.mp3", SongName: "Song1", ArtistName: "Barry", ContentHash: "abc123"}
+		if _, err := s.UserAddTrack(owner, track); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if track.Id == 0 || track.Uuid == "" {
+			t.Fatalf("Want track id and uuid to be set, got '%+v'", track)
+		}
+		first := &storage.Playlist{Name: "First"}
+		second := &storage.Playlist{Name: "Second"}
+		s.UserAddPlaylist(owner, first)
+		s.UserAddPlaylist(owner, second)
+		uuid := track.Uuid
+		for _, p := range []*storage.Playlist{first, second} {
+			shared := &storage.Track{Id: track.Id}
+			s.SelectTrack(shared)
+			if _, err := s.PlaylistAddTrack(p, shared); err != nil {
+				t.Fatalf("Want no error, got '%s'", err.Error())
+			}
+			if shared.Uuid != uuid {
+				t.Errorf("Want the library track uuid '%s' kept, got '%s'", uuid, shared.Uuid)
+			}
+		}
+
+		track.SongName = "Renamed"
+		s.UpdateTrack(track)
+		for _, p := range []*storage.Playlist{first, second} {
+			s.SelectPlaylist(p)
+			if len(p.Tracks) != 1 || p.Tracks[0].SongName != "Renamed" {
+				t.Errorf("Want the renamed track in '%s', got %d tracks", p.Name, len(p.Tracks))
+			}
+		}
+		tracks, _ := s.FindTracks(storage.TrackFilter{OwnerEmail: "owner@test.com"})
+		if len(tracks) != 1 {
+			t.Errorf("Want 1 track in the library, got %d", len(tracks))
+		}
+
+		if err := s.PlaylistRemoveTrack(first, track); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if len(first.Tracks) != 0 {
+			t.Errorf("Want 0 tracks left in the playlist, got %d", len(first.Tracks))
+		}
+		if err := s.PlaylistRemoveTrack(first, track); err != storage.ErrTrackNotFound {
+			t.Errorf("Want error '%v', got '%v'", storage.ErrTrackNotFound, err)
+		}
+		s.SelectPlaylist(second)
+		tracks, _ = s.FindTracks(storage.TrackFilter{OwnerEmail: "owner@test.com"})
+		if len(second.Tracks) != 1 || len(tracks) != 1 {
+			t.Errorf("Want the track kept in the other playlist and the library")
+		}
+	})
+	t.Run("Find playlists by a track they hold", func(t *testing.T) {
+		s := open(t)
+		owner := &storage.User{EmailAddress: "owner@test.com"}
+		s.InsertUser(owner)
+		track := &storage.Track{Path: "/music/Album/01.mp3", SongName: "Song1"}
+		s.UserAddTrack(owner, track)
+		first := &storage.Playlist{Name: "First"}
+		second := &storage.Playlist{Name: "Second"}
+		other := &storage.Playlist{Name: "Other"}
+		s.UserAddPlaylist(owner, first)
+		s.UserAddPlaylist(owner, second)
+		s.UserAddPlaylist(owner, other)
+		s.PlaylistAddTrack(first, &storage.Track{Id: track.Id, Uuid: track.Uuid})
+		s.PlaylistAddTrack(second, &storage.Track{Id: track.Id, Uuid: track.Uuid})
+		s.PlaylistAddTrack(other, &storage.Track{Path: "/music/Album/02.mp3", SongName: "Song2"})
+
+		playlists, err := s.FindPlaylists(storage.PlaylistFilter{TrackUuid: track.Uuid})
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if len(playlists) != 2 || playlists[0].Uuid != first.Uuid || playlists[1].Uuid != second.Uuid {
+			t.Errorf("Want the 2 playlists holding the track, got %d", len(playlists))
+		}
+		playlists, _ = s.FindPlaylists(storage.PlaylistFilter{OwnerEmail: owner.EmailAddress, TrackUuid: track.Uuid})
+		if len(playlists) != 2 {
+			t.Errorf("Want the owner's 2 playlists holding the track, got %d", len(playlists))
+		}
+		playlists, _ = s.FindPlaylists(storage.PlaylistFilter{TrackUuid: "missing"})
+		if len(playlists) != 0 {
+			t.Errorf("Want no playlists for a missing track, got %d", len(playlists))
+		}
+	})
+	t.Run("Find tracks by path, hash and search", func(t *testing.T) {
+		s := open(t)
+		owner := &storage.User{EmailAddress: "owner@test.com"}
+		s.InsertUser(owner)
+		s.UserAddTrack(owner, &storage.Track{Path: "/music/Album/01.mp3", SongName: "Song One", ContentHash: "abc123"})
+		s.UserAddTrack(owner, &storage.Track{Path: "/music/Album/02.mp3", SongName: "Song Two", ArtistName: "Barry"})
+		s.UserAddTrack(owner, &storage.Track{Path: "/music/100%_pure.mp3", SongName: "Other"})
+
+		tests := []struct {
+			filter storage.TrackFilter
+			want   int
+		}{
+			{storage.TrackFilter{OwnerEmail: "owner@test.com", Path: "/music/Album/02.mp3"}, 1},
+			{storage.TrackFilter{OwnerEmail: "owner@test.com", ContentHash: "abc123"}, 1},
+			{storage.TrackFilter{OwnerEmail: "owner@test.com", Search: "song"}, 2},
+			{storage.TrackFilter{OwnerEmail: "owner@test.com", Search: "BARRY"}, 1},
+			{storage.TrackFilter{OwnerEmail: "owner@test.com", Search: "album/0"}, 2},
+			{storage.TrackFilter{OwnerEmail: "owner@test.com", Search: "0%_"}, 1},
+			{storage.TrackFilter{OwnerEmail: "other@test.com", Search: "song"}, 0},
+			{storage.TrackFilter{Search: "two"}, 1},
+		}
+		for _, test := range tests {
+			tracks, err := s.FindTracks(test.filter)
+			if err != nil {
+				t.Fatalf("Want no error, got '%s'", err.Error())
+			}
+			if len(tracks) != test.want {
+				t.Errorf("Want %d tracks for '%+v', got %d", test.want, test.filter, len(tracks))
+			}
+		}
+	})
+	t.Run("Deleting a track removes it from its playlist", func(t *testing.T) {
+		s := open(t)
+		owner := &storage.User{EmailAddress: "owner@test.com"}
+		s.InsertUser(owner)
+		playlist := &storage.Playlist{Name: "Test"}
+		s.UserAddPlaylist(owner, playlist)
+		track := &storage.Track{SongName: "Song1"}
+		s.PlaylistAddTrack(playlist, track)
+
+		if err := s.DeleteTrack(track); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		s.SelectPlaylist(playlist)
+		if len(playlist.Tracks) != 0 {
+			t.Errorf("Want 0 tracks, got %d", len(playlist.Tracks))
+		}
+	})
+}
+
+func testFriends(t *testing.T, open Open) {
+	t.Run("Add and remove a friend", func(t *testing.T) {
+		s := open(t)
+		user := &storage.User{EmailAddress: "test@test.com"}
+		s.InsertUser(user)
+		friend := &storage.Friend{FriendId: "48cf9b84-6162-430a-92ac-6804146ad2a4"}
+		if _, err := s.UserAddFriend(user, friend); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		s.SelectUser(user)
+		if len(user.Friends) != 1 {
+			t.Fatalf("Want 1 friend, got %d", len(user.Friends))
+		}
+		if err := s.DeleteFriend(friend); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		s.SelectUser(user)
+		if len(user.Friends) != 0 {
+			t.Errorf("Want 0 friends, got %d", len(user.Friends))
+		}
+	})
+	t.Run("Accept a friend request", func(t *testing.T) {
+		s := open(t)
+		user := &storage.User{EmailAddress: "test@test.com"}
+		s.InsertUser(user)
+		friend := &storage.Friend{FriendId: "48cf9b84-6162-430a-92ac-6804146ad2a4", Status: storage.FriendRequestReceived, UpdatedAt: 100}
+		s.UserAddFriend(user, friend)
+
+		friend.Status = storage.FriendAccepted
+		friend.UpdatedAt = 200
+		if err := s.UpdateFriend(friend); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		s.SelectUser(user)
+		if len(user.Friends) != 1 || user.Friends[0].Status != storage.FriendAccepted || user.Friends[0].UpdatedAt != 200 {
+			t.Errorf("Want the accepted friend, got '%+v'", user.Friends)
+		}
+		if err := s.UpdateFriend(&storage.Friend{Id: 99, Status: storage.FriendAccepted}); err != storage.ErrFriendNotFound {
+			t.Errorf("Want '%v', got '%v'", storage.ErrFriendNotFound, err)
+		}
+	})
+}
+
+func testShares(t *testing.T, open Open) {
+	t.Run("There is one share per playlist and user", func(t *testing.T) {
+		s := open(t)
+		s.SavePlaylistShare(&storage.PlaylistShare{PlaylistUuid: "playlist-1", OwnerUuid: "owner", UserUuid: "user-1", Access: storage.ShareRead})
+		s.SavePlaylistShare(&storage.PlaylistShare{PlaylistUuid: "playlist-2", OwnerUuid: "owner", UserUuid: "user-1", Access: storage.ShareRead})
+		s.SavePlaylistShare(&storage.PlaylistShare{PlaylistUuid: "playlist-1", OwnerUuid: "owner", UserUuid: "user-2", Access: storage.ShareRead})
+		share := &storage.PlaylistShare{PlaylistUuid: "playlist-1", OwnerUuid: "owner", UserUuid: "user-1", Access: storage.ShareCollaborate}
+		if err := s.SavePlaylistShare(share); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if share.Id == 0 {
+			t.Error("Want the share id to be set")
+		}
+
+		shares, err := s.FindPlaylistShares(storage.PlaylistShareFilter{PlaylistUuid: "playlist-1"})
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if len(shares) != 2 || shares[0].UserUuid != "user-1" || shares[0].Access != storage.ShareCollaborate {
+			t.Errorf("Want 2 shares, the first replaced, got '%+v'", shares)
+		}
+		shares, _ = s.FindPlaylistShares(storage.PlaylistShareFilter{OwnerUuid: "owner", UserUuid: "user-1"})
+		if len(shares) != 2 {
+			t.Errorf("Want 2 shares with user-1, got %d", len(shares))
+		}
+
+		if err := s.DeletePlaylistShare(share); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if err := s.DeletePlaylistShare(share); err != storage.ErrShareNotFound {
+			t.Errorf("Want '%v', got '%v'", storage.ErrShareNotFound, err)
+		}
+		shares, _ = s.FindPlaylistShares(storage.PlaylistShareFilter{PlaylistUuid: "playlist-1"})
+		if len(shares) != 1 {
+			t.Errorf("Want 1 share left, got %d", len(shares))
+		}
+	})
+	t.Run("Deleting a playlist deletes its shares", func(t *testing.T) {
+		s := open(t)
+		user := &storage.User{EmailAddress: "test@test.com"}
+		s.InsertUser(user)
+		playlist := &storage.Playlist{Name: "Shared"}
+		s.UserAddPlaylist(user, playlist)
+		s.SavePlaylistShare(&storage.PlaylistShare{PlaylistUuid: playlist.Uuid, OwnerUuid: user.Uuid, UserUuid: "user-1", Access: storage.ShareRead})
+		s.SavePlaylistShare(&storage.PlaylistShare{PlaylistUuid: "playlist-2", OwnerUuid: user.Uuid, UserUuid: "user-1", Access: storage.ShareRead})
+
+		if err := s.DeletePlaylist(playlist); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		shares, _ := s.FindPlaylistShares(storage.PlaylistShareFilter{UserUuid: "user-1"})
+		if len(shares) != 1 || shares[0].PlaylistUuid != "playlist-2" {
+			t.Errorf("Want only the other playlist's share, got '%+v'", shares)
+		}
+	})
+}
+
+func testGroups(t *testing.T, open Open) {
+	t.Run("Groups keep their members", func(t *testing.T) {
+		s := open(t)
+		group := &storage.Group{Name: "Home", Members: []*storage.GroupMember{
+			{UserUuid: "parent", Manager: true},
+			{UserUuid: "child", Restrictions: []string{storage.RestrictDeletePlaylists, storage.RestrictEditPlaylists}},
+		}}
+		if err := s.SaveGroup(group); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if group.Id == 0 || group.Uuid == "" {
+			t.Fatalf("Want an id and a uuid, got '%+v'", group)
+		}
+
+		groups, err := s.FindGroups(storage.GroupFilter{MemberUuid: "child"})
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if len(groups) != 1 || groups[0].Name != "Home" || len(groups[0].Members) != 2 {
+			t.Fatalf("Want the group with 2 members, got '%+v'", groups)
+		}
+		child := groups[0].Member("child")
+		if child == nil || child.Manager || !child.Restricted(storage.RestrictEditPlaylists) || !groups[0].Member("parent").Manager {
+			t.Errorf("Want the members kept, got '%+v'", groups[0].Members)
+		}
+		groups, _ = s.FindGroups(storage.GroupFilter{MemberUuid: "stranger"})
+		if len(groups) != 0 {
+			t.Errorf("Want no group for someone else, got %d", len(groups))
+		}
+
+		group.Name = "House"
+		group.Members = group.Members[:1]
+		if err := s.SaveGroup(group); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		groups, _ = s.FindGroups(storage.GroupFilter{Uuid: group.Uuid})
+		if len(groups) != 1 || groups[0].Name != "House" || len(groups[0].Members) != 1 {
+			t.Errorf("Want the group replaced, got '%+v'", groups)
+		}
+	})
+	t.Run("A user is only in one group", func(t *testing.T) {
+		s := open(t)
+		s.SaveGroup(&storage.Group{Name: "First", Members: []*storage.GroupMember{{UserUuid: "user-1", Manager: true}}})
+		second := &storage.Group{Name: "Second", Members: []*storage.GroupMember{{UserUuid: "user-2", Manager: true}, {UserUuid: "user-1"}}}
+		if err := s.SaveGroup(second); err != storage.ErrAlreadyInGroup {
+			t.Errorf("Want '%v', got '%v'", storage.ErrAlreadyInGroup, err)
+		}
+		groups, _ := s.FindGroups(storage.GroupFilter{})
+		if len(groups) != 1 {
+			t.Errorf("Want only the first group, got %d", len(groups))
+		}
+	})
+	t.Run("Invites are kept apart from members", func(t *testing.T) {
+		s := open(t)
+		first := &storage.Group{Name: "First", Members: []*storage.GroupMember{{UserUuid: "parent-1", Manager: true}},
+			Invites: []*storage.GroupInvite{{UserUuid: "friend", InvitedBy: "parent-1", CreatedAt: 1000}}}
+		second := &storage.Group{Name: "Second", Members: []*storage.GroupMember{{UserUuid: "parent-2", Manager: true}},
+			Invites: []*storage.GroupInvite{{UserUuid: "friend", InvitedBy: "parent-2", CreatedAt: 2000}}}
+		for _, group := range []*storage.Group{first, second} {
+			if err := s.SaveGroup(group); err != nil {
+				t.Fatalf("Want no error, got '%s'", err.Error())
+			}
+		}
+
+		groups, err := s.FindGroups(storage.GroupFilter{InviteeUuid: "friend"})
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if len(groups) != 2 || groups[0].Uuid != first.Uuid || groups[1].Uuid != second.Uuid {
+			t.Fatalf("Want both groups inviting the user, got '%+v'", groups)
+		}
+		invite := groups[0].Invite("friend")
+		if invite == nil || invite.InvitedBy != "parent-1" || invite.CreatedAt != 1000 {
+			t.Errorf("Want the invite kept, got '%+v'", invite)
+		}
+		groups, _ = s.FindGroups(storage.GroupFilter{MemberUuid: "friend"})
+		if len(groups) != 0 {
+			t.Errorf("Want an invite not to count as membership, got %d groups", len(groups))
+		}
+
+		// Accepting moves the invite over to the members
+		first.Invites = nil
+		first.Members = append(first.Members, &storage.GroupMember{UserUuid: "friend"})
+		if err := s.SaveGroup(first); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		groups, _ = s.FindGroups(storage.GroupFilter{InviteeUuid: "friend"})
+		if len(groups) != 1 || groups[0].Uuid != second.Uuid {
+			t.Errorf("Want only the second invite left, got '%+v'", groups)
+		}
+		groups, _ = s.FindGroups(storage.GroupFilter{MemberUuid: "friend"})
+		if len(groups) != 1 || groups[0].Uuid != first.Uuid || len(groups[0].Invites) != 0 {
+			t.Errorf("Want the user in the first group, got '%+v'", groups)
+		}
+	})
+	t.Run("A stale group is not saved or deleted", func(t *testing.T) {
+		s := open(t)
+		group := &storage.Group{Name: "Home", Members: []*storage.GroupMember{
+			{UserUuid: "parent-1", Manager: true}, {UserUuid: "parent-2", Manager: true}}}
+		s.SaveGroup(group)
+		groups, _ := s.FindGroups(storage.GroupFilter{Uuid: group.Uuid})
+		first, second := groups[0], &storage.Group{}
+		storage.DeepCopy(first, second)
+
+		// Both managers step down from the same copy, only the first gets to
+		first.Member("parent-1").Manager = false
+		if err := s.SaveGroup(first); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if first.Revision != second.Revision+1 {
+			t.Errorf("Want the revision moved on from %d, got %d", second.Revision, first.Revision)
+		}
+		second.Member("parent-2").Manager = false
+		if err := s.SaveGroup(second); err != storage.ErrGroupConflict {
+			t.Fatalf("Want error '%v', got '%v'", storage.ErrGroupConflict, err)
+		}
+		if err := s.DeleteGroup(second); err != storage.ErrGroupConflict {
+			t.Fatalf("Want error '%v', got '%v'", storage.ErrGroupConflict, err)
+		}
+		groups, _ = s.FindGroups(storage.GroupFilter{Uuid: group.Uuid})
+		if len(groups) != 1 || groups[0].Revision != first.Revision ||
+			groups[0].Member("parent-1").Manager || !groups[0].Member("parent-2").Manager {
+			t.Errorf("Want only the first change stored, got '%+v'", groups)
+		}
+
+		// Saving again from the stored copy works
+		groups[0].Name = "House"
+		if err := s.SaveGroup(groups[0]); err != nil {
+			t.Errorf("Want no error, got '%s'", err.Error())
+		}
+	})
+	t.Run("Deleting a user moves their group's revision on", func(t *testing.T) {
+		s := open(t)
+		user := &storage.User{EmailAddress: "test@test.com"}
+		s.InsertUser(user)
+		group := &storage.Group{Name: "Home", Members: []*storage.GroupMember{{UserUuid: "parent", Manager: true}, {UserUuid: user.Uuid}}}
+		s.SaveGroup(group)
+
+		s.DeleteUser(user)
+		if err := s.SaveGroup(group); err != storage.ErrGroupConflict {
+			t.Errorf("Want error '%v' putting the deleted user back, got '%v'", storage.ErrGroupConflict, err)
+		}
+	})
+	t.Run("Deleting a group leaves its playlists with their owners", func(t *testing.T) {
+		s := open(t)
+		user := &storage.User{EmailAddress: "test@test.com"}
+		s.InsertUser(user)
+		group := &storage.Group{Name: "Home", Members: []*storage.GroupMember{{UserUuid: user.Uuid, Manager: true}}}
+		s.SaveGroup(group)
+		s.UserAddPlaylist(user, &storage.Playlist{Name: "Shared", GroupUuid: group.Uuid})
+		s.UserAddPlaylist(user, &storage.Playlist{Name: "Mine"})
+
+		playlists, _ := s.FindPlaylists(storage.PlaylistFilter{GroupUuid: group.Uuid})
+		if len(playlists) != 1 || playlists[0].Name != "Shared" {
+			t.Fatalf("Want the group's playlist, got '%+v'", playlists)
+		}
+		if err := s.DeleteGroup(group); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if err := s.DeleteGroup(group); err != storage.ErrGroupNotFound {
+			t.Errorf("Want '%v', got '%v'", storage.ErrGroupNotFound, err)
+		}
+		playlists, _ = s.FindPlaylists(storage.PlaylistFilter{OwnerEmail: user.EmailAddress})
+		if len(playlists) != 2 || playlists[0].GroupUuid != "" || playlists[1].GroupUuid != "" {
+			t.Errorf("Want both playlists kept outside the group, got '%+v'", playlists)
+		}
+	})
+	t.Run("Deleting a user takes them out of their group", func(t *testing.T) {
+		s := open(t)
+		user := &storage.User{EmailAddress: "test@test.com"}
+		s.InsertUser(user)
+		group := &storage.Group{Name: "Home", Members: []*storage.GroupMember{{UserUuid: "parent", Manager: true}, {UserUuid: user.Uuid}}}
+		s.SaveGroup(group)
+
+		if err := s.DeleteUser(user); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		groups, _ := s.FindGroups(storage.GroupFilter{Uuid: group.Uuid})
+		if len(groups) != 1 || len(groups[0].Members) != 1 || groups[0].Member(user.Uuid) != nil {
+			t.Errorf("Want only the parent left, got '%+v'", groups)
+		}
+	})
+	t.Run("Deleting a user drops their invites", func(t *testing.T) {
+		s := open(t)
+		user := &storage.User{EmailAddress: "test@test.com"}
+		s.InsertUser(user)
+		group := &storage.Group{Name: "Home", Members: []*storage.GroupMember{{UserUuid: "parent", Manager: true}},
+			Invites: []*storage.GroupInvite{{UserUuid: user.Uuid, InvitedBy: "parent"}}}
+		s.SaveGroup(group)
+
+		if err := s.DeleteUser(user); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		groups, _ := s.FindGroups(storage.GroupFilter{Uuid: group.Uuid})
+		if len(groups) != 1 || len(groups[0].Invites) != 0 {
+			t.Errorf("Want the invite gone, got '%+v'", groups)
+		}
+	})
+}
+
+func testExternalLogins(t *testing.T, open Open) {
+	t.Run("A subject is linked to one user", func(t *testing.T) {
+		s := open(t)
+		login := &storage.ExternalLogin{Provider: "stub", Subject: "subject-1", UserUuid: "user-1", EmailAddress: "test@test.com"}
+		id, err := s.InsertExternalLogin(login)
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if *id == 0 || login.Id != *id {
+			t.Errorf("Want the id set, got '%d'", login.Id)
+		}
+		s.InsertExternalLogin(&storage.ExternalLogin{Provider: "other", Subject: "subject-1", UserUuid: "user-1"})
+
+		for _, duplicate := range []*storage.ExternalLogin{
+			{Provider: "stub", Subject: "subject-1", UserUuid: "user-2"},
+			{Provider: "stub", Subject: "subject-2", UserUuid: "user-1"},
+		} {
+			if _, err := s.InsertExternalLogin(duplicate); err != storage.ErrLoginExists {
+				t.Errorf("Want '%v', got '%v'", storage.ErrLoginExists, err)
+			}
+		}
+
+		logins, _ := s.FindExternalLogins(storage.ExternalLoginFilter{Provider: "stub", Subject: "subject-1"})
+		if len(logins) != 1 || logins[0].UserUuid != "user-1" || logins[0].EmailAddress != "test@test.com" {
+			t.Fatalf("Want the login, got '%+v'", logins)
+		}
+		logins, _ = s.FindExternalLogins(storage.ExternalLoginFilter{UserUuid: "user-1"})
+		if len(logins) != 2 || logins[0].Provider != "stub" {
+			t.Errorf("Want both of the user's logins oldest first, got '%+v'", logins)
+		}
+
+		if err := s.DeleteExternalLogin(login); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if err := s.DeleteExternalLogin(login); err != storage.ErrLoginNotFound {
+			t.Errorf("Want '%v', got '%v'", storage.ErrLoginNotFound, err)
+		}
+	})
+	t.Run("Deleting a user unlinks their logins", func(t *testing.T) {
+		s := open(t)
+		user := &storage.User{EmailAddress: "test@test.com"}
+		s.InsertUser(user)
+		s.InsertExternalLogin(&storage.ExternalLogin{Provider: "stub", Subject: "subject-1", UserUuid: user.Uuid})
+		s.InsertExternalLogin(&storage.ExternalLogin{Provider: "stub", Subject: "subject-2", UserUuid: "user-2"})
+
+		s.DeleteUser(user)
+		logins, _ := s.FindExternalLogins(storage.ExternalLoginFilter{Provider: "stub"})
+		if len(logins) != 1 || logins[0].Subject != "subject-2" {
+			t.Errorf("Want only the other user's login, got '%+v'", logins)
+		}
+	})
+}
+
+func testApiKeys(t *testing.T, open Open) {
+	t.Run("Keys belong to their user", func(t *testing.T) {
+		s := open(t)
+		key := &storage.ApiKey{UserUuid: "user-1", Name: "Car", SecretHash: "hash", Scopes: []string{"position:write", "playlist:read"}, ExpiresAt: 100}
+		id, err := s.InsertApiKey(key)
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if *id == 0 || key.Id != *id || key.Uuid == "" {
+			t.Errorf("Want the id and uuid set, got '%+v'", key)
+		}
+		s.InsertApiKey(&storage.ApiKey{UserUuid: "user-2", Name: "Script", SecretHash: "other"})
+
+		keys, _ := s.FindApiKeys(storage.ApiKeyFilter{Uuid: key.Uuid})
+		if len(keys) != 1 || keys[0].SecretHash != "hash" || len(keys[0].Scopes) != 2 || keys[0].ExpiresAt != 100 {
+			t.Fatalf("Want the key, got '%+v'", keys)
+		}
+		key.LastUsed = 50
+		if err := s.UpdateApiKey(key); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		keys, _ = s.FindApiKeys(storage.ApiKeyFilter{UserUuid: "user-1"})
+		if len(keys) != 1 || keys[0].LastUsed != 50 {
+			t.Errorf("Want only the user's key updated, got '%+v'", keys)
+		}
+
+		if err := s.DeleteApiKey(key); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if err := s.DeleteApiKey(key); err != storage.ErrApiKeyNotFound {
+			t.Errorf("Want '%v', got '%v'", storage.ErrApiKeyNotFound, err)
+		}
+		if err := s.UpdateApiKey(key); err != storage.ErrApiKeyNotFound {
+			t.Errorf("Want '%v', got '%v'", storage.ErrApiKeyNotFound, err)
+		}
+	})
+	t.Run("Deleting a user deletes their keys", func(t *testing.T) {
+		s := open(t)
+		user := &storage.User{EmailAddress: "test@test.com"}
+		s.InsertUser(user)
+		s.InsertApiKey(&storage.ApiKey{UserUuid: user.Uuid, SecretHash: "hash"})
+		s.InsertApiKey(&storage.ApiKey{UserUuid: "user-2", SecretHash: "other"})
+
+		s.DeleteUser(user)
+		keys, _ := s.FindApiKeys(storage.ApiKeyFilter{})
+		if len(keys) != 1 || keys[0].UserUuid != "user-2" {
+			t.Errorf("Want only the other user's key, got '%+v'", keys)
+		}
+	})
+}
+
+func testDevices(t *testing.T, open Open) {
+	t.Run("Devices belong to their user", func(t *testing.T) {
+		s := open(t)
+		user := &storage.User{EmailAddress: "test@test.com"}
+		s.InsertUser(user)
+		other := &storage.User{EmailAddress: "other@test.com"}
+		s.InsertUser(other)
+		device := &storage.Device{Name: "Kitchen Speaker", Type: "speaker", LastTokenId: "token-1"}
+		if _, err := s.UserAddDevice(user, device); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if device.Id == 0 || device.Uuid == "" {
+			t.Fatalf("Want device id and uuid to be set, got '%+v'", device)
+		}
+
+		devices, _ := s.FindDevices(storage.DeviceFilter{Uuid: device.Uuid, OwnerEmail: "TEST@test.com"})
+		if len(devices) != 1 || devices[0].Name != "Kitchen Speaker" {
+			t.Errorf("Want the user's device, got %d devices", len(devices))
+		}
+		devices, _ = s.FindDevices(storage.DeviceFilter{Uuid: device.Uuid, OwnerEmail: "other@test.com"})
+		if len(devices) != 0 {
+			t.Errorf("Want 0 devices for another user, got %d", len(devices))
+		}
+		s.SelectUser(user)
+		if len(user.Devices) != 1 {
+			t.Errorf("Want 1 device on the user, got %d", len(user.Devices))
+		}
+	})
+	t.Run("Update and revoke a device", func(t *testing.T) {
+		s := open(t)
+		user := &storage.User{EmailAddress: "test@test.com"}
+		s.InsertUser(user)
+		device := &storage.Device{Name: "Phone"}
+		s.UserAddDevice(user, device)
+
+		device.Name = "Lounge"
+		device.LastTokenId = "token-2"
+		device.RefreshTokenHash = "hash-2"
+		device.RefreshTokenFamily = "family-1"
+		device.RefreshTokenGeneration = 2
+		device.RefreshTokenExpires = 200
+		device.Scopes = []string{"position:write", "playlist:read"}
+		if err := s.UpdateDevice(device); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		loaded := &storage.Device{Uuid: device.Uuid}
+		if err := s.SelectDevice(loaded); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if loaded.Name != "Lounge" || loaded.LastTokenId != "token-2" ||
+			loaded.RefreshTokenHash != "hash-2" || loaded.RefreshTokenFamily != "family-1" ||
+			loaded.RefreshTokenGeneration != 2 ||
+			loaded.RefreshTokenExpires != 200 ||
+			len(loaded.Scopes) != 2 || loaded.Scopes[1] != "playlist:read" {
+			t.Errorf("Want updated device, got '%+v'", loaded)
+		}
+
+		if err := s.DeleteDevice(device); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if err := s.SelectDevice(&storage.Device{Uuid: device.Uuid}); err != storage.ErrDeviceNotFound {
+			t.Errorf("Want error '%v', got '%v'", storage.ErrDeviceNotFound, err)
+		}
+		s.SelectUser(user)
+		if len(user.Devices) != 0 {
+			t.Errorf("Want 0 devices on the user, got %d", len(user.Devices))
+		}
+	})
+	t.Run("Updating the details of a stale device leaves its token alone", func(t *testing.T) {
+		s := open(t)
+		user := &storage.User{EmailAddress: "test@test.com"}
+		s.InsertUser(user)
+		device := &storage.Device{Name: "Phone", LastTokenId: "token-1", RefreshTokenHash: "hash-1"}
+		s.UserAddDevice(user, device)
+		stale := &storage.Device{Id: device.Id}
+		s.SelectDevice(stale)
+
+		// Refreshed in between loading and saving the stale copy
+		device.LastTokenId = "token-2"
+		device.RefreshTokenHash = "hash-2"
+		s.UpdateDevice(device)
+
+		stale.Name = "Lounge"
+		stale.LastSeen = 300
+		if err := s.UpdateDeviceDetails(stale); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		loaded := &storage.Device{Id: device.Id}
+		s.SelectDevice(loaded)
+		if loaded.Name != "Lounge" || loaded.LastSeen != 300 ||
+			loaded.LastTokenId != "token-2" || loaded.RefreshTokenHash != "hash-2" {
+			t.Errorf("Want the new details with the newer token, got '%+v'", loaded)
+		}
+		if err := s.UpdateDeviceDetails(&storage.Device{Id: 99}); err != storage.ErrDeviceNotFound {
+			t.Errorf("Want error '%v', got '%v'", storage.ErrDeviceNotFound, err)
+		}
+	})
+	t.Run("Rotating a refresh token only replaces the expected token", func(t *testing.T) {
+		s := open(t)
+		user := &storage.User{EmailAddress: "test@test.com"}
+		s.InsertUser(user)
+		device := &storage.Device{Name: "Phone", LastTokenId: "token-1", RefreshTokenHash: "hash-1"}
+		s.UserAddDevice(user, device)
+
+		first := &storage.Device{Id: device.Id}
+		s.SelectDevice(first)
+		second := &storage.Device{Id: device.Id}
+		s.SelectDevice(second)
+
+		first.Name = "Lounge"
+		first.LastSeen = 300
+		first.LastTokenId = "token-2"
+		first.RefreshTokenHash = "hash-2"
+		first.RefreshTokenExpires = 400
+		first.RefreshTokenFamily = "family-1"
+		first.RefreshTokenGeneration = 2
+		first.Scopes = []string{"playlist:read"}
+		if err := s.RotateDeviceRefreshToken(first, "hash-1"); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		// Both refreshed with hash-1, only the first one gets to swap it
+		second.RefreshTokenHash = "hash-3"
+		if err := s.RotateDeviceRefreshToken(second, "hash-1"); err != storage.ErrRefreshConflict {
+			t.Fatalf("Want error '%v', got '%v'", storage.ErrRefreshConflict, err)
+		}
+
+		loaded := &storage.Device{Id: device.Id}
+		s.SelectDevice(loaded)
+		if loaded.Name != "Phone" || loaded.LastSeen != 300 || loaded.LastTokenId != "token-2" ||
+			loaded.RefreshTokenHash != "hash-2" || loaded.RefreshTokenExpires != 400 ||
+			loaded.RefreshTokenFamily != "family-1" || loaded.RefreshTokenGeneration != 2 ||
+			len(loaded.Scopes) != 1 || loaded.Scopes[0] != "playlist:read" {
+			t.Errorf("Want the first rotation stored without the name, got '%+v'", loaded)
+		}
+
+		// A sign out in between leaves nothing to rotate
+		loaded.RefreshTokenHash = ""
+		s.UpdateDevice(loaded)
+		if err := s.RotateDeviceRefreshToken(first, "hash-2"); err != storage.ErrRefreshConflict {
+			t.Errorf("Want error '%v', got '%v'", storage.ErrRefreshConflict, err)
+		}
+		if err := s.RotateDeviceRefreshToken(&storage.Device{Id: 99}, ""); err != storage.ErrDeviceNotFound {
+			t.Errorf("Want error '%v', got '%v'", storage.ErrDeviceNotFound, err)
+		}
+	})
+}
+
+func testListening(t *testing.T, open Open) {
+	t.Run("There is one resume position per user and track", func(t *testing.T) {
+		s := open(t)
+		s.SaveResumePosition(&storage.ResumePosition{UserUuid: "user-1", TrackUuid: "track-1", Elapsed: 10, UpdatedAt: 100})
+		s.SaveResumePosition(&storage.ResumePosition{UserUuid: "user-1", TrackUuid: "track-2", Elapsed: 20, UpdatedAt: 200})
+		s.SaveResumePosition(&storage.ResumePosition{UserUuid: "user-2", TrackUuid: "track-1", Elapsed: 30, UpdatedAt: 300})
+		position := &storage.ResumePosition{UserUuid: "user-1", TrackUuid: "track-1", Elapsed: 40, UpdatedAt: 400}
+		if err := s.SaveResumePosition(position); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if position.Id == 0 {
+			t.Error("Want the position id to be set")
+		}
+
+		positions, err := s.FindResumePositions(storage.ResumePositionFilter{UserUuid: "user-1"})
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if len(positions) != 2 {
+			t.Fatalf("Want 2 positions, got %d", len(positions))
+		}
+		if positions[0].TrackUuid != "track-1" || positions[0].Elapsed != 40 {
+			t.Errorf("Want the latest position first, got '%+v'", positions[0])
+		}
+		positions, _ = s.FindResumePositions(storage.ResumePositionFilter{UserUuid: "user-1", TrackUuid: "track-2"})
+		if len(positions) != 1 || positions[0].Elapsed != 20 {
+			t.Errorf("Want the track's position, got %d positions", len(positions))
+		}
+		positions, _ = s.FindResumePositions(storage.ResumePositionFilter{UserUuid: "user-1", Limit: 1})
+		if len(positions) != 1 {
+			t.Errorf("Want 1 position, got %d", len(positions))
+		}
+	})
+	t.Run("Plays are listed most recent first", func(t *testing.T) {
+		s := open(t)
+		for i, track := range []string{"track-1", "track-2", "track-1"} {
+			play := &storage.Play{UserUuid: "user-1", TrackUuid: track, StartOffset: i, EndOffset: i + 10, StartedAt: int64(i * 100), EndedAt: int64(i*100 + 10)}
+			if _, err := s.AddPlay(play); err != nil {
+				t.Fatalf("Want no error, got '%s'", err.Error())
+			}
+			if play.Id == 0 {
+				t.Error("Want the play id to be set")
+			}
+		}
+		s.AddPlay(&storage.Play{UserUuid: "user-2", TrackUuid: "track-1", EndedAt: 500})
+
+		plays, err := s.FindPlays(storage.PlayFilter{UserUuid: "user-1"})
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if len(plays) != 3 {
+			t.Fatalf("Want 3 plays, got %d", len(plays))
+		}
+		if plays[0].EndedAt != 210 || plays[2].EndedAt != 10 {
+			t.Errorf("Want plays most recent first, got '%+v'", plays)
+		}
+		plays, _ = s.FindPlays(storage.PlayFilter{UserUuid: "user-1", TrackUuid: "track-1"})
+		if len(plays) != 2 {
+			t.Errorf("Want 2 plays of the track, got %d", len(plays))
+		}
+		plays, _ = s.FindPlays(storage.PlayFilter{UserUuid: "user-1", Since: 100})
+		if len(plays) != 2 {
+			t.Errorf("Want 2 plays since 100, got %d", len(plays))
+		}
+		plays, _ = s.FindPlays(storage.PlayFilter{UserUuid: "user-1", Limit: 1})
+		if len(plays) != 1 || plays[0].EndedAt != 210 {
+			t.Errorf("Want only the latest play, got %d plays", len(plays))
+		}
+	})
+}
+
+func testRevokedTokens(t *testing.T, open Open) {
+	t.Run("Revoke a token", func(t *testing.T) {
+		s := open(t)
+		if revoked, err := s.IsTokenRevoked("token-1"); err != nil || revoked {
+			t.Fatalf("Want token-1 not revoked yet, got '%v' '%v'", revoked, err)
+		}
+		token := &storage.RevokedToken{TokenId: "token-1", ExpiresAt: 100}
+		if err := s.RevokeToken(token); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if err := s.RevokeToken(&storage.RevokedToken{TokenId: "token-1", ExpiresAt: 100}); err != nil {
+			t.Errorf("Want revoking twice to succeed, got '%s'", err.Error())
+		}
+		if revoked, err := s.IsTokenRevoked("token-1"); err != nil || !revoked {
+			t.Errorf("Want token-1 revoked, got '%v' '%v'", revoked, err)
+		}
+		if revoked, _ := s.IsTokenRevoked("token-2"); revoked {
+			t.Errorf("Want token-2 not revoked")
+		}
+	})
+	t.Run("Expired tokens are pruned", func(t *testing.T) {
+		s := open(t)
+		s.RevokeToken(&storage.RevokedToken{TokenId: "token-1", ExpiresAt: 100})
+		s.RevokeToken(&storage.RevokedToken{TokenId: "token-2", ExpiresAt: 200})
+		s.RevokeToken(&storage.RevokedToken{TokenId: "token-3", ExpiresAt: 300})
+
+		pruned, err := s.PruneRevokedTokens(200)
+		if err != nil || pruned != 1 {
+			t.Fatalf("Want 1 token pruned, got %d '%v'", pruned, err)
+		}
+		for tokenId, want := range map[string]bool{"token-1": false, "token-2": true, "token-3": true} {
+			if revoked, _ := s.IsTokenRevoked(tokenId); revoked != want {
+				t.Errorf("Want '%s' revoked '%v', got '%v'", tokenId, want, revoked)
+			}
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package webhelper
+
+import (
+	"context"
+	"net/http"
+)
+
+// Principal is the signed in user a request is made by
+type Principal struct {
+	UserId       uint64
+	UserUuid     string
+	EmailAddress string
+	Permissions  []string // everything the user's roles allow
+	Device       string   // uuid of the device the token was issued to
+	TokenId      string
+	ExpiresAt    int64
+	Scopes       []string // what the token or API key is limited to, nil when it isn't limited
+}
+
+// Can reports whether the principal has the permission
+func (p *Principal) Can(permission string) bool {
+	for _, granted := range p.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the principal can use routes that require the
+// scope, which is any route when it isn't limited to scopes
+func (p *Principal) HasScope(scope string) bool {
+	if p.Scopes == nil {
+		return true
+	}
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator works out who made the request, or the status to turn it
+// away with
+type Authenticator func(r *http.Request) (*Principal, int)
+
+// OwnerCheck reports whether the principal owns what the request is for
+type OwnerCheck func(r *http.Request, p *Principal) bool
+
+type principalKey struct{}
+
+type scopeKey struct{}
+
+var ErrPermissionRequired = NewError(CodePermissionRequired, "Permission required")
+var ErrAccessDenied = NewError(CodeAccessDenied, "Access Denied")
+var ErrScopeRequired = NewError(CodeScopeRequired, "Not allowed with this token's scopes")
+
+// Authenticate is middleware that authenticates the request once and puts the
+// principal in its context for the handler, see CurrentPrincipal
+func Authenticate(authenticate Authenticator) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			principal, response := authenticate(r)
+			if response != http.StatusOK {
+				ReturnStatus(w, r, response)
+				return
+			}
+			next(w, WithPrincipal(r, principal))
+		}
+	}
+}
+
+// CurrentPrincipal returns the principal Authenticate found, or nil
+func CurrentPrincipal(r *http.Request) *Principal {
+	principal, _ := r.Context().Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// WithPrincipal returns a copy of the request made by the principal
+func WithPrincipal(r *http.Request, principal *Principal) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), principalKey{}, principal))
+}
+
+// RequirePermission is route middleware that only lets through users with the
+// permission
+func RequirePermission(permission string) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			principal := CurrentPrincipal(r)
+			if principal == nil {
+				ReturnStatus(w, r, http.StatusUnauthorized)
+				return
+			}
+			if !principal.Can(permission) {
+				ReturnError(w, r, ErrPermissionRequired, &[]int{http.StatusForbidden}[0])
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// RequireOwner is route middleware that only lets through the owner of what
+// the request is for, and users with the permission
+func RequireOwner(owns OwnerCheck, permission string) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			principal := CurrentPrincipal(r)
+			if principal == nil {
+				ReturnStatus(w, r, http.StatusUnauthorized)
+				return
+			}
+			if !principal.Can(permission) && !owns(r, principal) {
+				ReturnError(w, r, ErrAccessDenied, &[]int{http.StatusForbidden}[0])
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// RequireScope is route middleware that only lets through principals with
+// one of the scopes. Principals limited to scopes can only use routes that
+// require one of theirs, every other route turns them away.
+func RequireScope(scopes ...string) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			principal := CurrentPrincipal(r)
+			if principal != nil && principal.Scopes != nil {
+				allowed := false
+				for _, scope := range scopes {
+					allowed = allowed || principal.HasScope(scope)
+				}
+				if !allowed {
+					ReturnError(w, r, ErrScopeRequired, &[]int{http.StatusForbidden}[0])
+					return
+				}
+			}
+			next(w, r.WithContext(context.WithValue(r.Context(), scopeKey{}, scopes)))
+		}
+	}
+}
+
+// AnyScope is route middleware for routes every principal can use whatever it
+// is limited to, like signing out
+func AnyScope(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next(w, r.WithContext(context.WithValue(r.Context(), scopeKey{}, []string{})))
+	}
+}
+
+// scopeGuard runs last for every route, turning away principals limited to
+// scopes when the route didn't require one with RequireScope
+func scopeGuard(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := CurrentPrincipal(r)
+		if principal != nil && principal.Scopes != nil && r.Context().Value(scopeKey{}) == nil {
+			ReturnError(w, r, ErrScopeRequired, &[]int{http.StatusForbidden}[0])
+			return
+		}
+		next(w, r)
+	}
+}
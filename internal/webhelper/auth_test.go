@@ -0,0 +1,129 @@
+package webhelper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func ok(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Run("The principal reaches the handler", func(t *testing.T) {
+		var got *Principal
+		handler := Authenticate(func(r *http.Request) (*Principal, int) {
+			return &Principal{UserId: 2, EmailAddress: "test@test.com"}, http.StatusOK
+		})(func(w http.ResponseWriter, r *http.Request) {
+			got = CurrentPrincipal(r)
+		})
+		handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		if got == nil || got.UserId != 2 {
+			t.Errorf("Want principal for user '%d', got '%+v'", 2, got)
+		}
+	})
+	t.Run("Failed authentication stops the request", func(t *testing.T) {
+		handler := Authenticate(func(r *http.Request) (*Principal, int) {
+			return nil, http.StatusUnauthorized
+		})(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("Handler should not be called")
+		})
+		responseRecorder := httptest.NewRecorder()
+
+		handler(responseRecorder, httptest.NewRequest("GET", "/", nil))
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
+		}
+	})
+}
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *Principal
+		want      int
+	}{
+		{"Has the permission", &Principal{UserId: 1, Permissions: []string{"users:write", "users:read"}}, http.StatusOK},
+		{"Has other permissions", &Principal{UserId: 2, Permissions: []string{"users:write"}}, http.StatusForbidden},
+		{"Not signed in", nil, http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "/users", nil)
+			if test.principal != nil {
+				request = WithPrincipal(request, test.principal)
+			}
+			responseRecorder := httptest.NewRecorder()
+
+			Chain(ok, RequirePermission("users:read"))(responseRecorder, request)
+			if responseRecorder.Code != test.want {
+				t.Errorf("Want status '%d', got '%d'", test.want, responseRecorder.Code)
+			}
+		})
+	}
+}
+
+func TestRequireOwner(t *testing.T) {
+	owns := func(r *http.Request, p *Principal) bool {
+		return Param(r, "id") == "2" && p.UserId == 2
+	}
+	tests := []struct {
+		name      string
+		principal *Principal
+		want      int
+	}{
+		{"Owner", &Principal{UserId: 2}, http.StatusOK},
+		{"Has the permission", &Principal{UserId: 1, Permissions: []string{"users:read"}}, http.StatusOK},
+		{"Someone else", &Principal{UserId: 3, Permissions: []string{"library:read"}}, http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := WithParams(httptest.NewRequest("GET", "/users/2", nil), Params{"id": "2"})
+			request = WithPrincipal(request, test.principal)
+			responseRecorder := httptest.NewRecorder()
+
+			Chain(ok, RequireOwner(owns, "users:read"))(responseRecorder, request)
+			if responseRecorder.Code != test.want {
+				t.Errorf("Want status '%d', got '%d'", test.want, responseRecorder.Code)
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *Principal
+		scopes    string // "" for a route without RequireScope, "*" for AnyScope
+		want      int
+	}{
+		{"Has the scope", &Principal{UserId: 2, Scopes: []string{"position:write"}}, "position:write", http.StatusOK},
+		{"Has other scopes", &Principal{UserId: 2, Scopes: []string{"playlist:read"}}, "position:write", http.StatusForbidden},
+		{"A route that doesn't allow any scopes", &Principal{UserId: 2, Scopes: []string{"playlist:read"}}, "", http.StatusForbidden},
+		{"Has one of the scopes", &Principal{UserId: 2, Scopes: []string{"playlist:write"}}, "position:write playlist:write", http.StatusOK},
+		{"Not limited to scopes", &Principal{UserId: 2}, "position:write", http.StatusOK},
+		{"Not limited to scopes on a route that doesn't allow any", &Principal{UserId: 2}, "", http.StatusOK},
+		{"A route any scope can use", &Principal{UserId: 2, Scopes: []string{"position:write"}}, "*", http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := WithPrincipal(httptest.NewRequest("PUT", "/tracks/1/position", nil), test.principal)
+			responseRecorder := httptest.NewRecorder()
+
+			var chain []Middleware
+			switch test.scopes {
+			case "":
+			case "*":
+				chain = append(chain, AnyScope)
+			default:
+				chain = append(chain, RequireScope(strings.Fields(test.scopes)...))
+			}
+			Chain(ok, append(chain, scopeGuard)...)(responseRecorder, request)
+			if responseRecorder.Code != test.want {
+				t.Errorf("Want status '%d', got '%d'", test.want, responseRecorder.Code)
+			}
+		})
+	}
+}
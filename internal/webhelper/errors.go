@@ -0,0 +1,179 @@
+package webhelper
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// The error codes clients can switch on, they don't change once released.
+// Errors without a code of their own get the one for their status.
+const (
+	// By status
+	CodeBadRequest       = "bad_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeTooManyRequests  = "too_many_requests"
+	CodeInternal         = "internal_error"
+
+	// Requests
+	CodeJsonRequired = "json_required" // a body that isn't sent as application/json
+	CodeInvalidUuid  = "invalid_uuid"  // a uuid in the url that isn't one
+
+	// Signing in
+	CodeInvalidCredentials   = "invalid_credentials"
+	CodeEmailNotVerified     = "email_not_verified"
+	CodeTooManySignins       = "too_many_signins"
+	CodeAccountLocked        = "account_locked"
+	CodeAccountDisabled      = "account_disabled"
+	CodeTotpCodeInvalid      = "totp_code_invalid"
+	CodeTotpChallengeInvalid = "totp_challenge_invalid" // sign in again
+	CodeRefreshTokenInvalid  = "refresh_token_invalid"
+	CodeRefreshTokenExpired  = "refresh_token_expired"
+	CodeRefreshTokenReused   = "refresh_token_reused" // the device has been signed out
+	CodeEmailTokenInvalid    = "email_token_invalid"
+	CodeOidcNotLinked        = "oidc_not_linked"
+	CodeAccountExists        = "account_exists"
+	CodeLoginLinked          = "login_linked"
+
+	// Access
+	CodePermissionRequired = "permission_required"
+	CodeAccessDenied       = "access_denied"
+	CodeScopeRequired      = "scope_required"
+	CodeUnknownScope       = "unknown_scope"
+	CodeScopeNotHeld       = "scope_not_held"
+	CodeLastAdmin          = "last_admin"
+
+	// Playlists
+	CodePlaylistLocked    = "playlist_locked"   // details has the lock
+	CodeRevisionConflict  = "revision_conflict" // details has the current playlist for a position update
+	CodePlaylistReadOnly  = "playlist_read_only"
+	CodeNotOwner          = "not_owner"
+	CodeGroupRestricted   = "group_restricted"
+	CodeAlreadyInGroup    = "already_in_group"
+	CodeGroupConflict     = "group_conflict" // load the group again and retry
+	CodeNotFriends        = "not_friends"
+	CodeTrackExists       = "track_exists"
+	CodeInvalidTrackOrder = "invalid_track_order"
+)
+
+var statusCodes = map[int]string{
+	http.StatusBadRequest:       CodeBadRequest,
+	http.StatusUnauthorized:     CodeUnauthorized,
+	http.StatusForbidden:        CodeForbidden,
+	http.StatusNotFound:         CodeNotFound,
+	http.StatusMethodNotAllowed: CodeMethodNotAllowed,
+	http.StatusConflict:         CodeConflict,
+	http.StatusTooManyRequests:  CodeTooManyRequests,
+}
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestId string      `json:"requestId,omitempty"`
+}
+
+// Error is an error with a code from the catalogue
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// NewError makes an error that is returned with the code
+func NewError(code string, message string) error {
+	return &Error{Code: code, Message: message}
+}
+
+var registeredCodes = struct {
+	sync.RWMutex
+	codes map[error]string
+}{codes: map[error]string{}}
+
+// RegisterErrorCode gives an error from a package that can't use NewError,
+// like storage, its code
+func RegisterErrorCode(err error, code string) {
+	registeredCodes.Lock()
+	defer registeredCodes.Unlock()
+	registeredCodes.codes[err] = code
+}
+
+// ErrorCode returns the code err is returned with, at the status
+func ErrorCode(err error, status int) string {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	registeredCodes.RLock()
+	code, ok := registeredCodes.codes[err]
+	registeredCodes.RUnlock()
+	if ok {
+		return code
+	}
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, response ErrorResponse) {
+	if r != nil {
+		response.RequestId = RequestId(r)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// ReturnError writes err as the response when there is one, reporting whether
+// it did
+func ReturnError(w http.ResponseWriter, r *http.Request, err error, httpCode *int) bool {
+	return ReturnErrorDetails(w, r, err, httpCode, nil)
+}
+
+// ReturnErrorDetails is ReturnError with more about the error for the client,
+// like the lock that got in the way. A server error only tells the client its
+// status, what went wrong is logged with the request id instead
+func ReturnErrorDetails(w http.ResponseWriter, r *http.Request, err error, httpCode *int, details interface{}) bool {
+	if httpCode == nil || err == nil {
+		return false
+	}
+	if *httpCode >= http.StatusInternalServerError {
+		if r != nil {
+			log.Printf("%s %s %d %s: %v", r.Method, r.URL.Path, *httpCode, RequestId(r), err)
+		}
+		writeError(w, r, *httpCode, ErrorResponse{
+			Code:    ErrorCode(err, *httpCode),
+			Message: http.StatusText(*httpCode),
+		})
+		return true
+	}
+	writeError(w, r, *httpCode, ErrorResponse{
+		Code:    ErrorCode(err, *httpCode),
+		Message: err.Error(),
+		Details: details,
+	})
+	return true
+}
+
+// ReturnStatus writes an error for the status alone, for when there is
+// nothing more to say than the status does
+func ReturnStatus(w http.ResponseWriter, r *http.Request, status int) {
+	writeError(w, r, status, ErrorResponse{
+		Code:    ErrorCode(nil, status),
+		Message: http.StatusText(status),
+	})
+}
@@ -0,0 +1,99 @@
+package webhelper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestErrorCode(t *testing.T) {
+	registered := errors.New("Registered")
+	RegisterErrorCode(registered, CodeAlreadyInGroup)
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		want   string
+	}{
+		{"An error with a code", NewError(CodeTrackExists, "Track exists"), http.StatusBadRequest, CodeTrackExists},
+		{"A wrapped error with a code", fmt.Errorf("saving: %w", ErrScopeRequired), http.StatusForbidden, CodeScopeRequired},
+		{"A registered error", registered, http.StatusConflict, CodeAlreadyInGroup},
+		{"Anything else gets the status's code", errors.New("Not here"), http.StatusNotFound, CodeNotFound},
+		{"A server error", errors.New("Broken"), http.StatusBadGateway, CodeInternal},
+		{"A status without a code", errors.New("Too big"), http.StatusRequestEntityTooLarge, CodeBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if code := ErrorCode(test.err, test.status); code != test.want {
+				t.Errorf("Want code '%s', got '%s'", test.want, code)
+			}
+		})
+	}
+}
+
+func TestReturnErrorDetails(t *testing.T) {
+	handler := RequestIds(func(w http.ResponseWriter, r *http.Request) {
+		ReturnErrorDetails(w, r, NewError(CodePlaylistLocked, "Locked"), &[]int{http.StatusLocked}[0], map[string]bool{"locked": true})
+	})
+	request := httptest.NewRequest("POST", "/", nil)
+	request.Header.Set("X-Request-Id", "abc-123")
+	responseRecorder := httptest.NewRecorder()
+
+	handler(responseRecorder, request)
+	if responseRecorder.Code != http.StatusLocked {
+		t.Fatalf("Want status '%d', got '%d'", http.StatusLocked, responseRecorder.Code)
+	}
+	if contentType := responseRecorder.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Want content type '%s', got '%s'", "application/json", contentType)
+	}
+	var details map[string]bool
+	response := ErrorResponse{Details: &details}
+	json.NewDecoder(responseRecorder.Body).Decode(&response)
+	if response.Code != CodePlaylistLocked || response.Message != "Locked" || response.RequestId != "abc-123" || !details["locked"] {
+		t.Errorf("Want the whole envelope, got '%+v'", response)
+	}
+}
+
+func TestReturnErrorDetailsServerError(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+	handler := RequestIds(func(w http.ResponseWriter, r *http.Request) {
+		ReturnErrorDetails(w, r, errors.New("database is locked"), &[]int{http.StatusInternalServerError}[0], map[string]bool{"locked": true})
+	})
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set("X-Request-Id", "abc-123")
+	responseRecorder := httptest.NewRecorder()
+
+	handler(responseRecorder, request)
+	var response map[string]interface{}
+	json.NewDecoder(responseRecorder.Body).Decode(&response)
+	if responseRecorder.Code != http.StatusInternalServerError || response["code"] != CodeInternal || response["message"] != "Internal Server Error" || response["requestId"] != "abc-123" {
+		t.Errorf("Want an internal error envelope, got '%d' '%+v'", responseRecorder.Code, response)
+	}
+	if _, ok := response["details"]; ok {
+		t.Errorf("Want no details, got '%+v'", response)
+	}
+	if !strings.Contains(logged.String(), "abc-123: database is locked") {
+		t.Errorf("Want the error logged with the request id, got '%s'", logged.String())
+	}
+}
+
+func TestReturnStatus(t *testing.T) {
+	request := httptest.NewRequest("GET", "/", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	ReturnStatus(responseRecorder, request, http.StatusUnauthorized)
+	var response ErrorResponse
+	json.NewDecoder(responseRecorder.Body).Decode(&response)
+	if responseRecorder.Code != http.StatusUnauthorized || response.Code != CodeUnauthorized || response.Message != "Unauthorized" {
+		t.Errorf("Want an unauthorized envelope, got '%d' '%+v'", responseRecorder.Code, response)
+	}
+}
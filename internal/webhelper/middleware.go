@@ -0,0 +1,101 @@
+package webhelper
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type requestIdKey struct{}
+
+// requestIdPattern is what a client's X-Request-Id has to look like to be
+// used, anything else gets a new one
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// statusRecorder remembers the status written, for the request log
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// Flush keeps the events stream working behind the logger
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// RequestIds gives every request an id, the X-Request-Id the client sent or a
+// new one. It is sent back as X-Request-Id, logged, and in every error.
+func RequestIds(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-Id")
+		if !requestIdPattern.MatchString(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set("X-Request-Id", id)
+		next(w, r.WithContext(context.WithValue(r.Context(), requestIdKey{}, id)))
+	}
+}
+
+// RequestId returns the id RequestIds gave the request, or "" without it
+func RequestId(r *http.Request) string {
+	id, _ := r.Context().Value(requestIdKey{}).(string)
+	return id
+}
+
+// Logging logs the method, path, status and duration of every request
+func Logging(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		next(recorder, r)
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		if id := RequestId(r); id != "" {
+			log.Printf("%s %s %d %s %s", r.Method, r.URL.Path, recorder.status, time.Since(start), id)
+			return
+		}
+		log.Printf("%s %s %d %s", r.Method, r.URL.Path, recorder.status, time.Since(start))
+	}
+}
+
+// Recovery turns a panic in a handler into a 500 rather than a dropped
+// connection, and logs it with the stack
+func Recovery(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			log.Printf("panic serving %s %s %s: %v\n%s", r.Method, r.URL.Path, RequestId(r), err, debug.Stack())
+			ReturnError(w, r, errors.New("Internal Server Error"), &[]int{http.StatusInternalServerError}[0])
+		}()
+		next(w, r)
+	}
+}
@@ -0,0 +1,184 @@
+package webhelper
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Middleware wraps a handler, doing its work before and/or after calling next
+type Middleware func(next http.HandlerFunc) http.HandlerFunc
+
+// Params holds the named path parameters of the matched route
+type Params map[string]string
+
+type Route struct {
+	method  string
+	pattern string
+	regex   *regexp.Regexp
+	handler http.HandlerFunc
+}
+
+// Group is a set of routes sharing a path prefix and middleware
+type Group struct {
+	prefix     string
+	middleware []Middleware
+}
+
+type ctxKey struct{}
+
+var Routes = []Route{}
+
+var errJsonRequired = NewError(CodeJsonRequired, "Request body has to be sent as application/json")
+
+// middleware runs on every request, including the ones no route matches
+var middleware []Middleware
+
+var root = &Group{}
+
+// paramPattern finds the {name} placeholders in a route pattern
+var paramPattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Use adds middleware that runs on every request, the first added runs first
+func Use(m ...Middleware) {
+	middleware = append(middleware, m...)
+}
+
+// NewRoute adds a route. The pattern is a regular expression matched against
+// the whole path, where {name} matches one path segment that the handler can
+// read with Param. Any middleware given only runs for this route. Principals
+// limited to scopes are turned away unless it includes RequireScope.
+func NewRoute(method, pattern string, handler http.HandlerFunc, m ...Middleware) {
+	root.NewRoute(method, pattern, handler, m...)
+}
+
+// NewGroup starts a group of routes under the prefix
+func NewGroup(prefix string, m ...Middleware) *Group {
+	return root.Group(prefix, m...)
+}
+
+// Group starts a group of routes nested in g, they run g's middleware first
+func (g *Group) Group(prefix string, m ...Middleware) *Group {
+	return &Group{
+		prefix:     g.prefix + prefix,
+		middleware: append(append([]Middleware{}, g.middleware...), m...),
+	}
+}
+
+// Use adds middleware to the routes added to the group from now on
+func (g *Group) Use(m ...Middleware) {
+	g.middleware = append(g.middleware, m...)
+}
+
+// NewRoute adds a route under the group's prefix, see NewRoute
+func (g *Group) NewRoute(method, pattern string, handler http.HandlerFunc, m ...Middleware) {
+	pattern = g.prefix + pattern
+	regex := regexp.MustCompile("^" + paramPattern.ReplaceAllString(pattern, "(?P<$1>[^/]+)") + "$")
+	chain := append(append(append([]Middleware{}, g.middleware...), m...), scopeGuard)
+	Routes = append(Routes, Route{method, pattern, regex, Chain(handler, chain...)})
+}
+
+// Chain wraps handler in the middleware, the first one given runs first
+func Chain(handler http.HandlerFunc, m ...Middleware) http.HandlerFunc {
+	for i := len(m) - 1; i >= 0; i-- {
+		handler = m[i](handler)
+	}
+	return handler
+}
+
+// Param returns the named path parameter of the route that matched the
+// request, or "" when there isn't one
+func Param(r *http.Request, name string) string {
+	params, _ := r.Context().Value(ctxKey{}).(Params)
+	return params[name]
+}
+
+// UuidParam returns the named path parameter, which has to be a uuid
+func UuidParam(r *http.Request, name string) (string, error) {
+	value := Param(r, name)
+	if err := CheckUuid(value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// WithParams returns a copy of the request carrying the path parameters, as
+// Serve does once a route matches. Handy for calling a handler directly.
+func WithParams(r *http.Request, params Params) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), ctxKey{}, params))
+}
+
+// match returns the route for the request, or the methods the path does
+// allow when none of the routes for it has the request's method. A HEAD
+// request is served by the GET route.
+func match(r *http.Request) (*Route, Params, []string) {
+	var allow []string
+	for i, route := range Routes {
+		matches := route.regex.FindStringSubmatch(r.URL.Path)
+		if len(matches) == 0 {
+			continue
+		}
+		if r.Method != route.method && !(r.Method == http.MethodHead && route.method == http.MethodGet) {
+			allow = append(allow, route.method)
+			continue
+		}
+		params := Params{}
+		for j, name := range route.regex.SubexpNames() {
+			if name != "" {
+				params[name] = matches[j]
+			}
+		}
+		return &Routes[i], params, nil
+	}
+	return nil, nil, allow
+}
+
+// allowHeader lists each method once, in order
+func allowHeader(methods []string) string {
+	seen := map[string]bool{}
+	var allow []string
+	for _, method := range methods {
+		if !seen[method] {
+			seen[method] = true
+			allow = append(allow, method)
+		}
+	}
+	sort.Strings(allow)
+	return strings.Join(allow, ", ")
+}
+
+func Serve(w http.ResponseWriter, r *http.Request) {
+	Chain(dispatch, middleware...)(w, r)
+}
+
+func dispatch(w http.ResponseWriter, r *http.Request) {
+	// Force it to be a json only application
+	body, _ := ioutil.ReadAll((r.Body))
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewBuffer(body))
+	if len(body) > 0 && r.Header.Get("Content-type") != "application/json" {
+		ReturnError(w, r, errJsonRequired, &[]int{http.StatusBadRequest}[0])
+		return
+	}
+
+	route, params, allow := match(r)
+	if route != nil {
+		route.handler(w, WithParams(r, params))
+		return
+	}
+	if len(allow) > 0 {
+		w.Header().Set("Allow", allowHeader(append(allow, http.MethodOptions)))
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		ReturnStatus(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+	ReturnStatus(w, r, http.StatusNotFound)
+}
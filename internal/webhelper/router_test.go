@@ -0,0 +1,193 @@
+package webhelper
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testRoutes swaps in an empty route table for the test
+func testRoutes(t *testing.T) {
+	routes, global := Routes, middleware
+	Routes, middleware = []Route{}, nil
+	t.Cleanup(func() {
+		Routes, middleware = routes, global
+	})
+}
+
+func writeParams(names ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var values []string
+		for _, name := range names {
+			values = append(values, Param(r, name))
+		}
+		w.Write([]byte(strings.Join(values, " ")))
+	}
+}
+
+// tag is middleware that adds its name to the X-Chain header
+func tag(name string) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Chain", name)
+			next(w, r)
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+	t.Run("Named path parameters", func(t *testing.T) {
+		testRoutes(t)
+		NewRoute("GET", "/playlists/shared", writeParams())
+		NewRoute("GET", "/playlists/{uuid}/tracks/{trackUuid}", writeParams("uuid", "trackUuid"))
+		request := httptest.NewRequest("GET", "/playlists/abc/tracks/def", nil)
+		responseRecorder := httptest.NewRecorder()
+
+		Serve(responseRecorder, request)
+		if responseRecorder.Body.String() != "abc def" {
+			t.Errorf("Want params '%s', got '%s'", "abc def", responseRecorder.Body.String())
+		}
+	})
+	t.Run("Groups nest their prefix and middleware", func(t *testing.T) {
+		testRoutes(t)
+		Use(tag("global"))
+		api := NewGroup("/api", tag("api"))
+		playlists := api.Group("/playlists", tag("playlists"))
+		playlists.NewRoute("GET", "/{uuid}", writeParams("uuid"), tag("route"))
+		request := httptest.NewRequest("GET", "/api/playlists/abc", nil)
+		responseRecorder := httptest.NewRecorder()
+
+		Serve(responseRecorder, request)
+		chain := strings.Join(responseRecorder.Header()["X-Chain"], ",")
+		if chain != "global,api,playlists,route" {
+			t.Errorf("Want middleware run in order '%s', got '%s'", "global,api,playlists,route", chain)
+		}
+		if responseRecorder.Body.String() != "abc" {
+			t.Errorf("Want param '%s', got '%s'", "abc", responseRecorder.Body.String())
+		}
+	})
+	t.Run("Wrong method lists the allowed ones", func(t *testing.T) {
+		testRoutes(t)
+		NewRoute("GET", "/devices/{uuid}", writeParams())
+		NewRoute("DELETE", "/devices/{uuid}", writeParams())
+		NewRoute("PATCH", "/devices/{uuid}", writeParams())
+		request := httptest.NewRequest("POST", "/devices/abc", nil)
+		responseRecorder := httptest.NewRecorder()
+
+		Serve(responseRecorder, request)
+		if responseRecorder.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Want status '%d', got '%d'", http.StatusMethodNotAllowed, responseRecorder.Code)
+		}
+		if allow := responseRecorder.Header().Get("Allow"); allow != "DELETE, GET, OPTIONS, PATCH" {
+			t.Errorf("Want Allow '%s', got '%s'", "DELETE, GET, OPTIONS, PATCH", allow)
+		}
+	})
+	t.Run("OPTIONS and HEAD", func(t *testing.T) {
+		testRoutes(t)
+		NewRoute("GET", "/devices", writeParams())
+		request := httptest.NewRequest("OPTIONS", "/devices", nil)
+		responseRecorder := httptest.NewRecorder()
+
+		Serve(responseRecorder, request)
+		if responseRecorder.Code != http.StatusNoContent || responseRecorder.Header().Get("Allow") != "GET, OPTIONS" {
+			t.Errorf("Want status '%d' allowing GET, got '%d' '%s'", http.StatusNoContent, responseRecorder.Code, responseRecorder.Header().Get("Allow"))
+		}
+
+		request = httptest.NewRequest("HEAD", "/devices", nil)
+		responseRecorder = httptest.NewRecorder()
+		Serve(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Errorf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+	})
+	t.Run("Unknown path", func(t *testing.T) {
+		testRoutes(t)
+		NewRoute("GET", "/devices", writeParams())
+		request := httptest.NewRequest("GET", "/device", nil)
+		responseRecorder := httptest.NewRecorder()
+
+		Serve(responseRecorder, request)
+		if responseRecorder.Code != http.StatusNotFound {
+			t.Errorf("Want status '%d', got '%d'", http.StatusNotFound, responseRecorder.Code)
+		}
+		var response ErrorResponse
+		json.NewDecoder(responseRecorder.Body).Decode(&response)
+		if response.Code != CodeNotFound {
+			t.Errorf("Want code '%s', got '%s'", CodeNotFound, response.Code)
+		}
+	})
+	t.Run("A body has to be json", func(t *testing.T) {
+		testRoutes(t)
+		NewRoute("POST", "/devices", writeParams())
+		request := httptest.NewRequest("POST", "/devices", strings.NewReader("name=car"))
+		responseRecorder := httptest.NewRecorder()
+
+		Serve(responseRecorder, request)
+		var response ErrorResponse
+		json.NewDecoder(responseRecorder.Body).Decode(&response)
+		if responseRecorder.Code != http.StatusBadRequest || response.Code != CodeJsonRequired {
+			t.Errorf("Want status '%d' with code '%s', got '%d' '%s'", http.StatusBadRequest, CodeJsonRequired, responseRecorder.Code, response.Code)
+		}
+	})
+}
+
+func TestUuidParam(t *testing.T) {
+	request := WithParams(httptest.NewRequest("GET", "/", nil), Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4", "id": "1"})
+	if value, err := UuidParam(request, "uuid"); err != nil || value != "48cf9b84-6162-430a-92ac-6804146ad2a4" {
+		t.Errorf("Want the uuid, got '%s' '%v'", value, err)
+	}
+	if _, err := UuidParam(request, "id"); err != ErrInvalidUrl {
+		t.Errorf("Want error '%v', got '%v'", ErrInvalidUrl, err)
+	}
+	if _, err := UuidParam(request, "missing"); err != ErrInvalidUrl {
+		t.Errorf("Want error '%v', got '%v'", ErrInvalidUrl, err)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	handler := Chain(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}, Logging, Recovery)
+	request := httptest.NewRequest("GET", "/", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	handler(responseRecorder, request)
+	if responseRecorder.Code != http.StatusInternalServerError {
+		t.Errorf("Want status '%d', got '%d'", http.StatusInternalServerError, responseRecorder.Code)
+	}
+}
+
+func TestRequestIds(t *testing.T) {
+	tests := []struct {
+		name string
+		sent string
+		keep bool
+	}{
+		{"The client's id is kept", "car-42.7", true},
+		{"A missing id is made", "", false},
+		{"An id that isn't safe to log is replaced", "bad id\nwith a newline", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var seen string
+			handler := RequestIds(func(w http.ResponseWriter, r *http.Request) {
+				seen = RequestId(r)
+			})
+			request := httptest.NewRequest("GET", "/", nil)
+			if test.sent != "" {
+				request.Header.Set("X-Request-Id", test.sent)
+			}
+			responseRecorder := httptest.NewRecorder()
+
+			handler(responseRecorder, request)
+			if seen == "" || responseRecorder.Header().Get("X-Request-Id") != seen {
+				t.Fatalf("Want the id sent back, got '%s' '%s'", seen, responseRecorder.Header().Get("X-Request-Id"))
+			}
+			if (seen == test.sent) != test.keep {
+				t.Errorf("Want kept '%v', got '%s'", test.keep, seen)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Package events pushes playlist and track changes to every connected device
+// of a user as Server-Sent Events, so clients don't have to poll for them.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"mimpidev/sinkrontrack-server/pkg/userLogin"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types sent down the stream
+const (
+	PlaylistPosition = "position"
+	PlaylistLock     = "lock"
+	PlaylistTracks   = "tracks"
+	TrackUpdated     = "track"
+	ResumePosition   = "resume"
+	TrackPlayed      = "play"
+)
+
+type Event struct {
+	Type   string      `json:"type"`
+	Client string      `json:"client,omitempty"` // Uuid of the device that made the change
+	Data   interface{} `json:"data"`
+}
+
+// How many events a slow client can fall behind before events are dropped
+const subscriberBuffer = 16
+
+// Broker keeps track of the open streams for each user
+type Broker struct {
+	mutex       sync.RWMutex
+	subscribers map[string]map[chan Event]bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[string]map[chan Event]bool)}
+}
+
+// Email addresses are matched case insensitively everywhere else, so do the
+// same here
+func brokerKey(username string) string {
+	return strings.ToLower(username)
+}
+
+func (b *Broker) Subscribe(username string) chan Event {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	key := brokerKey(username)
+	if b.subscribers[key] == nil {
+		b.subscribers[key] = make(map[chan Event]bool)
+	}
+	ch := make(chan Event, subscriberBuffer)
+	b.subscribers[key][ch] = true
+	return ch
+}
+
+func (b *Broker) Unsubscribe(username string, ch chan Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	key := brokerKey(username)
+	delete(b.subscribers[key], ch)
+	if len(b.subscribers[key]) == 0 {
+		delete(b.subscribers, key)
+	}
+}
+
+// Publish sends the event to every stream the user has open. It never blocks,
+// a client that isn't keeping up misses the event and picks up the current
+// state with its next GET.
+func (b *Broker) Publish(username string, event Event) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	for ch := range b.subscribers[brokerKey(username)] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribers returns the number of streams the user has open
+func (b *Broker) Subscribers(username string) int {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return len(b.subscribers[brokerKey(username)])
+}
+
+var DefaultBroker = NewBroker()
+
+func Publish(username string, event Event) {
+	DefaultBroker.Publish(username, event)
+}
+
+var requestClaimsVar = userLogin.RequestClaims
+var checkTokenVar = userLogin.CheckToken
+
+// Comments are sent this often so proxies don't close an idle stream
+var keepAliveInterval = 30 * time.Second
+
+// Stream is the GET /events handler, it holds the connection open and writes
+// every event published for the signed in user until the client goes away.
+// The token is checked again before every event and keep-alive, so the stream
+// ends once it expires or its device is signed out or revoked, without
+// writing anything more.
+func Stream(w http.ResponseWriter, r *http.Request) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		webhelper.ReturnStatus(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	ch := DefaultBroker.Subscribe(claims.Username)
+	defer DefaultBroker.Unsubscribe(claims.Username, ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, response := checkTokenVar(r); response != http.StatusOK {
+				return
+			}
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case event := <-ch:
+			if _, response := checkTokenVar(r); response != http.StatusOK {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"mimpidev/sinkrontrack-server/pkg/userLogin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func TestBroker(t *testing.T) {
+	t.Run("Events only go to the user's streams", func(t *testing.T) {
+		broker := NewBroker()
+		mine := broker.Subscribe("test@test.com.au")
+		theirs := broker.Subscribe("other@test.com.au")
+
+		broker.Publish("TEST@test.com.au", Event{Type: PlaylistPosition})
+		select {
+		case event := <-mine:
+			if event.Type != PlaylistPosition {
+				t.Errorf("Want event '%s', got '%s'", PlaylistPosition, event.Type)
+			}
+		default:
+			t.Errorf("Expected an event for the user")
+		}
+		select {
+		case <-theirs:
+			t.Errorf("Expected no event for another user")
+		default:
+		}
+	})
+	t.Run("Unsubscribe removes the stream", func(t *testing.T) {
+		broker := NewBroker()
+		ch := broker.Subscribe("test@test.com.au")
+		broker.Unsubscribe("test@test.com.au", ch)
+		if broker.Subscribers("test@test.com.au") != 0 {
+			t.Errorf("Want 0 subscribers, got %d", broker.Subscribers("test@test.com.au"))
+		}
+	})
+	t.Run("Publish does not block on a slow client", func(t *testing.T) {
+		broker := NewBroker()
+		broker.Subscribe("test@test.com.au")
+		for i := 0; i < subscriberBuffer*2; i++ {
+			broker.Publish("test@test.com.au", Event{Type: TrackUpdated})
+		}
+	})
+}
+
+func TestStream(t *testing.T) {
+	t.Run("Invalid token", func(t *testing.T) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
+			return nil, http.StatusUnauthorized
+		}
+		request := httptest.NewRequest("GET", "/events", nil)
+		responseRecorder := httptest.NewRecorder()
+
+		Stream(responseRecorder, request)
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
+		}
+	})
+	t.Run("Published events are written to the stream", func(t *testing.T) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
+			claims := &userLogin.Claims{
+				Username:       "test@test.com.au",
+				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
+			}
+			return claims, http.StatusOK
+		}
+		checkTokenVar = requestClaimsVar
+		defer func() { checkTokenVar = userLogin.CheckToken }()
+		server := httptest.NewServer(http.HandlerFunc(Stream))
+		defer server.Close()
+
+		response, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		defer response.Body.Close()
+		if response.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("Want content type 'text/event-stream', got '%s'", response.Header.Get("Content-Type"))
+		}
+
+		// The handler subscribes before it sends the headers
+		Publish("test@test.com.au", Event{Type: PlaylistPosition, Client: "client-1", Data: "playlist"})
+
+		reader := bufio.NewReader(response.Body)
+		line, _ := reader.ReadString('\n')
+		if strings.TrimSpace(line) != "event: "+PlaylistPosition {
+			t.Fatalf("Want event line, got '%s'", line)
+		}
+		line, _ = reader.ReadString('\n')
+		var event Event
+		json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(line), "data: ")), &event)
+		if event.Client != "client-1" || event.Data != "playlist" {
+			t.Errorf("Want the published event, got '%+v'", event)
+		}
+	})
+	t.Run("The stream ends once the token stops working", func(t *testing.T) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
+			return &userLogin.Claims{Username: "test@test.com.au"}, http.StatusOK
+		}
+		interval := keepAliveInterval
+		keepAliveInterval = 10 * time.Millisecond
+		signedOut := make(chan bool, 1)
+		defer func() {
+			keepAliveInterval = interval
+			checkTokenVar = userLogin.CheckToken
+		}()
+		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+			select {
+			case <-signedOut:
+				return nil, http.StatusUnauthorized
+			default:
+				return &userLogin.Claims{Username: "test@test.com.au"}, http.StatusOK
+			}
+		}
+		server := httptest.NewServer(http.HandlerFunc(Stream))
+		defer server.Close()
+
+		response, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		defer response.Body.Close()
+		reader := bufio.NewReader(response.Body)
+		if line, _ := reader.ReadString('\n'); !strings.HasPrefix(line, ": keep-alive") {
+			t.Fatalf("Want a keep-alive while the token works, got '%s'", line)
+		}
+
+		signedOut <- true
+		done := make(chan error)
+		go func() {
+			_, err := ioutil.ReadAll(reader)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("Want the stream closed, got '%s'", err.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Want the stream closed after signing out")
+		}
+	})
+	t.Run("No event is written once the token stops working", func(t *testing.T) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
+			return &userLogin.Claims{Username: "test@test.com.au"}, http.StatusOK
+		}
+		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+			return nil, http.StatusUnauthorized
+		}
+		defer func() { checkTokenVar = userLogin.CheckToken }()
+		server := httptest.NewServer(http.HandlerFunc(Stream))
+		defer server.Close()
+
+		response, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		defer response.Body.Close()
+
+		// Signed out between keep-alives, the next change isn't sent
+		Publish("test@test.com.au", Event{Type: PlaylistPosition, Data: "playlist"})
+		done := make(chan []byte)
+		go func() {
+			body, _ := ioutil.ReadAll(response.Body)
+			done <- body
+		}()
+		select {
+		case body := <-done:
+			if len(body) != 0 {
+				t.Errorf("Want nothing written, got '%s'", body)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Want the stream closed instead of writing the event")
+		}
+	})
+}
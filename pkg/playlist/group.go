@@ -0,0 +1,184 @@
+package playlist
+
+import (
+	"encoding/json"
+	"errors"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"mimpidev/sinkrontrack-server/pkg/userLogin"
+	"net/http"
+)
+
+// The Access of a playlist or track another member of the user's household
+// group made for the group
+const accessGroup = "group"
+
+var errRestricted = webhelper.NewError(webhelper.CodeGroupRestricted, "Your group doesn't allow you to do that")
+var errNotInGroup = errors.New("You are not in that group")
+
+var executeFindGroups = func(filter storage.GroupFilter) ([]*storage.Group, error) {
+	var search storage.Group
+	return search.Find(filter)
+}
+
+// groupMembership returns the user's group and their place in it, both nil
+// when they aren't in one
+func groupMembership(claims *userLogin.Claims) (*storage.Group, *storage.GroupMember, error) {
+	userUuid, err := lookupUserUuidVar(claims.Username)
+	if err != nil {
+		return nil, nil, err
+	}
+	groups, err := executeFindGroups(storage.GroupFilter{MemberUuid: userUuid})
+	if err != nil || len(groups) == 0 {
+		return nil, nil, err
+	}
+	return groups[0], groups[0].Member(userUuid), nil
+}
+
+// getGroupPlaylistByUuid loads a playlist made for the user's group
+func getGroupPlaylistByUuid(uuid string, claims *userLogin.Claims) ([]*Playlist, error) {
+	group, _, err := groupMembership(claims)
+	if err != nil || group == nil {
+		return nil, err
+	}
+	var playlist Playlist
+	playlistResults, err := playlist.Find(storage.PlaylistFilter{Uuid: uuid, GroupUuid: group.Uuid})
+	if err != nil {
+		return nil, err
+	}
+	var playlists []*Playlist
+	for _, sp := range playlistResults {
+		p := &Playlist{}
+		storage.DeepCopy(sp, p)
+		p.Access = accessGroup
+		playlists = append(playlists, p)
+	}
+	return playlists, nil
+}
+
+// applyGroupRestrictions limits what the user can do with a group playlist,
+// their own included. Members restricted from editing only get to read them.
+func applyGroupRestrictions(playlist *Playlist, claims *userLogin.Claims) error {
+	if playlist.GroupUuid == "" || (playlist.Access != "" && playlist.Access != accessGroup) {
+		return nil
+	}
+	group, member, err := groupMembership(claims)
+	if err != nil || group == nil || group.Uuid != playlist.GroupUuid {
+		return err
+	}
+	playlist.restrictions = member.Restrictions
+	if member.Restricted(storage.RestrictEditPlaylists) {
+		playlist.Access = storage.ShareRead
+	}
+	return nil
+}
+
+// getGroupTrackByUuid loads a track from one of the playlists of the user's
+// group
+func getGroupTrackByUuid(uuid string, claims *userLogin.Claims) ([]*Track, error) {
+	group, _, err := groupMembership(claims)
+	if err != nil || group == nil {
+		return nil, err
+	}
+	var playlist Playlist
+	playlistResults, err := playlist.Find(storage.PlaylistFilter{GroupUuid: group.Uuid})
+	if err != nil {
+		return nil, err
+	}
+	for _, sp := range playlistResults {
+		for _, st := range sp.Tracks {
+			if st.Uuid == uuid {
+				t := &Track{}
+				storage.DeepCopy(st, t)
+				t.Access = accessGroup
+				return []*Track{t}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// checkCanRename lets the owner, and the rest of the group for group
+// playlists, rename the playlist
+func checkCanRename(w http.ResponseWriter, r *http.Request, playlist *Playlist) bool {
+	if playlist.Access != "" && playlist.Access != accessGroup {
+		webhelper.ReturnError(w, r, errNotOwner, &[]int{http.StatusForbidden}[0])
+		return false
+	}
+	return true
+}
+
+// checkCanDelete is checkCanRename, unless the user's group restricts them from
+// deleting its playlists
+func checkCanDelete(w http.ResponseWriter, r *http.Request, playlist *Playlist) bool {
+	if !checkCanRename(w, r, playlist) {
+		return false
+	}
+	if playlist.restricted(storage.RestrictDeletePlaylists) {
+		webhelper.ReturnError(w, r, errRestricted, &[]int{http.StatusForbidden}[0])
+		return false
+	}
+	return true
+}
+
+// checkOwnsTrack stops the rest of the group changing a track that is only in
+// the group's playlists, which is still in its owner's library
+func checkOwnsTrack(w http.ResponseWriter, r *http.Request, track *Track) bool {
+	if track.Access != "" {
+		webhelper.ReturnError(w, r, errNotOwner, &[]int{http.StatusForbidden}[0])
+		return false
+	}
+	return true
+}
+
+// checkGroupPlaylist checks the user can make a playlist for the group they
+// asked for
+func checkGroupPlaylist(claims *userLogin.Claims, groupUuid string) (error, *int) {
+	group, member, err := groupMembership(claims)
+	if err != nil {
+		return err, &[]int{http.StatusInternalServerError}[0]
+	}
+	if group == nil || group.Uuid != groupUuid {
+		return errNotInGroup, &[]int{http.StatusForbidden}[0]
+	}
+	if member.Restricted(storage.RestrictEditPlaylists) {
+		return errRestricted, &[]int{http.StatusForbidden}[0]
+	}
+	return nil, nil
+}
+
+// ListGroupPlaylists returns the playlists made for the group in
+// /groups/{uuid}/playlists, to its members
+func ListGroupPlaylists(w http.ResponseWriter, r *http.Request) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+	group, member, err := groupMembership(claims)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	if group == nil || group.Uuid != webhelper.Param(r, "uuid") {
+		webhelper.ReturnError(w, r, storage.ErrGroupNotFound, &[]int{http.StatusNotFound}[0])
+		return
+	}
+
+	var search Playlist
+	playlistResults, err := search.Find(storage.PlaylistFilter{GroupUuid: group.Uuid})
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	playlists := []*Playlist{}
+	for _, sp := range playlistResults {
+		playlist := &Playlist{}
+		storage.DeepCopy(sp, playlist)
+		playlist.Access = accessGroup
+		if member.Restricted(storage.RestrictEditPlaylists) {
+			playlist.Access = storage.ShareRead
+		}
+		playlist.CurrentTrackPosition = trackPosition(playlist.Tracks, playlist.CurrentTrackUuid)
+		playlists = append(playlists, playlist)
+	}
+	json.NewEncoder(w).Encode(playlists)
+}
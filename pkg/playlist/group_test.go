@@ -0,0 +1,195 @@
+package playlist
+
+import (
+	"encoding/json"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"mimpidev/sinkrontrack-server/pkg/userLogin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const groupUuid = "9b0e8d7c-6a5f-4e3d-8c2b-1a0f9e8d7c01"
+
+// groupTest stubs out shareFriend in a group with shareOwner, who made
+// sharePlaylist for the group. It isn't shared with shareFriend.
+func groupTest(restrictions ...string) {
+	shareTest()
+	executeFindGroups = func(filter storage.GroupFilter) ([]*storage.Group, error) {
+		return []*storage.Group{{Id: 1, Uuid: groupUuid, Name: "Home", Members: []*storage.GroupMember{
+			{UserUuid: shareOwner, Manager: true},
+			{UserUuid: shareFriend, Restrictions: restrictions},
+		}}}, nil
+	}
+	executeFindPlaylist = func(filter storage.PlaylistFilter) ([]*Playlist, error) {
+		if filter.OwnerEmail != "" || (filter.GroupUuid != "" && filter.GroupUuid != groupUuid) {
+			return nil, nil
+		}
+		p := &Playlist{}
+		p.Id = 1
+		p.Uuid = sharePlaylist
+		p.GroupUuid = groupUuid
+		p.Tracks = []*storage.Track{{Id: 1, Uuid: orderTrack1}}
+		return []*Playlist{p}, nil
+	}
+}
+
+func groupClaims() *userLogin.Claims {
+	return &userLogin.Claims{
+		Username:       "friend@test.com.au",
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
+	}
+}
+
+func TestGetGroupPlaylistByUrl(t *testing.T) {
+	defer restoreShareTest()
+
+	t.Run("The rest of the group can change a group playlist", func(t *testing.T) {
+		groupTest()
+
+		playlist, err, statusCode := getPlaylistByUuid(sharePlaylist, groupClaims())
+		if *statusCode != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d' '%v'", http.StatusOK, *statusCode, err)
+		}
+		if playlist.Access != accessGroup {
+			t.Errorf("Want access '%s', got '%s'", accessGroup, playlist.Access)
+		}
+	})
+	t.Run("Members restricted from editing can only read it", func(t *testing.T) {
+		groupTest(storage.RestrictEditPlaylists)
+
+		playlist, _, _ := getPlaylistByUuid(sharePlaylist, groupClaims())
+		if playlist.Access != storage.ShareRead {
+			t.Errorf("Want access '%s', got '%s'", storage.ShareRead, playlist.Access)
+		}
+	})
+	t.Run("Someone outside the group can't see it", func(t *testing.T) {
+		groupTest()
+		executeFindGroups = func(filter storage.GroupFilter) ([]*storage.Group, error) {
+			return nil, nil
+		}
+
+		_, _, statusCode := getPlaylistByUuid(sharePlaylist, groupClaims())
+		if *statusCode != http.StatusNotFound {
+			t.Errorf("Want status '%d', got '%d'", http.StatusNotFound, *statusCode)
+		}
+	})
+	t.Run("A track in a group playlist", func(t *testing.T) {
+		groupTest()
+
+		tracks, err := getGroupTrackByUuid(orderTrack1, groupClaims())
+		if err != nil || len(tracks) != 1 || tracks[0].Access != accessGroup {
+			t.Errorf("Want the track with access '%s', got '%+v' '%v'", accessGroup, tracks, err)
+		}
+		tracks, _ = getGroupTrackByUuid(orderTrack2, groupClaims())
+		if len(tracks) != 0 {
+			t.Errorf("Want no track outside the group's playlists, got '%+v'", tracks)
+		}
+	})
+}
+
+func TestGroupAccess(t *testing.T) {
+	defer restoreShareTest()
+
+	tests := []struct {
+		name         string
+		restrictions []string
+		method       string
+		data         string
+		handler      http.HandlerFunc
+		want         int
+	}{
+		{"The group can rename", nil, "PATCH", `{"name":"Ours"}`, UpdatePlaylist, http.StatusOK},
+		{"The group can delete", nil, "DELETE", ``, DeletePlaylist, http.StatusOK},
+		{"Restricted from deleting", []string{storage.RestrictDeletePlaylists}, "DELETE", ``, DeletePlaylist, http.StatusForbidden},
+		{"Restricted from deleting can still rename", []string{storage.RestrictDeletePlaylists}, "PATCH", `{"name":"Ours"}`, UpdatePlaylist, http.StatusOK},
+		{"Restricted from editing", []string{storage.RestrictEditPlaylists}, "PATCH", `{"name":"Ours"}`, UpdatePlaylist, http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			groupTest(test.restrictions...)
+			getPlaylistByUuidVar = getPlaylistByUuid
+			executeUpdatePlaylist = func(p *Playlist, revision uint64) error {
+				return nil
+			}
+			executeDeletePlaylist = func(p *Playlist) error {
+				return nil
+			}
+			request := httptest.NewRequest(test.method, "/playlists/"+sharePlaylist, strings.NewReader(test.data))
+			request = webhelper.WithParams(request, webhelper.Params{"uuid": sharePlaylist})
+			responseRecorder := httptest.NewRecorder()
+
+			test.handler(responseRecorder, request)
+			if responseRecorder.Code != test.want {
+				t.Errorf("Want status '%d', got '%d'", test.want, responseRecorder.Code)
+			}
+		})
+	}
+	t.Run("The group can't edit a track that isn't theirs", func(t *testing.T) {
+		groupTest()
+		getTrackByUuidVar = func(uuid string, claims *userLogin.Claims) (*Track, error, *int) {
+			track := &Track{Access: accessGroup}
+			track.Uuid = uuid
+			return track, nil, &[]int{http.StatusOK}[0]
+		}
+		defer func() { getTrackByUuidVar = getTrackByUuid }()
+		request := httptest.NewRequest("PATCH", "/tracks/"+orderTrack1, strings.NewReader(`{"songName":"Mine"}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": orderTrack1})
+		responseRecorder := httptest.NewRecorder()
+
+		UpdateTrack(responseRecorder, request)
+		if responseRecorder.Code != http.StatusForbidden {
+			t.Errorf("Want status '%d', got '%d'", http.StatusForbidden, responseRecorder.Code)
+		}
+	})
+}
+
+func TestCreateGroupPlaylist(t *testing.T) {
+	defer restoreShareTest()
+	groupTest()
+	executeAddPlaylist = func(m *User, p *Playlist) (*uint64, error) {
+		return &[]uint64{1}[0], nil
+	}
+
+	t.Run("For the user's group", func(t *testing.T) {
+		request := httptest.NewRequest("POST", "/playlists", strings.NewReader(`{"name":"Ours","groupUuid":"`+groupUuid+`"}`))
+		responseRecorder := httptest.NewRecorder()
+
+		CreatePlaylist(responseRecorder, request)
+		var playlist Playlist
+		json.NewDecoder(responseRecorder.Body).Decode(&playlist)
+		if responseRecorder.Code != http.StatusOK || playlist.GroupUuid != groupUuid {
+			t.Errorf("Want the playlist in the group, got '%d' '%+v'", responseRecorder.Code, playlist)
+		}
+	})
+	t.Run("For someone else's group", func(t *testing.T) {
+		request := httptest.NewRequest("POST", "/playlists", strings.NewReader(`{"name":"Ours","groupUuid":"`+sharePlaylist+`"}`))
+		responseRecorder := httptest.NewRecorder()
+
+		CreatePlaylist(responseRecorder, request)
+		if responseRecorder.Code != http.StatusForbidden {
+			t.Errorf("Want status '%d', got '%d'", http.StatusForbidden, responseRecorder.Code)
+		}
+	})
+}
+
+func TestListGroupPlaylists(t *testing.T) {
+	defer restoreShareTest()
+	groupTest(storage.RestrictEditPlaylists)
+
+	request := httptest.NewRequest("GET", "/groups/"+groupUuid+"/playlists", nil)
+	request = webhelper.WithParams(request, webhelper.Params{"uuid": groupUuid})
+	responseRecorder := httptest.NewRecorder()
+
+	ListGroupPlaylists(responseRecorder, request)
+	var playlists []*Playlist
+	json.NewDecoder(responseRecorder.Body).Decode(&playlists)
+	if len(playlists) != 1 || playlists[0].Access != storage.ShareRead {
+		t.Errorf("Want the group playlist read only, got %+v", playlists)
+	}
+}
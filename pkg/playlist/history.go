@@ -0,0 +1,413 @@
+package playlist
+
+import (
+	"encoding/json"
+	"errors"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"mimpidev/sinkrontrack-server/pkg/events"
+	"mimpidev/sinkrontrack-server/pkg/userLogin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type ResumePosition struct {
+	storage.ResumePosition
+}
+
+type Play struct {
+	storage.Play
+}
+
+type ResumePositionData struct {
+	TrackId    string `json:"trackId"`
+	PlaylistId string `json:"playlistId,omitempty"`
+	DeviceId   string `json:"deviceId,omitempty"`
+	Elapsed    int    `json:"elapsed"`
+	UpdatedAt  int64  `json:"updatedAt,omitempty"` // unix seconds
+	Track      *Track `json:"track,omitempty"`     // only set when listing
+}
+
+type UpdateResumePositionData struct {
+	Elapsed    *int   `json:"elapsed"`
+	PlaylistId string `json:"playlistId,omitempty"`
+}
+
+type PlayData struct {
+	Id          uint64 `json:"id"`
+	TrackId     string `json:"trackId"`
+	PlaylistId  string `json:"playlistId,omitempty"`
+	DeviceId    string `json:"deviceId,omitempty"`
+	StartOffset int    `json:"startOffset"`
+	EndOffset   int    `json:"endOffset"`
+	StartedAt   int64  `json:"startedAt"`       // unix seconds
+	EndedAt     int64  `json:"endedAt"`         // unix seconds
+	Track       *Track `json:"track,omitempty"` // only set when listing
+}
+
+type AddPlayData struct {
+	PlaylistId  string `json:"playlistId,omitempty"`
+	StartOffset *int   `json:"startOffset"`
+	EndOffset   *int   `json:"endOffset"`
+	StartedAt   int64  `json:"startedAt,omitempty"` // defaults to now, less the time played
+	EndedAt     int64  `json:"endedAt,omitempty"`   // defaults to now
+}
+
+// A track counts as finished, and drops off the continue list, once the resume
+// position is this close to its TrackLength
+const finishedMargin = 5
+
+const defaultHistoryLimit = 50
+const maxHistoryLimit = 500
+
+var lookupUserUuidVar = lookupUserUuid
+var lookupTrackVar = lookupTrack
+
+var executeSaveResumePosition = func(r *ResumePosition) error {
+	return r.Save()
+}
+
+var executeFindResumePositions = func(filter storage.ResumePositionFilter) ([]*storage.ResumePosition, error) {
+	var search ResumePosition
+	return search.Find(filter)
+}
+
+var executeAddPlay = func(p *Play) (*uint64, error) {
+	return p.Insert()
+}
+
+var executeFindPlays = func(filter storage.PlayFilter) ([]*storage.Play, error) {
+	var search Play
+	return search.Find(filter)
+}
+
+// Positions and plays are kept against the user's uuid, which unlike the email
+// address never changes
+func lookupUserUuid(username string) (string, error) {
+	var user User
+	user.EmailAddress = username
+	err := user.Select()
+	return user.Uuid, err
+}
+
+func lookupTrack(uuid string) (*Track, error) {
+	track := &Track{}
+	track.Uuid = uuid
+	err := track.Select()
+	return track, err
+}
+
+func newResumePositionData(r *storage.ResumePosition) *ResumePositionData {
+	return &ResumePositionData{
+		TrackId:    r.TrackUuid,
+		PlaylistId: r.PlaylistUuid,
+		DeviceId:   r.DeviceUuid,
+		Elapsed:    r.Elapsed,
+		UpdatedAt:  r.UpdatedAt,
+	}
+}
+
+func newPlayData(p *storage.Play) *PlayData {
+	return &PlayData{
+		Id:          p.Id,
+		TrackId:     p.TrackUuid,
+		PlaylistId:  p.PlaylistUuid,
+		DeviceId:    p.DeviceUuid,
+		StartOffset: p.StartOffset,
+		EndOffset:   p.EndOffset,
+		StartedAt:   p.StartedAt,
+		EndedAt:     p.EndedAt,
+	}
+}
+
+func finished(position *storage.ResumePosition, track *Track) bool {
+	return track.TrackLength > 0 && track.TrackLength-position.Elapsed <= finishedMargin
+}
+
+// queryInt reads a non negative integer query parameter, returning fallback
+// when it isn't set
+func queryInt(r *http.Request, name string, fallback int64) (int64, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || number < 0 {
+		return 0, errors.New("Invalid " + name)
+	}
+	return number, nil
+}
+
+func historyLimit(r *http.Request) (int, error) {
+	limit, err := queryInt(r, "limit", defaultHistoryLimit)
+	if err != nil {
+		return 0, err
+	}
+	if limit == 0 || limit > maxHistoryLimit {
+		return 0, errors.New("Limit must be between 1 and " + strconv.Itoa(maxHistoryLimit))
+	}
+	return int(limit), nil
+}
+
+// getHistoryTrack checks the token and the track in /tracks/{uuid}/...
+func getHistoryTrack(w http.ResponseWriter, r *http.Request) (*Track, *userLogin.Claims, string, bool) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return nil, nil, "", false
+	}
+
+	track, err, httpStatus := getTrackByUuidVar(webhelper.Param(r, "uuid"), claims)
+	if err != nil {
+		if webhelper.ReturnError(w, r, err, httpStatus) {
+			return nil, nil, "", false
+		}
+	}
+
+	userUuid, err := lookupUserUuidVar(claims.Username)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return nil, nil, "", false
+	}
+	return track, claims, userUuid, true
+}
+
+// GetResumePosition returns where the user left off in the track, a track that
+// hasn't been played yet starts at 0
+func GetResumePosition(w http.ResponseWriter, r *http.Request) {
+	track, _, userUuid, ok := getHistoryTrack(w, r)
+	if !ok {
+		return
+	}
+
+	positions, err := executeFindResumePositions(storage.ResumePositionFilter{UserUuid: userUuid, TrackUuid: track.Uuid})
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	position := &ResumePositionData{TrackId: track.Uuid}
+	if len(positions) > 0 {
+		position = newResumePositionData(positions[0])
+	}
+	json.NewEncoder(w).Encode(position)
+	return
+}
+
+func UpdateResumePosition(w http.ResponseWriter, r *http.Request) {
+	track, claims, userUuid, ok := getHistoryTrack(w, r)
+	if !ok {
+		return
+	}
+
+	var positionData UpdateResumePositionData
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&positionData)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	if positionData.Elapsed == nil || *positionData.Elapsed < 0 {
+		webhelper.ReturnError(w, r, errors.New("Missing Elapsed"), &[]int{http.StatusBadRequest}[0])
+		return
+	}
+
+	var position ResumePosition
+	position.UserUuid = userUuid
+	position.TrackUuid = track.Uuid
+	position.PlaylistUuid = positionData.PlaylistId
+	position.DeviceUuid = claims.Device
+	position.Elapsed = *positionData.Elapsed
+	position.UpdatedAt = time.Now().Unix()
+	err = executeSaveResumePosition(&position)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+
+	returnPosition := newResumePositionData(&position.ResumePosition)
+	publishEvent(claims.Username, events.Event{Type: events.ResumePosition, Client: claims.Device, Data: returnPosition})
+	json.NewEncoder(w).Encode(returnPosition)
+	return
+}
+
+// AddPlay appends a play of the track to the listening history, and moves the
+// resume position to where the play ended
+func AddPlay(w http.ResponseWriter, r *http.Request) {
+	track, claims, userUuid, ok := getHistoryTrack(w, r)
+	if !ok {
+		return
+	}
+
+	var playData AddPlayData
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&playData)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	if playData.StartOffset == nil || playData.EndOffset == nil {
+		webhelper.ReturnError(w, r, errors.New("Missing Start or End Offset"), &[]int{http.StatusBadRequest}[0])
+		return
+	}
+	if *playData.StartOffset < 0 || *playData.EndOffset < *playData.StartOffset {
+		webhelper.ReturnError(w, r, errors.New("End Offset must not be before Start Offset"), &[]int{http.StatusBadRequest}[0])
+		return
+	}
+
+	now := time.Now().Unix()
+	if playData.EndedAt == 0 {
+		playData.EndedAt = now
+	}
+	if playData.StartedAt == 0 {
+		playData.StartedAt = playData.EndedAt - int64(*playData.EndOffset-*playData.StartOffset)
+	}
+	if playData.EndedAt < playData.StartedAt || playData.EndedAt > now+int64(maxClockSkew.Seconds()) {
+		webhelper.ReturnError(w, r, errors.New("Invalid Play Times"), &[]int{http.StatusBadRequest}[0])
+		return
+	}
+
+	var play Play
+	play.UserUuid = userUuid
+	play.TrackUuid = track.Uuid
+	play.PlaylistUuid = playData.PlaylistId
+	play.DeviceUuid = claims.Device
+	play.StartOffset = *playData.StartOffset
+	play.EndOffset = *playData.EndOffset
+	play.StartedAt = playData.StartedAt
+	play.EndedAt = playData.EndedAt
+	_, err = executeAddPlay(&play)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+
+	// A play uploaded late must not move the position back past a newer one
+	positions, err := executeFindResumePositions(storage.ResumePositionFilter{UserUuid: userUuid, TrackUuid: track.Uuid})
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	if len(positions) == 0 || positions[0].UpdatedAt <= play.EndedAt {
+		var position ResumePosition
+		position.UserUuid = userUuid
+		position.TrackUuid = track.Uuid
+		position.PlaylistUuid = play.PlaylistUuid
+		position.DeviceUuid = claims.Device
+		position.Elapsed = play.EndOffset
+		position.UpdatedAt = play.EndedAt
+		err = executeSaveResumePosition(&position)
+		if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+			return
+		}
+	}
+
+	returnPlay := newPlayData(&play.Play)
+	publishEvent(claims.Username, events.Event{Type: events.TrackPlayed, Client: claims.Device, Data: returnPlay})
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(returnPlay)
+	return
+}
+
+// ListTrackPlays returns the user's plays of one track, most recent first
+func ListTrackPlays(w http.ResponseWriter, r *http.Request) {
+	track, _, userUuid, ok := getHistoryTrack(w, r)
+	if !ok {
+		return
+	}
+	listPlays(w, r, storage.PlayFilter{UserUuid: userUuid, TrackUuid: track.Uuid}, false)
+	return
+}
+
+// ListHistory returns the user's recent plays of every track, most recent
+// first. ?limit and ?since (unix seconds) narrow it down.
+func ListHistory(w http.ResponseWriter, r *http.Request) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+	userUuid, err := lookupUserUuidVar(claims.Username)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	listPlays(w, r, storage.PlayFilter{UserUuid: userUuid}, true)
+	return
+}
+
+func listPlays(w http.ResponseWriter, r *http.Request, filter storage.PlayFilter, withTracks bool) {
+	limit, err := historyLimit(r)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	since, err := queryInt(r, "since", 0)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	filter.Limit = limit
+	filter.Since = since
+
+	plays, err := executeFindPlays(filter)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	tracks := make(map[string]*Track)
+	playList := []*PlayData{}
+	for _, p := range plays {
+		playData := newPlayData(p)
+		if withTracks {
+			if _, ok := tracks[p.TrackUuid]; !ok {
+				track, err := lookupTrackVar(p.TrackUuid)
+				if err != nil {
+					track = nil
+				}
+				tracks[p.TrackUuid] = track
+			}
+			playData.Track = tracks[p.TrackUuid]
+		}
+		playList = append(playList, playData)
+	}
+	json.NewEncoder(w).Encode(playList)
+}
+
+// ContinueListening is "continue where I left off", the tracks the user
+// started but hasn't finished, most recently played first
+func ContinueListening(w http.ResponseWriter, r *http.Request) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+	limit, err := historyLimit(r)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	userUuid, err := lookupUserUuidVar(claims.Username)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+
+	positions, err := executeFindResumePositions(storage.ResumePositionFilter{UserUuid: userUuid})
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	positionList := []*ResumePositionData{}
+	for _, position := range positions {
+		if len(positionList) == limit {
+			break
+		}
+		if position.Elapsed == 0 {
+			continue
+		}
+		track, err := lookupTrackVar(position.TrackUuid)
+		if err == storage.ErrTrackNotFound {
+			continue
+		}
+		if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+			return
+		}
+		if finished(position, track) {
+			continue
+		}
+		positionData := newResumePositionData(position)
+		positionData.Track = track
+		positionList = append(positionList, positionData)
+	}
+	json.NewEncoder(w).Encode(positionList)
+	return
+}
@@ -0,0 +1,298 @@
+package playlist
+
+import (
+	"encoding/json"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"mimpidev/sinkrontrack-server/pkg/events"
+	"mimpidev/sinkrontrack-server/pkg/userLogin"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const historyTrack = "7d1f4a0e-2c55-4f0b-9b4e-8d0c6e3a9f10"
+
+// historyTest stubs out the token, track and user lookups, the resume
+// positions and plays are kept in memory
+func historyTest() (map[string]*storage.ResumePosition, *[]*storage.Play) {
+	requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
+		claims := &userLogin.Claims{
+			Username:       "test@test.com.au",
+			Device:         myDevice,
+			StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
+		}
+		return claims, http.StatusOK
+	}
+	getTrackByUuidVar = func(uuid string, claims *userLogin.Claims) (*Track, error, *int) {
+		t := &Track{}
+		t.Id = 1
+		t.Uuid = historyTrack
+		t.TrackLength = 3600
+		return t, nil, &[]int{http.StatusOK}[0]
+	}
+	lookupTrackVar = func(uuid string) (*Track, error) {
+		t := &Track{}
+		t.Uuid = uuid
+		t.SongName = "Episode " + uuid
+		t.TrackLength = 3600
+		return t, nil
+	}
+	lookupUserUuidVar = func(username string) (string, error) {
+		return "user-1", nil
+	}
+	publishEvent = func(username string, event events.Event) {}
+
+	positions := make(map[string]*storage.ResumePosition)
+	plays := &[]*storage.Play{}
+	executeSaveResumePosition = func(r *ResumePosition) error {
+		stored := r.ResumePosition
+		positions[r.TrackUuid] = &stored
+		return nil
+	}
+	executeFindResumePositions = func(filter storage.ResumePositionFilter) ([]*storage.ResumePosition, error) {
+		var found []*storage.ResumePosition
+		for _, position := range positions {
+			if filter.TrackUuid == "" || position.TrackUuid == filter.TrackUuid {
+				found = append(found, position)
+			}
+		}
+		return found, nil
+	}
+	executeAddPlay = func(p *Play) (*uint64, error) {
+		p.Id = uint64(len(*plays)) + 1
+		stored := p.Play
+		*plays = append(*plays, &stored)
+		return &p.Id, nil
+	}
+	executeFindPlays = func(filter storage.PlayFilter) ([]*storage.Play, error) {
+		return *plays, nil
+	}
+	return positions, plays
+}
+
+func restoreHistoryTest() {
+	getTrackByUuidVar = getTrackByUuid
+	lookupTrackVar = lookupTrack
+	lookupUserUuidVar = lookupUserUuid
+	publishEvent = events.Publish
+}
+
+func TestResumePosition(t *testing.T) {
+	defer restoreHistoryTest()
+
+	t.Run("Invalid token", func(t *testing.T) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
+			return nil, http.StatusUnauthorized
+		}
+		request := httptest.NewRequest("GET", "/tracks/"+historyTrack+"/position", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": historyTrack})
+		responseRecorder := httptest.NewRecorder()
+
+		GetResumePosition(responseRecorder, request)
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
+		}
+	})
+	t.Run("A track that hasn't been played starts at 0", func(t *testing.T) {
+		historyTest()
+		request := httptest.NewRequest("GET", "/tracks/"+historyTrack+"/position", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": historyTrack})
+		responseRecorder := httptest.NewRecorder()
+
+		GetResumePosition(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		var position ResumePositionData
+		json.NewDecoder(responseRecorder.Body).Decode(&position)
+		if position.TrackId != historyTrack || position.Elapsed != 0 {
+			t.Errorf("Want position 0 for the track, got '%+v'", position)
+		}
+	})
+	t.Run("Missing elapsed", func(t *testing.T) {
+		historyTest()
+		request := httptest.NewRequest("PUT", "/tracks/"+historyTrack+"/position", strings.NewReader(`{"playlistId":"abc"}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": historyTrack})
+		responseRecorder := httptest.NewRecorder()
+
+		UpdateResumePosition(responseRecorder, request)
+		if responseRecorder.Code != http.StatusBadRequest {
+			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
+		}
+	})
+	t.Run("Save and read back a position", func(t *testing.T) {
+		positions, _ := historyTest()
+		var published []events.Event
+		publishEvent = func(username string, event events.Event) {
+			published = append(published, event)
+		}
+		request := httptest.NewRequest("PUT", "/tracks/"+historyTrack+"/position", strings.NewReader(`{"elapsed":754}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": historyTrack})
+		responseRecorder := httptest.NewRecorder()
+
+		UpdateResumePosition(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		stored := positions[historyTrack]
+		if stored == nil || stored.Elapsed != 754 || stored.UserUuid != "user-1" || stored.DeviceUuid != myDevice {
+			t.Errorf("Want position stored for the user and device, got '%+v'", stored)
+		}
+		if len(published) != 1 || published[0].Type != events.ResumePosition {
+			t.Errorf("Want one '%s' event, got '%+v'", events.ResumePosition, published)
+		}
+
+		request = httptest.NewRequest("GET", "/tracks/"+historyTrack+"/position", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": historyTrack})
+		responseRecorder = httptest.NewRecorder()
+		GetResumePosition(responseRecorder, request)
+		var position ResumePositionData
+		json.NewDecoder(responseRecorder.Body).Decode(&position)
+		if position.Elapsed != 754 || position.DeviceId != myDevice {
+			t.Errorf("Want the saved position, got '%+v'", position)
+		}
+	})
+}
+
+func TestAddPlay(t *testing.T) {
+	defer restoreHistoryTest()
+
+	t.Run("Missing offsets", func(t *testing.T) {
+		historyTest()
+		request := httptest.NewRequest("POST", "/tracks/"+historyTrack+"/plays", strings.NewReader(`{"startOffset":10}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": historyTrack})
+		responseRecorder := httptest.NewRecorder()
+
+		AddPlay(responseRecorder, request)
+		if responseRecorder.Code != http.StatusBadRequest {
+			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
+		}
+	})
+	t.Run("End before start", func(t *testing.T) {
+		historyTest()
+		request := httptest.NewRequest("POST", "/tracks/"+historyTrack+"/plays", strings.NewReader(`{"startOffset":100,"endOffset":10}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": historyTrack})
+		responseRecorder := httptest.NewRecorder()
+
+		AddPlay(responseRecorder, request)
+		if responseRecorder.Code != http.StatusBadRequest {
+			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
+		}
+	})
+	t.Run("A play is recorded and moves the resume position", func(t *testing.T) {
+		positions, plays := historyTest()
+		request := httptest.NewRequest("POST", "/tracks/"+historyTrack+"/plays", strings.NewReader(`{"startOffset":100,"endOffset":400}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": historyTrack})
+		responseRecorder := httptest.NewRecorder()
+
+		AddPlay(responseRecorder, request)
+		if responseRecorder.Code != http.StatusCreated {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusCreated, responseRecorder.Code)
+		}
+		if len(*plays) != 1 {
+			t.Fatalf("Want 1 play, got %d", len(*plays))
+		}
+		play := (*plays)[0]
+		if play.EndedAt-play.StartedAt != 300 || play.DeviceUuid != myDevice {
+			t.Errorf("Want a 300 second play from the device, got '%+v'", play)
+		}
+		if positions[historyTrack] == nil || positions[historyTrack].Elapsed != 400 {
+			t.Errorf("Want resume position 400, got '%+v'", positions[historyTrack])
+		}
+	})
+	t.Run("A late play doesn't move a newer resume position back", func(t *testing.T) {
+		positions, _ := historyTest()
+		now := time.Now().Unix()
+		positions[historyTrack] = &storage.ResumePosition{TrackUuid: historyTrack, Elapsed: 900, UpdatedAt: now}
+		body := `{"startOffset":100,"endOffset":400,"endedAt":` + strconv.FormatInt(now-3600, 10) + `}`
+		request := httptest.NewRequest("POST", "/tracks/"+historyTrack+"/plays", strings.NewReader(body))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": historyTrack})
+		responseRecorder := httptest.NewRecorder()
+
+		AddPlay(responseRecorder, request)
+		if responseRecorder.Code != http.StatusCreated {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusCreated, responseRecorder.Code)
+		}
+		if positions[historyTrack].Elapsed != 900 {
+			t.Errorf("Want resume position to stay at 900, got %d", positions[historyTrack].Elapsed)
+		}
+	})
+}
+
+func TestListHistory(t *testing.T) {
+	defer restoreHistoryTest()
+
+	t.Run("Invalid limit", func(t *testing.T) {
+		historyTest()
+		request := httptest.NewRequest("GET", "/history?limit=abc", nil)
+		responseRecorder := httptest.NewRecorder()
+
+		ListHistory(responseRecorder, request)
+		if responseRecorder.Code != http.StatusBadRequest {
+			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
+		}
+	})
+	t.Run("Plays are listed with their track", func(t *testing.T) {
+		_, plays := historyTest()
+		var filter storage.PlayFilter
+		executeFindPlays = func(f storage.PlayFilter) ([]*storage.Play, error) {
+			filter = f
+			return *plays, nil
+		}
+		*plays = append(*plays, &storage.Play{Id: 2, TrackUuid: "track-2"}, &storage.Play{Id: 1, TrackUuid: "track-1"})
+		request := httptest.NewRequest("GET", "/history?limit=10&since=100", nil)
+		responseRecorder := httptest.NewRecorder()
+
+		ListHistory(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if filter.UserUuid != "user-1" || filter.Limit != 10 || filter.Since != 100 {
+			t.Errorf("Want the user's plays filtered, got '%+v'", filter)
+		}
+		var history []PlayData
+		json.NewDecoder(responseRecorder.Body).Decode(&history)
+		if len(history) != 2 || history[0].Track == nil || history[0].Track.SongName != "Episode track-2" {
+			t.Errorf("Want 2 plays with tracks, got '%+v'", history)
+		}
+	})
+}
+
+func TestContinueListening(t *testing.T) {
+	defer restoreHistoryTest()
+
+	t.Run("Only started, unfinished tracks are listed", func(t *testing.T) {
+		positions, _ := historyTest()
+		positions["started"] = &storage.ResumePosition{TrackUuid: "started", Elapsed: 120}
+		positions["finished"] = &storage.ResumePosition{TrackUuid: "finished", Elapsed: 3598}
+		positions["untouched"] = &storage.ResumePosition{TrackUuid: "untouched", Elapsed: 0}
+		positions["deleted"] = &storage.ResumePosition{TrackUuid: "deleted", Elapsed: 50}
+		lookupTrackVar = func(uuid string) (*Track, error) {
+			if uuid == "deleted" {
+				return nil, storage.ErrTrackNotFound
+			}
+			t := &Track{}
+			t.Uuid = uuid
+			t.TrackLength = 3600
+			return t, nil
+		}
+		request := httptest.NewRequest("GET", "/continue", nil)
+		responseRecorder := httptest.NewRecorder()
+
+		ContinueListening(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		var continueList []ResumePositionData
+		json.NewDecoder(responseRecorder.Body).Decode(&continueList)
+		if len(continueList) != 1 || continueList[0].TrackId != "started" || continueList[0].Track == nil {
+			t.Errorf("Want only the started track, got '%+v'", continueList)
+		}
+	})
+}
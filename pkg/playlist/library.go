@@ -0,0 +1,204 @@
+package playlist
+
+import (
+	"encoding/json"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"mimpidev/sinkrontrack-server/pkg/events"
+	"mimpidev/sinkrontrack-server/pkg/userLogin"
+	"net/http"
+)
+
+var errTrackInPlaylist = webhelper.NewError(webhelper.CodeTrackExists, "Track is already in the playlist")
+var errTrackInLibrary = webhelper.NewError(webhelper.CodeTrackExists, "A track with that Path or Content Hash is already in the library")
+
+var executeFindTracks = func(filter storage.TrackFilter) ([]*storage.Track, error) {
+	var search Track
+	return search.Find(filter)
+}
+
+var executeUserAddTrack = func(m *User, t *Track) (*uint64, error) {
+	return storage.UserAddTrack(&m.User, &t.Track)
+}
+
+var executeRemoveTrack = func(p *Playlist, t *Track) error {
+	return storage.PlaylistRemoveTrack(&p.Playlist, &t.Track)
+}
+
+func (m *User) AddTrack(t *Track) (*uint64, error) {
+	return executeUserAddTrack(m, t)
+}
+
+func (p *Playlist) RemoveTrack(t *Track) error {
+	return executeRemoveTrack(p, t)
+}
+
+// findLibraryTrack looks for a track already in the user's library, by
+// content hash first as it survives files being moved, then by path
+func findLibraryTrack(username string, trackData *TrackData) (*Track, error) {
+	var filters []storage.TrackFilter
+	if trackData.ContentHash != "" {
+		filters = append(filters, storage.TrackFilter{OwnerEmail: username, ContentHash: trackData.ContentHash})
+	}
+	if trackData.Path != "" {
+		filters = append(filters, storage.TrackFilter{OwnerEmail: username, Path: trackData.Path})
+	}
+	for _, filter := range filters {
+		tracks, err := executeFindTracks(filter)
+		if err != nil {
+			return nil, err
+		}
+		if len(tracks) > 0 {
+			track := &Track{}
+			storage.DeepCopy(tracks[0], track)
+			return track, nil
+		}
+	}
+	return nil, nil
+}
+
+// libraryTrack returns the library track matching trackData, adding a new one
+// when there isn't one yet. created is false when an existing track was found.
+func libraryTrack(claims *userLogin.Claims, trackData *TrackData) (track *Track, created bool, err error, httpStatus *int) {
+	track, err = findLibraryTrack(claims.Username, trackData)
+	if err != nil {
+		return nil, false, err, &[]int{http.StatusInternalServerError}[0]
+	}
+	if track != nil {
+		return track, false, nil, &[]int{http.StatusOK}[0]
+	}
+
+	var user *User
+	user = new(User)
+	user.EmailAddress = claims.Username
+	err = user.Select()
+	if err != nil {
+		return nil, false, err, &[]int{http.StatusUnauthorized}[0]
+	}
+
+	track = &Track{}
+	storage.DeepCopy(trackData, track)
+	_, err = user.AddTrack(track)
+	if err != nil {
+		return nil, false, err, &[]int{http.StatusBadRequest}[0]
+	}
+	return track, true, nil, &[]int{http.StatusCreated}[0]
+}
+
+// getLibraryTrack loads a track by uuid, it must be in the user's library
+func getLibraryTrack(claims *userLogin.Claims, uuid string) (*Track, error, *int) {
+	tracks, err := executeFindTracks(storage.TrackFilter{Uuid: uuid, OwnerEmail: claims.Username})
+	if err != nil {
+		return nil, err, &[]int{http.StatusInternalServerError}[0]
+	}
+	if len(tracks) != 1 {
+		return nil, storage.ErrTrackNotFound, &[]int{http.StatusNotFound}[0]
+	}
+	track := &Track{}
+	storage.DeepCopy(tracks[0], track)
+	return track, nil, &[]int{http.StatusOK}[0]
+}
+
+// checkLibraryConflict stops an edit giving a track the path or content hash
+// of another track in the library
+func checkLibraryConflict(username string, track *Track, trackData *TrackData) (error, *int) {
+	var changed TrackData
+	if trackData.Path != "" && trackData.Path != track.Path {
+		changed.Path = trackData.Path
+	}
+	if trackData.ContentHash != "" && trackData.ContentHash != track.ContentHash {
+		changed.ContentHash = trackData.ContentHash
+	}
+	existing, err := findLibraryTrack(username, &changed)
+	if err != nil {
+		return err, &[]int{http.StatusInternalServerError}[0]
+	}
+	if existing != nil && existing.Uuid != track.Uuid {
+		return errTrackInLibrary, &[]int{http.StatusConflict}[0]
+	}
+	return nil, &[]int{http.StatusOK}[0]
+}
+
+// ListTracks returns the user's track library, ?search narrows it down to
+// tracks with the text in the song, artist, album or path
+func ListTracks(w http.ResponseWriter, r *http.Request) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+
+	tracks, err := executeFindTracks(storage.TrackFilter{OwnerEmail: claims.Username, Search: r.URL.Query().Get("search")})
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	if tracks == nil {
+		tracks = []*storage.Track{}
+	}
+	json.NewEncoder(w).Encode(tracks)
+	return
+}
+
+// CreateTrack adds a track to the user's library. A track already there with
+// the same path or content hash is returned instead, with a 200 not a 201.
+func CreateTrack(w http.ResponseWriter, r *http.Request) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+
+	var trackData TrackData
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(&trackData)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+
+	track, created, err, httpStatus := libraryTrack(claims, &trackData)
+	if webhelper.ReturnError(w, r, err, httpStatus) {
+		return
+	}
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(track)
+	return
+}
+
+// RemoveTrack takes a track out of the playlist, it stays in the library and
+// any other playlist it is in
+func RemoveTrack(w http.ResponseWriter, r *http.Request) {
+	playlist, claims, ok := getOrderedPlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	trackUuid, err := webhelper.UuidParam(r, "trackUuid")
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	position := trackPosition(playlist.Tracks, trackUuid)
+	if position == 0 {
+		webhelper.ReturnError(w, r, errTrackNotInPlaylist, &[]int{http.StatusNotFound}[0])
+		return
+	}
+
+	track := &Track{}
+	storage.DeepCopy(playlist.Tracks[position-1], track)
+	err = playlist.RemoveTrack(track)
+	if err == storage.ErrTrackNotFound {
+		webhelper.ReturnError(w, r, errTrackNotInPlaylist, &[]int{http.StatusNotFound}[0])
+		return
+	}
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+
+	playlist.CurrentTrackPosition = trackPosition(playlist.Tracks, playlist.CurrentTrackUuid)
+	publishPlaylistEvent(claims, playlist, events.Event{Type: events.PlaylistTracks, Client: claims.Device, Data: playlist})
+	json.NewEncoder(w).Encode(playlist)
+	return
+}
@@ -0,0 +1,301 @@
+package playlist
+
+import (
+	"encoding/json"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"mimpidev/sinkrontrack-server/pkg/events"
+	"mimpidev/sinkrontrack-server/pkg/userLogin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	libraryTrack1 = "9b3c2f1e-5d4a-4e8b-8c7d-6e5f4a3b2c01"
+	libraryTrack2 = "9b3c2f1e-5d4a-4e8b-8c7d-6e5f4a3b2c02"
+	libraryNew    = "9b3c2f1e-5d4a-4e8b-8c7d-6e5f4a3b2c99"
+)
+
+// libraryTest stubs out a library of two tracks, tracks added to it are kept
+// in memory
+func libraryTest() *[]*storage.Track {
+	lockTest("", 0)
+	library := &[]*storage.Track{
+		{Id: 1, Uuid: libraryTrack1, Path: "Artist/Album/01 - Opening.mp3", SongName: "Opening", ArtistName: "Artist", ContentHash: "aaaa"},
+		{Id: 2, Uuid: libraryTrack2, Path: "Artist/Album/02 - Closing.mp3", SongName: "Closing", ArtistName: "Artist"},
+	}
+	executeFindTracks = func(filter storage.TrackFilter) ([]*storage.Track, error) {
+		var found []*storage.Track
+		for _, t := range *library {
+			if filter.Matches(t) {
+				found = append(found, t)
+			}
+		}
+		return found, nil
+	}
+	executeSelectUser = func(m *User) error {
+		m.Id = 1
+		return nil
+	}
+	executeUserAddTrack = func(m *User, t *Track) (*uint64, error) {
+		t.Id = uint64(len(*library)) + 1
+		t.Uuid = libraryNew
+		stored := t.Track
+		*library = append(*library, &stored)
+		return &t.Id, nil
+	}
+	executeAddTrack = func(p *Playlist, t *Track) (*uint64, error) {
+		p.Tracks = append(p.Tracks, &t.Track)
+		return &p.Id, nil
+	}
+	executeFindPlaylist = func(filter storage.PlaylistFilter) ([]*Playlist, error) {
+		return nil, nil
+	}
+	executeRemoveTrack = func(p *Playlist, t *Track) error {
+		var tracks []*storage.Track
+		for _, pt := range p.Tracks {
+			if pt.Uuid != t.Uuid {
+				tracks = append(tracks, pt)
+			}
+		}
+		p.Tracks = tracks
+		return nil
+	}
+	return library
+}
+
+func TestListTracks(t *testing.T) {
+	defer restoreLockTest()
+
+	t.Run("List the whole library", func(t *testing.T) {
+		libraryTest()
+		request := httptest.NewRequest("GET", "/tracks", nil)
+		responseRecorder := httptest.NewRecorder()
+
+		ListTracks(responseRecorder, request)
+		var tracks []*storage.Track
+		json.NewDecoder(responseRecorder.Body).Decode(&tracks)
+		if responseRecorder.Code != http.StatusOK || len(tracks) != 2 {
+			t.Errorf("Want status '%d' and 2 tracks, got '%d' and %d", http.StatusOK, responseRecorder.Code, len(tracks))
+		}
+	})
+	t.Run("Search the library", func(t *testing.T) {
+		libraryTest()
+		request := httptest.NewRequest("GET", "/tracks?search=closing", nil)
+		responseRecorder := httptest.NewRecorder()
+
+		ListTracks(responseRecorder, request)
+		var tracks []*storage.Track
+		json.NewDecoder(responseRecorder.Body).Decode(&tracks)
+		if len(tracks) != 1 || tracks[0].Uuid != libraryTrack2 {
+			t.Errorf("Want only the closing track, got %+v", tracks)
+		}
+	})
+	t.Run("No matches is an empty list", func(t *testing.T) {
+		libraryTest()
+		request := httptest.NewRequest("GET", "/tracks?search=nothing", nil)
+		responseRecorder := httptest.NewRecorder()
+
+		ListTracks(responseRecorder, request)
+		if body := strings.TrimSpace(responseRecorder.Body.String()); body != "[]" {
+			t.Errorf("Want an empty list, got '%s'", body)
+		}
+	})
+}
+
+func TestCreateTrack(t *testing.T) {
+	defer restoreLockTest()
+
+	t.Run("A new path is added to the library", func(t *testing.T) {
+		library := libraryTest()
+		data := `{"path":"Artist/Album/03 - Encore.mp3","songName":"Encore"}`
+		request := httptest.NewRequest("POST", "/tracks", strings.NewReader(data))
+		responseRecorder := httptest.NewRecorder()
+
+		CreateTrack(responseRecorder, request)
+		if responseRecorder.Code != http.StatusCreated {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusCreated, responseRecorder.Code)
+		}
+		if len(*library) != 3 || (*library)[2].SongName != "Encore" {
+			t.Errorf("Want the track stored in the library, got %+v", *library)
+		}
+	})
+	t.Run("A known path returns the library track", func(t *testing.T) {
+		library := libraryTest()
+		data := `{"path":"Artist/Album/02 - Closing.mp3","songName":"Closing"}`
+		request := httptest.NewRequest("POST", "/tracks", strings.NewReader(data))
+		responseRecorder := httptest.NewRecorder()
+
+		CreateTrack(responseRecorder, request)
+		var track Track
+		json.NewDecoder(responseRecorder.Body).Decode(&track)
+		if responseRecorder.Code != http.StatusOK || track.Uuid != libraryTrack2 {
+			t.Errorf("Want status '%d' and track '%s', got '%d' and '%s'", http.StatusOK, libraryTrack2, responseRecorder.Code, track.Uuid)
+		}
+		if len(*library) != 2 {
+			t.Errorf("Want no new track in the library, got %d tracks", len(*library))
+		}
+	})
+	t.Run("A known content hash returns the library track", func(t *testing.T) {
+		libraryTest()
+		data := `{"path":"Moved/01 - Opening.mp3","contentHash":"aaaa"}`
+		request := httptest.NewRequest("POST", "/tracks", strings.NewReader(data))
+		responseRecorder := httptest.NewRecorder()
+
+		CreateTrack(responseRecorder, request)
+		var track Track
+		json.NewDecoder(responseRecorder.Body).Decode(&track)
+		if responseRecorder.Code != http.StatusOK || track.Uuid != libraryTrack1 {
+			t.Errorf("Want status '%d' and track '%s', got '%d' and '%s'", http.StatusOK, libraryTrack1, responseRecorder.Code, track.Uuid)
+		}
+	})
+}
+
+func TestAddLibraryTrack(t *testing.T) {
+	defer restoreLockTest()
+
+	t.Run("Add a library track by uuid", func(t *testing.T) {
+		libraryTest()
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/track", strings.NewReader(`{"track":"`+libraryTrack2+`"}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		AddTrack(responseRecorder, request)
+		var track Track
+		json.NewDecoder(responseRecorder.Body).Decode(&track)
+		if responseRecorder.Code != http.StatusOK || track.Uuid != libraryTrack2 {
+			t.Errorf("Want status '%d' and track '%s', got '%d' and '%s'", http.StatusOK, libraryTrack2, responseRecorder.Code, track.Uuid)
+		}
+	})
+	t.Run("Unknown library track", func(t *testing.T) {
+		libraryTest()
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/track", strings.NewReader(`{"track":"`+libraryNew+`"}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		AddTrack(responseRecorder, request)
+		if responseRecorder.Code != http.StatusNotFound {
+			t.Errorf("Want status '%d', got '%d'", http.StatusNotFound, responseRecorder.Code)
+		}
+	})
+	t.Run("A known path reuses the library track", func(t *testing.T) {
+		library := libraryTest()
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/track", strings.NewReader(`{"path":"Artist/Album/01 - Opening.mp3"}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		AddTrack(responseRecorder, request)
+		var track Track
+		json.NewDecoder(responseRecorder.Body).Decode(&track)
+		if track.Uuid != libraryTrack1 || len(*library) != 2 {
+			t.Errorf("Want track '%s' reused, got '%s' and %d library tracks", libraryTrack1, track.Uuid, len(*library))
+		}
+	})
+	t.Run("A track already in the playlist is a conflict", func(t *testing.T) {
+		libraryTest()
+		getPlaylistByUuidVar = func(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
+			p := &Playlist{}
+			p.Id = 1
+			p.Tracks = []*storage.Track{{Id: 1, Uuid: libraryTrack1}}
+			return p, nil, &[]int{http.StatusOK}[0]
+		}
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/track", strings.NewReader(`{"track":"`+libraryTrack1+`"}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		AddTrack(responseRecorder, request)
+		if responseRecorder.Code != http.StatusConflict {
+			t.Errorf("Want status '%d', got '%d'", http.StatusConflict, responseRecorder.Code)
+		}
+	})
+}
+
+func TestUpdateLibraryTrack(t *testing.T) {
+	defer restoreLockTest()
+	defer func() { getTrackByUuidVar = getTrackByUuid }()
+
+	libraryTest()
+	getTrackByUuidVar = func(uuid string, claims *userLogin.Claims) (*Track, error, *int) {
+		track := &Track{}
+		track.Id = 2
+		track.Uuid = libraryTrack2
+		track.Path = "Artist/Album/02 - Closing.mp3"
+		return track, nil, &[]int{http.StatusOK}[0]
+	}
+	executeUpdateTrack = func(t *Track) error {
+		return nil
+	}
+
+	t.Run("Moving onto another track's path is a conflict", func(t *testing.T) {
+		request := httptest.NewRequest("PATCH", "/tracks/"+libraryTrack2, strings.NewReader(`{"path":"Artist/Album/01 - Opening.mp3"}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": libraryTrack2})
+		responseRecorder := httptest.NewRecorder()
+
+		UpdateTrack(responseRecorder, request)
+		if responseRecorder.Code != http.StatusConflict {
+			t.Errorf("Want status '%d', got '%d'", http.StatusConflict, responseRecorder.Code)
+		}
+	})
+	t.Run("Keeping the track's own path", func(t *testing.T) {
+		request := httptest.NewRequest("PATCH", "/tracks/"+libraryTrack2, strings.NewReader(`{"path":"Artist/Album/02 - Closing.mp3","songName":"Closer"}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": libraryTrack2})
+		responseRecorder := httptest.NewRecorder()
+
+		UpdateTrack(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Errorf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+	})
+}
+
+func TestRemoveTrack(t *testing.T) {
+	defer restoreLockTest()
+
+	playlistWithTracks := func(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
+		p := &Playlist{}
+		p.Id = 1
+		p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
+		p.CurrentTrackUuid = libraryTrack2
+		p.Tracks = []*storage.Track{{Id: 1, Uuid: libraryTrack1}, {Id: 2, Uuid: libraryTrack2}}
+		return p, nil, &[]int{http.StatusOK}[0]
+	}
+
+	t.Run("Remove a track from the playlist", func(t *testing.T) {
+		libraryTest()
+		getPlaylistByUuidVar = playlistWithTracks
+		var published []events.Event
+		publishEvent = func(username string, event events.Event) {
+			published = append(published, event)
+		}
+		request := httptest.NewRequest("DELETE", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/tracks/"+libraryTrack1, nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4", "trackUuid": libraryTrack1})
+		responseRecorder := httptest.NewRecorder()
+
+		RemoveTrack(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		var playlist Playlist
+		json.NewDecoder(responseRecorder.Body).Decode(&playlist)
+		if len(playlist.Tracks) != 1 || playlist.CurrentTrackPosition != 1 {
+			t.Errorf("Want one track left and current, got %d tracks at position %d", len(playlist.Tracks), playlist.CurrentTrackPosition)
+		}
+		if len(published) != 1 || published[0].Type != events.PlaylistTracks {
+			t.Errorf("Want one '%s' event, got '%+v'", events.PlaylistTracks, published)
+		}
+	})
+	t.Run("Track is not in the playlist", func(t *testing.T) {
+		libraryTest()
+		getPlaylistByUuidVar = playlistWithTracks
+		request := httptest.NewRequest("DELETE", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/tracks/"+libraryNew, nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4", "trackUuid": libraryNew})
+		responseRecorder := httptest.NewRecorder()
+
+		RemoveTrack(responseRecorder, request)
+		if responseRecorder.Code != http.StatusNotFound {
+			t.Errorf("Want status '%d', got '%d'", http.StatusNotFound, responseRecorder.Code)
+		}
+	})
+}
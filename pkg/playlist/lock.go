@@ -0,0 +1,203 @@
+package playlist
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"mimpidev/sinkrontrack-server/pkg/events"
+	"mimpidev/sinkrontrack-server/pkg/userLogin"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+type LockRequest struct {
+	TTL   *int64 `json:"ttl,omitempty"`   // seconds, defaults to PLAYLIST_LOCK_TTL
+	Force bool   `json:"force,omitempty"` // owner only, take the lock from another device
+}
+
+type LockData struct {
+	Playlist string                `json:"playlist"`
+	Locked   bool                  `json:"locked"`
+	Device   *userLogin.DeviceData `json:"device,omitempty"`
+	Expires  int64                 `json:"expires,omitempty"` // unix seconds
+	Mine     bool                  `json:"mine,omitempty"`    // held by the device making the request
+}
+
+const defaultLockTTL = 10 * 60
+const minLockTTL = 10
+
+var errPlaylistLocked = webhelper.NewError(webhelper.CodePlaylistLocked, "Playlist is locked by another device")
+var errLockNeedsDevice = errors.New("Only a signed in device can take the lock, not an API key")
+
+var isPlaylistOwnerVar = isPlaylistOwner
+
+// lockTTL is the longest a lock is held without a heartbeat, in seconds. It
+// can be set with PLAYLIST_LOCK_TTL and a client can ask for a shorter one.
+func lockTTL() int64 {
+	ttl, err := strconv.ParseInt(os.Getenv("PLAYLIST_LOCK_TTL"), 10, 64)
+	if err != nil || ttl < minLockTTL {
+		return defaultLockTTL
+	}
+	return ttl
+}
+
+// lockedByOther reports whether another device holds an unexpired lock
+func lockedByOther(playlist *Playlist, claims *userLogin.Claims) bool {
+	return playlist.LockDeviceUuid != "" &&
+		playlist.LockDeviceUuid != claims.Device &&
+		playlist.ClientLockExpires > time.Now().Unix()
+}
+
+func isPlaylistOwner(username string, playlist *Playlist) bool {
+	var search Playlist
+	playlists, err := search.Find(storage.PlaylistFilter{Uuid: playlist.Uuid, OwnerEmail: username})
+	return err == nil && len(playlists) == 1
+}
+
+func newLockData(playlist *Playlist, claims *userLogin.Claims) *LockData {
+	lock := &LockData{Playlist: playlist.Uuid}
+	if playlist.LockDeviceUuid == "" ||
+		playlist.ClientLockExpires <= time.Now().Unix() {
+		return lock
+	}
+	lock.Locked = true
+	lock.Expires = playlist.ClientLockExpires
+	lock.Mine = playlist.LockDeviceUuid == claims.Device
+	device, err := lookupDeviceVar(playlist.LockDeviceUuid)
+	if err == nil {
+		lock.Device = userLogin.NewDeviceData(device)
+	}
+	return lock
+}
+
+// returnLocked turns the request away with a 423, the details has the lock
+func returnLocked(w http.ResponseWriter, r *http.Request, playlist *Playlist, claims *userLogin.Claims) {
+	webhelper.ReturnErrorDetails(w, r, errPlaylistLocked, &[]int{http.StatusLocked}[0], newLockData(playlist, claims))
+}
+
+func getLockedPlaylist(w http.ResponseWriter, r *http.Request) (*Playlist, *userLogin.Claims, bool) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return nil, nil, false
+	}
+
+	playlist, err, httpStatus := getPlaylistByUuidVar(webhelper.Param(r, "uuid"), claims)
+	if err != nil {
+		if webhelper.ReturnError(w, r, err, httpStatus) {
+			return nil, nil, false
+		}
+	}
+	return playlist, claims, true
+}
+
+// storeLock saves the lock held by the playlist, replacing heldBy. When
+// another device changed the lock in the meantime the current lock is
+// returned with a 423.
+func storeLock(w http.ResponseWriter, r *http.Request, playlist *Playlist, claims *userLogin.Claims, heldBy string) bool {
+	err := playlist.UpdateLock(heldBy)
+	if err == storage.ErrLockConflict {
+		current, _, _ := getPlaylistByUuidVar(webhelper.Param(r, "uuid"), claims)
+		returnLocked(w, r, current, claims)
+		return false
+	}
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return false
+	}
+	if playlist.LockDeviceUuid != heldBy {
+		publishPlaylistEvent(claims, playlist, events.Event{Type: events.PlaylistLock, Client: claims.Device, Data: newLockData(playlist, claims)})
+	}
+	return true
+}
+
+func GetLock(w http.ResponseWriter, r *http.Request) {
+	playlist, claims, ok := getLockedPlaylist(w, r)
+	if !ok {
+		return
+	}
+	json.NewEncoder(w).Encode(newLockData(playlist, claims))
+	return
+}
+
+// AcquireLock claims the playlist for the calling device, calling it again
+// while holding the lock renews it (heartbeat)
+func AcquireLock(w http.ResponseWriter, r *http.Request) {
+	playlist, claims, ok := getLockedPlaylist(w, r)
+	if !ok || !checkCanChange(w, r, playlist) {
+		return
+	}
+	if claims.Device == "" {
+		webhelper.ReturnError(w, r, errLockNeedsDevice, &[]int{http.StatusForbidden}[0])
+		return
+	}
+
+	var lockRequest LockRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&lockRequest)
+	if err != nil && err != io.EOF {
+		webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0])
+		return
+	}
+
+	ttl := lockTTL()
+	if lockRequest.TTL != nil {
+		if *lockRequest.TTL < minLockTTL || *lockRequest.TTL > ttl {
+			err := errors.New("Lock ttl must be between " + strconv.Itoa(minLockTTL) + " and " + strconv.FormatInt(ttl, 10) + " seconds")
+			webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0])
+			return
+		}
+		ttl = *lockRequest.TTL
+	}
+
+	if lockedByOther(playlist, claims) {
+		if !lockRequest.Force {
+			returnLocked(w, r, playlist, claims)
+			return
+		}
+		if !isPlaylistOwnerVar(claims.Username, playlist) {
+			err := errors.New("Only the playlist owner can take over the lock")
+			webhelper.ReturnError(w, r, err, &[]int{http.StatusForbidden}[0])
+			return
+		}
+	}
+
+	heldBy := playlist.LockDeviceUuid
+	playlist.LockDeviceUuid = claims.Device
+	playlist.ClientLockExpires = time.Now().Unix() + ttl
+	if !storeLock(w, r, playlist, claims, heldBy) {
+		return
+	}
+	json.NewEncoder(w).Encode(newLockData(playlist, claims))
+	return
+}
+
+// ReleaseLock gives up the calling device's lock, the owner can also release
+// a lock held by another device
+func ReleaseLock(w http.ResponseWriter, r *http.Request) {
+	playlist, claims, ok := getLockedPlaylist(w, r)
+	if !ok || !checkCanChange(w, r, playlist) {
+		return
+	}
+
+	if lockedByOther(playlist, claims) &&
+		!isPlaylistOwnerVar(claims.Username, playlist) {
+		returnLocked(w, r, playlist, claims)
+		return
+	}
+
+	heldBy := playlist.LockDeviceUuid
+	if heldBy != "" {
+		playlist.LockDeviceUuid = ""
+		playlist.ClientLockExpires = 0
+		if !storeLock(w, r, playlist, claims, heldBy) {
+			return
+		}
+	}
+	json.NewEncoder(w).Encode(newLockData(playlist, claims))
+	return
+}
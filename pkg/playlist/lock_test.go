@@ -0,0 +1,366 @@
+package playlist
+
+import (
+	"encoding/json"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"mimpidev/sinkrontrack-server/pkg/events"
+	"mimpidev/sinkrontrack-server/pkg/userLogin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var executeUpdateLock func(p *Playlist, heldBy string) error
+
+func (p *Playlist) UpdateLock(heldBy string) error {
+	return executeUpdateLock(p, heldBy)
+}
+
+const (
+	myDevice    = "0b5c6d2e-54c2-4bd8-9d5c-0f4a4f1c3a11"
+	otherDevice = "5e0c1f5a-4d33-4c43-a3f7-2f9e2b0c1d22"
+)
+
+// lockTest stubs out the token and storage calls for a playlist locked by
+// lockedBy until expires
+func lockTest(lockedBy string, expires int64) {
+	requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
+		claims := &userLogin.Claims{
+			Username:       "test@test.com.au",
+			Device:         myDevice,
+			StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
+		}
+		return claims, http.StatusOK
+	}
+	getPlaylistByUuidVar = func(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
+		p := &Playlist{}
+		p.Id = 1
+		p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
+		p.LockDeviceUuid = lockedBy
+		p.ClientLockExpires = expires
+		return p, nil, &[]int{http.StatusOK}[0]
+	}
+	lookupDeviceVar = func(uuid string) (*storage.Device, error) {
+		return &storage.Device{Id: 1, Uuid: uuid, Name: "Kitchen Speaker"}, nil
+	}
+	isPlaylistOwnerVar = func(username string, playlist *Playlist) bool {
+		return true
+	}
+	publishEvent = func(username string, event events.Event) {}
+	playlistFollowersVar = func(claims *userLogin.Claims, playlist *Playlist) []string {
+		return []string{claims.Username}
+	}
+}
+
+func restoreLockTest() {
+	getPlaylistByUuidVar = getPlaylistByUuid
+	getTrackByUuidVar = getTrackByUuid
+	lookupDeviceVar = lookupDevice
+	isPlaylistOwnerVar = isPlaylistOwner
+	publishEvent = events.Publish
+	playlistFollowersVar = playlistFollowers
+}
+
+func TestLockTTL(t *testing.T) {
+	t.Setenv("PLAYLIST_LOCK_TTL", "")
+	if lockTTL() != defaultLockTTL {
+		t.Errorf("Want default ttl %d, got %d", defaultLockTTL, lockTTL())
+	}
+	t.Setenv("PLAYLIST_LOCK_TTL", "120")
+	if lockTTL() != 120 {
+		t.Errorf("Want ttl %d, got %d", 120, lockTTL())
+	}
+	t.Setenv("PLAYLIST_LOCK_TTL", "1")
+	if lockTTL() != defaultLockTTL {
+		t.Errorf("Want default ttl for a value below the minimum, got %d", lockTTL())
+	}
+}
+
+func TestAcquireLock(t *testing.T) {
+	defer restoreLockTest()
+
+	t.Run("Invalid token", func(t *testing.T) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
+			return nil, http.StatusUnauthorized
+		}
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/lock", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		AcquireLock(responseRecorder, request)
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
+		}
+	})
+	t.Run("An API key has no device to lock to", func(t *testing.T) {
+		lockTest("", 0)
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
+			return &userLogin.Claims{Username: "test@test.com.au", Scopes: []string{userLogin.ScopePositionWrite}}, http.StatusOK
+		}
+		executeUpdateLock = func(p *Playlist, heldBy string) error {
+			t.Error("The lock should not be stored")
+			return nil
+		}
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/lock", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		AcquireLock(responseRecorder, request)
+		if responseRecorder.Code != http.StatusForbidden {
+			t.Errorf("Want status '%d', got '%d'", http.StatusForbidden, responseRecorder.Code)
+		}
+	})
+	t.Run("Unlocked playlist is locked to the device", func(t *testing.T) {
+		lockTest("", 0)
+		var stored *Playlist
+		executeUpdateLock = func(p *Playlist, heldBy string) error {
+			stored = p
+			return nil
+		}
+		var published []events.Event
+		publishEvent = func(username string, event events.Event) {
+			published = append(published, event)
+		}
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/lock", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		AcquireLock(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if stored == nil || stored.LockDeviceUuid != myDevice || stored.ClientLockExpires <= time.Now().Unix() {
+			t.Errorf("Want lock stored for '%s', got '%+v'", myDevice, stored)
+		}
+		var lock LockData
+		json.NewDecoder(responseRecorder.Body).Decode(&lock)
+		if !lock.Locked || !lock.Mine {
+			t.Errorf("Want the lock to be held by the device, got '%+v'", lock)
+		}
+		if len(published) != 1 || published[0].Type != events.PlaylistLock {
+			t.Errorf("Want a '%s' event, got '%+v'", events.PlaylistLock, published)
+		}
+	})
+	t.Run("Requested ttl is used", func(t *testing.T) {
+		lockTest("", 0)
+		var stored *Playlist
+		executeUpdateLock = func(p *Playlist, heldBy string) error {
+			stored = p
+			return nil
+		}
+		var data = `{"ttl":30}`
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/lock", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		AcquireLock(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if stored.ClientLockExpires > time.Now().Unix()+30 {
+			t.Errorf("Want lock to expire within 30 seconds, got %d", stored.ClientLockExpires-time.Now().Unix())
+		}
+	})
+	t.Run("Requested ttl is out of range", func(t *testing.T) {
+		lockTest("", 0)
+		var data = `{"ttl":5}`
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/lock", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		AcquireLock(responseRecorder, request)
+		if responseRecorder.Code != http.StatusBadRequest {
+			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
+		}
+	})
+	t.Run("Heartbeat renews the lock", func(t *testing.T) {
+		expires := time.Now().Add(10 * time.Second).Unix()
+		lockTest(myDevice, expires)
+		var stored *Playlist
+		var storedHeldBy string
+		executeUpdateLock = func(p *Playlist, heldBy string) error {
+			stored = p
+			storedHeldBy = heldBy
+			return nil
+		}
+		publishEvent = func(username string, event events.Event) {
+			t.Errorf("Expected no event for a heartbeat")
+		}
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/lock", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		AcquireLock(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if storedHeldBy != myDevice || stored.ClientLockExpires <= expires {
+			t.Errorf("Want the lock renewed, got '%+v'", stored)
+		}
+	})
+	t.Run("Playlist locked by another device", func(t *testing.T) {
+		lockTest(otherDevice, time.Now().Add(time.Minute).Unix())
+		executeUpdateLock = func(p *Playlist, heldBy string) error {
+			t.Errorf("Expected the lock not to be stored")
+			return nil
+		}
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/lock", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		AcquireLock(responseRecorder, request)
+		if responseRecorder.Code != http.StatusLocked {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusLocked, responseRecorder.Code)
+		}
+		var lock LockData
+		json.NewDecoder(responseRecorder.Body).Decode(&webhelper.ErrorResponse{Details: &lock})
+		if !lock.Locked || lock.Mine || lock.Device == nil || lock.Device.Id != otherDevice {
+			t.Errorf("Want the lock held by '%s', got '%+v'", otherDevice, lock)
+		}
+	})
+	t.Run("Expired lock of another device is taken", func(t *testing.T) {
+		lockTest(otherDevice, time.Now().Add(-time.Minute).Unix())
+		var storedHeldBy string
+		executeUpdateLock = func(p *Playlist, heldBy string) error {
+			storedHeldBy = heldBy
+			return nil
+		}
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/lock", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		AcquireLock(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if storedHeldBy != otherDevice {
+			t.Errorf("Want the lock replaced from '%s', got '%s'", otherDevice, storedHeldBy)
+		}
+	})
+	t.Run("Force takeover by someone other than the owner", func(t *testing.T) {
+		lockTest(otherDevice, time.Now().Add(time.Minute).Unix())
+		isPlaylistOwnerVar = func(username string, playlist *Playlist) bool {
+			return false
+		}
+		var data = `{"force":true}`
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/lock", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		AcquireLock(responseRecorder, request)
+		if responseRecorder.Code != http.StatusForbidden {
+			t.Errorf("Want status '%d', got '%d'", http.StatusForbidden, responseRecorder.Code)
+		}
+	})
+	t.Run("Force takeover by the owner", func(t *testing.T) {
+		lockTest(otherDevice, time.Now().Add(time.Minute).Unix())
+		var stored *Playlist
+		executeUpdateLock = func(p *Playlist, heldBy string) error {
+			stored = p
+			return nil
+		}
+		var data = `{"force":true}`
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/lock", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		AcquireLock(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if stored.LockDeviceUuid != myDevice {
+			t.Errorf("Want the lock taken by '%s', got '%s'", myDevice, stored.LockDeviceUuid)
+		}
+	})
+	t.Run("Lock changed by another device while storing", func(t *testing.T) {
+		lockTest("", 0)
+		executeUpdateLock = func(p *Playlist, heldBy string) error {
+			return storage.ErrLockConflict
+		}
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/lock", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		AcquireLock(responseRecorder, request)
+		if responseRecorder.Code != http.StatusLocked {
+			t.Errorf("Want status '%d', got '%d'", http.StatusLocked, responseRecorder.Code)
+		}
+	})
+}
+
+func TestReleaseLock(t *testing.T) {
+	defer restoreLockTest()
+
+	t.Run("Device releases its lock", func(t *testing.T) {
+		lockTest(myDevice, time.Now().Add(time.Minute).Unix())
+		var stored *Playlist
+		executeUpdateLock = func(p *Playlist, heldBy string) error {
+			stored = p
+			return nil
+		}
+		request := httptest.NewRequest("DELETE", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/lock", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		ReleaseLock(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if stored == nil || stored.LockDeviceUuid != "" || stored.ClientLockExpires != 0 {
+			t.Errorf("Want the lock cleared, got '%+v'", stored)
+		}
+	})
+	t.Run("Lock held by another device can't be released", func(t *testing.T) {
+		lockTest(otherDevice, time.Now().Add(time.Minute).Unix())
+		isPlaylistOwnerVar = func(username string, playlist *Playlist) bool {
+			return false
+		}
+		request := httptest.NewRequest("DELETE", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/lock", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		ReleaseLock(responseRecorder, request)
+		if responseRecorder.Code != http.StatusLocked {
+			t.Errorf("Want status '%d', got '%d'", http.StatusLocked, responseRecorder.Code)
+		}
+	})
+	t.Run("Owner releases another device's lock", func(t *testing.T) {
+		lockTest(otherDevice, time.Now().Add(time.Minute).Unix())
+		var storedHeldBy string
+		executeUpdateLock = func(p *Playlist, heldBy string) error {
+			storedHeldBy = heldBy
+			return nil
+		}
+		request := httptest.NewRequest("DELETE", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/lock", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		ReleaseLock(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if storedHeldBy != otherDevice {
+			t.Errorf("Want the lock released from '%s', got '%s'", otherDevice, storedHeldBy)
+		}
+	})
+	t.Run("Unlocked playlist", func(t *testing.T) {
+		lockTest("", 0)
+		executeUpdateLock = func(p *Playlist, heldBy string) error {
+			t.Errorf("Expected nothing to be stored")
+			return nil
+		}
+		request := httptest.NewRequest("DELETE", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/lock", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		ReleaseLock(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Errorf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+	})
+}
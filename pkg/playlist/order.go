@@ -0,0 +1,175 @@
+package playlist
+
+import (
+	"encoding/json"
+	"errors"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"mimpidev/sinkrontrack-server/pkg/events"
+	"mimpidev/sinkrontrack-server/pkg/userLogin"
+	"net/http"
+)
+
+type ReorderTracksData struct {
+	Tracks []string `json:"tracks"` // every track uuid in the playlist, in the new order
+}
+
+type MoveTrackData struct {
+	Position int `json:"position"` // 1 based
+}
+
+var errInvalidPosition = errors.New("Position is outside the playlist")
+var errTrackNotInPlaylist = errors.New("Track is not in the playlist")
+
+// trackPosition is the 1 based position of the track in tracks, or 0 when it
+// isn't there
+func trackPosition(tracks []*storage.Track, uuid string) int {
+	if uuid == "" {
+		return 0
+	}
+	for i, t := range tracks {
+		if t.Uuid == uuid {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func trackUuids(tracks []*storage.Track) []string {
+	uuids := make([]string, 0, len(tracks))
+	for _, t := range tracks {
+		uuids = append(uuids, t.Uuid)
+	}
+	return uuids
+}
+
+// moveTrack returns uuids with uuid moved to the 1 based position, the tracks
+// in between shuffle along to make room
+func moveTrack(uuids []string, uuid string, position int) []string {
+	moved := make([]string, 0, len(uuids))
+	for _, u := range uuids {
+		if u != uuid {
+			moved = append(moved, u)
+		}
+	}
+	index := position - 1
+	moved = append(moved, "")
+	copy(moved[index+1:], moved[index:])
+	moved[index] = uuid
+	return moved
+}
+
+// findCurrentTrack resolves the current track of a position update, given as
+// a uuid, a position, or both as long as they agree
+func findCurrentTrack(playlist *Playlist, playlistData *UpdatePlaylistData) (string, error) {
+	position := playlistData.CurrentTrackPosition
+	if playlistData.CurrentTrack != "" {
+		found := trackPosition(playlist.Tracks, playlistData.CurrentTrack)
+		if found == 0 {
+			return "", errTrackNotInPlaylist
+		}
+		if position != 0 && position != found {
+			return "", errors.New("Current Track and Current Track Position don't match")
+		}
+		return playlistData.CurrentTrack, nil
+	}
+	if position < 1 || position > len(playlist.Tracks) {
+		return "", errInvalidPosition
+	}
+	return playlist.Tracks[position-1].Uuid, nil
+}
+
+// getOrderedPlaylist loads the playlist from /playlists/{uuid}/tracks[/...],
+// only the device holding the lock can reorder it and only when the playlist
+// isn't shared read only
+func getOrderedPlaylist(w http.ResponseWriter, r *http.Request) (*Playlist, *userLogin.Claims, bool) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return nil, nil, false
+	}
+
+	playlist, err, httpStatus := getPlaylistByUuidVar(webhelper.Param(r, "uuid"), claims)
+	if err != nil {
+		if webhelper.ReturnError(w, r, err, httpStatus) {
+			return nil, nil, false
+		}
+	}
+
+	if !checkCanChange(w, r, playlist) {
+		return nil, nil, false
+	}
+	if lockedByOther(playlist, claims) {
+		returnLocked(w, r, playlist, claims)
+		return nil, nil, false
+	}
+	return playlist, claims, true
+}
+
+// storeTrackOrder saves the new order, a 409 means tracks were added or
+// removed since the playlist was loaded
+func storeTrackOrder(w http.ResponseWriter, r *http.Request, playlist *Playlist, claims *userLogin.Claims, order []string) {
+	err := playlist.ReorderTracks(order)
+	if err == storage.ErrTrackOrder {
+		webhelper.ReturnError(w, r, err, &[]int{http.StatusConflict}[0])
+		return
+	}
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+
+	playlist.CurrentTrackPosition = trackPosition(playlist.Tracks, playlist.CurrentTrackUuid)
+	publishPlaylistEvent(claims, playlist, events.Event{Type: events.PlaylistTracks, Client: claims.Device, Data: playlist})
+	json.NewEncoder(w).Encode(playlist)
+}
+
+// ReorderTracks replaces the order of every track in the playlist at once
+func ReorderTracks(w http.ResponseWriter, r *http.Request) {
+	playlist, claims, ok := getOrderedPlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	var reorderData ReorderTracksData
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&reorderData)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+
+	storeTrackOrder(w, r, playlist, claims, reorderData.Tracks)
+	return
+}
+
+// MoveTrack moves one track of the playlist to a new position
+func MoveTrack(w http.ResponseWriter, r *http.Request) {
+	playlist, claims, ok := getOrderedPlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	trackUuid, err := webhelper.UuidParam(r, "trackUuid")
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	if trackPosition(playlist.Tracks, trackUuid) == 0 {
+		webhelper.ReturnError(w, r, errTrackNotInPlaylist, &[]int{http.StatusNotFound}[0])
+		return
+	}
+
+	var moveData MoveTrackData
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&moveData)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	if moveData.Position < 1 || moveData.Position > len(playlist.Tracks) {
+		webhelper.ReturnError(w, r, errInvalidPosition, &[]int{http.StatusBadRequest}[0])
+		return
+	}
+
+	storeTrackOrder(w, r, playlist, claims, moveTrack(trackUuids(playlist.Tracks), trackUuid, moveData.Position))
+	return
+}
@@ -0,0 +1,212 @@
+package playlist
+
+import (
+	"encoding/json"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"mimpidev/sinkrontrack-server/pkg/events"
+	"mimpidev/sinkrontrack-server/pkg/userLogin"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var executeReorderTracks func(p *Playlist, order []string) error
+
+func (p *Playlist) ReorderTracks(order []string) error {
+	return executeReorderTracks(p, order)
+}
+
+const (
+	orderTrack1 = "2a4e4f4c-6a1b-4c8e-9d3f-1b2c3d4e5f01"
+	orderTrack2 = "2a4e4f4c-6a1b-4c8e-9d3f-1b2c3d4e5f02"
+	orderTrack3 = "2a4e4f4c-6a1b-4c8e-9d3f-1b2c3d4e5f03"
+)
+
+// orderTest stubs out a playlist of three tracks, the second one current,
+// and stores the order it is given
+func orderTest() *[]string {
+	lockTest("", 0)
+	getPlaylistByUuidVar = func(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
+		p := &Playlist{}
+		p.Id = 1
+		p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
+		p.CurrentTrackUuid = orderTrack2
+		for _, uuid := range []string{orderTrack1, orderTrack2, orderTrack3} {
+			p.Tracks = append(p.Tracks, &storage.Track{Uuid: uuid})
+		}
+		return p, nil, &[]int{http.StatusOK}[0]
+	}
+	stored := &[]string{}
+	executeReorderTracks = func(p *Playlist, order []string) error {
+		tracks, err := storage.OrderTracks(p.Tracks, order)
+		if err != nil {
+			return err
+		}
+		p.Tracks = tracks
+		*stored = order
+		return nil
+	}
+	return stored
+}
+
+func TestMoveTrackOrder(t *testing.T) {
+	uuids := []string{"a", "b", "c", "d"}
+	tests := []struct {
+		uuid     string
+		position int
+		want     []string
+	}{
+		{"a", 3, []string{"b", "c", "a", "d"}},
+		{"d", 1, []string{"d", "a", "b", "c"}},
+		{"b", 2, []string{"a", "b", "c", "d"}},
+		{"c", 4, []string{"a", "b", "d", "c"}},
+	}
+	for _, test := range tests {
+		got := moveTrack(uuids, test.uuid, test.position)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Moving '%s' to %d, want %v, got %v", test.uuid, test.position, test.want, got)
+		}
+	}
+}
+
+func TestReorderTracks(t *testing.T) {
+	defer restoreLockTest()
+
+	t.Run("Tracks are stored in the new order and the current track follows", func(t *testing.T) {
+		stored := orderTest()
+		var published []events.Event
+		publishEvent = func(username string, event events.Event) {
+			published = append(published, event)
+		}
+		data := `{"tracks":["` + orderTrack2 + `","` + orderTrack3 + `","` + orderTrack1 + `"]}`
+		request := httptest.NewRequest("PUT", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/tracks", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		ReorderTracks(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if len(*stored) != 3 || (*stored)[0] != orderTrack2 {
+			t.Errorf("Want the new order stored, got %v", *stored)
+		}
+		var playlist Playlist
+		json.NewDecoder(responseRecorder.Body).Decode(&playlist)
+		if playlist.CurrentTrackUuid != orderTrack2 || playlist.CurrentTrackPosition != 1 {
+			t.Errorf("Want the current track at position 1, got '%s' at %d", playlist.CurrentTrackUuid, playlist.CurrentTrackPosition)
+		}
+		if len(published) != 1 || published[0].Type != events.PlaylistTracks {
+			t.Errorf("Want one '%s' event, got '%+v'", events.PlaylistTracks, published)
+		}
+	})
+	t.Run("An order missing a track is a conflict", func(t *testing.T) {
+		orderTest()
+		data := `{"tracks":["` + orderTrack2 + `","` + orderTrack1 + `"]}`
+		request := httptest.NewRequest("PUT", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/tracks", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		ReorderTracks(responseRecorder, request)
+		if responseRecorder.Code != http.StatusConflict {
+			t.Errorf("Want status '%d', got '%d'", http.StatusConflict, responseRecorder.Code)
+		}
+	})
+	t.Run("Playlist is locked by another device", func(t *testing.T) {
+		orderTest()
+		getLocked := getPlaylistByUuidVar
+		getPlaylistByUuidVar = func(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
+			p, err, status := getLocked(uuid, claims)
+			p.LockDeviceUuid = otherDevice
+			p.ClientLockExpires = time.Now().Unix() + 60
+			return p, err, status
+		}
+		executeReorderTracks = func(p *Playlist, order []string) error {
+			t.Error("Order should not be stored while another device holds the lock")
+			return nil
+		}
+		data := `{"tracks":["` + orderTrack2 + `","` + orderTrack3 + `","` + orderTrack1 + `"]}`
+		request := httptest.NewRequest("PUT", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/tracks", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		ReorderTracks(responseRecorder, request)
+		if responseRecorder.Code != http.StatusLocked {
+			t.Errorf("Want status '%d', got '%d'", http.StatusLocked, responseRecorder.Code)
+		}
+	})
+}
+
+func TestMoveTrack(t *testing.T) {
+	defer restoreLockTest()
+
+	t.Run("Move a track", func(t *testing.T) {
+		stored := orderTest()
+		request := httptest.NewRequest("PATCH", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/tracks/"+orderTrack1, strings.NewReader(`{"position":3}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4", "trackUuid": orderTrack1})
+		responseRecorder := httptest.NewRecorder()
+
+		MoveTrack(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		want := []string{orderTrack2, orderTrack3, orderTrack1}
+		if !reflect.DeepEqual(*stored, want) {
+			t.Errorf("Want order %v, got %v", want, *stored)
+		}
+	})
+	t.Run("Position outside the playlist", func(t *testing.T) {
+		orderTest()
+		request := httptest.NewRequest("PATCH", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/tracks/"+orderTrack1, strings.NewReader(`{"position":4}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4", "trackUuid": orderTrack1})
+		responseRecorder := httptest.NewRecorder()
+
+		MoveTrack(responseRecorder, request)
+		if responseRecorder.Code != http.StatusBadRequest {
+			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
+		}
+	})
+	t.Run("Track is not in the playlist", func(t *testing.T) {
+		orderTest()
+		request := httptest.NewRequest("PATCH", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/tracks/48cf9b84-6162-430a-92ac-6804146ad2a9", strings.NewReader(`{"position":1}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4", "trackUuid": "48cf9b84-6162-430a-92ac-6804146ad2a9"})
+		responseRecorder := httptest.NewRecorder()
+
+		MoveTrack(responseRecorder, request)
+		if responseRecorder.Code != http.StatusNotFound {
+			t.Errorf("Want status '%d', got '%d'", http.StatusNotFound, responseRecorder.Code)
+		}
+	})
+}
+
+func TestFindCurrentTrack(t *testing.T) {
+	playlist := &Playlist{}
+	playlist.Tracks = []*storage.Track{{Uuid: orderTrack1}, {Uuid: orderTrack2}}
+	tests := []struct {
+		name    string
+		data    UpdatePlaylistData
+		want    string
+		wantErr bool
+	}{
+		{"By uuid", UpdatePlaylistData{CurrentTrack: orderTrack2}, orderTrack2, false},
+		{"By position", UpdatePlaylistData{CurrentTrackPosition: 1}, orderTrack1, false},
+		{"Uuid and position agree", UpdatePlaylistData{CurrentTrack: orderTrack2, CurrentTrackPosition: 2}, orderTrack2, false},
+		{"Uuid and position disagree", UpdatePlaylistData{CurrentTrack: orderTrack2, CurrentTrackPosition: 1}, "", true},
+		{"Uuid not in the playlist", UpdatePlaylistData{CurrentTrack: orderTrack3}, "", true},
+		{"Position outside the playlist", UpdatePlaylistData{CurrentTrackPosition: 3}, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := findCurrentTrack(playlist, &test.data)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Want error %v, got '%v'", test.wantErr, err)
+			}
+			if got != test.want {
+				t.Errorf("Want current track '%s', got '%s'", test.want, got)
+			}
+		})
+	}
+}
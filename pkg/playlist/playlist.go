@@ -5,6 +5,7 @@ import (
 	"errors"
 	"mimpidev/sinkrontrack-server/internal/storage"
 	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"mimpidev/sinkrontrack-server/pkg/events"
 	"mimpidev/sinkrontrack-server/pkg/userLogin"
 	"net/http"
 	"time"
@@ -12,6 +13,19 @@ import (
 
 type Playlist struct {
 	storage.Playlist
+	CurrentTrackPosition int       `json:"currentTrackPosition,omitempty"` // 1 based position of CurrentTrackUuid in Tracks
+	Lock                 *LockData `json:"lock,omitempty"`
+	Access               string    `json:"access,omitempty"` // blank for the owner, group for the rest of its group, otherwise the access a friend was given
+	restrictions         []string  // what the user's group restricts them from doing with it
+}
+
+func (p *Playlist) restricted(restriction string) bool {
+	for _, r := range p.restrictions {
+		if r == restriction {
+			return true
+		}
+	}
+	return false
 }
 
 type User struct {
@@ -20,6 +34,7 @@ type User struct {
 
 type Track struct {
 	storage.Track
+	Access string `json:"access,omitempty"` // blank for the owner, group when it is only in the user's group's playlists
 }
 
 type TrackData struct {
@@ -28,23 +43,131 @@ type TrackData struct {
 	SongName         string `json:"songName,omitempty"`
 	AlbumName        string `json:"albumName,omitempty"`
 	AlbumTrackNumber int    `json:"albumTrackNumber,omitempty"`
+	ContentHash      string `json:"contentHash,omitempty"`
+}
+
+// AddTrackData adds a track from the library by its uuid, or by its details,
+// which reuse the library track with the same path or content hash
+type AddTrackData struct {
+	TrackData
+	Track    string `json:"track,omitempty"`    // library track uuid
+	Position int    `json:"position,omitempty"` // 1 based, defaults to the end of the playlist
 }
 
 type UpdatePlaylistData struct {
-	Name           string `json:"name,omitempty"`
-	CurrentTrackId uint16 `json:"currentTrack,omitempty"`
-	Elapsed        *int   `json:"elapsed,omitempty"`
+	Name                 string  `json:"name,omitempty"`
+	CurrentTrack         string  `json:"currentTrack,omitempty"`         // track uuid
+	CurrentTrackPosition int     `json:"currentTrackPosition,omitempty"` // or its 1 based position
+	Elapsed              *int    `json:"elapsed,omitempty"`
+	Revision             *uint64 `json:"revision,omitempty"`  // PositionRevision the client last saw
+	Timestamp            *int64  `json:"timestamp,omitempty"` // when the client recorded the position, unix milliseconds
+}
+
+// PositionConflict is the details of the 409 for a stale position update, so
+// the client can carry on from the position the server already has
+type PositionConflict struct {
+	Playlist *Playlist `json:"playlist"`
 }
 
+// How far ahead of the server clock a client timestamp may be
+const maxClockSkew = 5 * time.Minute
+
 type PlaylistData struct {
-	Name string `json:"name,omitempty"`
+	Name      string `json:"name,omitempty"`
+	GroupUuid string `json:"groupUuid,omitempty"` // make the playlist for the user's household group
 }
 
-var checkTokenVar = userLogin.CheckToken
-var isAdminUserVar = storage.IsAdminUser
-var getPlaylistByUrlPath = getPlaylistByUrl
-var getTrackByUrlPath = getTrackByUrl
+var requestClaimsVar = userLogin.RequestClaims
+var getPlaylistByUuidVar = getPlaylistByUuid
+var getTrackByUuidVar = getTrackByUuid
 var copyPlaylist = deepCopyPlaylist
+var publishEvent = events.Publish
+var lookupDeviceVar = lookupDevice
+var playlistFollowersVar = playlistFollowers
+
+var executeFindUsers = func(filter storage.UserFilter) ([]*storage.User, error) {
+	var search storage.User
+	return search.Find(filter)
+}
+
+// lookupDevice loads the device holding a playlist lock
+func lookupDevice(uuid string) (*storage.Device, error) {
+	device := &storage.Device{Uuid: uuid}
+	err := device.Select()
+	return device, err
+}
+
+// playlistFollowers returns the email address of everyone who can see the
+// playlist: the user who changed it, its owner, the friends it is shared with
+// and the members of its group. Anyone who can't be looked up is left out,
+// they will see the change next time they load the playlist.
+func playlistFollowers(claims *userLogin.Claims, playlist *Playlist) []string {
+	followers := []string{claims.Username}
+	seen := map[string]bool{claims.Username: true}
+	follow := func(emailAddress string) {
+		if !seen[emailAddress] {
+			seen[emailAddress] = true
+			followers = append(followers, emailAddress)
+		}
+	}
+
+	if owners, err := executeFindUsers(storage.UserFilter{PlaylistUuid: playlist.Uuid}); err == nil {
+		for _, owner := range owners {
+			follow(owner.EmailAddress)
+		}
+	}
+	var userUuids []string
+	if shares, err := executeFindShares(storage.PlaylistShareFilter{PlaylistUuid: playlist.Uuid}); err == nil {
+		for _, share := range shares {
+			userUuids = append(userUuids, share.UserUuid)
+		}
+	}
+	if playlist.GroupUuid != "" {
+		if groups, err := executeFindGroups(storage.GroupFilter{Uuid: playlist.GroupUuid}); err == nil {
+			for _, group := range groups {
+				for _, member := range group.Members {
+					userUuids = append(userUuids, member.UserUuid)
+				}
+			}
+		}
+	}
+	for _, userUuid := range userUuids {
+		if user, err := lookupUserVar(userUuid); err == nil {
+			follow(user.EmailAddress)
+		}
+	}
+	return followers
+}
+
+// publishPlaylistEvent lets everyone following the playlist know it changed
+func publishPlaylistEvent(claims *userLogin.Claims, playlist *Playlist, event events.Event) {
+	for _, emailAddress := range playlistFollowersVar(claims, playlist) {
+		publishEvent(emailAddress, event)
+	}
+}
+
+// trackFollowers returns the email address of everyone following a playlist
+// the track is in, starting with the user who changed it
+func trackFollowers(claims *userLogin.Claims, track *Track) []string {
+	followers := []string{claims.Username}
+	seen := map[string]bool{claims.Username: true}
+	var search Playlist
+	playlists, err := search.Find(storage.PlaylistFilter{TrackUuid: track.Uuid})
+	if err != nil {
+		return followers
+	}
+	for _, sp := range playlists {
+		playlist := &Playlist{}
+		storage.DeepCopy(sp, playlist)
+		for _, emailAddress := range playlistFollowersVar(claims, playlist) {
+			if !seen[emailAddress] {
+				seen[emailAddress] = true
+				followers = append(followers, emailAddress)
+			}
+		}
+	}
+	return followers
+}
 
 func (p *Playlist) Copy(src *storage.Playlist) {
 	copyPlaylist(src, p)
@@ -54,29 +177,24 @@ func deepCopyPlaylist(src *storage.Playlist, dest *Playlist) {
 	dest.Id = src.Id
 	dest.Uuid = src.Uuid
 	dest.Name = src.Name
-	dest.CurrentTrackId = src.CurrentTrackId
+	dest.CurrentTrackUuid = src.CurrentTrackUuid
 	dest.Elapsed = src.Elapsed
-	dest.ClientIdLock = src.ClientIdLock
+	dest.LockDeviceUuid = src.LockDeviceUuid
 	dest.ClientLockExpires = src.ClientLockExpires
+	dest.PositionRevision = src.PositionRevision
+	dest.PositionUpdatedAt = src.PositionUpdatedAt
+	dest.GroupUuid = src.GroupUuid
 	for _, sTrack := range src.Tracks {
 		dest.Tracks = append(dest.Tracks, sTrack)
 	}
 }
 
 var executeAddPlaylist = func(m *User, p *Playlist) (*uint64, error) {
-	var user *storage.User
-	storage.DeepCopy(m, user)
-	var playlist *storage.Playlist
-	storage.DeepCopy(p, playlist)
-	return storage.UserAddPlaylist(user, playlist)
+	return storage.UserAddPlaylist(&m.User, &p.Playlist)
 }
 
 var executeAddTrack = func(p *Playlist, t *Track) (*uint64, error) {
-	var playlist *storage.Playlist
-	storage.DeepCopy(p, playlist)
-	var track *storage.Track
-	storage.DeepCopy(t, track)
-	return storage.PlaylistAddTrack(playlist, track)
+	return storage.PlaylistAddTrack(&p.Playlist, &t.Track)
 }
 
 func (m *User) AddPlaylist(p *Playlist) (*uint64, error) {
@@ -88,9 +206,9 @@ func (p *Playlist) AddTrack(t *Track) (*uint64, error) {
 }
 
 func CreatePlaylist(w http.ResponseWriter, r *http.Request) {
-	claims, response := checkTokenVar(r)
+	claims, response := requestClaimsVar(r)
 	if response != 200 {
-		w.WriteHeader(response)
+		webhelper.ReturnStatus(w, r, response)
 		return
 	}
 
@@ -106,6 +224,12 @@ func CreatePlaylist(w http.ResponseWriter, r *http.Request) {
 	var playlist *Playlist
 	playlist = &Playlist{}
 	storage.DeepCopy(playlistData, playlist)
+	if playlist.GroupUuid != "" {
+		err, httpStatus := checkGroupPlaylist(claims, playlist.GroupUuid)
+		if webhelper.ReturnError(w, r, err, httpStatus) {
+			return
+		}
+	}
 
 	// Load User from claims
 	var user *User
@@ -126,23 +250,24 @@ func CreatePlaylist(w http.ResponseWriter, r *http.Request) {
 }
 
 func UpdatePlaylist(w http.ResponseWriter, r *http.Request) {
-	claims, response := checkTokenVar(r)
+	claims, response := requestClaimsVar(r)
 	if response != 200 {
-		w.WriteHeader(response)
+		webhelper.ReturnStatus(w, r, response)
 		return
 	}
 
-	playlist, err, httpStatus := getPlaylistByUrlPath(r.URL.Path, claims)
+	playlist, err, httpStatus := getPlaylistByUuidVar(webhelper.Param(r, "uuid"), claims)
 	if err != nil {
 		if webhelper.ReturnError(w, r, err, httpStatus) {
 			return
 		}
 	}
 
-	now := time.Now().Unix()
-	if playlist.ClientLockExpires > 0 &&
-		playlist.ClientLockExpires < now {
-		webhelper.ReturnError(w, r, errors.New("Playlist is locked"), &[]int{http.StatusLocked}[0])
+	if !checkCanChange(w, r, playlist) {
+		return
+	}
+	if lockedByOther(playlist, claims) {
+		returnLocked(w, r, playlist, claims)
 		return
 	}
 
@@ -151,22 +276,52 @@ func UpdatePlaylist(w http.ResponseWriter, r *http.Request) {
 	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
 		return
 	}
+	// Collaborators can follow along and change the tracks, but not rename it
+	if playlistData.Name != "" && !checkCanRename(w, r, playlist) {
+		return
+	}
+	// The route also lets through API keys that can only move the position
+	if playlistData.Name != "" && !claims.HasScope(userLogin.ScopePlaylistWrite) {
+		webhelper.ReturnError(w, r, webhelper.ErrScopeRequired, &[]int{http.StatusForbidden}[0])
+		return
+	}
 
-	playlist.ClientIdLock = claims.Id
-	expires := time.Now().Add(time.Minute * 10)
-	playlist.ClientLockExpires = expires.Unix()
+	revision := playlist.PositionRevision
+	changesTrack := playlistData.CurrentTrack != "" || playlistData.CurrentTrackPosition != 0
+	if changesTrack || playlistData.Elapsed != nil {
+		httpStatus, err := checkPositionUpdate(playlist, &playlistData)
+		if httpStatus != nil && *httpStatus == http.StatusConflict {
+			returnPositionConflict(w, r, playlist)
+			return
+		}
+		if webhelper.ReturnError(w, r, err, httpStatus) {
+			return
+		}
+		if changesTrack {
+			currentTrack, err := findCurrentTrack(playlist, &playlistData)
+			if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+				return
+			}
+			playlist.CurrentTrackUuid = currentTrack
+		}
+		if playlistData.Elapsed != nil {
+			playlist.Elapsed = *playlistData.Elapsed
+		}
+		playlist.PositionRevision = revision + 1
+		playlist.PositionUpdatedAt = *playlistData.Timestamp
+	}
 
 	if playlistData.Name != "" {
 		playlist.Name = playlistData.Name
 	}
-	if playlistData.CurrentTrackId != 0 {
-		playlist.CurrentTrackId = playlistData.CurrentTrackId
-	}
-	if playlistData.Elapsed != nil {
-		playlist.Elapsed = *playlistData.Elapsed
-	}
 
-	err = playlist.Update()
+	err = playlist.UpdateAtRevision(revision)
+	if err == storage.ErrRevisionConflict {
+		// Someone else got in between loading and saving the playlist
+		current, _, _ := getPlaylistByUuidVar(webhelper.Param(r, "uuid"), claims)
+		returnPositionConflict(w, r, current)
+		return
+	}
 	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
 		return
 	}
@@ -174,16 +329,52 @@ func UpdatePlaylist(w http.ResponseWriter, r *http.Request) {
 	var returnPlaylist Playlist
 	returnPlaylist.Uuid = playlist.Uuid
 	returnPlaylist.Name = playlist.Name
-	returnPlaylist.CurrentTrackId = playlist.CurrentTrackId
+	returnPlaylist.CurrentTrackUuid = playlist.CurrentTrackUuid
+	returnPlaylist.CurrentTrackPosition = trackPosition(playlist.Tracks, playlist.CurrentTrackUuid)
 	returnPlaylist.Elapsed = playlist.Elapsed
+	returnPlaylist.PositionRevision = playlist.PositionRevision
+	returnPlaylist.PositionUpdatedAt = playlist.PositionUpdatedAt
+	returnPlaylist.GroupUuid = playlist.GroupUuid
+	returnPlaylist.Access = playlist.Access
 	returnPlaylist.Tracks = append(returnPlaylist.Tracks, playlist.Tracks...)
 
+	// Let the user's other devices know straight away
+	if playlist.PositionRevision != revision {
+		publishPlaylistEvent(claims, playlist, events.Event{Type: events.PlaylistPosition, Client: claims.Device, Data: returnPlaylist})
+	}
+
 	json.NewEncoder(w).Encode(returnPlaylist)
 	return
 }
 
-func getTrackByUrl(path string, claims *userLogin.Claims) (*Track, error, *int) {
-	uuid, err := webhelper.GetUUidFromUrl(path, "^/tracks/([^/]+)$")
+// checkPositionUpdate decides whether a position update can be applied. The
+// revision must match the stored one, unless the client recorded its position
+// after the stored position was recorded, in which case it is still the most
+// recent position and wins. Anything else is stale and gets a 409.
+func checkPositionUpdate(playlist *Playlist, playlistData *UpdatePlaylistData) (*int, error) {
+	if playlistData.Revision == nil ||
+		playlistData.Timestamp == nil {
+		return &[]int{http.StatusBadRequest}[0], errors.New("Position updates require a revision and timestamp")
+	}
+	if *playlistData.Timestamp > time.Now().Add(maxClockSkew).UnixMilli() {
+		return &[]int{http.StatusBadRequest}[0], errors.New("Timestamp is in the future")
+	}
+	if *playlistData.Revision == playlist.PositionRevision {
+		return nil, nil
+	}
+	if *playlistData.Revision < playlist.PositionRevision &&
+		*playlistData.Timestamp > playlist.PositionUpdatedAt {
+		return nil, nil
+	}
+	return &[]int{http.StatusConflict}[0], storage.ErrRevisionConflict
+}
+
+func returnPositionConflict(w http.ResponseWriter, r *http.Request, playlist *Playlist) {
+	webhelper.ReturnErrorDetails(w, r, storage.ErrRevisionConflict, &[]int{http.StatusConflict}[0], &PositionConflict{Playlist: playlist})
+}
+
+func getTrackByUuid(uuid string, claims *userLogin.Claims) (*Track, error, *int) {
+	err := webhelper.CheckUuid(uuid)
 	if err != nil {
 		return nil, err, &[]int{http.StatusBadRequest}[0]
 	}
@@ -191,17 +382,19 @@ func getTrackByUrl(path string, claims *userLogin.Claims) (*Track, error, *int)
 	var track Track
 	var tracks []*Track
 
-	if !isAdminUserVar(claims.Username) {
-		trackResults, err1 := track.Find(storage.Track_.Uuid.Equals(*uuid, true),
-			storage.User_.Playlists.Link(storage.User_.EmailAddress.Equals(claims.Username, true)))
+	if !claims.Can(userLogin.PermLibraryWrite) {
+		trackResults, err1 := track.Find(storage.TrackFilter{Uuid: uuid, OwnerEmail: claims.Username})
 		err = err1
 		for _, st := range trackResults {
 			t := &Track{}
 			storage.DeepCopy(st, t)
 			tracks = append(tracks, t)
 		}
+		if err == nil && len(tracks) == 0 {
+			tracks, err = getGroupTrackByUuid(uuid, claims)
+		}
 	} else {
-		trackResults, err1 := track.Find(storage.Track_.Uuid.Equals(*uuid, true))
+		trackResults, err1 := track.Find(storage.TrackFilter{Uuid: uuid})
 		err = err1
 		for _, st := range trackResults {
 			t := &Track{}
@@ -220,8 +413,8 @@ func getTrackByUrl(path string, claims *userLogin.Claims) (*Track, error, *int)
 	return tracks[0], nil, &[]int{http.StatusOK}[0]
 }
 
-func getPlaylistByUrl(path string, claims *userLogin.Claims) (*Playlist, error, *int) {
-	uuid, err := webhelper.GetUUidFromUrl(path, "^/playlist/([^/]+)$")
+func getPlaylistByUuid(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
+	err := webhelper.CheckUuid(uuid)
 	if err != nil {
 		return nil, err, &[]int{http.StatusBadRequest}[0]
 	}
@@ -229,17 +422,36 @@ func getPlaylistByUrl(path string, claims *userLogin.Claims) (*Playlist, error,
 	var playlist Playlist
 	var playlists []*Playlist
 
-	if !isAdminUserVar(claims.Username) {
-		playlistResults, err1 := playlist.Find(storage.Playlist_.Uuid.Equals(*uuid, true),
-			storage.User_.Playlists.Link(storage.User_.EmailAddress.Equals(claims.Username, true)))
+	if !claims.Can(userLogin.PermLibraryWrite) {
+		playlistResults, err1 := playlist.Find(storage.PlaylistFilter{Uuid: uuid, OwnerEmail: claims.Username})
 		err = err1
 		for _, sp := range playlistResults {
 			p := &Playlist{}
 			storage.DeepCopy(sp, p)
 			playlists = append(playlists, p)
 		}
+		if err == nil && len(playlists) == 0 {
+			playlists, err = getGroupPlaylistByUuid(uuid, claims)
+		}
+		if err == nil && len(playlists) == 0 {
+			playlists, err = getSharedPlaylistByUuid(uuid, claims)
+		}
+		// Anyone's playlist can be read with library:read, as if it was shared
+		if err == nil && len(playlists) == 0 && claims.Can(userLogin.PermLibraryRead) {
+			playlistResults, err1 := playlist.Find(storage.PlaylistFilter{Uuid: uuid})
+			err = err1
+			for _, sp := range playlistResults {
+				p := &Playlist{}
+				storage.DeepCopy(sp, p)
+				p.Access = storage.ShareRead
+				playlists = append(playlists, p)
+			}
+		}
+		if err == nil && len(playlists) == 1 {
+			err = applyGroupRestrictions(playlists[0], claims)
+		}
 	} else {
-		playlistResults, err1 := playlist.Find(storage.Playlist_.Uuid.Equals(*uuid, true))
+		playlistResults, err1 := playlist.Find(storage.PlaylistFilter{Uuid: uuid})
 		err = err1
 		for _, sp := range playlistResults {
 			p := &Playlist{}
@@ -258,37 +470,68 @@ func getPlaylistByUrl(path string, claims *userLogin.Claims) (*Playlist, error,
 	return playlists[0], nil, &[]int{http.StatusOK}[0]
 }
 
+// getSharedPlaylistByUuid loads a playlist a friend has shared with the user
+func getSharedPlaylistByUuid(uuid string, claims *userLogin.Claims) ([]*Playlist, error) {
+	userUuid, err := lookupUserUuidVar(claims.Username)
+	if err != nil {
+		return nil, err
+	}
+	share, err := findShare(uuid, userUuid)
+	if err != nil || share == nil {
+		return nil, err
+	}
+	var playlist Playlist
+	playlistResults, err := playlist.Find(storage.PlaylistFilter{Uuid: uuid})
+	if err != nil {
+		return nil, err
+	}
+	var playlists []*Playlist
+	for _, sp := range playlistResults {
+		p := &Playlist{}
+		storage.DeepCopy(sp, p)
+		p.Access = share.Access
+		playlists = append(playlists, p)
+	}
+	return playlists, nil
+}
+
 func GetPlaylist(w http.ResponseWriter, r *http.Request) {
-	claims, response := checkTokenVar(r)
+	claims, response := requestClaimsVar(r)
 	if response != 200 {
-		w.WriteHeader(response)
+		webhelper.ReturnStatus(w, r, response)
 		return
 	}
 
-	playlist, err, httpStatus := getPlaylistByUrlPath(r.URL.Path, claims)
+	playlist, err, httpStatus := getPlaylistByUuidVar(webhelper.Param(r, "uuid"), claims)
 	if err != nil {
 		if webhelper.ReturnError(w, r, err, httpStatus) {
 			return
 		}
 	}
 
+	playlist.CurrentTrackPosition = trackPosition(playlist.Tracks, playlist.CurrentTrackUuid)
+	playlist.Lock = newLockData(playlist, claims)
+
 	json.NewEncoder(w).Encode(playlist)
 	return
 }
 
 func DeletePlaylist(w http.ResponseWriter, r *http.Request) {
-	claims, response := checkTokenVar(r)
+	claims, response := requestClaimsVar(r)
 	if response != 200 {
-		w.WriteHeader(response)
+		webhelper.ReturnStatus(w, r, response)
 		return
 	}
 
-	playlist, err, statusCode := getPlaylistByUrlPath(r.URL.Path, claims)
+	playlist, err, statusCode := getPlaylistByUuidVar(webhelper.Param(r, "uuid"), claims)
 	if err != nil {
 		if webhelper.ReturnError(w, r, err, statusCode) {
 			return
 		}
 	}
+	if !checkCanDelete(w, r, playlist) {
+		return
+	}
 
 	err = playlist.Delete()
 	if err != nil {
@@ -303,15 +546,15 @@ func DeletePlaylist(w http.ResponseWriter, r *http.Request) {
 }
 
 func ListPlaylist(w http.ResponseWriter, r *http.Request) {
-	claims, response := checkTokenVar(r)
+	claims, response := requestClaimsVar(r)
 	if response != 200 {
-		w.WriteHeader(response)
+		webhelper.ReturnStatus(w, r, response)
 		return
 	}
 
 	// Load user, to pull playlists
 	var user User
-	userList, err := user.Find(storage.User_.EmailAddress.Equals(claims.Username, true))
+	userList, err := user.Find(storage.UserFilter{EmailAddress: claims.Username})
 	if err != nil ||
 		len(userList) == 0 {
 		err := errors.New("Failed to Find user account")
@@ -332,19 +575,23 @@ func ListPlaylist(w http.ResponseWriter, r *http.Request) {
 }
 
 func AddTrack(w http.ResponseWriter, r *http.Request) {
-	claims, response := checkTokenVar(r)
+	claims, response := requestClaimsVar(r)
 	if response != 200 {
-		w.WriteHeader(response)
+		webhelper.ReturnStatus(w, r, response)
 		return
 	}
-	playlist, err, httpStatus := getPlaylistByUrlPath(r.URL.Path, claims)
+	playlist, err, httpStatus := getPlaylistByUuidVar(webhelper.Param(r, "uuid"), claims)
 	if err != nil {
 		if webhelper.ReturnError(w, r, err, httpStatus) {
 			return
 		}
 	}
 
-	var trackData TrackData
+	if !checkCanChange(w, r, playlist) {
+		return
+	}
+
+	var trackData AddTrackData
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
 
@@ -352,32 +599,71 @@ func AddTrack(w http.ResponseWriter, r *http.Request) {
 	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
 		return
 	}
+	if trackData.Position < 0 || trackData.Position > len(playlist.Tracks)+1 {
+		webhelper.ReturnError(w, r, errInvalidPosition, &[]int{http.StatusBadRequest}[0])
+		return
+	}
+	// Inserting reorders the playlist, which only the device holding the lock can do
+	if trackData.Position != 0 && lockedByOther(playlist, claims) {
+		returnLocked(w, r, playlist, claims)
+		return
+	}
 
 	var track *Track
-	track = &Track{}
-	storage.DeepCopy(trackData, track)
+	if trackData.Track != "" {
+		track, err, httpStatus = getLibraryTrack(claims, trackData.Track)
+	} else {
+		track, _, err, httpStatus = libraryTrack(claims, &trackData.TrackData)
+	}
+	if webhelper.ReturnError(w, r, err, httpStatus) {
+		return
+	}
+	if trackPosition(playlist.Tracks, track.Uuid) != 0 {
+		webhelper.ReturnError(w, r, errTrackInPlaylist, &[]int{http.StatusConflict}[0])
+		return
+	}
 	_, err = playlist.AddTrack(track)
 
 	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
 		return
 	}
+
+	// New tracks go on the end, unless the client asked for a position
+	if trackData.Position != 0 && trackData.Position != len(playlist.Tracks) {
+		order := moveTrack(trackUuids(playlist.Tracks), track.Uuid, trackData.Position)
+		err = playlist.ReorderTracks(order)
+		if err == storage.ErrTrackOrder {
+			webhelper.ReturnError(w, r, err, &[]int{http.StatusConflict}[0])
+			return
+		}
+		if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+			return
+		}
+	}
+
+	playlist.CurrentTrackPosition = trackPosition(playlist.Tracks, playlist.CurrentTrackUuid)
+	publishPlaylistEvent(claims, playlist, events.Event{Type: events.PlaylistTracks, Client: claims.Device, Data: playlist})
 	json.NewEncoder(w).Encode(track)
 	return
 }
 
 func UpdateTrack(w http.ResponseWriter, r *http.Request) {
-	claims, response := checkTokenVar(r)
+	claims, response := requestClaimsVar(r)
 	if response != 200 {
-		w.WriteHeader(response)
+		webhelper.ReturnStatus(w, r, response)
 		return
 	}
-	track, err, httpStatus := getTrackByUrlPath(r.URL.Path, claims)
+	track, err, httpStatus := getTrackByUuidVar(webhelper.Param(r, "uuid"), claims)
 	if err != nil {
 		if webhelper.ReturnError(w, r, err, httpStatus) {
 			return
 		}
 	}
 
+	if !checkOwnsTrack(w, r, track) {
+		return
+	}
+
 	var trackData TrackData
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
@@ -387,28 +673,40 @@ func UpdateTrack(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err, httpStatus = checkLibraryConflict(claims.Username, track, &trackData)
+	if webhelper.ReturnError(w, r, err, httpStatus) {
+		return
+	}
+
+	// The track is shared, so the edit shows in every playlist it is in
 	storage.DeepCopy(trackData, track)
 	err = track.Update()
 	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
 		return
 	}
+	for _, emailAddress := range trackFollowers(claims, track) {
+		publishEvent(emailAddress, events.Event{Type: events.TrackUpdated, Client: claims.Device, Data: track})
+	}
 
 	json.NewEncoder(w).Encode(track)
 	return
 }
 
 func DeleteTrack(w http.ResponseWriter, r *http.Request) {
-	claims, response := checkTokenVar(r)
+	claims, response := requestClaimsVar(r)
 	if response != 200 {
-		w.WriteHeader(response)
+		webhelper.ReturnStatus(w, r, response)
 		return
 	}
-	track, err, httpStatus := getTrackByUrlPath(r.URL.Path, claims)
+	track, err, httpStatus := getTrackByUuidVar(webhelper.Param(r, "uuid"), claims)
 	if err != nil {
 		if webhelper.ReturnError(w, r, err, httpStatus) {
 			return
 		}
 	}
+	if !checkOwnsTrack(w, r, track) {
+		return
+	}
 	err = track.Delete()
 	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
 		return
@@ -1,24 +1,27 @@
 package playlist
 
 import (
+	"encoding/json"
 	"errors"
 	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"mimpidev/sinkrontrack-server/pkg/events"
 	"mimpidev/sinkrontrack-server/pkg/userLogin"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
-	"github.com/objectbox/objectbox-go/objectbox"
 )
 
-var executeFindPlaylist func(conditions []objectbox.Condition) ([]*Playlist, error)
+var executeFindPlaylist func(filter storage.PlaylistFilter) ([]*Playlist, error)
 var executeSelectPlaylist func(p *Playlist) error
 var executeDeletePlaylist func(p *Playlist) error
-var executeUpdatePlaylist func(p *Playlist) error
-var executeFindUser func(conditions []objectbox.Condition) ([]*User, error)
+var executeUpdatePlaylist func(p *Playlist, revision uint64) error
+var executeFindUser func(filter storage.UserFilter) ([]*User, error)
 var executeSelectUser func(m *User) error
 var executeUpdateTrack func(t *Track) error
 var executeDeleteTrack func(t *Track) error
@@ -31,20 +34,20 @@ func (p *Playlist) Select() error {
 	return executeSelectPlaylist(p)
 }
 
-func (p *Playlist) Find(conditions ...objectbox.Condition) ([]*Playlist, error) {
-	return executeFindPlaylist(conditions)
+func (p *Playlist) Find(filter storage.PlaylistFilter) ([]*Playlist, error) {
+	return executeFindPlaylist(filter)
 }
 
-func (m *User) Find(conditions ...objectbox.Condition) ([]*User, error) {
-	return executeFindUser(conditions)
+func (m *User) Find(filter storage.UserFilter) ([]*User, error) {
+	return executeFindUser(filter)
 }
 
 func (m *User) Select() error {
 	return executeSelectUser(m)
 }
 
-func (p *Playlist) Update() error {
-	return executeUpdatePlaylist(p)
+func (p *Playlist) UpdateAtRevision(revision uint64) error {
+	return executeUpdatePlaylist(p, revision)
 }
 
 func (t *Track) Update() error {
@@ -62,7 +65,7 @@ func TestPlaylistCopy(t *testing.T) {
 		srcPlaylist.Id = 1
 		srcPlaylist.Name = "Test playlist"
 		srcPlaylist.Uuid = "2898da6e-b222-4227-8b7b-6bbc239705b0"
-		srcPlaylist.ClientIdLock = "sad8976sdf87sdf"
+		srcPlaylist.LockDeviceUuid = "sad8976sdf87sdf"
 		srcPlaylist.ClientLockExpires = 57
 		srcPlaylist.Elapsed = 132
 		tr := &storage.Track{Id: 1,
@@ -87,7 +90,7 @@ func TestPlaylistCopy(t *testing.T) {
 		if destPlaylist.Id != srcPlaylist.Id ||
 			destPlaylist.Name != srcPlaylist.Name ||
 			destPlaylist.Uuid != srcPlaylist.Uuid ||
-			destPlaylist.ClientIdLock != srcPlaylist.ClientIdLock ||
+			destPlaylist.LockDeviceUuid != srcPlaylist.LockDeviceUuid ||
 			destPlaylist.ClientLockExpires != srcPlaylist.ClientLockExpires ||
 			destPlaylist.Elapsed != srcPlaylist.Elapsed ||
 			destPlaylist.Tracks[0].Uuid != srcPlaylist.Tracks[0].Uuid {
@@ -98,12 +101,12 @@ func TestPlaylistCopy(t *testing.T) {
 
 func TestCreatePlaylist(t *testing.T) {
 	t.Run("Invalid User Token", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			return nil, http.StatusUnauthorized
 		}
 
 		var data = `{"name":"Test"}`
-		request := httptest.NewRequest("POST", "/playlist", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/playlists", strings.NewReader(data))
 		responseRecorder := httptest.NewRecorder()
 
 		CreatePlaylist(responseRecorder, request)
@@ -113,7 +116,7 @@ func TestCreatePlaylist(t *testing.T) {
 		}
 	})
 	t.Run("Invalid JSON", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -122,7 +125,7 @@ func TestCreatePlaylist(t *testing.T) {
 		}
 
 		var data = `{"name":"Test"`
-		request := httptest.NewRequest("POST", "/playlist", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/playlists", strings.NewReader(data))
 		responseRecorder := httptest.NewRecorder()
 
 		CreatePlaylist(responseRecorder, request)
@@ -132,7 +135,7 @@ func TestCreatePlaylist(t *testing.T) {
 		}
 	})
 	t.Run("Mismatch JSON", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -141,7 +144,7 @@ func TestCreatePlaylist(t *testing.T) {
 		}
 
 		var data = `{"owner":"Test"}`
-		request := httptest.NewRequest("POST", "/playlist", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/playlists", strings.NewReader(data))
 		responseRecorder := httptest.NewRecorder()
 
 		CreatePlaylist(responseRecorder, request)
@@ -151,7 +154,7 @@ func TestCreatePlaylist(t *testing.T) {
 		}
 	})
 	t.Run("Error Loading user", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -168,7 +171,7 @@ func TestCreatePlaylist(t *testing.T) {
 		}
 
 		var data = `{"name":"Test"}`
-		request := httptest.NewRequest("POST", "/playlist", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/playlists", strings.NewReader(data))
 		responseRecorder := httptest.NewRecorder()
 
 		CreatePlaylist(responseRecorder, request)
@@ -178,7 +181,7 @@ func TestCreatePlaylist(t *testing.T) {
 		}
 	})
 	t.Run("Fail to add new Playlist to user", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -196,13 +199,12 @@ func TestCreatePlaylist(t *testing.T) {
 			m.EmailAddress = "test@test.com.au"
 			m.Id = 2
 			m.Enabled = true
-			m.AdminUser = false
 
 			return nil
 		}
 
 		var data = `{"name":"Test"}`
-		request := httptest.NewRequest("POST", "/playlist", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/playlists", strings.NewReader(data))
 		responseRecorder := httptest.NewRecorder()
 
 		CreatePlaylist(responseRecorder, request)
@@ -212,7 +214,7 @@ func TestCreatePlaylist(t *testing.T) {
 		}
 	})
 	t.Run("Add new Playlist to user", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -230,13 +232,12 @@ func TestCreatePlaylist(t *testing.T) {
 			m.EmailAddress = "test@test.com.au"
 			m.Id = 2
 			m.Enabled = true
-			m.AdminUser = false
 
 			return nil
 		}
 
 		var data = `{"name":"Test"}`
-		request := httptest.NewRequest("POST", "/playlist", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/playlists", strings.NewReader(data))
 		responseRecorder := httptest.NewRecorder()
 
 		CreatePlaylist(responseRecorder, request)
@@ -249,12 +250,13 @@ func TestCreatePlaylist(t *testing.T) {
 
 func TestUpdatePlaylist(t *testing.T) {
 	t.Run("Invalid token", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			return nil, http.StatusUnauthorized
 		}
 
 		var data = `{"firstName":"Test","lastName":"User","emailAddress":"test@test.com"}`
-		request := httptest.NewRequest("PATCH", "/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4", strings.NewReader(data))
+		request := httptest.NewRequest("PATCH", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
 		responseRecorder := httptest.NewRecorder()
 
 		UpdatePlaylist(responseRecorder, request)
@@ -263,7 +265,7 @@ func TestUpdatePlaylist(t *testing.T) {
 		}
 	})
 	t.Run("no playlist in url", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -271,12 +273,8 @@ func TestUpdatePlaylist(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		isAdminUserVar = func(username string) bool {
-			return false
-		}
-
 		var data = `{"name":"Test"}`
-		request := httptest.NewRequest("PATCH", "/playlist/", strings.NewReader(data))
+		request := httptest.NewRequest("PATCH", "/playlists/", strings.NewReader(data))
 		responseRecorder := httptest.NewRecorder()
 
 		UpdatePlaylist(responseRecorder, request)
@@ -285,7 +283,7 @@ func TestUpdatePlaylist(t *testing.T) {
 		}
 	})
 	t.Run("playlist uuid in url is invalid", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -293,12 +291,9 @@ func TestUpdatePlaylist(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		isAdminUserVar = func(username string) bool {
-			return false
-		}
-
 		var data = `{"name":"Test"}`
-		request := httptest.NewRequest("PATCH", "/playlist/d9g87sdgf98-sdf98sdf9sdf", strings.NewReader(data))
+		request := httptest.NewRequest("PATCH", "/playlists/d9g87sdgf98-sdf98sdf9sdf", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "d9g87sdgf98-sdf98sdf9sdf"})
 		responseRecorder := httptest.NewRecorder()
 
 		UpdatePlaylist(responseRecorder, request)
@@ -306,8 +301,11 @@ func TestUpdatePlaylist(t *testing.T) {
 			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
 		}
 	})
-	t.Run("ClientLockExpires has not yet passed (ie Playlist is still locked)", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+	t.Run("Playlist is locked by another device", func(t *testing.T) {
+		lookupDeviceVar = func(uuid string) (*storage.Device, error) {
+			return &storage.Device{Id: 1, Uuid: uuid, Name: "Kitchen Speaker"}, nil
+		}
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -315,19 +313,15 @@ func TestUpdatePlaylist(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		isAdminUserVar = func(username string) bool {
-			return false
-		}
-
-		executeFindPlaylist = func(conditions []objectbox.Condition) ([]*Playlist, error) {
+		executeFindPlaylist = func(filter storage.PlaylistFilter) ([]*Playlist, error) {
 			var playlists []*Playlist
 			p := &Playlist{}
 			p.Id = 1
 			p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
 			p.Name = "Test Playlist 1"
-			p.CurrentTrackId = 1
-			p.ClientIdLock = "sd7fsd8f76sdf876sdf"
-			p.ClientLockExpires = time.Now().Unix() - 1000*60
+			p.CurrentTrackUuid = "48cf9b84-6162-430a-92ac-6804146ad2a5"
+			p.LockDeviceUuid = "sd7fsd8f76sdf876sdf"
+			p.ClientLockExpires = time.Now().Unix() + 60
 			p.Elapsed = 0
 			t := &storage.Track{Id: 1,
 				Path:             "/mnt/sdb/Album1/Track1.mp3",
@@ -358,12 +352,13 @@ func TestUpdatePlaylist(t *testing.T) {
 			return playlists, nil
 		}
 
-		executeUpdatePlaylist = func(p *Playlist) error {
+		executeUpdatePlaylist = func(p *Playlist, revision uint64) error {
 			return nil
 		}
 
 		var data = `{"name":"Test"}`
-		request := httptest.NewRequest("PATCH", "/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4", strings.NewReader(data))
+		request := httptest.NewRequest("PATCH", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
 		responseRecorder := httptest.NewRecorder()
 
 		UpdatePlaylist(responseRecorder, request)
@@ -373,7 +368,7 @@ func TestUpdatePlaylist(t *testing.T) {
 
 	})
 	t.Run("Incoming data is invalid", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -381,17 +376,13 @@ func TestUpdatePlaylist(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		isAdminUserVar = func(username string) bool {
-			return false
-		}
-
-		executeFindPlaylist = func(conditions []objectbox.Condition) ([]*Playlist, error) {
+		executeFindPlaylist = func(filter storage.PlaylistFilter) ([]*Playlist, error) {
 			var playlists []*Playlist
 			p := &Playlist{}
 			p.Id = 1
 			p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
 			p.Name = "Test Playlist 1"
-			p.CurrentTrackId = 1
+			p.CurrentTrackUuid = "48cf9b84-6162-430a-92ac-6804146ad2a5"
 			p.Elapsed = 0
 			t := &storage.Track{Id: 1,
 				Path:             "/mnt/sdb/Album1/Track1.mp3",
@@ -422,12 +413,13 @@ func TestUpdatePlaylist(t *testing.T) {
 			return playlists, nil
 		}
 
-		executeUpdatePlaylist = func(p *Playlist) error {
+		executeUpdatePlaylist = func(p *Playlist, revision uint64) error {
 			return nil
 		}
 
 		var data = `{"name":"Test"`
-		request := httptest.NewRequest("PATCH", "/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4", strings.NewReader(data))
+		request := httptest.NewRequest("PATCH", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
 		responseRecorder := httptest.NewRecorder()
 
 		UpdatePlaylist(responseRecorder, request)
@@ -437,7 +429,7 @@ func TestUpdatePlaylist(t *testing.T) {
 
 	})
 	t.Run("Update Paylist throws an error", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -445,17 +437,13 @@ func TestUpdatePlaylist(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		isAdminUserVar = func(username string) bool {
-			return false
-		}
-
-		executeFindPlaylist = func(conditions []objectbox.Condition) ([]*Playlist, error) {
+		executeFindPlaylist = func(filter storage.PlaylistFilter) ([]*Playlist, error) {
 			var playlists []*Playlist
 			p := &Playlist{}
 			p.Id = 1
 			p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
 			p.Name = "Test Playlist 1"
-			p.CurrentTrackId = 1
+			p.CurrentTrackUuid = "48cf9b84-6162-430a-92ac-6804146ad2a5"
 			p.Elapsed = 0
 			t := &storage.Track{Id: 1,
 				Path:             "/mnt/sdb/Album1/Track1.mp3",
@@ -486,12 +474,13 @@ func TestUpdatePlaylist(t *testing.T) {
 			return playlists, nil
 		}
 
-		executeUpdatePlaylist = func(p *Playlist) error {
+		executeUpdatePlaylist = func(p *Playlist, revision uint64) error {
 			return errors.New("Just throwing a test Error")
 		}
 
 		var data = `{"name":"Test"}`
-		request := httptest.NewRequest("PATCH", "/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4", strings.NewReader(data))
+		request := httptest.NewRequest("PATCH", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
 		responseRecorder := httptest.NewRecorder()
 
 		UpdatePlaylist(responseRecorder, request)
@@ -501,7 +490,7 @@ func TestUpdatePlaylist(t *testing.T) {
 
 	})
 	t.Run("Update Playlist details", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -509,17 +498,13 @@ func TestUpdatePlaylist(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		isAdminUserVar = func(username string) bool {
-			return false
-		}
-
-		executeFindPlaylist = func(conditions []objectbox.Condition) ([]*Playlist, error) {
+		executeFindPlaylist = func(filter storage.PlaylistFilter) ([]*Playlist, error) {
 			var playlists []*Playlist
 			p := &Playlist{}
 			p.Id = 1
 			p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
 			p.Name = "Test Playlist 1"
-			p.CurrentTrackId = 1
+			p.CurrentTrackUuid = "48cf9b84-6162-430a-92ac-6804146ad2a5"
 			p.Elapsed = 0
 			t := &storage.Track{Id: 1,
 				Path:             "/mnt/sdb/Album1/Track1.mp3",
@@ -550,12 +535,13 @@ func TestUpdatePlaylist(t *testing.T) {
 			return playlists, nil
 		}
 
-		executeUpdatePlaylist = func(p *Playlist) error {
+		executeUpdatePlaylist = func(p *Playlist, revision uint64) error {
 			return nil
 		}
 
 		var data = `{"name":"Test"}`
-		request := httptest.NewRequest("PATCH", "/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4", strings.NewReader(data))
+		request := httptest.NewRequest("PATCH", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
 		responseRecorder := httptest.NewRecorder()
 
 		UpdatePlaylist(responseRecorder, request)
@@ -565,6 +551,199 @@ func TestUpdatePlaylist(t *testing.T) {
 	})
 }
 
+func TestUpdatePlaylistPosition(t *testing.T) {
+	requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
+		claims := &userLogin.Claims{
+			Username:       "test@test.com.au",
+			StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
+		}
+		return claims, http.StatusOK
+	}
+	// Stored position was recorded at 1000 and is at revision 5
+	executeFindPlaylist = func(filter storage.PlaylistFilter) ([]*Playlist, error) {
+		p := &Playlist{}
+		p.Id = 1
+		p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
+		p.Name = "Test Playlist 1"
+		p.CurrentTrackUuid = "48cf9b84-6162-430a-92ac-6804146ad2a5"
+		p.Elapsed = 100
+		p.PositionRevision = 5
+		p.PositionUpdatedAt = 1000
+		return []*Playlist{p}, nil
+	}
+	playlistFollowersVar = func(claims *userLogin.Claims, playlist *Playlist) []string {
+		return []string{claims.Username}
+	}
+	defer func() { playlistFollowersVar = playlistFollowers }()
+
+	t.Run("Position update without a revision is rejected", func(t *testing.T) {
+		executeUpdatePlaylist = func(p *Playlist, revision uint64) error {
+			return nil
+		}
+		var data = `{"elapsed":200}`
+		request := httptest.NewRequest("PATCH", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		UpdatePlaylist(responseRecorder, request)
+		if responseRecorder.Code != http.StatusBadRequest {
+			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
+		}
+	})
+	t.Run("Position update with a timestamp in the future is rejected", func(t *testing.T) {
+		var data = `{"elapsed":200,"revision":5,"timestamp":` + strconv.FormatInt(time.Now().Add(time.Hour).UnixMilli(), 10) + `}`
+		request := httptest.NewRequest("PATCH", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		UpdatePlaylist(responseRecorder, request)
+		if responseRecorder.Code != http.StatusBadRequest {
+			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
+		}
+	})
+	t.Run("Position update at the current revision is applied", func(t *testing.T) {
+		var saved *Playlist
+		var savedAt uint64
+		executeUpdatePlaylist = func(p *Playlist, revision uint64) error {
+			saved = p
+			savedAt = revision
+			return nil
+		}
+		var data = `{"elapsed":200,"revision":5,"timestamp":2000}`
+		request := httptest.NewRequest("PATCH", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		UpdatePlaylist(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if savedAt != 5 || saved.PositionRevision != 6 || saved.Elapsed != 200 || saved.PositionUpdatedAt != 2000 {
+			t.Errorf("Playlist saved with the wrong position: revision %d->%d elapsed %d timestamp %d",
+				savedAt, saved.PositionRevision, saved.Elapsed, saved.PositionUpdatedAt)
+		}
+	})
+	t.Run("Position update is pushed to everyone following the playlist", func(t *testing.T) {
+		executeUpdatePlaylist = func(p *Playlist, revision uint64) error {
+			return nil
+		}
+		playlistFollowersVar = func(claims *userLogin.Claims, playlist *Playlist) []string {
+			return []string{claims.Username, "friend@test.com.au"}
+		}
+		var published []events.Event
+		var publishedTo []string
+		publishEvent = func(username string, event events.Event) {
+			publishedTo = append(publishedTo, username)
+			published = append(published, event)
+		}
+		defer func() {
+			publishEvent = events.Publish
+			playlistFollowersVar = func(claims *userLogin.Claims, playlist *Playlist) []string {
+				return []string{claims.Username}
+			}
+		}()
+		var data = `{"elapsed":200,"revision":5,"timestamp":2000}`
+		request := httptest.NewRequest("PATCH", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		UpdatePlaylist(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if len(published) == 0 || published[0].Type != events.PlaylistPosition {
+			t.Errorf("Want a '%s' event, got '%+v'", events.PlaylistPosition, published)
+		}
+		if len(publishedTo) != 2 || publishedTo[0] != "test@test.com.au" || publishedTo[1] != "friend@test.com.au" {
+			t.Errorf("Want the event for the user and their friend, got '%v'", publishedTo)
+		}
+	})
+	t.Run("Stale revision recorded after the stored position is merged", func(t *testing.T) {
+		var saved *Playlist
+		executeUpdatePlaylist = func(p *Playlist, revision uint64) error {
+			saved = p
+			return nil
+		}
+		var data = `{"elapsed":300,"revision":3,"timestamp":1500}`
+		request := httptest.NewRequest("PATCH", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		UpdatePlaylist(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if saved.PositionRevision != 6 || saved.Elapsed != 300 {
+			t.Errorf("Want revision 6 elapsed 300, got revision %d elapsed %d", saved.PositionRevision, saved.Elapsed)
+		}
+	})
+	t.Run("Stale revision recorded before the stored position is rejected", func(t *testing.T) {
+		executeUpdatePlaylist = func(p *Playlist, revision uint64) error {
+			t.Error("Stale position should not be saved")
+			return nil
+		}
+		var data = `{"elapsed":50,"revision":4,"timestamp":900}`
+		request := httptest.NewRequest("PATCH", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		UpdatePlaylist(responseRecorder, request)
+		if responseRecorder.Code != http.StatusConflict {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusConflict, responseRecorder.Code)
+		}
+		var conflict PositionConflict
+		json.NewDecoder(responseRecorder.Body).Decode(&webhelper.ErrorResponse{Details: &conflict})
+		if conflict.Playlist == nil || conflict.Playlist.Elapsed != 100 || conflict.Playlist.PositionRevision != 5 {
+			t.Errorf("Want the current position in the response, got '%+v'", conflict.Playlist)
+		}
+	})
+	t.Run("Revision changed by another client while saving", func(t *testing.T) {
+		executeUpdatePlaylist = func(p *Playlist, revision uint64) error {
+			return storage.ErrRevisionConflict
+		}
+		var data = `{"elapsed":200,"revision":5,"timestamp":2000}`
+		request := httptest.NewRequest("PATCH", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		UpdatePlaylist(responseRecorder, request)
+		if responseRecorder.Code != http.StatusConflict {
+			t.Errorf("Want status '%d', got '%d'", http.StatusConflict, responseRecorder.Code)
+		}
+	})
+	t.Run("A key that can only save positions can't rename", func(t *testing.T) {
+		defer func(claims func(*http.Request) (*userLogin.Claims, int)) { requestClaimsVar = claims }(requestClaimsVar)
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
+			claims := &userLogin.Claims{
+				Username:       "test@test.com.au",
+				Scopes:         []string{userLogin.ScopePositionWrite},
+				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
+			}
+			return claims, http.StatusOK
+		}
+		executeUpdatePlaylist = func(p *Playlist, revision uint64) error {
+			return nil
+		}
+		tests := []struct {
+			data string
+			want int
+		}{
+			{`{"name":"Renamed","elapsed":200,"revision":5,"timestamp":2000}`, http.StatusForbidden},
+			{`{"elapsed":200,"revision":5,"timestamp":2000}`, http.StatusOK},
+		}
+		for _, test := range tests {
+			request := httptest.NewRequest("PATCH", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", strings.NewReader(test.data))
+			request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+			responseRecorder := httptest.NewRecorder()
+
+			UpdatePlaylist(responseRecorder, request)
+			if responseRecorder.Code != test.want {
+				t.Errorf("Want status '%d' for '%s', got '%d'", test.want, test.data, responseRecorder.Code)
+			}
+		}
+	})
+}
+
 func TestGetPlaylistByUrl(t *testing.T) {
 	t.Run("Get Playlist from url", func(t *testing.T) {
 		claims := &userLogin.Claims{
@@ -572,17 +751,13 @@ func TestGetPlaylistByUrl(t *testing.T) {
 			StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
 		}
 
-		isAdminUserVar = func(username string) bool {
-			return false
-		}
-
-		executeFindPlaylist = func(conditions []objectbox.Condition) ([]*Playlist, error) {
+		executeFindPlaylist = func(filter storage.PlaylistFilter) ([]*Playlist, error) {
 			var playlists []*Playlist
 			p := &Playlist{}
 			p.Id = 1
 			p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
 			p.Name = "Test Playlist 1"
-			p.CurrentTrackId = 1
+			p.CurrentTrackUuid = "48cf9b84-6162-430a-92ac-6804146ad2a5"
 			p.Elapsed = 0
 			t := &storage.Track{Id: 1,
 				Path:             "/mnt/sdb/Album1/Track1.mp3",
@@ -612,7 +787,7 @@ func TestGetPlaylistByUrl(t *testing.T) {
 			return playlists, nil
 		}
 
-		playlist, _, statusCode := getPlaylistByUrl("/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4", claims)
+		playlist, _, statusCode := getPlaylistByUuid("48cf9b84-6162-430a-92ac-6804146ad2a4", claims)
 		if *statusCode != http.StatusOK {
 			t.Errorf("Want status '%d', got '%d'", http.StatusOK, statusCode)
 		}
@@ -624,20 +799,17 @@ func TestGetPlaylistByUrl(t *testing.T) {
 	t.Run("Get Playlist from url as Admin user", func(t *testing.T) {
 		claims := &userLogin.Claims{
 			Username:       "test@test.com",
+			Permissions:    []string{userLogin.PermLibraryWrite},
 			StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
 		}
 
-		isAdminUserVar = func(username string) bool {
-			return true
-		}
-
-		executeFindPlaylist = func(conditions []objectbox.Condition) ([]*Playlist, error) {
+		executeFindPlaylist = func(filter storage.PlaylistFilter) ([]*Playlist, error) {
 			var playlists []*Playlist
 			p := &Playlist{}
 			p.Id = 1
 			p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
 			p.Name = "Test Playlist 1"
-			p.CurrentTrackId = 1
+			p.CurrentTrackUuid = "48cf9b84-6162-430a-92ac-6804146ad2a5"
 			p.Elapsed = 0
 			t := &storage.Track{Id: 1,
 				Path:             "/mnt/sdb/Album1/Track1.mp3",
@@ -668,7 +840,7 @@ func TestGetPlaylistByUrl(t *testing.T) {
 			return playlists, nil
 		}
 
-		playlist, _, statusCode := getPlaylistByUrl("/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4", claims)
+		playlist, _, statusCode := getPlaylistByUuid("48cf9b84-6162-430a-92ac-6804146ad2a4", claims)
 		if *statusCode != http.StatusOK {
 			t.Errorf("Want status '%d', got '%d'", http.StatusOK, statusCode)
 		}
@@ -683,11 +855,7 @@ func TestGetPlaylistByUrl(t *testing.T) {
 			StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
 		}
 
-		isAdminUserVar = func(username string) bool {
-			return false
-		}
-
-		playlist, err, statusCode := getPlaylistByUrl("/playlist/1", claims)
+		playlist, err, statusCode := getPlaylistByUuid("1", claims)
 		if *statusCode != http.StatusBadRequest {
 			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, statusCode)
 		}
@@ -706,15 +874,11 @@ func TestGetPlaylistByUrl(t *testing.T) {
 			StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
 		}
 
-		isAdminUserVar = func(username string) bool {
-			return false
-		}
-
-		executeFindPlaylist = func(conditions []objectbox.Condition) ([]*Playlist, error) {
+		executeFindPlaylist = func(filter storage.PlaylistFilter) ([]*Playlist, error) {
 			return nil, errors.New("Error getting playlist")
 		}
 
-		playlist, err, statusCode := getPlaylistByUrl("/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4", claims)
+		playlist, err, statusCode := getPlaylistByUuid("48cf9b84-6162-430a-92ac-6804146ad2a4", claims)
 		if *statusCode != http.StatusInternalServerError {
 			t.Errorf("Want status '%d', got '%d'", http.StatusInternalServerError, statusCode)
 		}
@@ -730,18 +894,15 @@ func TestGetPlaylistByUrl(t *testing.T) {
 	t.Run("Get Playlist from storage throws an error as admin user", func(t *testing.T) {
 		claims := &userLogin.Claims{
 			Username:       "test@test.com",
+			Permissions:    []string{userLogin.PermLibraryWrite},
 			StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
 		}
 
-		isAdminUserVar = func(username string) bool {
-			return true
-		}
-
-		executeFindPlaylist = func(conditions []objectbox.Condition) ([]*Playlist, error) {
+		executeFindPlaylist = func(filter storage.PlaylistFilter) ([]*Playlist, error) {
 			return nil, errors.New("Error getting playlist")
 		}
 
-		playlist, err, statusCode := getPlaylistByUrl("/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4", claims)
+		playlist, err, statusCode := getPlaylistByUuid("48cf9b84-6162-430a-92ac-6804146ad2a4", claims)
 		if *statusCode != http.StatusInternalServerError {
 			t.Errorf("Want status '%d', got '%d'", http.StatusInternalServerError, statusCode)
 		}
@@ -760,15 +921,13 @@ func TestGetPlaylistByUrl(t *testing.T) {
 			StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
 		}
 
-		isAdminUserVar = func(username string) bool {
-			return false
-		}
-
-		executeFindPlaylist = func(conditions []objectbox.Condition) ([]*Playlist, error) {
+		executeFindPlaylist = func(filter storage.PlaylistFilter) ([]*Playlist, error) {
 			return []*Playlist{}, nil
 		}
+		shareTest()
+		defer restoreShareTest()
 
-		playlist, err, statusCode := getPlaylistByUrl("/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4", claims)
+		playlist, err, statusCode := getPlaylistByUuid("48cf9b84-6162-430a-92ac-6804146ad2a4", claims)
 		if *statusCode != http.StatusNotFound {
 			t.Errorf("Want status '%d', got '%d'", http.StatusNotFound, statusCode)
 		}
@@ -784,12 +943,14 @@ func TestGetPlaylistByUrl(t *testing.T) {
 }
 
 func TestGetPlaylist(t *testing.T) {
+	defer restoreLockTest()
 	t.Run("Invalid token", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			return nil, http.StatusUnauthorized
 		}
 
-		request := httptest.NewRequest("GET", "/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4", nil)
+		request := httptest.NewRequest("GET", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
 		responseRecorder := httptest.NewRecorder()
 
 		GetPlaylist(responseRecorder, request)
@@ -798,7 +959,7 @@ func TestGetPlaylist(t *testing.T) {
 		}
 	})
 	t.Run("Successfully Get playlist", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -806,12 +967,12 @@ func TestGetPlaylist(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		getPlaylistByUrlPath = func(url string, claims *userLogin.Claims) (*Playlist, error, *int) {
+		getPlaylistByUuidVar = func(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
 			var p Playlist
 			p.Id = 1
 			p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
 			p.Name = "Test Playlist 1"
-			p.CurrentTrackId = 1
+			p.CurrentTrackUuid = "48cf9b84-6162-430a-92ac-6804146ad2a5"
 			p.Elapsed = 0
 			t := &storage.Track{Id: 1,
 				Path:             "/mnt/sdb/Album1/Track1.mp3",
@@ -839,7 +1000,8 @@ func TestGetPlaylist(t *testing.T) {
 			p.Tracks = append(p.Tracks, t)
 			return &p, nil, &[]int{http.StatusOK}[0]
 		}
-		request := httptest.NewRequest("GET", "/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4", nil)
+		request := httptest.NewRequest("GET", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
 		responseRecorder := httptest.NewRecorder()
 
 		GetPlaylist(responseRecorder, request)
@@ -847,8 +1009,52 @@ func TestGetPlaylist(t *testing.T) {
 			t.Errorf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
 		}
 	})
-	t.Run("getPlaylistByUrl throws an error", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+	t.Run("Locked playlist shows the device holding the lock", func(t *testing.T) {
+		getPlaylistByUuidVar = func(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
+			var p Playlist
+			p.Id = 1
+			p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
+			p.LockDeviceUuid = "0b5c6d2e-54c2-4bd8-9d5c-0f4a4f1c3a11"
+			p.ClientLockExpires = time.Now().Add(5 * time.Minute).Unix()
+			return &p, nil, &[]int{http.StatusOK}[0]
+		}
+		lookupDeviceVar = func(uuid string) (*storage.Device, error) {
+			return &storage.Device{Id: 1, Uuid: uuid, Name: "Kitchen Speaker"}, nil
+		}
+		request := httptest.NewRequest("GET", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		GetPlaylist(responseRecorder, request)
+		var playlist Playlist
+		json.NewDecoder(responseRecorder.Body).Decode(&playlist)
+		if playlist.Lock == nil || !playlist.Lock.Locked ||
+			playlist.Lock.Device == nil || playlist.Lock.Device.Name != "Kitchen Speaker" {
+			t.Errorf("Want playlist locked by '%s', got '%+v'", "Kitchen Speaker", playlist.Lock)
+		}
+	})
+	t.Run("Expired lock is reported as unlocked", func(t *testing.T) {
+		getPlaylistByUuidVar = func(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
+			var p Playlist
+			p.Id = 1
+			p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
+			p.LockDeviceUuid = "0b5c6d2e-54c2-4bd8-9d5c-0f4a4f1c3a11"
+			p.ClientLockExpires = time.Now().Add(-5 * time.Minute).Unix()
+			return &p, nil, &[]int{http.StatusOK}[0]
+		}
+		request := httptest.NewRequest("GET", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+
+		GetPlaylist(responseRecorder, request)
+		var playlist Playlist
+		json.NewDecoder(responseRecorder.Body).Decode(&playlist)
+		if playlist.Lock == nil || playlist.Lock.Locked {
+			t.Errorf("Want playlist unlocked, got '%+v'", playlist.Lock)
+		}
+	})
+	t.Run("getPlaylistByUuid throws an error", func(t *testing.T) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -856,10 +1062,11 @@ func TestGetPlaylist(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		getPlaylistByUrlPath = func(url string, claims *userLogin.Claims) (*Playlist, error, *int) {
+		getPlaylistByUuidVar = func(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
 			return nil, errors.New("Error Thrown"), &[]int{http.StatusInternalServerError}[0]
 		}
-		request := httptest.NewRequest("GET", "/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4", nil)
+		request := httptest.NewRequest("GET", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
 		responseRecorder := httptest.NewRecorder()
 
 		GetPlaylist(responseRecorder, request)
@@ -870,12 +1077,14 @@ func TestGetPlaylist(t *testing.T) {
 }
 
 func TestDeletePlaylist(t *testing.T) {
+	defer restoreLockTest()
 	t.Run("Invalid token", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			return nil, http.StatusUnauthorized
 		}
 
-		request := httptest.NewRequest("GET", "/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4", nil)
+		request := httptest.NewRequest("GET", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
 		responseRecorder := httptest.NewRecorder()
 
 		DeletePlaylist(responseRecorder, request)
@@ -884,19 +1093,19 @@ func TestDeletePlaylist(t *testing.T) {
 		}
 	})
 	t.Run("Delete Playlist details", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
 			}
 			return claims, http.StatusOK
 		}
-		getPlaylistByUrlPath = func(url string, claims *userLogin.Claims) (*Playlist, error, *int) {
+		getPlaylistByUuidVar = func(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
 			var p Playlist
 			p.Id = 1
 			p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
 			p.Name = "Test Playlist 1"
-			p.CurrentTrackId = 1
+			p.CurrentTrackUuid = "48cf9b84-6162-430a-92ac-6804146ad2a5"
 			p.Elapsed = 0
 			t := &storage.Track{Id: 1,
 				Path:             "/mnt/sdb/Album1/Track1.mp3",
@@ -929,7 +1138,8 @@ func TestDeletePlaylist(t *testing.T) {
 			return nil
 		}
 
-		request := httptest.NewRequest("DELETE", "/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4", nil)
+		request := httptest.NewRequest("DELETE", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
 		responseRecorder := httptest.NewRecorder()
 
 		DeletePlaylist(responseRecorder, request)
@@ -937,19 +1147,20 @@ func TestDeletePlaylist(t *testing.T) {
 			t.Errorf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
 		}
 	})
-	t.Run("getPlaylistByUrl returns an error", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+	t.Run("getPlaylistByUuid returns an error", func(t *testing.T) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
 			}
 			return claims, http.StatusOK
 		}
-		getPlaylistByUrlPath = func(url string, claims *userLogin.Claims) (*Playlist, error, *int) {
+		getPlaylistByUuidVar = func(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
 			return nil, errors.New("Error Thrown"), &[]int{http.StatusInternalServerError}[0]
 		}
 
-		request := httptest.NewRequest("DELETE", "/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4", nil)
+		request := httptest.NewRequest("DELETE", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
 		responseRecorder := httptest.NewRecorder()
 
 		DeletePlaylist(responseRecorder, request)
@@ -958,19 +1169,19 @@ func TestDeletePlaylist(t *testing.T) {
 		}
 	})
 	t.Run("Delete Playlist details", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
 			}
 			return claims, http.StatusOK
 		}
-		getPlaylistByUrlPath = func(url string, claims *userLogin.Claims) (*Playlist, error, *int) {
+		getPlaylistByUuidVar = func(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
 			var p Playlist
 			p.Id = 1
 			p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
 			p.Name = "Test Playlist 1"
-			p.CurrentTrackId = 1
+			p.CurrentTrackUuid = "48cf9b84-6162-430a-92ac-6804146ad2a5"
 			p.Elapsed = 0
 			t := &storage.Track{Id: 1,
 				Path:             "/mnt/sdb/Album1/Track1.mp3",
@@ -1003,7 +1214,8 @@ func TestDeletePlaylist(t *testing.T) {
 			return errors.New("Some kind of error")
 		}
 
-		request := httptest.NewRequest("DELETE", "/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4", nil)
+		request := httptest.NewRequest("DELETE", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
 		responseRecorder := httptest.NewRecorder()
 
 		DeletePlaylist(responseRecorder, request)
@@ -1015,7 +1227,7 @@ func TestDeletePlaylist(t *testing.T) {
 
 func TestListPlaylist(t *testing.T) {
 	t.Run("List Playlists", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -1023,13 +1235,12 @@ func TestListPlaylist(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			user := User{}
 			user.FirstName = "Test"
 			user.LastName = "User"
 			user.EmailAddress = "test@test.com"
 			user.Enabled = true
-			user.AdminUser = false
 
 			var userlist []*User
 			userlist = append(userlist, &user)
@@ -1042,11 +1253,10 @@ func TestListPlaylist(t *testing.T) {
 			m.EmailAddress = "test@test.com"
 			m.Id = 2
 			m.Enabled = true
-			m.AdminUser = false
 			p := &storage.Playlist{}
 			p.Id = 1
 			p.Name = "Test Playlist 1"
-			p.CurrentTrackId = 1
+			p.CurrentTrackUuid = "48cf9b84-6162-430a-92ac-6804146ad2a5"
 			p.Elapsed = 0
 			t := &storage.Track{Id: 1,
 				Path:             "/mnt/sdb/Album1/Track1.mp3",
@@ -1073,7 +1283,7 @@ func TestListPlaylist(t *testing.T) {
 			p = &storage.Playlist{}
 			p.Id = 2
 			p.Name = "Test Playlist 2"
-			p.CurrentTrackId = 1
+			p.CurrentTrackUuid = "48cf9b84-6162-430a-92ac-6804146ad2a5"
 			p.Elapsed = 0
 			t = &storage.Track{Id: 4,
 				Path:             "/mnt/sdb/Album2/Track1.mp3",
@@ -1094,7 +1304,7 @@ func TestListPlaylist(t *testing.T) {
 			return nil
 		}
 
-		request := httptest.NewRequest("GET", "/playlist/", nil)
+		request := httptest.NewRequest("GET", "/playlists/", nil)
 		responseRecorder := httptest.NewRecorder()
 
 		ListPlaylist(responseRecorder, request)
@@ -1103,7 +1313,7 @@ func TestListPlaylist(t *testing.T) {
 		}
 	})
 	t.Run("List Users Playlists, but user has none", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -1111,13 +1321,12 @@ func TestListPlaylist(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			user := User{}
 			user.FirstName = "Test"
 			user.LastName = "User"
 			user.EmailAddress = "test@test.com"
 			user.Enabled = true
-			user.AdminUser = false
 
 			var userlist []*User
 			userlist = append(userlist, &user)
@@ -1130,12 +1339,11 @@ func TestListPlaylist(t *testing.T) {
 			m.EmailAddress = "test@test.com"
 			m.Id = 2
 			m.Enabled = true
-			m.AdminUser = false
 
 			return nil
 		}
 
-		request := httptest.NewRequest("GET", "/playlist/", nil)
+		request := httptest.NewRequest("GET", "/playlists/", nil)
 		responseRecorder := httptest.NewRecorder()
 
 		ListPlaylist(responseRecorder, request)
@@ -1144,17 +1352,16 @@ func TestListPlaylist(t *testing.T) {
 		}
 	})
 	t.Run("invalid token", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			return nil, http.StatusUnauthorized
 		}
 
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			user := User{}
 			user.FirstName = "Test"
 			user.LastName = "User"
 			user.EmailAddress = "test@test.com"
 			user.Enabled = true
-			user.AdminUser = false
 
 			var userlist []*User
 			userlist = append(userlist, &user)
@@ -1167,12 +1374,11 @@ func TestListPlaylist(t *testing.T) {
 			m.EmailAddress = "test@test.com"
 			m.Id = 2
 			m.Enabled = true
-			m.AdminUser = false
 
 			return nil
 		}
 
-		request := httptest.NewRequest("GET", "/playlist/", nil)
+		request := httptest.NewRequest("GET", "/playlists/", nil)
 		responseRecorder := httptest.NewRecorder()
 
 		ListPlaylist(responseRecorder, request)
@@ -1181,7 +1387,7 @@ func TestListPlaylist(t *testing.T) {
 		}
 	})
 	t.Run("user is not found", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -1189,13 +1395,12 @@ func TestListPlaylist(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			user := User{}
 			user.FirstName = "Test"
 			user.LastName = "User"
 			user.EmailAddress = "test@test.com"
 			user.Enabled = true
-			user.AdminUser = false
 
 			var userlist []*User
 			return userlist, nil
@@ -1207,12 +1412,11 @@ func TestListPlaylist(t *testing.T) {
 			m.EmailAddress = "test@test.com"
 			m.Id = 2
 			m.Enabled = true
-			m.AdminUser = false
 
 			return nil
 		}
 
-		request := httptest.NewRequest("GET", "/playlist/", nil)
+		request := httptest.NewRequest("GET", "/playlists/", nil)
 		responseRecorder := httptest.NewRecorder()
 
 		ListPlaylist(responseRecorder, request)
@@ -1223,13 +1427,17 @@ func TestListPlaylist(t *testing.T) {
 }
 
 func TestAddTrack(t *testing.T) {
+	libraryTest()
+	defer restoreLockTest()
+
 	t.Run("Invalid token", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			return nil, http.StatusUnauthorized
 		}
 
 		var data = `{"path":"/mnt/sdb/sorted-mp3z/Album/Track1","artistName":"Frankie","songName":"Track 1","albumName":"Album","albumTrackNumber":1}`
-		request := httptest.NewRequest("POST", "/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4/track", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/track", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
 		responseRecorder := httptest.NewRecorder()
 		AddTrack(responseRecorder, request)
 		if responseRecorder.Code != http.StatusUnauthorized {
@@ -1237,7 +1445,7 @@ func TestAddTrack(t *testing.T) {
 		}
 	})
 	t.Run("getPlaylist Failed", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -1245,12 +1453,13 @@ func TestAddTrack(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		getPlaylistByUrlPath = func(url string, claims *userLogin.Claims) (*Playlist, error, *int) {
+		getPlaylistByUuidVar = func(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
 			return nil, errors.New("Error Thrown"), &[]int{http.StatusInternalServerError}[0]
 		}
 
 		var data = `{"path":"/mnt/sdb/sorted-mp3z/Album/Track1","artistName":"Frankie","songName":"Track 1","albumName":"Album","albumTrackNumber":1}`
-		request := httptest.NewRequest("POST", "/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4/track", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/track", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
 		responseRecorder := httptest.NewRecorder()
 		AddTrack(responseRecorder, request)
 		if responseRecorder.Code != http.StatusInternalServerError {
@@ -1259,7 +1468,7 @@ func TestAddTrack(t *testing.T) {
 		}
 	})
 	t.Run("Unknown fields in JSON", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -1267,18 +1476,19 @@ func TestAddTrack(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		getPlaylistByUrlPath = func(url string, claims *userLogin.Claims) (*Playlist, error, *int) {
+		getPlaylistByUuidVar = func(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
 			var p Playlist
 			p.Id = 1
 			p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
 			p.Name = "Test Playlist 1"
-			p.CurrentTrackId = 1
+			p.CurrentTrackUuid = "48cf9b84-6162-430a-92ac-6804146ad2a5"
 			p.Elapsed = 0
 			return &p, nil, &[]int{http.StatusOK}[0]
 		}
 
 		var data = `{"pathogen":"/mnt/sdb/sorted-mp3z/Album/Track1","artistName":"Frankie","songName":"Track 1","albumName":"Album","albumTrackNumber":1}`
-		request := httptest.NewRequest("POST", "/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4/track", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/track", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
 		responseRecorder := httptest.NewRecorder()
 		AddTrack(responseRecorder, request)
 		if responseRecorder.Code != http.StatusBadRequest {
@@ -1287,7 +1497,7 @@ func TestAddTrack(t *testing.T) {
 		}
 	})
 	t.Run("Adding a track Failed", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -1295,12 +1505,12 @@ func TestAddTrack(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		getPlaylistByUrlPath = func(url string, claims *userLogin.Claims) (*Playlist, error, *int) {
+		getPlaylistByUuidVar = func(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
 			var p Playlist
 			p.Id = 1
 			p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
 			p.Name = "Test Playlist 1"
-			p.CurrentTrackId = 1
+			p.CurrentTrackUuid = "48cf9b84-6162-430a-92ac-6804146ad2a5"
 			p.Elapsed = 0
 			return &p, nil, &[]int{http.StatusOK}[0]
 		}
@@ -1309,7 +1519,8 @@ func TestAddTrack(t *testing.T) {
 		}
 
 		var data = `{"path":"/mnt/sdb/sorted-mp3z/Album/Track1","artistName":"Frankie","songName":"Track 1","albumName":"Album","albumTrackNumber":1}`
-		request := httptest.NewRequest("POST", "/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4/track", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/track", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
 		responseRecorder := httptest.NewRecorder()
 		AddTrack(responseRecorder, request)
 		if responseRecorder.Code != http.StatusBadRequest {
@@ -1318,7 +1529,7 @@ func TestAddTrack(t *testing.T) {
 		}
 	})
 	t.Run("Adding a track Succeeded", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -1326,12 +1537,12 @@ func TestAddTrack(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		getPlaylistByUrlPath = func(url string, claims *userLogin.Claims) (*Playlist, error, *int) {
+		getPlaylistByUuidVar = func(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
 			var p Playlist
 			p.Id = 1
 			p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
 			p.Name = "Test Playlist 1"
-			p.CurrentTrackId = 1
+			p.CurrentTrackUuid = "48cf9b84-6162-430a-92ac-6804146ad2a5"
 			p.Elapsed = 0
 			return &p, nil, &[]int{http.StatusOK}[0]
 		}
@@ -1340,7 +1551,8 @@ func TestAddTrack(t *testing.T) {
 		}
 
 		var data = `{"path":"/mnt/sdb/sorted-mp3z/Album/Track1","artistName":"Frankie","songName":"Track 1","albumName":"Album","albumTrackNumber":1}`
-		request := httptest.NewRequest("POST", "/playlist/48cf9b84-6162-430a-92ac-6804146ad2a4/track", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/track", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
 		responseRecorder := httptest.NewRecorder()
 		AddTrack(responseRecorder, request)
 		if responseRecorder.Code != http.StatusOK {
@@ -1348,16 +1560,90 @@ func TestAddTrack(t *testing.T) {
 			t.Errorf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
 		}
 	})
+	t.Run("Adding a track at a position", func(t *testing.T) {
+		getPlaylistByUuidVar = func(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
+			var p Playlist
+			p.Id = 1
+			p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
+			p.Tracks = []*storage.Track{{Uuid: "48cf9b84-6162-430a-92ac-6804146ad2a5"}}
+			return &p, nil, &[]int{http.StatusOK}[0]
+		}
+		executeAddTrack = func(p *Playlist, t *Track) (*uint64, error) {
+			t.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a6"
+			p.Tracks = append(p.Tracks, &t.Track)
+			return &[]uint64{1}[0], nil
+		}
+		var order []string
+		executeReorderTracks = func(p *Playlist, o []string) error {
+			order = o
+			return nil
+		}
+		var published []events.Event
+		publishEvent = func(username string, event events.Event) {
+			published = append(published, event)
+		}
+
+		var data = `{"songName":"Track 0","position":1}`
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/track", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+		AddTrack(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if len(order) != 2 || order[0] != "48cf9b84-6162-430a-92ac-6804146ad2a6" {
+			t.Errorf("Want the new track first, got %v", order)
+		}
+		if len(published) != 1 || published[0].Type != events.PlaylistTracks {
+			t.Errorf("Want one '%s' event, got '%+v'", events.PlaylistTracks, published)
+		}
+
+		data = `{"songName":"Track 0","position":3}`
+		request = httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/track", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder = httptest.NewRecorder()
+		AddTrack(responseRecorder, request)
+		if responseRecorder.Code != http.StatusBadRequest {
+			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
+		}
+	})
+	t.Run("Only the device holding the lock can insert at a position", func(t *testing.T) {
+		getPlaylistByUuidVar = func(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
+			var p Playlist
+			p.Id = 1
+			p.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
+			p.Tracks = []*storage.Track{{Uuid: "48cf9b84-6162-430a-92ac-6804146ad2a5"}}
+			p.LockDeviceUuid = otherDevice
+			p.ClientLockExpires = time.Now().Add(time.Minute).Unix()
+			return &p, nil, &[]int{http.StatusOK}[0]
+		}
+		executeAddTrack = func(p *Playlist, t *Track) (*uint64, error) {
+			return nil, errors.New("Track should not be added while another device holds the lock")
+		}
+
+		var data = `{"songName":"Track 0","position":1}`
+		request := httptest.NewRequest("POST", "/playlists/48cf9b84-6162-430a-92ac-6804146ad2a4/track", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "48cf9b84-6162-430a-92ac-6804146ad2a4"})
+		responseRecorder := httptest.NewRecorder()
+		AddTrack(responseRecorder, request)
+		if responseRecorder.Code != http.StatusLocked {
+			t.Errorf("Want status '%d', got '%d'", http.StatusLocked, responseRecorder.Code)
+		}
+	})
 }
 
 func TestUpdateTrack(t *testing.T) {
+	libraryTest()
+	defer restoreLockTest()
+
 	t.Run("Invalid token", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			return nil, http.StatusUnauthorized
 		}
 
 		var data = `{"path":"Album of Testing Awesomeness/01 - Track.ogg"}`
-		request := httptest.NewRequest("PATCH", "/track/5e638c1c-adce-46de-b780-d8247bd91e78", strings.NewReader(data))
+		request := httptest.NewRequest("PATCH", "/tracks/5e638c1c-adce-46de-b780-d8247bd91e78", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "5e638c1c-adce-46de-b780-d8247bd91e78"})
 		responseRecorder := httptest.NewRecorder()
 		UpdateTrack(responseRecorder, request)
 		if responseRecorder.Code != http.StatusUnauthorized {
@@ -1366,7 +1652,7 @@ func TestUpdateTrack(t *testing.T) {
 		}
 	})
 	t.Run("Get track failed", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -1374,12 +1660,13 @@ func TestUpdateTrack(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		getTrackByUrlPath = func(path string, claims *userLogin.Claims) (*Track, error, *int) {
+		getTrackByUuidVar = func(uuid string, claims *userLogin.Claims) (*Track, error, *int) {
 			return nil, errors.New("Error Thrown"), &[]int{http.StatusInternalServerError}[0]
 		}
 
 		var data = `{"path":"Album of Testing Awesomeness/01 - Track.ogg"}`
-		request := httptest.NewRequest("PATCH", "/track/5e638c1c-adce-46de-b780-d8247bd91e78", strings.NewReader(data))
+		request := httptest.NewRequest("PATCH", "/tracks/5e638c1c-adce-46de-b780-d8247bd91e78", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "5e638c1c-adce-46de-b780-d8247bd91e78"})
 		responseRecorder := httptest.NewRecorder()
 		UpdateTrack(responseRecorder, request)
 		if responseRecorder.Code != http.StatusInternalServerError {
@@ -1388,7 +1675,7 @@ func TestUpdateTrack(t *testing.T) {
 		}
 	})
 	t.Run("Unknown fields in JSON", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -1396,7 +1683,7 @@ func TestUpdateTrack(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		getTrackByUrlPath = func(path string, claims *userLogin.Claims) (*Track, error, *int) {
+		getTrackByUuidVar = func(uuid string, claims *userLogin.Claims) (*Track, error, *int) {
 			var t Track
 			t.Id = 1
 			t.Uuid = "5e638c1c-adce-46de-b780-d8247bd91e78"
@@ -1410,7 +1697,8 @@ func TestUpdateTrack(t *testing.T) {
 		}
 
 		var data = `{"pathHome":"Album of Testing Awesomeness/01 - Track.ogg"}`
-		request := httptest.NewRequest("PATCH", "/track/5e638c1c-adce-46de-b780-d8247bd91e78", strings.NewReader(data))
+		request := httptest.NewRequest("PATCH", "/tracks/5e638c1c-adce-46de-b780-d8247bd91e78", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "5e638c1c-adce-46de-b780-d8247bd91e78"})
 		responseRecorder := httptest.NewRecorder()
 		UpdateTrack(responseRecorder, request)
 		if responseRecorder.Code != http.StatusBadRequest {
@@ -1419,7 +1707,7 @@ func TestUpdateTrack(t *testing.T) {
 		}
 	})
 	t.Run("Updating a track Failed", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -1427,7 +1715,7 @@ func TestUpdateTrack(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		getTrackByUrlPath = func(path string, claims *userLogin.Claims) (*Track, error, *int) {
+		getTrackByUuidVar = func(uuid string, claims *userLogin.Claims) (*Track, error, *int) {
 			var t Track
 			t.Id = 1
 			t.Uuid = "5e638c1c-adce-46de-b780-d8247bd91e78"
@@ -1445,7 +1733,8 @@ func TestUpdateTrack(t *testing.T) {
 		}
 
 		var data = `{"path":"Album of Testing Awesomeness/01 - Track.ogg"}`
-		request := httptest.NewRequest("PATCH", "/track/5e638c1c-adce-46de-b780-d8247bd91e78", strings.NewReader(data))
+		request := httptest.NewRequest("PATCH", "/tracks/5e638c1c-adce-46de-b780-d8247bd91e78", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "5e638c1c-adce-46de-b780-d8247bd91e78"})
 		responseRecorder := httptest.NewRecorder()
 		UpdateTrack(responseRecorder, request)
 		if responseRecorder.Code != http.StatusBadRequest {
@@ -1454,7 +1743,7 @@ func TestUpdateTrack(t *testing.T) {
 		}
 	})
 	t.Run("Update a track Succeeded", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -1462,7 +1751,7 @@ func TestUpdateTrack(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		getTrackByUrlPath = func(path string, claims *userLogin.Claims) (*Track, error, *int) {
+		getTrackByUuidVar = func(uuid string, claims *userLogin.Claims) (*Track, error, *int) {
 			var t Track
 			t.Id = 1
 			t.Uuid = "5e638c1c-adce-46de-b780-d8247bd91e78"
@@ -1478,25 +1767,54 @@ func TestUpdateTrack(t *testing.T) {
 		executeUpdateTrack = func(t *Track) error {
 			return nil
 		}
+		// The track is in two playlists, one shared with a friend
+		executeFindPlaylist = func(filter storage.PlaylistFilter) ([]*Playlist, error) {
+			if filter.TrackUuid != "5e638c1c-adce-46de-b780-d8247bd91e78" {
+				return nil, nil
+			}
+			first := &Playlist{}
+			first.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a4"
+			second := &Playlist{}
+			second.Uuid = "48cf9b84-6162-430a-92ac-6804146ad2a9"
+			return []*Playlist{first, second}, nil
+		}
+		playlistFollowersVar = func(claims *userLogin.Claims, playlist *Playlist) []string {
+			if playlist.Uuid == "48cf9b84-6162-430a-92ac-6804146ad2a9" {
+				return []string{claims.Username, "friend@test.com.au"}
+			}
+			return []string{claims.Username}
+		}
+		published := map[string]int{}
+		publishEvent = func(username string, event events.Event) {
+			if event.Type == events.TrackUpdated {
+				published[username]++
+			}
+		}
 
 		var data = `{"path":"Album of Testing Awesomeness/01 - Track.ogg"}`
-		request := httptest.NewRequest("PATCH", "/track/5e638c1c-adce-46de-b780-d8247bd91e78", strings.NewReader(data))
+		request := httptest.NewRequest("PATCH", "/tracks/5e638c1c-adce-46de-b780-d8247bd91e78", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "5e638c1c-adce-46de-b780-d8247bd91e78"})
 		responseRecorder := httptest.NewRecorder()
 		UpdateTrack(responseRecorder, request)
 		if responseRecorder.Code != http.StatusOK {
 			t.Errorf("Status Message: '%s'", responseRecorder.Body)
 			t.Errorf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
 		}
+		if len(published) != 2 || published["test@test.com.au"] != 1 || published["friend@test.com.au"] != 1 {
+			t.Errorf("Want one '%s' event each for the user and the friend, got '%v'", events.TrackUpdated, published)
+		}
 	})
 }
 
 func TestDeleteTrack(t *testing.T) {
+	defer restoreLockTest()
 	t.Run("Invalid token", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			return nil, http.StatusUnauthorized
 		}
 
-		request := httptest.NewRequest("DELETE", "/track/5e638c1c-adce-46de-b780-d8247bd91e78", nil)
+		request := httptest.NewRequest("DELETE", "/tracks/5e638c1c-adce-46de-b780-d8247bd91e78", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "5e638c1c-adce-46de-b780-d8247bd91e78"})
 		responseRecorder := httptest.NewRecorder()
 		DeleteTrack(responseRecorder, request)
 		if responseRecorder.Code != http.StatusUnauthorized {
@@ -1505,7 +1823,7 @@ func TestDeleteTrack(t *testing.T) {
 		}
 	})
 	t.Run("Get track failed", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -1513,11 +1831,12 @@ func TestDeleteTrack(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		getTrackByUrlPath = func(path string, claims *userLogin.Claims) (*Track, error, *int) {
+		getTrackByUuidVar = func(uuid string, claims *userLogin.Claims) (*Track, error, *int) {
 			return nil, errors.New("Error Thrown"), &[]int{http.StatusInternalServerError}[0]
 		}
 
-		request := httptest.NewRequest("DELETE", "/track/5e638c1c-adce-46de-b780-d8247bd91e78", nil)
+		request := httptest.NewRequest("DELETE", "/tracks/5e638c1c-adce-46de-b780-d8247bd91e78", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "5e638c1c-adce-46de-b780-d8247bd91e78"})
 		responseRecorder := httptest.NewRecorder()
 		DeleteTrack(responseRecorder, request)
 		if responseRecorder.Code != http.StatusInternalServerError {
@@ -1526,7 +1845,7 @@ func TestDeleteTrack(t *testing.T) {
 		}
 	})
 	t.Run("Deleting a track Failed", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -1534,7 +1853,7 @@ func TestDeleteTrack(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		getTrackByUrlPath = func(path string, claims *userLogin.Claims) (*Track, error, *int) {
+		getTrackByUuidVar = func(uuid string, claims *userLogin.Claims) (*Track, error, *int) {
 			var t Track
 			t.Id = 1
 			t.Uuid = "5e638c1c-adce-46de-b780-d8247bd91e78"
@@ -1551,7 +1870,8 @@ func TestDeleteTrack(t *testing.T) {
 			return errors.New("Error thrown")
 		}
 
-		request := httptest.NewRequest("DELETE", "/track/5e638c1c-adce-46de-b780-d8247bd91e78", nil)
+		request := httptest.NewRequest("DELETE", "/tracks/5e638c1c-adce-46de-b780-d8247bd91e78", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "5e638c1c-adce-46de-b780-d8247bd91e78"})
 		responseRecorder := httptest.NewRecorder()
 		DeleteTrack(responseRecorder, request)
 		if responseRecorder.Code != http.StatusBadRequest {
@@ -1560,7 +1880,7 @@ func TestDeleteTrack(t *testing.T) {
 		}
 	})
 	t.Run("Deleting a track Succeeded", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*userLogin.Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*userLogin.Claims, int) {
 			claims := &userLogin.Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -1568,7 +1888,7 @@ func TestDeleteTrack(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		getTrackByUrlPath = func(path string, claims *userLogin.Claims) (*Track, error, *int) {
+		getTrackByUuidVar = func(uuid string, claims *userLogin.Claims) (*Track, error, *int) {
 			var t Track
 			t.Id = 1
 			t.Uuid = "5e638c1c-adce-46de-b780-d8247bd91e78"
@@ -1585,7 +1905,8 @@ func TestDeleteTrack(t *testing.T) {
 			return nil
 		}
 
-		request := httptest.NewRequest("DELETE", "/track/5e638c1c-adce-46de-b780-d8247bd91e78", nil)
+		request := httptest.NewRequest("DELETE", "/tracks/5e638c1c-adce-46de-b780-d8247bd91e78", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "5e638c1c-adce-46de-b780-d8247bd91e78"})
 		responseRecorder := httptest.NewRecorder()
 		DeleteTrack(responseRecorder, request)
 		if responseRecorder.Code != http.StatusOK {
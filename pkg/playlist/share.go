@@ -0,0 +1,273 @@
+package playlist
+
+import (
+	"encoding/json"
+	"errors"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"mimpidev/sinkrontrack-server/pkg/userLogin"
+	"net/http"
+	"time"
+)
+
+type ShareData struct {
+	UserId       string `json:"userId"`
+	FirstName    string `json:"firstName,omitempty"`
+	LastName     string `json:"lastName,omitempty"`
+	EmailAddress string `json:"emailAddress,omitempty"`
+	Access       string `json:"access"` // read or collaborate
+	CreatedAt    int64  `json:"createdAt,omitempty"`
+}
+
+type UpdateShareData struct {
+	Access string `json:"access"`
+}
+
+var errReadOnly = webhelper.NewError(webhelper.CodePlaylistReadOnly, "Playlist is shared read only")
+var errNotOwner = webhelper.NewError(webhelper.CodeNotOwner, "Only the playlist owner can do that")
+var errNotFriend = webhelper.NewError(webhelper.CodeNotFriends, "Playlists can only be shared with friends")
+
+var executeFindShares = func(filter storage.PlaylistShareFilter) ([]*storage.PlaylistShare, error) {
+	var search storage.PlaylistShare
+	return search.Find(filter)
+}
+
+var executeSaveShare = func(s *storage.PlaylistShare) error {
+	return s.Save()
+}
+
+var executeDeleteShare = func(s *storage.PlaylistShare) error {
+	return s.Delete()
+}
+
+var lookupUserVar = lookupUser
+
+// lookupUser loads a user by uuid
+func lookupUser(uuid string) (*User, error) {
+	user := &User{}
+	user.Uuid = uuid
+	err := user.Select()
+	return user, err
+}
+
+// findShare returns the share of the playlist with the user, or nil
+func findShare(playlistUuid string, userUuid string) (*storage.PlaylistShare, error) {
+	shares, err := executeFindShares(storage.PlaylistShareFilter{PlaylistUuid: playlistUuid, UserUuid: userUuid})
+	if err != nil || len(shares) == 0 {
+		return nil, err
+	}
+	return shares[0], nil
+}
+
+// checkCanChange stops a friend the playlist is shared with read only from
+// changing it
+func checkCanChange(w http.ResponseWriter, r *http.Request, playlist *Playlist) bool {
+	if playlist.Access == storage.ShareRead {
+		webhelper.ReturnError(w, r, errReadOnly, &[]int{http.StatusForbidden}[0])
+		return false
+	}
+	return true
+}
+
+// checkIsOwner stops anyone the playlist is shared with from doing what only
+// its owner can
+func checkIsOwner(w http.ResponseWriter, r *http.Request, playlist *Playlist) bool {
+	if playlist.Access != "" {
+		webhelper.ReturnError(w, r, errNotOwner, &[]int{http.StatusForbidden}[0])
+		return false
+	}
+	return true
+}
+
+func newShareData(share *storage.PlaylistShare) *ShareData {
+	shareData := &ShareData{
+		UserId:    share.UserUuid,
+		Access:    share.Access,
+		CreatedAt: share.CreatedAt,
+	}
+	if user, err := lookupUserVar(share.UserUuid); err == nil {
+		shareData.FirstName = user.FirstName
+		shareData.LastName = user.LastName
+		shareData.EmailAddress = user.EmailAddress
+	}
+	return shareData
+}
+
+// getSharedPlaylist loads the playlist in /playlists/{uuid}/shares/... and
+// its owner, only the owner can see and change who it is shared with
+func getSharedPlaylist(w http.ResponseWriter, r *http.Request) (*Playlist, *User, *userLogin.Claims, bool) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return nil, nil, nil, false
+	}
+
+	playlist, err, httpStatus := getPlaylistByUuidVar(webhelper.Param(r, "uuid"), claims)
+	if err != nil {
+		if webhelper.ReturnError(w, r, err, httpStatus) {
+			return nil, nil, nil, false
+		}
+	}
+	if !checkIsOwner(w, r, playlist) {
+		return nil, nil, nil, false
+	}
+	// Users with library:write can see any playlist, but only its owner can share it
+	if !isPlaylistOwnerVar(claims.Username, playlist) {
+		webhelper.ReturnError(w, r, errNotOwner, &[]int{http.StatusForbidden}[0])
+		return nil, nil, nil, false
+	}
+
+	owner := &User{}
+	owner.EmailAddress = claims.Username
+	err = owner.Select()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusUnauthorized}[0]) {
+		return nil, nil, nil, false
+	}
+	return playlist, owner, claims, true
+}
+
+// ListShares returns who the playlist is shared with
+func ListShares(w http.ResponseWriter, r *http.Request) {
+	playlist, _, _, ok := getSharedPlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	shares, err := executeFindShares(storage.PlaylistShareFilter{PlaylistUuid: playlist.Uuid})
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	shareList := []*ShareData{}
+	for _, share := range shares {
+		shareList = append(shareList, newShareData(share))
+	}
+	json.NewEncoder(w).Encode(shareList)
+	return
+}
+
+// SharePlaylist shares the playlist with a friend, or changes their access
+func SharePlaylist(w http.ResponseWriter, r *http.Request) {
+	playlist, owner, _, ok := getSharedPlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	userUuid, err := webhelper.UuidParam(r, "userUuid")
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+
+	var shareData UpdateShareData
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&shareData)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	if shareData.Access != storage.ShareRead && shareData.Access != storage.ShareCollaborate {
+		err := errors.New("Access must be " + storage.ShareRead + " or " + storage.ShareCollaborate)
+		webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0])
+		return
+	}
+
+	friend := false
+	for _, f := range owner.Friends {
+		if f.FriendId == userUuid && f.Status == storage.FriendAccepted {
+			friend = true
+		}
+	}
+	if !friend {
+		webhelper.ReturnError(w, r, errNotFriend, &[]int{http.StatusForbidden}[0])
+		return
+	}
+
+	share := &storage.PlaylistShare{
+		PlaylistUuid: playlist.Uuid,
+		OwnerUuid:    owner.Uuid,
+		UserUuid:     userUuid,
+		Access:       shareData.Access,
+		CreatedAt:    time.Now().Unix(),
+	}
+	existing, err := findShare(playlist.Uuid, userUuid)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	if existing != nil {
+		share.CreatedAt = existing.CreatedAt
+	}
+	err = executeSaveShare(share)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+
+	if existing == nil {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(newShareData(share))
+	return
+}
+
+// UnsharePlaylist stops sharing the playlist with a friend
+func UnsharePlaylist(w http.ResponseWriter, r *http.Request) {
+	playlist, _, _, ok := getSharedPlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	userUuid, err := webhelper.UuidParam(r, "userUuid")
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	share, err := findShare(playlist.Uuid, userUuid)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	if share == nil {
+		webhelper.ReturnError(w, r, storage.ErrShareNotFound, &[]int{http.StatusNotFound}[0])
+		return
+	}
+	err = executeDeleteShare(share)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+
+	var responseDetails webhelper.Response
+	responseDetails.Message = "Playlist No Longer Shared"
+	json.NewEncoder(w).Encode(responseDetails)
+	return
+}
+
+// ListSharedPlaylists returns the playlists friends have shared with the user
+func ListSharedPlaylists(w http.ResponseWriter, r *http.Request) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+	userUuid, err := lookupUserUuidVar(claims.Username)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+
+	shares, err := executeFindShares(storage.PlaylistShareFilter{UserUuid: userUuid})
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	playlists := []*Playlist{}
+	for _, share := range shares {
+		playlist := &Playlist{}
+		playlist.Uuid = share.PlaylistUuid
+		err := playlist.Select()
+		if err == storage.ErrPlaylistNotFound {
+			continue
+		}
+		if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+			return
+		}
+		playlist.Access = share.Access
+		playlist.CurrentTrackPosition = trackPosition(playlist.Tracks, playlist.CurrentTrackUuid)
+		playlists = append(playlists, playlist)
+	}
+	json.NewEncoder(w).Encode(playlists)
+	return
+}
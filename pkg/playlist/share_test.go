@@ -0,0 +1,356 @@
+package playlist
+
+import (
+	"encoding/json"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"mimpidev/sinkrontrack-server/pkg/userLogin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	sharePlaylist = "48cf9b84-6162-430a-92ac-6804146ad2a4"
+	shareOwner    = "6a1d0c3e-7b2f-4e59-a8c4-1f0e9d8c7b01"
+	shareFriend   = "6a1d0c3e-7b2f-4e59-a8c4-1f0e9d8c7b02"
+	sharePending  = "6a1d0c3e-7b2f-4e59-a8c4-1f0e9d8c7b03"
+)
+
+// shareTest stubs out a playlist owned by shareOwner, who is friends with
+// shareFriend and has a request out to sharePending. The shares are kept in
+// memory.
+func shareTest() *[]*storage.PlaylistShare {
+	lockTest("", 0)
+	shares := &[]*storage.PlaylistShare{}
+	lookupUserUuidVar = func(username string) (string, error) {
+		return shareFriend, nil
+	}
+	lookupUserVar = func(uuid string) (*User, error) {
+		user := &User{}
+		user.Uuid = uuid
+		user.EmailAddress = uuid + "@test.com.au"
+		return user, nil
+	}
+	executeSelectUser = func(m *User) error {
+		m.Uuid = shareOwner
+		m.Friends = []*storage.Friend{
+			{Id: 1, FriendId: shareFriend, Status: storage.FriendAccepted},
+			{Id: 2, FriendId: sharePending, Status: storage.FriendRequestSent},
+		}
+		return nil
+	}
+	executeFindShares = func(filter storage.PlaylistShareFilter) ([]*storage.PlaylistShare, error) {
+		var found []*storage.PlaylistShare
+		for _, share := range *shares {
+			if (filter.PlaylistUuid == "" || share.PlaylistUuid == filter.PlaylistUuid) &&
+				(filter.UserUuid == "" || share.UserUuid == filter.UserUuid) {
+				found = append(found, share)
+			}
+		}
+		return found, nil
+	}
+	executeSaveShare = func(s *storage.PlaylistShare) error {
+		for i, share := range *shares {
+			if share.PlaylistUuid == s.PlaylistUuid && share.UserUuid == s.UserUuid {
+				(*shares)[i] = s
+				return nil
+			}
+		}
+		s.Id = uint64(len(*shares)) + 1
+		*shares = append(*shares, s)
+		return nil
+	}
+	executeFindGroups = func(filter storage.GroupFilter) ([]*storage.Group, error) {
+		return nil, nil
+	}
+	executeDeleteShare = func(s *storage.PlaylistShare) error {
+		var kept []*storage.PlaylistShare
+		for _, share := range *shares {
+			if share.Id != s.Id {
+				kept = append(kept, share)
+			}
+		}
+		*shares = kept
+		return nil
+	}
+	return shares
+}
+
+func restoreShareTest() {
+	restoreLockTest()
+	lookupUserUuidVar = lookupUserUuid
+	lookupUserVar = lookupUser
+}
+
+// sharedWith makes the stubbed playlist one shared with the caller
+func sharedWith(access string) {
+	getPlaylistByUuidVar = func(uuid string, claims *userLogin.Claims) (*Playlist, error, *int) {
+		p := &Playlist{}
+		p.Id = 1
+		p.Uuid = sharePlaylist
+		p.Access = access
+		p.Tracks = []*storage.Track{{Id: 1, Uuid: orderTrack1}, {Id: 2, Uuid: orderTrack2}}
+		return p, nil, &[]int{http.StatusOK}[0]
+	}
+}
+
+func TestGetSharedPlaylistByUrl(t *testing.T) {
+	defer restoreShareTest()
+	claims := &userLogin.Claims{
+		Username:       "friend@test.com.au",
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
+	}
+	// Only found when it isn't limited to the caller's own playlists
+	executeFindPlaylist = func(filter storage.PlaylistFilter) ([]*Playlist, error) {
+		if filter.OwnerEmail != "" {
+			return nil, nil
+		}
+		p := &Playlist{}
+		p.Id = 1
+		p.Uuid = filter.Uuid
+		return []*Playlist{p}, nil
+	}
+
+	t.Run("A playlist shared by a friend", func(t *testing.T) {
+		shares := shareTest()
+		*shares = append(*shares, &storage.PlaylistShare{Id: 1, PlaylistUuid: sharePlaylist, OwnerUuid: shareOwner, UserUuid: shareFriend, Access: storage.ShareRead})
+
+		playlist, err, statusCode := getPlaylistByUuid(sharePlaylist, claims)
+		if *statusCode != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d' '%v'", http.StatusOK, *statusCode, err)
+		}
+		if playlist.Access != storage.ShareRead {
+			t.Errorf("Want access '%s', got '%s'", storage.ShareRead, playlist.Access)
+		}
+	})
+	t.Run("A playlist that isn't shared", func(t *testing.T) {
+		shareTest()
+
+		_, _, statusCode := getPlaylistByUuid(sharePlaylist, claims)
+		if *statusCode != http.StatusNotFound {
+			t.Errorf("Want status '%d', got '%d'", http.StatusNotFound, *statusCode)
+		}
+	})
+	t.Run("Any playlist can be read with library:read", func(t *testing.T) {
+		shareTest()
+		auditor := &userLogin.Claims{
+			Username:       "auditor@test.com.au",
+			Permissions:    []string{userLogin.PermLibraryRead},
+			StandardClaims: claims.StandardClaims,
+		}
+
+		playlist, err, statusCode := getPlaylistByUuid(sharePlaylist, auditor)
+		if *statusCode != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d' '%v'", http.StatusOK, *statusCode, err)
+		}
+		if playlist.Access != storage.ShareRead {
+			t.Errorf("Want access '%s', got '%s'", storage.ShareRead, playlist.Access)
+		}
+	})
+}
+
+func TestSharePlaylist(t *testing.T) {
+	defer restoreShareTest()
+
+	t.Run("Share with a friend, then change their access", func(t *testing.T) {
+		shares := shareTest()
+		request := httptest.NewRequest("PUT", "/playlists/"+sharePlaylist+"/shares/"+shareFriend, strings.NewReader(`{"access":"read"}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": sharePlaylist, "userUuid": shareFriend})
+		responseRecorder := httptest.NewRecorder()
+
+		SharePlaylist(responseRecorder, request)
+		if responseRecorder.Code != http.StatusCreated {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusCreated, responseRecorder.Code)
+		}
+		if len(*shares) != 1 || (*shares)[0].OwnerUuid != shareOwner || (*shares)[0].Access != storage.ShareRead {
+			t.Errorf("Want a read share from the owner, got '%+v'", *shares)
+		}
+
+		request = httptest.NewRequest("PUT", "/playlists/"+sharePlaylist+"/shares/"+shareFriend, strings.NewReader(`{"access":"collaborate"}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": sharePlaylist, "userUuid": shareFriend})
+		responseRecorder = httptest.NewRecorder()
+		SharePlaylist(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		var share ShareData
+		json.NewDecoder(responseRecorder.Body).Decode(&share)
+		if len(*shares) != 1 || share.Access != storage.ShareCollaborate || share.EmailAddress == "" {
+			t.Errorf("Want the share changed to collaborate, got '%+v'", share)
+		}
+	})
+	t.Run("Only friends can be shared with", func(t *testing.T) {
+		shareTest()
+		request := httptest.NewRequest("PUT", "/playlists/"+sharePlaylist+"/shares/"+sharePending, strings.NewReader(`{"access":"read"}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": sharePlaylist, "userUuid": sharePending})
+		responseRecorder := httptest.NewRecorder()
+
+		SharePlaylist(responseRecorder, request)
+		if responseRecorder.Code != http.StatusForbidden {
+			t.Errorf("Want status '%d', got '%d'", http.StatusForbidden, responseRecorder.Code)
+		}
+	})
+	t.Run("Unknown access", func(t *testing.T) {
+		shareTest()
+		request := httptest.NewRequest("PUT", "/playlists/"+sharePlaylist+"/shares/"+shareFriend, strings.NewReader(`{"access":"admin"}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": sharePlaylist, "userUuid": shareFriend})
+		responseRecorder := httptest.NewRecorder()
+
+		SharePlaylist(responseRecorder, request)
+		if responseRecorder.Code != http.StatusBadRequest {
+			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
+		}
+	})
+	t.Run("A collaborator can't share the playlist", func(t *testing.T) {
+		shareTest()
+		sharedWith(storage.ShareCollaborate)
+		request := httptest.NewRequest("PUT", "/playlists/"+sharePlaylist+"/shares/"+shareFriend, strings.NewReader(`{"access":"read"}`))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": sharePlaylist, "userUuid": shareFriend})
+		responseRecorder := httptest.NewRecorder()
+
+		SharePlaylist(responseRecorder, request)
+		if responseRecorder.Code != http.StatusForbidden {
+			t.Errorf("Want status '%d', got '%d'", http.StatusForbidden, responseRecorder.Code)
+		}
+	})
+}
+
+func TestUnsharePlaylist(t *testing.T) {
+	defer restoreShareTest()
+
+	t.Run("Stop sharing", func(t *testing.T) {
+		shares := shareTest()
+		*shares = append(*shares, &storage.PlaylistShare{Id: 1, PlaylistUuid: sharePlaylist, OwnerUuid: shareOwner, UserUuid: shareFriend, Access: storage.ShareRead})
+		request := httptest.NewRequest("DELETE", "/playlists/"+sharePlaylist+"/shares/"+shareFriend, nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": sharePlaylist, "userUuid": shareFriend})
+		responseRecorder := httptest.NewRecorder()
+
+		UnsharePlaylist(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK || len(*shares) != 0 {
+			t.Errorf("Want status '%d' and no shares, got '%d' and %d", http.StatusOK, responseRecorder.Code, len(*shares))
+		}
+	})
+	t.Run("Not shared with that user", func(t *testing.T) {
+		shareTest()
+		request := httptest.NewRequest("DELETE", "/playlists/"+sharePlaylist+"/shares/"+shareFriend, nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": sharePlaylist, "userUuid": shareFriend})
+		responseRecorder := httptest.NewRecorder()
+
+		UnsharePlaylist(responseRecorder, request)
+		if responseRecorder.Code != http.StatusNotFound {
+			t.Errorf("Want status '%d', got '%d'", http.StatusNotFound, responseRecorder.Code)
+		}
+	})
+}
+
+func TestSharedAccess(t *testing.T) {
+	defer restoreShareTest()
+
+	tests := []struct {
+		name    string
+		access  string
+		method  string
+		url     string
+		data    string
+		handler http.HandlerFunc
+		want    int
+	}{
+		{"Read only can't change the position", storage.ShareRead, "PATCH", "/playlists/" + sharePlaylist, `{"elapsed":10}`, UpdatePlaylist, http.StatusForbidden},
+		{"Read only can't reorder", storage.ShareRead, "PUT", "/playlists/" + sharePlaylist + "/tracks", `{"tracks":[]}`, ReorderTracks, http.StatusForbidden},
+		{"Read only can't add tracks", storage.ShareRead, "POST", "/playlists/" + sharePlaylist + "/track", `{"track":"` + orderTrack3 + `"}`, AddTrack, http.StatusForbidden},
+		{"Read only can't lock", storage.ShareRead, "POST", "/playlists/" + sharePlaylist + "/lock", ``, AcquireLock, http.StatusForbidden},
+		{"Read only can see the lock", storage.ShareRead, "GET", "/playlists/" + sharePlaylist + "/lock", ``, GetLock, http.StatusOK},
+		{"Collaborator can't rename", storage.ShareCollaborate, "PATCH", "/playlists/" + sharePlaylist, `{"name":"Mine now"}`, UpdatePlaylist, http.StatusForbidden},
+		{"Collaborator can't delete", storage.ShareCollaborate, "DELETE", "/playlists/" + sharePlaylist, ``, DeletePlaylist, http.StatusForbidden},
+		{"Collaborator can reorder", storage.ShareCollaborate, "PUT", "/playlists/" + sharePlaylist + "/tracks", `{"tracks":["` + orderTrack2 + `","` + orderTrack1 + `"]}`, ReorderTracks, http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shareTest()
+			sharedWith(test.access)
+			executeReorderTracks = func(p *Playlist, order []string) error {
+				return nil
+			}
+			executeUpdateLock = func(p *Playlist, heldBy string) error {
+				return nil
+			}
+			request := httptest.NewRequest(test.method, test.url, strings.NewReader(test.data))
+			request = webhelper.WithParams(request, webhelper.Params{"uuid": sharePlaylist})
+			responseRecorder := httptest.NewRecorder()
+
+			test.handler(responseRecorder, request)
+			if responseRecorder.Code != test.want {
+				t.Errorf("Want status '%d', got '%d'", test.want, responseRecorder.Code)
+			}
+		})
+	}
+}
+
+func TestListSharedPlaylists(t *testing.T) {
+	defer restoreShareTest()
+
+	shares := shareTest()
+	*shares = append(*shares,
+		&storage.PlaylistShare{Id: 1, PlaylistUuid: sharePlaylist, OwnerUuid: shareOwner, UserUuid: shareFriend, Access: storage.ShareCollaborate},
+		&storage.PlaylistShare{Id: 2, PlaylistUuid: "deleted", OwnerUuid: shareOwner, UserUuid: shareFriend, Access: storage.ShareRead})
+	executeSelectPlaylist = func(p *Playlist) error {
+		if p.Uuid != sharePlaylist {
+			return storage.ErrPlaylistNotFound
+		}
+		p.Name = "Road Trip"
+		return nil
+	}
+	request := httptest.NewRequest("GET", "/playlists/shared", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	ListSharedPlaylists(responseRecorder, request)
+	var playlists []*Playlist
+	json.NewDecoder(responseRecorder.Body).Decode(&playlists)
+	if len(playlists) != 1 || playlists[0].Name != "Road Trip" || playlists[0].Access != storage.ShareCollaborate {
+		t.Errorf("Want the collaborative playlist, got %+v", playlists)
+	}
+}
+
+func TestPlaylistFollowers(t *testing.T) {
+	shares := shareTest()
+	defer restoreShareTest()
+	findUsers := executeFindUsers
+	defer func() { executeFindUsers = findUsers }()
+	executeFindUsers = func(filter storage.UserFilter) ([]*storage.User, error) {
+		if filter.PlaylistUuid != sharePlaylist {
+			t.Errorf("Want the owner of '%s', got '%+v'", sharePlaylist, filter)
+		}
+		return []*storage.User{{Uuid: shareOwner, EmailAddress: shareOwner + "@test.com.au"}}, nil
+	}
+	*shares = append(*shares, &storage.PlaylistShare{Id: 1, PlaylistUuid: sharePlaylist, OwnerUuid: shareOwner, UserUuid: shareFriend, Access: storage.ShareCollaborate})
+	executeFindGroups = func(filter storage.GroupFilter) ([]*storage.Group, error) {
+		if filter.Uuid != groupUuid {
+			t.Errorf("Want group '%s', got '%+v'", groupUuid, filter)
+		}
+		return []*storage.Group{{Id: 1, Uuid: groupUuid, Members: []*storage.GroupMember{
+			{UserUuid: shareOwner, Manager: true},
+			{UserUuid: sharePending},
+		}}}, nil
+	}
+	playlist := &Playlist{}
+	playlist.Uuid = sharePlaylist
+	playlist.GroupUuid = groupUuid
+	claims := &userLogin.Claims{Username: shareFriend + "@test.com.au"}
+
+	followers := playlistFollowers(claims, playlist)
+	want := []string{shareFriend + "@test.com.au", shareOwner + "@test.com.au", sharePending + "@test.com.au"}
+	if len(followers) != len(want) {
+		t.Fatalf("Want followers '%v', got '%v'", want, followers)
+	}
+	for i := range want {
+		if followers[i] != want[i] {
+			t.Errorf("Want followers '%v', got '%v'", want, followers)
+			break
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package userLogin
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// An API key is the prefix, the key's uuid and its secret joined with
+// underscores, so it can't be mistaken for a JWT
+const apiKeyPrefix = "stk"
+
+var errMissingApiKeyName = errors.New("Missing API Key Name")
+var errNoScopes = errors.New("At least one scope is required")
+var errExpiryInPast = errors.New("The expiry has to be in the future")
+
+var checkApiKeyVar = checkApiKey
+
+var executeInsertApiKey = func(k *storage.ApiKey) (*uint64, error) {
+	return k.Insert()
+}
+
+var executeUpdateApiKey = func(k *storage.ApiKey) error {
+	return k.Update()
+}
+
+var executeDeleteApiKey = func(k *storage.ApiKey) error {
+	return k.Delete()
+}
+
+var executeFindApiKeys = func(filter storage.ApiKeyFilter) ([]*storage.ApiKey, error) {
+	var search storage.ApiKey
+	return search.Find(filter)
+}
+
+type ApiKeyData struct {
+	Uuid      string   `json:"uuid"`
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	CreatedAt int64    `json:"createdAt"`           // unix seconds
+	ExpiresAt int64    `json:"expiresAt,omitempty"` // unix seconds, missing when it doesn't expire
+	LastUsed  int64    `json:"lastUsed,omitempty"`  // unix seconds
+	Key       string   `json:"key,omitempty"`       // only when it is made, it can't be shown again
+}
+
+type ApiKeyRequestData struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresAt int64    `json:"expiresAt,omitempty"` // unix seconds, leave out for a key that doesn't expire
+}
+
+func newApiKeyData(k *storage.ApiKey) *ApiKeyData {
+	return &ApiKeyData{
+		Uuid:      k.Uuid,
+		Name:      k.Name,
+		Scopes:    k.Scopes,
+		CreatedAt: k.CreatedAt,
+		ExpiresAt: k.ExpiresAt,
+		LastUsed:  k.LastUsed,
+	}
+}
+
+func hashApiKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(apiKeyPrefix + "\x00" + secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func parseApiKey(key string) (string, string, bool) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// checkApiKey is CheckToken for an API key, the claims are limited to the
+// key's scopes. Keys stop working while their user is disabled.
+func checkApiKey(key string) (*Claims, int) {
+	keyUuid, secret, ok := parseApiKey(key)
+	if !ok {
+		return nil, http.StatusUnauthorized
+	}
+	keys, err := executeFindApiKeys(storage.ApiKeyFilter{Uuid: keyUuid})
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+	if len(keys) == 0 || subtle.ConstantTimeCompare([]byte(keys[0].SecretHash), []byte(hashApiKeySecret(secret))) != 1 {
+		return nil, http.StatusUnauthorized
+	}
+	apiKey := keys[0]
+	now := time.Now()
+	if apiKey.ExpiresAt != 0 && now.Unix() >= apiKey.ExpiresAt {
+		return nil, http.StatusUnauthorized
+	}
+	var user User
+	user.Uuid = apiKey.UserUuid
+	if err := user.Select(); err != nil || !user.Enabled {
+		return nil, http.StatusUnauthorized
+	}
+	if now.Sub(time.Unix(apiKey.LastUsed, 0)) >= lastSeenInterval {
+		apiKey.LastUsed = now.Unix()
+		executeUpdateApiKey(apiKey)
+	}
+	return &Claims{
+		Username:       user.EmailAddress,
+		Scopes:         append([]string{}, apiKey.Scopes...),
+		StandardClaims: jwt.StandardClaims{ExpiresAt: apiKey.ExpiresAt},
+	}, http.StatusOK
+}
+
+// ListApiKeys returns the signed in user's API keys, without the keys
+// themselves
+func ListApiKeys(w http.ResponseWriter, r *http.Request) {
+	user, ok := signedInUser(w, r)
+	if !ok {
+		return
+	}
+	keys, err := executeFindApiKeys(storage.ApiKeyFilter{UserUuid: user.Uuid})
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	keyData := []*ApiKeyData{}
+	for _, key := range keys {
+		keyData = append(keyData, newApiKeyData(key))
+	}
+	json.NewEncoder(w).Encode(keyData)
+}
+
+// CreateApiKey makes a new API key for the signed in user, the response is
+// the only time the key is shown
+func CreateApiKey(w http.ResponseWriter, r *http.Request) {
+	user, ok := signedInUser(w, r)
+	if !ok {
+		return
+	}
+	var data ApiKeyRequestData
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	data.Name = strings.TrimSpace(data.Name)
+	if data.Name == "" {
+		webhelper.ReturnError(w, r, errMissingApiKeyName, &[]int{http.StatusBadRequest}[0])
+		return
+	}
+	if len(data.Scopes) == 0 {
+		webhelper.ReturnError(w, r, errNoScopes, &[]int{http.StatusBadRequest}[0])
+		return
+	}
+	claims, _ := requestClaimsVar(r)
+	if err := checkScopes(data.Scopes, claims.Scopes); err != nil {
+		webhelper.ReturnError(w, r, err, scopeErrorStatus(err))
+		return
+	}
+	now := time.Now().Unix()
+	if data.ExpiresAt != 0 && data.ExpiresAt <= now {
+		webhelper.ReturnError(w, r, errExpiryInPast, &[]int{http.StatusBadRequest}[0])
+		return
+	}
+
+	b := make([]byte, 32)
+	_, err = rand.Read(b)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	secret := hex.EncodeToString(b)
+	apiKey := &storage.ApiKey{
+		UserUuid:   user.Uuid,
+		Name:       data.Name,
+		SecretHash: hashApiKeySecret(secret),
+		Scopes:     data.Scopes,
+		CreatedAt:  now,
+		ExpiresAt:  data.ExpiresAt,
+	}
+	_, err = executeInsertApiKey(apiKey)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	keyData := newApiKeyData(apiKey)
+	keyData.Key = strings.Join([]string{apiKeyPrefix, apiKey.Uuid, secret}, "_")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(keyData)
+}
+
+// DeleteApiKey revokes the API key in /users/apikeys/{uuid}, it stops working
+// straight away
+func DeleteApiKey(w http.ResponseWriter, r *http.Request) {
+	user, ok := signedInUser(w, r)
+	if !ok {
+		return
+	}
+	keys, err := executeFindApiKeys(storage.ApiKeyFilter{Uuid: webhelper.Param(r, "uuid"), UserUuid: user.Uuid})
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	if len(keys) == 0 {
+		webhelper.ReturnError(w, r, storage.ErrApiKeyNotFound, &[]int{http.StatusNotFound}[0])
+		return
+	}
+	err = executeDeleteApiKey(keys[0])
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	json.NewEncoder(w).Encode(webhelper.Response{Message: "Revoked " + keys[0].Name})
+}
@@ -0,0 +1,196 @@
+package userLogin
+
+import (
+	"encoding/json"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testApiKeyUuid = "4f1c1e0a-7d0b-4c1e-9b59-0c3b1f7a2d11"
+
+// apiKeyTest stubs out one key for test@test.com on top of totpTest, with the
+// secret "secret", which restores the stubs once the test finishes. The keys
+// saved so far are returned.
+func apiKeyTest(t *testing.T, scopes []string, expiresAt int64) *[]*storage.ApiKey {
+	totpTest(t, false)
+	keys := []*storage.ApiKey{{
+		Id:         1,
+		Uuid:       testApiKeyUuid,
+		UserUuid:   emailUserUuid,
+		Name:       "Car",
+		SecretHash: hashApiKeySecret("secret"),
+		Scopes:     scopes,
+		ExpiresAt:  expiresAt,
+	}}
+	executeFindApiKeys = func(filter storage.ApiKeyFilter) ([]*storage.ApiKey, error) {
+		var found []*storage.ApiKey
+		for _, k := range keys {
+			if (filter.Uuid == "" || k.Uuid == filter.Uuid) && (filter.UserUuid == "" || k.UserUuid == filter.UserUuid) {
+				key := *k
+				found = append(found, &key)
+			}
+		}
+		return found, nil
+	}
+	executeInsertApiKey = func(k *storage.ApiKey) (*uint64, error) {
+		k.Uuid = "new-key"
+		keys = append(keys, k)
+		return &[]uint64{uint64(len(keys))}[0], nil
+	}
+	executeUpdateApiKey = func(k *storage.ApiKey) error {
+		keys[0] = k
+		return nil
+	}
+	return &keys
+}
+
+func TestCheckApiKey(t *testing.T) {
+	t.Run("A key signs in with its scopes", func(t *testing.T) {
+		keys := apiKeyTest(t, []string{ScopePositionWrite}, 0)
+
+		claims, status := checkApiKey("stk_" + testApiKeyUuid + "_secret")
+		if status != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, status)
+		}
+		if claims.Username != "test@test.com" || len(claims.Scopes) != 1 || !claims.HasScope(ScopePositionWrite) || claims.HasScope(ScopePlaylistRead) {
+			t.Errorf("Want the user limited to the key's scopes, got '%+v'", claims)
+		}
+		if (*keys)[0].LastUsed == 0 {
+			t.Error("Want the key's last use recorded")
+		}
+	})
+	tests := []struct {
+		name      string
+		key       string
+		expiresAt int64
+	}{
+		{"The wrong secret", "stk_" + testApiKeyUuid + "_guess", 0},
+		{"A key that doesn't exist", "stk_unknown_secret", 0},
+		{"An expired key", "stk_" + testApiKeyUuid + "_secret", time.Now().Add(-time.Minute).Unix()},
+		{"Not an API key", "stk_" + testApiKeyUuid, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			apiKeyTest(t, []string{ScopePositionWrite}, test.expiresAt)
+
+			if _, status := checkApiKey(test.key); status != http.StatusUnauthorized {
+				t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, status)
+			}
+		})
+	}
+	t.Run("A disabled user's key", func(t *testing.T) {
+		apiKeyTest(t, []string{ScopePositionWrite}, 0)
+		selectUser := executeSelectUser
+		executeSelectUser = func(m *User) error {
+			err := selectUser(m)
+			m.Enabled = false
+			return err
+		}
+
+		if _, status := checkApiKey("stk_" + testApiKeyUuid + "_secret"); status != http.StatusUnauthorized {
+			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, status)
+		}
+	})
+	t.Run("CheckToken hands API keys over", func(t *testing.T) {
+		restoreStubs(t)
+		var checked string
+		checkApiKeyVar = func(key string) (*Claims, int) {
+			checked = key
+			return &Claims{Username: "test@test.com", Scopes: []string{}}, http.StatusOK
+		}
+		request := httptest.NewRequest("GET", "/playlists", nil)
+		request.Header.Set("Authorization", "Bearer stk_"+testApiKeyUuid+"_secret")
+
+		if _, status := CheckToken(request); status != http.StatusOK || checked != "stk_"+testApiKeyUuid+"_secret" {
+			t.Errorf("Want the key checked, got '%d' '%s'", status, checked)
+		}
+	})
+}
+
+func TestCreateApiKey(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		callerScopes []string
+		want         int
+	}{
+		{"Makes a key", `{"name":"Car","scopes":["position:write"]}`, nil, http.StatusCreated},
+		{"Makes a key that expires", `{"name":"Car","scopes":["position:write"],"expiresAt":` + strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10) + `}`, nil, http.StatusCreated},
+		{"Needs a name", `{"name":" ","scopes":["position:write"]}`, nil, http.StatusBadRequest},
+		{"Needs a scope", `{"name":"Car","scopes":[]}`, nil, http.StatusBadRequest},
+		{"Unknown scope", `{"name":"Car","scopes":["everything"]}`, nil, http.StatusBadRequest},
+		{"Already expired", `{"name":"Car","scopes":["position:write"],"expiresAt":1000}`, nil, http.StatusBadRequest},
+		{"A key can't hand out more than it has", `{"name":"Car","scopes":["user:admin"]}`, []string{ScopePositionWrite}, http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keys := apiKeyTest(t, nil, 0)
+			requestClaimsVar = func(r *http.Request) (*Claims, int) {
+				claims, status := deviceClaims(r)
+				claims.Scopes = test.callerScopes
+				return claims, status
+			}
+			request := httptest.NewRequest("POST", "/users/apikeys", strings.NewReader(test.data))
+			responseRecorder := httptest.NewRecorder()
+
+			CreateApiKey(responseRecorder, request)
+			if responseRecorder.Code != test.want {
+				t.Fatalf("Want status '%d', got '%d'", test.want, responseRecorder.Code)
+			}
+			if test.want != http.StatusCreated {
+				if len(*keys) != 1 {
+					t.Errorf("Want no key saved, got '%+v'", (*keys)[1:])
+				}
+				return
+			}
+			var keyData ApiKeyData
+			json.NewDecoder(responseRecorder.Body).Decode(&keyData)
+			keyUuid, secret, ok := parseApiKey(keyData.Key)
+			if !ok || keyUuid != "new-key" {
+				t.Fatalf("Want the new key in the response, got '%s'", keyData.Key)
+			}
+			saved := (*keys)[len(*keys)-1]
+			if saved.SecretHash != hashApiKeySecret(secret) || strings.Contains(saved.SecretHash, secret) || saved.UserUuid != emailUserUuid {
+				t.Errorf("Want only the secret's hash saved for the user, got '%+v'", saved)
+			}
+		})
+	}
+}
+
+func TestDeleteApiKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyUuid string
+		want    int
+	}{
+		{"Revokes the key", testApiKeyUuid, http.StatusOK},
+		{"A key that doesn't exist", "unknown", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			apiKeyTest(t, []string{ScopePositionWrite}, 0)
+			var deleted *storage.ApiKey
+			executeDeleteApiKey = func(k *storage.ApiKey) error {
+				deleted = k
+				return nil
+			}
+			request := httptest.NewRequest("DELETE", "/users/apikeys/"+test.keyUuid, nil)
+			request = webhelper.WithParams(request, webhelper.Params{"uuid": test.keyUuid})
+			responseRecorder := httptest.NewRecorder()
+
+			DeleteApiKey(responseRecorder, request)
+			if responseRecorder.Code != test.want {
+				t.Fatalf("Want status '%d', got '%d'", test.want, responseRecorder.Code)
+			}
+			if (deleted != nil) != (test.want == http.StatusOK) {
+				t.Errorf("Want deleted '%v', got '%+v'", test.want == http.StatusOK, deleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package userLogin
+
+import (
+	"encoding/json"
+	"errors"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"net/http"
+	"time"
+)
+
+type Device struct {
+	storage.Device
+}
+
+type DeviceData struct {
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	Type     string `json:"type,omitempty"`
+	LastSeen int64  `json:"lastSeen,omitempty"`
+	Current  bool   `json:"current,omitempty"` // the device making the request
+}
+
+type UpdateDeviceData struct {
+	Name string `json:"name"`
+}
+
+// LastSeen is only written back to storage this often, rather than on every request
+const lastSeenInterval = time.Minute
+
+var errDeviceRevoked = errors.New("Device has been signed out")
+
+var executeAddDevice = func(m *User, d *Device) (*uint64, error) {
+	return storage.UserAddDevice(&m.User, &d.Device)
+}
+
+var releaseDeviceLocksVar = releaseDeviceLocks
+
+func NewDeviceData(d *storage.Device) *DeviceData {
+	return &DeviceData{
+		Id:       d.Uuid,
+		Name:     d.Name,
+		Type:     d.Type,
+		LastSeen: d.LastSeen,
+	}
+}
+
+func findUserDevice(emailAddress string, deviceUuid string) (*Device, error) {
+	var device Device
+	devices, err := device.Find(storage.DeviceFilter{Uuid: deviceUuid, OwnerEmail: emailAddress})
+	if err != nil {
+		return nil, err
+	}
+	if len(devices) != 1 {
+		return nil, storage.ErrDeviceNotFound
+	}
+	device.Device = *devices[0]
+	return &device, nil
+}
+
+// registerDevice records a sign in against the device the client says it is,
+// or registers a new device when it is unknown (or was revoked)
+func registerDevice(emailAddress string, creds *Credentials, authType string, s *session) (*Device, error) {
+	now := time.Now().Unix()
+	if creds.DeviceId != "" {
+		device, err := findUserDevice(emailAddress, creds.DeviceId)
+		if err != nil && err != storage.ErrDeviceNotFound {
+			return nil, err
+		}
+		if err == nil {
+			if creds.DeviceName != "" {
+				device.Name = creds.DeviceName
+			}
+			if authType != "" {
+				device.Type = authType
+			}
+			device.LastSeen = now
+			s.apply(&device.Device)
+			return device, device.Update()
+		}
+	}
+
+	var owner User
+	owner.EmailAddress = emailAddress
+	if err := owner.Select(); err != nil {
+		return nil, err
+	}
+	device := &Device{}
+	device.Name = creds.DeviceName
+	if device.Name == "" {
+		device.Name = authType
+	}
+	if device.Name == "" {
+		device.Name = "Unknown device"
+	}
+	device.Type = authType
+	device.LastSeen = now
+	s.apply(&device.Device)
+	_, err := executeAddDevice(&owner, device)
+	if err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// checkDevice confirms the token was the last one issued to a device that has
+// not been revoked or signed out
+func checkDevice(claims *Claims) error {
+	if claims.Device == "" {
+		return errDeviceRevoked
+	}
+	device, err := findUserDevice(claims.Username, claims.Device)
+	if err != nil {
+		return err
+	}
+	if device.LastTokenId == "" || device.LastTokenId != claims.Id {
+		return errDeviceRevoked
+	}
+	now := time.Now()
+	if now.Sub(time.Unix(device.LastSeen, 0)) >= lastSeenInterval {
+		device.LastSeen = now.Unix()
+		device.UpdateDetails()
+	}
+	return nil
+}
+
+// releaseDeviceLocks frees any playlist the device still has locked, so
+// another device can take over straight away. Shared and household playlists
+// are locked by devices that don't own them, so they are found by the lock.
+func releaseDeviceLocks(deviceUuid string) error {
+	var playlist storage.Playlist
+	playlists, err := playlist.Find(storage.PlaylistFilter{LockDeviceUuid: deviceUuid})
+	if err != nil {
+		return err
+	}
+	for _, p := range playlists {
+		p.LockDeviceUuid = ""
+		p.ClientLockExpires = 0
+		if err := p.UpdateLock(deviceUuid); err != nil && err != storage.ErrLockConflict {
+			return err
+		}
+	}
+	return nil
+}
+
+func ListDevices(w http.ResponseWriter, r *http.Request) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+
+	var device Device
+	devices, err := device.Find(storage.DeviceFilter{OwnerEmail: claims.Username})
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	deviceList := []*DeviceData{}
+	for _, d := range devices {
+		deviceData := NewDeviceData(d)
+		deviceData.Current = d.Uuid == claims.Device
+		deviceList = append(deviceList, deviceData)
+	}
+	json.NewEncoder(w).Encode(deviceList)
+	return
+}
+
+func getDeviceByUuid(uuid string, claims *Claims) (*Device, error, *int) {
+	if err := webhelper.CheckUuid(uuid); err != nil {
+		return nil, err, &[]int{http.StatusBadRequest}[0]
+	}
+	device, err := findUserDevice(claims.Username, uuid)
+	if err == storage.ErrDeviceNotFound {
+		return nil, err, &[]int{http.StatusNotFound}[0]
+	}
+	if err != nil {
+		return nil, err, &[]int{http.StatusInternalServerError}[0]
+	}
+	return device, nil, &[]int{http.StatusOK}[0]
+}
+
+func UpdateDevice(w http.ResponseWriter, r *http.Request) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+
+	device, err, httpStatus := getDeviceByUuid(webhelper.Param(r, "uuid"), claims)
+	if webhelper.ReturnError(w, r, err, httpStatus) {
+		return
+	}
+
+	var deviceData UpdateDeviceData
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&deviceData)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	if deviceData.Name == "" {
+		webhelper.ReturnError(w, r, errors.New("Missing Device Name"), &[]int{http.StatusBadRequest}[0])
+		return
+	}
+
+	device.Name = deviceData.Name
+	err = device.UpdateDetails()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	returnDevice := NewDeviceData(&device.Device)
+	returnDevice.Current = device.Uuid == claims.Device
+	json.NewEncoder(w).Encode(returnDevice)
+	return
+}
+
+// DeleteDevice revokes the device, any token issued to it stops working and
+// its playlist locks are released
+func DeleteDevice(w http.ResponseWriter, r *http.Request) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+
+	device, err, httpStatus := getDeviceByUuid(webhelper.Param(r, "uuid"), claims)
+	if webhelper.ReturnError(w, r, err, httpStatus) {
+		return
+	}
+
+	err = device.Delete()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	err = releaseDeviceLocksVar(device.Uuid)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+
+	var responseDetails webhelper.Response
+	responseDetails.Message = "Device Successfully Revoked"
+	json.NewEncoder(w).Encode(responseDetails)
+	return
+}
@@ -0,0 +1,292 @@
+package userLogin
+
+import (
+	"encoding/json"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var executeFindDevices func(filter storage.DeviceFilter) ([]*storage.Device, error)
+var executeUpdateDevice func(d *Device) error
+var executeUpdateDeviceDetails func(d *Device) error
+var executeRotateDeviceRefreshToken func(d *Device, expectedHash string) error
+var executeDeleteDevice func(d *Device) error
+
+func (d *Device) Find(filter storage.DeviceFilter) ([]*storage.Device, error) {
+	return executeFindDevices(filter)
+}
+
+func (d *Device) Update() error {
+	return executeUpdateDevice(d)
+}
+
+func (d *Device) UpdateDetails() error {
+	return executeUpdateDeviceDetails(d)
+}
+
+func (d *Device) RotateRefreshToken(expectedHash string) error {
+	return executeRotateDeviceRefreshToken(d, expectedHash)
+}
+
+func (d *Device) Delete() error {
+	return executeDeleteDevice(d)
+}
+
+const testDeviceUuid = "0b5c6d2e-54c2-4bd8-9d5c-0f4a4f1c3a11"
+
+func findTestDevice(filter storage.DeviceFilter) ([]*storage.Device, error) {
+	if filter.Uuid != "" && filter.Uuid != testDeviceUuid {
+		return nil, nil
+	}
+	return []*storage.Device{{
+		Id:          1,
+		Uuid:        testDeviceUuid,
+		Name:        "Kitchen Speaker",
+		Type:        "speaker",
+		LastSeen:    time.Now().Unix(),
+		LastTokenId: "token-1",
+	}}, nil
+}
+
+func deviceClaims(r *http.Request) (*Claims, int) {
+	claims := &Claims{
+		Username:       "test@test.com",
+		Device:         testDeviceUuid,
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix(), Id: "token-1"},
+	}
+	return claims, http.StatusOK
+}
+
+func TestRegisterDevice(t *testing.T) {
+	t.Run("Known device is reused", func(t *testing.T) {
+		executeFindDevices = findTestDevice
+		var updated *Device
+		executeUpdateDevice = func(d *Device) error {
+			updated = d
+			return nil
+		}
+		executeAddDevice = func(m *User, d *Device) (*uint64, error) {
+			t.Errorf("Expected the existing device to be used")
+			return nil, nil
+		}
+
+		creds := &Credentials{DeviceId: testDeviceUuid}
+		device, err := registerDevice("test@test.com", creds, "", &session{tokenId: "token-2", refreshSecret: "secret-2"})
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if updated == nil || device.Uuid != testDeviceUuid || device.LastTokenId != "token-2" || device.RefreshTokenHash != hashRefreshSecret("secret-2") {
+			t.Errorf("Want device updated with the new token, got '%+v'", device)
+		}
+	})
+	t.Run("Unknown device is registered", func(t *testing.T) {
+		executeFindDevices = findTestDevice
+		executeSelectUser = func(m *User) error {
+			m.Id = 1
+			return nil
+		}
+		var added *Device
+		executeAddDevice = func(m *User, d *Device) (*uint64, error) {
+			d.Id = 2
+			d.Uuid = "5e0c1f5a-4d33-4c43-a3f7-2f9e2b0c1d22"
+			added = d
+			return &d.Id, nil
+		}
+
+		creds := &Credentials{DeviceId: "9d2f8a44-8d3b-4f59-9f0e-0a8b4d3c2e33"}
+		device, err := registerDevice("test@test.com", creds, "phone", &session{tokenId: "token-3"})
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if added == nil || device.Name != "phone" || device.Type != "phone" || device.LastTokenId != "token-3" {
+			t.Errorf("Want a new device named after its type, got '%+v'", device)
+		}
+	})
+}
+
+func TestCheckDevice(t *testing.T) {
+	executeFindDevices = findTestDevice
+	executeUpdateDevice = func(d *Device) error {
+		return nil
+	}
+
+	t.Run("Token without a device", func(t *testing.T) {
+		if checkDevice(&Claims{Username: "test@test.com"}) == nil {
+			t.Errorf("Expected token without a device to be rejected")
+		}
+	})
+	t.Run("Revoked device", func(t *testing.T) {
+		claims := &Claims{Username: "test@test.com", Device: "9d2f8a44-8d3b-4f59-9f0e-0a8b4d3c2e33"}
+		if checkDevice(claims) != storage.ErrDeviceNotFound {
+			t.Errorf("Expected token for a revoked device to be rejected")
+		}
+	})
+	t.Run("Token replaced by a newer sign in", func(t *testing.T) {
+		claims := &Claims{Username: "test@test.com", Device: testDeviceUuid}
+		claims.Id = "token-0"
+		if checkDevice(claims) == nil {
+			t.Errorf("Expected replaced token to be rejected")
+		}
+	})
+	t.Run("Signed out device", func(t *testing.T) {
+		executeFindDevices = func(filter storage.DeviceFilter) ([]*storage.Device, error) {
+			return []*storage.Device{{Id: 1, Uuid: testDeviceUuid}}, nil
+		}
+		defer func() { executeFindDevices = findTestDevice }()
+		claims := &Claims{Username: "test@test.com", Device: testDeviceUuid}
+		if checkDevice(claims) == nil {
+			t.Errorf("Expected token for a signed out device to be rejected")
+		}
+	})
+	t.Run("Current token", func(t *testing.T) {
+		claims, _ := deviceClaims(nil)
+		if err := checkDevice(claims); err != nil {
+			t.Errorf("Want no error, got '%s'", err.Error())
+		}
+	})
+	t.Run("Last seen is stored without the token", func(t *testing.T) {
+		executeFindDevices = func(filter storage.DeviceFilter) ([]*storage.Device, error) {
+			devices, _ := findTestDevice(filter)
+			devices[0].LastSeen = time.Now().Add(-time.Hour).Unix()
+			return devices, nil
+		}
+		defer func() { executeFindDevices = findTestDevice }()
+		executeUpdateDevice = func(d *Device) error {
+			t.Error("Last seen should only store the device's details")
+			return nil
+		}
+		var updated *Device
+		executeUpdateDeviceDetails = func(d *Device) error {
+			updated = d
+			return nil
+		}
+		claims, _ := deviceClaims(nil)
+		if err := checkDevice(claims); err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		if updated == nil || time.Now().Unix()-updated.LastSeen > 1 {
+			t.Errorf("Want last seen stored as now, got '%+v'", updated)
+		}
+	})
+}
+
+func TestListDevices(t *testing.T) {
+	t.Run("List devices with no token", func(t *testing.T) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
+			return nil, http.StatusUnauthorized
+		}
+		request := httptest.NewRequest("GET", "/devices", nil)
+		responseRecorder := httptest.NewRecorder()
+
+		ListDevices(responseRecorder, request)
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
+		}
+	})
+	t.Run("Current device is flagged", func(t *testing.T) {
+		requestClaimsVar = deviceClaims
+		executeFindDevices = func(filter storage.DeviceFilter) ([]*storage.Device, error) {
+			if filter.OwnerEmail != "test@test.com" {
+				t.Errorf("Want devices for '%s', got '%s'", "test@test.com", filter.OwnerEmail)
+			}
+			devices, _ := findTestDevice(filter)
+			return append(devices, &storage.Device{Id: 2, Uuid: "5e0c1f5a-4d33-4c43-a3f7-2f9e2b0c1d22", Name: "Phone"}), nil
+		}
+		request := httptest.NewRequest("GET", "/devices", nil)
+		responseRecorder := httptest.NewRecorder()
+
+		ListDevices(responseRecorder, request)
+		var devices []DeviceData
+		json.NewDecoder(responseRecorder.Body).Decode(&devices)
+		if len(devices) != 2 || !devices[0].Current || devices[1].Current {
+			t.Errorf("Want 2 devices with the first flagged current, got '%+v'", devices)
+		}
+	})
+}
+
+func TestUpdateDevice(t *testing.T) {
+	requestClaimsVar = deviceClaims
+	executeFindDevices = findTestDevice
+
+	t.Run("Unknown device", func(t *testing.T) {
+		var data = `{"name":"Lounge"}`
+		request := httptest.NewRequest("PATCH", "/devices/9d2f8a44-8d3b-4f59-9f0e-0a8b4d3c2e33", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": "9d2f8a44-8d3b-4f59-9f0e-0a8b4d3c2e33"})
+		responseRecorder := httptest.NewRecorder()
+
+		UpdateDevice(responseRecorder, request)
+		if responseRecorder.Code != http.StatusNotFound {
+			t.Errorf("Want status '%d', got '%d'", http.StatusNotFound, responseRecorder.Code)
+		}
+	})
+	t.Run("Blank name", func(t *testing.T) {
+		var data = `{"name":""}`
+		request := httptest.NewRequest("PATCH", "/devices/"+testDeviceUuid, strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": testDeviceUuid})
+		responseRecorder := httptest.NewRecorder()
+
+		UpdateDevice(responseRecorder, request)
+		if responseRecorder.Code != http.StatusBadRequest {
+			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
+		}
+	})
+	t.Run("Device is renamed", func(t *testing.T) {
+		var updated *Device
+		executeUpdateDevice = func(d *Device) error {
+			t.Error("Renaming should only store the device's details")
+			return nil
+		}
+		executeUpdateDeviceDetails = func(d *Device) error {
+			updated = d
+			return nil
+		}
+		var data = `{"name":"Lounge"}`
+		request := httptest.NewRequest("PATCH", "/devices/"+testDeviceUuid, strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": testDeviceUuid})
+		responseRecorder := httptest.NewRecorder()
+
+		UpdateDevice(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Errorf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if updated == nil || updated.Name != "Lounge" {
+			t.Errorf("Want device renamed to '%s', got '%+v'", "Lounge", updated)
+		}
+	})
+}
+
+func TestDeleteDevice(t *testing.T) {
+	requestClaimsVar = deviceClaims
+	executeFindDevices = findTestDevice
+
+	t.Run("Device is revoked and its locks released", func(t *testing.T) {
+		var deleted *Device
+		executeDeleteDevice = func(d *Device) error {
+			deleted = d
+			return nil
+		}
+		var released string
+		releaseDeviceLocksVar = func(deviceUuid string) error {
+			released = deviceUuid
+			return nil
+		}
+		request := httptest.NewRequest("DELETE", "/devices/"+testDeviceUuid, nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": testDeviceUuid})
+		responseRecorder := httptest.NewRecorder()
+
+		DeleteDevice(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Errorf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if deleted == nil || deleted.Uuid != testDeviceUuid || released != testDeviceUuid {
+			t.Errorf("Want device '%s' deleted and its locks released", testDeviceUuid)
+		}
+	})
+}
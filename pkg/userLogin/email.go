@@ -0,0 +1,309 @@
+package userLogin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"mimpidev/sinkrontrack-server/internal/mailer"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const defaultPasswordResetTTL = 60 * 60
+const defaultVerifyEmailTTL = 24 * 60 * 60
+
+// The purpose of an emailed token is its audience, access tokens don't have
+// one so neither kind of token can be used in place of the other
+const passwordResetPurpose = "password-reset"
+const verifyEmailPurpose = "verify-email"
+
+var errEmailTokenInvalid = webhelper.NewError(webhelper.CodeEmailTokenInvalid, "Invalid or expired token")
+var errEmailNotVerified = webhelper.NewError(webhelper.CodeEmailNotVerified, "Email address has not been verified")
+
+var sendMailVar = mailer.Send
+
+type PasswordResetRequestData struct {
+	EmailAddress string `json:"emailAddress"`
+}
+
+type PasswordResetData struct {
+	Token           string `json:"token"`
+	Password        string `json:"password"`
+	PasswordConfirm string `json:"confirmPassword"`
+}
+
+type VerifyEmailData struct {
+	Token string `json:"token"`
+}
+
+// EmailClaims are the claims of a password reset or email verification token.
+// The fingerprint is of what the token changes, so a reset token stops working
+// once the password has been changed and a verification token once the email
+// address has. Both stop working when the account is disabled.
+type EmailClaims struct {
+	Fingerprint string `json:"fpr"`
+	jwt.StandardClaims
+}
+
+// passwordResetTTL can be set with PASSWORD_RESET_TTL
+func passwordResetTTL() time.Duration {
+	return ttlFromEnv("PASSWORD_RESET_TTL", defaultPasswordResetTTL)
+}
+
+// verifyEmailTTL can be set with VERIFY_EMAIL_TTL
+func verifyEmailTTL() time.Duration {
+	return ttlFromEnv("VERIFY_EMAIL_TTL", defaultVerifyEmailTTL)
+}
+
+// requireVerifiedEmail is set with REQUIRE_EMAIL_VERIFICATION, users can't
+// sign in until they have verified their email address
+func requireVerifiedEmail() bool {
+	return os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true"
+}
+
+func emailFingerprint(user *User, purpose string) string {
+	state := user.EmailAddress
+	if purpose == passwordResetPurpose {
+		state = user.Password
+	}
+	sum := sha256.Sum256([]byte(purpose + "\x00" + strconv.FormatBool(user.Enabled) + "\x00" + state))
+	return hex.EncodeToString(sum[:16])
+}
+
+// newEmailToken signs a token for the user to prove they got the email
+func newEmailToken(user *User, purpose string, ttl time.Duration) (string, error) {
+	keys, err := currentKeys()
+	if err != nil {
+		return "", err
+	}
+	return keys.sign(&EmailClaims{
+		Fingerprint: emailFingerprint(user, purpose),
+		StandardClaims: jwt.StandardClaims{
+			Audience:  purpose,
+			Subject:   user.Uuid,
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: time.Now().Add(ttl).Unix(),
+		},
+	})
+}
+
+// checkEmailToken returns the user the token was emailed to, as long as the
+// token is for the purpose and still matches the user
+func checkEmailToken(tokenString string, purpose string) (*User, error) {
+	keys, err := currentKeys()
+	if err != nil {
+		return nil, err
+	}
+	claims := &EmailClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, keys.keyFunc)
+	if err != nil || !token.Valid || claims.Audience != purpose || claims.Subject == "" {
+		return nil, errEmailTokenInvalid
+	}
+	var user User
+	user.Uuid = claims.Subject
+	if err := user.Select(); err != nil {
+		return nil, errEmailTokenInvalid
+	}
+	if claims.Fingerprint != emailFingerprint(&user, purpose) {
+		return nil, errEmailTokenInvalid
+	}
+	return &user, nil
+}
+
+// emailLink is the page of the client app at APP_URL the token is handed to,
+// or blank when there is no app to link to
+func emailLink(page string, token string) string {
+	appUrl := strings.TrimSuffix(os.Getenv("APP_URL"), "/")
+	if appUrl == "" {
+		return ""
+	}
+	return appUrl + "/" + page + "?token=" + url.QueryEscape(token)
+}
+
+func sendEmailToken(user *User, purpose string) error {
+	var subject, page, action string
+	ttl := verifyEmailTTL()
+	switch purpose {
+	case passwordResetPurpose:
+		subject, page, action = "Reset your password", "reset-password", "reset your password"
+		ttl = passwordResetTTL()
+	default:
+		subject, page, action = "Verify your email address", "verify-email", "verify your email address"
+	}
+	token, err := newEmailToken(user, purpose, ttl)
+	if err != nil {
+		return err
+	}
+
+	var body strings.Builder
+	body.WriteString("Hello " + user.FirstName + ",\n\n")
+	if link := emailLink(page, token); link != "" {
+		body.WriteString("Follow this link to " + action + ":\n\n" + link + "\n\n")
+	} else {
+		body.WriteString("Use this code to " + action + ":\n\n" + token + "\n\n")
+	}
+	body.WriteString("It expires in " + ttl.String() + ". If you didn't ask for this you can ignore this email.\n")
+	return sendMailVar(&mailer.Message{
+		To:      user.EmailAddress,
+		Subject: subject,
+		Body:    body.String(),
+		Secrets: []string{token, url.QueryEscape(token)},
+	})
+}
+
+// sendVerificationEmail is sent when an account is created or its email
+// address changes, failing to send it doesn't stop either
+func sendVerificationEmail(user *User) {
+	if err := sendEmailToken(user, verifyEmailPurpose); err != nil {
+		log.Printf("sending verification email to %s: %v", user.EmailAddress, err)
+	}
+}
+
+// RequestPasswordReset emails a password reset token. The response is the same
+// whether or not the account exists, so it can't be used to find accounts.
+func RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var data PasswordResetRequestData
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	if data.EmailAddress == "" {
+		webhelper.ReturnError(w, r, errors.New("Missing Email Address"), &[]int{http.StatusBadRequest}[0])
+		return
+	}
+	// Counted with the sign ins, so reset emails can't be sent over and over
+	if wait, err := loginLimits.allow(clientIp(r), data.EmailAddress, time.Now()); err == errTooManySignins {
+		tooManyRequests(w, r, wait, err)
+		return
+	}
+
+	var user User
+	user.EmailAddress = data.EmailAddress
+	if err := user.Select(); err == nil && user.Enabled {
+		if err := sendEmailToken(&user, passwordResetPurpose); err != nil {
+			log.Printf("sending password reset email to %s: %v", user.EmailAddress, err)
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	var responseDetails webhelper.Response
+	responseDetails.Message = "If the account exists a password reset email has been sent"
+	json.NewEncoder(w).Encode(responseDetails)
+}
+
+// ResetPassword sets a new password with the token from the reset email. Every
+// device is signed out, and the account unlocked. Disabled accounts can't be
+// reset.
+func ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var data PasswordResetData
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	httpCode, err := checkPassword(data.Password, data.PasswordConfirm, true)
+	if webhelper.ReturnError(w, r, err, httpCode) {
+		return
+	}
+	user, err := checkEmailToken(data.Token, passwordResetPurpose)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	if !user.Enabled {
+		webhelper.ReturnError(w, r, errAccountDisabled, &[]int{http.StatusForbidden}[0])
+		return
+	}
+
+	passwordHash, err := bcryptGenerateFromPassword([]byte(data.Password), 14)
+	if err != nil {
+		webhelper.ReturnError(w, r, errors.New("Failed to Encrypt Password"), &[]int{http.StatusBadRequest}[0])
+		return
+	}
+	user.Password = string(passwordHash)
+	// Getting the email proves the address is theirs
+	user.EmailVerified = true
+	err = user.Update()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	err = signOutEverywhere(user.EmailAddress)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	loginLimits.succeeded(user.EmailAddress)
+
+	var responseDetails webhelper.Response
+	responseDetails.Message = "Password Reset"
+	json.NewEncoder(w).Encode(responseDetails)
+}
+
+// SendVerifyEmail emails the signed in user a new verification token
+func SendVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+
+	var user User
+	user.EmailAddress = claims.Username
+	err := user.Select()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusNotFound}[0]) {
+		return
+	}
+	var responseDetails webhelper.Response
+	if user.EmailVerified {
+		responseDetails.Message = "Email Address Already Verified"
+		json.NewEncoder(w).Encode(responseDetails)
+		return
+	}
+	err = sendEmailToken(&user, verifyEmailPurpose)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	responseDetails.Message = "Verification Email Sent"
+	json.NewEncoder(w).Encode(responseDetails)
+}
+
+// VerifyEmail marks the email address verified with the token from the
+// verification email
+func VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var data VerifyEmailData
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	user, err := checkEmailToken(data.Token, verifyEmailPurpose)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	if !user.EmailVerified {
+		user.EmailVerified = true
+		err = user.Update()
+		if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+			return
+		}
+	}
+
+	var responseDetails webhelper.Response
+	responseDetails.Message = "Email Address Verified"
+	json.NewEncoder(w).Encode(responseDetails)
+}
+
+// signInVerified is the error for a user who can't sign in until they have
+// verified their email address, or nil
+func signInVerified(emailVerified bool) error {
+	if requireVerifiedEmail() && !emailVerified {
+		return errEmailNotVerified
+	}
+	return nil
+}
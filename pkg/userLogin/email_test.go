@@ -0,0 +1,244 @@
+package userLogin
+
+import (
+	"mimpidev/sinkrontrack-server/internal/mailer"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const emailUserUuid = "3f9e1b2c-4d5a-4e6f-8a7b-9c0d1e2f3a4b"
+
+// emailTest stubs out test@test.com and the mailer, returning the mail sent and
+// the user as it was last saved
+func emailTest(t *testing.T, verified bool) (*[]*mailer.Message, **User) {
+	testSigningKey()
+	stored := User{}
+	stored.Id = 2
+	stored.Uuid = emailUserUuid
+	stored.EmailAddress = "test@test.com"
+	stored.Password = "old-hash"
+	stored.Enabled = true
+	stored.EmailVerified = verified
+	saved := stubUser(t, stored)
+	executeFindApiKeys = func(filter storage.ApiKeyFilter) ([]*storage.ApiKey, error) {
+		return nil, nil
+	}
+	sent := []*mailer.Message{}
+	sendMailVar = func(m *mailer.Message) error {
+		sent = append(sent, m)
+		return nil
+	}
+	bcryptGenerateFromPassword = func(password []byte, cost int) ([]byte, error) {
+		return bcrypt.GenerateFromPassword(password, bcrypt.MinCost)
+	}
+	jwtParseWithClaims = jwt.ParseWithClaims
+	isTokenRevokedVar = func(tokenId string) (bool, error) {
+		return false, nil
+	}
+	return &sent, saved
+}
+
+// mailedToken is the code in the email, which is sent without APP_URL set
+func mailedToken(t *testing.T, sent []*mailer.Message) string {
+	if len(sent) != 1 {
+		t.Fatalf("Want 1 email, got %d", len(sent))
+	}
+	parts := strings.Split(sent[0].Body, "\n\n")
+	if len(parts) < 3 {
+		t.Fatalf("Want a code in the email, got '%s'", sent[0].Body)
+	}
+	if len(sent[0].Secrets) == 0 || sent[0].Secrets[0] != parts[2] {
+		t.Errorf("Want the code kept out of the log, got secrets '%v'", sent[0].Secrets)
+	}
+	return parts[2]
+}
+
+func TestRequestPasswordReset(t *testing.T) {
+	t.Run("The reset email is sent to the account", func(t *testing.T) {
+		sent, _ := emailTest(t, true)
+		request := httptest.NewRequest("POST", "/users/passwordReset", strings.NewReader(`{"emailAddress":"test@test.com"}`))
+		responseRecorder := httptest.NewRecorder()
+
+		RequestPasswordReset(responseRecorder, request)
+		if responseRecorder.Code != http.StatusAccepted {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusAccepted, responseRecorder.Code)
+		}
+		if _, err := checkEmailToken(mailedToken(t, *sent), passwordResetPurpose); err != nil {
+			t.Errorf("Want a password reset token, got '%v'", err)
+		}
+	})
+	t.Run("An unknown account gets the same response without an email", func(t *testing.T) {
+		sent, _ := emailTest(t, true)
+		request := httptest.NewRequest("POST", "/users/passwordReset", strings.NewReader(`{"emailAddress":"nobody@test.com"}`))
+		responseRecorder := httptest.NewRecorder()
+
+		RequestPasswordReset(responseRecorder, request)
+		if responseRecorder.Code != http.StatusAccepted {
+			t.Errorf("Want status '%d', got '%d'", http.StatusAccepted, responseRecorder.Code)
+		}
+		if len(*sent) != 0 {
+			t.Errorf("Want no email, got %d", len(*sent))
+		}
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	resetRequest := func(token string, password string, confirm string) *http.Request {
+		return httptest.NewRequest("POST", "/users/passwordReset/confirm",
+			strings.NewReader(`{"token":"`+token+`","password":"`+password+`","confirmPassword":"`+confirm+`"}`))
+	}
+	resetToken := func(t *testing.T, purpose string) string {
+		var user User
+		user.Uuid = emailUserUuid
+		executeSelectUser(&user)
+		token, err := newEmailToken(&user, purpose, passwordResetTTL())
+		if err != nil {
+			t.Fatalf("Want no error, got '%s'", err.Error())
+		}
+		return token
+	}
+
+	t.Run("The password is changed and every device signed out", func(t *testing.T) {
+		_, saved := emailTest(t, false)
+		executeFindDevices = findTestDevice
+		var signedOut *Device
+		executeUpdateDevice = func(d *Device) error {
+			signedOut = d
+			return nil
+		}
+		releaseDeviceLocksVar = func(deviceUuid string) error {
+			return nil
+		}
+		responseRecorder := httptest.NewRecorder()
+
+		ResetPassword(responseRecorder, resetRequest(resetToken(t, passwordResetPurpose), "new-password", "new-password"))
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		user := *saved
+		if user == nil || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("new-password")) != nil || !user.EmailVerified {
+			t.Errorf("Want the new password saved and the email verified, got '%+v'", user)
+		}
+		if signedOut == nil || signedOut.LastTokenId != "" {
+			t.Errorf("Want the device signed out, got '%+v'", signedOut)
+		}
+	})
+	t.Run("A reset token only works once", func(t *testing.T) {
+		emailTest(t, true)
+		executeFindDevices = func(filter storage.DeviceFilter) ([]*storage.Device, error) {
+			return nil, nil
+		}
+		token := resetToken(t, passwordResetPurpose)
+		ResetPassword(httptest.NewRecorder(), resetRequest(token, "new-password", "new-password"))
+		responseRecorder := httptest.NewRecorder()
+
+		ResetPassword(responseRecorder, resetRequest(token, "other-password", "other-password"))
+		if responseRecorder.Code != http.StatusBadRequest {
+			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
+		}
+	})
+	t.Run("Disabling the account stops its reset token working", func(t *testing.T) {
+		_, saved := emailTest(t, true)
+		token := resetToken(t, passwordResetPurpose)
+		selectUser := executeSelectUser
+		executeSelectUser = func(m *User) error {
+			err := selectUser(m)
+			m.Enabled = false
+			return err
+		}
+		responseRecorder := httptest.NewRecorder()
+
+		ResetPassword(responseRecorder, resetRequest(token, "new-password", "new-password"))
+		if responseRecorder.Code != http.StatusBadRequest || *saved != nil {
+			t.Errorf("Want status '%d' without saving, got '%d'", http.StatusBadRequest, responseRecorder.Code)
+		}
+		responseRecorder = httptest.NewRecorder()
+
+		// Even with a token that matches the disabled account
+		ResetPassword(responseRecorder, resetRequest(resetToken(t, passwordResetPurpose), "new-password", "new-password"))
+		if responseRecorder.Code != http.StatusForbidden || *saved != nil {
+			t.Errorf("Want status '%d' without saving, got '%d'", http.StatusForbidden, responseRecorder.Code)
+		}
+	})
+	t.Run("A verification token can't reset the password", func(t *testing.T) {
+		_, saved := emailTest(t, true)
+		responseRecorder := httptest.NewRecorder()
+
+		ResetPassword(responseRecorder, resetRequest(resetToken(t, verifyEmailPurpose), "new-password", "new-password"))
+		if responseRecorder.Code != http.StatusBadRequest || *saved != nil {
+			t.Errorf("Want status '%d' without saving, got '%d'", http.StatusBadRequest, responseRecorder.Code)
+		}
+	})
+	t.Run("Passwords do not match", func(t *testing.T) {
+		emailTest(t, true)
+		responseRecorder := httptest.NewRecorder()
+
+		ResetPassword(responseRecorder, resetRequest(resetToken(t, passwordResetPurpose), "new-password", "other-password"))
+		if responseRecorder.Code != http.StatusBadRequest {
+			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
+		}
+	})
+}
+
+func TestVerifyEmail(t *testing.T) {
+	verifyRequest := func(token string) *http.Request {
+		return httptest.NewRequest("POST", "/users/verifyEmail/confirm", strings.NewReader(`{"token":"`+token+`"}`))
+	}
+
+	t.Run("The emailed token verifies the address", func(t *testing.T) {
+		sent, saved := emailTest(t, false)
+		requestClaimsVar = deviceClaims
+		responseRecorder := httptest.NewRecorder()
+
+		SendVerifyEmail(responseRecorder, httptest.NewRequest("POST", "/users/verifyEmail", nil))
+		if responseRecorder.Code != http.StatusAccepted {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusAccepted, responseRecorder.Code)
+		}
+		responseRecorder = httptest.NewRecorder()
+
+		VerifyEmail(responseRecorder, verifyRequest(mailedToken(t, *sent)))
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if *saved == nil || !(*saved).EmailVerified {
+			t.Errorf("Want the email address verified, got '%+v'", *saved)
+		}
+	})
+	t.Run("The token stops working once the address changes", func(t *testing.T) {
+		sent, _ := emailTest(t, false)
+		requestClaimsVar = deviceClaims
+		SendVerifyEmail(httptest.NewRecorder(), httptest.NewRequest("POST", "/users/verifyEmail", nil))
+		token := mailedToken(t, *sent)
+		selectUser := executeSelectUser
+		executeSelectUser = func(m *User) error {
+			err := selectUser(m)
+			m.EmailAddress = "changed@test.com"
+			return err
+		}
+		responseRecorder := httptest.NewRecorder()
+
+		VerifyEmail(responseRecorder, verifyRequest(token))
+		if responseRecorder.Code != http.StatusBadRequest {
+			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
+		}
+	})
+	t.Run("An emailed token can't be used to sign in", func(t *testing.T) {
+		emailTest(t, false)
+		var user User
+		user.Uuid = emailUserUuid
+		executeSelectUser(&user)
+		token, _ := newEmailToken(&user, verifyEmailPurpose, verifyEmailTTL())
+		request := httptest.NewRequest("GET", "/users/2", nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+
+		if _, response := CheckToken(request); response != http.StatusUnauthorized {
+			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, response)
+		}
+	})
+}
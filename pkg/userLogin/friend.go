@@ -0,0 +1,340 @@
+package userLogin
+
+import (
+	"encoding/json"
+	"errors"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type FriendData struct {
+	Id           string `json:"id"` // the friend's user uuid
+	FirstName    string `json:"firstName"`
+	LastName     string `json:"lastName"`
+	EmailAddress string `json:"emailAddress"`
+	Status       string `json:"status"` // sent, received or accepted
+	UpdatedAt    int64  `json:"updatedAt,omitempty"`
+}
+
+type FriendRequestData struct {
+	EmailAddress string `json:"emailAddress"`
+}
+
+var errAlreadyFriends = errors.New("Already friends")
+var errRequestSent = errors.New("Friend request already sent")
+var errNoFriendRequest = errors.New("No friend request from that user")
+
+var executeAddFriend = func(m *User, f *storage.Friend) (*uint64, error) {
+	return storage.UserAddFriend(&m.User, f)
+}
+
+var executeUpdateFriend = func(f *storage.Friend) error {
+	return f.Update()
+}
+
+var executeDeleteFriend = func(f *storage.Friend) error {
+	return f.Delete()
+}
+
+var removeSharesVar = removeShares
+
+func newFriendData(friend *User, f *storage.Friend) *FriendData {
+	return &FriendData{
+		Id:           friend.Uuid,
+		FirstName:    friend.FirstName,
+		LastName:     friend.LastName,
+		EmailAddress: friend.EmailAddress,
+		Status:       f.Status,
+		UpdatedAt:    f.UpdatedAt,
+	}
+}
+
+// findFriend returns the user's entry for the other user, or nil
+func findFriend(m *User, friendUuid string) *storage.Friend {
+	for _, f := range m.Friends {
+		if f.FriendId == friendUuid {
+			return f
+		}
+	}
+	return nil
+}
+
+// removeShares deletes the playlists either user shared with the other, once
+// they stop being friends
+func removeShares(userUuid string, friendUuid string) error {
+	var search storage.PlaylistShare
+	for _, filter := range []storage.PlaylistShareFilter{
+		{OwnerUuid: userUuid, UserUuid: friendUuid},
+		{OwnerUuid: friendUuid, UserUuid: userUuid},
+	} {
+		shares, err := search.Find(filter)
+		if err != nil {
+			return err
+		}
+		for _, share := range shares {
+			if err := share.Delete(); err != nil && err != storage.ErrShareNotFound {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// acceptFriend marks both sides of the friendship as accepted
+func acceptFriend(mine *storage.Friend, theirs *storage.Friend) error {
+	now := time.Now().Unix()
+	for _, f := range []*storage.Friend{mine, theirs} {
+		f.Status = storage.FriendAccepted
+		f.UpdatedAt = now
+		if err := executeUpdateFriend(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteFriend removes both sides of the friendship, theirs may already be
+// gone
+func deleteFriend(mine *storage.Friend, theirs *storage.Friend) error {
+	if err := executeDeleteFriend(mine); err != nil {
+		return err
+	}
+	if theirs == nil {
+		return nil
+	}
+	if err := executeDeleteFriend(theirs); err != nil && err != storage.ErrFriendNotFound {
+		return err
+	}
+	return nil
+}
+
+// getFriendByUuid loads the signed in user and the friend, with each of their
+// entries for the other
+func getFriendByUuid(uuid string, claims *Claims) (*User, *User, *storage.Friend, *storage.Friend, error, *int) {
+	if err := webhelper.CheckUuid(uuid); err != nil {
+		return nil, nil, nil, nil, err, &[]int{http.StatusBadRequest}[0]
+	}
+
+	var user User
+	user.EmailAddress = claims.Username
+	if err := user.Select(); err != nil {
+		return nil, nil, nil, nil, err, &[]int{http.StatusUnauthorized}[0]
+	}
+	mine := findFriend(&user, uuid)
+	if mine == nil {
+		return nil, nil, nil, nil, storage.ErrFriendNotFound, &[]int{http.StatusNotFound}[0]
+	}
+
+	var friend User
+	friend.Uuid = uuid
+	err := friend.Select()
+	if err != nil && err != storage.ErrUserNotFound {
+		return nil, nil, nil, nil, err, &[]int{http.StatusInternalServerError}[0]
+	}
+	return &user, &friend, mine, findFriend(&friend, user.Uuid), nil, &[]int{http.StatusOK}[0]
+}
+
+// ListFriends returns the user's friends along with the friend requests they
+// have sent and received
+func ListFriends(w http.ResponseWriter, r *http.Request) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+
+	var user User
+	user.EmailAddress = claims.Username
+	err := user.Select()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusUnauthorized}[0]) {
+		return
+	}
+
+	friendList := []*FriendData{}
+	for _, f := range user.Friends {
+		var friend User
+		friend.Uuid = f.FriendId
+		if err := friend.Select(); err != nil {
+			// The other user has been deleted
+			continue
+		}
+		friendList = append(friendList, newFriendData(&friend, f))
+	}
+	json.NewEncoder(w).Encode(friendList)
+	return
+}
+
+// RequestFriend sends a friend request to the user with the email address.
+// When that user has already sent one to the caller it is accepted instead.
+func RequestFriend(w http.ResponseWriter, r *http.Request) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+
+	var requestData FriendRequestData
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&requestData)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	if requestData.EmailAddress == "" {
+		webhelper.ReturnError(w, r, errors.New("Missing Email Address"), &[]int{http.StatusBadRequest}[0])
+		return
+	}
+	if strings.EqualFold(requestData.EmailAddress, claims.Username) {
+		webhelper.ReturnError(w, r, errors.New("You can't send a friend request to yourself"), &[]int{http.StatusBadRequest}[0])
+		return
+	}
+
+	var user User
+	user.EmailAddress = claims.Username
+	err = user.Select()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusUnauthorized}[0]) {
+		return
+	}
+	var friend User
+	friend.EmailAddress = requestData.EmailAddress
+	err = friend.Select()
+	if err == storage.ErrUserNotFound {
+		webhelper.ReturnError(w, r, err, &[]int{http.StatusNotFound}[0])
+		return
+	}
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+
+	mine := findFriend(&user, friend.Uuid)
+	theirs := findFriend(&friend, user.Uuid)
+	if mine != nil && mine.Status == storage.FriendAccepted {
+		webhelper.ReturnError(w, r, errAlreadyFriends, &[]int{http.StatusConflict}[0])
+		return
+	}
+	if mine != nil && mine.Status == storage.FriendRequestSent {
+		webhelper.ReturnError(w, r, errRequestSent, &[]int{http.StatusConflict}[0])
+		return
+	}
+	if mine != nil && theirs != nil {
+		err = acceptFriend(mine, theirs)
+		if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+			return
+		}
+		json.NewEncoder(w).Encode(newFriendData(&friend, mine))
+		return
+	}
+
+	// Clear out either half of an earlier request before starting again
+	for _, f := range []*storage.Friend{mine, theirs} {
+		if f != nil {
+			if err := executeDeleteFriend(f); err != nil && err != storage.ErrFriendNotFound {
+				webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0])
+				return
+			}
+		}
+	}
+	now := time.Now().Unix()
+	mine = &storage.Friend{FriendId: friend.Uuid, Status: storage.FriendRequestSent, UpdatedAt: now}
+	_, err = executeAddFriend(&user, mine)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	theirs = &storage.Friend{FriendId: user.Uuid, Status: storage.FriendRequestReceived, UpdatedAt: now}
+	_, err = executeAddFriend(&friend, theirs)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newFriendData(&friend, mine))
+	return
+}
+
+// AcceptFriend accepts a friend request the user has received
+func AcceptFriend(w http.ResponseWriter, r *http.Request) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+
+	_, friend, mine, theirs, err, httpStatus := getFriendByUuid(webhelper.Param(r, "uuid"), claims)
+	if webhelper.ReturnError(w, r, err, httpStatus) {
+		return
+	}
+	if mine.Status == storage.FriendAccepted {
+		webhelper.ReturnError(w, r, errAlreadyFriends, &[]int{http.StatusConflict}[0])
+		return
+	}
+	if mine.Status != storage.FriendRequestReceived || theirs == nil {
+		webhelper.ReturnError(w, r, errNoFriendRequest, &[]int{http.StatusNotFound}[0])
+		return
+	}
+
+	err = acceptFriend(mine, theirs)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	json.NewEncoder(w).Encode(newFriendData(friend, mine))
+	return
+}
+
+// DeclineFriend turns down a friend request the user has received
+func DeclineFriend(w http.ResponseWriter, r *http.Request) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+
+	_, _, mine, theirs, err, httpStatus := getFriendByUuid(webhelper.Param(r, "uuid"), claims)
+	if webhelper.ReturnError(w, r, err, httpStatus) {
+		return
+	}
+	if mine.Status != storage.FriendRequestReceived {
+		webhelper.ReturnError(w, r, errNoFriendRequest, &[]int{http.StatusNotFound}[0])
+		return
+	}
+
+	err = deleteFriend(mine, theirs)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	var responseDetails webhelper.Response
+	responseDetails.Message = "Friend Request Declined"
+	json.NewEncoder(w).Encode(responseDetails)
+	return
+}
+
+// DeleteFriend removes a friend, or cancels a friend request the user sent.
+// Playlists shared between the two users stop being shared.
+func DeleteFriend(w http.ResponseWriter, r *http.Request) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+
+	user, friend, mine, theirs, err, httpStatus := getFriendByUuid(webhelper.Param(r, "uuid"), claims)
+	if webhelper.ReturnError(w, r, err, httpStatus) {
+		return
+	}
+
+	err = deleteFriend(mine, theirs)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	err = removeSharesVar(user.Uuid, friend.Uuid)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+
+	var responseDetails webhelper.Response
+	responseDetails.Message = "Friend Successfully Removed"
+	json.NewEncoder(w).Encode(responseDetails)
+	return
+}
@@ -0,0 +1,195 @@
+package userLogin
+
+import (
+	"encoding/json"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	friendUserUuid  = "2f1e4c8a-6b0d-4f3e-9a71-5c2d8e0b1a01"
+	friendOtherUuid = "2f1e4c8a-6b0d-4f3e-9a71-5c2d8e0b1a02"
+)
+
+// friendTest stubs out two users, test@test.com and other@test.com, starting
+// with the friend entries given for each
+func friendTest(mine *storage.Friend, theirs *storage.Friend) map[string]*User {
+	requestClaimsVar = deviceClaims
+	users := map[string]*User{}
+	for _, u := range []struct {
+		uuid   string
+		email  string
+		friend *storage.Friend
+	}{
+		{friendUserUuid, "test@test.com", mine},
+		{friendOtherUuid, "other@test.com", theirs},
+	} {
+		user := &User{}
+		user.Uuid = u.uuid
+		user.EmailAddress = u.email
+		if u.friend != nil {
+			user.Friends = []*storage.Friend{u.friend}
+		}
+		users[u.uuid] = user
+	}
+	executeSelectUser = func(m *User) error {
+		for _, user := range users {
+			if user.Uuid == m.Uuid || user.EmailAddress == m.EmailAddress {
+				*m = *user
+				return nil
+			}
+		}
+		return storage.ErrUserNotFound
+	}
+	executeAddFriend = func(m *User, f *storage.Friend) (*uint64, error) {
+		users[m.Uuid].Friends = append(users[m.Uuid].Friends, f)
+		return &f.Id, nil
+	}
+	executeUpdateFriend = func(f *storage.Friend) error {
+		return nil
+	}
+	executeDeleteFriend = func(f *storage.Friend) error {
+		for _, user := range users {
+			var kept []*storage.Friend
+			for _, friend := range user.Friends {
+				if friend != f {
+					kept = append(kept, friend)
+				}
+			}
+			user.Friends = kept
+		}
+		return nil
+	}
+	return users
+}
+
+func TestRequestFriend(t *testing.T) {
+	tests := []struct {
+		name   string
+		mine   *storage.Friend
+		theirs *storage.Friend
+		email  string
+		want   int
+		status string
+	}{
+		{"Send a friend request", nil, nil, "other@test.com", http.StatusCreated, storage.FriendRequestSent},
+		{"Accept their friend request instead", &storage.Friend{FriendId: friendOtherUuid, Status: storage.FriendRequestReceived}, &storage.Friend{FriendId: friendUserUuid, Status: storage.FriendRequestSent}, "other@test.com", http.StatusOK, storage.FriendAccepted},
+		{"Request already sent", &storage.Friend{FriendId: friendOtherUuid, Status: storage.FriendRequestSent}, &storage.Friend{FriendId: friendUserUuid, Status: storage.FriendRequestReceived}, "other@test.com", http.StatusConflict, ""},
+		{"Already friends", &storage.Friend{FriendId: friendOtherUuid, Status: storage.FriendAccepted}, &storage.Friend{FriendId: friendUserUuid, Status: storage.FriendAccepted}, "other@test.com", http.StatusConflict, ""},
+		{"Unknown user", nil, nil, "nobody@test.com", http.StatusNotFound, ""},
+		{"Not yourself", nil, nil, "TEST@test.com", http.StatusBadRequest, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			users := friendTest(test.mine, test.theirs)
+			request := httptest.NewRequest("POST", "/friends", strings.NewReader(`{"emailAddress":"`+test.email+`"}`))
+			responseRecorder := httptest.NewRecorder()
+
+			RequestFriend(responseRecorder, request)
+			if responseRecorder.Code != test.want {
+				t.Fatalf("Want status '%d', got '%d'", test.want, responseRecorder.Code)
+			}
+			if test.status == "" {
+				return
+			}
+			var friend FriendData
+			json.NewDecoder(responseRecorder.Body).Decode(&friend)
+			if friend.Id != friendOtherUuid || friend.Status != test.status {
+				t.Errorf("Want friend '%s' with status '%s', got '%+v'", friendOtherUuid, test.status, friend)
+			}
+			theirs := users[friendOtherUuid].Friends
+			if len(theirs) != 1 || theirs[0].FriendId != friendUserUuid {
+				t.Errorf("Want the other user to have a single entry for '%s', got '%+v'", friendUserUuid, theirs)
+			}
+		})
+	}
+}
+
+func TestAcceptFriend(t *testing.T) {
+	t.Run("Accept a friend request", func(t *testing.T) {
+		users := friendTest(&storage.Friend{FriendId: friendOtherUuid, Status: storage.FriendRequestReceived}, &storage.Friend{FriendId: friendUserUuid, Status: storage.FriendRequestSent})
+		request := httptest.NewRequest("POST", "/friends/"+friendOtherUuid+"/accept", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": friendOtherUuid})
+		responseRecorder := httptest.NewRecorder()
+
+		AcceptFriend(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		for _, user := range users {
+			if user.Friends[0].Status != storage.FriendAccepted {
+				t.Errorf("Want '%s' to be friends, got '%s'", user.EmailAddress, user.Friends[0].Status)
+			}
+		}
+	})
+	t.Run("Can't accept a request you sent", func(t *testing.T) {
+		friendTest(&storage.Friend{FriendId: friendOtherUuid, Status: storage.FriendRequestSent}, &storage.Friend{FriendId: friendUserUuid, Status: storage.FriendRequestReceived})
+		request := httptest.NewRequest("POST", "/friends/"+friendOtherUuid+"/accept", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": friendOtherUuid})
+		responseRecorder := httptest.NewRecorder()
+
+		AcceptFriend(responseRecorder, request)
+		if responseRecorder.Code != http.StatusNotFound {
+			t.Errorf("Want status '%d', got '%d'", http.StatusNotFound, responseRecorder.Code)
+		}
+	})
+}
+
+func TestDeclineFriend(t *testing.T) {
+	users := friendTest(&storage.Friend{FriendId: friendOtherUuid, Status: storage.FriendRequestReceived}, &storage.Friend{FriendId: friendUserUuid, Status: storage.FriendRequestSent})
+	request := httptest.NewRequest("POST", "/friends/"+friendOtherUuid+"/decline", nil)
+	request = webhelper.WithParams(request, webhelper.Params{"uuid": friendOtherUuid})
+	responseRecorder := httptest.NewRecorder()
+
+	DeclineFriend(responseRecorder, request)
+	if responseRecorder.Code != http.StatusOK {
+		t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+	}
+	if len(users[friendUserUuid].Friends) != 0 || len(users[friendOtherUuid].Friends) != 0 {
+		t.Errorf("Want the friend request removed from both users")
+	}
+}
+
+func TestDeleteFriend(t *testing.T) {
+	defer func() {
+		removeSharesVar = removeShares
+	}()
+
+	t.Run("Remove a friend and what they were sharing", func(t *testing.T) {
+		users := friendTest(&storage.Friend{FriendId: friendOtherUuid, Status: storage.FriendAccepted}, &storage.Friend{FriendId: friendUserUuid, Status: storage.FriendAccepted})
+		var removed []string
+		removeSharesVar = func(userUuid string, friendUuid string) error {
+			removed = []string{userUuid, friendUuid}
+			return nil
+		}
+		request := httptest.NewRequest("DELETE", "/friends/"+friendOtherUuid, nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": friendOtherUuid})
+		responseRecorder := httptest.NewRecorder()
+
+		DeleteFriend(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if len(users[friendUserUuid].Friends) != 0 || len(users[friendOtherUuid].Friends) != 0 {
+			t.Errorf("Want the friendship removed from both users")
+		}
+		if len(removed) != 2 || removed[0] != friendUserUuid || removed[1] != friendOtherUuid {
+			t.Errorf("Want the shares between them removed, got '%v'", removed)
+		}
+	})
+	t.Run("Not a friend", func(t *testing.T) {
+		friendTest(nil, nil)
+		request := httptest.NewRequest("DELETE", "/friends/"+friendOtherUuid, nil)
+		request = webhelper.WithParams(request, webhelper.Params{"uuid": friendOtherUuid})
+		responseRecorder := httptest.NewRecorder()
+
+		DeleteFriend(responseRecorder, request)
+		if responseRecorder.Code != http.StatusNotFound {
+			t.Errorf("Want status '%d', got '%d'", http.StatusNotFound, responseRecorder.Code)
+		}
+	})
+}
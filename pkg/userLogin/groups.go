@@ -0,0 +1,580 @@
+package userLogin
+
+import (
+	"encoding/json"
+	"errors"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// The role child accounts are created with, they can't make friends or
+// groups of their own
+const childRole = "family"
+
+// Children can't delete the group's playlists until a manager says so
+var childRestrictions = []string{storage.RestrictDeletePlaylists}
+
+var restrictions = []string{storage.RestrictEditPlaylists, storage.RestrictDeletePlaylists}
+
+type GroupData struct {
+	Uuid      string             `json:"uuid"`
+	Name      string             `json:"name"`
+	CreatedAt int64              `json:"createdAt,omitempty"`
+	Members   []*GroupMemberData `json:"members"`
+	Invites   []*GroupInviteData `json:"invites"`
+}
+
+type GroupMemberData struct {
+	Id           string   `json:"id"` // the member's user uuid
+	FirstName    string   `json:"firstName"`
+	LastName     string   `json:"lastName"`
+	EmailAddress string   `json:"emailAddress"`
+	Manager      bool     `json:"manager"`
+	Restrictions []string `json:"restrictions"`
+}
+
+type GroupInviteData struct {
+	Id           string `json:"id"` // the invited user's uuid
+	FirstName    string `json:"firstName"`
+	LastName     string `json:"lastName"`
+	EmailAddress string `json:"emailAddress"`
+	InvitedBy    string `json:"invitedBy"` // uuid of the manager who sent it
+	CreatedAt    int64  `json:"createdAt,omitempty"`
+}
+
+type GroupRequestData struct {
+	Name string `json:"name"`
+}
+
+type GroupMemberRequestData struct {
+	EmailAddress string `json:"emailAddress"` // an accepted friend of the manager
+}
+
+type UpdateGroupMemberData struct {
+	Manager      *bool     `json:"manager,omitempty"`
+	Restrictions *[]string `json:"restrictions,omitempty"`
+}
+
+var errMissingGroupName = errors.New("Missing Group Name")
+var errNotGroupManager = errors.New("Only a manager of the group can do that")
+var errUnknownRestriction = errors.New("Unknown Restriction")
+var errLastManager = errors.New("The group needs another manager first")
+var errNotFriendForGroup = webhelper.NewError(webhelper.CodeNotFriends, "Only friends can be invited to a group")
+var errAlreadyInvited = errors.New("Already invited to the group")
+var errNoGroupInvite = errors.New("No invite from that group")
+
+var executeSaveGroup = func(g *storage.Group) error {
+	return g.Save()
+}
+
+var executeDeleteGroup = func(g *storage.Group) error {
+	return g.Delete()
+}
+
+var executeFindGroups = func(filter storage.GroupFilter) ([]*storage.Group, error) {
+	var search storage.Group
+	return search.Find(filter)
+}
+
+// findUserGroup returns the group the user is in, or nil
+func findUserGroup(userUuid string) (*storage.Group, error) {
+	groups, err := executeFindGroups(storage.GroupFilter{MemberUuid: userUuid})
+	if err != nil || len(groups) == 0 {
+		return nil, err
+	}
+	return groups[0], nil
+}
+
+func newGroupData(group *storage.Group) *GroupData {
+	groupData := &GroupData{Uuid: group.Uuid, Name: group.Name, CreatedAt: group.CreatedAt,
+		Members: []*GroupMemberData{}, Invites: []*GroupInviteData{}}
+	for _, member := range group.Members {
+		memberData := &GroupMemberData{Id: member.UserUuid, Manager: member.Manager, Restrictions: member.Restrictions}
+		if memberData.Restrictions == nil {
+			memberData.Restrictions = []string{}
+		}
+		var user User
+		user.Uuid = member.UserUuid
+		if err := user.Select(); err == nil {
+			memberData.FirstName = user.FirstName
+			memberData.LastName = user.LastName
+			memberData.EmailAddress = user.EmailAddress
+		}
+		groupData.Members = append(groupData.Members, memberData)
+	}
+	for _, invite := range group.Invites {
+		inviteData := &GroupInviteData{Id: invite.UserUuid, InvitedBy: invite.InvitedBy, CreatedAt: invite.CreatedAt}
+		var user User
+		user.Uuid = invite.UserUuid
+		if err := user.Select(); err == nil {
+			inviteData.FirstName = user.FirstName
+			inviteData.LastName = user.LastName
+			inviteData.EmailAddress = user.EmailAddress
+		}
+		groupData.Invites = append(groupData.Invites, inviteData)
+	}
+	return groupData
+}
+
+// removeInvite takes the user's invite out of the group
+func removeInvite(group *storage.Group, userUuid string) {
+	var invites []*storage.GroupInvite
+	for _, invite := range group.Invites {
+		if invite.UserUuid != userUuid {
+			invites = append(invites, invite)
+		}
+	}
+	group.Invites = invites
+}
+
+// managers counts the members who can manage the group
+func managers(group *storage.Group) int {
+	count := 0
+	for _, member := range group.Members {
+		if member.Manager {
+			count++
+		}
+	}
+	return count
+}
+
+// getGroup loads the signed in user and the group in /groups/{uuid}, which
+// they have to be a member of
+func getGroup(w http.ResponseWriter, r *http.Request) (*User, *storage.Group, *storage.GroupMember, bool) {
+	user, ok := signedInUser(w, r)
+	if !ok {
+		return nil, nil, nil, false
+	}
+	uuid := webhelper.Param(r, "uuid")
+	if webhelper.ReturnError(w, r, webhelper.CheckUuid(uuid), &[]int{http.StatusBadRequest}[0]) {
+		return nil, nil, nil, false
+	}
+	group, err := findUserGroup(user.Uuid)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return nil, nil, nil, false
+	}
+	if group == nil || group.Uuid != uuid {
+		webhelper.ReturnError(w, r, storage.ErrGroupNotFound, &[]int{http.StatusNotFound}[0])
+		return nil, nil, nil, false
+	}
+	return user, group, group.Member(user.Uuid), true
+}
+
+// getManagedGroup is getGroup for what only a manager of the group can do
+func getManagedGroup(w http.ResponseWriter, r *http.Request) (*User, *storage.Group, bool) {
+	user, group, member, ok := getGroup(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	if !member.Manager {
+		webhelper.ReturnError(w, r, errNotGroupManager, &[]int{http.StatusForbidden}[0])
+		return nil, nil, false
+	}
+	return user, group, true
+}
+
+// getInvitingGroup loads the signed in user and the group in /groups/{uuid},
+// which has to have invited them
+func getInvitingGroup(w http.ResponseWriter, r *http.Request) (*User, *storage.Group, bool) {
+	user, ok := signedInUser(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	uuid := webhelper.Param(r, "uuid")
+	if webhelper.ReturnError(w, r, webhelper.CheckUuid(uuid), &[]int{http.StatusBadRequest}[0]) {
+		return nil, nil, false
+	}
+	groups, err := executeFindGroups(storage.GroupFilter{Uuid: uuid, InviteeUuid: user.Uuid})
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return nil, nil, false
+	}
+	if len(groups) == 0 || groups[0].Invite(user.Uuid) == nil {
+		webhelper.ReturnError(w, r, errNoGroupInvite, &[]int{http.StatusNotFound}[0])
+		return nil, nil, false
+	}
+	return user, groups[0], true
+}
+
+// returnGroupError writes the error from saving or deleting the group, a user
+// who is already in another group or a group someone else has changed since
+// it was loaded gets a 409
+func returnGroupError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if err == storage.ErrAlreadyInGroup || err == storage.ErrGroupConflict {
+		return webhelper.ReturnError(w, r, err, &[]int{http.StatusConflict}[0])
+	}
+	return webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0])
+}
+
+// saveGroup stores the group and returns it
+func saveGroup(w http.ResponseWriter, r *http.Request, group *storage.Group, status int) {
+	err := executeSaveGroup(group)
+	if returnGroupError(w, r, err) {
+		return
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(newGroupData(group))
+}
+
+// ListGroups returns the group the user is in, if they are in one
+func ListGroups(w http.ResponseWriter, r *http.Request) {
+	user, ok := signedInUser(w, r)
+	if !ok {
+		return
+	}
+	group, err := findUserGroup(user.Uuid)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	groupList := []*GroupData{}
+	if group != nil {
+		groupList = append(groupList, newGroupData(group))
+	}
+	json.NewEncoder(w).Encode(groupList)
+}
+
+// CreateGroup starts a household group with the user as its manager, users
+// can only be in one group
+func CreateGroup(w http.ResponseWriter, r *http.Request) {
+	user, ok := signedInUser(w, r)
+	if !ok {
+		return
+	}
+	var requestData GroupRequestData
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&requestData)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	if strings.TrimSpace(requestData.Name) == "" {
+		webhelper.ReturnError(w, r, errMissingGroupName, &[]int{http.StatusBadRequest}[0])
+		return
+	}
+
+	group := &storage.Group{
+		Name:      strings.TrimSpace(requestData.Name),
+		CreatedAt: time.Now().Unix(),
+		Members:   []*storage.GroupMember{{UserUuid: user.Uuid, Manager: true}},
+	}
+	saveGroup(w, r, group, http.StatusCreated)
+}
+
+// GetGroup returns the group in /groups/{uuid} to its members
+func GetGroup(w http.ResponseWriter, r *http.Request) {
+	_, group, _, ok := getGroup(w, r)
+	if !ok {
+		return
+	}
+	json.NewEncoder(w).Encode(newGroupData(group))
+}
+
+// UpdateGroup renames the group
+func UpdateGroup(w http.ResponseWriter, r *http.Request) {
+	_, group, ok := getManagedGroup(w, r)
+	if !ok {
+		return
+	}
+	var requestData GroupRequestData
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&requestData)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	if strings.TrimSpace(requestData.Name) == "" {
+		webhelper.ReturnError(w, r, errMissingGroupName, &[]int{http.StatusBadRequest}[0])
+		return
+	}
+	group.Name = strings.TrimSpace(requestData.Name)
+	saveGroup(w, r, group, http.StatusOK)
+}
+
+// DeleteGroup breaks up the group. Its playlists stay with whoever made them
+// and its members keep their accounts.
+func DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	_, group, ok := getManagedGroup(w, r)
+	if !ok {
+		return
+	}
+	err := executeDeleteGroup(group)
+	if returnGroupError(w, r, err) {
+		return
+	}
+	var responseDetails webhelper.Response
+	responseDetails.Message = "Group Successfully Deleted"
+	json.NewEncoder(w).Encode(responseDetails)
+}
+
+// ListGroupInvites returns the groups that have invited the user to join,
+// showing only the user's own invite
+func ListGroupInvites(w http.ResponseWriter, r *http.Request) {
+	user, ok := signedInUser(w, r)
+	if !ok {
+		return
+	}
+	groups, err := executeFindGroups(storage.GroupFilter{InviteeUuid: user.Uuid})
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	groupList := []*GroupData{}
+	for _, group := range groups {
+		invite := group.Invite(user.Uuid)
+		if invite == nil {
+			continue
+		}
+		shown := *group
+		shown.Invites = []*storage.GroupInvite{invite}
+		groupList = append(groupList, newGroupData(&shown))
+	}
+	json.NewEncoder(w).Encode(groupList)
+}
+
+// InviteGroupMember invites an existing user to join the group, they have to
+// be friends with the manager inviting them. They only join once they accept.
+func InviteGroupMember(w http.ResponseWriter, r *http.Request) {
+	user, group, ok := getManagedGroup(w, r)
+	if !ok {
+		return
+	}
+	var requestData GroupMemberRequestData
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&requestData)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	if requestData.EmailAddress == "" {
+		webhelper.ReturnError(w, r, errors.New("Missing Email Address"), &[]int{http.StatusBadRequest}[0])
+		return
+	}
+
+	var friend User
+	friend.EmailAddress = requestData.EmailAddress
+	err = friend.Select()
+	if err == storage.ErrUserNotFound {
+		webhelper.ReturnError(w, r, err, &[]int{http.StatusNotFound}[0])
+		return
+	}
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	if f := findFriend(user, friend.Uuid); f == nil || f.Status != storage.FriendAccepted {
+		webhelper.ReturnError(w, r, errNotFriendForGroup, &[]int{http.StatusForbidden}[0])
+		return
+	}
+	if group.Member(friend.Uuid) != nil {
+		webhelper.ReturnError(w, r, storage.ErrAlreadyInGroup, &[]int{http.StatusConflict}[0])
+		return
+	}
+	if group.Invite(friend.Uuid) != nil {
+		webhelper.ReturnError(w, r, errAlreadyInvited, &[]int{http.StatusConflict}[0])
+		return
+	}
+
+	group.Invites = append(group.Invites, &storage.GroupInvite{UserUuid: friend.Uuid, InvitedBy: user.Uuid, CreatedAt: time.Now().Unix()})
+	saveGroup(w, r, group, http.StatusCreated)
+}
+
+// AcceptGroupInvite makes the user a member of the group that invited them,
+// they have to leave any group they are already in first
+func AcceptGroupInvite(w http.ResponseWriter, r *http.Request) {
+	user, group, ok := getInvitingGroup(w, r)
+	if !ok {
+		return
+	}
+	current, err := findUserGroup(user.Uuid)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	if current != nil {
+		webhelper.ReturnError(w, r, storage.ErrAlreadyInGroup, &[]int{http.StatusConflict}[0])
+		return
+	}
+
+	removeInvite(group, user.Uuid)
+	group.Members = append(group.Members, &storage.GroupMember{UserUuid: user.Uuid})
+	saveGroup(w, r, group, http.StatusOK)
+}
+
+// DeclineGroupInvite turns down an invite to join the group
+func DeclineGroupInvite(w http.ResponseWriter, r *http.Request) {
+	user, group, ok := getInvitingGroup(w, r)
+	if !ok {
+		return
+	}
+	removeInvite(group, user.Uuid)
+	err := executeSaveGroup(group)
+	if returnGroupError(w, r, err) {
+		return
+	}
+	var responseDetails webhelper.Response
+	responseDetails.Message = "Group Invite Declined"
+	json.NewEncoder(w).Encode(responseDetails)
+}
+
+// CancelGroupInvite withdraws an invite the group has sent
+func CancelGroupInvite(w http.ResponseWriter, r *http.Request) {
+	_, group, ok := getManagedGroup(w, r)
+	if !ok {
+		return
+	}
+	userUuid := webhelper.Param(r, "userUuid")
+	if group.Invite(userUuid) == nil {
+		webhelper.ReturnError(w, r, errNoGroupInvite, &[]int{http.StatusNotFound}[0])
+		return
+	}
+	removeInvite(group, userUuid)
+	err := executeSaveGroup(group)
+	if returnGroupError(w, r, err) {
+		return
+	}
+	var responseDetails webhelper.Response
+	responseDetails.Message = "Group Invite Cancelled"
+	json.NewEncoder(w).Encode(responseDetails)
+}
+
+// CreateChildAccount creates an account for a child in the group. It has the
+// family role, so it can't make friends, and starts out restricted from
+// deleting the group's playlists. The manager set it up, so its email
+// address doesn't need verifying. The account is removed again if the group
+// can't be saved.
+func CreateChildAccount(w http.ResponseWriter, r *http.Request) {
+	_, group, ok := getManagedGroup(w, r)
+	if !ok {
+		return
+	}
+	var userData UserData
+	err := json.NewDecoder(r.Body).Decode(&userData)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	httpCode, err := checkPassword(userData.Password, userData.PasswordConfirm, true)
+	if webhelper.ReturnError(w, r, err, httpCode) {
+		return
+	}
+	httpCode, err = checkEmail(userData.EmailAddress, true)
+	if webhelper.ReturnError(w, r, err, httpCode) {
+		return
+	}
+
+	child := new(User)
+	child.FirstName = userData.FirstName
+	child.LastName = userData.LastName
+	child.EmailAddress = userData.EmailAddress
+	child.Password = userData.Password
+	child.Enabled = true
+	child.EmailVerified = true
+	child.Roles = []string{childRole}
+	_, err = CreateUser(child)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	err = child.Select()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+
+	group.Members = append(group.Members, &storage.GroupMember{UserUuid: child.Uuid, Restrictions: childRestrictions})
+	err = executeSaveGroup(group)
+	if err != nil {
+		// Don't leave an account behind that belongs to no group and holds
+		// the email address a retry would need
+		if deleteErr := child.Delete(); deleteErr != nil {
+			err = deleteErr
+		}
+		returnGroupError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newGroupData(group))
+}
+
+// UpdateGroupMember makes a member a manager or not, and sets what they are
+// restricted from doing
+func UpdateGroupMember(w http.ResponseWriter, r *http.Request) {
+	_, group, ok := getManagedGroup(w, r)
+	if !ok {
+		return
+	}
+	member := group.Member(webhelper.Param(r, "userUuid"))
+	if member == nil {
+		webhelper.ReturnError(w, r, storage.ErrUserNotFound, &[]int{http.StatusNotFound}[0])
+		return
+	}
+	var requestData UpdateGroupMemberData
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&requestData)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+
+	if requestData.Restrictions != nil {
+		var newRestrictions []string
+		for _, restriction := range *requestData.Restrictions {
+			if !contains(restrictions, restriction) {
+				webhelper.ReturnError(w, r, errors.New(errUnknownRestriction.Error()+": "+restriction), &[]int{http.StatusBadRequest}[0])
+				return
+			}
+			if !contains(newRestrictions, restriction) {
+				newRestrictions = append(newRestrictions, restriction)
+			}
+		}
+		member.Restrictions = newRestrictions
+	}
+	if requestData.Manager != nil {
+		if member.Manager && !*requestData.Manager && managers(group) == 1 {
+			webhelper.ReturnError(w, r, errLastManager, &[]int{http.StatusBadRequest}[0])
+			return
+		}
+		member.Manager = *requestData.Manager
+	}
+	saveGroup(w, r, group, http.StatusOK)
+}
+
+// RemoveGroupMember takes a member out of the group, managers can remove
+// anyone and members can leave. The last manager has to hand over or delete
+// the group instead.
+func RemoveGroupMember(w http.ResponseWriter, r *http.Request) {
+	user, group, signedIn, ok := getGroup(w, r)
+	if !ok {
+		return
+	}
+	userUuid := webhelper.Param(r, "userUuid")
+	if userUuid != user.Uuid && !signedIn.Manager {
+		webhelper.ReturnError(w, r, errNotGroupManager, &[]int{http.StatusForbidden}[0])
+		return
+	}
+	member := group.Member(userUuid)
+	if member == nil {
+		webhelper.ReturnError(w, r, storage.ErrUserNotFound, &[]int{http.StatusNotFound}[0])
+		return
+	}
+	if member.Manager && managers(group) == 1 && len(group.Members) > 1 {
+		webhelper.ReturnError(w, r, errLastManager, &[]int{http.StatusBadRequest}[0])
+		return
+	}
+
+	var err error
+	if len(group.Members) == 1 {
+		err = executeDeleteGroup(group)
+	} else {
+		var members []*storage.GroupMember
+		for _, m := range group.Members {
+			if m != member {
+				members = append(members, m)
+			}
+		}
+		group.Members = members
+		err = executeSaveGroup(group)
+	}
+	if returnGroupError(w, r, err) {
+		return
+	}
+	var responseDetails webhelper.Response
+	responseDetails.Message = "Member Successfully Removed"
+	json.NewEncoder(w).Encode(responseDetails)
+}
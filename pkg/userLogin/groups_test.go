@@ -0,0 +1,338 @@
+package userLogin
+
+import (
+	"encoding/json"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	groupUuid    = "9b0e8d7c-6a5f-4e3d-8c2b-1a0f9e8d7c01"
+	parentUuid   = "6a1d0c3e-7b2f-4e59-a8c4-1f0e9d8c7b01"
+	memberUuid   = "6a1d0c3e-7b2f-4e59-a8c4-1f0e9d8c7b02"
+	strangerUuid = "6a1d0c3e-7b2f-4e59-a8c4-1f0e9d8c7b03"
+)
+
+// groupTest stubs out the signed in user, who is in a group with parentUuid
+// managing it and memberUuid, and is friends with strangerUuid. The group is
+// kept in memory and returned.
+func groupTest(t *testing.T, signedIn string) **storage.Group {
+	restoreStubs(t)
+	group := &storage.Group{Id: 1, Uuid: groupUuid, Name: "Home", Members: []*storage.GroupMember{
+		{UserUuid: parentUuid, Manager: true},
+		{UserUuid: memberUuid},
+	}}
+	requestClaimsVar = func(r *http.Request) (*Claims, int) {
+		return &Claims{Username: signedIn + "@test.com"}, http.StatusOK
+	}
+	executeSelectUser = func(m *User) error {
+		uuid := m.Uuid
+		if uuid == "" {
+			uuid = strings.TrimSuffix(m.EmailAddress, "@test.com")
+		}
+		m.Uuid = uuid
+		m.EmailAddress = uuid + "@test.com"
+		if uuid == parentUuid {
+			m.Friends = []*storage.Friend{{Id: 1, FriendId: strangerUuid, Status: storage.FriendAccepted}}
+		}
+		return nil
+	}
+	executeFindGroups = func(filter storage.GroupFilter) ([]*storage.Group, error) {
+		if group == nil || (filter.MemberUuid != "" && group.Member(filter.MemberUuid) == nil) ||
+			(filter.InviteeUuid != "" && group.Invite(filter.InviteeUuid) == nil) {
+			return nil, nil
+		}
+		return []*storage.Group{group}, nil
+	}
+	executeSaveGroup = func(g *storage.Group) error {
+		if g.Id == 0 {
+			g.Id = 2
+			g.Uuid = groupUuid
+		}
+		group = g
+		return nil
+	}
+	executeDeleteGroup = func(g *storage.Group) error {
+		group = nil
+		return nil
+	}
+	return &group
+}
+
+func groupRequest(method string, body string, params webhelper.Params) *http.Request {
+	request := httptest.NewRequest(method, "/groups", strings.NewReader(body))
+	return webhelper.WithParams(request, params)
+}
+
+func TestCreateGroup(t *testing.T) {
+	t.Run("The creator manages the new group", func(t *testing.T) {
+		group := groupTest(t, strangerUuid)
+		responseRecorder := httptest.NewRecorder()
+
+		CreateGroup(responseRecorder, groupRequest("POST", `{"name":" The Smiths "}`, nil))
+		if responseRecorder.Code != http.StatusCreated {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusCreated, responseRecorder.Code)
+		}
+		var groupData GroupData
+		json.NewDecoder(responseRecorder.Body).Decode(&groupData)
+		if groupData.Name != "The Smiths" || len(groupData.Members) != 1 || !groupData.Members[0].Manager ||
+			(*group).Member(strangerUuid) == nil {
+			t.Errorf("Want the group saved with its manager, got '%+v'", groupData)
+		}
+	})
+	t.Run("Already in a group", func(t *testing.T) {
+		groupTest(t, memberUuid)
+		executeSaveGroup = func(g *storage.Group) error {
+			return storage.ErrAlreadyInGroup
+		}
+		responseRecorder := httptest.NewRecorder()
+
+		CreateGroup(responseRecorder, groupRequest("POST", `{"name":"Another"}`, nil))
+		if responseRecorder.Code != http.StatusConflict {
+			t.Errorf("Want status '%d', got '%d'", http.StatusConflict, responseRecorder.Code)
+		}
+	})
+}
+
+func TestGroupMembers(t *testing.T) {
+	tests := []struct {
+		name     string
+		signedIn string
+		method   string
+		handler  http.HandlerFunc
+		params   webhelper.Params
+		body     string
+		want     int
+	}{
+		{"A manager invites a friend", parentUuid, "POST", InviteGroupMember, webhelper.Params{"uuid": groupUuid}, `{"emailAddress":"` + strangerUuid + `@test.com"}`, http.StatusCreated},
+		{"A manager can only invite friends", parentUuid, "POST", InviteGroupMember, webhelper.Params{"uuid": groupUuid}, `{"emailAddress":"someone@test.com"}`, http.StatusForbidden},
+		{"A member can't invite anyone", memberUuid, "POST", InviteGroupMember, webhelper.Params{"uuid": groupUuid}, `{"emailAddress":"` + strangerUuid + `@test.com"}`, http.StatusForbidden},
+		{"A manager can't cancel an invite that wasn't sent", parentUuid, "DELETE", CancelGroupInvite, webhelper.Params{"uuid": groupUuid, "userUuid": strangerUuid}, ``, http.StatusNotFound},
+		{"Accepting without an invite", strangerUuid, "POST", AcceptGroupInvite, webhelper.Params{"uuid": groupUuid}, ``, http.StatusNotFound},
+		{"Someone outside the group can't see it", strangerUuid, "GET", GetGroup, webhelper.Params{"uuid": groupUuid}, ``, http.StatusNotFound},
+		{"A manager restricts a member", parentUuid, "PATCH", UpdateGroupMember, webhelper.Params{"uuid": groupUuid, "userUuid": memberUuid}, `{"restrictions":["edit-playlists"]}`, http.StatusOK},
+		{"A restriction that doesn't exist", parentUuid, "PATCH", UpdateGroupMember, webhelper.Params{"uuid": groupUuid, "userUuid": memberUuid}, `{"restrictions":["everything"]}`, http.StatusBadRequest},
+		{"The last manager can't stand down", parentUuid, "PATCH", UpdateGroupMember, webhelper.Params{"uuid": groupUuid, "userUuid": parentUuid}, `{"manager":false}`, http.StatusBadRequest},
+		{"A member can leave", memberUuid, "DELETE", RemoveGroupMember, webhelper.Params{"uuid": groupUuid, "userUuid": memberUuid}, ``, http.StatusOK},
+		{"A member can't remove anyone else", memberUuid, "DELETE", RemoveGroupMember, webhelper.Params{"uuid": groupUuid, "userUuid": parentUuid}, ``, http.StatusForbidden},
+		{"The last manager can't leave the others behind", parentUuid, "DELETE", RemoveGroupMember, webhelper.Params{"uuid": groupUuid, "userUuid": parentUuid}, ``, http.StatusBadRequest},
+		{"A member can't delete the group", memberUuid, "DELETE", DeleteGroup, webhelper.Params{"uuid": groupUuid}, ``, http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			groupTest(t, test.signedIn)
+			responseRecorder := httptest.NewRecorder()
+
+			test.handler(responseRecorder, groupRequest(test.method, test.body, test.params))
+			if responseRecorder.Code != test.want {
+				t.Errorf("Want status '%d', got '%d'", test.want, responseRecorder.Code)
+			}
+		})
+	}
+}
+
+func TestGroupConflicts(t *testing.T) {
+	tests := []struct {
+		name     string
+		signedIn string
+		method   string
+		handler  http.HandlerFunc
+		params   webhelper.Params
+		body     string
+	}{
+		{"Making a member a manager", parentUuid, "PATCH", UpdateGroupMember, webhelper.Params{"uuid": groupUuid, "userUuid": memberUuid}, `{"manager":true}`},
+		{"Removing a member", parentUuid, "DELETE", RemoveGroupMember, webhelper.Params{"uuid": groupUuid, "userUuid": memberUuid}, ``},
+		{"Deleting the group", parentUuid, "DELETE", DeleteGroup, webhelper.Params{"uuid": groupUuid}, ``},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			groupTest(t, test.signedIn)
+			// Someone else changed the group after it was loaded
+			executeSaveGroup = func(g *storage.Group) error {
+				return storage.ErrGroupConflict
+			}
+			executeDeleteGroup = func(g *storage.Group) error {
+				return storage.ErrGroupConflict
+			}
+			responseRecorder := httptest.NewRecorder()
+
+			test.handler(responseRecorder, groupRequest(test.method, test.body, test.params))
+			if responseRecorder.Code != http.StatusConflict {
+				t.Errorf("Want status '%d', got '%d'", http.StatusConflict, responseRecorder.Code)
+			}
+		})
+	}
+}
+
+func TestCreateChildAccount(t *testing.T) {
+	group := groupTest(t, parentUuid)
+	executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
+		return nil, nil
+	}
+	bcryptGenerateFromPassword = func(password []byte, cost int) ([]byte, error) {
+		return []byte("hash"), nil
+	}
+	var created *User
+	executeCreateUser = func(m *User) (*uint64, error) {
+		created = m
+		return &[]uint64{3}[0], nil
+	}
+	responseRecorder := httptest.NewRecorder()
+
+	CreateChildAccount(responseRecorder, groupRequest("POST",
+		`{"firstName":"Kid","emailAddress":"kid@test.com","password":"pw","confirmPassword":"pw"}`,
+		webhelper.Params{"uuid": groupUuid}))
+	if responseRecorder.Code != http.StatusCreated {
+		t.Fatalf("Want status '%d', got '%d'", http.StatusCreated, responseRecorder.Code)
+	}
+	if created == nil || !created.HasRole(childRole) || created.HasRole(storage.RoleMember) || !created.EmailVerified {
+		t.Fatalf("Want a verified family account, got '%+v'", created)
+	}
+	child := (*group).Member("kid")
+	if child == nil || child.Manager || !child.Restricted(storage.RestrictDeletePlaylists) {
+		t.Errorf("Want the child in the group restricted from deleting playlists, got '%+v'", (*group).Members)
+	}
+}
+
+func TestCreateChildAccountConflict(t *testing.T) {
+	groupTest(t, parentUuid)
+	executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
+		return nil, nil
+	}
+	bcryptGenerateFromPassword = func(password []byte, cost int) ([]byte, error) {
+		return []byte("hash"), nil
+	}
+	users := map[string]bool{}
+	executeCreateUser = func(m *User) (*uint64, error) {
+		users[m.EmailAddress] = true
+		return &[]uint64{3}[0], nil
+	}
+	executeDeleteUser = func(m *User) error {
+		delete(users, m.EmailAddress)
+		return nil
+	}
+	// Someone else changed the group after it was loaded
+	executeSaveGroup = func(g *storage.Group) error {
+		return storage.ErrGroupConflict
+	}
+	responseRecorder := httptest.NewRecorder()
+
+	CreateChildAccount(responseRecorder, groupRequest("POST",
+		`{"firstName":"Kid","emailAddress":"kid@test.com","password":"pw","confirmPassword":"pw"}`,
+		webhelper.Params{"uuid": groupUuid}))
+	if responseRecorder.Code != http.StatusConflict {
+		t.Errorf("Want status '%d', got '%d'", http.StatusConflict, responseRecorder.Code)
+	}
+	if len(users) != 0 {
+		t.Errorf("Want no account left behind, got '%v'", users)
+	}
+}
+
+func TestGroupInvites(t *testing.T) {
+	// inviteTest is groupTest with the parent's friend invited into the group
+	inviteTest := func(t *testing.T, signedIn string) **storage.Group {
+		group := groupTest(t, signedIn)
+		(*group).Invites = []*storage.GroupInvite{{UserUuid: strangerUuid, InvitedBy: parentUuid, CreatedAt: 1000}}
+		return group
+	}
+
+	t.Run("An invited friend is not a member until they accept", func(t *testing.T) {
+		group := groupTest(t, parentUuid)
+		InviteGroupMember(httptest.NewRecorder(), groupRequest("POST", `{"emailAddress":"`+strangerUuid+`@test.com"}`,
+			webhelper.Params{"uuid": groupUuid}))
+		invite := (*group).Invite(strangerUuid)
+		if invite == nil || invite.InvitedBy != parentUuid || invite.CreatedAt == 0 || (*group).Member(strangerUuid) != nil {
+			t.Fatalf("Want only an invite from the parent, got '%+v'", *group)
+		}
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
+			return &Claims{Username: strangerUuid + "@test.com"}, http.StatusOK
+		}
+		responseRecorder := httptest.NewRecorder()
+
+		ListGroupInvites(responseRecorder, groupRequest("GET", ``, nil))
+		var groupList []*GroupData
+		json.NewDecoder(responseRecorder.Body).Decode(&groupList)
+		if len(groupList) != 1 || groupList[0].Uuid != groupUuid || len(groupList[0].Invites) != 1 {
+			t.Errorf("Want the group's invite listed, got '%+v'", groupList)
+		}
+	})
+	t.Run("Inviting the same friend twice", func(t *testing.T) {
+		inviteTest(t, parentUuid)
+		responseRecorder := httptest.NewRecorder()
+
+		InviteGroupMember(responseRecorder, groupRequest("POST", `{"emailAddress":"`+strangerUuid+`@test.com"}`,
+			webhelper.Params{"uuid": groupUuid}))
+		if responseRecorder.Code != http.StatusConflict {
+			t.Errorf("Want status '%d', got '%d'", http.StatusConflict, responseRecorder.Code)
+		}
+	})
+	t.Run("Accepting joins the group", func(t *testing.T) {
+		group := inviteTest(t, strangerUuid)
+		responseRecorder := httptest.NewRecorder()
+
+		AcceptGroupInvite(responseRecorder, groupRequest("POST", ``, webhelper.Params{"uuid": groupUuid}))
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		member := (*group).Member(strangerUuid)
+		if member == nil || member.Manager || (*group).Invite(strangerUuid) != nil {
+			t.Errorf("Want the friend moved from the invites to the members, got '%+v'", *group)
+		}
+	})
+	t.Run("Accepting while in another group", func(t *testing.T) {
+		group := inviteTest(t, strangerUuid)
+		other := &storage.Group{Id: 2, Uuid: "9b0e8d7c-6a5f-4e3d-8c2b-1a0f9e8d7c02", Members: []*storage.GroupMember{{UserUuid: strangerUuid, Manager: true}}}
+		executeFindGroups = func(filter storage.GroupFilter) ([]*storage.Group, error) {
+			if filter.MemberUuid == strangerUuid {
+				return []*storage.Group{other}, nil
+			}
+			return []*storage.Group{*group}, nil
+		}
+		responseRecorder := httptest.NewRecorder()
+
+		AcceptGroupInvite(responseRecorder, groupRequest("POST", ``, webhelper.Params{"uuid": groupUuid}))
+		if responseRecorder.Code != http.StatusConflict {
+			t.Errorf("Want status '%d', got '%d'", http.StatusConflict, responseRecorder.Code)
+		}
+		if (*group).Member(strangerUuid) != nil {
+			t.Errorf("Want the friend kept out of the group, got '%+v'", (*group).Members)
+		}
+	})
+	t.Run("Declining drops the invite", func(t *testing.T) {
+		group := inviteTest(t, strangerUuid)
+		responseRecorder := httptest.NewRecorder()
+
+		DeclineGroupInvite(responseRecorder, groupRequest("POST", ``, webhelper.Params{"uuid": groupUuid}))
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if (*group).Invite(strangerUuid) != nil || (*group).Member(strangerUuid) != nil {
+			t.Errorf("Want the invite gone without joining, got '%+v'", *group)
+		}
+	})
+	t.Run("A manager cancels the invite", func(t *testing.T) {
+		group := inviteTest(t, parentUuid)
+		responseRecorder := httptest.NewRecorder()
+
+		CancelGroupInvite(responseRecorder, groupRequest("DELETE", ``, webhelper.Params{"uuid": groupUuid, "userUuid": strangerUuid}))
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if (*group).Invite(strangerUuid) != nil {
+			t.Errorf("Want the invite gone, got '%+v'", (*group).Invites)
+		}
+	})
+	t.Run("A member can't cancel an invite", func(t *testing.T) {
+		inviteTest(t, memberUuid)
+		responseRecorder := httptest.NewRecorder()
+
+		CancelGroupInvite(responseRecorder, groupRequest("DELETE", ``, webhelper.Params{"uuid": groupUuid, "userUuid": strangerUuid}))
+		if responseRecorder.Code != http.StatusForbidden {
+			t.Errorf("Want status '%d', got '%d'", http.StatusForbidden, responseRecorder.Code)
+		}
+	})
+}
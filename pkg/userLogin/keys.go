@@ -0,0 +1,275 @@
+package userLogin
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Tokens without a kid header are verified with the JWT_KEY secret, which is
+// how every token was signed before key ids
+const secretKid = ""
+
+const keyFileExt = ".pem"
+
+var errNoSigningKey = errors.New("No JWT Key defined in environment")
+var errUnknownKey = errors.New("Token signed with an unknown key")
+
+// signingKey is one of the keys tokens are verified with. Keys without a
+// private half only verify, which is how an old key is kept while the tokens
+// it signed run out.
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+// keyring is every key tokens are verified with, by kid, and the key new
+// tokens are signed with
+type keyring struct {
+	keys    map[string]*signingKey
+	signing *signingKey
+}
+
+// signingKeys is loaded once at startup by LoadSigningKeys
+var signingKeys *keyring
+
+// LoadSigningKeys reads the keys tokens are signed and verified with:
+//
+//   - JWT_KEY, an HS256 secret
+//   - JWT_KEY_DIR, a directory of RSA or Ed25519 PEM files, the file name is the kid
+//   - JWT_SIGNING_KID, the kid new tokens are signed with
+//
+// Without JWT_SIGNING_KID the only private key in JWT_KEY_DIR signs, or the
+// JWT_KEY secret when the directory has none.
+func LoadSigningKeys() error {
+	keys, err := loadKeyring(os.Getenv("JWT_KEY"), os.Getenv("JWT_KEY_DIR"), os.Getenv("JWT_SIGNING_KID"))
+	if err != nil {
+		return err
+	}
+	signingKeys = keys
+	return nil
+}
+
+func loadKeyring(secret string, dir string, signingKid string) (*keyring, error) {
+	keys := &keyring{keys: map[string]*signingKey{}}
+	if secret != "" {
+		keys.keys[secretKid] = &signingKey{
+			kid:     secretKid,
+			method:  jwt.SigningMethodHS256,
+			private: []byte(secret),
+			public:  []byte(secret),
+		}
+	}
+	var privateKids []string
+	if dir != "" {
+		files, err := filepath.Glob(filepath.Join(dir, "*"+keyFileExt))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			kid := strings.TrimSuffix(filepath.Base(file), keyFileExt)
+			key, err := parseKey(kid, data)
+			if err != nil {
+				return nil, fmt.Errorf("JWT key %s: %w", file, err)
+			}
+			keys.keys[kid] = key
+			if key.private != nil {
+				privateKids = append(privateKids, kid)
+			}
+		}
+	}
+
+	switch {
+	case signingKid != "":
+		key, ok := keys.keys[signingKid]
+		if !ok || key.private == nil {
+			return nil, fmt.Errorf("JWT_SIGNING_KID %s is not a private key in JWT_KEY_DIR", signingKid)
+		}
+		keys.signing = key
+	case len(privateKids) == 1:
+		keys.signing = keys.keys[privateKids[0]]
+	case len(privateKids) > 1:
+		sort.Strings(privateKids)
+		return nil, fmt.Errorf("JWT_SIGNING_KID must name one of %s", strings.Join(privateKids, ", "))
+	case secret != "":
+		keys.signing = keys.keys[secretKid]
+	default:
+		return nil, errNoSigningKey
+	}
+	return keys, nil
+}
+
+// parseKey reads a PKCS#1 or PKCS#8 private key, or a PKIX public key
+func parseKey(kid string, data []byte) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %s", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{kid: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.method, key.public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.method, key.private, key.public = signingMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.method, key.public = signingMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+	return key, nil
+}
+
+// sign signs the claims with the current signing key
+func (k *keyring) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signing.method, claims)
+	if k.signing.kid != secretKid {
+		token.Header["kid"] = k.signing.kid
+	}
+	return token.SignedString(k.signing.private)
+}
+
+// keyFunc finds the key a token was signed with, the token has to use the
+// key's algorithm so a public key can't be passed off as an HMAC secret
+func (k *keyring) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok || token.Method.Alg() != key.method.Alg() {
+		return nil, errUnknownKey
+	}
+	return key.public, nil
+}
+
+func currentKeys() (*keyring, error) {
+	if signingKeys == nil {
+		return nil, errNoSigningKey
+	}
+	return signingKeys, nil
+}
+
+// signingMethodEd25519 is EdDSA (RFC 8037) with Ed25519 keys, which jwt-go
+// doesn't have
+type signingMethodEd25519 struct{}
+
+var signingMethodEdDSA = &signingMethodEd25519{}
+
+func init() {
+	jwt.RegisterSigningMethod(signingMethodEdDSA.Alg(), func() jwt.SigningMethod {
+		return signingMethodEdDSA
+	})
+}
+
+func (m *signingMethodEd25519) Alg() string {
+	return "EdDSA"
+}
+
+func (m *signingMethodEd25519) Sign(signingString string, key interface{}) (string, error) {
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+	return jwt.EncodeSegment(ed25519.Sign(private, []byte(signingString))), nil
+}
+
+func (m *signingMethodEd25519) Verify(signingString string, signature string, key interface{}) error {
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(public, []byte(signingString), sig) {
+		return jwt.ErrSignatureInvalid
+	}
+	return nil
+}
+
+// JWK is a public key in the JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // OKP curve
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func newJWK(key *signingKey) (*JWK, bool) {
+	jwk := &JWK{Kid: key.kid, Use: "sig", Alg: key.method.Alg()}
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	default:
+		// The HS256 secret is never published
+		return nil, false
+	}
+	return jwk, true
+}
+
+// JWKS publishes the public keys tokens are verified with, so other services
+// can verify our tokens
+func JWKS(w http.ResponseWriter, r *http.Request) {
+	set := JWKSet{Keys: []JWK{}}
+	if keys, err := currentKeys(); err == nil {
+		for _, key := range keys.keys {
+			if jwk, ok := newJWK(key); ok {
+				set.Keys = append(set.Keys, *jwk)
+			}
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(set)
+}
@@ -0,0 +1,181 @@
+package userLogin
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// testSigningKey signs tokens with an HS256 secret, the way JWT_KEY does
+func testSigningKey() {
+	signingKeys, _ = loadKeyring("D5H5H65H56H5G4F3F3F32G", "", "")
+}
+
+// writeKey writes the key to dir as kid.pem, the private key unless public is set
+func writeKey(t *testing.T, dir string, kid string, key interface{}, public bool) {
+	var block *pem.Block
+	if public {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	} else {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	if err := os.WriteFile(filepath.Join(dir, kid+keyFileExt), pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testKeyDir(t *testing.T) (string, *rsa.PrivateKey, ed25519.PrivateKey) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeKey(t, dir, "rsa-1", rsaKey, false)
+	writeKey(t, dir, "ed-1", edKey, false)
+	return dir, rsaKey, edKey
+}
+
+func testClaims() *Claims {
+	return &Claims{
+		Username:       "test@test.com",
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Minute).Unix()},
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	dir, _, _ := testKeyDir(t)
+
+	t.Run("No keys", func(t *testing.T) {
+		if _, err := loadKeyring("", "", ""); err != errNoSigningKey {
+			t.Errorf("Want error '%v', got '%v'", errNoSigningKey, err)
+		}
+	})
+	t.Run("JWT_KEY on its own signs", func(t *testing.T) {
+		keys, err := loadKeyring("secret", "", "")
+		if err != nil || keys.signing.method != jwt.SigningMethodHS256 {
+			t.Errorf("Want the HS256 secret to sign, got '%v'", err)
+		}
+	})
+	t.Run("Several private keys need JWT_SIGNING_KID", func(t *testing.T) {
+		if _, err := loadKeyring("", dir, ""); err == nil {
+			t.Errorf("Want an error naming the keys")
+		}
+		keys, err := loadKeyring("", dir, "ed-1")
+		if err != nil || keys.signing.kid != "ed-1" {
+			t.Errorf("Want ed-1 to sign, got '%v'", err)
+		}
+	})
+	t.Run("JWT_SIGNING_KID has to be a private key", func(t *testing.T) {
+		if _, err := loadKeyring("", dir, "missing"); err == nil {
+			t.Errorf("Want an error for an unknown kid")
+		}
+	})
+}
+
+func TestSignAndVerify(t *testing.T) {
+	dir, rsaKey, _ := testKeyDir(t)
+
+	for _, kid := range []string{"rsa-1", "ed-1"} {
+		t.Run("Sign with "+kid, func(t *testing.T) {
+			keys, err := loadKeyring("secret", dir, kid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokenString, err := keys.sign(testClaims())
+			if err != nil {
+				t.Fatalf("Want no error, got '%s'", err.Error())
+			}
+			claims := &Claims{}
+			token, err := jwt.ParseWithClaims(tokenString, claims, keys.keyFunc)
+			if err != nil || !token.Valid || token.Header["kid"] != kid || claims.Username != "test@test.com" {
+				t.Errorf("Want a valid token signed by '%s', got '%v' '%v'", kid, token.Header, err)
+			}
+		})
+	}
+	t.Run("Rotated keys keep verifying until they are removed", func(t *testing.T) {
+		old, _ := loadKeyring("", dir, "rsa-1")
+		tokenString, _ := old.sign(testClaims())
+
+		// rsa-1 is retired to its public key and ed-1 takes over
+		os.Remove(filepath.Join(dir, "rsa-1"+keyFileExt))
+		writeKey(t, dir, "rsa-1", &rsaKey.PublicKey, true)
+		rotated, err := loadKeyring("", dir, "")
+		if err != nil || rotated.signing.kid != "ed-1" {
+			t.Fatalf("Want ed-1 to sign after rotation, got '%v'", err)
+		}
+		if _, err := jwt.ParseWithClaims(tokenString, &Claims{}, rotated.keyFunc); err != nil {
+			t.Errorf("Want the old token to verify, got '%s'", err.Error())
+		}
+
+		os.Remove(filepath.Join(dir, "rsa-1"+keyFileExt))
+		retired, _ := loadKeyring("", dir, "")
+		if _, err := jwt.ParseWithClaims(tokenString, &Claims{}, retired.keyFunc); err == nil {
+			t.Errorf("Want the token rejected once its key is retired")
+		}
+	})
+	t.Run("Tokens without a kid are checked against JWT_KEY", func(t *testing.T) {
+		secret, _ := loadKeyring("secret", "", "")
+		tokenString, _ := secret.sign(testClaims())
+		keys, _ := loadKeyring("secret", dir, "ed-1")
+		if _, err := jwt.ParseWithClaims(tokenString, &Claims{}, keys.keyFunc); err != nil {
+			t.Errorf("Want the token to verify, got '%s'", err.Error())
+		}
+	})
+	t.Run("A public key can't be used as an HMAC secret", func(t *testing.T) {
+		keys, _ := loadKeyring("", dir, "ed-1")
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+		forged.Header["kid"] = "ed-1"
+		tokenString, _ := forged.SignedString([]byte(keys.keys["ed-1"].public.(ed25519.PublicKey)))
+		if _, err := jwt.ParseWithClaims(tokenString, &Claims{}, keys.keyFunc); err == nil {
+			t.Errorf("Want the forged token rejected")
+		}
+	})
+}
+
+func TestJWKS(t *testing.T) {
+	defer testSigningKey()
+	dir, rsaKey, edKey := testKeyDir(t)
+	signingKeys, _ = loadKeyring("secret", dir, "ed-1")
+	request := httptest.NewRequest("GET", "/.well-known/jwks.json", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	JWKS(responseRecorder, request)
+	if responseRecorder.Code != http.StatusOK {
+		t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+	}
+	var set JWKSet
+	json.NewDecoder(responseRecorder.Body).Decode(&set)
+	if len(set.Keys) != 2 {
+		t.Fatalf("Want the 2 public keys without the secret, got '%+v'", set.Keys)
+	}
+	ed, rsa := set.Keys[0], set.Keys[1]
+	if ed.Kid != "ed-1" || ed.Kty != "OKP" || ed.Alg != "EdDSA" || ed.X != jwt.EncodeSegment(edKey.Public().(ed25519.PublicKey)) {
+		t.Errorf("Want the Ed25519 key, got '%+v'", ed)
+	}
+	if rsa.Kid != "rsa-1" || rsa.Kty != "RSA" || rsa.Alg != "RS256" || rsa.N != jwt.EncodeSegment(rsaKey.N.Bytes()) || rsa.E != "AQAB" {
+		t.Errorf("Want the RSA key, got '%+v'", rsa)
+	}
+}
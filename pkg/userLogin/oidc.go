@@ -0,0 +1,340 @@
+package userLogin
+
+import (
+	"encoding/json"
+	"errors"
+	"mimpidev/sinkrontrack-server/internal/oidc"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const oidcLoginTTL = 10 * time.Minute
+
+// The audience of the token kept in the cookie while the user is away at the
+// identity provider
+const oidcLoginPurpose = "oidc-login"
+
+// oidcLoginCookie is only sent back to the sign in routes
+const oidcLoginCookie = "oidcLogin"
+const oidcLoginPath = "/users/oidc"
+
+var errOidcLoginInvalid = errors.New("Invalid or expired single sign on, start again")
+var errOidcNotLinked = webhelper.NewError(webhelper.CodeOidcNotLinked, "No account is linked to that sign in")
+var errOidcAccountExists = webhelper.NewError(webhelper.CodeAccountExists, "An account with that email address already exists, sign in and link it instead")
+var errOidcNoEmail = errors.New("The identity provider didn't share an email address")
+
+var lookupProviderVar = oidc.Lookup
+
+var executeInsertExternalLogin = func(l *storage.ExternalLogin) (*uint64, error) {
+	return l.Insert()
+}
+
+var executeDeleteExternalLogin = func(l *storage.ExternalLogin) error {
+	return l.Delete()
+}
+
+var executeFindExternalLogins = func(filter storage.ExternalLoginFilter) ([]*storage.ExternalLogin, error) {
+	var search storage.ExternalLogin
+	return search.Find(filter)
+}
+
+// ExternalLoginData is an identity provider account linked to the user
+type ExternalLoginData struct {
+	Provider     string `json:"provider"`
+	EmailAddress string `json:"emailAddress,omitempty"`
+	CreatedAt    int64  `json:"createdAt"` // unix seconds
+}
+
+// OidcClaims carry the sign in over the round trip to the identity provider,
+// in a cookie so it can only be finished by the browser that started it
+type OidcClaims struct {
+	Provider     string   `json:"provider"`
+	State        string   `json:"state"`
+	Nonce        string   `json:"nonce"`
+	CodeVerifier string   `json:"codeVerifier"`
+	LinkUserUuid string   `json:"linkUserUuid,omitempty"` // linking to a signed in user, not signing in
+	DeviceId     string   `json:"deviceId,omitempty"`
+	DeviceName   string   `json:"deviceName,omitempty"`
+	AuthType     string   `json:"authType,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+	jwt.StandardClaims
+}
+
+// oidcAutoProvision is set with OIDC_AUTO_PROVISION, someone signing in with
+// a provider for the first time gets a new member account
+func oidcAutoProvision() bool {
+	return os.Getenv("OIDC_AUTO_PROVISION") == "true"
+}
+
+// startOidcLogin sends the browser to the provider, linkUserUuid is blank to
+// sign in
+func startOidcLogin(w http.ResponseWriter, r *http.Request, linkUserUuid string) {
+	name := webhelper.Param(r, "provider")
+	provider, err := lookupProviderVar(name)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusNotFound}[0]) {
+		return
+	}
+	claims := &OidcClaims{
+		Provider:     name,
+		LinkUserUuid: linkUserUuid,
+		DeviceId:     r.URL.Query().Get("deviceId"),
+		DeviceName:   r.URL.Query().Get("deviceName"),
+		AuthType:     r.URL.Query().Get("authType"),
+	}
+	if scopes := r.URL.Query().Get("scopes"); scopes != "" {
+		claims.Scopes = strings.Split(scopes, ",")
+	}
+	if err := checkScopes(claims.Scopes, nil); webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	for _, value := range []*string{&claims.State, &claims.Nonce, &claims.CodeVerifier} {
+		*value, err = oidc.RandomString()
+		if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+			return
+		}
+	}
+	keys, err := currentKeys()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	expiresAt := time.Now().Add(oidcLoginTTL)
+	claims.StandardClaims = jwt.StandardClaims{Audience: oidcLoginPurpose, ExpiresAt: expiresAt.Unix()}
+	token, err := keys.sign(claims)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	// Lax, the browser has to send it back when the provider redirects
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcLoginCookie,
+		Value:    token,
+		Path:     oidcLoginPath,
+		Expires:  expiresAt,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, provider.AuthCodeURL(claims.State, claims.Nonce, oidc.CodeChallenge(claims.CodeVerifier)), http.StatusFound)
+}
+
+// ListOidcProviders returns the names of the providers users can sign in with
+func ListOidcProviders(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(oidc.Providers())
+}
+
+// OidcLogin starts signing in with the provider in /users/oidc/{provider}, the
+// device can be given as the deviceId, deviceName and authType query params,
+// and comma separated scopes to limit its tokens
+func OidcLogin(w http.ResponseWriter, r *http.Request) {
+	startOidcLogin(w, r, "")
+}
+
+// LinkOidc starts linking the signed in user to their account at the provider
+// in /users/identities/{provider}/link
+func LinkOidc(w http.ResponseWriter, r *http.Request) {
+	user, ok := signedInUser(w, r)
+	if !ok {
+		return
+	}
+	startOidcLogin(w, r, user.Uuid)
+}
+
+// oidcIdentity finishes the round trip to the provider, checking it is the
+// one this browser started
+func oidcIdentity(w http.ResponseWriter, r *http.Request) (*OidcClaims, *oidc.Identity, bool) {
+	cookie, err := r.Cookie(oidcLoginCookie)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return nil, nil, false
+	}
+	clearCookie(w, oidcLoginCookie, oidcLoginPath)
+	keys, err := currentKeys()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return nil, nil, false
+	}
+	claims := &OidcClaims{}
+	token, err := jwt.ParseWithClaims(cookie.Value, claims, keys.keyFunc)
+	query := r.URL.Query()
+	if err != nil || !token.Valid || claims.Audience != oidcLoginPurpose ||
+		claims.Provider != webhelper.Param(r, "provider") || claims.State != query.Get("state") {
+		webhelper.ReturnError(w, r, errOidcLoginInvalid, &[]int{http.StatusBadRequest}[0])
+		return nil, nil, false
+	}
+	if query.Get("error") != "" {
+		webhelper.ReturnError(w, r, errors.New(query.Get("error")+" "+query.Get("error_description")), &[]int{http.StatusUnauthorized}[0])
+		return nil, nil, false
+	}
+	provider, err := lookupProviderVar(claims.Provider)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusNotFound}[0]) {
+		return nil, nil, false
+	}
+	identity, err := provider.Exchange(query.Get("code"), claims.CodeVerifier, claims.Nonce)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusUnauthorized}[0]) {
+		return nil, nil, false
+	}
+	return claims, identity, true
+}
+
+// provisionOidcUser makes a member account for someone signing in with the
+// provider for the first time
+func provisionOidcUser(identity *oidc.Identity) (*User, error, *int) {
+	if identity.EmailAddress == "" {
+		return nil, errOidcNoEmail, &[]int{http.StatusForbidden}[0]
+	}
+	// Taking over an account by claiming its email address at a provider
+	// isn't allowed, its owner has to link the provider themselves
+	if _, err := checkEmail(identity.EmailAddress, true); err != nil {
+		return nil, errOidcAccountExists, &[]int{http.StatusConflict}[0]
+	}
+	// Nobody knows the password, it can be reset by email to sign in without
+	// the provider
+	password, err := oidc.RandomString()
+	if err != nil {
+		return nil, err, &[]int{http.StatusInternalServerError}[0]
+	}
+	user := new(User)
+	user.FirstName = identity.FirstName
+	user.LastName = identity.LastName
+	user.EmailAddress = identity.EmailAddress
+	user.Password = password
+	user.Enabled = true
+	user.EmailVerified = identity.EmailVerified
+	user.Roles = []string{storage.RoleMember}
+	if _, err := CreateUser(user); err != nil {
+		return nil, err, &[]int{http.StatusInternalServerError}[0]
+	}
+	if err := user.Select(); err != nil {
+		return nil, err, &[]int{http.StatusInternalServerError}[0]
+	}
+	return user, nil, nil
+}
+
+// OidcCallback is where the provider sends the browser back to, in
+// /users/oidc/{provider}/callback. It signs in the linked user, makes them an
+// account when OIDC_AUTO_PROVISION is on, or links the provider when that was
+// started with LinkOidc.
+func OidcCallback(w http.ResponseWriter, r *http.Request) {
+	claims, identity, ok := oidcIdentity(w, r)
+	if !ok {
+		return
+	}
+	logins, err := executeFindExternalLogins(storage.ExternalLoginFilter{Provider: claims.Provider, Subject: identity.Subject})
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+
+	if claims.LinkUserUuid != "" {
+		login := &storage.ExternalLogin{
+			Provider:     claims.Provider,
+			Subject:      identity.Subject,
+			UserUuid:     claims.LinkUserUuid,
+			EmailAddress: identity.EmailAddress,
+			CreatedAt:    time.Now().Unix(),
+		}
+		_, err := executeInsertExternalLogin(login)
+		if err == storage.ErrLoginExists {
+			webhelper.ReturnError(w, r, err, &[]int{http.StatusConflict}[0])
+			return
+		}
+		if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(newExternalLoginData(login))
+		return
+	}
+
+	var user *User
+	switch {
+	case len(logins) > 0:
+		user = new(User)
+		user.Uuid = logins[0].UserUuid
+		if err := user.Select(); webhelper.ReturnError(w, r, err, &[]int{http.StatusUnauthorized}[0]) {
+			return
+		}
+	case oidcAutoProvision():
+		var httpCode *int
+		user, err, httpCode = provisionOidcUser(identity)
+		if webhelper.ReturnError(w, r, err, httpCode) {
+			return
+		}
+		_, err = executeInsertExternalLogin(&storage.ExternalLogin{
+			Provider:     claims.Provider,
+			Subject:      identity.Subject,
+			UserUuid:     user.Uuid,
+			EmailAddress: identity.EmailAddress,
+			CreatedAt:    time.Now().Unix(),
+		})
+		if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+			return
+		}
+	default:
+		webhelper.ReturnError(w, r, errOidcNotLinked, &[]int{http.StatusForbidden}[0])
+		return
+	}
+	oidcSignin(w, r, user, claims)
+}
+
+// oidcSignin signs the user in the same as a password would, two factor
+// authentication still applies
+func oidcSignin(w http.ResponseWriter, r *http.Request, user *User, claims *OidcClaims) {
+	if !user.Enabled {
+		webhelper.ReturnError(w, r, errAccountDisabled, &[]int{http.StatusForbidden}[0])
+		return
+	}
+	if err := signInVerified(user.EmailVerified); webhelper.ReturnError(w, r, err, &[]int{http.StatusForbidden}[0]) {
+		return
+	}
+	creds := &Credentials{Username: user.EmailAddress, DeviceId: claims.DeviceId, DeviceName: claims.DeviceName, Scopes: claims.Scopes}
+	if user.TotpEnabled {
+		totpChallenge(w, r, user.Uuid, totpFingerprint(user.Password, user.TotpSecret), creds, claims.AuthType)
+		return
+	}
+	completeSignin(w, r, user.EmailAddress, user.Uuid, creds, claims.AuthType)
+}
+
+func newExternalLoginData(l *storage.ExternalLogin) *ExternalLoginData {
+	return &ExternalLoginData{Provider: l.Provider, EmailAddress: l.EmailAddress, CreatedAt: l.CreatedAt}
+}
+
+// ListExternalLogins returns the providers the signed in user has linked
+func ListExternalLogins(w http.ResponseWriter, r *http.Request) {
+	user, ok := signedInUser(w, r)
+	if !ok {
+		return
+	}
+	logins, err := executeFindExternalLogins(storage.ExternalLoginFilter{UserUuid: user.Uuid})
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	loginData := []*ExternalLoginData{}
+	for _, login := range logins {
+		loginData = append(loginData, newExternalLoginData(login))
+	}
+	json.NewEncoder(w).Encode(loginData)
+}
+
+// DeleteExternalLogin unlinks the provider in /users/identities/{provider}
+// from the signed in user
+func DeleteExternalLogin(w http.ResponseWriter, r *http.Request) {
+	user, ok := signedInUser(w, r)
+	if !ok {
+		return
+	}
+	logins, err := executeFindExternalLogins(storage.ExternalLoginFilter{Provider: webhelper.Param(r, "provider"), UserUuid: user.Uuid})
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	if len(logins) == 0 {
+		webhelper.ReturnError(w, r, storage.ErrLoginNotFound, &[]int{http.StatusNotFound}[0])
+		return
+	}
+	err = executeDeleteExternalLogin(logins[0])
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	json.NewEncoder(w).Encode(webhelper.Response{Message: "Unlinked " + logins[0].Provider})
+}
@@ -0,0 +1,235 @@
+package userLogin
+
+import (
+	"encoding/json"
+	"mimpidev/sinkrontrack-server/internal/oidc"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+const oidcSubject = "idp-subject"
+
+// fakeProvider hands out the identity for "code", once the verifier matches
+// the challenge the sign in started with
+type fakeProvider struct {
+	identity  *oidc.Identity
+	challenge string
+}
+
+func (p *fakeProvider) AuthCodeURL(state string, nonce string, codeChallenge string) string {
+	p.challenge = codeChallenge
+	return "https://idp.test/authorize?" + url.Values{"state": {state}, "nonce": {nonce}}.Encode()
+}
+
+func (p *fakeProvider) Exchange(code string, codeVerifier string, nonce string) (*oidc.Identity, error) {
+	if code != "code" || oidc.CodeChallenge(codeVerifier) != p.challenge {
+		return nil, oidc.ErrUnknownProvider
+	}
+	return p.identity, nil
+}
+
+// oidcTest stubs out the "test" provider signing in as oidcSubject, on top of
+// totpTest. The logins linked so far are returned.
+func oidcTest(t *testing.T, totpEnabled bool, linked bool) *[]*storage.ExternalLogin {
+	totpTest(t, totpEnabled)
+	provider := &fakeProvider{identity: &oidc.Identity{Subject: oidcSubject, EmailAddress: "test@test.com", EmailVerified: true}}
+	lookupProviderVar = func(name string) (oidc.Provider, error) {
+		if name != "test" {
+			return nil, oidc.ErrUnknownProvider
+		}
+		return provider, nil
+	}
+	logins := []*storage.ExternalLogin{}
+	if linked {
+		logins = append(logins, &storage.ExternalLogin{Id: 1, Provider: "test", Subject: oidcSubject, UserUuid: emailUserUuid})
+	}
+	executeFindExternalLogins = func(filter storage.ExternalLoginFilter) ([]*storage.ExternalLogin, error) {
+		var found []*storage.ExternalLogin
+		for _, l := range logins {
+			if (filter.Subject == "" || l.Subject == filter.Subject) && (filter.UserUuid == "" || l.UserUuid == filter.UserUuid) {
+				found = append(found, l)
+			}
+		}
+		return found, nil
+	}
+	executeInsertExternalLogin = func(l *storage.ExternalLogin) (*uint64, error) {
+		for _, existing := range logins {
+			if existing.Subject == l.Subject || existing.UserUuid == l.UserUuid {
+				return nil, storage.ErrLoginExists
+			}
+		}
+		logins = append(logins, l)
+		return &[]uint64{uint64(len(logins))}[0], nil
+	}
+	return &logins
+}
+
+// oidcRoundTrip starts the sign in with the handler, then comes back to the
+// callback the way the provider would
+func oidcRoundTrip(t *testing.T, start http.HandlerFunc, state string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest("GET", "/users/oidc/test?deviceName=Browser", nil)
+	request = webhelper.WithParams(request, webhelper.Params{"provider": "test"})
+	responseRecorder := httptest.NewRecorder()
+	start(responseRecorder, request)
+	if responseRecorder.Code != http.StatusFound {
+		t.Fatalf("Want status '%d', got '%d'", http.StatusFound, responseRecorder.Code)
+	}
+	location, _ := url.Parse(responseRecorder.Header().Get("Location"))
+	if state == "" {
+		state = location.Query().Get("state")
+	}
+	cookies := responseRecorder.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != oidcLoginCookie || !cookies[0].HttpOnly {
+		t.Fatalf("Want the sign in kept in an http only cookie, got '%+v'", cookies)
+	}
+
+	request = httptest.NewRequest("GET", "/users/oidc/test/callback?"+url.Values{"code": {"code"}, "state": {state}}.Encode(), nil)
+	request = webhelper.WithParams(request, webhelper.Params{"provider": "test"})
+	request.AddCookie(cookies[0])
+	responseRecorder = httptest.NewRecorder()
+	OidcCallback(responseRecorder, request)
+	return responseRecorder
+}
+
+func TestOidcCallback(t *testing.T) {
+	t.Run("A linked account signs in", func(t *testing.T) {
+		oidcTest(t, false, true)
+
+		responseRecorder := oidcRoundTrip(t, OidcLogin, "")
+		if responseRecorder.Code != http.StatusAccepted {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusAccepted, responseRecorder.Code)
+		}
+		var signin SigninData
+		json.NewDecoder(responseRecorder.Body).Decode(&signin)
+		if signin.Token == "" || signin.Name != "Browser" {
+			t.Errorf("Want a token for the device, got '%+v'", signin)
+		}
+	})
+	t.Run("Two factor authentication still applies", func(t *testing.T) {
+		oidcTest(t, true, true)
+
+		responseRecorder := oidcRoundTrip(t, OidcLogin, "")
+		var challenge TotpChallengeData
+		json.NewDecoder(responseRecorder.Body).Decode(&challenge)
+		if responseRecorder.Code != http.StatusAccepted || !challenge.TotpRequired {
+			t.Errorf("Want a two factor challenge, got '%d' '%+v'", responseRecorder.Code, challenge)
+		}
+	})
+	t.Run("The state has to match", func(t *testing.T) {
+		oidcTest(t, false, true)
+
+		responseRecorder := oidcRoundTrip(t, OidcLogin, "forged")
+		if responseRecorder.Code != http.StatusBadRequest {
+			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
+		}
+	})
+	t.Run("Nobody is linked", func(t *testing.T) {
+		oidcTest(t, false, false)
+
+		responseRecorder := oidcRoundTrip(t, OidcLogin, "")
+		if responseRecorder.Code != http.StatusForbidden {
+			t.Errorf("Want status '%d', got '%d'", http.StatusForbidden, responseRecorder.Code)
+		}
+	})
+	t.Run("Auto provisioning doesn't take over an existing account", func(t *testing.T) {
+		oidcTest(t, false, false)
+		t.Setenv("OIDC_AUTO_PROVISION", "true")
+
+		responseRecorder := oidcRoundTrip(t, OidcLogin, "")
+		if responseRecorder.Code != http.StatusConflict {
+			t.Errorf("Want status '%d', got '%d'", http.StatusConflict, responseRecorder.Code)
+		}
+	})
+	t.Run("Auto provisioning makes a member account", func(t *testing.T) {
+		logins := oidcTest(t, false, false)
+		t.Setenv("OIDC_AUTO_PROVISION", "true")
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
+			return nil, nil
+		}
+		var created *User
+		executeCreateUser = func(m *User) (*uint64, error) {
+			m.Uuid = "new-user"
+			created = m
+			return &[]uint64{3}[0], nil
+		}
+		executeSelectUser = func(m *User) error {
+			*m = *created
+			return nil
+		}
+
+		responseRecorder := oidcRoundTrip(t, OidcLogin, "")
+		if responseRecorder.Code != http.StatusAccepted {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusAccepted, responseRecorder.Code)
+		}
+		if created == nil || !created.HasRole(storage.RoleMember) || !created.EmailVerified || created.Password == "" {
+			t.Errorf("Want a verified member account, got '%+v'", created)
+		}
+		if len(*logins) != 1 || (*logins)[0].UserUuid != "new-user" {
+			t.Errorf("Want the new account linked, got '%+v'", *logins)
+		}
+	})
+}
+
+func TestLinkOidc(t *testing.T) {
+	t.Run("The signed in user links the provider", func(t *testing.T) {
+		logins := oidcTest(t, false, false)
+
+		responseRecorder := oidcRoundTrip(t, LinkOidc, "")
+		if responseRecorder.Code != http.StatusCreated {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusCreated, responseRecorder.Code)
+		}
+		if len(*logins) != 1 || (*logins)[0].UserUuid != emailUserUuid || (*logins)[0].Subject != oidcSubject {
+			t.Errorf("Want the login linked, got '%+v'", *logins)
+		}
+	})
+	t.Run("Already linked", func(t *testing.T) {
+		oidcTest(t, false, true)
+
+		responseRecorder := oidcRoundTrip(t, LinkOidc, "")
+		if responseRecorder.Code != http.StatusConflict {
+			t.Errorf("Want status '%d', got '%d'", http.StatusConflict, responseRecorder.Code)
+		}
+	})
+}
+
+func TestDeleteExternalLogin(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		want     int
+	}{
+		{"Unlinks the provider", "test", http.StatusOK},
+		{"A provider that isn't linked", "other", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			oidcTest(t, false, true)
+			executeFindExternalLogins = func(filter storage.ExternalLoginFilter) ([]*storage.ExternalLogin, error) {
+				if filter.Provider != "test" {
+					return nil, nil
+				}
+				return []*storage.ExternalLogin{{Id: 1, Provider: "test", UserUuid: filter.UserUuid}}, nil
+			}
+			var deleted *storage.ExternalLogin
+			executeDeleteExternalLogin = func(l *storage.ExternalLogin) error {
+				deleted = l
+				return nil
+			}
+			request := httptest.NewRequest("DELETE", "/users/identities/"+test.provider, nil)
+			request = webhelper.WithParams(request, webhelper.Params{"provider": test.provider})
+			responseRecorder := httptest.NewRecorder()
+
+			DeleteExternalLogin(responseRecorder, request)
+			if responseRecorder.Code != test.want {
+				t.Fatalf("Want status '%d', got '%d'", test.want, responseRecorder.Code)
+			}
+			if (deleted != nil) != (test.want == http.StatusOK) {
+				t.Errorf("Want deleted '%v', got '%+v'", test.want == http.StatusOK, deleted)
+			}
+		})
+	}
+}
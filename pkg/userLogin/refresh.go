@@ -0,0 +1,312 @@
+package userLogin
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+)
+
+const defaultAccessTokenTTL = 15 * 60
+const defaultRefreshTokenTTL = 30 * 24 * 60 * 60
+
+// refreshTokenCookie is only sent to the refresh endpoint
+const refreshTokenCookie = "refreshToken"
+
+var errRefreshTokenInvalid = webhelper.NewError(webhelper.CodeRefreshTokenInvalid, "Invalid Refresh Token")
+var errRefreshTokenExpired = webhelper.NewError(webhelper.CodeRefreshTokenExpired, "Refresh Token has expired")
+var errRefreshTokenReused = webhelper.NewError(webhelper.CodeRefreshTokenReused, "Refresh Token has already been used, the device has been signed out")
+
+type RefreshTokenData struct {
+	RefreshToken string   `json:"refreshToken"`
+	Scopes       []string `json:"scopes,omitempty"` // narrows the device's scopes, left out to keep them
+}
+
+// session is the access and refresh token handed to a device by a sign in or
+// a refresh. Only the hash of the refresh token is stored, on the device,
+// along with the scopes so a refresh hands out the same ones. Every refresh
+// token from one sign in is in the same family, numbered by generation.
+type session struct {
+	tokenId        string // jti of the access token
+	refreshSecret  string
+	refreshExpires time.Time
+	scopes         []string // nil when the tokens aren't limited
+	family         string
+	generation     uint64
+}
+
+// ttlFromEnv reads a lifetime in seconds from the environment, or the default
+// when it isn't set
+func ttlFromEnv(name string, defaultTTL int64) time.Duration {
+	ttl, err := strconv.ParseInt(os.Getenv(name), 10, 64)
+	if err != nil || ttl <= 0 {
+		ttl = defaultTTL
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+// accessTokenTTL can be set with ACCESS_TOKEN_TTL
+func accessTokenTTL() time.Duration {
+	return ttlFromEnv("ACCESS_TOKEN_TTL", defaultAccessTokenTTL)
+}
+
+// refreshTokenTTL can be set with REFRESH_TOKEN_TTL
+func refreshTokenTTL() time.Duration {
+	return ttlFromEnv("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL)
+}
+
+func newSession(scopes []string) (*session, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return &session{
+		tokenId:        uuid.NewString(),
+		refreshSecret:  base64.RawURLEncoding.EncodeToString(secret),
+		refreshExpires: time.Now().Add(refreshTokenTTL()),
+		scopes:         scopes,
+		family:         uuid.NewString(),
+		generation:     1,
+	}, nil
+}
+
+// continueFamily makes the session the next generation of the device's
+// refresh tokens, a device from before families starts a new one
+func (s *session) continueFamily(d *storage.Device) {
+	if d.RefreshTokenFamily != "" {
+		s.family = d.RefreshTokenFamily
+		s.generation = d.RefreshTokenGeneration + 1
+	}
+}
+
+// apply makes the session the only one the device accepts
+func (s *session) apply(d *storage.Device) {
+	d.LastTokenId = s.tokenId
+	d.RefreshTokenHash = hashRefreshSecret(s.refreshSecret)
+	d.RefreshTokenExpires = s.refreshExpires.Unix()
+	d.RefreshTokenFamily = s.family
+	d.RefreshTokenGeneration = s.generation
+	d.Scopes = s.scopes
+}
+
+func hashRefreshSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// signOutDevice stops the device's access and refresh tokens working, the
+// device itself is kept
+func signOutDevice(d *storage.Device) {
+	d.LastTokenId = ""
+	d.RefreshTokenHash = ""
+	d.RefreshTokenExpires = 0
+	d.RefreshTokenFamily = ""
+	d.RefreshTokenGeneration = 0
+}
+
+// refreshToken is what a refresh token says about itself, so it can be
+// checked without knowing who sent it
+type refreshToken struct {
+	userUuid   string
+	deviceUuid string
+	family     string // blank for tokens from before families
+	generation uint64
+	secret     string
+}
+
+// A refresh token is the user uuid, device uuid, family, generation and
+// secret joined with dots
+func formatRefreshToken(t refreshToken) string {
+	return strings.Join([]string{t.userUuid, t.deviceUuid, t.family, strconv.FormatUint(t.generation, 10), t.secret}, ".")
+}
+
+// parseRefreshToken reads a refresh token, including the user uuid, device
+// uuid and secret ones handed out before families
+func parseRefreshToken(token string) (*refreshToken, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 && len(parts) != 5 {
+		return nil, errRefreshTokenInvalid
+	}
+	for _, id := range parts[:2] {
+		if webhelper.CheckUuid(id) != nil {
+			return nil, errRefreshTokenInvalid
+		}
+	}
+	t := &refreshToken{userUuid: parts[0], deviceUuid: parts[1], secret: parts[len(parts)-1]}
+	if len(parts) == 5 {
+		generation, err := strconv.ParseUint(parts[3], 10, 64)
+		if err != nil || webhelper.CheckUuid(parts[2]) != nil {
+			return nil, errRefreshTokenInvalid
+		}
+		t.family = parts[2]
+		t.generation = generation
+	}
+	if t.secret == "" {
+		return nil, errRefreshTokenInvalid
+	}
+	return t, nil
+}
+
+// reusedBy reports whether t is an earlier token of the device's family,
+// which has been swapped for a newer one already
+func (t *refreshToken) reusedBy(d *storage.Device) bool {
+	return t.family != "" &&
+		subtle.ConstantTimeCompare([]byte(t.family), []byte(d.RefreshTokenFamily)) == 1 &&
+		t.generation < d.RefreshTokenGeneration
+}
+
+// issueSession hands out the access token for the claims along with the
+// session's refresh token, which browsers get as a cookie only sent back to
+// the refresh endpoint
+func issueSession(w http.ResponseWriter, claims *Claims, userUuid string, s *session) (*TokenData, error) {
+	expirationTime := time.Now().Add(accessTokenTTL())
+	claims.ExpiresAt = expirationTime.Unix()
+	claims.Id = s.tokenId
+	claims.Scopes = s.scopes
+	token, err := issueToken(w, claims, expirationTime)
+	if err != nil {
+		return nil, err
+	}
+	token.Scopes = s.scopes
+	token.RefreshToken = formatRefreshToken(refreshToken{
+		userUuid:   userUuid,
+		deviceUuid: claims.Device,
+		family:     s.family,
+		generation: s.generation,
+		secret:     s.refreshSecret,
+	})
+	token.RefreshExpiresAt = s.refreshExpires.Unix()
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshTokenCookie,
+		Value:    token.RefreshToken,
+		Path:     "/users/refreshToken",
+		Expires:  s.refreshExpires,
+		HttpOnly: true,
+	})
+	return token, nil
+}
+
+// requestRefreshToken reads the body, the refresh token comes from the cookie
+// when the body doesn't have one
+func requestRefreshToken(r *http.Request) (*RefreshTokenData, error) {
+	var data RefreshTokenData
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if data.RefreshToken == "" {
+		c, err := r.Cookie(refreshTokenCookie)
+		if err != nil {
+			return nil, errRefreshTokenInvalid
+		}
+		data.RefreshToken = c.Value
+	}
+	return &data, nil
+}
+
+// rejectReusedToken signs the device out, a refresh token used twice has
+// most likely been stolen
+func rejectReusedToken(w http.ResponseWriter, r *http.Request, device *Device) {
+	signOutDevice(&device.Device)
+	err := device.Update()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	webhelper.ReturnError(w, r, errRefreshTokenReused, &[]int{http.StatusUnauthorized}[0])
+}
+
+// RefreshToken swaps a refresh token for a new access token and refresh token.
+// Each refresh token only works once, using any earlier token of the device's
+// family, or the same token twice at once, signs the device out as it has
+// most likely been stolen. Any other
+// refresh token is turned away without touching the device. The new tokens
+// keep the device's scopes, asking for scopes narrows them from then on.
+func RefreshToken(w http.ResponseWriter, r *http.Request) {
+	data, err := requestRefreshToken(r)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusUnauthorized}[0]) {
+		return
+	}
+	presented, err := parseRefreshToken(data.RefreshToken)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusUnauthorized}[0]) {
+		return
+	}
+
+	var user User
+	user.Uuid = presented.userUuid
+	if err := user.Select(); err != nil {
+		webhelper.ReturnError(w, r, errRefreshTokenInvalid, &[]int{http.StatusUnauthorized}[0])
+		return
+	}
+	if !user.Enabled {
+		webhelper.ReturnError(w, r, errAccountDisabled, &[]int{http.StatusForbidden}[0])
+		return
+	}
+	device, err := findUserDevice(user.EmailAddress, presented.deviceUuid)
+	if err == storage.ErrDeviceNotFound || (err == nil && device.RefreshTokenHash == "") {
+		webhelper.ReturnError(w, r, errRefreshTokenInvalid, &[]int{http.StatusUnauthorized}[0])
+		return
+	}
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	hash := hashRefreshSecret(presented.secret)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(device.RefreshTokenHash)) != 1 {
+		if !presented.reusedBy(&device.Device) {
+			webhelper.ReturnError(w, r, errRefreshTokenInvalid, &[]int{http.StatusUnauthorized}[0])
+			return
+		}
+		rejectReusedToken(w, r, device)
+		return
+	}
+	if device.RefreshTokenExpires <= time.Now().Unix() {
+		webhelper.ReturnError(w, r, errRefreshTokenExpired, &[]int{http.StatusUnauthorized}[0])
+		return
+	}
+	scopes := device.Scopes
+	if len(data.Scopes) != 0 {
+		if err := checkScopes(data.Scopes, device.Scopes); webhelper.ReturnError(w, r, err, scopeErrorStatus(err)) {
+			return
+		}
+		scopes = requestedScopes(data.Scopes)
+	}
+
+	s, err := newSession(scopes)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	s.continueFamily(&device.Device)
+	s.apply(&device.Device)
+	device.LastSeen = time.Now().Unix()
+	err = device.RotateRefreshToken(hash)
+	if err == storage.ErrRefreshConflict {
+		// Another refresh or a sign out swapped the token since it was checked,
+		// so it has been used twice
+		rejectReusedToken(w, r, device)
+		return
+	}
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	claims := &Claims{
+		Username:       user.EmailAddress,
+		Device:         device.Uuid,
+		StandardClaims: jwt.StandardClaims{Subject: device.Type},
+	}
+	token, err := issueSession(w, claims, user.Uuid, s)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	json.NewEncoder(w).Encode(token)
+}
@@ -0,0 +1,251 @@
+package userLogin
+
+import (
+	"encoding/json"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const refreshUserUuid = "6a1d0c3e-7b2f-4e59-a8c4-1f0e9d8c7b01"
+
+// The test device's refresh tokens are in refreshFamily, its current one is
+// refreshGeneration
+const refreshFamily = "0b5c7f2e-3d41-4a8e-9f6b-2c1d0e9a8b7c"
+const refreshGeneration = 3
+
+// refreshTest stubs out test@test.com with the test device holding the
+// refresh token secret, returning the device as it was last saved
+func refreshTest(t *testing.T, secret string, expires int64) **Device {
+	restoreStubs(t)
+	testSigningKey()
+	executeSelectUser = func(m *User) error {
+		if m.Uuid != refreshUserUuid {
+			return storage.ErrUserNotFound
+		}
+		m.EmailAddress = "test@test.com"
+		m.Enabled = true
+		return nil
+	}
+	executeFindDevices = func(filter storage.DeviceFilter) ([]*storage.Device, error) {
+		devices, _ := findTestDevice(filter)
+		for _, d := range devices {
+			d.RefreshTokenHash = hashRefreshSecret(secret)
+			d.RefreshTokenExpires = expires
+			d.RefreshTokenFamily = refreshFamily
+			d.RefreshTokenGeneration = refreshGeneration
+		}
+		return devices, nil
+	}
+	var saved *Device
+	executeUpdateDevice = func(d *Device) error {
+		saved = d
+		return nil
+	}
+	executeRotateDeviceRefreshToken = func(d *Device, expectedHash string) error {
+		if expectedHash != hashRefreshSecret(secret) {
+			return storage.ErrRefreshConflict
+		}
+		saved = d
+		return nil
+	}
+	return &saved
+}
+
+// testRefreshToken is a refresh token for the test device in refreshFamily
+func testRefreshToken(generation uint64, secret string) string {
+	return formatRefreshToken(refreshToken{
+		userUuid:   refreshUserUuid,
+		deviceUuid: testDeviceUuid,
+		family:     refreshFamily,
+		generation: generation,
+		secret:     secret,
+	})
+}
+
+func refreshRequest(token string) *http.Request {
+	return httptest.NewRequest("POST", "/users/refreshToken", strings.NewReader(`{"refreshToken":"`+token+`"}`))
+}
+
+func TestRefreshToken(t *testing.T) {
+	future := time.Now().Add(time.Hour).Unix()
+
+	t.Run("RefreshToken call with no token", func(t *testing.T) {
+		refreshTest(t, "secret-1", future)
+		request := httptest.NewRequest("POST", "/users/refreshToken", nil)
+		responseRecorder := httptest.NewRecorder()
+
+		RefreshToken(responseRecorder, request)
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
+		}
+	})
+	t.Run("The refresh token is rotated", func(t *testing.T) {
+		saved := refreshTest(t, "secret-1", future)
+		request := refreshRequest(testRefreshToken(refreshGeneration, "secret-1"))
+		responseRecorder := httptest.NewRecorder()
+
+		RefreshToken(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		var token TokenData
+		json.NewDecoder(responseRecorder.Body).Decode(&token)
+		rotated, err := parseRefreshToken(token.RefreshToken)
+		if err != nil || rotated.secret == "secret-1" {
+			t.Fatalf("Want a new refresh token, got '%s'", token.RefreshToken)
+		}
+		if rotated.family != refreshFamily || rotated.generation != refreshGeneration+1 {
+			t.Errorf("Want the next generation of the family, got '%s' %d", rotated.family, rotated.generation)
+		}
+		device := *saved
+		if device == nil || device.RefreshTokenHash != hashRefreshSecret(rotated.secret) || device.LastTokenId == "token-1" {
+			t.Errorf("Want the device to only accept the new tokens, got '%+v'", device)
+		}
+		if device != nil && (device.RefreshTokenFamily != refreshFamily || device.RefreshTokenGeneration != refreshGeneration+1) {
+			t.Errorf("Want the device on the next generation, got '%+v'", device)
+		}
+		if token.Token == "" || token.ExpiresIn != int64(accessTokenTTL().Seconds()) {
+			t.Errorf("Want a new access token, got '%+v'", token)
+		}
+	})
+	t.Run("The refresh token can be sent as a cookie", func(t *testing.T) {
+		refreshTest(t, "secret-1", future)
+		request := httptest.NewRequest("POST", "/users/refreshToken", nil)
+		request.AddCookie(&http.Cookie{Name: refreshTokenCookie, Value: testRefreshToken(refreshGeneration, "secret-1")})
+		responseRecorder := httptest.NewRecorder()
+
+		RefreshToken(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Errorf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+	})
+	t.Run("Reusing a refresh token signs the device out", func(t *testing.T) {
+		for _, generation := range []uint64{refreshGeneration - 1, 1} {
+			saved := refreshTest(t, "secret-1", future)
+			request := refreshRequest(testRefreshToken(generation, "secret-used"))
+			responseRecorder := httptest.NewRecorder()
+
+			RefreshToken(responseRecorder, request)
+			if responseRecorder.Code != http.StatusUnauthorized {
+				t.Fatalf("Want status '%d' for generation %d, got '%d'", http.StatusUnauthorized, generation, responseRecorder.Code)
+			}
+			device := *saved
+			if device == nil || device.RefreshTokenHash != "" || device.LastTokenId != "" || device.RefreshTokenFamily != "" {
+				t.Errorf("Want the device signed out for generation %d, got '%+v'", generation, device)
+			}
+		}
+	})
+	t.Run("A refresh that loses the race to another refresh signs the device out", func(t *testing.T) {
+		saved := refreshTest(t, "secret-1", future)
+		executeRotateDeviceRefreshToken = func(d *Device, expectedHash string) error {
+			return storage.ErrRefreshConflict
+		}
+		request := refreshRequest(testRefreshToken(refreshGeneration, "secret-1"))
+		responseRecorder := httptest.NewRecorder()
+
+		RefreshToken(responseRecorder, request)
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
+		}
+		device := *saved
+		if device == nil || device.RefreshTokenHash != "" || device.LastTokenId != "" {
+			t.Errorf("Want the device signed out, got '%+v'", device)
+		}
+	})
+	t.Run("A refresh token from another family leaves the device alone", func(t *testing.T) {
+		saved := refreshTest(t, "secret-1", future)
+		request := refreshRequest(formatRefreshToken(refreshToken{
+			userUuid:   refreshUserUuid,
+			deviceUuid: testDeviceUuid,
+			family:     friendOtherUuid,
+			generation: 1,
+			secret:     "secret-other",
+		}))
+		responseRecorder := httptest.NewRecorder()
+
+		RefreshToken(responseRecorder, request)
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
+		}
+		if *saved != nil {
+			t.Errorf("Want the device left alone, got '%+v'", *saved)
+		}
+	})
+	t.Run("A refresh token from before families still works", func(t *testing.T) {
+		saved := refreshTest(t, "secret-1", future)
+		request := refreshRequest(strings.Join([]string{refreshUserUuid, testDeviceUuid, "secret-1"}, "."))
+		responseRecorder := httptest.NewRecorder()
+
+		RefreshToken(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if *saved == nil || (*saved).RefreshTokenGeneration != refreshGeneration+1 {
+			t.Errorf("Want the device on the next generation, got '%+v'", *saved)
+		}
+	})
+	t.Run("A disabled user can't refresh", func(t *testing.T) {
+		saved := refreshTest(t, "secret-1", future)
+		executeSelectUser = func(m *User) error {
+			m.EmailAddress = "test@test.com"
+			m.Enabled = false
+			return nil
+		}
+		request := refreshRequest(testRefreshToken(refreshGeneration, "secret-1"))
+		responseRecorder := httptest.NewRecorder()
+
+		RefreshToken(responseRecorder, request)
+		if responseRecorder.Code != http.StatusForbidden {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusForbidden, responseRecorder.Code)
+		}
+		if *saved != nil {
+			t.Errorf("Want the device left alone, got '%+v'", *saved)
+		}
+	})
+	t.Run("An unknown refresh token leaves the device alone", func(t *testing.T) {
+		saved := refreshTest(t, "secret-1", future)
+		request := refreshRequest(testRefreshToken(refreshGeneration, "guessed"))
+		responseRecorder := httptest.NewRecorder()
+
+		RefreshToken(responseRecorder, request)
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
+		}
+		if *saved != nil {
+			t.Errorf("Want the device left alone, got '%+v'", *saved)
+		}
+	})
+	t.Run("Expired refresh token", func(t *testing.T) {
+		saved := refreshTest(t, "secret-1", time.Now().Add(-time.Hour).Unix())
+		request := refreshRequest(testRefreshToken(refreshGeneration, "secret-1"))
+		responseRecorder := httptest.NewRecorder()
+
+		RefreshToken(responseRecorder, request)
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
+		}
+		if *saved != nil {
+			t.Errorf("Want the device left alone, got '%+v'", *saved)
+		}
+	})
+	t.Run("Unknown device", func(t *testing.T) {
+		refreshTest(t, "secret-1", future)
+		request := refreshRequest(formatRefreshToken(refreshToken{
+			userUuid:   refreshUserUuid,
+			deviceUuid: friendOtherUuid,
+			family:     refreshFamily,
+			generation: refreshGeneration,
+			secret:     "secret-1",
+		}))
+		responseRecorder := httptest.NewRecorder()
+
+		RefreshToken(responseRecorder, request)
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
+		}
+	})
+}
@@ -0,0 +1,232 @@
+package userLogin
+
+import (
+	"encoding/json"
+	"errors"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"net/http"
+	"strconv"
+)
+
+// Permissions are what the routes and handlers check, users get them from
+// their roles
+const (
+	PermUsersRead      = "users:read"      // see every user
+	PermUsersWrite     = "users:write"     // change other users' accounts
+	PermUsersDelete    = "users:delete"    // delete other users' accounts
+	PermRolesManage    = "roles:manage"    // give users roles
+	PermLockoutsManage = "lockouts:manage" // see and clear sign in lockouts
+	PermLibraryRead    = "library:read"    // read anyone's playlists
+	PermLibraryWrite   = "library:write"   // change anyone's playlists and tracks
+	PermFriendsWrite   = "friends:write"   // make friends and share playlists with them
+	PermGroupsWrite    = "groups:write"    // start a household group
+)
+
+// Role is a named set of permissions. Users can have more than one role and
+// get the permissions of all of them.
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+var roles = []*Role{
+	{
+		Name:        storage.RoleAdmin,
+		Description: "Everything",
+		Permissions: []string{PermUsersRead, PermUsersWrite, PermUsersDelete, PermRolesManage,
+			PermLockoutsManage, PermLibraryRead, PermLibraryWrite, PermFriendsWrite, PermGroupsWrite},
+	},
+	{
+		Name:        "user-manager",
+		Description: "Look after other users' accounts",
+		Permissions: []string{PermUsersRead, PermUsersWrite, PermUsersDelete, PermLockoutsManage},
+	},
+	{
+		Name:        "auditor",
+		Description: "See every user and playlist without changing them",
+		Permissions: []string{PermUsersRead, PermLibraryRead},
+	},
+	{
+		Name:        storage.RoleMember,
+		Description: "Their own library, friends, sharing and household groups",
+		Permissions: []string{PermFriendsWrite, PermGroupsWrite},
+	},
+	{
+		Name:        childRole,
+		Description: "Their own library and what is shared with them, without making friends",
+		Permissions: []string{},
+	},
+}
+
+var errUnknownRole = errors.New("Unknown Role")
+var errNoRoles = errors.New("At least one role is required")
+var errLastAdmin = webhelper.NewError(webhelper.CodeLastAdmin, "The last admin account can not be removed")
+
+type RolesData struct {
+	Roles []string `json:"roles"`
+}
+
+func findRole(name string) *Role {
+	for _, role := range roles {
+		if role.Name == name {
+			return role
+		}
+	}
+	return nil
+}
+
+// permissionsFor is every permission the roles give, roles that no longer
+// exist give nothing
+func permissionsFor(roleNames []string) []string {
+	var permissions []string
+	seen := map[string]bool{}
+	for _, name := range roleNames {
+		role := findRole(name)
+		if role == nil {
+			continue
+		}
+		for _, permission := range role.Permissions {
+			if !seen[permission] {
+				seen[permission] = true
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	return permissions
+}
+
+// Can reports whether the signed in user has the permission
+func (c *Claims) Can(permission string) bool {
+	for _, granted := range c.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// adminPermissions are the permissions over other users and their libraries,
+// the ones canManage compares
+var adminPermissions = map[string]bool{
+	PermUsersRead:      true,
+	PermUsersWrite:     true,
+	PermUsersDelete:    true,
+	PermRolesManage:    true,
+	PermLockoutsManage: true,
+	PermLibraryRead:    true,
+	PermLibraryWrite:   true,
+}
+
+// canManage reports whether the signed in user has every admin permission the
+// roles give, so nobody can change an account that can do more than they can or
+// hand out more than they have. What a user can do with their own library,
+// like making friends, doesn't count.
+func canManage(claims *Claims, roleNames []string) bool {
+	for _, permission := range permissionsFor(roleNames) {
+		if adminPermissions[permission] && !claims.Can(permission) {
+			return false
+		}
+	}
+	return true
+}
+
+// lastAdmin reports whether the user is the only one left with the admin role
+func lastAdmin(user *User) (bool, error) {
+	if !user.HasRole(storage.RoleAdmin) {
+		return false, nil
+	}
+	admins, err := user.Find(storage.UserFilter{Role: storage.RoleAdmin})
+	if err != nil {
+		return false, err
+	}
+	return len(admins) <= 1, nil
+}
+
+// ListRoles returns every role and the permissions it gives
+func ListRoles(w http.ResponseWriter, r *http.Request) {
+	_, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+	json.NewEncoder(w).Encode(roles)
+}
+
+// SetUserRoles replaces the roles of the user in /users/{id}
+func SetUserRoles(w http.ResponseWriter, r *http.Request) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+
+	var data RolesData
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	var newRoles []string
+	for _, name := range data.Roles {
+		if findRole(name) == nil {
+			webhelper.ReturnError(w, r, errors.New(errUnknownRole.Error()+": "+name), &[]int{http.StatusBadRequest}[0])
+			return
+		}
+		if !contains(newRoles, name) {
+			newRoles = append(newRoles, name)
+		}
+	}
+	if len(newRoles) == 0 {
+		webhelper.ReturnError(w, r, errNoRoles, &[]int{http.StatusBadRequest}[0])
+		return
+	}
+	id, err := strconv.ParseUint(webhelper.Param(r, "id"), 10, 64)
+	if err != nil {
+		webhelper.ReturnError(w, r, errors.New("User Account is invalid"), &[]int{http.StatusBadRequest}[0])
+		return
+	}
+
+	var user User
+	user.Id = id
+	err = user.Select()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusNotFound}[0]) {
+		return
+	}
+	if !canManage(claims, user.Roles) || !canManage(claims, newRoles) {
+		webhelper.ReturnError(w, r, webhelper.ErrAccessDenied, &[]int{http.StatusForbidden}[0])
+		return
+	}
+	if !contains(newRoles, storage.RoleAdmin) {
+		last, err := lastAdmin(&user)
+		if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+			return
+		}
+		if last {
+			webhelper.ReturnError(w, r, errLastAdmin, &[]int{http.StatusBadRequest}[0])
+			return
+		}
+	}
+
+	user.Roles = newRoles
+	err = user.Update()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	var userData UserData
+	userData.Id = user.Id
+	userData.FirstName = user.FirstName
+	userData.LastName = user.LastName
+	userData.EmailAddress = user.EmailAddress
+	userData.Roles = user.Roles
+	json.NewEncoder(w).Encode(userData)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package userLogin
+
+import (
+	"encoding/json"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPermissionsFor(t *testing.T) {
+	t.Run("Roles give the permissions of all of them", func(t *testing.T) {
+		permissions := permissionsFor([]string{storage.RoleMember, "auditor", "no-longer-a-role"})
+		claims := &Claims{Permissions: permissions}
+		if len(permissions) != 4 || !claims.Can(PermFriendsWrite) || !claims.Can(PermLibraryRead) || !claims.Can(PermUsersRead) {
+			t.Errorf("Want the member and auditor permissions, got '%v'", permissions)
+		}
+	})
+	t.Run("Family members can't make friends", func(t *testing.T) {
+		claims := &Claims{Permissions: permissionsFor([]string{"family"})}
+		if claims.Can(PermFriendsWrite) {
+			t.Error("Want no friends:write")
+		}
+	})
+}
+
+// rolesTest stubs out user 2 with the roles, signed in with the roles of
+// signedIn, and as many admins as admins. It returns the user as it was last
+// saved.
+func rolesTest(t *testing.T, signedIn []string, roles []string, admins int) **User {
+	restoreStubs(t)
+	requestClaimsVar = func(r *http.Request) (*Claims, int) {
+		return &Claims{Username: "signedin@test.com", Permissions: permissionsFor(signedIn)}, http.StatusOK
+	}
+	executeSelectUser = func(m *User) error {
+		m.Id = 2
+		m.EmailAddress = "test@test.com"
+		m.Roles = roles
+		return nil
+	}
+	executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
+		var users []*User
+		for i := 0; i < admins; i++ {
+			users = append(users, &User{})
+		}
+		return users, nil
+	}
+	var saved *User
+	executeUpdateUser = func(m *User) error {
+		saved = m
+		return nil
+	}
+	return &saved
+}
+
+func rolesRequest(roles string) *http.Request {
+	request := httptest.NewRequest("PUT", "/users/2/roles", strings.NewReader(`{"roles":`+roles+`}`))
+	return webhelper.WithParams(request, webhelper.Params{"id": "2"})
+}
+
+func TestSetUserRoles(t *testing.T) {
+	tests := []struct {
+		name     string
+		signedIn []string
+		roles    []string
+		admins   int
+		body     string
+		want     int
+	}{
+		{"An admin makes a member an auditor", []string{storage.RoleAdmin}, []string{storage.RoleMember}, 1, `["member","auditor","member"]`, http.StatusOK},
+		{"A role that doesn't exist", []string{storage.RoleAdmin}, []string{storage.RoleMember}, 1, `["owner"]`, http.StatusBadRequest},
+		{"No roles", []string{storage.RoleAdmin}, []string{storage.RoleMember}, 1, `[]`, http.StatusBadRequest},
+		{"The last admin", []string{storage.RoleAdmin}, []string{storage.RoleAdmin}, 1, `["member"]`, http.StatusBadRequest},
+		{"One of two admins", []string{storage.RoleAdmin}, []string{storage.RoleAdmin}, 2, `["member"]`, http.StatusOK},
+		{"Handing out more than the signed in user has", []string{"user-manager"}, []string{storage.RoleMember}, 1, `["admin"]`, http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			saved := rolesTest(t, test.signedIn, test.roles, test.admins)
+			responseRecorder := httptest.NewRecorder()
+
+			SetUserRoles(responseRecorder, rolesRequest(test.body))
+			if responseRecorder.Code != test.want {
+				t.Fatalf("Want status '%d', got '%d'", test.want, responseRecorder.Code)
+			}
+			if test.want != http.StatusOK {
+				if *saved != nil {
+					t.Error("Want the roles left alone")
+				}
+				return
+			}
+			var userData UserData
+			json.NewDecoder(responseRecorder.Body).Decode(&userData)
+			if *saved == nil || strings.Join((*saved).Roles, ",") != strings.Join(userData.Roles, ",") {
+				t.Errorf("Want the roles saved, got '%v'", userData.Roles)
+			}
+		})
+	}
+}
+
+func TestUpdateUserLoginRoles(t *testing.T) {
+	t.Run("A user manager can't change an admin's account", func(t *testing.T) {
+		saved := rolesTest(t, []string{"user-manager"}, []string{storage.RoleAdmin}, 1)
+		request := httptest.NewRequest("PATCH", "/users/2", strings.NewReader(`{"firstName":"Changed"}`))
+		request = webhelper.WithParams(request, webhelper.Params{"id": "2"})
+		responseRecorder := httptest.NewRecorder()
+
+		UpdateUserLogin(responseRecorder, request)
+		if responseRecorder.Code != http.StatusForbidden || *saved != nil {
+			t.Errorf("Want status '%d' without saving, got '%d'", http.StatusForbidden, responseRecorder.Code)
+		}
+	})
+	t.Run("A user manager can change a member's account", func(t *testing.T) {
+		saved := rolesTest(t, []string{"user-manager"}, []string{storage.RoleMember}, 1)
+		request := httptest.NewRequest("PATCH", "/users/2", strings.NewReader(`{"firstName":"Changed"}`))
+		request = webhelper.WithParams(request, webhelper.Params{"id": "2"})
+		responseRecorder := httptest.NewRecorder()
+
+		UpdateUserLogin(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK || *saved == nil || (*saved).FirstName != "Changed" {
+			t.Errorf("Want status '%d' with the change saved, got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+	})
+}
+
+func TestDeleteUserLoginRoles(t *testing.T) {
+	tests := []struct {
+		name  string
+		roles []string
+		want  int
+	}{
+		{"A user manager can delete a member", []string{storage.RoleMember}, http.StatusOK},
+		{"A user manager can't delete an auditor", []string{"auditor"}, http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rolesTest(t, []string{"user-manager"}, test.roles, 1)
+			deleted := false
+			executeDeleteUser = func(m *User) error {
+				deleted = true
+				return nil
+			}
+			request := httptest.NewRequest("DELETE", "/users/2", nil)
+			request = webhelper.WithParams(request, webhelper.Params{"id": "2"})
+			responseRecorder := httptest.NewRecorder()
+
+			DeleteUserLogin(responseRecorder, request)
+			if responseRecorder.Code != test.want || deleted != (test.want == http.StatusOK) {
+				t.Errorf("Want status '%d', got '%d' deleted %t", test.want, responseRecorder.Code, deleted)
+			}
+		})
+	}
+}
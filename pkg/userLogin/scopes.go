@@ -0,0 +1,57 @@
+package userLogin
+
+import (
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"net/http"
+)
+
+// Scopes limit what a token or API key can do, every route a limited one can
+// use requires one of them. Tokens signed in without asking for scopes aren't
+// limited.
+const (
+	ScopePlaylistRead  = "playlist:read"  // load playlists, tracks and history, and follow live updates
+	ScopePlaylistWrite = "playlist:write" // change playlists and tracks
+	ScopePositionWrite = "position:write" // save resume positions and plays, and take the playlist lock
+	ScopeUserAdmin     = "user:admin"     // look after the account, and other users' with the permissions
+)
+
+var scopes = []string{ScopePlaylistRead, ScopePlaylistWrite, ScopePositionWrite, ScopeUserAdmin}
+
+var errUnknownScope = webhelper.NewError(webhelper.CodeUnknownScope, "Unknown Scope")
+var errScopeNotHeld = webhelper.NewError(webhelper.CodeScopeNotHeld, "Can't be given scopes the signed in token doesn't have")
+
+// HasScope reports whether the request can do what the scope allows, which
+// is anything when the token isn't limited to scopes
+func (c *Claims) HasScope(scope string) bool {
+	return c.Scopes == nil || contains(c.Scopes, scope)
+}
+
+// checkScopes checks the requested scopes exist, and that they are all in
+// held unless held is nil, so a limited token can only hand out less
+func checkScopes(requested []string, held []string) error {
+	for _, scope := range requested {
+		if !contains(scopes, scope) {
+			return errUnknownScope
+		}
+		if held != nil && !contains(held, scope) {
+			return errScopeNotHeld
+		}
+	}
+	return nil
+}
+
+func scopeErrorStatus(err error) *int {
+	if err == errScopeNotHeld {
+		return &[]int{http.StatusForbidden}[0]
+	}
+	return &[]int{http.StatusBadRequest}[0]
+}
+
+// requestedScopes is nil when no scopes were asked for, which leaves the
+// token unlimited
+func requestedScopes(requested []string) []string {
+	if len(requested) == 0 {
+		return nil
+	}
+	return append([]string{}, requested...)
+}
@@ -0,0 +1,146 @@
+package userLogin
+
+import (
+	"encoding/json"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// tokenScopes reads the scopes claim back out of a signed token
+func tokenScopes(t *testing.T, token string) []string {
+	keys, err := currentKeys()
+	if err != nil {
+		t.Fatalf("Want no error, got '%s'", err.Error())
+	}
+	claims := &Claims{}
+	if _, err := jwt.ParseWithClaims(token, claims, keys.keyFunc); err != nil {
+		t.Fatalf("Want a valid token, got '%s'", err.Error())
+	}
+	return claims.Scopes
+}
+
+func TestSigninScopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes string
+		want   int
+	}{
+		{"A full sign in", ``, http.StatusAccepted},
+		{"Limited to saving positions", `,"scopes":["position:write"]`, http.StatusAccepted},
+		{"Unknown scope", `,"scopes":["everything"]`, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			totpTest(t, false)
+			var device *Device
+			register := registerDeviceVar
+			registerDeviceVar = func(emailAddress string, creds *Credentials, authType string, s *session) (*Device, error) {
+				device, _ = register(emailAddress, creds, authType, s)
+				return device, nil
+			}
+			request := httptest.NewRequest("POST", "/users/signin", strings.NewReader(`{"username":"test@test.com","password":"pw"`+test.scopes+`}`))
+			responseRecorder := httptest.NewRecorder()
+
+			Signin(responseRecorder, request)
+			if responseRecorder.Code != test.want {
+				t.Fatalf("Want status '%d', got '%d'", test.want, responseRecorder.Code)
+			}
+			if test.want != http.StatusAccepted {
+				return
+			}
+			var signin SigninData
+			json.NewDecoder(responseRecorder.Body).Decode(&signin)
+			scopes := tokenScopes(t, signin.Token)
+			if test.scopes == "" {
+				if scopes != nil || signin.Scopes != nil || device.Scopes != nil {
+					t.Errorf("Want an unlimited token, got '%v'", scopes)
+				}
+				return
+			}
+			if len(scopes) != 1 || scopes[0] != ScopePositionWrite || len(signin.Scopes) != 1 {
+				t.Errorf("Want the token limited to '%s', got '%v'", ScopePositionWrite, scopes)
+			}
+			if len(device.Scopes) != 1 || device.Scopes[0] != ScopePositionWrite {
+				t.Errorf("Want the scopes kept on the device, got '%v'", device.Scopes)
+			}
+		})
+	}
+	t.Run("Two factor sign in keeps the scopes", func(t *testing.T) {
+		totpTest(t, true)
+		request := httptest.NewRequest("POST", "/users/signin", strings.NewReader(`{"username":"test@test.com","password":"pw","scopes":["position:write"]}`))
+		responseRecorder := httptest.NewRecorder()
+		Signin(responseRecorder, request)
+		var challenge TotpChallengeData
+		json.NewDecoder(responseRecorder.Body).Decode(&challenge)
+
+		responseRecorder = httptest.NewRecorder()
+		SigninTotp(responseRecorder, totpSigninRequest(challenge.TotpToken, currentTotpCode()))
+		if responseRecorder.Code != http.StatusAccepted {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusAccepted, responseRecorder.Code)
+		}
+		var signin SigninData
+		json.NewDecoder(responseRecorder.Body).Decode(&signin)
+		if scopes := tokenScopes(t, signin.Token); len(scopes) != 1 || scopes[0] != ScopePositionWrite {
+			t.Errorf("Want the token limited to '%s', got '%v'", ScopePositionWrite, scopes)
+		}
+	})
+}
+
+func TestRefreshTokenScopes(t *testing.T) {
+	future := time.Now().Add(time.Hour).Unix()
+	tests := []struct {
+		name      string
+		held      []string
+		requested string
+		want      int
+		wantHeld  []string
+	}{
+		{"The device keeps its scopes", []string{ScopePositionWrite, ScopePlaylistRead}, ``, http.StatusOK, []string{ScopePositionWrite, ScopePlaylistRead}},
+		{"Asking for fewer narrows them", []string{ScopePositionWrite, ScopePlaylistRead}, `,"scopes":["position:write"]`, http.StatusOK, []string{ScopePositionWrite}},
+		{"A limited device can't widen them", []string{ScopePositionWrite}, `,"scopes":["user:admin"]`, http.StatusForbidden, nil},
+		{"An unlimited device can be limited", nil, `,"scopes":["playlist:read"]`, http.StatusOK, []string{ScopePlaylistRead}},
+		{"An unlimited device stays unlimited", nil, ``, http.StatusOK, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			saved := refreshTest(t, "secret-1", future)
+			findDevices := executeFindDevices
+			executeFindDevices = func(filter storage.DeviceFilter) ([]*storage.Device, error) {
+				devices, err := findDevices(filter)
+				for _, d := range devices {
+					d.Scopes = test.held
+				}
+				return devices, err
+			}
+			token := testRefreshToken(refreshGeneration, "secret-1")
+			request := httptest.NewRequest("POST", "/users/refreshToken", strings.NewReader(`{"refreshToken":"`+token+`"`+test.requested+`}`))
+			responseRecorder := httptest.NewRecorder()
+
+			RefreshToken(responseRecorder, request)
+			if responseRecorder.Code != test.want {
+				t.Fatalf("Want status '%d', got '%d'", test.want, responseRecorder.Code)
+			}
+			if test.want != http.StatusOK {
+				if *saved != nil {
+					t.Errorf("Want the device left alone, got '%+v'", *saved)
+				}
+				return
+			}
+			var tokenData TokenData
+			json.NewDecoder(responseRecorder.Body).Decode(&tokenData)
+			scopes := tokenScopes(t, tokenData.Token)
+			if strings.Join(scopes, " ") != strings.Join(test.wantHeld, " ") || (scopes == nil) != (test.wantHeld == nil) {
+				t.Errorf("Want token scopes '%v', got '%v'", test.wantHeld, scopes)
+			}
+			if strings.Join((*saved).Scopes, " ") != strings.Join(test.wantHeld, " ") {
+				t.Errorf("Want device scopes '%v', got '%v'", test.wantHeld, (*saved).Scopes)
+			}
+		})
+	}
+}
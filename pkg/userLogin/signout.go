@@ -0,0 +1,138 @@
+package userLogin
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"net/http"
+	"time"
+)
+
+var executeRevokeToken = func(t *storage.RevokedToken) error {
+	return t.Insert()
+}
+
+var isTokenRevokedVar = storage.IsTokenRevoked
+
+var errApiKeySignout = errors.New("An API key can't sign out, revoke it instead")
+
+// clearCookie tells the browser to forget the cookie
+func clearCookie(w http.ResponseWriter, name string, path string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:    name,
+		Value:   "",
+		Path:    path,
+		Expires: time.Unix(0, 0),
+		MaxAge:  -1,
+	})
+}
+
+// Signout revokes the token the request was made with and signs its device
+// out, so neither the token nor the device's refresh token work again
+func Signout(w http.ResponseWriter, r *http.Request) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+	if claims.Device == "" {
+		webhelper.ReturnError(w, r, errApiKeySignout, &[]int{http.StatusBadRequest}[0])
+		return
+	}
+
+	// The token is only kept in the denylist until it would have expired anyway
+	err := executeRevokeToken(&storage.RevokedToken{TokenId: claims.Id, ExpiresAt: claims.ExpiresAt})
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	device, err := findUserDevice(claims.Username, claims.Device)
+	if err != nil && err != storage.ErrDeviceNotFound {
+		webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0])
+		return
+	}
+	if err == nil {
+		signOutDevice(&device.Device)
+		err = device.Update()
+		if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+			return
+		}
+		err = releaseDeviceLocksVar(device.Uuid)
+		if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+			return
+		}
+	}
+	clearCookie(w, "token", "/")
+	clearCookie(w, refreshTokenCookie, "/users/refreshToken")
+
+	var responseDetails webhelper.Response
+	responseDetails.Message = "Signed Out"
+	json.NewEncoder(w).Encode(responseDetails)
+}
+
+// SignoutEverywhere signs every one of the user's devices out, including the
+// one making the request, and revokes their API keys. The devices are kept,
+// their next sign in reuses them.
+func SignoutEverywhere(w http.ResponseWriter, r *http.Request) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return
+	}
+
+	err := signOutEverywhere(claims.Username)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+
+	var responseDetails webhelper.Response
+	responseDetails.Message = "Signed Out Everywhere"
+	json.NewEncoder(w).Encode(responseDetails)
+}
+
+// signOutEverywhere signs out every one of the user's devices, releases their
+// playlist locks and revokes the user's API keys
+func signOutEverywhere(emailAddress string) error {
+	var user User
+	user.EmailAddress = emailAddress
+	if err := user.Select(); err != nil {
+		return err
+	}
+	keys, err := executeFindApiKeys(storage.ApiKeyFilter{UserUuid: user.Uuid})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := executeDeleteApiKey(key); err != nil {
+			return err
+		}
+	}
+
+	var device Device
+	devices, err := device.Find(storage.DeviceFilter{OwnerEmail: emailAddress})
+	if err != nil {
+		return err
+	}
+	for _, d := range devices {
+		signedOut := Device{Device: *d}
+		signOutDevice(&signedOut.Device)
+		if err := signedOut.Update(); err != nil {
+			return err
+		}
+		if err := releaseDeviceLocksVar(d.Uuid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneRevokedTokens forgets revoked tokens once they have expired, every
+// interval until the server stops
+func PruneRevokedTokens(interval time.Duration) {
+	for range time.Tick(interval) {
+		if _, err := storage.PruneRevokedTokens(time.Now().Unix()); err != nil {
+			log.Printf("pruning revoked tokens: %v", err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package userLogin
+
+import (
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSignout(t *testing.T) {
+	defer func() {
+		releaseDeviceLocksVar = releaseDeviceLocks
+	}()
+	requestClaimsVar = deviceClaims
+	executeFindDevices = findTestDevice
+	var revoked *storage.RevokedToken
+	executeRevokeToken = func(token *storage.RevokedToken) error {
+		revoked = token
+		return nil
+	}
+	var saved *Device
+	executeUpdateDevice = func(d *Device) error {
+		saved = d
+		return nil
+	}
+	var released string
+	releaseDeviceLocksVar = func(deviceUuid string) error {
+		released = deviceUuid
+		return nil
+	}
+	request := httptest.NewRequest("POST", "/users/signout", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	Signout(responseRecorder, request)
+	if responseRecorder.Code != http.StatusOK {
+		t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+	}
+	claims, _ := deviceClaims(request)
+	if revoked == nil || revoked.TokenId != "token-1" || revoked.ExpiresAt != claims.ExpiresAt {
+		t.Errorf("Want token-1 revoked until it expires, got '%+v'", revoked)
+	}
+	if saved == nil || saved.LastTokenId != "" || saved.RefreshTokenHash != "" || released != testDeviceUuid {
+		t.Errorf("Want the device signed out and its locks released, got '%+v'", saved)
+	}
+	for _, cookie := range responseRecorder.Result().Cookies() {
+		if cookie.Value != "" || cookie.MaxAge >= 0 {
+			t.Errorf("Want cookie '%s' cleared, got '%+v'", cookie.Name, cookie)
+		}
+	}
+}
+
+func TestSignoutApiKey(t *testing.T) {
+	requestClaimsVar = func(r *http.Request) (*Claims, int) {
+		return &Claims{Username: "test@test.com", Scopes: []string{ScopePositionWrite}}, http.StatusOK
+	}
+	executeRevokeToken = func(token *storage.RevokedToken) error {
+		t.Error("Nothing should be revoked")
+		return nil
+	}
+	request := httptest.NewRequest("POST", "/users/signout", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	Signout(responseRecorder, request)
+	if responseRecorder.Code != http.StatusBadRequest {
+		t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
+	}
+}
+
+func TestSignoutEverywhere(t *testing.T) {
+	apiKeyTest(t, []string{ScopePositionWrite}, 0)
+	var revoked []string
+	executeDeleteApiKey = func(k *storage.ApiKey) error {
+		revoked = append(revoked, k.Uuid)
+		return nil
+	}
+	requestClaimsVar = deviceClaims
+	executeFindDevices = func(filter storage.DeviceFilter) ([]*storage.Device, error) {
+		return []*storage.Device{
+			{Id: 1, Uuid: testDeviceUuid, LastTokenId: "token-1", RefreshTokenHash: "hash-1"},
+			{Id: 2, Uuid: friendOtherUuid, LastTokenId: "token-2", RefreshTokenHash: "hash-2"},
+		}, nil
+	}
+	var saved []*Device
+	executeUpdateDevice = func(d *Device) error {
+		saved = append(saved, d)
+		return nil
+	}
+	var released []string
+	releaseDeviceLocksVar = func(deviceUuid string) error {
+		released = append(released, deviceUuid)
+		return nil
+	}
+	request := httptest.NewRequest("POST", "/users/signoutEverywhere", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	SignoutEverywhere(responseRecorder, request)
+	if responseRecorder.Code != http.StatusOK {
+		t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+	}
+	if len(saved) != 2 || len(released) != 2 {
+		t.Fatalf("Want both devices signed out, got %d saved and %d released", len(saved), len(released))
+	}
+	for _, d := range saved {
+		if d.LastTokenId != "" || d.RefreshTokenHash != "" {
+			t.Errorf("Want device '%s' signed out, got '%+v'", d.Uuid, d)
+		}
+	}
+	if len(revoked) != 1 || revoked[0] != testApiKeyUuid {
+		t.Errorf("Want the API key revoked, got '%v'", revoked)
+	}
+}
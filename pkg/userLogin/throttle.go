@@ -0,0 +1,238 @@
+package userLogin
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultLoginWindow = 60
+const defaultLoginIpLimit = 20
+const defaultLoginEmailLimit = 10
+const defaultLoginMaxFailures = 5
+const defaultLoginLockout = 15 * 60
+
+var errTooManySignins = webhelper.NewError(webhelper.CodeTooManySignins, "Too many sign in attempts, try again later")
+var errAccountLocked = webhelper.NewError(webhelper.CodeAccountLocked, "Too many failed sign ins, the account is locked for now")
+var errLockoutNotFound = errors.New("Account is not locked")
+
+// LockoutData is an account locked after too many failed sign ins
+type LockoutData struct {
+	EmailAddress string `json:"emailAddress"`
+	Failures     int    `json:"failures"`
+	LockedUntil  int64  `json:"lockedUntil"` // unix seconds
+}
+
+// loginAttempts counts the sign ins in the current window, and for an email
+// address the failures in a row
+type loginAttempts struct {
+	windowStart time.Time
+	count       int
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// loginLimiter throttles sign ins per client IP and per email address, and
+// locks an account after too many failures in a row. Like the event streams
+// it is kept in memory, so a restart forgets it.
+type loginLimiter struct {
+	mutex     sync.Mutex
+	ips       map[string]*loginAttempts
+	emails    map[string]*loginAttempts
+	lastPrune time.Time
+}
+
+var loginLimits = newLoginLimiter()
+
+func newLoginLimiter() *loginLimiter {
+	return &loginLimiter{
+		ips:    map[string]*loginAttempts{},
+		emails: map[string]*loginAttempts{},
+	}
+}
+
+// intFromEnv reads a positive whole number from the environment, or the
+// default when it isn't set
+func intFromEnv(name string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// loginWindow is how long LOGIN_IP_LIMIT and LOGIN_EMAIL_LIMIT count sign ins for
+func loginWindow() time.Duration {
+	return ttlFromEnv("LOGIN_WINDOW", defaultLoginWindow)
+}
+
+// loginLockout is how long an account is locked after LOGIN_MAX_FAILURES
+// failures in a row
+func loginLockout() time.Duration {
+	return ttlFromEnv("LOGIN_LOCKOUT", defaultLoginLockout)
+}
+
+// clientIp is the address the request came from, without the port
+func clientIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (l *loginLimiter) entry(entries map[string]*loginAttempts, key string, now time.Time) *loginAttempts {
+	attempts, ok := entries[key]
+	if !ok {
+		attempts = &loginAttempts{windowStart: now}
+		entries[key] = attempts
+	}
+	if now.Sub(attempts.windowStart) >= loginWindow() {
+		attempts.windowStart = now
+		attempts.count = 0
+	}
+	return attempts
+}
+
+// prune forgets entries that no longer limit anything, at most once a window
+func (l *loginLimiter) prune(now time.Time) {
+	window := loginWindow()
+	if now.Sub(l.lastPrune) < window {
+		return
+	}
+	l.lastPrune = now
+	for _, entries := range []map[string]*loginAttempts{l.ips, l.emails} {
+		for key, attempts := range entries {
+			if now.Sub(attempts.windowStart) >= window &&
+				!now.Before(attempts.lockedUntil) &&
+				now.Sub(attempts.lastFailure) >= loginLockout() {
+				delete(entries, key)
+			}
+		}
+	}
+}
+
+// allow records a sign in attempt, returning how long the client has to wait
+// when it is over a limit (and the reason), or 0
+func (l *loginLimiter) allow(ip string, email string, now time.Time) (time.Duration, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.prune(now)
+
+	account := l.entry(l.emails, strings.ToLower(email), now)
+	if now.Before(account.lockedUntil) {
+		return account.lockedUntil.Sub(now), errAccountLocked
+	}
+	client := l.entry(l.ips, ip, now)
+	client.count++
+	account.count++
+	if client.count > intFromEnv("LOGIN_IP_LIMIT", defaultLoginIpLimit) {
+		return client.windowStart.Add(loginWindow()).Sub(now), errTooManySignins
+	}
+	if account.count > intFromEnv("LOGIN_EMAIL_LIMIT", defaultLoginEmailLimit) {
+		return account.windowStart.Add(loginWindow()).Sub(now), errTooManySignins
+	}
+	return 0, nil
+}
+
+// failed records a wrong password, locking the account after too many in a
+// row. Failures are forgotten once there have been none for LOGIN_LOCKOUT.
+func (l *loginLimiter) failed(email string, now time.Time) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	account := l.entry(l.emails, strings.ToLower(email), now)
+	lockout := loginLockout()
+	if now.Sub(account.lastFailure) >= lockout {
+		account.failures = 0
+	}
+	account.failures++
+	account.lastFailure = now
+	if account.failures >= intFromEnv("LOGIN_MAX_FAILURES", defaultLoginMaxFailures) {
+		account.lockedUntil = now.Add(lockout)
+	}
+}
+
+// succeeded forgets the failures for the account
+func (l *loginLimiter) succeeded(email string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if account, ok := l.emails[strings.ToLower(email)]; ok {
+		account.failures = 0
+		account.lockedUntil = time.Time{}
+	}
+}
+
+// lockouts lists the accounts locked at the time, soonest unlocked first
+func (l *loginLimiter) lockouts(now time.Time) []*LockoutData {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	lockouts := []*LockoutData{}
+	for email, account := range l.emails {
+		if now.Before(account.lockedUntil) {
+			lockouts = append(lockouts, &LockoutData{
+				EmailAddress: email,
+				Failures:     account.failures,
+				LockedUntil:  account.lockedUntil.Unix(),
+			})
+		}
+	}
+	sort.Slice(lockouts, func(i, j int) bool {
+		if lockouts[i].LockedUntil != lockouts[j].LockedUntil {
+			return lockouts[i].LockedUntil < lockouts[j].LockedUntil
+		}
+		return lockouts[i].EmailAddress < lockouts[j].EmailAddress
+	})
+	return lockouts
+}
+
+// unlock lifts the lockout on the account, reporting whether it was locked
+func (l *loginLimiter) unlock(email string, now time.Time) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	account, ok := l.emails[strings.ToLower(email)]
+	if !ok || !now.Before(account.lockedUntil) {
+		return false
+	}
+	account.failures = 0
+	account.lockedUntil = time.Time{}
+	return true
+}
+
+// tooManyRequests turns the client away for the wait, in whole seconds
+func tooManyRequests(w http.ResponseWriter, r *http.Request, wait time.Duration, err error) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	webhelper.ReturnError(w, r, err, &[]int{http.StatusTooManyRequests}[0])
+}
+
+// ListLockouts lists the accounts locked after too many failed sign ins, for users
+// with lockouts:manage
+func ListLockouts(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(loginLimits.lockouts(time.Now()))
+}
+
+// DeleteLockout unlocks an account before its lockout runs out, for users with
+// lockouts:manage
+func DeleteLockout(w http.ResponseWriter, r *http.Request) {
+	if !loginLimits.unlock(webhelper.Param(r, "email"), time.Now()) {
+		webhelper.ReturnError(w, r, errLockoutNotFound, &[]int{http.StatusNotFound}[0])
+		return
+	}
+
+	var responseDetails webhelper.Response
+	responseDetails.Message = "Account Unlocked"
+	json.NewEncoder(w).Encode(responseDetails)
+}
@@ -0,0 +1,161 @@
+package userLogin
+
+import (
+	"encoding/json"
+	"errors"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoginLimiter(t *testing.T) {
+	now := time.Unix(1000000, 0)
+
+	t.Run("Sign ins per IP", func(t *testing.T) {
+		t.Setenv("LOGIN_IP_LIMIT", "2")
+		limiter := newLoginLimiter()
+		limiter.allow("192.0.2.1", "a@test.com", now)
+		limiter.allow("192.0.2.1", "b@test.com", now)
+		wait, err := limiter.allow("192.0.2.1", "c@test.com", now.Add(10*time.Second))
+		if err != errTooManySignins || wait != 50*time.Second {
+			t.Errorf("Want to wait 50s, got '%s' '%v'", wait, err)
+		}
+		if _, err := limiter.allow("192.0.2.2", "c@test.com", now); err != nil {
+			t.Errorf("Want another IP allowed, got '%v'", err)
+		}
+		if _, err := limiter.allow("192.0.2.1", "c@test.com", now.Add(time.Minute)); err != nil {
+			t.Errorf("Want the IP allowed in the next window, got '%v'", err)
+		}
+	})
+	t.Run("Sign ins per email address", func(t *testing.T) {
+		t.Setenv("LOGIN_EMAIL_LIMIT", "2")
+		limiter := newLoginLimiter()
+		limiter.allow("192.0.2.1", "a@test.com", now)
+		limiter.allow("192.0.2.2", "A@test.com", now)
+		if _, err := limiter.allow("192.0.2.3", "a@test.com", now); err != errTooManySignins {
+			t.Errorf("Want error '%v', got '%v'", errTooManySignins, err)
+		}
+	})
+	t.Run("Failures in a row lock the account", func(t *testing.T) {
+		limiter := newLoginLimiter()
+		for i := 0; i < defaultLoginMaxFailures; i++ {
+			if _, err := limiter.allow("192.0.2.1", "a@test.com", now); err != nil {
+				t.Fatalf("Want attempt %d allowed, got '%v'", i+1, err)
+			}
+			limiter.failed("a@test.com", now)
+		}
+		wait, err := limiter.allow("192.0.2.1", "a@test.com", now.Add(time.Minute))
+		if err != errAccountLocked || wait != loginLockout()-time.Minute {
+			t.Errorf("Want the account locked for another %s, got '%s' '%v'", loginLockout()-time.Minute, wait, err)
+		}
+		lockouts := limiter.lockouts(now)
+		if len(lockouts) != 1 || lockouts[0].EmailAddress != "a@test.com" || lockouts[0].Failures != defaultLoginMaxFailures {
+			t.Errorf("Want a@test.com reported, got '%+v'", lockouts)
+		}
+		if _, err := limiter.allow("192.0.2.1", "a@test.com", now.Add(loginLockout())); err != nil {
+			t.Errorf("Want the lockout over, got '%v'", err)
+		}
+	})
+	t.Run("A successful sign in forgets the failures", func(t *testing.T) {
+		limiter := newLoginLimiter()
+		for i := 0; i < defaultLoginMaxFailures-1; i++ {
+			limiter.failed("a@test.com", now)
+		}
+		limiter.succeeded("a@test.com")
+		limiter.failed("a@test.com", now)
+		if _, err := limiter.allow("192.0.2.1", "a@test.com", now); err != nil {
+			t.Errorf("Want the account not locked, got '%v'", err)
+		}
+	})
+	t.Run("Unlock an account", func(t *testing.T) {
+		limiter := newLoginLimiter()
+		for i := 0; i < defaultLoginMaxFailures; i++ {
+			limiter.failed("a@test.com", now)
+		}
+		if !limiter.unlock("A@test.com", now) || limiter.unlock("a@test.com", now) {
+			t.Errorf("Want a@test.com unlocked once")
+		}
+		if _, err := limiter.allow("192.0.2.1", "a@test.com", now); err != nil {
+			t.Errorf("Want the account unlocked, got '%v'", err)
+		}
+	})
+}
+
+func TestSigninLockout(t *testing.T) {
+	defer func() { loginLimits = newLoginLimiter() }()
+	bcryptCompareHashAndPassword = func(hashedPassword []byte, password []byte) error {
+		return errors.New("Password does not match stored hash")
+	}
+	signinUntilLocked := func() *httptest.ResponseRecorder {
+		var responseRecorder *httptest.ResponseRecorder
+		for i := 0; i <= defaultLoginMaxFailures; i++ {
+			var data = `{"username":"test@test.com","password":"blahblahblah"}`
+			request := httptest.NewRequest("POST", "/users/signin", strings.NewReader(data))
+			responseRecorder = httptest.NewRecorder()
+			Signin(responseRecorder, request)
+		}
+		return responseRecorder
+	}
+
+	t.Run("Wrong passwords lock the account", func(t *testing.T) {
+		loginLimits = newLoginLimiter()
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
+			user := User{}
+			user.EmailAddress = "test@test.com"
+			return []*User{&user}, nil
+		}
+
+		responseRecorder := signinUntilLocked()
+		if responseRecorder.Code != http.StatusTooManyRequests {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusTooManyRequests, responseRecorder.Code)
+		}
+		if retryAfter := responseRecorder.Header().Get("Retry-After"); retryAfter != "900" {
+			t.Errorf("Want Retry-After '%s', got '%s'", "900", retryAfter)
+		}
+	})
+	t.Run("Unknown email addresses are locked the same way", func(t *testing.T) {
+		loginLimits = newLoginLimiter()
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
+			return nil, nil
+		}
+
+		responseRecorder := signinUntilLocked()
+		if responseRecorder.Code != http.StatusTooManyRequests {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusTooManyRequests, responseRecorder.Code)
+		}
+	})
+}
+
+func TestLockouts(t *testing.T) {
+	defer func() { loginLimits = newLoginLimiter() }()
+	loginLimits = newLoginLimiter()
+	for i := 0; i < defaultLoginMaxFailures; i++ {
+		loginLimits.failed("test@test.com", time.Now())
+	}
+
+	request := httptest.NewRequest("GET", "/users/lockouts", nil)
+	responseRecorder := httptest.NewRecorder()
+	ListLockouts(responseRecorder, request)
+	var lockouts []LockoutData
+	json.NewDecoder(responseRecorder.Body).Decode(&lockouts)
+	if len(lockouts) != 1 || lockouts[0].EmailAddress != "test@test.com" {
+		t.Fatalf("Want test@test.com locked, got '%+v'", lockouts)
+	}
+
+	request = httptest.NewRequest("DELETE", "/users/lockouts/test@test.com", nil)
+	request = webhelper.WithParams(request, webhelper.Params{"email": "test@test.com"})
+	responseRecorder = httptest.NewRecorder()
+	DeleteLockout(responseRecorder, request)
+	if responseRecorder.Code != http.StatusOK {
+		t.Errorf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+	}
+	responseRecorder = httptest.NewRecorder()
+	DeleteLockout(responseRecorder, request)
+	if responseRecorder.Code != http.StatusNotFound {
+		t.Errorf("Want status '%d', got '%d'", http.StatusNotFound, responseRecorder.Code)
+	}
+}
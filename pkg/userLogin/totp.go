@@ -0,0 +1,426 @@
+package userLogin
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Codes are the RFC 6238 defaults every authenticator app understands
+const totpDigits = 6
+const totpModulus = 1000000 // 10^totpDigits
+const totpPeriod = 30
+const totpSkew = 1 // steps either side of now a code is still accepted for
+
+const defaultTotpIssuer = "SinkronTrack"
+const totpChallengeTTL = 5 * time.Minute
+const recoveryCodeCount = 10
+
+// The audience of the token handed out between the two sign in steps
+const totpSigninPurpose = "totp-signin"
+
+var errTotpEnabled = errors.New("Two factor authentication is already enabled")
+var errTotpNotEnabled = errors.New("Two factor authentication is not enabled")
+var errTotpNotEnrolling = errors.New("Start two factor enrolment first")
+var errTotpCodeInvalid = webhelper.NewError(webhelper.CodeTotpCodeInvalid, "Invalid two factor code")
+var errTotpChallengeInvalid = webhelper.NewError(webhelper.CodeTotpChallengeInvalid, "Invalid or expired two factor sign in, sign in again")
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type TotpEnrolmentData struct {
+	Secret string `json:"secret"`
+	Uri    string `json:"uri"` // otpauth:// URI, for a QR code
+}
+
+type TotpCodeData struct {
+	Code string `json:"code"` // a code from the authenticator app or a recovery code
+}
+
+type RecoveryCodesData struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// TotpChallengeData is the response to a sign in with the right password
+// when the user has two factor authentication. The token and a code are sent
+// to /users/signin/totp to finish signing in.
+type TotpChallengeData struct {
+	TotpRequired bool   `json:"totpRequired"`
+	TotpToken    string `json:"totpToken"`
+	ExpiresAt    int64  `json:"expiresAt"` // unix seconds
+}
+
+type TotpSigninData struct {
+	TotpToken string `json:"totpToken"`
+	Code      string `json:"code"`
+}
+
+// TotpClaims carry the sign in over to the second step. The fingerprint is of
+// the password and secret, so the token stops working if either changes.
+type TotpClaims struct {
+	Fingerprint string   `json:"fpr"`
+	DeviceId    string   `json:"deviceId,omitempty"`
+	DeviceName  string   `json:"deviceName,omitempty"`
+	AuthType    string   `json:"authType,omitempty"`
+	Scopes      []string `json:"scopes,omitempty"`
+	jwt.StandardClaims
+}
+
+// totpIssuer is the account name shown in authenticator apps, set with TOTP_ISSUER
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return defaultTotpIssuer
+}
+
+func newTotpSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+func totpUri(emailAddress string, secret string) string {
+	issuer := totpIssuer()
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+emailAddress) + "?" + query.Encode()
+}
+
+// totpCode is the code for the time step (RFC 4226 section 5.3)
+func totpCode(key []byte, step int64) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%totpModulus)
+}
+
+// checkTotpCode returns the time step the code is for, when it is valid now
+// and newer than the last code used
+func checkTotpCode(secret string, code string, lastStep int64, now time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// Recovery codes are shown as two groups of five, dashes, spaces and case
+// don't matter when one is typed back in
+func normaliseRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normaliseRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// newRecoveryCodes returns the codes to show the user, and the hashes to store
+func newRecoveryCodes() ([]string, []string, error) {
+	var codes, hashes []string
+	for i := 0; i < recoveryCodeCount; i++ {
+		random := make([]byte, 6)
+		if _, err := rand.Read(random); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(random))
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// useSecondFactor checks a code from the authenticator app or one of the
+// recovery codes, using it up. A code another request used first is rejected
+// the same as a wrong one.
+func useSecondFactor(user *User, code string, now time.Time) (bool, error) {
+	code = strings.TrimSpace(code)
+	if step, ok := checkTotpCode(user.TotpSecret, code, user.TotpLastStep, now); ok {
+		lastStep := user.TotpLastStep
+		user.TotpLastStep = step
+		return usedSecondFactor(user.UseTotpStep(lastStep))
+	}
+	hash := hashRecoveryCode(code)
+	for _, stored := range user.TotpRecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(stored)) == 1 {
+			return usedSecondFactor(user.UseRecoveryCode(stored))
+		}
+	}
+	return false, nil
+}
+
+func usedSecondFactor(err error) (bool, error) {
+	if err == storage.ErrSecondFactorUsed {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func totpFingerprint(password string, secret string) string {
+	sum := sha256.Sum256([]byte(totpSigninPurpose + "\x00" + password + "\x00" + secret))
+	return hex.EncodeToString(sum[:16])
+}
+
+// totpChallenge asks for the second step of a sign in, the device the client
+// asked for goes in the token so the second step signs in the same one
+func totpChallenge(w http.ResponseWriter, r *http.Request, userUuid string, fingerprint string, creds *Credentials, authType string) {
+	keys, err := currentKeys()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	expiresAt := time.Now().Add(totpChallengeTTL)
+	token, err := keys.sign(&TotpClaims{
+		Fingerprint: fingerprint,
+		DeviceId:    creds.DeviceId,
+		DeviceName:  creds.DeviceName,
+		AuthType:    authType,
+		Scopes:      creds.Scopes,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  totpSigninPurpose,
+			Subject:   userUuid,
+			ExpiresAt: expiresAt.Unix(),
+		},
+	})
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(TotpChallengeData{TotpRequired: true, TotpToken: token, ExpiresAt: expiresAt.Unix()})
+}
+
+// SigninTotp is the second step of a sign in with two factor authentication,
+// a wrong code counts as a failed sign in
+func SigninTotp(w http.ResponseWriter, r *http.Request) {
+	var data TotpSigninData
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	keys, err := currentKeys()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	claims := &TotpClaims{}
+	token, err := jwt.ParseWithClaims(data.TotpToken, claims, keys.keyFunc)
+	if err != nil || !token.Valid || claims.Audience != totpSigninPurpose {
+		webhelper.ReturnError(w, r, errTotpChallengeInvalid, &[]int{http.StatusUnauthorized}[0])
+		return
+	}
+	var user User
+	user.Uuid = claims.Subject
+	if err := user.Select(); err != nil || !user.TotpEnabled ||
+		claims.Fingerprint != totpFingerprint(user.Password, user.TotpSecret) {
+		webhelper.ReturnError(w, r, errTotpChallengeInvalid, &[]int{http.StatusUnauthorized}[0])
+		return
+	}
+	if !user.Enabled {
+		webhelper.ReturnError(w, r, errAccountDisabled, &[]int{http.StatusForbidden}[0])
+		return
+	}
+	if wait, err := loginLimits.allow(clientIp(r), user.EmailAddress, time.Now()); err != nil {
+		tooManyRequests(w, r, wait, err)
+		return
+	}
+	used, err := useSecondFactor(&user, data.Code, time.Now())
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	if !used {
+		loginLimits.failed(user.EmailAddress, time.Now())
+		webhelper.ReturnError(w, r, errTotpCodeInvalid, &[]int{http.StatusUnauthorized}[0])
+		return
+	}
+	loginLimits.succeeded(user.EmailAddress)
+
+	creds := &Credentials{Username: user.EmailAddress, DeviceId: claims.DeviceId, DeviceName: claims.DeviceName, Scopes: claims.Scopes}
+	completeSignin(w, r, user.EmailAddress, user.Uuid, creds, claims.AuthType)
+}
+
+// signedInUser loads the user the request was made by
+func signedInUser(w http.ResponseWriter, r *http.Request) (*User, bool) {
+	claims, response := requestClaimsVar(r)
+	if response != 200 {
+		webhelper.ReturnStatus(w, r, response)
+		return nil, false
+	}
+	var user User
+	user.EmailAddress = claims.Username
+	err := user.Select()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusNotFound}[0]) {
+		return nil, false
+	}
+	return &user, true
+}
+
+// EnrolTotp starts two factor enrolment with a new secret, which is only used
+// once a code from it is sent to ConfirmTotp
+func EnrolTotp(w http.ResponseWriter, r *http.Request) {
+	user, ok := signedInUser(w, r)
+	if !ok {
+		return
+	}
+	if user.TotpEnabled {
+		webhelper.ReturnError(w, r, errTotpEnabled, &[]int{http.StatusConflict}[0])
+		return
+	}
+	secret, err := newTotpSecret()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	user.TotpSecret = secret
+	user.TotpLastStep = 0
+	err = user.Update()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	json.NewEncoder(w).Encode(TotpEnrolmentData{Secret: secret, Uri: totpUri(user.EmailAddress, secret)})
+}
+
+// ConfirmTotp turns two factor authentication on with a code from the new
+// secret, returning the recovery codes. They are only ever shown this once.
+func ConfirmTotp(w http.ResponseWriter, r *http.Request) {
+	user, ok := signedInUser(w, r)
+	if !ok {
+		return
+	}
+	var data TotpCodeData
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	if user.TotpEnabled {
+		webhelper.ReturnError(w, r, errTotpEnabled, &[]int{http.StatusConflict}[0])
+		return
+	}
+	if user.TotpSecret == "" {
+		webhelper.ReturnError(w, r, errTotpNotEnrolling, &[]int{http.StatusBadRequest}[0])
+		return
+	}
+	step, valid := checkTotpCode(user.TotpSecret, strings.TrimSpace(data.Code), 0, time.Now())
+	if !valid {
+		webhelper.ReturnError(w, r, errTotpCodeInvalid, &[]int{http.StatusBadRequest}[0])
+		return
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	user.TotpEnabled = true
+	user.TotpLastStep = step
+	user.TotpRecoveryCodes = hashes
+	err = user.Update()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	json.NewEncoder(w).Encode(RecoveryCodesData{RecoveryCodes: codes})
+}
+
+// checkedSecondFactor is the signed in user with two factor authentication,
+// after checking the code sent with the request. Wrong codes count towards the
+// sign in limits, the same as they do when signing in.
+func checkedSecondFactor(w http.ResponseWriter, r *http.Request) (*User, bool) {
+	user, ok := signedInUser(w, r)
+	if !ok {
+		return nil, false
+	}
+	var data TotpCodeData
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return nil, false
+	}
+	if !user.TotpEnabled {
+		webhelper.ReturnError(w, r, errTotpNotEnabled, &[]int{http.StatusBadRequest}[0])
+		return nil, false
+	}
+	if wait, err := loginLimits.allow(clientIp(r), user.EmailAddress, time.Now()); err != nil {
+		tooManyRequests(w, r, wait, err)
+		return nil, false
+	}
+	used, err := useSecondFactor(user, data.Code, time.Now())
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return nil, false
+	}
+	if !used {
+		loginLimits.failed(user.EmailAddress, time.Now())
+		webhelper.ReturnError(w, r, errTotpCodeInvalid, &[]int{http.StatusForbidden}[0])
+		return nil, false
+	}
+	loginLimits.succeeded(user.EmailAddress)
+	return user, true
+}
+
+// DisableTotp turns two factor authentication off, with a current code or a
+// recovery code
+func DisableTotp(w http.ResponseWriter, r *http.Request) {
+	user, ok := checkedSecondFactor(w, r)
+	if !ok {
+		return
+	}
+	user.TotpEnabled = false
+	user.TotpSecret = ""
+	user.TotpLastStep = 0
+	user.TotpRecoveryCodes = nil
+	err := user.Update()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+
+	var responseDetails webhelper.Response
+	responseDetails.Message = "Two Factor Authentication Disabled"
+	json.NewEncoder(w).Encode(responseDetails)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes, with a current code or
+// one of the old recovery codes
+func RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user, ok := checkedSecondFactor(w, r)
+	if !ok {
+		return
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	user.TotpRecoveryCodes = hashes
+	err = user.Update()
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	json.NewEncoder(w).Encode(RecoveryCodesData{RecoveryCodes: codes})
+}
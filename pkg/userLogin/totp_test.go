@@ -0,0 +1,275 @@
+package userLogin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The RFC 6238 test secret, "12345678901234567890"
+const totpTestSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTotpCode(t *testing.T) {
+	key, _ := totpEncoding.DecodeString(totpTestSecret)
+	// The RFC 6238 appendix B SHA1 values, less the first two of the eight digits
+	tests := []struct {
+		time int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, test := range tests {
+		if got := totpCode(key, test.time/totpPeriod); got != test.want {
+			t.Errorf("Want code '%s' at %d, got '%s'", test.want, test.time, got)
+		}
+	}
+}
+
+func TestCheckTotpCode(t *testing.T) {
+	key, _ := totpEncoding.DecodeString(totpTestSecret)
+	now := time.Unix(1111111109, 0)
+	step := now.Unix() / totpPeriod
+
+	t.Run("The code from the step before is still accepted", func(t *testing.T) {
+		got, ok := checkTotpCode(totpTestSecret, totpCode(key, step-1), 0, now)
+		if !ok || got != step-1 {
+			t.Errorf("Want step '%d' accepted, got '%d' '%v'", step-1, got, ok)
+		}
+	})
+	t.Run("A code only works once", func(t *testing.T) {
+		if _, ok := checkTotpCode(totpTestSecret, totpCode(key, step), step, now); ok {
+			t.Error("Want the used code rejected")
+		}
+	})
+	t.Run("An old code is rejected", func(t *testing.T) {
+		if _, ok := checkTotpCode(totpTestSecret, totpCode(key, step-2), 0, now); ok {
+			t.Error("Want the old code rejected")
+		}
+	})
+}
+
+// totpTest stubs out test@test.com with two factor authentication, returning
+// the user as it was last saved
+func totpTest(t *testing.T, enabled bool, recoveryCodes ...string) **User {
+	testSigningKey()
+	stored := User{}
+	stored.Id = 2
+	stored.Uuid = emailUserUuid
+	stored.EmailAddress = "test@test.com"
+	stored.Password = "hash"
+	stored.Enabled = true
+	stored.TotpSecret = totpTestSecret
+	stored.TotpEnabled = enabled
+	for _, code := range recoveryCodes {
+		stored.TotpRecoveryCodes = append(stored.TotpRecoveryCodes, hashRecoveryCode(code))
+	}
+	saved := stubUser(t, stored)
+	bcryptCompareHashAndPassword = func(hashedPassword []byte, password []byte) error {
+		return nil
+	}
+	registerDeviceVar = func(emailAddress string, creds *Credentials, authType string, s *session) (*Device, error) {
+		device := &Device{}
+		device.Uuid = testDeviceUuid
+		device.Name = creds.DeviceName
+		s.apply(&device.Device)
+		return device, nil
+	}
+	requestClaimsVar = deviceClaims
+	return saved
+}
+
+func currentTotpCode() string {
+	key, _ := totpEncoding.DecodeString(totpTestSecret)
+	return totpCode(key, time.Now().Unix()/totpPeriod)
+}
+
+// totpChallengeToken signs in with the password, returning the token for the
+// second step
+func totpChallengeToken(t *testing.T) string {
+	request := httptest.NewRequest("POST", "/users/signin", strings.NewReader(`{"username":"test@test.com","password":"pw","deviceName":"Phone"}`))
+	responseRecorder := httptest.NewRecorder()
+
+	Signin(responseRecorder, request)
+	var challenge TotpChallengeData
+	json.NewDecoder(responseRecorder.Body).Decode(&challenge)
+	if responseRecorder.Code != http.StatusAccepted || !challenge.TotpRequired || challenge.TotpToken == "" {
+		t.Fatalf("Want a two factor challenge, got '%d' '%+v'", responseRecorder.Code, challenge)
+	}
+	return challenge.TotpToken
+}
+
+func totpSigninRequest(token string, code string) *http.Request {
+	return httptest.NewRequest("POST", "/users/signin/totp", strings.NewReader(`{"totpToken":"`+token+`","code":"`+code+`"}`))
+}
+
+func TestSigninTotp(t *testing.T) {
+	t.Run("The code finishes signing in on the same device", func(t *testing.T) {
+		saved := totpTest(t, true)
+		token := totpChallengeToken(t)
+		responseRecorder := httptest.NewRecorder()
+
+		SigninTotp(responseRecorder, totpSigninRequest(token, currentTotpCode()))
+		if responseRecorder.Code != http.StatusAccepted {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusAccepted, responseRecorder.Code)
+		}
+		var signin SigninData
+		json.NewDecoder(responseRecorder.Body).Decode(&signin)
+		if signin.Token == "" || signin.Name != "Phone" {
+			t.Errorf("Want a token for the device, got '%+v'", signin)
+		}
+		if *saved == nil || (*saved).TotpLastStep == 0 {
+			t.Error("Want the code used up")
+		}
+	})
+	t.Run("A wrong code counts as a failed sign in", func(t *testing.T) {
+		totpTest(t, true)
+		token := totpChallengeToken(t)
+		responseRecorder := httptest.NewRecorder()
+
+		SigninTotp(responseRecorder, totpSigninRequest(token, "000000x"))
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
+		}
+		if loginLimits.emails["test@test.com"].failures != 1 {
+			t.Errorf("Want 1 failure, got %d", loginLimits.emails["test@test.com"].failures)
+		}
+	})
+	t.Run("A recovery code only works once", func(t *testing.T) {
+		saved := totpTest(t, true, "abcde-fghij", "klmno-pqrst")
+		token := totpChallengeToken(t)
+		responseRecorder := httptest.NewRecorder()
+
+		SigninTotp(responseRecorder, totpSigninRequest(token, "ABCDE FGHIJ"))
+		if responseRecorder.Code != http.StatusAccepted {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusAccepted, responseRecorder.Code)
+		}
+		if len((*saved).TotpRecoveryCodes) != 1 {
+			t.Errorf("Want 1 recovery code left, got %d", len((*saved).TotpRecoveryCodes))
+		}
+		responseRecorder = httptest.NewRecorder()
+
+		SigninTotp(responseRecorder, totpSigninRequest(token, "abcde-fghij"))
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
+		}
+	})
+	for name, code := range map[string]string{"code": currentTotpCode(), "recovery code": "abcde-fghij"} {
+		t.Run("Two requests racing with the same "+name, func(t *testing.T) {
+			totpTest(t, true, "abcde-fghij")
+			token := totpChallengeToken(t)
+			// Both requests load the user before either has used the code
+			loaded := User{}
+			loaded.Uuid = emailUserUuid
+			executeSelectUser(&loaded)
+			executeSelectUser = func(m *User) error {
+				*m = loaded
+				return nil
+			}
+			responseRecorder := httptest.NewRecorder()
+
+			SigninTotp(responseRecorder, totpSigninRequest(token, code))
+			if responseRecorder.Code != http.StatusAccepted {
+				t.Fatalf("Want status '%d', got '%d'", http.StatusAccepted, responseRecorder.Code)
+			}
+			responseRecorder = httptest.NewRecorder()
+
+			SigninTotp(responseRecorder, totpSigninRequest(token, code))
+			if responseRecorder.Code != http.StatusUnauthorized {
+				t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
+			}
+		})
+	}
+	t.Run("The challenge stops working once the password changes", func(t *testing.T) {
+		totpTest(t, true)
+		token := totpChallengeToken(t)
+		selectUser := executeSelectUser
+		executeSelectUser = func(m *User) error {
+			err := selectUser(m)
+			m.Password = "new-hash"
+			return err
+		}
+		responseRecorder := httptest.NewRecorder()
+
+		SigninTotp(responseRecorder, totpSigninRequest(token, currentTotpCode()))
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
+		}
+	})
+}
+
+func TestEnrolTotp(t *testing.T) {
+	t.Run("Enrolment is confirmed with a code from the new secret", func(t *testing.T) {
+		saved := totpTest(t, false)
+		responseRecorder := httptest.NewRecorder()
+
+		EnrolTotp(responseRecorder, httptest.NewRequest("POST", "/users/totp", nil))
+		var enrolment TotpEnrolmentData
+		json.NewDecoder(responseRecorder.Body).Decode(&enrolment)
+		if !strings.HasPrefix(enrolment.Uri, "otpauth://totp/SinkronTrack:test@test.com?") ||
+			!strings.Contains(enrolment.Uri, "secret="+enrolment.Secret) {
+			t.Fatalf("Want an otpauth URI with the secret, got '%+v'", enrolment)
+		}
+		key, _ := totpEncoding.DecodeString(enrolment.Secret)
+		code := totpCode(key, time.Now().Unix()/totpPeriod)
+		responseRecorder = httptest.NewRecorder()
+
+		ConfirmTotp(responseRecorder, httptest.NewRequest("POST", "/users/totp/confirm", strings.NewReader(`{"code":"`+code+`"}`)))
+		if responseRecorder.Code != http.StatusOK {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		var recovery RecoveryCodesData
+		json.NewDecoder(responseRecorder.Body).Decode(&recovery)
+		user := *saved
+		if !user.TotpEnabled || len(recovery.RecoveryCodes) != recoveryCodeCount ||
+			user.TotpRecoveryCodes[0] != hashRecoveryCode(recovery.RecoveryCodes[0]) {
+			t.Errorf("Want two factor enabled with hashed recovery codes, got '%+v'", user)
+		}
+	})
+	t.Run("Enrolling again while enabled", func(t *testing.T) {
+		totpTest(t, true)
+		responseRecorder := httptest.NewRecorder()
+
+		EnrolTotp(responseRecorder, httptest.NewRequest("POST", "/users/totp", nil))
+		if responseRecorder.Code != http.StatusConflict {
+			t.Errorf("Want status '%d', got '%d'", http.StatusConflict, responseRecorder.Code)
+		}
+	})
+	t.Run("Disabling needs a code", func(t *testing.T) {
+		saved := totpTest(t, true)
+		responseRecorder := httptest.NewRecorder()
+
+		DisableTotp(responseRecorder, httptest.NewRequest("DELETE", "/users/totp", strings.NewReader(`{"code":"123"}`)))
+		if responseRecorder.Code != http.StatusForbidden || *saved != nil {
+			t.Fatalf("Want status '%d' without saving, got '%d'", http.StatusForbidden, responseRecorder.Code)
+		}
+		responseRecorder = httptest.NewRecorder()
+
+		DisableTotp(responseRecorder, httptest.NewRequest("DELETE", "/users/totp", strings.NewReader(`{"code":"`+currentTotpCode()+`"}`)))
+		if responseRecorder.Code != http.StatusOK || (*saved).TotpEnabled || (*saved).TotpSecret != "" {
+			t.Errorf("Want two factor disabled, got '%d' '%+v'", responseRecorder.Code, *saved)
+		}
+	})
+	t.Run("Wrong codes lock the account", func(t *testing.T) {
+		saved := totpTest(t, true)
+		var responseRecorder *httptest.ResponseRecorder
+		for i := 0; i <= defaultLoginMaxFailures; i++ {
+			responseRecorder = httptest.NewRecorder()
+			RegenerateRecoveryCodes(responseRecorder, httptest.NewRequest("POST", "/users/totp/recoveryCodes", strings.NewReader(`{"code":"123"}`)))
+		}
+		if responseRecorder.Code != http.StatusTooManyRequests {
+			t.Fatalf("Want status '%d', got '%d'", http.StatusTooManyRequests, responseRecorder.Code)
+		}
+		responseRecorder = httptest.NewRecorder()
+
+		DisableTotp(responseRecorder, httptest.NewRequest("DELETE", "/users/totp", strings.NewReader(`{"code":"`+currentTotpCode()+`"}`)))
+		if responseRecorder.Code != http.StatusTooManyRequests || *saved != nil {
+			t.Errorf("Want status '%d' without saving, got '%d'", http.StatusTooManyRequests, responseRecorder.Code)
+		}
+	})
+}
@@ -6,25 +6,25 @@ import (
 	"mimpidev/sinkrontrack-server/internal/storage"
 	"mimpidev/sinkrontrack-server/internal/webhelper"
 	"net/http"
-	"os"
-	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
-	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserData struct {
-	Id              uint64 `json:"id,omitempty"`
-	FirstName       string `json:"firstName"`
-	LastName        string `json:"lastName"`
-	EmailAddress    string `json:"emailAddress"`
-	Password        string `json:"password,omitempty"`
-	PasswordConfirm string `json:"confirmPassword,omitempty"`
-	Enabled         *bool  `json:"enabled,omitempty"`
-	AdminUser       *bool  `json:"adminUser,omitempty"`
+	Id              uint64   `json:"id,omitempty"`
+	FirstName       string   `json:"firstName"`
+	LastName        string   `json:"lastName"`
+	EmailAddress    string   `json:"emailAddress"`
+	Password        string   `json:"password,omitempty"`
+	PasswordConfirm string   `json:"confirmPassword,omitempty"`
+	Enabled         *bool    `json:"enabled,omitempty"`
+	EmailVerified   *bool    `json:"emailVerified,omitempty"` // only ever returned, verifying is done by email
+	AdminUser       *bool    `json:"adminUser,omitempty"`     // only ever returned, whether Roles includes admin
+	Roles           []string `json:"roles,omitempty"`         // only ever returned, changed with SetUserRoles
 }
 
 type UpdateUserData struct {
@@ -34,16 +34,40 @@ type UpdateUserData struct {
 	Password        string `json:"password,omitempty"`
 	PasswordConfirm string `json:"confirmPassword,omitempty"`
 	Enabled         *bool  `json:"enabled,omitempty"`
-	AdminUser       *bool  `json:"adminUser,omitempty"`
 }
 
 type Credentials struct {
-	Password string `json:"password"`
-	Username string `json:"username"`
+	Password   string   `json:"password"`
+	Username   string   `json:"username"`
+	DeviceId   string   `json:"deviceId,omitempty"` // returned by an earlier sign in from the same device
+	DeviceName string   `json:"deviceName,omitempty"`
+	Scopes     []string `json:"scopes,omitempty"` // limits the device's tokens, left out for a full sign in
+}
+
+// TokenData is a token handed to the client, for clients that can't use the
+// token cookie and send it as an Authorization: Bearer header instead
+type TokenData struct {
+	Token     string `json:"token"`
+	TokenType string `json:"tokenType"`
+	ExpiresAt int64  `json:"expiresAt"` // unix seconds
+	ExpiresIn int64  `json:"expiresIn"` // seconds from now
+
+	RefreshToken     string   `json:"refreshToken,omitempty"`
+	RefreshExpiresAt int64    `json:"refreshExpiresAt,omitempty"` // unix seconds
+	Scopes           []string `json:"scopes,omitempty"`           // missing when the token isn't limited
+}
+
+// SigninData is the device signed in from, and its token
+type SigninData struct {
+	DeviceData
+	TokenData
 }
 
 type Claims struct {
-	Username string `json:"username"`
+	Username    string   `json:"username"`
+	Device      string   `json:"device,omitempty"` // Uuid of the Device the token was issued to
+	Permissions []string `json:"-"`                // from the signed in user's roles, never the token
+	Scopes      []string `json:"scopes,omitempty"` // asked for when signing in or from the API key, nil when not limited
 	jwt.StandardClaims
 }
 
@@ -53,10 +77,16 @@ type User struct {
 
 var jwtParseWithClaims = jwt.ParseWithClaims
 var checkTokenVar = CheckToken
-var storageIsAdminUser = storage.IsAdminUser
+var requestClaimsVar = RequestClaims
+var authenticateVar = authenticate
+var registerDeviceVar = registerDevice
+var checkDeviceVar = checkDevice
 var bcryptGenerateFromPassword = bcrypt.GenerateFromPassword
 var bcryptCompareHashAndPassword = bcrypt.CompareHashAndPassword
 
+var errInvalidCredentials = webhelper.NewError(webhelper.CodeInvalidCredentials, "Invalid email address or password")
+var errAccountDisabled = webhelper.NewError(webhelper.CodeAccountDisabled, "User account is disabled")
+
 func CreateUser(m *User) (*uint64, error) {
 	// Encrypt the password
 	if m.Password == "" {
@@ -97,7 +127,7 @@ func checkEmail(emailAddress string, checkEmailEmpty bool) (*int, error) {
 		return &request, errors.New("Missing Email Address")
 	}
 	var user User
-	findResults, _ := user.Find(storage.User_.EmailAddress.Equals(emailAddress, false))
+	findResults, _ := user.Find(storage.UserFilter{EmailAddress: emailAddress})
 	if len(findResults) > 0 {
 		var request = http.StatusBadRequest
 		return &request, errors.New("User already exists")
@@ -117,8 +147,6 @@ func CreateUserLogin(w http.ResponseWriter, r *http.Request) {
 		userData.Enabled = &[]bool{true}[0]
 	}
 
-	userData.AdminUser = &[]bool{false}[0]
-
 	httpCode, err := checkPassword(userData.Password, userData.PasswordConfirm, true)
 	if webhelper.ReturnError(w, r, err, httpCode) {
 		return
@@ -136,7 +164,7 @@ func CreateUserLogin(w http.ResponseWriter, r *http.Request) {
 	user.EmailAddress = userData.EmailAddress
 	user.Password = userData.Password
 	user.Enabled = *userData.Enabled
-	user.AdminUser = *userData.AdminUser
+	user.Roles = []string{storage.RoleMember}
 
 	id, err := CreateUser(user)
 	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
@@ -153,57 +181,38 @@ func CreateUserLogin(w http.ResponseWriter, r *http.Request) {
 	userData.LastName = userOutput.LastName
 	userData.EmailAddress = userOutput.EmailAddress
 	userData.Enabled = &userOutput.Enabled
-	userData.AdminUser = &userOutput.AdminUser
+	userData.EmailVerified = &userOutput.EmailVerified
+	userData.AdminUser = &[]bool{userOutput.HasRole(storage.RoleAdmin)}[0]
+	userData.Roles = userOutput.Roles
 	userData.Id = userOutput.Id
 	userData.Password = ""
 	userData.PasswordConfirm = ""
+	sendVerificationEmail(&userOutput)
 	json.NewEncoder(w).Encode(userData)
 	return
 }
 
 func DeleteUserLogin(w http.ResponseWriter, r *http.Request) {
-	claims, tokenResponse := checkTokenVar(r)
+	claims, tokenResponse := requestClaimsVar(r)
 	if tokenResponse != http.StatusOK {
-		w.WriteHeader(tokenResponse)
+		webhelper.ReturnStatus(w, r, tokenResponse)
 		return
 	}
 
-	regex := regexp.MustCompile("^/user/(?:delete/|)([^/]+)$")
-	matches := regex.FindStringSubmatch(r.URL.Path)
-	if len(matches) == 0 {
+	param := webhelper.Param(r, "id")
+	if param == "" {
 		err := errors.New("No user account specified")
 		if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
 			return
 		}
 	}
-	id, err := strconv.ParseUint(matches[1], 10, 64)
+	id, err := strconv.ParseUint(param, 10, 64)
 	if err != nil {
 		err := errors.New("User Account is invalid")
 		if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
 			return
 		}
 	}
-	if id == 1 {
-		err := errors.New("Admin Account can not be deleted")
-		if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
-			return
-		}
-	}
-
-	// Check if user is not admin trying to delete another user
-	var currentUser User
-	users, _ := currentUser.Find(storage.User_.EmailAddress.Equals(claims.Username, false))
-	if users != nil {
-		for _, searchUser := range users {
-			if !searchUser.AdminUser &&
-				searchUser.Id != id {
-				err := errors.New("Access Denied")
-				if webhelper.ReturnError(w, r, err, &[]int{http.StatusForbidden}[0]) {
-					return
-				}
-			}
-		}
-	}
 
 	var user User
 	user.Id = id
@@ -211,6 +220,18 @@ func DeleteUserLogin(w http.ResponseWriter, r *http.Request) {
 	if webhelper.ReturnError(w, r, err, &[]int{http.StatusNotFound}[0]) {
 		return
 	}
+	if !strings.EqualFold(user.EmailAddress, claims.Username) && !canManage(claims, user.Roles) {
+		webhelper.ReturnError(w, r, webhelper.ErrAccessDenied, &[]int{http.StatusForbidden}[0])
+		return
+	}
+	last, err := lastAdmin(&user)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
+		return
+	}
+	if last {
+		webhelper.ReturnError(w, r, errLastAdmin, &[]int{http.StatusBadRequest}[0])
+		return
+	}
 	err = user.Delete()
 	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
 		return
@@ -221,45 +242,41 @@ func DeleteUserLogin(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+// ListUsers returns every user, or the one in /users/{id}. Who can see them is
+// declared on the routes, users with users:read for every user and the user
+// themselves.
 func ListUsers(w http.ResponseWriter, r *http.Request) {
-	claims, response := checkTokenVar(r)
+	_, response := requestClaimsVar(r)
 	if response != 200 {
-		w.WriteHeader(response)
+		webhelper.ReturnStatus(w, r, response)
 		return
 	}
 
-	regex := regexp.MustCompile("^/user/([^/]+)$")
-	matches := regex.FindStringSubmatch(r.URL.Path)
+	param := webhelper.Param(r, "id")
 
-	if len(matches) == 0 {
-		if isAdmin(claims) {
-			var user User
-			users, err := user.Find(storage.User_.Id.GreaterOrEqual(1))
-			if err != nil {
-				err := errors.New("User Account is invalid")
-				if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
-					return
-				}
-			}
-			var userList []*UserData
-			for _, user := range users {
-				var userData UserData
-				userData.Id = user.Id
-				userData.FirstName = user.FirstName
-				userData.LastName = user.LastName
-				userData.EmailAddress = user.EmailAddress
-				userList = append(userList, &userData)
-			}
-			json.NewEncoder(w).Encode(userList)
-			return
-		} else {
+	if param == "" {
+		var user User
+		users, err := user.Find(storage.UserFilter{})
+		if err != nil {
 			err := errors.New("User Account is invalid")
-			if webhelper.ReturnError(w, r, err, &[]int{http.StatusNotFound}[0]) {
+			if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
 				return
 			}
 		}
+		var userList []*UserData
+		for _, user := range users {
+			var userData UserData
+			userData.Id = user.Id
+			userData.FirstName = user.FirstName
+			userData.LastName = user.LastName
+			userData.EmailAddress = user.EmailAddress
+			userData.Roles = user.Roles
+			userList = append(userList, &userData)
+		}
+		json.NewEncoder(w).Encode(userList)
+		return
 	}
-	id, err := strconv.ParseUint(matches[1], 10, 64)
+	id, err := strconv.ParseUint(param, 10, 64)
 	if err != nil {
 		err := errors.New("User Account is invalid")
 		if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
@@ -276,28 +293,22 @@ func ListUsers(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if !isAdmin(claims) &&
-		claims.Username != user.EmailAddress {
-		err := errors.New("Permission denied")
-		if webhelper.ReturnError(w, r, err, &[]int{http.StatusForbidden}[0]) {
-			return
-		}
-	}
-
 	var userData UserData
 	userData.Id = user.Id
 	userData.FirstName = user.FirstName
 	userData.LastName = user.LastName
 	userData.EmailAddress = user.EmailAddress
+	userData.EmailVerified = &user.EmailVerified
+	userData.Roles = user.Roles
 
 	json.NewEncoder(w).Encode(userData)
 	return
 }
 
 func UpdateUserLogin(w http.ResponseWriter, r *http.Request) {
-	claims, response := checkTokenVar(r)
+	claims, response := requestClaimsVar(r)
 	if response != 200 {
-		w.WriteHeader(response)
+		webhelper.ReturnStatus(w, r, response)
 		return
 	}
 
@@ -306,15 +317,14 @@ func UpdateUserLogin(w http.ResponseWriter, r *http.Request) {
 	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
 		return
 	}
-	regex := regexp.MustCompile("^/user/([^/]+)$")
-	matches := regex.FindStringSubmatch(r.URL.Path)
-	if len(matches) == 0 {
+	param := webhelper.Param(r, "id")
+	if param == "" {
 		err := errors.New("No user account specified")
 		if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
 			return
 		}
 	}
-	id, err := strconv.ParseUint(matches[1], 10, 64)
+	id, err := strconv.ParseUint(param, 10, 64)
 	if err != nil {
 		err := errors.New("User Account is invalid")
 		if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
@@ -328,26 +338,28 @@ func UpdateUserLogin(w http.ResponseWriter, r *http.Request) {
 	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
 		return
 	}
-	if user.EmailAddress != claims.Username &&
-		!storageIsAdminUser(claims.Username) {
-		err := errors.New("User can not modify another user account")
-		if webhelper.ReturnError(w, r, err, &[]int{http.StatusUnauthorized}[0]) {
-			return
-		}
+	if !strings.EqualFold(user.EmailAddress, claims.Username) && !canManage(claims, user.Roles) {
+		webhelper.ReturnError(w, r, webhelper.ErrAccessDenied, &[]int{http.StatusForbidden}[0])
+		return
 	}
-
 	if userData.FirstName != "" {
 		user.FirstName = userData.FirstName
 	}
 	if userData.LastName != "" {
 		user.LastName = userData.LastName
 	}
+	emailChanged := userData.EmailAddress != "" && !strings.EqualFold(userData.EmailAddress, user.EmailAddress)
 	if userData.EmailAddress != "" {
 		user.EmailAddress = userData.EmailAddress
 	}
-	ec, err := checkEmail(userData.EmailAddress, false)
-	if webhelper.ReturnError(w, r, err, ec) {
-		return
+	if emailChanged {
+		user.EmailVerified = false
+	}
+	if emailChanged {
+		ec, err := checkEmail(userData.EmailAddress, false)
+		if webhelper.ReturnError(w, r, err, ec) {
+			return
+		}
 	}
 
 	// Need to check if email address already exists in the system and if so reject it
@@ -370,20 +382,20 @@ func UpdateUserLogin(w http.ResponseWriter, r *http.Request) {
 		*userData.Enabled != user.Enabled {
 		user.Enabled = *userData.Enabled
 	}
-	// Need to check if current user is an admin user, before allowing the AdminUser flag to be changed
-	if !isAdmin(claims) {
-		userData.AdminUser = &[]bool{false}[0]
-	}
-
 	err = user.Update()
 	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
 		return
 	}
+	if emailChanged {
+		sendVerificationEmail(&user)
+	}
 	var returnUserData UserData
 	returnUserData.FirstName = user.FirstName
 	returnUserData.LastName = user.LastName
 	returnUserData.EmailAddress = user.EmailAddress
 	returnUserData.Enabled = &user.Enabled
+	returnUserData.EmailVerified = &user.EmailVerified
+	returnUserData.Roles = user.Roles
 	returnUserData.Id = user.Id
 
 	json.NewEncoder(w).Encode(returnUserData)
@@ -397,13 +409,19 @@ func Signin(w http.ResponseWriter, r *http.Request) {
 
 	authType := r.Header.Get("X-Authentication-Type")
 
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	if err := checkScopes(creds.Scopes, nil); webhelper.ReturnError(w, r, err, &[]int{http.StatusBadRequest}[0]) {
+		return
+	}
+	if wait, err := loginLimits.allow(clientIp(r), creds.Username, time.Now()); err != nil {
+		tooManyRequests(w, r, wait, err)
 		return
 	}
 
 	var user User
-	findResults, err := user.Find(storage.User_.EmailAddress.Equals(creds.Username, false))
+	findResults, err := user.Find(storage.UserFilter{EmailAddress: creds.Username})
 
 	if err != nil {
 		// Give them a different message than the actual issue
@@ -416,118 +434,212 @@ func Signin(w http.ResponseWriter, r *http.Request) {
 	for _, user := range findResults {
 		err := bcryptCompareHashAndPassword([]byte(user.Password), []byte(creds.Password))
 		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
+			loginLimits.failed(creds.Username, time.Now())
+			webhelper.ReturnError(w, r, errInvalidCredentials, &[]int{http.StatusUnauthorized}[0])
 			return
 		} else {
-			expirationTime := time.Now().Add(60 * time.Minute)
-			// Create the JWT claims, which includes the username and expiry time
-			claims := &Claims{
-				Username: creds.Username,
-				StandardClaims: jwt.StandardClaims{
-					// In JWT, the expiry time is expressed as unix milliseconds
-					ExpiresAt: expirationTime.Unix(),
-				},
-			}
-			if authType != "" {
-				claims.StandardClaims = jwt.StandardClaims{
-					Subject: authType,
-					Id:      uuid.NewString(),
-				}
+			if !user.Enabled {
+				webhelper.ReturnError(w, r, errAccountDisabled, &[]int{http.StatusForbidden}[0])
+				return
 			}
-			// Declare the token with the algorithm used for signing, and the claims
-			token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-			jwtKey, err := getJwtKey()
-			if err != nil {
-				webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0])
+			if err := signInVerified(user.EmailVerified); webhelper.ReturnError(w, r, err, &[]int{http.StatusForbidden}[0]) {
 				return
 			}
-			tokenString, err := token.SignedString(jwtKey)
-			if err != nil {
-				// If there is an error in creating the JWT return an internal server error
-				w.WriteHeader(http.StatusInternalServerError)
+			// The failures are only forgotten once the second step is done too,
+			// otherwise the password could be used to keep guessing codes
+			if user.TotpEnabled {
+				totpChallenge(w, r, user.Uuid, totpFingerprint(user.Password, user.TotpSecret), &creds, authType)
 				return
 			}
-			// Finally, we set the client cookie for "token" as the JWT we just generated
-			// we also set an expiry time which is the same as the token itself
-			http.SetCookie(w, &http.Cookie{
-				Name:    "token",
-				Value:   tokenString,
-				Expires: expirationTime,
-			})
-			w.WriteHeader(http.StatusAccepted)
+			loginLimits.succeeded(creds.Username)
+			completeSignin(w, r, user.EmailAddress, user.Uuid, &creds, authType)
 			return
 		}
 	}
-	w.WriteHeader(http.StatusUnauthorized)
+	// Count guesses at addresses that aren't signed up too, so they can't be
+	// told apart from the ones that are by when they get locked
+	loginLimits.failed(creds.Username, time.Now())
+	webhelper.ReturnError(w, r, errInvalidCredentials, &[]int{http.StatusUnauthorized}[0])
 	return
 }
 
-func RefreshToken(w http.ResponseWriter, r *http.Request) {
-	claims, response := checkTokenVar(r)
-	if response != 200 {
-		w.WriteHeader(response)
+// completeSignin registers the device signed in from and hands it a session,
+// limited to the scopes in creds
+func completeSignin(w http.ResponseWriter, r *http.Request, username string, userUuid string, creds *Credentials, authType string) {
+	s, err := newSession(requestedScopes(creds.Scopes))
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
 		return
 	}
-
-	// Now, create a new token for the current use, with a renewed expiration time
-	expirationTime := time.Now().Add(5 * time.Minute)
-	claims.ExpiresAt = expirationTime.Unix()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	jwtKey, err := getJwtKey()
-	if err != nil {
-		webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0])
+	device, err := registerDeviceVar(username, creds, authType, s)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
 		return
 	}
-	tokenString, err := token.SignedString(jwtKey)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+
+	// Create the JWT claims, which includes the username and device,
+	// issueSession adds the token id, expiry time and scopes
+	claims := &Claims{
+		Username:       username,
+		Device:         device.Uuid,
+		StandardClaims: jwt.StandardClaims{Subject: authType},
+	}
+	token, err := issueSession(w, claims, userUuid, s)
+	if webhelper.ReturnError(w, r, err, &[]int{http.StatusInternalServerError}[0]) {
 		return
 	}
+	// The client sends the device id back with its next sign in
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(SigninData{DeviceData: *NewDeviceData(&device.Device), TokenData: *token})
+}
 
-	// Set the new token as the users `token` cookie
+// issueToken signs the claims and hands the token to the client, as the
+// "token" cookie for browsers and in the body for everything else
+func issueToken(w http.ResponseWriter, claims *Claims, expirationTime time.Time) (*TokenData, error) {
+	keys, err := currentKeys()
+	if err != nil {
+		return nil, err
+	}
+	tokenString, err := keys.sign(claims)
+	if err != nil {
+		return nil, err
+	}
+	// The cookie expires at the same time as the token itself
 	http.SetCookie(w, &http.Cookie{
 		Name:    "token",
 		Value:   tokenString,
+		Path:    "/",
 		Expires: expirationTime,
 	})
+	return &TokenData{
+		Token:     tokenString,
+		TokenType: "Bearer",
+		ExpiresAt: expirationTime.Unix(),
+		ExpiresIn: int64(time.Until(expirationTime).Round(time.Second).Seconds()),
+	}, nil
 }
 
-func getJwtKey() ([]byte, error) {
-	jwtKey := []byte(os.Getenv("JWT_KEY"))
-	if len(jwtKey) == 0 {
-		return nil, errors.New("No JWT Key defined in environment")
+// tokenErrorStatus is 400 for something that isn't a token at all, and 401 for
+// a token that is expired or can't be verified
+func tokenErrorStatus(err error) int {
+	if err == jwt.ErrSignatureInvalid {
+		return http.StatusUnauthorized
 	}
-	return jwtKey, nil
+	if validation, ok := err.(*jwt.ValidationError); ok && validation.Errors&jwt.ValidationErrorMalformed == 0 {
+		return http.StatusUnauthorized
+	}
+	return http.StatusBadRequest
 }
 
-func CheckToken(r *http.Request) (*Claims, int) {
+// requestToken returns the token from the Authorization header, or the token
+// cookie when there is no header
+func requestToken(r *http.Request) (string, int) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		fields := strings.Fields(header)
+		if len(fields) != 2 || !strings.EqualFold(fields[0], "Bearer") {
+			return "", http.StatusUnauthorized
+		}
+		return fields[1], http.StatusOK
+	}
 	c, err := r.Cookie("token")
 	if err != nil {
 		if err == http.ErrNoCookie {
-			return nil, http.StatusUnauthorized
+			return "", http.StatusUnauthorized
 		}
-		return nil, http.StatusBadRequest
+		return "", http.StatusBadRequest
+	}
+	return c.Value, http.StatusOK
+}
+
+// CheckToken validates the token sent with the request, either as an
+// Authorization: Bearer header or the token cookie. An API key can be sent
+// the same way.
+func CheckToken(r *http.Request) (*Claims, int) {
+	tknStr, response := requestToken(r)
+	if response != http.StatusOK {
+		return nil, response
+	}
+	if strings.HasPrefix(tknStr, apiKeyPrefix+"_") {
+		return checkApiKeyVar(tknStr)
 	}
-	tknStr := c.Value
 	claims := &Claims{}
 	tkn, err := jwtParseWithClaims(tknStr, claims, func(token *jwt.Token) (interface{}, error) {
-		jwtKey, err := getJwtKey()
-		return jwtKey, err
+		keys, err := currentKeys()
+		if err != nil {
+			return nil, err
+		}
+		return keys.keyFunc(token)
 	})
 	if err != nil {
-		if err == jwt.ErrSignatureInvalid {
-			return nil, http.StatusUnauthorized
-		}
-		return nil, http.StatusBadRequest
+		return nil, tokenErrorStatus(err)
 	}
-	if !tkn.Valid {
+	// Tokens with an audience were emailed for something else, see EmailClaims
+	if !tkn.Valid || claims.Audience != "" {
+		return nil, http.StatusUnauthorized
+	}
+	revoked, err := isTokenRevokedVar(claims.Id)
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+	if revoked {
+		return nil, http.StatusUnauthorized
+	}
+	if checkDeviceVar(claims) != nil {
 		return nil, http.StatusUnauthorized
 	}
 
 	return claims, http.StatusOK
 }
 
-func isAdmin(claims *Claims) bool {
-	// Need to hit up storage class to see if they are an admin user
-	return storageIsAdminUser(claims.Username)
+// authenticate checks the token and loads the user it was issued to, who has
+// to still be enabled
+func authenticate(r *http.Request) (*webhelper.Principal, int) {
+	claims, response := checkTokenVar(r)
+	if response != http.StatusOK {
+		return nil, response
+	}
+	var user User
+	user.EmailAddress = claims.Username
+	if err := user.Select(); err != nil || !user.Enabled {
+		return nil, http.StatusUnauthorized
+	}
+	return &webhelper.Principal{
+		UserId:       user.Id,
+		UserUuid:     user.Uuid,
+		EmailAddress: user.EmailAddress,
+		Permissions:  permissionsFor(user.Roles),
+		Device:       claims.Device,
+		TokenId:      claims.Id,
+		ExpiresAt:    claims.ExpiresAt,
+		Scopes:       claims.Scopes,
+	}, http.StatusOK
+}
+
+// Authenticate is the middleware for every route that needs a signed in user,
+// the handler gets them with RequestClaims or webhelper.CurrentPrincipal
+func Authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return webhelper.Authenticate(func(r *http.Request) (*webhelper.Principal, int) {
+		return authenticateVar(r)
+	})(next)
+}
+
+// RequestClaims returns the claims of the user Authenticate signed in
+func RequestClaims(r *http.Request) (*Claims, int) {
+	principal := webhelper.CurrentPrincipal(r)
+	if principal == nil {
+		return nil, http.StatusUnauthorized
+	}
+	return &Claims{
+		Username:    principal.EmailAddress,
+		Device:      principal.Device,
+		Permissions: principal.Permissions,
+		Scopes:      principal.Scopes,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: principal.ExpiresAt,
+			Id:        principal.TokenId,
+		},
+	}, http.StatusOK
+}
+
+// OwnsUserId is the owner check for /users/{id}
+func OwnsUserId(r *http.Request, p *webhelper.Principal) bool {
+	return webhelper.Param(r, "id") == strconv.FormatUint(p.UserId, 10)
 }
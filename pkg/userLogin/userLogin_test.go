@@ -1,38 +1,39 @@
 package userLogin
 
 import (
-	"bytes"
+	"encoding/json"
 	"errors"
+	"mimpidev/sinkrontrack-server/internal/storage"
+	"mimpidev/sinkrontrack-server/internal/webhelper"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
-	"github.com/objectbox/objectbox-go/objectbox"
 	"golang.org/x/crypto/bcrypt"
 )
 
 /*
- * Need to re-write my connection to objectbox to make it an interface, so I
- * can get the unit tests up and going, independent of objectbox-go.
- * It should also allow me to replace objectbox-go later if I really want to.
+ * The storage calls are stubbed out here so the handlers can be tested
+ * without opening a storage driver.
  */
 
 var executeCreateUser func(m *User) (*uint64, error)
-var executeFindUser func(conditions []objectbox.Condition) ([]*User, error)
+var executeFindUser func(filter storage.UserFilter) ([]*User, error)
 var executeSelectUser func(m *User) error
 var executeDeleteUser func(m *User) error
 var executeUpdateUser func(m *User) error
+var executeUseUserTotpStep func(m *User, expectedStep int64) error
+var executeUseUserRecoveryCode func(m *User, hash string) error
 
 func (m *User) Insert() (*uint64, error) {
 	return executeCreateUser(m)
 }
 
-func (m *User) Find(conditions ...objectbox.Condition) ([]*User, error) {
-	return executeFindUser(conditions)
+func (m *User) Find(filter storage.UserFilter) ([]*User, error) {
+	return executeFindUser(filter)
 }
 
 func (m *User) Select() error {
@@ -47,6 +48,103 @@ func (m *User) Update() error {
 	return executeUpdateUser(m)
 }
 
+func (m *User) UseTotpStep(expectedStep int64) error {
+	return executeUseUserTotpStep(m, expectedStep)
+}
+
+func (m *User) UseRecoveryCode(hash string) error {
+	return executeUseUserRecoveryCode(m, hash)
+}
+
+// restoreStubs puts every storage call and hook a test can stub out back the
+// way it was once the test has finished, the test gets its own login limits
+func restoreStubs(t *testing.T) {
+	createUser, findUser, selectUser, deleteUser, updateUser :=
+		executeCreateUser, executeFindUser, executeSelectUser, executeDeleteUser, executeUpdateUser
+	useTotpStep, useRecoveryCode := executeUseUserTotpStep, executeUseUserRecoveryCode
+	addDevice, findDevices, updateDevice, updateDeviceDetails, rotateDeviceRefreshToken, deleteDevice, releaseDeviceLocks :=
+		executeAddDevice, executeFindDevices, executeUpdateDevice, executeUpdateDeviceDetails,
+		executeRotateDeviceRefreshToken, executeDeleteDevice, releaseDeviceLocksVar
+	addFriend, updateFriend, deleteFriend, removeShares :=
+		executeAddFriend, executeUpdateFriend, executeDeleteFriend, removeSharesVar
+	saveGroup, deleteGroup, findGroups := executeSaveGroup, executeDeleteGroup, executeFindGroups
+	insertApiKey, updateApiKey, deleteApiKey, findApiKeys, checkApiKey :=
+		executeInsertApiKey, executeUpdateApiKey, executeDeleteApiKey, executeFindApiKeys, checkApiKeyVar
+	lookupProvider, insertExternalLogin, deleteExternalLogin, findExternalLogins :=
+		lookupProviderVar, executeInsertExternalLogin, executeDeleteExternalLogin, executeFindExternalLogins
+	revokeToken, isTokenRevoked, sendMail := executeRevokeToken, isTokenRevokedVar, sendMailVar
+	parseWithClaims, checkToken, requestClaims, authenticate, registerDevice, checkDevice :=
+		jwtParseWithClaims, checkTokenVar, requestClaimsVar, authenticateVar, registerDeviceVar, checkDeviceVar
+	generateFromPassword, compareHashAndPassword := bcryptGenerateFromPassword, bcryptCompareHashAndPassword
+	limits := loginLimits
+	loginLimits = newLoginLimiter()
+	t.Cleanup(func() {
+		executeCreateUser, executeFindUser, executeSelectUser, executeDeleteUser, executeUpdateUser =
+			createUser, findUser, selectUser, deleteUser, updateUser
+		executeUseUserTotpStep, executeUseUserRecoveryCode = useTotpStep, useRecoveryCode
+		executeAddDevice, executeFindDevices, executeUpdateDevice, executeUpdateDeviceDetails,
+			executeRotateDeviceRefreshToken, executeDeleteDevice, releaseDeviceLocksVar =
+			addDevice, findDevices, updateDevice, updateDeviceDetails, rotateDeviceRefreshToken, deleteDevice, releaseDeviceLocks
+		executeAddFriend, executeUpdateFriend, executeDeleteFriend, removeSharesVar =
+			addFriend, updateFriend, deleteFriend, removeShares
+		executeSaveGroup, executeDeleteGroup, executeFindGroups = saveGroup, deleteGroup, findGroups
+		executeInsertApiKey, executeUpdateApiKey, executeDeleteApiKey, executeFindApiKeys, checkApiKeyVar =
+			insertApiKey, updateApiKey, deleteApiKey, findApiKeys, checkApiKey
+		lookupProviderVar, executeInsertExternalLogin, executeDeleteExternalLogin, executeFindExternalLogins =
+			lookupProvider, insertExternalLogin, deleteExternalLogin, findExternalLogins
+		executeRevokeToken, isTokenRevokedVar, sendMailVar = revokeToken, isTokenRevoked, sendMail
+		jwtParseWithClaims, checkTokenVar, requestClaimsVar, authenticateVar, registerDeviceVar, checkDeviceVar =
+			parseWithClaims, checkToken, requestClaims, authenticate, registerDevice, checkDevice
+		bcryptGenerateFromPassword, bcryptCompareHashAndPassword = generateFromPassword, compareHashAndPassword
+		loginLimits = limits
+	})
+}
+
+// stubUser stubs out the storage of a single user, found by uuid or email
+// address, returning the user as it was last saved
+func stubUser(t *testing.T, stored User) **User {
+	restoreStubs(t)
+	executeSelectUser = func(m *User) error {
+		if (m.Uuid != "" && m.Uuid != stored.Uuid) || (m.Uuid == "" && m.EmailAddress != stored.EmailAddress) {
+			return storage.ErrUserNotFound
+		}
+		*m = stored
+		return nil
+	}
+	executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
+		user := stored
+		return []*User{&user}, nil
+	}
+	var saved *User
+	executeUpdateUser = func(m *User) error {
+		saved = m
+		stored = *m
+		return nil
+	}
+	executeUseUserTotpStep = func(m *User, expectedStep int64) error {
+		if stored.TotpLastStep != expectedStep {
+			return storage.ErrSecondFactorUsed
+		}
+		stored.TotpLastStep = m.TotpLastStep
+		user := stored
+		saved = &user
+		return nil
+	}
+	executeUseUserRecoveryCode = func(m *User, hash string) error {
+		for i, code := range stored.TotpRecoveryCodes {
+			if code == hash {
+				stored.TotpRecoveryCodes = append(stored.TotpRecoveryCodes[:i:i], stored.TotpRecoveryCodes[i+1:]...)
+				m.TotpRecoveryCodes = stored.TotpRecoveryCodes
+				user := stored
+				saved = &user
+				return nil
+			}
+		}
+		return storage.ErrSecondFactorUsed
+	}
+	return &saved
+}
+
 func TestCreateUser(t *testing.T) {
 	t.Run("create new user returning id", func(t *testing.T) {
 		user := User{}
@@ -55,7 +153,6 @@ func TestCreateUser(t *testing.T) {
 		user.EmailAddress = "test@test.com"
 		user.Password = "blah12345"
 		user.Enabled = true
-		user.AdminUser = false
 
 		executeCreateUser = func(m *User) (*uint64, error) {
 			return &[]uint64{1}[0], nil
@@ -72,7 +169,6 @@ func TestCreateUser(t *testing.T) {
 		user.LastName = "User"
 		user.EmailAddress = "test@test.com"
 		user.Enabled = true
-		user.AdminUser = false
 
 		executeCreateUser = func(m *User) (*uint64, error) {
 			return &[]uint64{1}[0], nil
@@ -88,12 +184,12 @@ func TestCreateUser(t *testing.T) {
 		user.LastName = "User"
 		user.EmailAddress = "test@test.com"
 		user.Enabled = true
-		user.AdminUser = false
 		user.Password = "blah12345"
 
 		bcryptGenerateFromPassword = func(password []byte, cost int) ([]byte, error) {
 			return nil, bcrypt.ErrMismatchedHashAndPassword
 		}
+		t.Cleanup(func() { bcryptGenerateFromPassword = bcrypt.GenerateFromPassword })
 
 		_, err := CreateUser(&user)
 		if err == nil {
@@ -150,7 +246,7 @@ func TestCheckEmail(t *testing.T) {
 		}
 	})
 	t.Run("emailAddress empty, checkEmailEmpty is false", func(t *testing.T) {
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			var userlist []*User
 			return userlist, nil
 		}
@@ -164,7 +260,7 @@ func TestCheckEmail(t *testing.T) {
 		}
 	})
 	t.Run("emailAddress does not exist, checkEmailEmpty is true", func(t *testing.T) {
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			var userlist []*User
 			return userlist, nil
 		}
@@ -178,13 +274,12 @@ func TestCheckEmail(t *testing.T) {
 		}
 	})
 	t.Run("emailAddress exists", func(t *testing.T) {
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			user := User{}
 			user.FirstName = "Test"
 			user.LastName = "User"
 			user.EmailAddress = "test@test.com"
 			user.Enabled = true
-			user.AdminUser = false
 
 			var userlist []*User
 			userlist = append(userlist, &user)
@@ -205,7 +300,7 @@ func TestCheckEmail(t *testing.T) {
 
 func TestCreateUserLogin(t *testing.T) {
 	t.Run("no data passed to the function", func(t *testing.T) {
-		request := httptest.NewRequest("POST", "/user", nil)
+		request := httptest.NewRequest("POST", "/users", nil)
 		responseRecorder := httptest.NewRecorder()
 		CreateUserLogin(responseRecorder, request)
 		if responseRecorder.Code != http.StatusBadRequest {
@@ -213,7 +308,7 @@ func TestCreateUserLogin(t *testing.T) {
 		}
 	})
 	t.Run("Confirm Bad Json throws an error", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			claims := &Claims{
 				Username:       "test@test.com",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -221,7 +316,7 @@ func TestCreateUserLogin(t *testing.T) {
 			return claims, http.StatusOK
 		}
 		var data = `{"firstName":"Test","lastName":"User","emailAddress":"test@test.com"`
-		request := httptest.NewRequest("POST", "/user", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/users", strings.NewReader(data))
 		responseRecorder := httptest.NewRecorder()
 		CreateUserLogin(responseRecorder, request)
 		if responseRecorder.Code != http.StatusBadRequest {
@@ -230,7 +325,7 @@ func TestCreateUserLogin(t *testing.T) {
 	})
 	t.Run("no password passed to the function", func(t *testing.T) {
 		var data = `{"firstName":"Test","lastName":"User","emailAddress":"test@test.com"}`
-		request := httptest.NewRequest("POST", "/user", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/users", strings.NewReader(data))
 		responseRecorder := httptest.NewRecorder()
 		CreateUserLogin(responseRecorder, request)
 		if responseRecorder.Code != http.StatusBadRequest {
@@ -240,7 +335,7 @@ func TestCreateUserLogin(t *testing.T) {
 
 	t.Run("passwords do not match passed to the function", func(t *testing.T) {
 		var data = `{"firstName":"Test","lastName":"User","emailAddress":"test@test.com", "password":"testPassword1", "confirmPassword":"testPassword2"}`
-		request := httptest.NewRequest("POST", "/user", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/users", strings.NewReader(data))
 		responseRecorder := httptest.NewRecorder()
 		CreateUserLogin(responseRecorder, request)
 		if responseRecorder.Code != http.StatusBadRequest {
@@ -250,7 +345,7 @@ func TestCreateUserLogin(t *testing.T) {
 
 	t.Run("blank email passed to the function", func(t *testing.T) {
 		var data = `{"firstName":"Test","lastName":"User","emailAddress":"", "password":"testPassword1", "confirmPassword":"testPassword2"}`
-		request := httptest.NewRequest("POST", "/user", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/users", strings.NewReader(data))
 		responseRecorder := httptest.NewRecorder()
 		CreateUserLogin(responseRecorder, request)
 		if responseRecorder.Code != http.StatusBadRequest {
@@ -260,17 +355,16 @@ func TestCreateUserLogin(t *testing.T) {
 
 	t.Run("check email does not exist in database for another user", func(t *testing.T) {
 		var data = `{"firstName":"Test","lastName":"User","emailAddress":"test@test.com.au", "password":"testPassword1", "confirmPassword":"testPassword1"}`
-		request := httptest.NewRequest("POST", "/user", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/users", strings.NewReader(data))
 		responseRecorder := httptest.NewRecorder()
 
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			user := User{}
 			user.Id = 2
 			user.FirstName = "Test"
 			user.LastName = "User"
 			user.EmailAddress = "test@test.com.au"
 			user.Enabled = true
-			user.AdminUser = false
 
 			var userlist []*User
 			userlist = append(userlist, &user)
@@ -286,7 +380,6 @@ func TestCreateUserLogin(t *testing.T) {
 			m.LastName = "User"
 			m.EmailAddress = "test@test.com.au"
 			m.Enabled = true
-			m.AdminUser = false
 
 			return nil
 		}
@@ -299,8 +392,13 @@ func TestCreateUserLogin(t *testing.T) {
 }
 
 func TestCheckToken(t *testing.T) {
+	restoreStubs(t)
+	isTokenRevokedVar = func(tokenId string) (bool, error) {
+		return false, nil
+	}
+
 	t.Run("no token passed for checking", func(t *testing.T) {
-		request := httptest.NewRequest("POST", "/user", nil)
+		request := httptest.NewRequest("POST", "/users", nil)
 
 		//request.AddCookie(&http.Cookie{Name:"token", Value:""})
 		_, status := CheckToken(request)
@@ -309,7 +407,7 @@ func TestCheckToken(t *testing.T) {
 		}
 	})
 	t.Run("empty token passed for checking", func(t *testing.T) {
-		request := httptest.NewRequest("POST", "/user", nil)
+		request := httptest.NewRequest("POST", "/users", nil)
 
 		request.AddCookie(&http.Cookie{Name: "token", Value: ""})
 
@@ -323,7 +421,7 @@ func TestCheckToken(t *testing.T) {
 		}
 	})
 	t.Run("invalid token passed for checking", func(t *testing.T) {
-		request := httptest.NewRequest("POST", "/user", nil)
+		request := httptest.NewRequest("POST", "/users", nil)
 
 		request.AddCookie(&http.Cookie{Name: "token", Value: "blahblahblah"})
 
@@ -337,7 +435,7 @@ func TestCheckToken(t *testing.T) {
 		}
 	})
 	t.Run("invalid token passed for checking", func(t *testing.T) {
-		request := httptest.NewRequest("POST", "/user", nil)
+		request := httptest.NewRequest("POST", "/users", nil)
 
 		request.AddCookie(&http.Cookie{Name: "token", Value: "blahblahblah"})
 
@@ -350,8 +448,22 @@ func TestCheckToken(t *testing.T) {
 			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, status)
 		}
 	})
+	t.Run("expired token passed for checking", func(t *testing.T) {
+		request := httptest.NewRequest("POST", "/users", nil)
+
+		request.AddCookie(&http.Cookie{Name: "token", Value: "blahblahblah"})
+
+		jwtParseWithClaims = func(tokenString string, claims jwt.Claims, keyFunc jwt.Keyfunc) (*jwt.Token, error) {
+			return nil, &jwt.ValidationError{Errors: jwt.ValidationErrorExpired}
+		}
+
+		_, status := CheckToken(request)
+		if status != http.StatusUnauthorized {
+			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, status)
+		}
+	})
 	t.Run("passed token is not valid", func(t *testing.T) {
-		request := httptest.NewRequest("POST", "/user", nil)
+		request := httptest.NewRequest("POST", "/users", nil)
 
 		request.AddCookie(&http.Cookie{Name: "token", Value: ""})
 
@@ -365,27 +477,160 @@ func TestCheckToken(t *testing.T) {
 		}
 	})
 	t.Run("passed token is valid", func(t *testing.T) {
-		request := httptest.NewRequest("POST", "/user", nil)
+		request := httptest.NewRequest("POST", "/users", nil)
 
 		request.AddCookie(&http.Cookie{Name: "token", Value: ""})
 
 		jwtParseWithClaims = func(tokenString string, claims jwt.Claims, keyFunc jwt.Keyfunc) (*jwt.Token, error) {
 			return &jwt.Token{Raw: "blah", Method: jwt.SigningMethodHS256, Claims: claims, Signature: "blah blah", Valid: true}, nil
 		}
+		checkDeviceVar = func(claims *Claims) error {
+			return nil
+		}
 
 		_, status := CheckToken(request)
 		if status != http.StatusOK {
 			t.Errorf("Want status '%d', got '%d'", http.StatusOK, status)
 		}
 	})
+	t.Run("token sent as a bearer token", func(t *testing.T) {
+		request := httptest.NewRequest("POST", "/users", nil)
+
+		request.Header.Set("Authorization", "Bearer blahblahblah")
+		var parsed string
+		jwtParseWithClaims = func(tokenString string, claims jwt.Claims, keyFunc jwt.Keyfunc) (*jwt.Token, error) {
+			parsed = tokenString
+			return &jwt.Token{Raw: "blah", Method: jwt.SigningMethodHS256, Claims: claims, Signature: "blah blah", Valid: true}, nil
+		}
+		checkDeviceVar = func(claims *Claims) error {
+			return nil
+		}
+
+		_, status := CheckToken(request)
+		if status != http.StatusOK || parsed != "blahblahblah" {
+			t.Errorf("Want status '%d' for token '%s', got '%d' for '%s'", http.StatusOK, "blahblahblah", status, parsed)
+		}
+	})
+	t.Run("authorization header is not a bearer token", func(t *testing.T) {
+		request := httptest.NewRequest("POST", "/users", nil)
+
+		request.Header.Set("Authorization", "Basic dGVzdDp0ZXN0")
+		request.AddCookie(&http.Cookie{Name: "token", Value: "blahblahblah"})
+
+		_, status := CheckToken(request)
+		if status != http.StatusUnauthorized {
+			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, status)
+		}
+	})
+	t.Run("token has been signed out", func(t *testing.T) {
+		request := httptest.NewRequest("POST", "/users", nil)
+
+		request.AddCookie(&http.Cookie{Name: "token", Value: ""})
+
+		jwtParseWithClaims = func(tokenString string, claims jwt.Claims, keyFunc jwt.Keyfunc) (*jwt.Token, error) {
+			claims.(*Claims).Id = "token-1"
+			return &jwt.Token{Raw: "blah", Method: jwt.SigningMethodHS256, Claims: claims, Signature: "blah blah", Valid: true}, nil
+		}
+		isTokenRevokedVar = func(tokenId string) (bool, error) {
+			return tokenId == "token-1", nil
+		}
+		defer func() {
+			isTokenRevokedVar = func(tokenId string) (bool, error) {
+				return false, nil
+			}
+		}()
+
+		_, status := CheckToken(request)
+		if status != http.StatusUnauthorized {
+			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, status)
+		}
+	})
+	t.Run("token issued to a revoked device", func(t *testing.T) {
+		request := httptest.NewRequest("POST", "/users", nil)
+
+		request.AddCookie(&http.Cookie{Name: "token", Value: ""})
+
+		jwtParseWithClaims = func(tokenString string, claims jwt.Claims, keyFunc jwt.Keyfunc) (*jwt.Token, error) {
+			return &jwt.Token{Raw: "blah", Method: jwt.SigningMethodHS256, Claims: claims, Signature: "blah blah", Valid: true}, nil
+		}
+		checkDeviceVar = func(claims *Claims) error {
+			return storage.ErrDeviceNotFound
+		}
+
+		_, status := CheckToken(request)
+		if status != http.StatusUnauthorized {
+			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, status)
+		}
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	defer func() { checkTokenVar = CheckToken }()
+	checkTokenVar = deviceClaims
+
+	t.Run("The principal is the user the token was issued to", func(t *testing.T) {
+		executeSelectUser = func(m *User) error {
+			m.Id = 2
+			m.Uuid = "6a1d0c3e-7b2f-4e59-a8c4-1f0e9d8c7b01"
+			m.Roles = []string{storage.RoleAdmin}
+			m.Enabled = true
+			return nil
+		}
+		var claims *Claims
+		handler := Authenticate(func(w http.ResponseWriter, r *http.Request) {
+			claims, _ = RequestClaims(r)
+		})
+		request := httptest.NewRequest("GET", "/devices", nil)
+		responseRecorder := httptest.NewRecorder()
+
+		handler(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK || claims == nil {
+			t.Fatalf("Want status '%d' and claims, got '%d'", http.StatusOK, responseRecorder.Code)
+		}
+		if claims.Username != "test@test.com" || claims.Device != testDeviceUuid || claims.Id != "token-1" || !claims.Can(PermRolesManage) {
+			t.Errorf("Want the claims of the admin user, got '%+v'", claims)
+		}
+	})
+	t.Run("The user has been disabled", func(t *testing.T) {
+		executeSelectUser = func(m *User) error {
+			m.Id = 2
+			m.Enabled = false
+			return nil
+		}
+		handler := Authenticate(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("Handler should not be called")
+		})
+		request := httptest.NewRequest("GET", "/devices", nil)
+		responseRecorder := httptest.NewRecorder()
+
+		handler(responseRecorder, request)
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
+		}
+	})
+	t.Run("The user has been deleted", func(t *testing.T) {
+		executeSelectUser = func(m *User) error {
+			return storage.ErrUserNotFound
+		}
+		handler := Authenticate(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("Handler should not be called")
+		})
+		request := httptest.NewRequest("GET", "/devices", nil)
+		responseRecorder := httptest.NewRecorder()
+
+		handler(responseRecorder, request)
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
+		}
+	})
 }
 
 func TestDeleteUserLogin(t *testing.T) {
 	t.Run("Delete User with no token", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			return nil, http.StatusUnauthorized
 		}
-		request := httptest.NewRequest("POST", "/user", nil)
+		request := httptest.NewRequest("POST", "/users", nil)
 		responseRecorder := httptest.NewRecorder()
 
 		DeleteUserLogin(responseRecorder, request)
@@ -394,11 +639,11 @@ func TestDeleteUserLogin(t *testing.T) {
 		}
 	})
 	t.Run("Confirm no user account specified on url for GET delete", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			return nil, http.StatusOK
 		}
 
-		request := httptest.NewRequest("GET", "/user/delete/", nil)
+		request := httptest.NewRequest("GET", "/users/delete/", nil)
 		responseRecorder := httptest.NewRecorder()
 
 		DeleteUserLogin(responseRecorder, request)
@@ -407,11 +652,11 @@ func TestDeleteUserLogin(t *testing.T) {
 		}
 	})
 	t.Run("Confirm no user account specified on url for DELETE ", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			return nil, http.StatusOK
 		}
 
-		request := httptest.NewRequest("GET", "/user/", nil)
+		request := httptest.NewRequest("GET", "/users/", nil)
 		responseRecorder := httptest.NewRecorder()
 
 		DeleteUserLogin(responseRecorder, request)
@@ -420,11 +665,12 @@ func TestDeleteUserLogin(t *testing.T) {
 		}
 	})
 	t.Run("Reject user id is of type string", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			return nil, http.StatusOK
 		}
 
-		request := httptest.NewRequest("GET", "/user/boo1", nil)
+		request := httptest.NewRequest("GET", "/users/boo1", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"id": "boo1"})
 		responseRecorder := httptest.NewRecorder()
 
 		DeleteUserLogin(responseRecorder, request)
@@ -432,12 +678,30 @@ func TestDeleteUserLogin(t *testing.T) {
 			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
 		}
 	})
-	t.Run("Confirm user id 1 (Primary Admin account) is rejected from deletion", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
-			return nil, http.StatusOK
+	t.Run("The last admin account can not be deleted", func(t *testing.T) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
+			return &Claims{Username: "admin@test.com", Permissions: permissionsFor([]string{storage.RoleAdmin})}, http.StatusOK
+		}
+		executeSelectUser = func(m *User) error {
+			m.EmailAddress = "admin@test.com"
+			m.Roles = []string{storage.RoleAdmin}
+			return nil
+		}
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
+			if filter.Role != storage.RoleAdmin {
+				t.Errorf("Want the admins found, got '%+v'", filter)
+			}
+			user := User{}
+			user.Id = 1
+			return []*User{&user}, nil
+		}
+		executeDeleteUser = func(m *User) error {
+			t.Error("User should not be deleted")
+			return nil
 		}
 
-		request := httptest.NewRequest("GET", "/user/1", nil)
+		request := httptest.NewRequest("DELETE", "/users/1", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"id": "1"})
 		responseRecorder := httptest.NewRecorder()
 
 		DeleteUserLogin(responseRecorder, request)
@@ -445,53 +709,64 @@ func TestDeleteUserLogin(t *testing.T) {
 			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
 		}
 	})
-	t.Run("Reject call when user does not exist", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
-			return nil, http.StatusOK
+	t.Run("A user manager can not delete an admin", func(t *testing.T) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
+			return &Claims{Username: "manager@test.com", Permissions: permissionsFor([]string{"user-manager"})}, http.StatusOK
+		}
+		executeSelectUser = func(m *User) error {
+			m.EmailAddress = "admin@test.com"
+			m.Roles = []string{storage.RoleAdmin}
+			return nil
+		}
+		executeDeleteUser = func(m *User) error {
+			t.Error("User should not be deleted")
+			return nil
 		}
 
-		request := httptest.NewRequest("GET", "/user/1", nil)
+		request := httptest.NewRequest("DELETE", "/users/1", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"id": "1"})
 		responseRecorder := httptest.NewRecorder()
 
 		DeleteUserLogin(responseRecorder, request)
-		if responseRecorder.Code != http.StatusBadRequest {
-			t.Errorf("Want status '%d', got '%d'", http.StatusBadRequest, responseRecorder.Code)
+		if responseRecorder.Code != http.StatusForbidden {
+			t.Errorf("Want status '%d', got '%d'", http.StatusForbidden, responseRecorder.Code)
 		}
-
 	})
-	t.Run("Confirm non admin user cannot delete another user", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
-			claims := &Claims{
-				Username:       "test@test.com",
-				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
-			}
-			return claims, http.StatusOK
+	t.Run("Reject call when user does not exist", func(t *testing.T) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
+			return &Claims{Username: "test@test.com"}, http.StatusOK
+		}
+		executeSelectUser = func(m *User) error {
+			return storage.ErrUserNotFound
 		}
 
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
-			user := User{}
-			user.Id = 2
-			user.FirstName = "Test"
-			user.LastName = "User"
-			user.EmailAddress = "test@test.com"
-			user.Enabled = true
-			user.AdminUser = false
+		request := httptest.NewRequest("GET", "/users/1", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"id": "1"})
+		responseRecorder := httptest.NewRecorder()
 
-			var userlist []*User
-			userlist = append(userlist, &user)
-			return userlist, nil
+		DeleteUserLogin(responseRecorder, request)
+		if responseRecorder.Code != http.StatusNotFound {
+			t.Errorf("Want status '%d', got '%d'", http.StatusNotFound, responseRecorder.Code)
 		}
 
-		request := httptest.NewRequest("DELETE", "/user/3", nil)
+	})
+	t.Run("Confirm non admin user cannot delete another user", func(t *testing.T) {
+		executeDeleteUser = func(m *User) error {
+			t.Error("User should not be deleted")
+			return nil
+		}
+		request := httptest.NewRequest("DELETE", "/users/3", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"id": "3"})
+		request = webhelper.WithPrincipal(request, &webhelper.Principal{UserId: 2, EmailAddress: "test@test.com"})
 		responseRecorder := httptest.NewRecorder()
 
-		DeleteUserLogin(responseRecorder, request)
+		webhelper.Chain(DeleteUserLogin, webhelper.RequireOwner(OwnsUserId, PermUsersDelete))(responseRecorder, request)
 		if responseRecorder.Code != http.StatusForbidden {
 			t.Errorf("Want status '%d', got '%d'", http.StatusForbidden, responseRecorder.Code)
 		}
 	})
 	t.Run("Confirm user is deleted by admin user", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			claims := &Claims{
 				Username:       "test@test.com",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -499,14 +774,14 @@ func TestDeleteUserLogin(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			user := User{}
 			user.Id = 2
 			user.FirstName = "Test"
 			user.LastName = "User"
 			user.EmailAddress = "test@test.com"
 			user.Enabled = true
-			user.AdminUser = true
+			user.Roles = []string{storage.RoleAdmin}
 
 			var userlist []*User
 			userlist = append(userlist, &user)
@@ -518,12 +793,12 @@ func TestDeleteUserLogin(t *testing.T) {
 			m.LastName = "User"
 			m.EmailAddress = "test@test.com.au"
 			m.Enabled = true
-			m.AdminUser = false
 
 			return nil
 		}
 
-		request := httptest.NewRequest("DELETE", "/user/3", nil)
+		request := httptest.NewRequest("DELETE", "/users/3", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"id": "3"})
 		responseRecorder := httptest.NewRecorder()
 
 		executeDeleteUser = func(m *User) error {
@@ -536,7 +811,7 @@ func TestDeleteUserLogin(t *testing.T) {
 		}
 	})
 	t.Run("Confirm user can delete themselves", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			claims := &Claims{
 				Username:       "test@test.com",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -544,14 +819,13 @@ func TestDeleteUserLogin(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			user := User{}
 			user.Id = 2
 			user.FirstName = "Test"
 			user.LastName = "User"
 			user.EmailAddress = "test@test.com"
 			user.Enabled = true
-			user.AdminUser = false
 
 			var userlist []*User
 			userlist = append(userlist, &user)
@@ -563,12 +837,12 @@ func TestDeleteUserLogin(t *testing.T) {
 			m.LastName = "User"
 			m.EmailAddress = "test@test.com"
 			m.Enabled = true
-			m.AdminUser = false
 
 			return nil
 		}
 
-		request := httptest.NewRequest("DELETE", "/user/2", nil)
+		request := httptest.NewRequest("DELETE", "/users/2", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"id": "2"})
 		responseRecorder := httptest.NewRecorder()
 
 		executeDeleteUser = func(m *User) error {
@@ -581,7 +855,7 @@ func TestDeleteUserLogin(t *testing.T) {
 		}
 	})
 	t.Run("Confirm error is thrown when user delete fails", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			claims := &Claims{
 				Username:       "test@test.com",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -589,14 +863,13 @@ func TestDeleteUserLogin(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			user := User{}
 			user.Id = 2
 			user.FirstName = "Test"
 			user.LastName = "User"
 			user.EmailAddress = "test@test.com"
 			user.Enabled = true
-			user.AdminUser = false
 
 			var userlist []*User
 			userlist = append(userlist, &user)
@@ -608,12 +881,12 @@ func TestDeleteUserLogin(t *testing.T) {
 			m.LastName = ""
 			m.EmailAddress = ""
 			m.Enabled = false
-			m.AdminUser = false
 
 			return nil
 		}
 
-		request := httptest.NewRequest("DELETE", "/user/2", nil)
+		request := httptest.NewRequest("DELETE", "/users/2", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"id": "2"})
 		responseRecorder := httptest.NewRecorder()
 
 		executeDeleteUser = func(m *User) error {
@@ -629,10 +902,10 @@ func TestDeleteUserLogin(t *testing.T) {
 
 func TestListUsers(t *testing.T) {
 	t.Run("List User with no token", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			return nil, http.StatusUnauthorized
 		}
-		request := httptest.NewRequest("GET", "/user", nil)
+		request := httptest.NewRequest("GET", "/users", nil)
 		responseRecorder := httptest.NewRecorder()
 
 		ListUsers(responseRecorder, request)
@@ -642,27 +915,17 @@ func TestListUsers(t *testing.T) {
 	})
 
 	t.Run("When no user id is in the url, check if user is an Admin account", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
-			claims := &Claims{
-				Username:       "test@test.com",
-				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
-			}
-			return claims, http.StatusOK
-		}
-
-		storageIsAdminUser = func(username string) bool {
-			return false
-		}
-		request := httptest.NewRequest("GET", "/user/", nil)
+		request := httptest.NewRequest("GET", "/users/", nil)
+		request = webhelper.WithPrincipal(request, &webhelper.Principal{UserId: 2, EmailAddress: "test@test.com"})
 		responseRecorder := httptest.NewRecorder()
 
-		ListUsers(responseRecorder, request)
-		if responseRecorder.Code != http.StatusNotFound {
-			t.Errorf("Want status '%d', got '%d'", http.StatusNotFound, responseRecorder.Code)
+		webhelper.Chain(ListUsers, webhelper.RequirePermission(PermUsersRead))(responseRecorder, request)
+		if responseRecorder.Code != http.StatusForbidden {
+			t.Errorf("Want status '%d', got '%d'", http.StatusForbidden, responseRecorder.Code)
 		}
 	})
 	t.Run("Confirm admin user can list all users", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			claims := &Claims{
 				Username:       "test@test.com",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -670,25 +933,20 @@ func TestListUsers(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		storageIsAdminUser = func(username string) bool {
-			return true
-		}
-
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			user := User{}
 			user.Id = 2
 			user.FirstName = "Test"
 			user.LastName = "User"
 			user.EmailAddress = "test@test.com"
 			user.Enabled = true
-			user.AdminUser = false
 
 			var userlist []*User
 			userlist = append(userlist, &user)
 			return userlist, nil
 		}
 
-		request := httptest.NewRequest("GET", "/user/", nil)
+		request := httptest.NewRequest("GET", "/users/", nil)
 		responseRecorder := httptest.NewRecorder()
 
 		ListUsers(responseRecorder, request)
@@ -698,7 +956,7 @@ func TestListUsers(t *testing.T) {
 
 	})
 	t.Run("Confirm user id is of type unsigned int", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			claims := &Claims{
 				Username:       "test@test.com",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -706,11 +964,8 @@ func TestListUsers(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		storageIsAdminUser = func(username string) bool {
-			return false
-		}
-
-		request := httptest.NewRequest("GET", "/user/FLAG", nil)
+		request := httptest.NewRequest("GET", "/users/FLAG", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"id": "FLAG"})
 		responseRecorder := httptest.NewRecorder()
 
 		ListUsers(responseRecorder, request)
@@ -719,7 +974,7 @@ func TestListUsers(t *testing.T) {
 		}
 	})
 	t.Run("Confirm user thrown when does not exist", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			claims := &Claims{
 				Username:       "test@test.com",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -727,21 +982,17 @@ func TestListUsers(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		storageIsAdminUser = func(username string) bool {
-			return true
-		}
-
 		executeSelectUser = func(m *User) error {
 			m.FirstName = ""
 			m.LastName = ""
 			m.EmailAddress = ""
 			m.Enabled = false
-			m.AdminUser = false
 
 			return errors.New("User Does not exist")
 		}
 
-		request := httptest.NewRequest("GET", "/user/2", nil)
+		request := httptest.NewRequest("GET", "/users/2", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"id": "2"})
 		responseRecorder := httptest.NewRecorder()
 
 		ListUsers(responseRecorder, request)
@@ -750,39 +1001,18 @@ func TestListUsers(t *testing.T) {
 		}
 	})
 	t.Run("Confirm non admin user cannot view another user", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
-			claims := &Claims{
-				Username:       "test@test.com",
-				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
-			}
-			return claims, http.StatusOK
-		}
-
-		storageIsAdminUser = func(username string) bool {
-			return false
-		}
-
-		executeSelectUser = func(m *User) error {
-			m.FirstName = "Test"
-			m.LastName = "User"
-			m.EmailAddress = "test@test.com.au"
-			m.Id = 2
-			m.Enabled = true
-			m.AdminUser = false
-
-			return nil
-		}
-
-		request := httptest.NewRequest("GET", "/user/2", nil)
+		request := httptest.NewRequest("GET", "/users/2", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"id": "2"})
+		request = webhelper.WithPrincipal(request, &webhelper.Principal{UserId: 3, EmailAddress: "test@test.com"})
 		responseRecorder := httptest.NewRecorder()
 
-		ListUsers(responseRecorder, request)
+		webhelper.Chain(ListUsers, webhelper.RequireOwner(OwnsUserId, PermUsersRead))(responseRecorder, request)
 		if responseRecorder.Code != http.StatusForbidden {
 			t.Errorf("Want status '%d', got '%d'", http.StatusForbidden, responseRecorder.Code)
 		}
 	})
 	t.Run("Confirm admin user can view any user", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			claims := &Claims{
 				Username:       "test@test.com",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -790,22 +1020,18 @@ func TestListUsers(t *testing.T) {
 			return claims, http.StatusOK
 		}
 
-		storageIsAdminUser = func(username string) bool {
-			return true
-		}
-
 		executeSelectUser = func(m *User) error {
 			m.FirstName = "Test"
 			m.LastName = "User"
 			m.EmailAddress = "test@test.com.au"
 			m.Id = 2
 			m.Enabled = true
-			m.AdminUser = false
 
 			return nil
 		}
 
-		request := httptest.NewRequest("GET", "/user/2", nil)
+		request := httptest.NewRequest("GET", "/users/2", nil)
+		request = webhelper.WithParams(request, webhelper.Params{"id": "2"})
 		responseRecorder := httptest.NewRecorder()
 
 		ListUsers(responseRecorder, request)
@@ -817,10 +1043,10 @@ func TestListUsers(t *testing.T) {
 
 func TestUpdateUserLogin(t *testing.T) {
 	t.Run("Update User with no token", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			return nil, http.StatusUnauthorized
 		}
-		request := httptest.NewRequest("PATCH", "/user", nil)
+		request := httptest.NewRequest("PATCH", "/users", nil)
 		responseRecorder := httptest.NewRecorder()
 
 		UpdateUserLogin(responseRecorder, request)
@@ -829,7 +1055,7 @@ func TestUpdateUserLogin(t *testing.T) {
 		}
 	})
 	t.Run("Confirm Bad Json throws an error", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			claims := &Claims{
 				Username:       "test@test.com",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -837,7 +1063,7 @@ func TestUpdateUserLogin(t *testing.T) {
 			return claims, http.StatusOK
 		}
 		var data = `{"firstName":"Test","lastName":"User","emailAddress":"test@test.com"`
-		request := httptest.NewRequest("POST", "/user", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/users", strings.NewReader(data))
 		responseRecorder := httptest.NewRecorder()
 		UpdateUserLogin(responseRecorder, request)
 		if responseRecorder.Code != http.StatusBadRequest {
@@ -845,7 +1071,7 @@ func TestUpdateUserLogin(t *testing.T) {
 		}
 	})
 	t.Run("Confirm user id missing in url, throws an error", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			claims := &Claims{
 				Username:       "test@test.com",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
@@ -853,7 +1079,7 @@ func TestUpdateUserLogin(t *testing.T) {
 			return claims, http.StatusOK
 		}
 		var data = `{"firstName":"Test","lastName":"User","emailAddress":"test@test.com"`
-		request := httptest.NewRequest("POST", "/user", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/users", strings.NewReader(data))
 		responseRecorder := httptest.NewRecorder()
 		UpdateUserLogin(responseRecorder, request)
 		if responseRecorder.Code != http.StatusBadRequest {
@@ -861,48 +1087,25 @@ func TestUpdateUserLogin(t *testing.T) {
 		}
 	})
 	t.Run("Confirm user id matches userlogin when user is not admin", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
-			claims := &Claims{
-				Username:       "test@test.com",
-				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
-			}
-			return claims, http.StatusOK
-		}
-
-		storageIsAdminUser = func(username string) bool {
-			return false
-		}
-
-		executeSelectUser = func(m *User) error {
-			m.FirstName = "Test"
-			m.LastName = "User"
-			m.EmailAddress = "test@test.com.au"
-			m.Id = 1
-			m.Enabled = true
-			m.AdminUser = false
-
-			return nil
-		}
-
 		var data = `{"firstName":"Test","lastName":"User","emailAddress":"test@test.com.au"}`
-		request := httptest.NewRequest("POST", "/user/1", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/users/1", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"id": "1"})
+		request = webhelper.WithPrincipal(request, &webhelper.Principal{UserId: 2, EmailAddress: "test@test.com"})
 		responseRecorder := httptest.NewRecorder()
-		UpdateUserLogin(responseRecorder, request)
-		if responseRecorder.Code != http.StatusUnauthorized {
-			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
+
+		webhelper.Chain(UpdateUserLogin, webhelper.RequireOwner(OwnsUserId, PermUsersWrite))(responseRecorder, request)
+		if responseRecorder.Code != http.StatusForbidden {
+			t.Errorf("Want status '%d', got '%d'", http.StatusForbidden, responseRecorder.Code)
 		}
 	})
 	t.Run("Updating user's email address to an email already in the system", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			claims := &Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
 			}
 			return claims, http.StatusOK
 		}
-		storageIsAdminUser = func(username string) bool {
-			return false
-		}
 
 		executeSelectUser = func(m *User) error {
 			m.FirstName = "Test"
@@ -910,18 +1113,16 @@ func TestUpdateUserLogin(t *testing.T) {
 			m.EmailAddress = "test@test.com.au"
 			m.Id = 1
 			m.Enabled = true
-			m.AdminUser = false
 
 			return nil
 		}
 
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			user := User{}
 			user.FirstName = "Test"
 			user.LastName = "User"
 			user.EmailAddress = "test@test.com"
 			user.Enabled = true
-			user.AdminUser = false
 
 			var userlist []*User
 			userlist = append(userlist, &user)
@@ -929,7 +1130,8 @@ func TestUpdateUserLogin(t *testing.T) {
 		}
 
 		var data = `{"firstName":"Test","lastName":"User","emailAddress":"test@test.com"}`
-		request := httptest.NewRequest("POST", "/user/2", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/users/2", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"id": "2"})
 		responseRecorder := httptest.NewRecorder()
 		UpdateUserLogin(responseRecorder, request)
 		if responseRecorder.Code != http.StatusBadRequest {
@@ -937,16 +1139,13 @@ func TestUpdateUserLogin(t *testing.T) {
 		}
 	})
 	t.Run("Confirm when password fails encryption it throws error", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			claims := &Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
 			}
 			return claims, http.StatusOK
 		}
-		storageIsAdminUser = func(username string) bool {
-			return false
-		}
 
 		executeSelectUser = func(m *User) error {
 			m.FirstName = "Test"
@@ -954,18 +1153,16 @@ func TestUpdateUserLogin(t *testing.T) {
 			m.EmailAddress = "test@test.com.au"
 			m.Id = 1
 			m.Enabled = true
-			m.AdminUser = false
 
 			return nil
 		}
 
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			user := User{}
 			user.FirstName = "Test"
 			user.LastName = "User"
 			user.EmailAddress = "test@test.com"
 			user.Enabled = true
-			user.AdminUser = false
 
 			var userlist []*User
 			//userlist = append(userlist, &user)
@@ -975,9 +1172,11 @@ func TestUpdateUserLogin(t *testing.T) {
 		bcryptGenerateFromPassword = func(password []byte, cost int) ([]byte, error) {
 			return nil, bcrypt.ErrMismatchedHashAndPassword
 		}
+		t.Cleanup(func() { bcryptGenerateFromPassword = bcrypt.GenerateFromPassword })
 
 		var data = `{"firstName":"Test","lastName":"User","emailAddress":"test@test.com","password":"blahblahblah","confirmPassword":"blahblahblah"}`
-		request := httptest.NewRequest("POST", "/user/2", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/users/2", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"id": "2"})
 		responseRecorder := httptest.NewRecorder()
 		UpdateUserLogin(responseRecorder, request)
 		if responseRecorder.Code != http.StatusBadRequest {
@@ -985,16 +1184,13 @@ func TestUpdateUserLogin(t *testing.T) {
 		}
 	})
 	t.Run("Confirm update failure throws an error", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			claims := &Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
 			}
 			return claims, http.StatusOK
 		}
-		storageIsAdminUser = func(username string) bool {
-			return false
-		}
 
 		executeSelectUser = func(m *User) error {
 			m.FirstName = "Test"
@@ -1002,18 +1198,16 @@ func TestUpdateUserLogin(t *testing.T) {
 			m.EmailAddress = "test@test.com.au"
 			m.Id = 1
 			m.Enabled = true
-			m.AdminUser = false
 
 			return nil
 		}
 
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			user := User{}
 			user.FirstName = "Test"
 			user.LastName = "User"
 			user.EmailAddress = "test@test.com"
 			user.Enabled = true
-			user.AdminUser = false
 
 			var userlist []*User
 			//userlist = append(userlist, &user)
@@ -1029,7 +1223,8 @@ func TestUpdateUserLogin(t *testing.T) {
 		}
 
 		var data = `{"firstName":"Test","lastName":"User","emailAddress":"test@test.com","password":"blahblahblah","confirmPassword":"blahblahblah"}`
-		request := httptest.NewRequest("POST", "/user/2", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/users/2", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"id": "2"})
 		responseRecorder := httptest.NewRecorder()
 		UpdateUserLogin(responseRecorder, request)
 		if responseRecorder.Code != http.StatusBadRequest {
@@ -1037,16 +1232,13 @@ func TestUpdateUserLogin(t *testing.T) {
 		}
 	})
 	t.Run("Confirm update success returns data", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
+		requestClaimsVar = func(r *http.Request) (*Claims, int) {
 			claims := &Claims{
 				Username:       "test@test.com.au",
 				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
 			}
 			return claims, http.StatusOK
 		}
-		storageIsAdminUser = func(username string) bool {
-			return false
-		}
 
 		executeSelectUser = func(m *User) error {
 			m.FirstName = "Test"
@@ -1054,18 +1246,16 @@ func TestUpdateUserLogin(t *testing.T) {
 			m.EmailAddress = "test@test.com.au"
 			m.Id = 1
 			m.Enabled = true
-			m.AdminUser = false
 
 			return nil
 		}
 
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			user := User{}
 			user.FirstName = "Test"
 			user.LastName = "User"
 			user.EmailAddress = "test@test.com"
 			user.Enabled = true
-			user.AdminUser = false
 
 			var userlist []*User
 			//userlist = append(userlist, &user)
@@ -1081,7 +1271,8 @@ func TestUpdateUserLogin(t *testing.T) {
 		}
 
 		var data = `{"firstName":"Test","lastName":"User","emailAddress":"test@test.com","password":"blahblahblah","confirmPassword":"blahblahblah"}`
-		request := httptest.NewRequest("POST", "/user/2", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/users/2", strings.NewReader(data))
+		request = webhelper.WithParams(request, webhelper.Params{"id": "2"})
 		responseRecorder := httptest.NewRecorder()
 		UpdateUserLogin(responseRecorder, request)
 		if responseRecorder.Code != http.StatusOK {
@@ -1091,13 +1282,14 @@ func TestUpdateUserLogin(t *testing.T) {
 }
 
 func TestSignIn(t *testing.T) {
+	loginLimits = newLoginLimiter()
 	t.Run("Confirm Bad Json throws an error", func(t *testing.T) {
-		os.Setenv("JWT_KEY", "")
+		signingKeys = nil
 		bcryptCompareHashAndPassword = func(hashedPassword []byte, password []byte) error {
 			return errors.New("Password does not match stored hash")
 		}
 		var data = `{"username":"test@test.com","password":"blahblahblah"`
-		request := httptest.NewRequest("POST", "/user/signin", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/users/signin", strings.NewReader(data))
 		responseRecorder := httptest.NewRecorder()
 
 		Signin(responseRecorder, request)
@@ -1106,78 +1298,88 @@ func TestSignIn(t *testing.T) {
 		}
 	})
 	t.Run("User matching email does not exist", func(t *testing.T) {
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			user := User{}
 			user.FirstName = "Test"
 			user.LastName = "User"
 			user.EmailAddress = "test@test.com"
 			user.Enabled = true
-			user.AdminUser = false
 
 			var userlist []*User
 			return userlist, nil
 		}
-		os.Setenv("JWT_KEY", "")
+		signingKeys = nil
 		bcryptCompareHashAndPassword = func(hashedPassword []byte, password []byte) error {
 			return errors.New("Password does not match stored hash")
 		}
 
 		var data = `{"username":"test@test.com","password":"blahblahblah"}`
-		request := httptest.NewRequest("POST", "/user/signin", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/users/signin", strings.NewReader(data))
 		responseRecorder := httptest.NewRecorder()
 
 		Signin(responseRecorder, request)
 		if responseRecorder.Code != http.StatusUnauthorized {
 			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
 		}
+		var response webhelper.ErrorResponse
+		json.NewDecoder(responseRecorder.Body).Decode(&response)
+		if response.Code != webhelper.CodeInvalidCredentials {
+			t.Errorf("Want code '%s', got '%s'", webhelper.CodeInvalidCredentials, response.Code)
+		}
 	})
 	t.Run("Password does not match stored user password", func(t *testing.T) {
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			user := User{}
 			user.FirstName = "Test"
 			user.LastName = "User"
 			user.EmailAddress = "test@test.com"
 			user.Enabled = true
-			user.AdminUser = false
 
 			var userlist []*User
 			userlist = append(userlist, &user)
 			return userlist, nil
 		}
-		os.Setenv("JWT_KEY", "")
+		signingKeys = nil
 		bcryptCompareHashAndPassword = func(hashedPassword []byte, password []byte) error {
 			return errors.New("Password does not match stored hash")
 		}
 
 		var data = `{"username":"test@test.com","password":"blahblahblah"}`
-		request := httptest.NewRequest("POST", "/user/signin", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/users/signin", strings.NewReader(data))
 		responseRecorder := httptest.NewRecorder()
 
 		Signin(responseRecorder, request)
 		if responseRecorder.Code != http.StatusUnauthorized {
 			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
 		}
+		var response webhelper.ErrorResponse
+		json.NewDecoder(responseRecorder.Body).Decode(&response)
+		if response.Code != webhelper.CodeInvalidCredentials {
+			t.Errorf("Want code '%s', got '%s'", webhelper.CodeInvalidCredentials, response.Code)
+		}
 	})
 	t.Run("JWT_KEY is not defined in the environment", func(t *testing.T) {
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			user := User{}
 			user.FirstName = "Test"
 			user.LastName = "User"
 			user.EmailAddress = "test@test.com"
 			user.Enabled = true
-			user.AdminUser = false
 
 			var userlist []*User
 			userlist = append(userlist, &user)
 			return userlist, nil
 		}
-		os.Setenv("JWT_KEY", "")
+		signingKeys = nil
 		bcryptCompareHashAndPassword = func(hashedPassword []byte, password []byte) error {
 			return nil
 		}
+		registerDeviceVar = func(emailAddress string, creds *Credentials, authType string, s *session) (*Device, error) {
+			return &Device{}, nil
+		}
 
 		var data = `{"username":"test@test.com","password":"blahblahblah"}`
-		request := httptest.NewRequest("POST", "/user/signin", strings.NewReader(data))
+		request := httptest.NewRequest("POST", "/users/signin", strings.NewReader(data))
 		responseRecorder := httptest.NewRecorder()
 
 		Signin(responseRecorder, request)
@@ -1186,114 +1388,96 @@ func TestSignIn(t *testing.T) {
 		}
 	})
 	t.Run("Token Signing Succeeds", func(t *testing.T) {
-		executeFindUser = func(conditions []objectbox.Condition) ([]*User, error) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
 			user := User{}
 			user.FirstName = "Test"
 			user.LastName = "User"
 			user.EmailAddress = "test@test.com"
 			user.Enabled = true
-			user.AdminUser = false
 
 			var userlist []*User
 			userlist = append(userlist, &user)
 			return userlist, nil
 		}
-		os.Setenv("JWT_KEY", "D5H5H65H56H5G4F3F3F32G")
+		testSigningKey()
 		bcryptCompareHashAndPassword = func(hashedPassword []byte, password []byte) error {
 			return nil
 		}
+		registerDeviceVar = func(emailAddress string, creds *Credentials, authType string, s *session) (*Device, error) {
+			device := &Device{}
+			device.Uuid = "0b5c6d2e-54c2-4bd8-9d5c-0f4a4f1c3a11"
+			device.Name = creds.DeviceName
+			s.apply(&device.Device)
+			return device, nil
+		}
 
-		var data = `{"username":"test@test.com","password":"blahblahblah"}`
-		request := httptest.NewRequest("POST", "/user/signin", strings.NewReader(data))
+		var data = `{"username":"test@test.com","password":"blahblahblah","deviceName":"Kitchen Speaker"}`
+		request := httptest.NewRequest("POST", "/users/signin", strings.NewReader(data))
 		responseRecorder := httptest.NewRecorder()
 
 		Signin(responseRecorder, request)
 		if responseRecorder.Code != http.StatusAccepted {
 			t.Errorf("Want status '%d', got '%d'", http.StatusAccepted, responseRecorder.Code)
 		}
-	})
-}
-
-func TestRefreshToken(t *testing.T) {
-	t.Run("RefreshToken call with no token", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
-			return nil, http.StatusUnauthorized
+		var signin SigninData
+		json.NewDecoder(responseRecorder.Body).Decode(&signin)
+		if signin.Id != "0b5c6d2e-54c2-4bd8-9d5c-0f4a4f1c3a11" || signin.Name != "Kitchen Speaker" {
+			t.Errorf("Want the registered device in the response, got '%+v'", signin.DeviceData)
 		}
-		os.Setenv("JWT_KEY", "")
-		request := httptest.NewRequest("PATCH", "/user", nil)
-		responseRecorder := httptest.NewRecorder()
-
-		RefreshToken(responseRecorder, request)
-		if responseRecorder.Code != http.StatusUnauthorized {
-			t.Errorf("Want status '%d', got '%d'", http.StatusUnauthorized, responseRecorder.Code)
+		cookie := responseRecorder.Result().Cookies()[0]
+		if signin.Token != cookie.Value || signin.TokenType != "Bearer" || signin.ExpiresAt != cookie.Expires.Unix() {
+			t.Errorf("Want the token cookie in the response, got '%+v'", signin.TokenData)
+		}
+		if signin.RefreshToken == "" || signin.RefreshExpiresAt <= signin.ExpiresAt {
+			t.Errorf("Want a refresh token outliving the access token, got '%+v'", signin.TokenData)
 		}
 	})
-	t.Run("Signing token succeeds", func(t *testing.T) {
-		checkTokenVar = func(r *http.Request) (*Claims, int) {
-			claims := &Claims{
-				Username:       "test@test.com",
-				StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
-			}
-			return claims, http.StatusOK
+	t.Run("A disabled user can't sign in", func(t *testing.T) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
+			user := User{}
+			user.EmailAddress = "test@test.com"
+			return []*User{&user}, nil
 		}
-		os.Setenv("JWT_KEY", "D5H5H65H56H5G4F3F3F32G")
-
-		request := httptest.NewRequest("PATCH", "/user", nil)
-		responseRecorder := httptest.NewRecorder()
-
-		RefreshToken(responseRecorder, request)
-		if responseRecorder.Code != http.StatusOK {
-			t.Errorf("Want status '%d', got '%d'", http.StatusOK, responseRecorder.Code)
+		testSigningKey()
+		bcryptCompareHashAndPassword = func(hashedPassword []byte, password []byte) error {
+			return nil
 		}
-	})
-}
-
-func TestIsAdmin(t *testing.T) {
-	t.Run("Given user is admin user, function should return true", func(t *testing.T) {
-		claims := &Claims{
-			Username:       "test@test.com",
-			StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
+		registerDeviceVar = func(emailAddress string, creds *Credentials, authType string, s *session) (*Device, error) {
+			t.Error("Want no device registered")
+			return nil, errors.New("Storage unavailable")
 		}
 
-		storageIsAdminUser = func(username string) bool {
-			return true
-		}
+		var data = `{"username":"test@test.com","password":"blahblahblah"}`
+		request := httptest.NewRequest("POST", "/users/signin", strings.NewReader(data))
+		responseRecorder := httptest.NewRecorder()
 
-		if !isAdmin(claims) {
-			t.Error("User should be declared admin user")
+		Signin(responseRecorder, request)
+		if responseRecorder.Code != http.StatusForbidden {
+			t.Errorf("Want status '%d', got '%d'", http.StatusForbidden, responseRecorder.Code)
 		}
 	})
-	t.Run("Given user is not an admin user, function should return false", func(t *testing.T) {
-		claims := &Claims{
-			Username:       "test@test.com",
-			StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(60 * time.Minute).Unix()},
+	t.Run("Device registration fails", func(t *testing.T) {
+		executeFindUser = func(filter storage.UserFilter) ([]*User, error) {
+			user := User{}
+			user.EmailAddress = "test@test.com"
+			user.Enabled = true
+			return []*User{&user}, nil
 		}
-
-		storageIsAdminUser = func(username string) bool {
-			return false
+		testSigningKey()
+		bcryptCompareHashAndPassword = func(hashedPassword []byte, password []byte) error {
+			return nil
 		}
-
-		if isAdmin(claims) {
-			t.Error("User should be declared nonadmin user")
+		registerDeviceVar = func(emailAddress string, creds *Credentials, authType string, s *session) (*Device, error) {
+			return nil, errors.New("Storage unavailable")
 		}
-	})
-}
-
-func TestGetJwtKey(t *testing.T) {
-	t.Run("Given JWT_KEY length is 0, it should fail", func(t *testing.T) {
-		os.Setenv("JWT_KEY", "")
 
-		_, err := getJwtKey()
-		if err == nil {
-			t.Error("Should throw an error")
-		}
-	})
-	t.Run("Given JWT_KEY length > 0, it should return key", func(t *testing.T) {
-		os.Setenv("JWT_KEY", "D5H5H65H56H5G4F3F3F32G")
+		var data = `{"username":"test@test.com","password":"blahblahblah"}`
+		request := httptest.NewRequest("POST", "/users/signin", strings.NewReader(data))
+		responseRecorder := httptest.NewRecorder()
 
-		jwtkey, _ := getJwtKey()
-		if bytes.Compare(jwtkey, []byte("D5H5H65H56H5G4F3F3F32G")) != 0 {
-			t.Error("Invalid JWT Key returned")
+		Signin(responseRecorder, request)
+		if responseRecorder.Code != http.StatusInternalServerError {
+			t.Errorf("Want status '%d', got '%d'", http.StatusInternalServerError, responseRecorder.Code)
 		}
 	})
 }